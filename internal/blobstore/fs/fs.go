@@ -0,0 +1,507 @@
+// Package fs implements the blobstore protocol on a local directory.
+//
+// Every object key maps to one regular file below the root directory. Writes
+// go to a private temporary file first and become visible through an atomic
+// link or rename, so readers observe either the previous or the next complete
+// object. Object tokens are content digests: a CompareAndSwap succeeds only
+// when the bytes currently stored still hash to the expected token.
+//
+// The root reserves the top-level ".unijord" directory for temporary files,
+// object attributes, and the cross-process lock used by CompareAndSwap.
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+)
+
+const (
+	// InternalDir is the reserved top-level directory for backend state. Keys
+	// inside it are rejected and it is never listed.
+	InternalDir = ".unijord"
+
+	tempDir  = InternalDir + "/tmp"
+	attrsDir = InternalDir + "/attrs"
+	lockName = InternalDir + "/lock"
+
+	dirMode  = 0o755
+	fileMode = 0o644
+)
+
+type Backend struct {
+	root string
+	// mu serializes CompareAndSwap inside this process. The file lock extends
+	// the same critical section to other processes sharing the root.
+	mu sync.Mutex
+}
+
+var _ blobstore.Store = (*Backend)(nil)
+
+// New opens a directory-backed store rooted at root, creating it if needed.
+func New(root string) (*Backend, error) {
+	if root == "" {
+		return nil, fmt.Errorf("internal/blobstore/fs: empty root")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("internal/blobstore/fs: resolve root: %w", err)
+	}
+	for _, dir := range []string{abs, filepath.Join(abs, filepath.FromSlash(tempDir)), filepath.Join(abs, filepath.FromSlash(attrsDir))} {
+		if err := os.MkdirAll(dir, dirMode); err != nil {
+			return nil, fmt.Errorf("internal/blobstore/fs: create %s: %w", dir, err)
+		}
+	}
+	return &Backend{root: abs}, nil
+}
+
+// Root returns the absolute root directory.
+func (b *Backend) Root() string {
+	return b.root
+}
+
+// Path validates key and returns the file that stores it.
+func (b *Backend) Path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+// ValidateKey rejects keys that cannot be mapped one-to-one onto a file below
+// the root.
+func ValidateKey(key string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("%w: empty key", blobstore.ErrInvalidRequest)
+	case strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/"):
+		return fmt.Errorf("%w: key %q has a leading or trailing slash", blobstore.ErrInvalidRequest, key)
+	case strings.ContainsAny(key, "\\\x00"):
+		return fmt.Errorf("%w: key %q contains a reserved character", blobstore.ErrInvalidRequest, key)
+	case key == InternalDir || strings.HasPrefix(key, InternalDir+"/"):
+		return fmt.Errorf("%w: key %q is inside the reserved %s directory", blobstore.ErrInvalidRequest, key, InternalDir)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("%w: key %q has an empty or relative path element", blobstore.ErrInvalidRequest, key)
+		}
+	}
+	return nil
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (blobstore.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return blobstore.Object{}, err
+	}
+	name, err := b.Path(key)
+	if err != nil {
+		return blobstore.Object{}, err
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return blobstore.Object{}, mapError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return blobstore.Object{}, mapError(err)
+	}
+	if !info.Mode().IsRegular() {
+		return blobstore.Object{}, blobstore.ErrObjectNotFound
+	}
+	body, err := io.ReadAll(f)
+	if err != nil {
+		return blobstore.Object{}, err
+	}
+	return blobstore.Object{
+		Key:       key,
+		Body:      body,
+		Token:     ContentToken(body),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+func (b *Backend) Put(ctx context.Context, key string, body []byte) (blobstore.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return blobstore.Object{}, err
+	}
+	info, err := b.Create(ctx, key, "", func(w io.Writer) error {
+		_, err := w.Write(body)
+		return err
+	})
+	if err == nil {
+		return blobstore.Object{Key: key, Body: bytes.Clone(body), Token: info.Token, CreatedAt: info.CreatedAt}, nil
+	}
+	if !errors.Is(err, blobstore.ErrImmutableConflict) {
+		return blobstore.Object{}, err
+	}
+	current, getErr := b.Get(ctx, key)
+	if getErr != nil {
+		return blobstore.Object{}, getErr
+	}
+	if !bytes.Equal(current.Body, body) {
+		return blobstore.Object{}, fmt.Errorf("%w: %s", blobstore.ErrImmutableConflict, key)
+	}
+	return current, nil
+}
+
+func (b *Backend) CompareAndSwap(ctx context.Context, key string, expectedToken string, body []byte) (blobstore.Object, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return blobstore.Object{}, false, err
+	}
+	name, err := b.Path(key)
+	if err != nil {
+		return blobstore.Object{}, false, err
+	}
+	unlock, err := b.lock()
+	if err != nil {
+		return blobstore.Object{}, false, err
+	}
+	defer unlock()
+
+	current, err := b.Get(ctx, key)
+	switch {
+	case errors.Is(err, blobstore.ErrObjectNotFound):
+		if expectedToken != "" {
+			return blobstore.Object{}, false, nil
+		}
+		info, err := b.Create(ctx, key, "", func(w io.Writer) error {
+			_, err := w.Write(body)
+			return err
+		})
+		if errors.Is(err, blobstore.ErrImmutableConflict) {
+			// Put does not take the CAS lock, so a concurrent immutable write
+			// can still win the create race.
+			current, getErr := b.Get(ctx, key)
+			if getErr != nil {
+				return blobstore.Object{}, false, getErr
+			}
+			return current, false, nil
+		}
+		if err != nil {
+			return blobstore.Object{}, false, err
+		}
+		return blobstore.Object{Key: key, Body: bytes.Clone(body), Token: info.Token, CreatedAt: info.CreatedAt}, true, nil
+	case err != nil:
+		return blobstore.Object{}, false, err
+	case current.Token != expectedToken:
+		return current, false, nil
+	}
+
+	tmp, token, err := b.writeTemp(func(w io.Writer) error {
+		_, err := w.Write(body)
+		return err
+	})
+	if err != nil {
+		return blobstore.Object{}, false, err
+	}
+	// Keep the original creation time, matching providers whose conditional
+	// overwrite does not reset object age.
+	if err := os.Chtimes(tmp, current.CreatedAt, current.CreatedAt); err != nil {
+		_ = os.Remove(tmp)
+		return blobstore.Object{}, false, err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		return blobstore.Object{}, false, err
+	}
+	syncDir(filepath.Dir(name))
+	return blobstore.Object{
+		Key:       key,
+		Body:      bytes.Clone(body),
+		Token:     token,
+		CreatedAt: current.CreatedAt,
+	}, true, nil
+}
+
+// List walks only the directory that contains prefix. Tokens are derived by
+// hashing each listed object.
+func (b *Backend) List(ctx context.Context, opts blobstore.ListOptions) (blobstore.ObjectPage, error) {
+	if err := ctx.Err(); err != nil {
+		return blobstore.ObjectPage{}, err
+	}
+	dirKey := ""
+	if i := strings.LastIndexByte(opts.Prefix, '/'); i >= 0 {
+		dirKey = opts.Prefix[:i]
+	}
+	start := b.root
+	if dirKey != "" {
+		if err := ValidateKey(dirKey); err != nil {
+			return blobstore.ObjectPage{}, nil
+		}
+		start = filepath.Join(b.root, filepath.FromSlash(dirKey))
+	}
+
+	keys := make([]string, 0)
+	err := filepath.WalkDir(start, func(name string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, iofs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(b.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if key == InternalDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !strings.HasPrefix(key, opts.Prefix) || opts.AfterKey != "" && key <= opts.AfterKey {
+			return nil
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return blobstore.ObjectPage{}, err
+	}
+	sort.Strings(keys)
+
+	page := blobstore.ObjectPage{}
+	if limit := opts.NormalizedLimit(); len(keys) > limit {
+		keys = keys[:limit]
+		page.HasMore = true
+		page.NextAfterKey = keys[len(keys)-1]
+	}
+	page.Objects = make([]blobstore.ObjectInfo, 0, len(keys))
+	for _, key := range keys {
+		info, err := b.stat(key)
+		if errors.Is(err, blobstore.ErrObjectNotFound) {
+			// Deleted after the walk observed it.
+			continue
+		}
+		if err != nil {
+			return blobstore.ObjectPage{}, err
+		}
+		page.Objects = append(page.Objects, info)
+	}
+	return page, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name, err := b.Path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(b.attrsPath(key)); err != nil && !errors.Is(err, iofs.ErrNotExist) {
+		return err
+	}
+	b.pruneEmptyDirs(filepath.Dir(name), b.root)
+	b.pruneEmptyDirs(filepath.Dir(b.attrsPath(key)), filepath.Join(b.root, filepath.FromSlash(attrsDir)))
+	return nil
+}
+
+// Create writes a new immutable object through write and publishes it only if
+// key does not exist yet. An existing key returns ErrImmutableConflict without
+// comparing bytes. A non-empty contentType is recorded as object attributes.
+func (b *Backend) Create(ctx context.Context, key string, contentType string, write func(io.Writer) error) (blobstore.ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	name, err := b.Path(key)
+	if err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	tmp, token, err := b.writeTemp(write)
+	if err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	defer os.Remove(tmp)
+	if err := ctx.Err(); err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), dirMode); err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	if err := os.Link(tmp, name); err != nil {
+		if errors.Is(err, iofs.ErrExist) {
+			return blobstore.ObjectInfo{}, fmt.Errorf("%w: %s", blobstore.ErrImmutableConflict, key)
+		}
+		return blobstore.ObjectInfo{}, err
+	}
+	syncDir(filepath.Dir(name))
+	if contentType != "" {
+		if err := b.writeAttrs(key, contentType); err != nil {
+			return blobstore.ObjectInfo{}, err
+		}
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return blobstore.ObjectInfo{}, mapError(err)
+	}
+	return blobstore.ObjectInfo{
+		Key:       key,
+		Token:     token,
+		SizeBytes: int(info.Size()),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+// ContentType returns the content type recorded by Create, or an empty string
+// when the object was written without one.
+func (b *Backend) ContentType(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	name, err := b.Path(key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(name); err != nil {
+		return "", mapError(err)
+	}
+	body, err := os.ReadFile(b.attrsPath(key))
+	if errors.Is(err, iofs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ContentToken returns the token this backend assigns to body.
+func ContentToken(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+func hashToken(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+func (b *Backend) stat(key string) (blobstore.ObjectInfo, error) {
+	name := filepath.Join(b.root, filepath.FromSlash(key))
+	f, err := os.Open(name)
+	if err != nil {
+		return blobstore.ObjectInfo{}, mapError(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return blobstore.ObjectInfo{}, mapError(err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return blobstore.ObjectInfo{}, err
+	}
+	return blobstore.ObjectInfo{
+		Key:       key,
+		Token:     hashToken(h),
+		SizeBytes: int(info.Size()),
+		CreatedAt: info.ModTime().UTC(),
+	}, nil
+}
+
+// writeTemp writes one complete, synced file in the private temp directory and
+// returns its path and content token.
+func (b *Backend) writeTemp(write func(io.Writer) error) (string, string, error) {
+	f, err := os.CreateTemp(filepath.Join(b.root, filepath.FromSlash(tempDir)), "object-*")
+	if err != nil {
+		return "", "", err
+	}
+	name := f.Name()
+	h := sha256.New()
+	err = write(io.MultiWriter(f, h))
+	if err == nil {
+		err = f.Chmod(fileMode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(name)
+		return "", "", err
+	}
+	return name, hashToken(h), nil
+}
+
+func (b *Backend) writeAttrs(key string, contentType string) error {
+	name := b.attrsPath(key)
+	if err := os.MkdirAll(filepath.Dir(name), dirMode); err != nil {
+		return err
+	}
+	tmp, _, err := b.writeTemp(func(w io.Writer) error {
+		_, err := io.WriteString(w, contentType)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (b *Backend) attrsPath(key string) string {
+	return filepath.Join(b.root, filepath.FromSlash(path.Join(attrsDir, key)))
+}
+
+func (b *Backend) lock() (func(), error) {
+	b.mu.Lock()
+	unlock, err := lockFile(filepath.Join(b.root, filepath.FromSlash(lockName)))
+	if err != nil {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("internal/blobstore/fs: lock: %w", err)
+	}
+	return func() {
+		unlock()
+		b.mu.Unlock()
+	}, nil
+}
+
+// pruneEmptyDirs removes empty directories from dir up to, but excluding,
+// stop. Failures are ignored because a concurrent writer may be repopulating
+// the directory.
+func (b *Backend) pruneEmptyDirs(dir string, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop+string(filepath.Separator)) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func mapError(err error) error {
+	if errors.Is(err, iofs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", blobstore.ErrObjectNotFound, err)
+	}
+	var pathErr *iofs.PathError
+	if errors.As(err, &pathErr) && isNotDir(pathErr.Err) {
+		return fmt.Errorf("%w: %w", blobstore.ErrObjectNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package fs
+
+// lockFile is a no-op where advisory file locks are unavailable. Backend.mu
+// still serializes CompareAndSwap inside one process; sharing a root across
+// processes is unsupported on these platforms.
+func lockFile(string) (func(), error) {
+	return func() {}, nil
+}
+
+func isNotDir(error) bool {
+	return false
+}
+
+func syncDir(string) {}
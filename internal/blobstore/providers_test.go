@@ -3,6 +3,7 @@ package blobstore_test
 import (
 	"github.com/ankur-anand/unijord/internal/blobstore"
 	blobazure "github.com/ankur-anand/unijord/internal/blobstore/azure"
+	blobfs "github.com/ankur-anand/unijord/internal/blobstore/fs"
 	blobgcs "github.com/ankur-anand/unijord/internal/blobstore/gcs"
 	blobs3 "github.com/ankur-anand/unijord/internal/blobstore/s3"
 )
@@ -11,4 +12,5 @@ var (
 	_ blobstore.Store = (*blobs3.Backend)(nil)
 	_ blobstore.Store = (*blobgcs.Backend)(nil)
 	_ blobstore.Store = (*blobazure.Backend)(nil)
+	_ blobstore.Store = (*blobfs.Backend)(nil)
 )
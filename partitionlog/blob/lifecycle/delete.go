@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
 )
 
 // batchDeleteBackend is an optional backend capability. Results align with
@@ -29,6 +31,12 @@ func (r *Reclaimer) executeDeletes(ctx context.Context, state *stateFile, candid
 	failed := 0
 	for start := 0; start < len(candidates); start += r.opts.DeleteBatchSize {
 		end := min(start+r.opts.DeleteBatchSize, len(candidates))
+		if err := r.checkHold(ctx, state.Partition); err != nil {
+			if firstFailed < 0 {
+				return candidates[start].beforeKey, err
+			}
+			return candidates[firstFailed].beforeKey, errors.Join(firstErr, err)
+		}
 		errs := r.deleteWave(ctx, state, candidates[start:end])
 		for i, err := range errs {
 			candidate := candidates[start+i]
@@ -65,6 +73,25 @@ func (r *Reclaimer) executeDeletes(ctx context.Context, state *stateFile, candid
 	)
 }
 
+// checkHold re-reads the legal holds before a delete batch, so a hold placed
+// after the pass loaded its snapshot stops the pass within one batch. A
+// catalog that is not a HoldAwareCatalog has no holds to read; its passes
+// delete only what its retention released, and purges are refused before
+// they delete anything.
+func (r *Reclaimer) checkHold(ctx context.Context, partition uint32) error {
+	if r.holds == nil {
+		return nil
+	}
+	holds, err := r.holds.LoadLegalHolds(ctx, partition)
+	if err != nil {
+		return fmt.Errorf("lifecycle: load legal holds: %w", err)
+	}
+	if holds.Active() {
+		return fmt.Errorf("%w: partition=%d", catalog.ErrLegalHold, partition)
+	}
+	return nil
+}
+
 func (r *Reclaimer) deleteWave(ctx context.Context, state *stateFile, candidates []deleteCandidate) []error {
 	keys := make([]string, len(candidates))
 	for i := range candidates {
@@ -0,0 +1,124 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+)
+
+// purgeDeleted removes every object of a tombstoned partition except its
+// head. Like the retention floor, the tombstone must stay observed for
+// DeleteDelay first, so a writer fenced by the tombstone has finished any
+// in-flight upload. The head is then marked purged and stays as a terminal
+// tombstone, so the partition cannot be recreated from LSN 0; an interrupted
+// purge resumes from the tombstone. The lifecycle state goes last.
+func (r *Reclaimer) purgeDeleted(ctx context.Context, state *stateFile, token *string, snapshot catalogblob.MaintenanceSnapshot, now time.Time, budget *runBudget) (bool, error) {
+	if r.holds == nil {
+		return false, fmt.Errorf("%w: purge partition=%d", ErrHoldsUnsupported, state.Partition)
+	}
+	statePath := catalogblob.GCStatePath(r.opts.CatalogPrefix, r.opts.StreamID, state.Partition)
+	if snapshot.Purged {
+		// Only the terminal tombstone is left, and the lease state this pass
+		// acquired.
+		if r.opts.DryRun {
+			return false, nil
+		}
+		_, err := r.executeDeletes(ctx, state, []deleteCandidate{{key: statePath}}, budget)
+		return err == nil, err
+	}
+	if state.DeleteObservedMS == 0 {
+		if r.opts.DryRun {
+			return false, nil
+		}
+		state.DeleteObservedMS = now.UnixMilli()
+		return false, r.saveState(ctx, state, token)
+	}
+	if now.Before(time.UnixMilli(state.DeleteObservedMS).Add(r.opts.DeleteDelay)) {
+		return false, nil
+	}
+
+	headPath := catalogblob.HeadPath(r.opts.CatalogPrefix, r.opts.StreamID, state.Partition)
+	for _, prefix := range []string{
+		r.layout.SegmentPrefix(r.opts.StreamID, state.Partition),
+		r.layout.PartitionStagingPrefix(r.opts.StreamID, state.Partition),
+		catalogblob.PartitionPrefix(r.opts.CatalogPrefix, r.opts.StreamID, state.Partition),
+	} {
+		empty, err := r.purgePrefix(ctx, state, prefix, headPath, statePath, budget)
+		if err != nil || !empty {
+			return false, err
+		}
+	}
+	if r.opts.DryRun {
+		return false, nil
+	}
+
+	snapshot, err := r.catalog.LoadMaintenanceSnapshot(ctx, state.Partition)
+	if err != nil {
+		return false, err
+	}
+	if err := r.validateSnapshot(snapshot, state.Partition); err != nil {
+		return false, err
+	}
+	if !snapshot.Deleted {
+		return false, fmt.Errorf("lifecycle: partition=%d tombstone disappeared during purge", state.Partition)
+	}
+	if err := r.holds.MarkPartitionPurged(ctx, state.Partition); err != nil {
+		return false, err
+	}
+	if _, err := r.executeDeletes(ctx, state, []deleteCandidate{{key: statePath}}, budget); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// purgePrefix deletes every object below prefix except the head and state
+// keys. Deleted objects disappear from later listings, so a purge needs no
+// checkpoint and the next pass restarts at the prefix. It reports whether the
+// listing completed.
+func (r *Reclaimer) purgePrefix(ctx context.Context, state *stateFile, prefix, headPath, statePath string, budget *runBudget) (bool, error) {
+	afterKey := prefix
+	for budget.available() {
+		limit := budget.listLimit()
+		if limit == 0 {
+			return false, nil
+		}
+		page, err := r.backend.List(ctx, ListOptions{Prefix: prefix, AfterKey: afterKey, Limit: limit})
+		if err != nil {
+			return false, err
+		}
+		if err := validateObjectPage(page, afterKey); err != nil {
+			return false, err
+		}
+		budget.recordScan(len(page.Objects))
+
+		candidates := make([]deleteCandidate, 0, len(page.Objects))
+		var scheduledBytes uint64
+		budgetStopped := false
+		for _, object := range page.Objects {
+			if object.Key == headPath || object.Key == statePath {
+				continue
+			}
+			budget.recordCandidate()
+			size := objectSize(object)
+			if !r.opts.DryRun && !budget.canScheduleDelete(size, uint64(len(candidates)), scheduledBytes) {
+				budgetStopped = true
+				break
+			}
+			candidates = append(candidates, deleteCandidate{key: object.Key, size: size})
+			scheduledBytes += size
+		}
+		if _, err := r.executeDeletes(ctx, state, candidates, budget); err != nil {
+			return false, err
+		}
+		if budgetStopped {
+			return false, nil
+		}
+		if !page.HasMore {
+			return true, nil
+		}
+		afterKey = page.NextAfterKey
+	}
+	return false, nil
+}
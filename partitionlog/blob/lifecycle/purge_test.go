@@ -0,0 +1,274 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	segmentsink "github.com/ankur-anand/unijord/partitionlog/blob/sink"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	plwriter "github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+func TestReclaimerPurgesDeletedPartitionAfterDelay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	cat := newPurgeTestCatalog(t, backend)
+	r := newTestReclaimer(t, backend, cat, layout, clock, Options{MaxDeletesPerRun: 3})
+
+	keys := seedPurgePartition(t, backend, cat, layout, 7)
+	kept := seedPurgePartition(t, backend, cat, layout, 8)
+	if _, deleted, err := cat.DeletePartition(ctx, 7); err != nil || !deleted {
+		t.Fatalf("DeletePartition() = %v, %v", deleted, err)
+	}
+
+	first, err := r.RunPartition(ctx, 7)
+	if err != nil {
+		t.Fatalf("RunPartition(observe) error = %v", err)
+	}
+	if first.Purged || !first.HasMore || first.DeletedObjects != 0 {
+		t.Fatalf("first result = %+v, want delayed purge", first)
+	}
+	assertExists(t, backend, keys...)
+
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+	passes := 0
+	for {
+		passes++
+		if passes > 10 {
+			t.Fatal("purge did not finish in 10 passes")
+		}
+		result, err := r.RunPartition(ctx, 7)
+		if err != nil {
+			t.Fatalf("RunPartition(purge pass %d) error = %v", passes, err)
+		}
+		if result.Purged {
+			break
+		}
+		if !result.HasMore {
+			t.Fatalf("purge pass %d = %+v, want more work", passes, result)
+		}
+	}
+	if passes < 2 {
+		t.Fatalf("purge finished in %d pass, want delete budget to split it", passes)
+	}
+	assertMissing(t, backend, keys...)
+	assertMissing(t, backend, catalogblob.GCStatePath("root/catalog", testStreamID, 7))
+	assertExists(t, backend, kept...)
+
+	// The head stays as a terminal tombstone, so the partition cannot be
+	// recreated from LSN 0.
+	assertExists(t, backend, catalogblob.HeadPath("root/catalog", testStreamID, 7))
+	if _, err := cat.LoadPartition(ctx, 7); !errors.Is(err, catalog.ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(purged) error = %v, want %v", err, catalog.ErrPartitionDeleted)
+	}
+	if _, err := cat.OpenWriter(ctx, 7, [16]byte{9}); !errors.Is(err, catalog.ErrPartitionDeleted) {
+		t.Fatalf("OpenWriter(purged) error = %v, want %v", err, catalog.ErrPartitionDeleted)
+	}
+	again, err := r.RunPartition(ctx, 7)
+	if err != nil || !again.Purged || again.HasMore {
+		t.Fatalf("RunPartition(purged) = %+v, %v; want a finished purge", again, err)
+	}
+	assertMissing(t, backend, catalogblob.GCStatePath("root/catalog", testStreamID, 7))
+}
+
+func TestReclaimerDeletesNothingUnderLegalHold(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	cat := newPurgeTestCatalog(t, backend)
+	r := newTestReclaimer(t, backend, cat, layout, clock, Options{})
+
+	keys := seedPurgePartition(t, backend, cat, layout, 7)
+	if _, deleted, err := cat.DeletePartition(ctx, 7); err != nil || !deleted {
+		t.Fatalf("DeletePartition() = %v, %v", deleted, err)
+	}
+	if _, err := r.RunPartition(ctx, 7); err != nil {
+		t.Fatalf("RunPartition(observe) error = %v", err)
+	}
+	// A hold placed after the delete still stops the purge.
+	if _, err := cat.SetPartitionHold(ctx, 7, catalog.LegalHold{Active: true, Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold() error = %v", err)
+	}
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+
+	held, err := r.RunPartition(ctx, 7)
+	if err != nil {
+		t.Fatalf("RunPartition(held) error = %v", err)
+	}
+	if !held.Held || held.HeldObjects == 0 || held.DeletedObjects != 0 || held.Purged || held.HasMore {
+		t.Fatalf("held result = %+v, want reported candidates and no more work", held)
+	}
+	assertExists(t, backend, keys...)
+	var stream StreamResult
+	req := StreamRequest{}
+	for {
+		page, err := r.RunStream(ctx, req)
+		if err != nil {
+			t.Fatalf("RunStream(held) error = %v", err)
+		}
+		stream.Held += page.Held
+		stream.HeldObjects += page.HeldObjects
+		if !page.HasMore {
+			break
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+	if stream.Held != 1 || stream.HeldObjects != held.HeldObjects {
+		t.Fatalf("RunStream(held) = %+v, want one held partition", stream)
+	}
+
+	if _, err := cat.SetPartitionHold(ctx, 7, catalog.LegalHold{Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold(clear) error = %v", err)
+	}
+	released, err := r.RunPartition(ctx, 7)
+	if err != nil {
+		t.Fatalf("RunPartition(released) error = %v", err)
+	}
+	if released.Held || !released.Purged {
+		t.Fatalf("released result = %+v, want purge", released)
+	}
+	assertMissing(t, backend, keys...)
+}
+
+func TestReclaimerRefusesPurgeAndShredWithoutHoldAwareCatalog(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	cat := newPurgeTestCatalog(t, backend)
+	// plain hides every method of the blob catalog but Catalog's.
+	plain := struct{ Catalog }{cat}
+	r := newTestReclaimer(t, backend, plain, layout, clock, Options{})
+
+	keys := seedPurgePartition(t, backend, cat, layout, 7)
+	if _, deleted, err := cat.DeletePartition(ctx, 7); err != nil || !deleted {
+		t.Fatalf("DeletePartition() = %v, %v", deleted, err)
+	}
+	for range 2 {
+		if _, err := r.RunPartition(ctx, 7); !errors.Is(err, ErrHoldsUnsupported) {
+			t.Fatalf("RunPartition(deleted) error = %v, want %v", err, ErrHoldsUnsupported)
+		}
+		clock.Advance(DefaultDeleteDelay + time.Millisecond)
+	}
+	assertExists(t, backend, keys...)
+
+	shredder := struct {
+		Catalog
+		Shredder
+	}{cat, cat}
+	_, err := newReclaimer(backend, shredder, layout, Options{StreamID: testStreamID, CatalogPrefix: "root/catalog", Keys: destroyedKeys{}}, clock.Now)
+	if !errors.Is(err, ErrHoldsUnsupported) {
+		t.Fatalf("newReclaimer(shred without holds) error = %v, want %v", err, ErrHoldsUnsupported)
+	}
+}
+
+func TestReclaimerRunStreamVisitsEveryPartition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	cat := newPurgeTestCatalog(t, backend)
+	r := newTestReclaimer(t, backend, cat, layout, clock, Options{})
+
+	var deletedKeys []string
+	for _, partition := range []uint32{1, 2, 3} {
+		deletedKeys = append(deletedKeys, seedPurgePartition(t, backend, cat, layout, partition)...)
+		if _, _, err := cat.DeletePartition(ctx, partition); err != nil {
+			t.Fatalf("DeletePartition(%d) error = %v", partition, err)
+		}
+	}
+	live := seedPurgePartition(t, backend, cat, layout, 4)
+
+	runStream := func() StreamResult {
+		t.Helper()
+		var total StreamResult
+		req := StreamRequest{Limit: 1}
+		for {
+			page, err := r.RunStream(ctx, req)
+			if err != nil {
+				t.Fatalf("RunStream(after=%q) error = %v", req.AfterKey, err)
+			}
+			total.Partitions += page.Partitions
+			total.Pending += page.Pending
+			total.Purged += page.Purged
+			if !page.HasMore {
+				return total
+			}
+			req.AfterKey = page.NextAfterKey
+		}
+	}
+	if observed := runStream(); observed.Partitions != 4 || observed.Pending != 3 || observed.Purged != 0 {
+		t.Fatalf("observe stream = %+v", observed)
+	}
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+	if purged := runStream(); purged.Partitions != 4 || purged.Purged != 3 {
+		t.Fatalf("purge stream = %+v", purged)
+	}
+	assertMissing(t, backend, deletedKeys...)
+	assertExists(t, backend, live...)
+	if after := runStream(); after.Partitions != 1 || after.Purged != 0 {
+		t.Fatalf("stream after purge = %+v, want only the live partition", after)
+	}
+
+	unsupported := newTestReclaimer(t, backend, &fakeCatalog{}, layout, clock, Options{})
+	if _, err := unsupported.RunStream(ctx, StreamRequest{}); !errors.Is(err, ErrPartitionListUnsupported) {
+		t.Fatalf("RunStream(fake catalog) error = %v, want %v", err, ErrPartitionListUnsupported)
+	}
+}
+
+func newPurgeTestCatalog(t testing.TB, backend catalogblob.Backend) *catalogblob.Catalog {
+	t.Helper()
+	cat, err := catalogblob.New(backend, catalogblob.Options{Prefix: "root/catalog", StreamID: testStreamID})
+	if err != nil {
+		t.Fatalf("catalogblob.New() error = %v", err)
+	}
+	return cat
+}
+
+// seedPurgePartition creates a partition head plus segment, staging, page and
+// retention objects, and returns every key a purge must remove. The head
+// stays as the terminal tombstone.
+func seedPurgePartition(t testing.TB, backend *blobmemory.Store, cat *catalogblob.Catalog, layout segmentsink.Layout, partition uint32) []string {
+	t.Helper()
+	ctx := context.Background()
+	if _, _, err := cat.InitializePartition(ctx, partition, 0); err != nil {
+		t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+	}
+	if _, err := cat.RequestRetention(ctx, partition, catalog.RetentionRequest{
+		Version: catalog.RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 10, CreatedUnixMS: 1,
+	}); err != nil {
+		t.Fatalf("RequestRetention(%d) error = %v", partition, err)
+	}
+	pageID := "0123456789abcdef0123456789abcdef"
+	keys := []string{
+		layout.SegmentKey(plwriter.SegmentInfo{StreamID: testStreamID, Partition: partition, BaseLSN: 0, WriterEpoch: 1, SegmentUUID: [16]byte{1}}),
+		layout.SegmentKey(plwriter.SegmentInfo{StreamID: testStreamID, Partition: partition, BaseLSN: 100, WriterEpoch: 1, SegmentUUID: [16]byte{2}}),
+		layout.StagingPrefix(plwriter.SegmentInfo{StreamID: testStreamID, Partition: partition, BaseLSN: 200, WriterEpoch: 1, SegmentUUID: [16]byte{3}}) + "/part-000001",
+		catalogblob.LeafPagePath("root/catalog", testStreamID, partition, 0, 99, 1, pageID),
+		catalogblob.IndexPagePath("root/catalog", testStreamID, partition, 1, 0, 99, 2, pageID),
+	}
+	putKeys(t, backend, keys)
+	return append(keys, catalogblob.RetentionRequestPath("root/catalog", testStreamID, partition))
+}
+
+// destroyedKeys reports every partition key destroyed.
+type destroyedKeys struct{}
+
+func (destroyedKeys) PartitionKeyDestroyed(context.Context, uint32) (bool, error) {
+	return true, nil
+}
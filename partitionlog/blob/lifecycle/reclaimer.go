@@ -8,7 +8,12 @@ import (
 	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
 )
 
-// RunPartition performs bounded lifecycle work for one known partition.
+// RunPartition performs bounded lifecycle work for one known partition: it
+// deletes retired segments and pages, abandoned staging objects, and segment
+// objects replaced by redaction once DeleteDelay has passed. A deleted
+// partition is purged once its tombstone has aged past DeleteDelay. A
+// partition under legal hold gets a dry-run pass that reports what the hold
+// keeps in Result.HeldObjects.
 func (r *Reclaimer) RunPartition(ctx context.Context, partition uint32) (result Result, err error) {
 	if err := ctx.Err(); err != nil {
 		return Result{}, err
@@ -22,7 +27,12 @@ func (r *Reclaimer) RunPartition(ctx context.Context, partition uint32) (result
 		return Result{}, err
 	}
 	acquiredState := state
+	purged := false
 	defer func() {
+		if purged {
+			// The lease object was deleted with the rest of the partition.
+			return
+		}
 		releaseState := &state
 		if r.opts.DryRun {
 			releaseState = &acquiredState
@@ -42,6 +52,37 @@ func (r *Reclaimer) RunPartition(ctx context.Context, partition uint32) (result
 	if err := r.validateSnapshot(snapshot, partition); err != nil {
 		return Result{}, err
 	}
+	if snapshot.Held && !r.opts.DryRun {
+		// The hold turns the rest of the pass into a dry run. The deferred
+		// release reads r too, so it keeps the acquired lease state.
+		r = r.heldCopy()
+		defer func() {
+			if err == nil {
+				markHeld(&result)
+			}
+		}()
+	}
+	if !snapshot.Deleted && r.opts.Keys != nil && !r.opts.DryRun {
+		shredded, err := r.finishShred(ctx, snapshot, partition)
+		if err != nil {
+			return Result{}, err
+		}
+		if shredded {
+			result.Shredded = true
+			result.HasMore = true
+			return result, nil
+		}
+	}
+	if snapshot.Deleted {
+		budget := runBudget{opts: r.opts, result: &result}
+		purged, err = r.purgeDeleted(ctx, &state, &token, snapshot, now, &budget)
+		if err != nil {
+			return Result{}, err
+		}
+		result.Purged = purged
+		result.HasMore = !purged
+		return result, nil
+	}
 	if r.observeHead(&state, snapshot, now) && !r.opts.DryRun {
 		if err := r.saveState(ctx, &state, &token); err != nil {
 			return Result{}, err
@@ -64,6 +105,11 @@ func (r *Reclaimer) RunPartition(ctx context.Context, partition uint32) (result
 			return Result{}, err
 		}
 	}
+	if budget.available() {
+		if err := r.reclaimReplaced(ctx, &state, &budget); err != nil {
+			return Result{}, err
+		}
+	}
 
 	result.SafeFloorLSN = state.SafeFloorLSN
 	result.ReclaimedThroughLSN = min(state.SegmentReclaimedThroughLSN, state.PageReclaimedThroughLSN)
@@ -72,6 +118,23 @@ func (r *Reclaimer) RunPartition(ctx context.Context, partition uint32) (result
 	return result, nil
 }
 
+// heldCopy returns a dry-run copy of r for the rest of a pass over a
+// partition under legal hold.
+func (r *Reclaimer) heldCopy() *Reclaimer {
+	held := *r
+	held.opts.DryRun = true
+	return &held
+}
+
+// markHeld reports a dry-run pass over a held partition. It reports no more
+// work so schedulers do not spin on a partition that cannot progress until
+// the hold is cleared.
+func markHeld(result *Result) {
+	result.Held = true
+	result.HeldObjects = result.CandidateObjects
+	result.HasMore = false
+}
+
 func (r *Reclaimer) validateSnapshot(snapshot catalogblob.MaintenanceSnapshot, partition uint32) error {
 	if snapshot.Head.StreamID != r.opts.StreamID {
 		return fmt.Errorf("lifecycle: head stream_id=%q want=%q", snapshot.Head.StreamID, r.opts.StreamID)
@@ -69,6 +69,33 @@ func TestReclaimerRetainsUntilDelayedFloorThenDeletesSegmentsPagesAndStaleStagin
 	assertExists(t, backend, segmentKeys[2], pageKeys[2], pageKeys[4], currentStaging)
 }
 
+func TestReclaimerStopsWhenHoldLandsAfterSnapshot(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	cat := &fakeCatalog{snapshot: maintenanceSnapshot(200, 300, 2, 1)}
+	r := newTestReclaimer(t, backend, cat, layout, clock, Options{})
+	segmentKeys := putSegments(t, backend, layout, 0, 100, 200)
+
+	if _, err := r.RunPartition(ctx, 7); err != nil {
+		t.Fatalf("RunPartition(observe) error = %v", err)
+	}
+	// The snapshot still reports no hold; the hold objects already do.
+	cat.mu.Lock()
+	cat.holds = catalog.LegalHolds{Partition: catalog.LegalHold{Active: true, Reason: "case-7"}}
+	cat.mu.Unlock()
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+
+	result, err := r.RunPartition(ctx, 7)
+	if !errors.Is(err, catalog.ErrLegalHold) {
+		t.Fatalf("RunPartition(held) = %+v, %v; want %v", result, err, catalog.ErrLegalHold)
+	}
+	assertExists(t, backend, segmentKeys...)
+}
+
 func TestReclaimerDoesNotStrandEligiblePageBehindSpanningRange(t *testing.T) {
 	t.Parallel()
 
@@ -332,6 +359,93 @@ func TestScrubPartitionDeletesOnlyProvenSegmentOrphansAndQuarantinedPages(t *tes
 	assertExists(t, backend, committed, inFlight, reachablePage, currentPage)
 }
 
+func TestScrubPartitionKeepsRedactedSegmentForDeleteDelay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC().Add(48 * time.Hour))
+	catalog := &fakeCatalog{
+		snapshot: maintenanceSnapshot(0, 200, 2, 0),
+		segments: make(map[uint64]pmeta.SegmentRef),
+	}
+	r := newTestReclaimer(t, backend, catalog, layout, clock, Options{})
+
+	replaced := putSegmentInfo(t, backend, layout, plwriter.SegmentInfo{
+		StreamID: testStreamID, Partition: 7, BaseLSN: 0, WriterEpoch: 1, SegmentUUID: [16]byte{1},
+	})
+	redacted := putSegmentInfo(t, backend, layout, plwriter.SegmentInfo{
+		StreamID: testStreamID, Partition: 7, BaseLSN: 0, WriterEpoch: 1, SegmentUUID: [16]byte{2},
+	})
+	catalog.segments[0] = pmeta.SegmentRef{URI: redacted, BaseLSN: 0, LastLSN: 99, RedactedUnixMS: clock.Now().UnixMilli()}
+
+	first, err := r.ScrubPartition(ctx, 7)
+	if err != nil {
+		t.Fatalf("ScrubPartition(within delay) error = %v", err)
+	}
+	if first.DeletedObjects != 0 {
+		t.Fatalf("first result = %+v, want replaced object kept", first)
+	}
+	assertExists(t, backend, replaced, redacted)
+
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+	second, err := r.ScrubPartition(ctx, 7)
+	if err != nil {
+		t.Fatalf("ScrubPartition(after delay) error = %v", err)
+	}
+	if second.DeletedObjects != 1 {
+		t.Fatalf("second result = %+v, want replaced object deleted", second)
+	}
+	assertMissing(t, backend, replaced)
+	assertExists(t, backend, redacted)
+}
+
+func TestReclaimerDeletesReplacedSegmentAfterDeleteDelay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := blobmemory.New()
+	layout := segmentsink.NewLayout("root")
+	clock := newFakeClock(time.Now().UTC())
+	catalog := &fakeCatalog{
+		snapshot: maintenanceSnapshot(0, 200, 2, 0),
+		segments: make(map[uint64]pmeta.SegmentRef),
+	}
+	r := newTestReclaimer(t, backend, catalog, layout, clock, Options{})
+
+	replaced := putSegmentInfo(t, backend, layout, plwriter.SegmentInfo{
+		StreamID: testStreamID, Partition: 7, BaseLSN: 0, WriterEpoch: 1, SegmentUUID: [16]byte{1},
+	})
+	redacted := putSegmentInfo(t, backend, layout, plwriter.SegmentInfo{
+		StreamID: testStreamID, Partition: 7, BaseLSN: 0, WriterEpoch: 1, SegmentUUID: [16]byte{2},
+	})
+	uncommitted := putSegmentInfo(t, backend, layout, plwriter.SegmentInfo{
+		StreamID: testStreamID, Partition: 7, BaseLSN: 100, WriterEpoch: 1, SegmentUUID: [16]byte{3},
+	})
+	catalog.segments[0] = pmeta.SegmentRef{URI: redacted, BaseLSN: 0, LastLSN: 99}
+	catalog.segments[100] = pmeta.SegmentRef{URI: uncommitted, BaseLSN: 100, LastLSN: 199}
+	markers := []string{
+		putReplacedMarker(t, backend, 0, [16]byte{1}, replaced),
+		// The swap of this marker never committed, so its object is live.
+		putReplacedMarker(t, backend, 100, [16]byte{3}, uncommitted),
+	}
+
+	if _, err := r.RunPartition(ctx, 7); err != nil {
+		t.Fatalf("RunPartition(within delay) error = %v", err)
+	}
+	assertExists(t, backend, replaced, redacted, uncommitted)
+	assertExists(t, backend, markers...)
+
+	clock.Advance(DefaultDeleteDelay + time.Millisecond)
+	if _, err := r.RunPartition(ctx, 7); err != nil {
+		t.Fatalf("RunPartition(after delay) error = %v", err)
+	}
+	assertMissing(t, backend, replaced)
+	assertMissing(t, backend, markers...)
+	assertExists(t, backend, redacted, uncommitted)
+}
+
 func TestScrubPartitionDoesNotBypassDelayedRetentionFloor(t *testing.T) {
 	t.Parallel()
 
@@ -456,6 +570,18 @@ func stagingKey(layout segmentsink.Layout, base, epoch uint64, id byte) string {
 	return layout.StagingPrefix(info) + "/part-000001"
 }
 
+func putReplacedMarker(t testing.TB, backend interface {
+	Put(context.Context, string, []byte) (blobstore.Object, error)
+}, baseLSN uint64, segmentUUID [16]byte, uri string) string {
+	t.Helper()
+	key := catalogblob.ReplacedSegmentPath("root/catalog", testStreamID, 7, baseLSN, segmentUUID)
+	body := fmt.Sprintf(`{"version":1,"stream_id":%q,"partition":7,"base_lsn":%d,"uri":%q,"size_bytes":10}`, testStreamID, baseLSN, uri)
+	if _, err := backend.Put(context.Background(), key, []byte(body)); err != nil {
+		t.Fatalf("Put(%q) error = %v", key, err)
+	}
+	return key
+}
+
 func putKeys(t testing.TB, backend interface {
 	Put(context.Context, string, []byte) (blobstore.Object, error)
 }, keys []string) {
@@ -515,6 +641,20 @@ type fakeCatalog struct {
 	onLoad         func(load int, snapshot *catalogblob.MaintenanceSnapshot)
 	segments       map[uint64]pmeta.SegmentRef
 	reachablePages map[string]bool
+	holds          catalog.LegalHolds
+}
+
+func (c *fakeCatalog) LoadLegalHolds(context.Context, uint32) (catalog.LegalHolds, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.holds, nil
+}
+
+func (c *fakeCatalog) MarkPartitionPurged(context.Context, uint32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot.Purged = true
+	return nil
 }
 
 func (c *fakeCatalog) LoadMaintenanceSnapshot(context.Context, uint32) (catalogblob.MaintenanceSnapshot, error) {
@@ -0,0 +1,118 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+)
+
+// reclaimReplaced deletes the segment objects that redaction swaps replaced,
+// once their marker is DeleteDelay old, so readers holding the old reference
+// can finish. A marker whose object the catalog still references belongs to a
+// swap that never committed, so only the marker goes. Each object is deleted
+// before its marker, and deleted markers disappear from later listings, so an
+// interrupted pass needs no checkpoint.
+func (r *Reclaimer) reclaimReplaced(ctx context.Context, state *stateFile, budget *runBudget) error {
+	prefix := catalogblob.ReplacedSegmentPrefix(r.opts.CatalogPrefix, r.opts.StreamID, state.Partition)
+	afterKey := prefix
+	now := r.now().UTC()
+	for budget.available() {
+		limit := budget.listLimit()
+		if limit == 0 {
+			return nil
+		}
+		page, err := r.backend.List(ctx, ListOptions{Prefix: prefix, AfterKey: afterKey, Limit: limit})
+		if err != nil {
+			return err
+		}
+		if err := validateObjectPage(page, afterKey); err != nil {
+			return err
+		}
+		budget.recordScan(len(page.Objects))
+
+		var segments, markers []deleteCandidate
+		var scheduledBytes uint64
+		budgetStopped := false
+		for _, object := range page.Objects {
+			if !oldEnough(object.CreatedAt, now, r.opts.DeleteDelay) {
+				continue
+			}
+			replaced, ok, err := r.loadReplaced(ctx, state.Partition, object.Key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				budget.invalid()
+				continue
+			}
+			referenced, err := r.segmentReferenced(ctx, state.Partition, replaced)
+			if err != nil {
+				return err
+			}
+			budget.recordCandidate()
+			size := replaced.SizeBytes + objectSize(object)
+			if !r.opts.DryRun && !budget.canScheduleDelete(size, uint64(len(segments)+len(markers)), scheduledBytes) {
+				budgetStopped = true
+				break
+			}
+			if !referenced {
+				segments = append(segments, deleteCandidate{key: replaced.URI, size: replaced.SizeBytes})
+			}
+			markers = append(markers, deleteCandidate{key: object.Key, size: objectSize(object)})
+			scheduledBytes += size
+		}
+		if _, err := r.executeDeletes(ctx, state, segments, budget); err != nil {
+			return err
+		}
+		if _, err := r.executeDeletes(ctx, state, markers, budget); err != nil {
+			return err
+		}
+		if budgetStopped || !page.HasMore {
+			return nil
+		}
+		afterKey = page.NextAfterKey
+	}
+	return nil
+}
+
+// loadReplaced reads one marker. It reports false for a marker that is gone
+// or does not decode.
+func (r *Reclaimer) loadReplaced(ctx context.Context, partition uint32, key string) (catalogblob.ReplacedSegment, bool, error) {
+	obj, err := r.backend.Get(ctx, key)
+	if errors.Is(err, blobstore.ErrObjectNotFound) {
+		return catalogblob.ReplacedSegment{}, false, nil
+	}
+	if err != nil {
+		return catalogblob.ReplacedSegment{}, false, err
+	}
+	replaced, err := catalogblob.DecodeReplacedSegment(obj.Body, r.opts.StreamID, partition)
+	if err != nil {
+		return catalogblob.ReplacedSegment{}, false, nil
+	}
+	return replaced, true, nil
+}
+
+// segmentReferenced reports whether the catalog still references the object
+// of replaced.
+func (r *Reclaimer) segmentReferenced(ctx context.Context, partition uint32, replaced catalogblob.ReplacedSegment) (bool, error) {
+	snapshot, page, err := r.catalog.ListMaintenanceSegments(ctx, catalog.ListSegmentsRequest{
+		Partition: partition,
+		FromLSN:   replaced.BaseLSN,
+		Limit:     1,
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := r.validateSnapshot(snapshot, partition); err != nil {
+		return false, err
+	}
+	for _, segment := range page.Segments {
+		if segment.URI == replaced.URI {
+			return true, nil
+		}
+	}
+	return false, nil
+}
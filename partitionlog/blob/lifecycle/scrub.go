@@ -19,6 +19,7 @@ type segmentScrubObject struct {
 // ScrubPartition performs bounded orphan discovery and quarantine work for
 // one partition. It is intentionally separate from normal retention GC
 // because reachability checks are more expensive than ordered range deletion.
+// Like RunPartition, it only reports candidates while a legal hold is active.
 func (r *Reclaimer) ScrubPartition(ctx context.Context, partition uint32) (result Result, err error) {
 	if err := ctx.Err(); err != nil {
 		return Result{}, err
@@ -52,6 +53,18 @@ func (r *Reclaimer) ScrubPartition(ctx context.Context, partition uint32) (resul
 	if err := r.validateSnapshot(snapshot, partition); err != nil {
 		return Result{}, err
 	}
+	if snapshot.Held && !r.opts.DryRun {
+		r = r.heldCopy()
+		defer func() {
+			if err == nil {
+				markHeld(&result)
+			}
+		}()
+	}
+	if snapshot.Deleted {
+		// RunPartition purges tombstoned partitions wholesale.
+		return result, nil
+	}
 	if snapshot.MaxIndexLevel > state.MaxPageLevel && !r.opts.DryRun {
 		state.MaxPageLevel = snapshot.MaxIndexLevel
 		if err := r.saveState(ctx, &state, &token); err != nil {
@@ -218,9 +231,10 @@ func (r *Reclaimer) scrubSegmentOrphans(ctx context.Context, snapshot catalogblo
 
 		fresh := snapshot
 		referenced := map[string]struct{}{}
+		redacted := map[uint64]int64{}
 		if len(parsedObjects) > 0 {
 			var err error
-			fresh, referenced, err = r.referencedSegmentsForObjectPage(ctx, state.Partition, parsedObjects)
+			fresh, referenced, redacted, err = r.referencedSegmentsForObjectPage(ctx, state.Partition, parsedObjects)
 			if err != nil {
 				return false, err
 			}
@@ -241,6 +255,13 @@ func (r *Reclaimer) scrubSegmentOrphans(ctx context.Context, snapshot catalogblo
 				lastProcessed = object.Key
 				continue
 			}
+			// A redaction swap replaces a segment at the same base LSN. Keep
+			// the replaced objects for the delete delay after the swap so
+			// readers holding the old reference can finish.
+			if redactedMS, ok := redacted[parsed.BaseLSN]; ok && !oldEnough(time.UnixMilli(redactedMS), now, r.opts.DeleteDelay) {
+				lastProcessed = object.Key
+				continue
+			}
 			budget.recordCandidate()
 			size := objectSize(object)
 			if !r.opts.DryRun && !budget.canScheduleDelete(size, uint64(len(candidates)), scheduledBytes) {
@@ -287,7 +308,10 @@ func (r *Reclaimer) scrubSegmentOrphans(ctx context.Context, snapshot catalogblo
 	return true, nil
 }
 
-func (r *Reclaimer) referencedSegmentsForObjectPage(ctx context.Context, partition uint32, objects []segmentScrubObject) (catalogblob.MaintenanceSnapshot, map[string]struct{}, error) {
+// referencedSegmentsForObjectPage returns the catalog snapshot, the object
+// keys it references, and the redaction time of referenced segments that
+// replaced an earlier object at the same base LSN.
+func (r *Reclaimer) referencedSegmentsForObjectPage(ctx context.Context, partition uint32, objects []segmentScrubObject) (catalogblob.MaintenanceSnapshot, map[string]struct{}, map[uint64]int64, error) {
 	fromLSN := objects[0].parsed.BaseLSN
 	throughLSN := fromLSN
 	for _, object := range objects[1:] {
@@ -303,6 +327,7 @@ func (r *Reclaimer) referencedSegmentsForObjectPage(ctx context.Context, partiti
 		physicalKeys[object.object.Key] = struct{}{}
 	}
 	referenced := make(map[string]struct{}, len(objects))
+	redacted := make(map[uint64]int64)
 	var observed catalogblob.MaintenanceSnapshot
 	hasObserved := false
 	for next := fromLSN; ; {
@@ -312,16 +337,16 @@ func (r *Reclaimer) referencedSegmentsForObjectPage(ctx context.Context, partiti
 			Limit:     catalog.MaxSegmentPageLimit,
 		})
 		if err != nil {
-			return catalogblob.MaintenanceSnapshot{}, nil, err
+			return catalogblob.MaintenanceSnapshot{}, nil, nil, err
 		}
 		if err := r.validateSnapshot(snapshot, partition); err != nil {
-			return catalogblob.MaintenanceSnapshot{}, nil, err
+			return catalogblob.MaintenanceSnapshot{}, nil, nil, err
 		}
 		if !hasObserved {
 			observed = snapshot
 			hasObserved = true
 		} else if snapshot.Generation != observed.Generation {
-			return catalogblob.MaintenanceSnapshot{}, nil, fmt.Errorf("lifecycle: catalog changed during segment scrub generation=%d current=%d", observed.Generation, snapshot.Generation)
+			return catalogblob.MaintenanceSnapshot{}, nil, nil, fmt.Errorf("lifecycle: catalog changed during segment scrub generation=%d current=%d", observed.Generation, snapshot.Generation)
 		}
 		for _, segment := range page.Segments {
 			if segment.BaseLSN > throughLSN {
@@ -330,12 +355,15 @@ func (r *Reclaimer) referencedSegmentsForObjectPage(ctx context.Context, partiti
 			if _, ok := physicalKeys[segment.URI]; ok {
 				referenced[segment.URI] = struct{}{}
 			}
+			if segment.RedactedUnixMS > 0 {
+				redacted[segment.BaseLSN] = segment.RedactedUnixMS
+			}
 		}
 		if !page.HasMore || page.NextLSN > throughLSN {
-			return observed, referenced, nil
+			return observed, referenced, redacted, nil
 		}
 		if page.NextLSN <= next {
-			return catalogblob.MaintenanceSnapshot{}, nil, fmt.Errorf("lifecycle: non-advancing catalog segment page next_lsn=%d from_lsn=%d", page.NextLSN, next)
+			return catalogblob.MaintenanceSnapshot{}, nil, nil, fmt.Errorf("lifecycle: non-advancing catalog segment page next_lsn=%d from_lsn=%d", page.NextLSN, next)
 		}
 		next = page.NextLSN
 	}
@@ -0,0 +1,31 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+)
+
+// finishShred tombstones a live partition whose keys were all destroyed, as
+// partitionlog.Log.ShredPartition would have. The key state is read after the
+// snapshot and the tombstone is pinned to the snapshot generation, so a writer
+// that creates a new key and commits under it keeps its partition: either the
+// key check sees the live key, or the pinned tombstone sees the moved head.
+func (r *Reclaimer) finishShred(ctx context.Context, snapshot catalogblob.MaintenanceSnapshot, partition uint32) (bool, error) {
+	destroyed, err := r.opts.Keys.PartitionKeyDestroyed(ctx, partition)
+	if err != nil {
+		return false, fmt.Errorf("lifecycle: partition=%d key state: %w", partition, err)
+	}
+	if !destroyed {
+		return false, nil
+	}
+	_, shredded, err := r.shredder.ShredPartitionAt(ctx, partition, snapshot.Generation)
+	if errors.Is(err, catalog.ErrConflict) {
+		// The head moved since the snapshot. The next pass decides again.
+		return false, nil
+	}
+	return shredded, err
+}
@@ -47,6 +47,10 @@ type stateFile struct {
 	OrphanPageAfterKey    string             `json:"orphan_page_after_key,omitempty"`
 	PageQuarantine        []quarantineObject `json:"page_quarantine,omitempty"`
 
+	// DeleteObservedMS is when this reclaimer first observed the partition
+	// tombstone. The purge waits DeleteDelay from this local observation.
+	DeleteObservedMS int64 `json:"delete_observed_unix_ms,omitempty"`
+
 	UpdatedMS int64 `json:"updated_unix_ms"`
 }
 
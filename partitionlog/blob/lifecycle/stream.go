@@ -0,0 +1,69 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+// StreamRequest selects one page of a stream-wide lifecycle pass. AfterKey is
+// the NextAfterKey of the previous StreamResult; empty starts the stream.
+type StreamRequest struct {
+	AfterKey string
+	Limit    int
+}
+
+// StreamResult aggregates the partition passes of one stream page.
+type StreamResult struct {
+	// Partitions is the number of partitions passed in this page.
+	Partitions int
+	// Pending is the number of those partitions that reported HasMore.
+	Pending int
+	// Purged is the number of deleted partitions that were fully reclaimed.
+	Purged int
+	// Held is the number of partitions a legal hold kept from deleting.
+	Held           int
+	HeldObjects    int
+	DeletedObjects int
+	DeletedBytes   uint64
+	NextAfterKey   string
+	HasMore        bool
+}
+
+// RunStream runs RunPartition for one page of the stream's partitions,
+// including deleted partitions that still await their purge. Walk the stream
+// by passing NextAfterKey until HasMore is false. A failed partition pass
+// stops the page; retrying the same request repeats only idempotent work.
+func (r *Reclaimer) RunStream(ctx context.Context, req StreamRequest) (StreamResult, error) {
+	lister, ok := r.catalog.(catalog.PartitionLister)
+	if !ok {
+		return StreamResult{}, ErrPartitionListUnsupported
+	}
+	page, err := lister.ListPartitions(ctx, catalog.ListPartitionsRequest{AfterKey: req.AfterKey, Limit: req.Limit, IncludeDeleted: true})
+	if err != nil {
+		return StreamResult{}, err
+	}
+
+	result := StreamResult{NextAfterKey: page.NextAfterKey, HasMore: page.HasMore}
+	for _, info := range page.Partitions {
+		pass, err := r.RunPartition(ctx, info.Partition)
+		if err != nil {
+			return StreamResult{}, fmt.Errorf("lifecycle: partition=%d: %w", info.Partition, err)
+		}
+		result.Partitions++
+		result.DeletedObjects += pass.DeletedObjects
+		result.DeletedBytes += pass.DeletedBytes
+		if pass.Purged {
+			result.Purged++
+		}
+		if pass.Held {
+			result.Held++
+			result.HeldObjects += pass.HeldObjects
+		}
+		if pass.HasMore {
+			result.Pending++
+		}
+	}
+	return result, nil
+}
@@ -21,6 +21,12 @@ var (
 	ErrLeaseHeld      = errors.New("lifecycle: lease held")
 	ErrLeaseLost      = errors.New("lifecycle: lease lost")
 	ErrCorruptState   = errors.New("lifecycle: corrupt state")
+	// ErrPartitionListUnsupported reports a catalog that cannot enumerate the
+	// partitions of a stream for RunStream.
+	ErrPartitionListUnsupported = errors.New("lifecycle: partition listing unsupported")
+	// ErrHoldsUnsupported reports a purge or shred refused because the
+	// catalog is not a HoldAwareCatalog, so no hold could stop it.
+	ErrHoldsUnsupported = errors.New("lifecycle: legal holds unsupported")
 )
 
 const (
@@ -59,6 +65,32 @@ type Catalog interface {
 	ListMaintenancePages(ctx context.Context, req catalogblob.MaintenancePageRequest) (catalogblob.MaintenanceSnapshot, catalogblob.MaintenancePage, error)
 }
 
+// HoldAwareCatalog is a Catalog that reports legal holds and finishes purges.
+// The blob catalog implements it. LoadLegalHolds is re-read before every
+// delete batch. MarkPartitionPurged turns a tombstone into the terminal head
+// a finished purge leaves behind. Over a Catalog without it, a Reclaimer
+// still deletes what the catalog's retention released but refuses to purge
+// or shred partitions with ErrHoldsUnsupported.
+type HoldAwareCatalog interface {
+	Catalog
+	LoadLegalHolds(ctx context.Context, partition uint32) (catalog.LegalHolds, error)
+	MarkPartitionPurged(ctx context.Context, partition uint32) error
+}
+
+var _ HoldAwareCatalog = (*catalogblob.Catalog)(nil)
+
+// KeyState reports partitions whose keys were all destroyed. keyring.Keyring
+// implements it.
+type KeyState interface {
+	PartitionKeyDestroyed(ctx context.Context, partition uint32) (bool, error)
+}
+
+// Shredder tombstones a shredded partition while its head is still at the
+// observed generation. The blob catalog implements it.
+type Shredder interface {
+	ShredPartitionAt(ctx context.Context, partition uint32, generation uint64) (pmeta.PartitionHead, bool, error)
+}
+
 // DeleteRateLimiter coordinates physical delete throughput across reclaimers.
 // The objects count is the number of keys in the provider request. Implementations
 // must be safe for concurrent use and return promptly when ctx is canceled.
@@ -97,6 +129,13 @@ type Options struct {
 	MaxQuarantine     int
 	CASAttempts       int
 	DryRun            bool
+	// Keys, when set, lets a pass finish a crypto-shred whose partition keys
+	// were destroyed without a tombstone: a live partition with no live key
+	// is tombstoned as shredded and purged after DeleteDelay like any other.
+	// The catalog must be a Shredder and a HoldAwareCatalog. A signed
+	// partition cannot be tombstoned here; shred it through
+	// partitionlog.Log.ShredPartition.
+	Keys KeyState
 }
 
 type Result struct {
@@ -109,15 +148,29 @@ type Result struct {
 	InvalidObjects      int
 	QuarantinedObjects  int
 	PendingQuarantine   int
-	HasMore             bool
+	// Purged reports that a deleted partition has no objects left, including
+	// its head and lifecycle state.
+	Purged bool
+	// Shredded reports that the pass tombstoned a partition whose keys were
+	// destroyed. Later passes purge it.
+	Shredded bool
+	// Held reports that a legal hold covered the partition. The pass ran as a
+	// dry run, so it deleted nothing and advanced no reclaim progress.
+	Held bool
+	// HeldObjects is the number of delete candidates the hold kept in place.
+	HeldObjects int
+	HasMore     bool
 }
 
 type Reclaimer struct {
 	backend Backend
 	catalog Catalog
-	layout  segmentsink.Layout
-	opts    Options
-	now     func() time.Time
+	// holds is catalog when it is a HoldAwareCatalog, or nil.
+	holds    HoldAwareCatalog
+	shredder Shredder
+	layout   segmentsink.Layout
+	opts     Options
+	now      func() time.Time
 }
 
 func New(backend Backend, catalog Catalog, layout segmentsink.Layout, opts Options) (*Reclaimer, error) {
@@ -209,7 +262,18 @@ func newReclaimer(backend Backend, catalog Catalog, layout segmentsink.Layout, o
 		}
 		opts.OwnerID = owner
 	}
-	return &Reclaimer{backend: backend, catalog: catalog, layout: layout, opts: opts, now: now}, nil
+	holds, _ := catalog.(HoldAwareCatalog)
+	var shredder Shredder
+	if opts.Keys != nil {
+		var ok bool
+		if shredder, ok = catalog.(Shredder); !ok {
+			return nil, fmt.Errorf("%w: key state needs a catalog that can shred", ErrInvalidOptions)
+		}
+		if holds == nil {
+			return nil, fmt.Errorf("%w: key state needs a catalog that reports legal holds: %w", ErrInvalidOptions, ErrHoldsUnsupported)
+		}
+	}
+	return &Reclaimer{backend: backend, catalog: catalog, holds: holds, shredder: shredder, layout: layout, opts: opts, now: now}, nil
 }
 
 func (r *Reclaimer) leaseDuration() time.Duration {
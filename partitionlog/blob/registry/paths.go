@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+)
+
+const (
+	DefaultPrefix = "partitionlog/registry"
+
+	// timelineObjectName terminates every timeline key so that a name and a
+	// longer name below it never need the same key as both object and prefix.
+	timelineObjectName = "timeline.json"
+)
+
+// TimelinePath returns the object key that stores name.
+func TimelinePath(prefix string, name string) string {
+	return timelinesPrefix(prefix) + name + "/" + timelineObjectName
+}
+
+// AllocatorPath returns the object key that allocates partitions in streamID.
+func AllocatorPath(prefix string, streamID string) string {
+	return fmt.Sprintf("%s/allocators/%s.json", normalizePrefix(prefix), keylayout.StreamKey(streamID))
+}
+
+func timelinesPrefix(prefix string) string {
+	return normalizePrefix(prefix) + "/timelines/"
+}
+
+// ParseTimelinePath returns the timeline name stored at key.
+func ParseTimelinePath(prefix string, key string) (string, bool) {
+	relative, ok := strings.CutPrefix(key, timelinesPrefix(prefix))
+	if !ok {
+		return "", false
+	}
+	name, ok := strings.CutSuffix(relative, "/"+timelineObjectName)
+	if !ok || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// CanonicalName normalizes and validates a timeline name. Names follow the
+// stream ID rules and may not use the reserved segment "timeline.json".
+func CanonicalName(name string) (string, error) {
+	name, err := keylayout.CanonicalStreamID(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: timeline name: %w", ErrInvalidRequest, err)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == timelineObjectName {
+			return "", fmt.Errorf("%w: timeline name %q contains reserved path segment %q", ErrInvalidRequest, name, segment)
+		}
+	}
+	return name, nil
+}
+
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return DefaultPrefix
+	}
+	return path.Clean(prefix)
+}
@@ -0,0 +1,385 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+)
+
+type timelineFile struct {
+	Version       uint16 `json:"version"`
+	Name          string `json:"name"`
+	StreamID      string `json:"stream_id"`
+	Partition     uint32 `json:"partition"`
+	State         State  `json:"state"`
+	CreatedUnixMS int64  `json:"created_unix_ms"`
+	UpdatedUnixMS int64  `json:"updated_unix_ms"`
+}
+
+type allocatorFile struct {
+	Version       uint16 `json:"version"`
+	StreamID      string `json:"stream_id"`
+	NextPartition uint64 `json:"next_partition"`
+}
+
+// Registry creates, resolves, and lists timelines. It holds no cached state;
+// every call reads object storage, so any number of processes may share one
+// registry prefix.
+type Registry struct {
+	backend Backend
+	opts    Options
+	now     func() time.Time
+}
+
+func New(backend Backend, opts Options) (*Registry, error) {
+	return newRegistry(backend, opts, time.Now)
+}
+
+func newRegistry(backend Backend, opts Options, now func() time.Time) (*Registry, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("%w: nil backend", ErrInvalidOptions)
+	}
+	if now == nil {
+		return nil, fmt.Errorf("%w: nil clock", ErrInvalidOptions)
+	}
+	opts.Prefix = normalizePrefix(opts.Prefix)
+	if opts.StreamID != "" {
+		streamID, err := keylayout.CanonicalStreamID(opts.StreamID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+		}
+		opts.StreamID = streamID
+	}
+	if opts.FirstPartition == 0 {
+		opts.FirstPartition = DefaultFirstPartition
+	}
+	if opts.CASAttempts < 0 {
+		return nil, fmt.Errorf("%w: negative CAS attempts", ErrInvalidOptions)
+	}
+	if opts.CASAttempts == 0 {
+		opts.CASAttempts = DefaultCASAttempts
+	}
+	return &Registry{backend: backend, opts: opts, now: now}, nil
+}
+
+// Prefix returns the normalized registry object-key root.
+func (r *Registry) Prefix() string {
+	return r.opts.Prefix
+}
+
+// CreateTimeline registers req.Name on a newly allocated partition. It is
+// idempotent: when the name already exists on the same stream, the existing
+// timeline is returned in whatever state it has reached.
+func (r *Registry) CreateTimeline(ctx context.Context, req CreateRequest) (CreateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CreateResult{}, err
+	}
+	name, err := CanonicalName(req.Name)
+	if err != nil {
+		return CreateResult{}, err
+	}
+	streamID := req.StreamID
+	if streamID == "" {
+		streamID = r.opts.StreamID
+	}
+	if streamID == "" {
+		return CreateResult{}, fmt.Errorf("%w: timeline %q has no stream id", ErrInvalidRequest, name)
+	}
+	streamID, err = keylayout.CanonicalStreamID(streamID)
+	if err != nil {
+		return CreateResult{}, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+
+	current, _, found, err := r.loadTimeline(ctx, name)
+	if err != nil {
+		return CreateResult{}, err
+	}
+	if found {
+		return existingTimeline(current, streamID)
+	}
+
+	partition, err := r.allocatePartition(ctx, streamID)
+	if err != nil {
+		return CreateResult{}, err
+	}
+	nowMS := r.now().UnixMilli()
+	candidate := timelineFile{
+		Version:       timelineVersion,
+		Name:          name,
+		StreamID:      streamID,
+		Partition:     partition,
+		State:         StateActive,
+		CreatedUnixMS: nowMS,
+		UpdatedUnixMS: nowMS,
+	}
+	body, err := json.Marshal(candidate)
+	if err != nil {
+		return CreateResult{}, err
+	}
+	obj, created, err := r.backend.CompareAndSwap(ctx, TimelinePath(r.opts.Prefix, name), "", body)
+	if err != nil {
+		return CreateResult{}, fmt.Errorf("create timeline %q: %w", name, err)
+	}
+	if created {
+		return CreateResult{Timeline: candidate.timeline(), Created: true}, nil
+	}
+	// A concurrent creator won the name. The partition allocated above stays
+	// unused; allocation never reuses a number.
+	observed, err := decodeTimelineFile(obj.Body, name)
+	if err != nil {
+		return CreateResult{}, err
+	}
+	return existingTimeline(observed, streamID)
+}
+
+// Resolve returns the timeline registered under name, including sealed and
+// deleted timelines. Callers decide whether a non-active state is usable.
+func (r *Registry) Resolve(ctx context.Context, name string) (Timeline, error) {
+	if err := ctx.Err(); err != nil {
+		return Timeline{}, err
+	}
+	name, err := CanonicalName(name)
+	if err != nil {
+		return Timeline{}, err
+	}
+	file, _, found, err := r.loadTimeline(ctx, name)
+	if err != nil {
+		return Timeline{}, err
+	}
+	if !found {
+		return Timeline{}, fmt.Errorf("%w: %q", ErrTimelineNotFound, name)
+	}
+	return file.timeline(), nil
+}
+
+// List returns one page of timelines whose names start with req.Prefix. The
+// prefix is matched byte-wise, so "orders/" lists names below "orders" while
+// "orders" also matches "orders-archive".
+func (r *Registry) List(ctx context.Context, req ListRequest) (ListPage, error) {
+	if err := ctx.Err(); err != nil {
+		return ListPage{}, err
+	}
+	if req.Limit < 0 {
+		return ListPage{}, fmt.Errorf("%w: negative list limit", ErrInvalidRequest)
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit > blobstore.MaxListLimit {
+		return ListPage{}, fmt.Errorf("%w: list limit=%d max=%d", ErrInvalidRequest, limit, blobstore.MaxListLimit)
+	}
+	opts := ListOptions{Prefix: timelinesPrefix(r.opts.Prefix) + req.Prefix, Limit: limit}
+	if req.AfterName != "" {
+		after, err := CanonicalName(req.AfterName)
+		if err != nil {
+			return ListPage{}, err
+		}
+		opts.AfterKey = TimelinePath(r.opts.Prefix, after)
+	}
+
+	page, err := r.backend.List(ctx, opts)
+	if err != nil {
+		return ListPage{}, fmt.Errorf("list timelines prefix=%q: %w", req.Prefix, err)
+	}
+	out := ListPage{Timelines: make([]Timeline, 0, len(page.Objects)), HasMore: page.HasMore}
+	for _, info := range page.Objects {
+		name, ok := ParseTimelinePath(r.opts.Prefix, info.Key)
+		if !ok {
+			return ListPage{}, fmt.Errorf("%w: unexpected object %q", ErrCorruptRegistry, info.Key)
+		}
+		file, _, found, err := r.loadTimeline(ctx, name)
+		if err != nil {
+			return ListPage{}, err
+		}
+		if found {
+			out.Timelines = append(out.Timelines, file.timeline())
+		}
+		out.NextAfterName = name
+	}
+	return out, nil
+}
+
+// Seal marks an active timeline sealed. Sealing a sealed timeline is a no-op.
+func (r *Registry) Seal(ctx context.Context, name string) (Timeline, error) {
+	return r.transition(ctx, name, StateSealed)
+}
+
+// MarkDeleted marks a timeline deleted. The name stays reserved and keeps its
+// partition so that a later create cannot alias the old partition's records.
+func (r *Registry) MarkDeleted(ctx context.Context, name string) (Timeline, error) {
+	return r.transition(ctx, name, StateDeleted)
+}
+
+func (r *Registry) transition(ctx context.Context, name string, target State) (Timeline, error) {
+	if err := ctx.Err(); err != nil {
+		return Timeline{}, err
+	}
+	name, err := CanonicalName(name)
+	if err != nil {
+		return Timeline{}, err
+	}
+	path := TimelinePath(r.opts.Prefix, name)
+	for attempt := 0; attempt < r.opts.CASAttempts; attempt++ {
+		file, token, found, err := r.loadTimeline(ctx, name)
+		if err != nil {
+			return Timeline{}, err
+		}
+		if !found {
+			return Timeline{}, fmt.Errorf("%w: %q", ErrTimelineNotFound, name)
+		}
+		if file.State == target {
+			return file.timeline(), nil
+		}
+		if !file.State.canMoveTo(target) {
+			return Timeline{}, fmt.Errorf("%w: timeline %q state=%s target=%s", ErrInvalidTransition, name, file.State, target)
+		}
+		file.State = target
+		file.UpdatedUnixMS = r.now().UnixMilli()
+		body, err := json.Marshal(file)
+		if err != nil {
+			return Timeline{}, err
+		}
+		_, swapped, err := r.backend.CompareAndSwap(ctx, path, token, body)
+		if err != nil {
+			return Timeline{}, fmt.Errorf("update timeline %q: %w", name, err)
+		}
+		if swapped {
+			return file.timeline(), nil
+		}
+	}
+	return Timeline{}, fmt.Errorf("%w: timeline %q state=%s retries exhausted", ErrConflict, name, target)
+}
+
+func (r *Registry) allocatePartition(ctx context.Context, streamID string) (uint32, error) {
+	path := AllocatorPath(r.opts.Prefix, streamID)
+	for attempt := 0; attempt < r.opts.CASAttempts; attempt++ {
+		file, token, err := r.loadAllocator(ctx, path, streamID)
+		if err != nil {
+			return 0, err
+		}
+		if file.NextPartition > math.MaxUint32 {
+			return 0, fmt.Errorf("%w: stream %q", ErrPartitionsExhausted, streamID)
+		}
+		partition := uint32(file.NextPartition)
+		file.NextPartition++
+		body, err := json.Marshal(file)
+		if err != nil {
+			return 0, err
+		}
+		_, swapped, err := r.backend.CompareAndSwap(ctx, path, token, body)
+		if err != nil {
+			return 0, fmt.Errorf("allocate partition stream=%q: %w", streamID, err)
+		}
+		if swapped {
+			return partition, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: allocate partition stream=%q retries exhausted", ErrConflict, streamID)
+}
+
+func (r *Registry) loadAllocator(ctx context.Context, path string, streamID string) (allocatorFile, string, error) {
+	obj, err := r.backend.Get(ctx, path)
+	if errors.Is(err, blobstore.ErrObjectNotFound) {
+		return allocatorFile{
+			Version:       allocatorVersion,
+			StreamID:      streamID,
+			NextPartition: uint64(r.opts.FirstPartition),
+		}, "", nil
+	}
+	if err != nil {
+		return allocatorFile{}, "", err
+	}
+	var file allocatorFile
+	if err := json.Unmarshal(obj.Body, &file); err != nil {
+		return allocatorFile{}, "", fmt.Errorf("%w: decode allocator stream=%q: %v", ErrCorruptRegistry, streamID, err)
+	}
+	if file.Version != allocatorVersion {
+		return allocatorFile{}, "", fmt.Errorf("%w: allocator version=%d", ErrCorruptRegistry, file.Version)
+	}
+	if file.StreamID != streamID {
+		return allocatorFile{}, "", fmt.Errorf("%w: allocator stream_id=%q want=%q", ErrCorruptRegistry, file.StreamID, streamID)
+	}
+	// FirstPartition may be raised after allocation has started.
+	file.NextPartition = max(file.NextPartition, uint64(r.opts.FirstPartition))
+	return file, obj.Token, nil
+}
+
+func (r *Registry) loadTimeline(ctx context.Context, name string) (timelineFile, string, bool, error) {
+	obj, err := r.backend.Get(ctx, TimelinePath(r.opts.Prefix, name))
+	if errors.Is(err, blobstore.ErrObjectNotFound) {
+		return timelineFile{}, "", false, nil
+	}
+	if err != nil {
+		return timelineFile{}, "", false, err
+	}
+	file, err := decodeTimelineFile(obj.Body, name)
+	if err != nil {
+		return timelineFile{}, "", false, err
+	}
+	return file, obj.Token, true, nil
+}
+
+func decodeTimelineFile(body []byte, name string) (timelineFile, error) {
+	var file timelineFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return timelineFile{}, fmt.Errorf("%w: decode timeline %q: %v", ErrCorruptRegistry, name, err)
+	}
+	if file.Version != timelineVersion {
+		return timelineFile{}, fmt.Errorf("%w: timeline %q version=%d", ErrCorruptRegistry, name, file.Version)
+	}
+	if file.Name != name {
+		return timelineFile{}, fmt.Errorf("%w: timeline name=%q want=%q", ErrCorruptRegistry, file.Name, name)
+	}
+	if _, err := keylayout.CanonicalStreamID(file.StreamID); err != nil {
+		return timelineFile{}, fmt.Errorf("%w: timeline %q: %v", ErrCorruptRegistry, name, err)
+	}
+	if !file.State.valid() {
+		return timelineFile{}, fmt.Errorf("%w: timeline %q state=%q", ErrCorruptRegistry, name, file.State)
+	}
+	return file, nil
+}
+
+func existingTimeline(file timelineFile, streamID string) (CreateResult, error) {
+	if file.StreamID != streamID {
+		return CreateResult{}, fmt.Errorf("%w: timeline %q stream=%q requested=%q", ErrTimelineExists, file.Name, file.StreamID, streamID)
+	}
+	return CreateResult{Timeline: file.timeline()}, nil
+}
+
+func (f timelineFile) timeline() Timeline {
+	return Timeline{
+		Name:      f.Name,
+		StreamID:  f.StreamID,
+		Partition: f.Partition,
+		State:     f.State,
+		CreatedAt: time.UnixMilli(f.CreatedUnixMS).UTC(),
+		UpdatedAt: time.UnixMilli(f.UpdatedUnixMS).UTC(),
+	}
+}
+
+func (s State) valid() bool {
+	switch s {
+	case StateActive, StateSealed, StateDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s State) canMoveTo(target State) bool {
+	switch s {
+	case StateActive:
+		return target == StateSealed || target == StateDeleted
+	case StateSealed:
+		return target == StateDeleted
+	default:
+		return false
+	}
+}
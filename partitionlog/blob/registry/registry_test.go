@@ -0,0 +1,275 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+)
+
+func TestCreateTimelineAllocatesPartitionsPerStream(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t, blobmemory.New(), Options{StreamID: "tenants/a"})
+
+	first := createTimeline(t, reg, CreateRequest{Name: "orders/2026"})
+	second := createTimeline(t, reg, CreateRequest{Name: "orders/2027"})
+	other := createTimeline(t, reg, CreateRequest{Name: "audit", StreamID: "tenants/b"})
+
+	if !first.Created || first.Timeline.Partition != DefaultFirstPartition || first.Timeline.State != StateActive {
+		t.Fatalf("first create = %+v", first)
+	}
+	if second.Timeline.Partition != DefaultFirstPartition+1 {
+		t.Fatalf("second partition = %d, want %d", second.Timeline.Partition, DefaultFirstPartition+1)
+	}
+	if other.Timeline.StreamID != "tenants/b" || other.Timeline.Partition != DefaultFirstPartition {
+		t.Fatalf("other stream timeline = %+v, want independent allocation", other.Timeline)
+	}
+
+	again, err := reg.CreateTimeline(ctx, CreateRequest{Name: "/orders/2026/"})
+	if err != nil {
+		t.Fatalf("CreateTimeline(existing) error = %v", err)
+	}
+	if again.Created || again.Timeline != first.Timeline {
+		t.Fatalf("CreateTimeline(existing) = %+v, want %+v unchanged", again, first.Timeline)
+	}
+	if _, err := reg.CreateTimeline(ctx, CreateRequest{Name: "orders/2026", StreamID: "tenants/b"}); !errors.Is(err, ErrTimelineExists) {
+		t.Fatalf("CreateTimeline(other stream) error = %v, want %v", err, ErrTimelineExists)
+	}
+
+	resolved, err := reg.Resolve(ctx, "orders/2027")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if resolved != second.Timeline {
+		t.Fatalf("Resolve() = %+v, want %+v", resolved, second.Timeline)
+	}
+	if opts := resolved.WriterOptions([16]byte{7}); opts.Partition != resolved.Partition || opts.WriterID != [16]byte{7} {
+		t.Fatalf("WriterOptions() = %+v", opts)
+	}
+	if _, err := reg.Resolve(ctx, "missing"); !errors.Is(err, ErrTimelineNotFound) {
+		t.Fatalf("Resolve(missing) error = %v, want %v", err, ErrTimelineNotFound)
+	}
+}
+
+func TestCreateTimelineRejectsInvalidRequests(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t, blobmemory.New(), Options{})
+
+	for _, req := range []CreateRequest{
+		{Name: "orders"},
+		{Name: "", StreamID: "s"},
+		{Name: "a/../b", StreamID: "s"},
+		{Name: "a/timeline.json/b", StreamID: "s"},
+		{Name: "ok", StreamID: "bad\x00stream"},
+	} {
+		if _, err := reg.CreateTimeline(ctx, req); !errors.Is(err, ErrInvalidRequest) {
+			t.Fatalf("CreateTimeline(%+v) error = %v, want %v", req, err, ErrInvalidRequest)
+		}
+	}
+	if _, err := New(nil, Options{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(nil) error = %v, want %v", err, ErrInvalidOptions)
+	}
+	if _, err := New(blobmemory.New(), Options{CASAttempts: -1}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(negative CAS attempts) error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+func TestConcurrentCreatesAgreeOnOnePartition(t *testing.T) {
+	ctx := context.Background()
+	backend := blobmemory.New()
+	const workers = 16
+
+	reg := newTestRegistry(t, backend, Options{StreamID: "s", CASAttempts: 4 * workers})
+	results := make([]CreateResult, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = reg.CreateTimeline(ctx, CreateRequest{Name: "shared"})
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for i := range workers {
+		if errs[i] != nil {
+			t.Fatalf("worker %d CreateTimeline() error = %v", i, errs[i])
+		}
+		if results[i].Created {
+			created++
+		}
+		if results[i].Timeline.Partition != results[0].Timeline.Partition {
+			t.Fatalf("worker %d partition = %d, worker 0 partition = %d", i, results[i].Timeline.Partition, results[0].Timeline.Partition)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("created count = %d, want 1", created)
+	}
+}
+
+func TestConcurrentCreatesAllocateDistinctPartitions(t *testing.T) {
+	ctx := context.Background()
+	backend := blobmemory.New()
+	const workers = 16
+
+	reg := newTestRegistry(t, backend, Options{StreamID: "s", CASAttempts: 4 * workers})
+	partitions := make([]uint32, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := reg.CreateTimeline(ctx, CreateRequest{Name: fmt.Sprintf("t%02d", i)})
+			partitions[i], errs[i] = result.Timeline.Partition, err
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[uint32]int)
+	for i, partition := range partitions {
+		if errs[i] != nil {
+			t.Fatalf("worker %d CreateTimeline() error = %v", i, errs[i])
+		}
+		if prev, ok := seen[partition]; ok {
+			t.Fatalf("workers %d and %d share partition %d", prev, i, partition)
+		}
+		seen[partition] = i
+	}
+}
+
+func TestListPagesByPrefix(t *testing.T) {
+	ctx := context.Background()
+	reg := newTestRegistry(t, blobmemory.New(), Options{StreamID: "s"})
+	for _, name := range []string{"orders/a", "orders/b", "orders/c", "orders-archive", "users/a"} {
+		createTimeline(t, reg, CreateRequest{Name: name})
+	}
+
+	var names []string
+	after := ""
+	for {
+		page, err := reg.List(ctx, ListRequest{Prefix: "orders/", AfterName: after, Limit: 2})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		for _, timeline := range page.Timelines {
+			names = append(names, timeline.Name)
+		}
+		if !page.HasMore {
+			break
+		}
+		after = page.NextAfterName
+	}
+	if fmt.Sprint(names) != "[orders/a orders/b orders/c]" {
+		t.Fatalf("List(orders/) names = %v", names)
+	}
+
+	page, err := reg.List(ctx, ListRequest{})
+	if err != nil {
+		t.Fatalf("List(all) error = %v", err)
+	}
+	if len(page.Timelines) != 5 || page.HasMore {
+		t.Fatalf("List(all) = %d timelines has_more=%v, want 5 complete", len(page.Timelines), page.HasMore)
+	}
+	if _, err := reg.List(ctx, ListRequest{Limit: -1}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("List(negative limit) error = %v, want %v", err, ErrInvalidRequest)
+	}
+}
+
+func TestStateTransitionsMoveForward(t *testing.T) {
+	ctx := context.Background()
+	clock := time.UnixMilli(1_000).UTC()
+	reg, err := newRegistry(blobmemory.New(), Options{StreamID: "s"}, func() time.Time { return clock })
+	if err != nil {
+		t.Fatalf("newRegistry() error = %v", err)
+	}
+	created := createTimeline(t, reg, CreateRequest{Name: "t"})
+
+	clock = clock.Add(time.Second)
+	sealed, err := reg.Seal(ctx, "t")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if sealed.State != StateSealed || !sealed.UpdatedAt.Equal(clock) || !sealed.CreatedAt.Equal(created.Timeline.CreatedAt) {
+		t.Fatalf("Seal() = %+v", sealed)
+	}
+	again, err := reg.Seal(ctx, "t")
+	if err != nil || again != sealed {
+		t.Fatalf("Seal(sealed) = %+v, %v; want unchanged", again, err)
+	}
+
+	deleted, err := reg.MarkDeleted(ctx, "t")
+	if err != nil {
+		t.Fatalf("MarkDeleted() error = %v", err)
+	}
+	if deleted.State != StateDeleted || deleted.Partition != created.Timeline.Partition {
+		t.Fatalf("MarkDeleted() = %+v", deleted)
+	}
+	if _, err := reg.Seal(ctx, "t"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Seal(deleted) error = %v, want %v", err, ErrInvalidTransition)
+	}
+	recreated, err := reg.CreateTimeline(ctx, CreateRequest{Name: "t"})
+	if err != nil {
+		t.Fatalf("CreateTimeline(deleted) error = %v", err)
+	}
+	if recreated.Created || recreated.Timeline.State != StateDeleted {
+		t.Fatalf("CreateTimeline(deleted) = %+v, want the reserved deleted timeline", recreated)
+	}
+	if _, err := reg.MarkDeleted(ctx, "missing"); !errors.Is(err, ErrTimelineNotFound) {
+		t.Fatalf("MarkDeleted(missing) error = %v, want %v", err, ErrTimelineNotFound)
+	}
+}
+
+func TestAllocatorHonorsFirstPartitionAndExhaustion(t *testing.T) {
+	ctx := context.Background()
+	backend := blobmemory.New()
+	reg := newTestRegistry(t, backend, Options{StreamID: "s", FirstPartition: math.MaxUint32})
+
+	last := createTimeline(t, reg, CreateRequest{Name: "last"})
+	if last.Timeline.Partition != math.MaxUint32 {
+		t.Fatalf("partition = %d, want %d", last.Timeline.Partition, uint32(math.MaxUint32))
+	}
+	if _, err := reg.CreateTimeline(ctx, CreateRequest{Name: "overflow"}); !errors.Is(err, ErrPartitionsExhausted) {
+		t.Fatalf("CreateTimeline(overflow) error = %v, want %v", err, ErrPartitionsExhausted)
+	}
+	if _, err := reg.Resolve(ctx, "overflow"); !errors.Is(err, ErrTimelineNotFound) {
+		t.Fatalf("Resolve(overflow) error = %v, want %v", err, ErrTimelineNotFound)
+	}
+}
+
+func TestCorruptTimelineObjectIsRejected(t *testing.T) {
+	ctx := context.Background()
+	backend := blobmemory.New()
+	reg := newTestRegistry(t, backend, Options{StreamID: "s"})
+	if _, _, err := backend.CompareAndSwap(ctx, TimelinePath(reg.Prefix(), "t"), "", []byte(`{"version":1,"name":"other"}`)); err != nil {
+		t.Fatalf("seed CompareAndSwap() error = %v", err)
+	}
+	if _, err := reg.Resolve(ctx, "t"); !errors.Is(err, ErrCorruptRegistry) {
+		t.Fatalf("Resolve(corrupt) error = %v, want %v", err, ErrCorruptRegistry)
+	}
+}
+
+func newTestRegistry(t *testing.T, backend Backend, opts Options) *Registry {
+	t.Helper()
+	reg, err := New(backend, opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return reg
+}
+
+func createTimeline(t *testing.T, reg *Registry, req CreateRequest) CreateResult {
+	t.Helper()
+	result, err := reg.CreateTimeline(context.Background(), req)
+	if err != nil {
+		t.Fatalf("CreateTimeline(%+v) error = %v", req, err)
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+// Package registry maps durable timeline names to stream partitions.
+//
+// The registry lives in object storage next to the catalog and uses only the
+// blobstore conditional-write protocol. Each timeline is one small JSON object
+// keyed by its name, and each stream has one allocator object whose
+// CompareAndSwap hands out partition numbers. A timeline object is created
+// with create-if-absent, so two concurrent creators of one name agree on a
+// single partition; a losing creator leaves an unused gap in the stream's
+// partition numbers rather than a shared partition.
+package registry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	"github.com/ankur-anand/unijord/partitionlog"
+)
+
+var (
+	ErrInvalidOptions      = errors.New("registry: invalid options")
+	ErrInvalidRequest      = errors.New("registry: invalid request")
+	ErrTimelineNotFound    = errors.New("registry: timeline not found")
+	ErrTimelineExists      = errors.New("registry: timeline exists on another stream")
+	ErrInvalidTransition   = errors.New("registry: invalid timeline state transition")
+	ErrPartitionsExhausted = errors.New("registry: stream partitions exhausted")
+	ErrConflict            = errors.New("registry: conflict")
+	ErrCorruptRegistry     = errors.New("registry: corrupt registry object")
+)
+
+const (
+	DefaultFirstPartition = 1
+	DefaultCASAttempts    = 8
+	DefaultListLimit      = 100
+
+	timelineVersion  uint16 = 1
+	allocatorVersion uint16 = 1
+)
+
+type Object = blobstore.Object
+type ListOptions = blobstore.ListOptions
+type ObjectPage = blobstore.ObjectPage
+
+// Backend is the conditional object protocol used by the registry.
+type Backend interface {
+	Get(ctx context.Context, key string) (Object, error)
+	CompareAndSwap(ctx context.Context, key string, expectedToken string, body []byte) (Object, bool, error)
+	List(ctx context.Context, opts ListOptions) (ObjectPage, error)
+}
+
+// State is the lifecycle state of one timeline. States only move forward:
+// active to sealed, and active or sealed to deleted.
+type State string
+
+const (
+	StateActive  State = "active"
+	StateSealed  State = "sealed"
+	StateDeleted State = "deleted"
+)
+
+type Options struct {
+	// Prefix is the registry object-key root.
+	Prefix string
+	// StreamID is the stream used by CreateTimeline requests that do not name
+	// one. It may be empty when every request names its stream.
+	StreamID string
+	// FirstPartition is the first partition number allocated in a stream. Zero
+	// uses DefaultFirstPartition. Raise it when a stream already holds
+	// partitions that were assigned outside the registry.
+	FirstPartition uint32
+	// CASAttempts bounds conditional-write retries per operation.
+	CASAttempts int
+}
+
+// Timeline is one registered name and the partition that stores its records.
+type Timeline struct {
+	Name      string
+	StreamID  string
+	Partition uint32
+	State     State
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WriterOptions returns options that open a writer for the timeline's
+// partition. Batch, backpressure, and pipeline settings keep their defaults.
+func (t Timeline) WriterOptions(writerID [16]byte) partitionlog.WriterOptions {
+	return partitionlog.WriterOptions{Partition: t.Partition, WriterID: writerID}
+}
+
+type CreateRequest struct {
+	Name string
+	// StreamID overrides Options.StreamID for this timeline.
+	StreamID string
+}
+
+// CreateResult reports the registered timeline and whether this call created
+// it. Creating an existing name on the same stream returns it unchanged.
+type CreateResult struct {
+	Timeline Timeline
+	Created  bool
+}
+
+// ListRequest pages timelines whose names start with Prefix. Results are in
+// object-key order, which groups names by path segment.
+type ListRequest struct {
+	Prefix    string
+	AfterName string
+	Limit     int
+}
+
+type ListPage struct {
+	Timelines     []Timeline
+	NextAfterName string
+	HasMore       bool
+}
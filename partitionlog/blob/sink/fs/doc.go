@@ -0,0 +1,8 @@
+// Package fs implements the partitionlog blob/sink multipart store on a local
+// directory.
+//
+// Each part is written as its own file under the upload staging prefix.
+// Complete concatenates the staged parts into a private temporary file and
+// links it into place only if the final key does not exist yet, which gives
+// the same create-once guarantee as the cloud providers' conditional writes.
+package fs
@@ -0,0 +1,258 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	blobfs "github.com/ankur-anand/unijord/internal/blobstore/fs"
+	"github.com/ankur-anand/unijord/partitionlog/blob/sink/multipart"
+)
+
+type Store struct {
+	backend *blobfs.Backend
+}
+
+var _ multipart.Store = (*Store)(nil)
+
+func NewStore(root string) (*Store, error) {
+	if root == "" {
+		return nil, fmt.Errorf("%w: empty fs root", multipart.ErrInvalidStore)
+	}
+	backend, err := blobfs.New(root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", multipart.ErrInvalidStore, err)
+	}
+	return &Store{backend: backend}, nil
+}
+
+func (s *Store) BeginMultipart(ctx context.Context, key string, opts multipart.Options) (multipart.Upload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts, err := multipart.NormalizeOptions(key, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := blobfs.ValidateKey(key); err != nil {
+		return nil, fmt.Errorf("%w: %w", multipart.ErrInvalidStore, err)
+	}
+	return &upload{
+		backend: s.backend,
+		key:     key,
+		opts:    opts,
+		parts:   make(map[int]stagedPart),
+	}, nil
+}
+
+type upload struct {
+	mu        sync.Mutex
+	backend   *blobfs.Backend
+	key       string
+	opts      multipart.Options
+	parts     map[int]stagedPart
+	aborted   bool
+	completed bool
+}
+
+type stagedPart struct {
+	key   string
+	token string
+	size  uint64
+}
+
+func (u *upload) UploadPart(ctx context.Context, part multipart.Part) (multipart.Receipt, error) {
+	if err := multipart.ValidatePart(part); err != nil {
+		return multipart.Receipt{}, err
+	}
+
+	partKey := multipart.StagingPartKey(u.opts.StagingPrefix, part.Number)
+	if err := u.reservePart(part.Number, partKey); err != nil {
+		return multipart.Receipt{}, err
+	}
+
+	info, err := u.backend.Create(ctx, partKey, u.opts.ContentType, func(w io.Writer) error {
+		_, err := bytes.NewReader(part.Bytes).WriteTo(w)
+		return err
+	})
+	if err != nil {
+		u.dropPart(part.Number)
+		return multipart.Receipt{}, mapError(err)
+	}
+	staged := stagedPart{
+		key:   partKey,
+		token: info.Token,
+		size:  uint64(len(part.Bytes)),
+	}
+
+	u.mu.Lock()
+	if u.aborted {
+		delete(u.parts, part.Number)
+		u.mu.Unlock()
+		_ = u.backend.Delete(ctx, staged.key)
+		return multipart.Receipt{}, multipart.ErrAborted
+	}
+	u.parts[part.Number] = staged
+	u.mu.Unlock()
+
+	return multipart.Receipt{
+		Number:    part.Number,
+		Token:     staged.token,
+		SizeBytes: staged.size,
+	}, nil
+}
+
+func (u *upload) Complete(ctx context.Context, receipts []multipart.Receipt) (multipart.ObjectAttrs, error) {
+	if err := multipart.ValidateReceipts(receipts); err != nil {
+		return multipart.ObjectAttrs{}, err
+	}
+
+	sources, size, err := u.sourcesFor(receipts)
+	if err != nil {
+		return multipart.ObjectAttrs{}, err
+	}
+	info, err := u.backend.Create(ctx, u.key, u.opts.ContentType, func(w io.Writer) error {
+		return concat(ctx, u.backend, w, sources)
+	})
+	if err != nil {
+		return multipart.ObjectAttrs{}, mapError(err)
+	}
+
+	u.mu.Lock()
+	u.completed = true
+	u.mu.Unlock()
+	_ = deleteParts(ctx, u.backend, sources)
+
+	return multipart.ObjectAttrs{
+		Key:       u.key,
+		SizeBytes: max(uint64(info.SizeBytes), size),
+		Token:     info.Token,
+	}, nil
+}
+
+func (u *upload) Abort(ctx context.Context) error {
+	u.mu.Lock()
+	if u.completed || u.aborted {
+		u.mu.Unlock()
+		return nil
+	}
+	u.aborted = true
+	cleanup := make([]stagedPart, 0, len(u.parts))
+	for _, part := range u.parts {
+		cleanup = append(cleanup, part)
+	}
+	u.parts = nil
+	u.mu.Unlock()
+
+	return deleteParts(ctx, u.backend, cleanup)
+}
+
+func (u *upload) reservePart(number int, key string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.aborted {
+		return multipart.ErrAborted
+	}
+	if u.completed {
+		return multipart.ErrCompleted
+	}
+	if _, exists := u.parts[number]; exists {
+		return fmt.Errorf("%w: duplicate part %d", multipart.ErrInvalidStore, number)
+	}
+	u.parts[number] = stagedPart{key: key}
+	return nil
+}
+
+func (u *upload) dropPart(number int) {
+	u.mu.Lock()
+	delete(u.parts, number)
+	u.mu.Unlock()
+}
+
+func (u *upload) sourcesFor(receipts []multipart.Receipt) ([]stagedPart, uint64, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.aborted {
+		return nil, 0, multipart.ErrAborted
+	}
+	if u.completed {
+		return nil, 0, multipart.ErrCompleted
+	}
+
+	sources := make([]stagedPart, 0, len(receipts))
+	var size uint64
+	for _, receipt := range receipts {
+		part, ok := u.parts[receipt.Number]
+		if !ok || part.token == "" {
+			return nil, 0, fmt.Errorf("%w: missing fs part %d", multipart.ErrInvalidStore, receipt.Number)
+		}
+		if receipt.Token != "" && receipt.Token != part.token {
+			return nil, 0, fmt.Errorf("%w: token mismatch for fs part %d", multipart.ErrInvalidStore, receipt.Number)
+		}
+		sources = append(sources, part)
+		size += part.size
+	}
+	return sources, size, nil
+}
+
+func concat(ctx context.Context, backend *blobfs.Backend, w io.Writer, parts []stagedPart) error {
+	for _, part := range parts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name, err := backend.Path(part.key)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("%w: staged part %s: %w", multipart.ErrAborted, part.key, err)
+		}
+		n, err := io.Copy(w, f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+		if uint64(n) != part.size {
+			return fmt.Errorf("%w: staged part %s size=%d want=%d", multipart.ErrInvalidStore, part.key, n, part.size)
+		}
+	}
+	return nil
+}
+
+func deleteParts(ctx context.Context, backend *blobfs.Backend, parts []stagedPart) error {
+	var firstErr error
+	for _, part := range parts {
+		if err := ctx.Err(); err != nil {
+			if firstErr != nil {
+				return firstErr
+			}
+			return err
+		}
+		if part.key == "" {
+			continue
+		}
+		if err := backend.Delete(ctx, part.key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, blobstore.ErrImmutableConflict) {
+		return fmt.Errorf("%w: %w", multipart.ErrPreconditionFailed, err)
+	}
+	if errors.Is(err, blobstore.ErrInvalidRequest) {
+		return fmt.Errorf("%w: %w", multipart.ErrInvalidStore, err)
+	}
+	return err
+}
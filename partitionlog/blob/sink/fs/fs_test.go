@@ -0,0 +1,110 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	blobfs "github.com/ankur-anand/unijord/internal/blobstore/fs"
+	"github.com/ankur-anand/unijord/partitionlog/blob/sink/internal/sinktest"
+	"github.com/ankur-anand/unijord/partitionlog/blob/sink/multipart"
+)
+
+func TestStoreConformance(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	read := readFSObject(t, root)
+
+	sinktest.RunMultipartStore(t, store, "sinktest", read)
+	sinktest.RunSegmentWriter(t, store, "sinktest", read)
+}
+
+func TestStoreRemovesStagedParts(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	store, err := NewStore(root)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	backend, err := blobfs.New(root)
+	if err != nil {
+		t.Fatalf("blobfs.New() error = %v", err)
+	}
+
+	upload, err := store.BeginMultipart(ctx, "segments/p-1.seg", multipart.Options{StagingPrefix: "staging/p-1"})
+	if err != nil {
+		t.Fatalf("BeginMultipart error = %v", err)
+	}
+	r1, err := upload.UploadPart(ctx, multipart.Part{Number: 1, Bytes: []byte("hello ")})
+	if err != nil {
+		t.Fatalf("UploadPart(1) error = %v", err)
+	}
+	r2, err := upload.UploadPart(ctx, multipart.Part{Number: 2, Bytes: []byte("world")})
+	if err != nil {
+		t.Fatalf("UploadPart(2) error = %v", err)
+	}
+	if _, err := upload.Complete(ctx, []multipart.Receipt{r1, r2}); err != nil {
+		t.Fatalf("Complete error = %v", err)
+	}
+	page, err := backend.List(ctx, blobstore.ListOptions{Prefix: "staging/"})
+	if err != nil {
+		t.Fatalf("List(staging) error = %v", err)
+	}
+	if len(page.Objects) != 0 {
+		t.Fatalf("staged objects after Complete = %+v, want none", page.Objects)
+	}
+	obj, err := backend.Get(ctx, "segments/p-1.seg")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(obj.Body) != "hello world" {
+		t.Fatalf("object bytes = %q, want %q", obj.Body, "hello world")
+	}
+}
+
+func TestStoreRejectsBadFSInputs(t *testing.T) {
+	if _, err := NewStore(""); !errors.Is(err, multipart.ErrInvalidStore) {
+		t.Fatalf("NewStore(empty root) error = %v, want %v", err, multipart.ErrInvalidStore)
+	}
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore error = %v", err)
+	}
+	for _, key := range []string{"", "../escape", "/abs"} {
+		if _, err := store.BeginMultipart(context.Background(), key, multipart.Options{}); !errors.Is(err, multipart.ErrInvalidStore) {
+			t.Fatalf("BeginMultipart(%q) error = %v, want %v", key, err, multipart.ErrInvalidStore)
+		}
+	}
+}
+
+func readFSObject(t testing.TB, root string) sinktest.ReadObject {
+	t.Helper()
+	backend, err := blobfs.New(root)
+	if err != nil {
+		t.Fatalf("blobfs.New() error = %v", err)
+	}
+	return func(ctx context.Context, key string) (sinktest.Object, error) {
+		name, err := backend.Path(key)
+		if err != nil {
+			return sinktest.Object{}, err
+		}
+		body, err := os.ReadFile(name)
+		if err != nil {
+			return sinktest.Object{}, err
+		}
+		contentType, err := backend.ContentType(ctx, key)
+		if err != nil {
+			return sinktest.Object{}, err
+		}
+		return sinktest.Object{
+			Body:        body,
+			ContentType: contentType,
+			SizeBytes:   uint64(len(body)),
+		}, nil
+	}
+}
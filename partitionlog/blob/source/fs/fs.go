@@ -0,0 +1,55 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	blobfs "github.com/ankur-anand/unijord/internal/blobstore/fs"
+	"github.com/ankur-anand/unijord/partitionlog/blob/source/internal/rangeread"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
+)
+
+type Store struct {
+	backend *blobfs.Backend
+}
+
+var _ segreader.SegmentStore = (*Store)(nil)
+
+func NewStore(root string) (*Store, error) {
+	if root == "" {
+		return nil, fmt.Errorf("blob/source/fs: empty root")
+	}
+	backend, err := blobfs.New(root)
+	if err != nil {
+		return nil, fmt.Errorf("blob/source/fs: %w", err)
+	}
+	return &Store{backend: backend}, nil
+}
+
+func (s *Store) ReadAt(ctx context.Context, key string, off uint64, n uint64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("blob/source/fs: empty key")
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	bounds, err := rangeread.Validate(off, n)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.backend.Path(key)
+	if err != nil {
+		return nil, fmt.Errorf("blob/source/fs: %w", err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rangeread.ReadExact(io.NewSectionReader(f, bounds.Offset, bounds.Count), n)
+}
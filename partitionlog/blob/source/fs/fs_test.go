@@ -0,0 +1,49 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	blobfs "github.com/ankur-anand/unijord/internal/blobstore/fs"
+	"github.com/ankur-anand/unijord/partitionlog/blob/source/internal/sourcetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	t.Parallel()
+
+	sourcetest.Run(t, sourcetest.Config{
+		NewFixture: func(t testing.TB) sourcetest.Fixture {
+			t.Helper()
+			const key = "segments/p-1.seg"
+			body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+			root := t.TempDir()
+			backend, err := blobfs.New(root)
+			if err != nil {
+				t.Fatalf("blobfs.New() error = %v", err)
+			}
+			if _, err := backend.Put(context.Background(), key, body); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			store, err := NewStore(root)
+			if err != nil {
+				t.Fatalf("NewStore() error = %v", err)
+			}
+			return sourcetest.Fixture{Store: store, Key: key, Body: body}
+		},
+	})
+}
+
+func TestStoreRejectsBadFSInputs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewStore(""); err == nil {
+		t.Fatal("NewStore(empty root) error = nil, want error")
+	}
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if _, err := store.ReadAt(context.Background(), "../escape", 0, 1); err == nil {
+		t.Fatal("ReadAt(escaping key) error = nil, want error")
+	}
+}
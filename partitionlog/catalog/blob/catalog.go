@@ -6,6 +6,8 @@ import (
 
 	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 const pageVersion = 1
@@ -35,6 +37,7 @@ type writerSession struct {
 
 	writerEpoch uint64
 	writerID    [16]byte
+	signer      signing.Signer
 
 	mu    sync.Mutex
 	head  headFile
@@ -52,6 +55,8 @@ type headFile struct {
 	WriterEpoch             uint64             `json:"writer_epoch"`
 	WriterID                [16]byte           `json:"writer_id,omitempty"`
 	SegmentCount            uint64             `json:"segment_count"`
+	RetainedBytes           uint64             `json:"retained_bytes,omitempty"`
+	Producers               pmeta.Producers    `json:"producers,omitzero"`
 	LastSegment             pmeta.SegmentRef   `json:"last_segment,omitempty"`
 	HasLastSegment          bool               `json:"has_last_segment,omitempty"`
 	IndexFrontier           []pageRef          `json:"index_frontier,omitempty"`
@@ -59,6 +64,37 @@ type headFile struct {
 	ActiveSegments          []pmeta.SegmentRef `json:"active_segments,omitempty"`
 	MaxIndexLevel           uint8              `json:"max_index_level,omitempty"`
 	Generation              uint64             `json:"generation"`
+	Sealed                  bool               `json:"sealed,omitempty"`
+	Deleted                 bool               `json:"deleted,omitempty"`
+	// HoldVersion counts legal hold placements. It is outside the signed
+	// state: bumping it only moves the head token, so a delete or retention
+	// apply that read the holds against an older head fails its CAS.
+	HoldVersion uint64 `json:"hold_version,omitempty"`
+	// WriterReleased marks a fence whose writer closed. Like HoldVersion it is
+	// outside the signed state; taking the fence clears it.
+	WriterReleased bool `json:"writer_released,omitempty"`
+	// Shredded marks a tombstone written by a crypto-shred. It is outside the
+	// signed state too, so a shred can mark an existing tombstone.
+	Shredded bool `json:"shredded,omitempty"`
+	// Purged marks a tombstone whose partition objects the lifecycle
+	// reclaimer removed. The tombstone stays as the terminal head, so the
+	// partition can never be recreated over LSNs readers have seen. It is
+	// outside the signed state, since the reclaimer holds no signer.
+	Purged bool `json:"purged,omitempty"`
+	// RetainedBytesExact marks RetainedBytes as counted since the partition
+	// had no retained segments. Heads written before byte accounting lack it
+	// and count from zero until a retention apply sums their segment tree.
+	RetainedBytesExact bool `json:"retained_bytes_exact,omitempty"`
+	// Tree is the compact range over every chained segment the partition
+	// committed, and TreeBase the part of it retention dropped. The public
+	// TreeRoot is Tree's root, so neither page layout nor retention changes
+	// it.
+	Tree     proof.Range `json:"tree,omitzero"`
+	TreeBase proof.Range `json:"tree_base,omitzero"`
+	// SignerKeyID and Signature sign the head state when the session that
+	// committed it had a signer.
+	SignerKeyID string          `json:"signer_key_id,omitempty"`
+	Signature   pmeta.Signature `json:"signature,omitzero"`
 }
 
 type pageRef struct {
@@ -0,0 +1,207 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+var (
+	_ csession.PartitionDeleter        = (*Catalog)(nil)
+	_ csession.SigningPartitionDeleter = (*Catalog)(nil)
+	_ csession.PartitionShredder       = (*Catalog)(nil)
+)
+
+// DeletePartition writes a tombstone into the partition head. The tombstone
+// advances the writer fence, so an active writer observes a moved fence on its
+// next commit, and OpenWriter rejects the partition from then on. Objects stay
+// in place until the lifecycle reclaimer purges the partition. A partition
+// under legal hold is not deleted and returns ErrLegalHold. A partition whose
+// head is signed needs DeleteSignedPartition and returns ErrSignerRequired.
+func (c *Catalog) DeletePartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, bool, error) {
+	return c.deletePartition(ctx, partition, tombstoneRequest{})
+}
+
+// DeleteSignedPartition deletes like DeletePartition and signs the tombstone
+// head with signer.
+func (c *Catalog) DeleteSignedPartition(ctx context.Context, partition uint32, signer signing.Signer) (pmeta.PartitionHead, bool, error) {
+	if signer == nil {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("%w: nil signer", csession.ErrInvalidRequest)
+	}
+	return c.deletePartition(ctx, partition, tombstoneRequest{signer: signer})
+}
+
+// ShredPartition deletes like DeletePartition and marks the tombstone
+// shredded, so reads report ErrPartitionShredded. An existing tombstone is
+// marked without moving its generation. signer signs a new tombstone and may
+// be nil for an unsigned head.
+func (c *Catalog) ShredPartition(ctx context.Context, partition uint32, signer signing.Signer) (pmeta.PartitionHead, bool, error) {
+	return c.deletePartition(ctx, partition, tombstoneRequest{signer: signer, shred: true})
+}
+
+// ShredPartitionAt shreds like ShredPartition, but only while the head is
+// still at generation. A head that moved returns ErrConflict. Lifecycle
+// workers use it to finish a shred they observed without racing a writer
+// that committed since.
+func (c *Catalog) ShredPartitionAt(ctx context.Context, partition uint32, generation uint64) (pmeta.PartitionHead, bool, error) {
+	return c.deletePartition(ctx, partition, tombstoneRequest{shred: true, generation: generation, pinned: true})
+}
+
+// MarkPartitionPurged marks the tombstone of partition purged once the
+// lifecycle reclaimer removed every other object of the partition. The head
+// stays behind as a terminal tombstone: OpenWriter and InitializePartition
+// keep rejecting the partition, and ListPartitions no longer lists it. A
+// partition that is not deleted returns ErrConflict.
+func (c *Catalog) MarkPartitionPurged(ctx context.Context, partition uint32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	path := HeadPath(c.opts.Prefix, c.opts.StreamID, partition)
+	backoff := c.opts.WriterAcquireInitialBackoff
+	var lastCASErr error
+	for attempt := 0; attempt < c.opts.WriterAcquireMaxAttempts; attempt++ {
+		head, token, err := c.loadHead(ctx, partition)
+		if err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		if token == "" || !head.Deleted {
+			return fmt.Errorf("%w: purge of partition=%d without a tombstone", csession.ErrConflict, partition)
+		}
+		if head.Purged {
+			return nil
+		}
+		candidate := head
+		candidate.Purged = true
+		body, err := marshalHead(candidate, c.opts.StreamID, partition)
+		if err != nil {
+			return err
+		}
+		_, swapped, err := c.backend.CompareAndSwap(ctx, path, token, body)
+		if err == nil && swapped {
+			return nil
+		}
+		lastCASErr = err
+
+		if attempt+1 == c.opts.WriterAcquireMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, c.opts.WriterAcquireMaxBackoff)
+	}
+	if lastCASErr != nil {
+		return fmt.Errorf("mark partition=%d purged: %w", partition, lastCASErr)
+	}
+	return fmt.Errorf("%w: purge mark contention partition=%d", csession.ErrConflict, partition)
+}
+
+// tombstoneRequest selects how deletePartition writes its tombstone.
+type tombstoneRequest struct {
+	signer signing.Signer
+	shred  bool
+	// pinned refuses a live head whose generation moved past generation.
+	pinned     bool
+	generation uint64
+}
+
+func (c *Catalog) deletePartition(ctx context.Context, partition uint32, req tombstoneRequest) (pmeta.PartitionHead, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, false, err
+	}
+
+	path := HeadPath(c.opts.Prefix, c.opts.StreamID, partition)
+	backoff := c.opts.WriterAcquireInitialBackoff
+	var candidate headFile
+	var lastCASErr error
+
+	for attempt := 0; attempt < c.opts.WriterAcquireMaxAttempts; attempt++ {
+		head, token, err := c.loadHead(ctx, partition)
+		if err != nil {
+			return pmeta.PartitionHead{}, false, errors.Join(lastCASErr, err)
+		}
+		if lastCASErr != nil && sameHeadState(head, candidate) {
+			// The previous CAS applied even though its response was lost.
+			return stateFromHead(head), true, nil
+		}
+		if token == "" || (head.Deleted && (head.Shredded || !req.shred)) {
+			return stateFromHead(head), false, nil
+		}
+		if head.Deleted {
+			// Shredding an existing tombstone only marks it. The marker is
+			// outside the signed state and the holds were checked when the
+			// tombstone was written.
+			candidate = head
+			candidate.Shredded = true
+		} else if candidate, err = c.tombstoneCandidate(ctx, head, partition, req); err != nil {
+			return pmeta.PartitionHead{}, false, errors.Join(lastCASErr, err)
+		}
+		body, err := marshalHead(candidate, c.opts.StreamID, partition)
+		if err != nil {
+			return pmeta.PartitionHead{}, false, err
+		}
+		_, swapped, err := c.backend.CompareAndSwap(ctx, path, token, body)
+		if err == nil && swapped {
+			return stateFromHead(candidate), true, nil
+		}
+		lastCASErr = err
+
+		if attempt+1 == c.opts.WriterAcquireMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return pmeta.PartitionHead{}, false, errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, c.opts.WriterAcquireMaxBackoff)
+	}
+	if lastCASErr != nil {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("delete partition=%d: %w", partition, lastCASErr)
+	}
+	return pmeta.PartitionHead{}, false, fmt.Errorf("%w: delete partition contention partition=%d", csession.ErrConflict, partition)
+}
+
+// tombstoneCandidate builds the tombstone of a live head after checking the
+// signer, the pinned generation and the legal holds.
+func (c *Catalog) tombstoneCandidate(ctx context.Context, head headFile, partition uint32, req tombstoneRequest) (headFile, error) {
+	if req.pinned && head.Generation != req.generation {
+		return headFile{}, fmt.Errorf("%w: partition=%d generation=%d moved from %d", csession.ErrConflict, partition, head.Generation, req.generation)
+	}
+	if err := requireSigner(head, req.signer); err != nil {
+		return headFile{}, err
+	}
+	holds, err := c.LoadLegalHolds(ctx, partition)
+	if err != nil {
+		return headFile{}, err
+	}
+	if holds.Active() {
+		return headFile{}, fmt.Errorf("%w: delete partition=%d", csession.ErrLegalHold, partition)
+	}
+	candidate, err := tombstoneHead(head, partition)
+	if err != nil {
+		return headFile{}, err
+	}
+	candidate.Shredded = req.shred
+	return signHead(ctx, candidate, req.signer)
+}
+
+func tombstoneHead(head headFile, partition uint32) (headFile, error) {
+	generation, err := nextGeneration(head.Generation, partition)
+	if err != nil {
+		return headFile{}, err
+	}
+	next := head
+	next.Deleted = true
+	next.Generation = generation
+	// A head that never had a writer has no fence to move. An exhausted fence
+	// cannot move either, but the tombstone alone rejects its appends.
+	if next.WriterEpoch > 0 && next.WriterEpoch < math.MaxUint64 {
+		next.WriterEpoch++
+	}
+	return next, nil
+}
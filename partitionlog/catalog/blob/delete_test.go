@@ -0,0 +1,170 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+func TestBlobCatalogDeletePartitionHidesAndFencesPartition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 0, 9, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+
+	deleted, ok, err := cat.DeletePartition(ctx, 1)
+	if err != nil || !ok {
+		t.Fatalf("DeletePartition() = %v, %v", ok, err)
+	}
+	if deleted.WriterEpoch != ws.Epoch()+1 || deleted.NextLSN != 10 {
+		t.Fatalf("tombstone head = %+v", deleted)
+	}
+	if _, ok, err := cat.DeletePartition(ctx, 1); err != nil || ok {
+		t.Fatalf("DeletePartition(again) = %v, %v; want false, nil", ok, err)
+	}
+	if _, ok, err := cat.DeletePartition(ctx, 2); err != nil || ok {
+		t.Fatalf("DeletePartition(absent) = %v, %v; want false, nil", ok, err)
+	}
+
+	if _, err := cat.LoadPartition(ctx, 1); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	if _, _, err := cat.FindSegment(ctx, 1, 0); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("FindSegment(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	if _, err := cat.ListSegments(ctx, pcatalog.ListSegmentsRequest{Partition: 1}); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("ListSegments(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 10, 19, ws.Epoch())); !errors.Is(err, pcatalog.ErrStaleWriter) {
+		t.Fatalf("AppendSegment(deleted) error = %v, want %v", err, pcatalog.ErrStaleWriter)
+	}
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{2}); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("OpenWriter(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	if _, _, err := cat.InitializePartition(ctx, 1, 0); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("InitializePartition(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+
+	snapshot, err := cat.LoadMaintenanceSnapshot(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadMaintenanceSnapshot() error = %v", err)
+	}
+	if !snapshot.Deleted {
+		t.Fatalf("maintenance snapshot = %+v, want deleted", snapshot)
+	}
+}
+
+func TestBlobCatalogShredPartitionMarksTombstone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	for _, partition := range []uint32{1, 2} {
+		ws, err := cat.OpenWriter(ctx, partition, [16]byte{1})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(partition, 0, 9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", partition, err)
+		}
+	}
+
+	// Shredding an existing tombstone marks it without moving the head.
+	deleted, _, err := cat.DeletePartition(ctx, 1)
+	if err != nil {
+		t.Fatalf("DeletePartition() error = %v", err)
+	}
+	if _, err := cat.LoadPartition(ctx, 1); errors.Is(err, pcatalog.ErrPartitionShredded) {
+		t.Fatalf("LoadPartition(deleted) error = %v, want no shred", err)
+	}
+	shredded, ok, err := cat.ShredPartition(ctx, 1, nil)
+	if err != nil || !ok || shredded != deleted {
+		t.Fatalf("ShredPartition(tombstone) = %+v, %v, %v; want %+v", shredded, ok, err, deleted)
+	}
+	if _, ok, err := cat.ShredPartition(ctx, 1, nil); err != nil || ok {
+		t.Fatalf("ShredPartition(again) = %v, %v; want false, nil", ok, err)
+	}
+	if _, err := cat.LoadPartition(ctx, 1); !errors.Is(err, pcatalog.ErrPartitionShredded) || !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(shredded) error = %v, want %v", err, pcatalog.ErrPartitionShredded)
+	}
+
+	snapshot, err := cat.LoadMaintenanceSnapshot(ctx, 2)
+	if err != nil {
+		t.Fatalf("LoadMaintenanceSnapshot() error = %v", err)
+	}
+	if _, _, err := cat.ShredPartitionAt(ctx, 2, snapshot.Generation-1); !errors.Is(err, pcatalog.ErrConflict) {
+		t.Fatalf("ShredPartitionAt(stale generation) error = %v, want %v", err, pcatalog.ErrConflict)
+	}
+	if _, ok, err := cat.ShredPartitionAt(ctx, 2, snapshot.Generation); err != nil || !ok {
+		t.Fatalf("ShredPartitionAt() = %v, %v; want true, nil", ok, err)
+	}
+	if _, err := cat.OpenWriter(ctx, 2, [16]byte{2}); !errors.Is(err, pcatalog.ErrPartitionShredded) {
+		t.Fatalf("OpenWriter(shredded) error = %v, want %v", err, pcatalog.ErrPartitionShredded)
+	}
+}
+
+func TestBlobCatalogListPartitionsSkipsDeletedHeads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	for _, partition := range []uint32{1, 2} {
+		if _, _, err := cat.InitializePartition(ctx, partition, 0); err != nil {
+			t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+		}
+	}
+	if _, _, err := cat.DeletePartition(ctx, 1); err != nil {
+		t.Fatalf("DeletePartition() error = %v", err)
+	}
+
+	// Listing agrees with LoadPartition, which rejects the tombstone.
+	if _, err := cat.LoadPartition(ctx, 1); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(deleted) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	for _, req := range []pcatalog.ListPartitionsRequest{{}, {IncludeHeads: true}} {
+		if live := listPartitions(t, cat, req); !slices.Equal(live, []uint32{2}) {
+			t.Fatalf("ListPartitions(%+v) = %v, want only partition 2", req, live)
+		}
+	}
+	all := listPartitions(t, cat, pcatalog.ListPartitionsRequest{IncludeDeleted: true})
+	slices.Sort(all)
+	if !slices.Equal(all, []uint32{1, 2}) {
+		t.Fatalf("ListPartitions(deleted) = %v, want the tombstone until purged", all)
+	}
+
+	if err := cat.MarkPartitionPurged(ctx, 2); !errors.Is(err, pcatalog.ErrConflict) {
+		t.Fatalf("MarkPartitionPurged(live) error = %v, want %v", err, pcatalog.ErrConflict)
+	}
+	if err := cat.MarkPartitionPurged(ctx, 1); err != nil {
+		t.Fatalf("MarkPartitionPurged() error = %v", err)
+	}
+	if all := listPartitions(t, cat, pcatalog.ListPartitionsRequest{IncludeDeleted: true}); !slices.Equal(all, []uint32{2}) {
+		t.Fatalf("ListPartitions(deleted, after purge) = %v, want only partition 2", all)
+	}
+	// The terminal tombstone keeps the partition from being recreated.
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{1}); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("OpenWriter(purged) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+	if _, _, err := cat.InitializePartition(ctx, 1, 0); !errors.Is(err, pcatalog.ErrPartitionDeleted) {
+		t.Fatalf("InitializePartition(purged) error = %v, want %v", err, pcatalog.ErrPartitionDeleted)
+	}
+}
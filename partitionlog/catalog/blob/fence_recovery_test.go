@@ -207,7 +207,7 @@ func TestAppendSegmentRejectsExhaustedGeneration(t *testing.T) {
 		WriterEpoch: 1,
 		WriterID:    [16]byte{1},
 		Generation:  math.MaxUint64,
-	}, "token")
+	}, "token", nil)
 	_, err = ws.AppendSegment(context.Background(), testSegmentRef(1, 0, 9, 1))
 	if !errors.Is(err, pcatalog.ErrGenerationExhausted) {
 		t.Fatalf("AppendSegment() error = %v, want %v", err, pcatalog.ErrGenerationExhausted)
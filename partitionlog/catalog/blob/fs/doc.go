@@ -0,0 +1,2 @@
+// Package fs implements catalog/blob.Backend on top of a local directory.
+package fs
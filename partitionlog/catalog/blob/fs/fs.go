@@ -0,0 +1,21 @@
+package fs
+
+import (
+	"github.com/ankur-anand/unijord/internal/blobstore/fs"
+	"github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+)
+
+type Backend = fs.Backend
+type Options = blob.Options
+
+func New(root string, opts Options) (*blob.Catalog, error) {
+	backend, err := fs.New(root)
+	if err != nil {
+		return nil, err
+	}
+	return blob.New(backend, opts)
+}
+
+func NewBackend(root string) (*Backend, error) {
+	return fs.New(root)
+}
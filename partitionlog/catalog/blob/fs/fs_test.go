@@ -0,0 +1,93 @@
+package fs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/catalog/blob/internal/backendtest"
+)
+
+func TestBackendConformance(t *testing.T) {
+	t.Parallel()
+
+	backendtest.Run(t, backendtest.Config{
+		NewBackend: func(t testing.TB) blob.Backend {
+			t.Helper()
+			backend, err := NewBackend(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewBackend() error = %v", err)
+			}
+			return backend
+		},
+	})
+}
+
+func TestBackendRejectsBadInputs(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New("", Options{}); err == nil {
+		t.Fatal("New(empty root) error = nil, want error")
+	}
+	if _, err := NewBackend(""); err == nil {
+		t.Fatal("NewBackend(empty root) error = nil, want error")
+	}
+	backend, err := NewBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	for _, key := range []string{"../escape", "a//b", "/abs", ".unijord/lock"} {
+		if _, err := backend.Put(context.Background(), key, []byte("x")); err == nil {
+			t.Fatalf("Put(%q) error = nil, want error", key)
+		}
+	}
+}
+
+func TestBackendSurvivesReopen(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	first, err := NewBackend(root)
+	if err != nil {
+		t.Fatalf("NewBackend() error = %v", err)
+	}
+	obj, ok, err := first.CompareAndSwap(ctx, "catalog/head.json", "", []byte(`{"v":1}`))
+	if err != nil || !ok {
+		t.Fatalf("CompareAndSwap(create) = %v, %v", ok, err)
+	}
+
+	second, err := NewBackend(root)
+	if err != nil {
+		t.Fatalf("NewBackend(reopen) error = %v", err)
+	}
+	got, err := second.Get(ctx, "catalog/head.json")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Token != obj.Token {
+		t.Fatalf("Token = %q, want %q", got.Token, obj.Token)
+	}
+	if _, ok, err := second.CompareAndSwap(ctx, "catalog/head.json", obj.Token, []byte(`{"v":2}`)); err != nil || !ok {
+		t.Fatalf("CompareAndSwap(reopened) = %v, %v", ok, err)
+	}
+	if _, ok, err := first.CompareAndSwap(ctx, "catalog/head.json", obj.Token, []byte(`{"v":3}`)); err != nil || ok {
+		t.Fatalf("CompareAndSwap(stale token) = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestNewCatalog(t *testing.T) {
+	t.Parallel()
+
+	cat, err := New(t.TempDir(), Options{Prefix: "catalog-test"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	head, err := cat.LoadPartition(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("LoadPartition() error = %v", err)
+	}
+	if head.Partition != 1 || head.NextLSN != 0 {
+		t.Fatalf("head = %+v, want empty partition 1", head)
+	}
+}
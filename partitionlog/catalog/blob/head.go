@@ -10,9 +10,11 @@ import (
 
 	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 var _ csession.WriterManager = (*Catalog)(nil)
+var _ csession.SigningWriterManager = (*Catalog)(nil)
 var _ csession.WriterSession = (*writerSession)(nil)
 
 func (c *Catalog) InitializePartition(ctx context.Context, partition uint32, nextLSN uint64) (pmeta.PartitionHead, bool, error) {
@@ -47,10 +49,33 @@ func (c *Catalog) InitializePartition(ctx context.Context, partition uint32, nex
 	if err != nil {
 		return pmeta.PartitionHead{}, false, err
 	}
+	if current.Deleted {
+		return pmeta.PartitionHead{}, false, csession.DeletedError(partition, current.Shredded)
+	}
 	return stateFromHead(current), false, nil
 }
 
 func (c *Catalog) OpenWriter(ctx context.Context, partition uint32, writerID [16]byte) (csession.WriterSession, error) {
+	return c.openWriter(ctx, partition, writerID, nil, false)
+}
+
+// OpenSigningWriter opens a writer session whose every head commit, starting
+// with the fencing head, is signed by signer.
+func (c *Catalog) OpenSigningWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer) (csession.WriterSession, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("%w: nil signer", csession.ErrInvalidRequest)
+	}
+	return c.openWriter(ctx, partition, writerID, signer, false)
+}
+
+// OpenIdleWriter opens a writer session only when the head has no writer or
+// its writer released the fence. The check and the fencing CAS read the same
+// head, so a writer that is still open is never fenced out.
+func (c *Catalog) OpenIdleWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer) (csession.WriterSession, error) {
+	return c.openWriter(ctx, partition, writerID, signer, true)
+}
+
+func (c *Catalog) openWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer, idle bool) (csession.WriterSession, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -72,11 +97,19 @@ func (c *Catalog) OpenWriter(ctx context.Context, partition uint32, writerID [16
 
 	for attempt := 0; attempt < c.opts.WriterAcquireMaxAttempts; attempt++ {
 		if !candidateReady {
+			if idle && head.WriterEpoch > 0 && !head.WriterReleased && !head.Deleted && !head.Sealed {
+				return nil, fmt.Errorf("%w: partition=%d epoch=%d", csession.ErrWriterActive, partition, head.WriterEpoch)
+			}
 			candidateBase = head
 			candidate, err = nextWriterHead(head, c.opts.StreamID, partition, writerID)
 			if err != nil {
 				return nil, err
 			}
+
+			candidate, err = signHead(ctx, candidate, signer)
+			if err != nil {
+				return nil, err
+			}
 			candidateBody, err = marshalHead(candidate, c.opts.StreamID, partition)
 			if err != nil {
 				return nil, err
@@ -89,14 +122,14 @@ func (c *Catalog) OpenWriter(ctx context.Context, partition uint32, writerID [16
 		if err != nil {
 			lastCASErr = err
 		} else if swapped {
-			return c.newWriterSession(candidate, obj.Token), nil
+			return c.newWriterSession(candidate, obj.Token, signer), nil
 		} else {
 			current, err := decodeHead(obj.Body, c.opts.StreamID, partition)
 			if err != nil {
 				return nil, err
 			}
 			if sameHeadState(current, candidate) {
-				return c.newWriterSession(candidate, obj.Token), nil
+				return c.newWriterSession(candidate, obj.Token, signer), nil
 			}
 			token = obj.Token
 			if !sameHeadState(current, candidateBase) {
@@ -128,7 +161,7 @@ func (c *Catalog) OpenWriter(ctx context.Context, partition uint32, writerID [16
 		return nil, indeterminateFence(partition, errors.Join(lastCASErr, err))
 	}
 	if candidateReady && sameHeadState(current, candidate) {
-		return c.newWriterSession(candidate, currentToken), nil
+		return c.newWriterSession(candidate, currentToken, signer), nil
 	}
 	if candidateReady && sameHeadState(current, candidateBase) && lastCASErr != nil {
 		return nil, fmt.Errorf("acquire writer fence partition=%d: %w", partition, lastCASErr)
@@ -137,6 +170,12 @@ func (c *Catalog) OpenWriter(ctx context.Context, partition uint32, writerID [16
 }
 
 func nextWriterHead(head headFile, streamID string, partition uint32, writerID [16]byte) (headFile, error) {
+	if head.Deleted {
+		return headFile{}, csession.DeletedError(partition, head.Shredded)
+	}
+	if head.Sealed {
+		return headFile{}, fmt.Errorf("%w: partition=%d", csession.ErrPartitionSealed, partition)
+	}
 	if head.WriterEpoch == math.MaxUint64 {
 		return headFile{}, fmt.Errorf("%w: writer_epoch partition=%d", csession.ErrFenceExhausted, partition)
 	}
@@ -151,6 +190,7 @@ func nextWriterHead(head headFile, streamID string, partition uint32, writerID [
 	next.WriterEpoch++
 	next.WriterID = writerID
 	next.Generation = generation
+	next.WriterReleased = false
 	return next, nil
 }
 
@@ -161,11 +201,12 @@ func nextGeneration(current uint64, partition uint32) (uint64, error) {
 	return current + 1, nil
 }
 
-func (c *Catalog) newWriterSession(head headFile, token string) *writerSession {
+func (c *Catalog) newWriterSession(head headFile, token string, signer signing.Signer) *writerSession {
 	return &writerSession{
 		cat:         c,
 		writerEpoch: head.WriterEpoch,
 		writerID:    head.WriterID,
+		signer:      signer,
 		head:        head,
 		token:       token,
 	}
@@ -242,6 +283,10 @@ func (s *writerSession) appendSegmentLocked(ctx context.Context, segment pmeta.S
 	next.LastSegment = segment
 	next.HasLastSegment = true
 	next.SegmentCount++
+	next.RetainedBytes += segment.SizeBytes
+	next.RetainedBytesExact = head.RetainedBytesExact || head.OldestLSN == head.NextLSN
+	next.Producers = stateFromHead(head).ProducerState().Merge(segment.Producers)
+	next.Tree = csession.AppendSegmentTree(head.Tree, segment)
 	next.IndexFrontier = pages.IndexFrontier
 	next.LeafFrontier = pages.LeafFrontier
 	next.ActiveSegments = pages.ActiveSegments
@@ -249,6 +294,9 @@ func (s *writerSession) appendSegmentLocked(ctx context.Context, segment pmeta.S
 		next.MaxIndexLevel = level
 	}
 	next.Generation = generation
+	if next, err = signHead(ctx, next, s.signer); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
 
 	body, err := marshalHead(next, s.cat.opts.StreamID, head.Partition)
 	if err != nil {
@@ -290,6 +338,14 @@ func (s *writerSession) commitSegmentHead(ctx context.Context, previous, next he
 			if observation == commitApplied {
 				return s.acceptObservedCommit(next, current, obj.Token), nil
 			}
+			if current.HoldVersion != next.HoldVersion {
+				// A legal hold placement moved the token; carry it forward.
+				previous.HoldVersion = current.HoldVersion
+				next.HoldVersion = current.HoldVersion
+				if body, err = marshalHead(next, s.cat.opts.StreamID, next.Partition); err != nil {
+					return pmeta.PartitionHead{}, err
+				}
+			}
 			expectedToken = obj.Token
 			if attempt+1 == s.cat.opts.WriterCommitMaxAttempts {
 				if lastCASErr != nil {
@@ -332,7 +388,7 @@ func (s *writerSession) observeSegmentCommit(ctx context.Context, previous, curr
 	if current.HasLastSegment && current.LastSegment == segment {
 		return commitApplied, nil
 	}
-	if sameHeadState(current, previous) {
+	if sameHeadStateExceptHold(current, previous) {
 		return commitNeedsRetry, nil
 	}
 	if current.NextLSN >= segment.NextLSN() {
@@ -380,9 +436,22 @@ func sameHeadState(a, b headFile) bool {
 		a.WriterEpoch != b.WriterEpoch ||
 		a.WriterID != b.WriterID ||
 		a.SegmentCount != b.SegmentCount ||
+		a.RetainedBytes != b.RetainedBytes ||
+		a.Producers != b.Producers ||
+		!a.Tree.Equal(b.Tree) ||
+		!a.TreeBase.Equal(b.TreeBase) ||
 		a.LastSegment != b.LastSegment ||
 		a.HasLastSegment != b.HasLastSegment ||
 		a.Generation != b.Generation ||
+		a.Sealed != b.Sealed ||
+		a.Deleted != b.Deleted ||
+		a.Shredded != b.Shredded ||
+		a.Purged != b.Purged ||
+		a.RetainedBytesExact != b.RetainedBytesExact ||
+		a.HoldVersion != b.HoldVersion ||
+		a.WriterReleased != b.WriterReleased ||
+		a.SignerKeyID != b.SignerKeyID ||
+		a.Signature != b.Signature ||
 		len(a.IndexFrontier) != len(b.IndexFrontier) ||
 		len(a.ActiveSegments) != len(b.ActiveSegments) {
 		return false
@@ -406,6 +475,13 @@ func sameHeadState(a, b headFile) bool {
 	return true
 }
 
+// sameHeadStateExceptHold reports whether a and b differ at most in
+// HoldVersion.
+func sameHeadStateExceptHold(a, b headFile) bool {
+	a.HoldVersion = b.HoldVersion
+	return sameHeadState(a, b)
+}
+
 func indeterminateCommit(partition uint32, cause error) error {
 	if cause == nil {
 		return fmt.Errorf("%w: partition=%d", csession.ErrCommitIndeterminate, partition)
@@ -442,6 +518,19 @@ func (c *Catalog) loadHead(ctx context.Context, partition uint32) (headFile, str
 	return head, obj.Token, nil
 }
 
+// loadVisibleHead loads the head for reader APIs, which must not observe a
+// tombstoned partition.
+func (c *Catalog) loadVisibleHead(ctx context.Context, partition uint32) (headFile, error) {
+	head, _, err := c.loadHead(ctx, partition)
+	if err != nil {
+		return headFile{}, err
+	}
+	if head.Deleted {
+		return headFile{}, csession.DeletedError(partition, head.Shredded)
+	}
+	return head, nil
+}
+
 func decodeHead(body []byte, streamID string, partition uint32) (headFile, error) {
 	var head headFile
 	if err := json.Unmarshal(body, &head); err != nil {
@@ -468,6 +557,12 @@ func validateAppend(head headFile, segment pmeta.SegmentRef) error {
 	if head.WriterEpoch == 0 || head.WriterID == ([16]byte{}) {
 		return fmt.Errorf("%w: writer fence not acquired", csession.ErrStaleWriter)
 	}
+	if head.Deleted {
+		return csession.DeletedError(head.Partition, head.Shredded)
+	}
+	if head.Sealed {
+		return fmt.Errorf("%w: partition=%d", csession.ErrPartitionSealed, head.Partition)
+	}
 	if segment.Partition != head.Partition {
 		return fmt.Errorf("%w: head partition=%d segment partition=%d", csession.ErrInvalidRequest, head.Partition, segment.Partition)
 	}
@@ -489,7 +584,7 @@ func validateAppend(head headFile, segment pmeta.SegmentRef) error {
 	if last, ok := stateFromHead(head).Last(); ok && segment.MinTimestampMS < last.MaxTimestampMS {
 		return fmt.Errorf("%w: segment min_ts=%d previous max_ts=%d", csession.ErrTimestampOrder, segment.MinTimestampMS, last.MaxTimestampMS)
 	}
-	return nil
+	return csession.ValidateSegmentChain(head.LastSegment.ChainHash, segment)
 }
 
 func idempotentHeadRetry(head headFile, segment pmeta.SegmentRef) (pmeta.PartitionHead, bool) {
@@ -515,11 +610,46 @@ func stateFromHead(head headFile) pmeta.PartitionHead {
 		AppliedRetentionVersion: head.AppliedRetentionVersion,
 		WriterEpoch:             head.WriterEpoch,
 		SegmentCount:            head.SegmentCount,
+		RetainedBytes:           head.RetainedBytes,
 		LastSegment:             head.LastSegment,
 		HasLastSegment:          head.HasLastSegment,
+		Sealed:                  head.Sealed,
+		TreeSize:                head.Tree.Size,
+		TreeRoot:                head.Tree.Root(),
+		Producers:               head.Producers,
+		SignerKeyID:             head.SignerKeyID,
+		Signature:               head.Signature,
 	}
 }
 
+// requireSigner refuses an unsigned administrative commit over a signed head,
+// which would strip the signature that verifying readers rely on. Writer
+// takeovers are not checked: a new owner chooses its own signer.
+func requireSigner(head headFile, signer signing.Signer) error {
+	if signer == nil && head.SignerKeyID != "" {
+		return fmt.Errorf("%w: head partition=%d is signed by key %q", csession.ErrSignerRequired, head.Partition, head.SignerKeyID)
+	}
+	return nil
+}
+
+// signHead signs head with signer, or clears an earlier committer's signature
+// when signer is nil, so a committed head is never signed for state its
+// signer did not see.
+func signHead(ctx context.Context, head headFile, signer signing.Signer) (headFile, error) {
+	head.SignerKeyID = ""
+	head.Signature = pmeta.Signature{}
+	if signer == nil {
+		return head, nil
+	}
+	signed, err := signing.SignHead(ctx, signer, stateFromHead(head))
+	if err != nil {
+		return headFile{}, err
+	}
+	head.SignerKeyID = signed.SignerKeyID
+	head.Signature = signed.Signature
+	return head, nil
+}
+
 func sleepBackoff(ctx context.Context, d time.Duration) error {
 	if d <= 0 {
 		return ctx.Err()
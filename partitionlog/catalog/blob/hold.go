@@ -0,0 +1,203 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+var _ csession.LegalHoldManager = (*Catalog)(nil)
+
+const holdVersion uint16 = 1
+
+// holdFile is one legal hold object. Partition is unset for the stream hold.
+type holdFile struct {
+	Version       uint16  `json:"version"`
+	StreamID      string  `json:"stream_id,omitempty"`
+	Partition     *uint32 `json:"partition,omitempty"`
+	Active        bool    `json:"active"`
+	Reason        string  `json:"reason,omitempty"`
+	UpdatedUnixMS int64   `json:"updated_unix_ms"`
+}
+
+// SetPartitionHold stores the legal hold of one partition. Storing a hold
+// with the same Active and Reason as the current one keeps the current one.
+// Placing a hold bumps the partition head, so a delete or retention apply
+// that checked the holds before it fails its head CAS and checks again.
+func (c *Catalog) SetPartitionHold(ctx context.Context, partition uint32, hold csession.LegalHold) (csession.LegalHold, error) {
+	return c.setHold(ctx, PartitionHoldPath(c.opts.Prefix, c.opts.StreamID, partition), &partition, hold)
+}
+
+// SetStreamHold stores the legal hold that covers every partition of the
+// stream. Placing it bumps the head of every partition.
+func (c *Catalog) SetStreamHold(ctx context.Context, hold csession.LegalHold) (csession.LegalHold, error) {
+	return c.setHold(ctx, StreamHoldPath(c.opts.Prefix, c.opts.StreamID), nil, hold)
+}
+
+// LoadLegalHolds reads the partition and stream holds that cover partition.
+func (c *Catalog) LoadLegalHolds(ctx context.Context, partition uint32) (csession.LegalHolds, error) {
+	if err := ctx.Err(); err != nil {
+		return csession.LegalHolds{}, err
+	}
+	partitionHold, _, err := c.loadHold(ctx, PartitionHoldPath(c.opts.Prefix, c.opts.StreamID, partition), &partition)
+	if err != nil {
+		return csession.LegalHolds{}, err
+	}
+	streamHold, _, err := c.loadHold(ctx, StreamHoldPath(c.opts.Prefix, c.opts.StreamID), nil)
+	if err != nil {
+		return csession.LegalHolds{}, err
+	}
+	return csession.LegalHolds{Partition: partitionHold, Stream: streamHold}, nil
+}
+
+func (c *Catalog) setHold(ctx context.Context, path string, partition *uint32, hold csession.LegalHold) (csession.LegalHold, error) {
+	if err := ctx.Err(); err != nil {
+		return csession.LegalHold{}, err
+	}
+	stored, err := c.storeHold(ctx, path, partition, hold)
+	if err != nil || !stored.Active {
+		return stored, err
+	}
+	// A stored hold that is already active is fenced again, which completes
+	// an earlier placement that failed before its fence.
+	if partition != nil {
+		err = c.fenceHold(ctx, *partition)
+	} else {
+		err = c.fenceStreamHold(ctx)
+	}
+	if err != nil {
+		return csession.LegalHold{}, err
+	}
+	return stored, nil
+}
+
+func (c *Catalog) storeHold(ctx context.Context, path string, partition *uint32, hold csession.LegalHold) (csession.LegalHold, error) {
+	body, err := json.Marshal(holdFile{
+		Version:       holdVersion,
+		StreamID:      c.opts.StreamID,
+		Partition:     partition,
+		Active:        hold.Active,
+		Reason:        hold.Reason,
+		UpdatedUnixMS: hold.UpdatedUnixMS,
+	})
+	if err != nil {
+		return csession.LegalHold{}, err
+	}
+
+	backoff := c.opts.WriterCommitInitialBackoff
+	var lastCASErr error
+	for attempt := 0; attempt < c.opts.WriterCommitMaxAttempts; attempt++ {
+		current, token, err := c.loadHold(ctx, path, partition)
+		if err != nil {
+			return csession.LegalHold{}, errors.Join(lastCASErr, err)
+		}
+		if current.Active == hold.Active && current.Reason == hold.Reason {
+			return current, nil
+		}
+		_, swapped, casErr := c.backend.CompareAndSwap(ctx, path, token, body)
+		if casErr == nil && swapped {
+			return hold, nil
+		}
+		lastCASErr = casErr
+
+		if attempt+1 == c.opts.WriterCommitMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return csession.LegalHold{}, errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, c.opts.WriterCommitMaxBackoff)
+	}
+	if lastCASErr != nil {
+		return csession.LegalHold{}, fmt.Errorf("set legal hold %s: %w", path, lastCASErr)
+	}
+	return csession.LegalHold{}, fmt.Errorf("%w: legal hold contention %s", csession.ErrConflict, path)
+}
+
+// loadHold returns an inactive hold with an empty token when no hold object
+// exists.
+func (c *Catalog) loadHold(ctx context.Context, path string, partition *uint32) (csession.LegalHold, string, error) {
+	obj, err := c.backend.Get(ctx, path)
+	if errors.Is(err, ErrObjectNotFound) {
+		return csession.LegalHold{}, "", nil
+	}
+	if err != nil {
+		return csession.LegalHold{}, "", err
+	}
+	var file holdFile
+	if err := json.Unmarshal(obj.Body, &file); err != nil {
+		return csession.LegalHold{}, "", fmt.Errorf("%w: decode legal hold %s: %v", ErrCorruptCatalog, path, err)
+	}
+	if file.Version != holdVersion {
+		return csession.LegalHold{}, "", fmt.Errorf("%w: legal hold version=%d", ErrCorruptCatalog, file.Version)
+	}
+	if file.StreamID != c.opts.StreamID {
+		return csession.LegalHold{}, "", fmt.Errorf("%w: legal hold stream_id=%q want=%q", ErrCorruptCatalog, file.StreamID, c.opts.StreamID)
+	}
+	if (file.Partition == nil) != (partition == nil) || (partition != nil && *file.Partition != *partition) {
+		return csession.LegalHold{}, "", fmt.Errorf("%w: legal hold %s has wrong scope", ErrCorruptCatalog, path)
+	}
+	return csession.LegalHold{Active: file.Active, Reason: file.Reason, UpdatedUnixMS: file.UpdatedUnixMS}, obj.Token, nil
+}
+
+// fenceHold bumps HoldVersion in the head of partition. A head change after
+// the load moves the token just as well, so losing the CAS race ends the
+// fence too.
+func (c *Catalog) fenceHold(ctx context.Context, partition uint32) error {
+	path := HeadPath(c.opts.Prefix, c.opts.StreamID, partition)
+	backoff := c.opts.WriterCommitInitialBackoff
+	var lastCASErr error
+	for attempt := 0; attempt < c.opts.WriterCommitMaxAttempts; attempt++ {
+		head, token, err := c.loadHead(ctx, partition)
+		if err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		if token == "" || head.Deleted {
+			return nil
+		}
+		next := head
+		next.HoldVersion++
+		body, err := marshalHead(next, c.opts.StreamID, partition)
+		if err != nil {
+			return err
+		}
+		_, _, casErr := c.backend.CompareAndSwap(ctx, path, token, body)
+		if casErr == nil {
+			return nil
+		}
+		lastCASErr = casErr
+
+		if attempt+1 == c.opts.WriterCommitMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, c.opts.WriterCommitMaxBackoff)
+	}
+	return fmt.Errorf("fence legal hold partition=%d: %w", partition, lastCASErr)
+}
+
+// fenceStreamHold fences every partition of the stream. A partition created
+// after the listing reads the stored hold on its first delete or apply.
+func (c *Catalog) fenceStreamHold(ctx context.Context) error {
+	req := csession.ListPartitionsRequest{IncludeDeleted: true}
+	for {
+		page, err := c.ListPartitions(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, info := range page.Partitions {
+			if err := c.fenceHold(ctx, info.Partition); err != nil {
+				return err
+			}
+		}
+		if !page.HasMore {
+			return nil
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+}
@@ -0,0 +1,227 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+func TestBlobCatalogLegalHoldBlocksRetentionAndDelete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for base := uint64(0); base < 40; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	retention := ws.(pcatalog.RetentionWriterSession)
+
+	hold, err := cat.SetPartitionHold(ctx, 1, pcatalog.LegalHold{Active: true, Reason: "case-7", UpdatedUnixMS: 10})
+	if err != nil || !hold.Active {
+		t.Fatalf("SetPartitionHold() = %+v, %v", hold, err)
+	}
+	again, err := cat.SetPartitionHold(ctx, 1, pcatalog.LegalHold{Active: true, Reason: "case-7", UpdatedUnixMS: 20})
+	if err != nil || again.UpdatedUnixMS != 10 {
+		t.Fatalf("SetPartitionHold(again) = %+v, %v; want stored hold", again, err)
+	}
+	if _, err := retention.ApplyPendingRetention(ctx); err != nil {
+		t.Fatalf("ApplyPendingRetention(no request) error = %v", err)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, pcatalog.RetentionRequest{
+		Version: pcatalog.RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 20,
+	}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	if _, err := retention.ApplyPendingRetention(ctx); !errors.Is(err, pcatalog.ErrLegalHold) {
+		t.Fatalf("ApplyPendingRetention(held) error = %v, want %v", err, pcatalog.ErrLegalHold)
+	}
+	if _, _, err := cat.DeletePartition(ctx, 1); !errors.Is(err, pcatalog.ErrLegalHold) {
+		t.Fatalf("DeletePartition(held) error = %v, want %v", err, pcatalog.ErrLegalHold)
+	}
+	snapshot, err := cat.LoadMaintenanceSnapshot(ctx, 1)
+	if err != nil || !snapshot.Held || snapshot.Head.OldestLSN != 0 {
+		t.Fatalf("LoadMaintenanceSnapshot() = %+v, %v; want held head", snapshot, err)
+	}
+
+	// A stream hold still covers the partition after its own hold clears.
+	if _, err := cat.SetStreamHold(ctx, pcatalog.LegalHold{Active: true, Reason: "litigation"}); err != nil {
+		t.Fatalf("SetStreamHold() error = %v", err)
+	}
+	if _, err := cat.SetPartitionHold(ctx, 1, pcatalog.LegalHold{Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold(clear) error = %v", err)
+	}
+	holds, err := cat.LoadLegalHolds(ctx, 1)
+	if err != nil || holds.Partition.Active || holds.Partition.Reason != "case-7" || !holds.Stream.Active || !holds.Active() {
+		t.Fatalf("LoadLegalHolds() = %+v, %v", holds, err)
+	}
+	if _, err := retention.ApplyPendingRetention(ctx); !errors.Is(err, pcatalog.ErrLegalHold) {
+		t.Fatalf("ApplyPendingRetention(stream held) error = %v, want %v", err, pcatalog.ErrLegalHold)
+	}
+	other, err := cat.LoadLegalHolds(ctx, 2)
+	if err != nil || other.Partition.Active || !other.Stream.Active {
+		t.Fatalf("LoadLegalHolds(other) = %+v, %v", other, err)
+	}
+
+	if _, err := cat.SetStreamHold(ctx, pcatalog.LegalHold{Reason: "litigation"}); err != nil {
+		t.Fatalf("SetStreamHold(clear) error = %v", err)
+	}
+	result, err := retention.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.OldestLSN != 20 {
+		t.Fatalf("ApplyPendingRetention(released) = %+v, %v", result, err)
+	}
+	if _, ok, err := cat.DeletePartition(ctx, 1); err != nil || !ok {
+		t.Fatalf("DeletePartition(released) = %v, %v", ok, err)
+	}
+}
+
+func TestBlobCatalogLegalHoldPathsStayOutsidePartitionListing(t *testing.T) {
+	t.Parallel()
+
+	if got, want := PartitionHoldPath("", "audit", 7), RetentionRequestPath("", "audit", 7); path.Dir(got) != path.Dir(want) {
+		t.Fatalf("PartitionHoldPath() = %q, want beside %q", got, want)
+	}
+	if got := StreamHoldPath("", "audit"); strings.HasPrefix(got, partitionPrefix("", "audit", 0)) {
+		t.Fatalf("StreamHoldPath() = %q, want outside partition buckets", got)
+	}
+	if StreamHoldPath("", "audit") == StreamHoldPath("", "billing") {
+		t.Fatalf("StreamHoldPath() is shared across streams")
+	}
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{StreamID: "audit"})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	if _, _, err := cat.InitializePartition(ctx, 3, 0); err != nil {
+		t.Fatalf("InitializePartition() error = %v", err)
+	}
+	if _, err := cat.SetStreamHold(ctx, pcatalog.LegalHold{Active: true}); err != nil {
+		t.Fatalf("SetStreamHold() error = %v", err)
+	}
+	if _, err := cat.SetPartitionHold(ctx, 3, pcatalog.LegalHold{Active: true}); err != nil {
+		t.Fatalf("SetPartitionHold() error = %v", err)
+	}
+	var partitions []uint32
+	req := pcatalog.ListPartitionsRequest{}
+	for {
+		page, err := cat.ListPartitions(ctx, req)
+		if err != nil {
+			t.Fatalf("ListPartitions() error = %v", err)
+		}
+		for _, info := range page.Partitions {
+			partitions = append(partitions, info.Partition)
+		}
+		if !page.HasMore {
+			break
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+	if len(partitions) != 1 || partitions[0] != 3 {
+		t.Fatalf("listed partitions = %v, want [3]", partitions)
+	}
+}
+
+func TestBlobCatalogHoldPlacedBeforeHeadCASStopsDeleteAndRetention(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := &holdRaceBackend{Backend: NewMemoryBackend()}
+	cat, err := New(backend, Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for base := uint64(0); base < 20; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	headPath := HeadPath("", "", 1)
+
+	// The hold lands after DeletePartition checked the holds and before its
+	// head CAS.
+	backend.beforeCAS(headPath, func() {
+		if _, err := cat.SetPartitionHold(ctx, 1, pcatalog.LegalHold{Active: true, Reason: "case-7"}); err != nil {
+			t.Errorf("SetPartitionHold() error = %v", err)
+		}
+	})
+	if _, _, err := cat.DeletePartition(ctx, 1); !errors.Is(err, pcatalog.ErrLegalHold) {
+		t.Fatalf("DeletePartition(raced hold) error = %v, want %v", err, pcatalog.ErrLegalHold)
+	}
+	// The live writer carries the bumped head forward.
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 20, 29, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment(after hold) error = %v", err)
+	}
+	if _, err := cat.SetPartitionHold(ctx, 1, pcatalog.LegalHold{Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold(clear) error = %v", err)
+	}
+
+	if _, err := cat.RequestRetention(ctx, 1, pcatalog.RetentionRequest{
+		Version: pcatalog.RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 20,
+	}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	backend.beforeCAS(headPath, func() {
+		if _, err := cat.SetStreamHold(ctx, pcatalog.LegalHold{Active: true, Reason: "litigation"}); err != nil {
+			t.Errorf("SetStreamHold() error = %v", err)
+		}
+	})
+	retention := ws.(pcatalog.RetentionWriterSession)
+	if result, err := retention.ApplyPendingRetention(ctx); err == nil || result.Applied {
+		t.Fatalf("ApplyPendingRetention(raced hold) = %+v, %v; want refused", result, err)
+	}
+	if _, err := retention.ApplyPendingRetention(ctx); !errors.Is(err, pcatalog.ErrLegalHold) {
+		t.Fatalf("ApplyPendingRetention(held) error = %v, want %v", err, pcatalog.ErrLegalHold)
+	}
+	head, err := cat.LoadPartition(ctx, 1)
+	if err != nil || head.OldestLSN != 0 || head.NextLSN != 30 {
+		t.Fatalf("LoadPartition() = %+v, %v; want untouched history", head, err)
+	}
+}
+
+// holdRaceBackend runs a callback once, just before the next CAS of one key.
+type holdRaceBackend struct {
+	Backend
+
+	mu     sync.Mutex
+	key    string
+	before func()
+}
+
+func (b *holdRaceBackend) beforeCAS(key string, before func()) {
+	b.mu.Lock()
+	b.key = key
+	b.before = before
+	b.mu.Unlock()
+}
+
+func (b *holdRaceBackend) CompareAndSwap(ctx context.Context, key string, expectedToken string, body []byte) (Object, bool, error) {
+	b.mu.Lock()
+	var before func()
+	if key == b.key {
+		before = b.before
+		b.before = nil
+	}
+	b.mu.Unlock()
+	if before != nil {
+		before()
+	}
+	return b.Backend.CompareAndSwap(ctx, key, expectedToken, body)
+}
@@ -14,6 +14,14 @@ type MaintenanceSnapshot struct {
 	Head          pmeta.PartitionHead
 	Generation    uint64
 	MaxIndexLevel uint8
+	// Deleted reports a partition tombstone. Every object of the partition is
+	// garbage once the tombstone has aged past the lifecycle delete delay.
+	Deleted bool
+	// Purged reports a tombstone whose partition objects were all removed.
+	Purged bool
+	// Held reports an active partition or stream legal hold. Lifecycle workers
+	// delete nothing while it is set.
+	Held bool
 }
 
 // MaintenancePageRequest selects a bounded ordered slice of reachable catalog
@@ -185,7 +193,7 @@ func (c *Catalog) pagePathReachable(ctx context.Context, ref pageRef, target Pag
 }
 
 // LoadMaintenanceSnapshot reads and validates the authoritative partition
-// head for physical lifecycle decisions.
+// head and the legal holds that cover it for physical lifecycle decisions.
 func (c *Catalog) LoadMaintenanceSnapshot(ctx context.Context, partition uint32) (MaintenanceSnapshot, error) {
 	if err := ctx.Err(); err != nil {
 		return MaintenanceSnapshot{}, err
@@ -194,9 +202,16 @@ func (c *Catalog) LoadMaintenanceSnapshot(ctx context.Context, partition uint32)
 	if err != nil {
 		return MaintenanceSnapshot{}, err
 	}
+	holds, err := c.LoadLegalHolds(ctx, partition)
+	if err != nil {
+		return MaintenanceSnapshot{}, err
+	}
 	return MaintenanceSnapshot{
 		Head:          stateFromHead(head),
 		Generation:    head.Generation,
 		MaxIndexLevel: head.MaxIndexLevel,
+		Deleted:       head.Deleted,
+		Purged:        head.Purged,
+		Held:          holds.Active(),
 	}, nil
 }
@@ -0,0 +1,128 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+)
+
+// partitionListCallBudget bounds backend List calls for one page so a sparse
+// stream cannot turn a single call into a walk of every layout bucket.
+const partitionListCallBudget = 256
+
+var _ csession.PartitionLister = (*Catalog)(nil)
+
+// ListPartitions discovers partitions from their head.json keys. Heads are
+// spread over layout buckets, so partitions are returned bucket by bucket and
+// in numeric order within a bucket. After each head the listing resumes past
+// the rest of that partition's keys, so catalog pages are never enumerated.
+// Each head is read to skip deleted partitions the way LoadPartition does.
+func (c *Catalog) ListPartitions(ctx context.Context, req csession.ListPartitionsRequest) (csession.PartitionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return csession.PartitionPage{}, err
+	}
+	bucket, after, err := c.parsePartitionCursor(req.AfterKey)
+	if err != nil {
+		return csession.PartitionPage{}, err
+	}
+	limit := req.NormalizedLimit()
+
+	var page csession.PartitionPage
+	for calls := 0; bucket < keylayout.BucketCount; {
+		prefix := BucketPartitionPrefix(c.opts.Prefix, c.opts.StreamID, formatBucket(bucket))
+		if after == "" {
+			after = prefix
+		}
+		if len(page.Partitions) == limit || calls == partitionListCallBudget {
+			page.NextAfterKey = after
+			page.HasMore = true
+			return page, nil
+		}
+		calls++
+		objects, err := c.backend.List(ctx, ListOptions{
+			Prefix:   prefix,
+			AfterKey: after,
+			Limit:    min(limit-len(page.Partitions), MaxObjectListLimit),
+		})
+		if err != nil {
+			return csession.PartitionPage{}, fmt.Errorf("list partitions bucket=%s: %w", formatBucket(bucket), err)
+		}
+
+		exhausted := !objects.HasMore
+		for i, object := range objects.Objects {
+			if object.Key <= after {
+				continue
+			}
+			partition, skip, err := parseBucketPartitionKey(prefix, object.Key)
+			if err != nil {
+				return csession.PartitionPage{}, err
+			}
+			after = skip
+			if object.Key != HeadPath(c.opts.Prefix, c.opts.StreamID, partition) {
+				continue
+			}
+			head, token, err := c.loadHead(ctx, partition)
+			if err != nil {
+				return csession.PartitionPage{}, err
+			}
+			if token == "" || head.Purged || (head.Deleted && !req.IncludeDeleted) {
+				continue
+			}
+			info := csession.PartitionInfo{Partition: partition}
+			if req.IncludeHeads {
+				info.Head = stateFromHead(head)
+			}
+			page.Partitions = append(page.Partitions, info)
+			if len(page.Partitions) == limit {
+				exhausted = exhausted && i == len(objects.Objects)-1
+				break
+			}
+		}
+		if exhausted {
+			bucket++
+			after = ""
+		}
+	}
+	return page, nil
+}
+
+func (c *Catalog) parsePartitionCursor(cursor string) (int, string, error) {
+	if cursor == "" {
+		return 0, "", nil
+	}
+	root := normalizePrefix(c.opts.Prefix) + "/"
+	rest, ok := strings.CutPrefix(cursor, root)
+	if ok && len(rest) >= keylayout.BucketHexLen {
+		text := rest[:keylayout.BucketHexLen]
+		bucket, err := strconv.ParseUint(text, 16, 32)
+		if err == nil && text == formatBucket(int(bucket)) && bucket < keylayout.BucketCount &&
+			strings.HasPrefix(cursor, BucketPartitionPrefix(c.opts.Prefix, c.opts.StreamID, text)) {
+			return int(bucket), cursor, nil
+		}
+	}
+	return 0, "", fmt.Errorf("%w: partition cursor %q is outside stream %q", csession.ErrInvalidRequest, cursor, c.opts.StreamID)
+}
+
+// parseBucketPartitionKey returns the partition that owns key and a synthetic
+// key that sorts after every key of that partition and before the next one.
+func parseBucketPartitionKey(prefix string, key string) (uint32, string, error) {
+	relative, _ := strings.CutPrefix(key, prefix)
+	digits, _, ok := strings.Cut(relative, "/")
+	if !ok || len(digits) < 8 || (len(digits) > 8 && digits[0] == '0') {
+		return 0, "", fmt.Errorf("%w: unexpected partition key %q", ErrCorruptCatalog, key)
+	}
+	partition, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: unexpected partition key %q", ErrCorruptCatalog, key)
+	}
+	// '0' sorts after '/', and partition numbers never gain a leading zero.
+	return uint32(partition), prefix + digits + "0", nil
+}
+
+func formatBucket(bucket int) string {
+	return fmt.Sprintf("%0*x", keylayout.BucketHexLen, bucket)
+}
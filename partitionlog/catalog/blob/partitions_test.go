@@ -0,0 +1,190 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+)
+
+func TestListPartitionsWalksBucketsAndSkipsPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	cat := newPartitionListCatalog(t, backend, "hosts/a")
+	other := newPartitionListCatalog(t, backend, "hosts/b")
+	unnamed := newPartitionListCatalog(t, backend, "")
+
+	want := []uint32{1, 2, 7, 99, 100_000_000}
+	for _, partition := range want {
+		appendPartitionSegments(t, cat, partition, 5)
+	}
+	appendPartitionSegments(t, other, 3, 1)
+	appendPartitionSegments(t, unnamed, 4, 1)
+
+	var got []uint32
+	after := ""
+	for pages := 0; ; pages++ {
+		if pages > len(want)+emptyWalkPages() {
+			t.Fatalf("ListPartitions() did not terminate, got %v", got)
+		}
+		page, err := cat.ListPartitions(ctx, pcatalog.ListPartitionsRequest{AfterKey: after, Limit: 2, IncludeHeads: true})
+		if err != nil {
+			t.Fatalf("ListPartitions(after=%q) error = %v", after, err)
+		}
+		if len(page.Partitions) > 2 {
+			t.Fatalf("ListPartitions() returned %d partitions, limit 2", len(page.Partitions))
+		}
+		for _, info := range page.Partitions {
+			if info.Head.Partition != info.Partition || info.Head.StreamID != "hosts/a" || info.Head.NextLSN != 50 {
+				t.Fatalf("partition %d head = %+v", info.Partition, info.Head)
+			}
+			got = append(got, info.Partition)
+		}
+		if !page.HasMore {
+			break
+		}
+		after = page.NextAfterKey
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("ListPartitions() partitions = %v, want %v", got, want)
+	}
+
+	for _, tc := range []struct {
+		cat  *Catalog
+		want []uint32
+	}{
+		{cat: other, want: []uint32{3}},
+		{cat: unnamed, want: []uint32{4}},
+	} {
+		got := listAllPartitions(t, tc.cat)
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("ListPartitions(stream=%q) = %v, want %v", tc.cat.opts.StreamID, got, tc.want)
+		}
+	}
+}
+
+func TestListPartitionsEmptyStreamIsBounded(t *testing.T) {
+	t.Parallel()
+
+	backend := &countingListBackend{Backend: NewMemoryBackend()}
+	cat, err := New(backend, Options{StreamID: "empty"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	page, err := cat.ListPartitions(context.Background(), pcatalog.ListPartitionsRequest{})
+	if err != nil {
+		t.Fatalf("ListPartitions() error = %v", err)
+	}
+	if len(page.Partitions) != 0 || !page.HasMore || backend.lists != partitionListCallBudget {
+		t.Fatalf("first page = %+v after %d list calls, want empty continuation after %d", page, backend.lists, partitionListCallBudget)
+	}
+	if got := listAllPartitions(t, cat); len(got) != 0 {
+		t.Fatalf("ListPartitions(empty) = %v", got)
+	}
+}
+
+func TestListPartitionsRejectsForeignCursor(t *testing.T) {
+	t.Parallel()
+
+	backend := NewMemoryBackend()
+	cat := newPartitionListCatalog(t, backend, "hosts/a")
+	for _, cursor := range []string{
+		"elsewhere/000/p",
+		BucketPartitionPrefix(cat.opts.Prefix, "hosts/b", "000"),
+		"catalog/zzz/streams/x/p",
+	} {
+		_, err := cat.ListPartitions(context.Background(), pcatalog.ListPartitionsRequest{AfterKey: cursor})
+		if !errors.Is(err, pcatalog.ErrInvalidRequest) {
+			t.Fatalf("ListPartitions(cursor=%q) error = %v, want %v", cursor, err, pcatalog.ErrInvalidRequest)
+		}
+	}
+}
+
+func TestParseBucketPartitionKeySkipsWholePartition(t *testing.T) {
+	t.Parallel()
+
+	prefix := BucketPartitionPrefix("catalog", "s", "000")
+	partition, skip, err := parseBucketPartitionKey(prefix, prefix+"00000007/head.json")
+	if err != nil || partition != 7 {
+		t.Fatalf("parseBucketPartitionKey() = %d, %v", partition, err)
+	}
+	if page := prefix + "00000007/pages/l00/leaf.json"; page >= skip {
+		t.Fatalf("skip key %q does not sort after page %q", skip, page)
+	}
+	if next := prefix + "00000008/head.json"; next <= skip {
+		t.Fatalf("skip key %q sorts after next partition %q", skip, next)
+	}
+	for _, key := range []string{prefix + "7/head.json", prefix + "000000007/head.json", prefix + "0000000x/head.json", prefix + "00000007"} {
+		if _, _, err := parseBucketPartitionKey(prefix, key); !errors.Is(err, ErrCorruptCatalog) {
+			t.Fatalf("parseBucketPartitionKey(%q) error = %v, want %v", key, err, ErrCorruptCatalog)
+		}
+	}
+}
+
+type countingListBackend struct {
+	Backend
+	lists int
+}
+
+func (b *countingListBackend) List(ctx context.Context, opts ListOptions) (ObjectPage, error) {
+	b.lists++
+	return b.Backend.List(ctx, opts)
+}
+
+func newPartitionListCatalog(t *testing.T, backend Backend, streamID string) *Catalog {
+	t.Helper()
+	cat, err := New(backend, Options{StreamID: streamID, LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return cat
+}
+
+func appendPartitionSegments(t *testing.T, cat *Catalog, partition uint32, segments int) {
+	t.Helper()
+	ws, err := cat.OpenWriter(context.Background(), partition, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+	}
+	for i := range uint64(segments) {
+		segment := testSegmentRef(partition, i*10, i*10+9, ws.Epoch())
+		segment.StreamID = cat.opts.StreamID
+		if _, err := ws.AppendSegment(context.Background(), segment); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", partition, err)
+		}
+	}
+}
+
+func listAllPartitions(t *testing.T, cat *Catalog) []uint32 {
+	t.Helper()
+	return listPartitions(t, cat, pcatalog.ListPartitionsRequest{})
+}
+
+// listPartitions walks every page of req.
+func listPartitions(t *testing.T, cat *Catalog, req pcatalog.ListPartitionsRequest) []uint32 {
+	t.Helper()
+	var out []uint32
+	for {
+		page, err := cat.ListPartitions(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ListPartitions(%+v) error = %v", req, err)
+		}
+		for _, info := range page.Partitions {
+			out = append(out, info.Partition)
+		}
+		if !page.HasMore {
+			return out
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+}
+
+func emptyWalkPages() int {
+	return keylayout.BucketCount/partitionListCallBudget + 1
+}
@@ -162,6 +162,22 @@ func HeadPath(prefix string, streamID string, partition uint32) string {
 	return fmt.Sprintf("%s/head.json", partitionPrefix(prefix, streamID, partition))
 }
 
+// BucketPartitionPrefix returns the key prefix shared by every partition of
+// streamID stored in bucket. Keys below it begin with the partition number.
+func BucketPartitionPrefix(prefix string, streamID string, bucket string) string {
+	streamID = keylayout.NormalizeStreamID(streamID)
+	if streamID == "" {
+		return fmt.Sprintf("%s/%s/p", normalizePrefix(prefix), bucket)
+	}
+	return fmt.Sprintf("%s/%s/streams/%s/p", normalizePrefix(prefix), bucket, keylayout.StreamKey(streamID))
+}
+
+// PartitionPrefix returns the key prefix shared by every catalog object of one
+// stream partition, including its head, pages and maintenance state.
+func PartitionPrefix(prefix string, streamID string, partition uint32) string {
+	return partitionPrefix(prefix, streamID, partition) + "/"
+}
+
 func PagePrefix(prefix string, streamID string, partition uint32) string {
 	return fmt.Sprintf("%s/pages/", partitionPrefix(prefix, streamID, partition))
 }
@@ -184,6 +200,51 @@ func RetentionRequestPath(prefix string, streamID string, partition uint32) stri
 	return fmt.Sprintf("%s/maintenance/retention.json", partitionPrefix(prefix, streamID, partition))
 }
 
+// PartitionHoldPath returns the key of one partition's legal hold.
+func PartitionHoldPath(prefix string, streamID string, partition uint32) string {
+	return fmt.Sprintf("%s/maintenance/hold.json", partitionPrefix(prefix, streamID, partition))
+}
+
+// PartitionKeyPath returns the key of one partition's key encryption key. It
+// lives below the partition prefix, so purging a deleted partition removes it.
+func PartitionKeyPath(prefix string, streamID string, partition uint32) string {
+	return fmt.Sprintf("%s/keys/partition.json", partitionPrefix(prefix, streamID, partition))
+}
+
+// StreamHoldPath returns the key of the legal hold that covers every
+// partition of streamID. It lives outside the partition buckets, so partition
+// discovery and partition purges never see it.
+func StreamHoldPath(prefix string, streamID string) string {
+	streamID = keylayout.NormalizeStreamID(streamID)
+	if streamID == "" {
+		return fmt.Sprintf("%s/holds/stream.json", normalizePrefix(prefix))
+	}
+	return fmt.Sprintf("%s/holds/streams/%s.json", normalizePrefix(prefix), keylayout.StreamKey(streamID))
+}
+
+// StreamDictionaryPath returns the key of one immutable compression
+// dictionary of streamID. Like stream holds it lives outside the partition
+// buckets, because every partition of the stream may use it.
+func StreamDictionaryPath(prefix string, streamID string, id uint32) string {
+	streamID = keylayout.NormalizeStreamID(streamID)
+	if streamID == "" {
+		return fmt.Sprintf("%s/dictionaries/stream/%010d.zdict", normalizePrefix(prefix), id)
+	}
+	return fmt.Sprintf("%s/dictionaries/streams/%s/%010d.zdict", normalizePrefix(prefix), keylayout.StreamKey(streamID), id)
+}
+
+// ReplacedSegmentPrefix returns the key prefix of the markers that record
+// segment objects replaced by redaction swaps.
+func ReplacedSegmentPrefix(prefix string, streamID string, partition uint32) string {
+	return fmt.Sprintf("%s/maintenance/replaced/", partitionPrefix(prefix, streamID, partition))
+}
+
+// ReplacedSegmentPath returns the key of the marker for the replaced segment
+// object with segmentUUID at baseLSN.
+func ReplacedSegmentPath(prefix string, streamID string, partition uint32, baseLSN uint64, segmentUUID [16]byte) string {
+	return fmt.Sprintf("%s%020d-%x.json", ReplacedSegmentPrefix(prefix, streamID, partition), baseLSN, segmentUUID)
+}
+
 func GCStatePath(prefix string, streamID string, partition uint32) string {
 	return fmt.Sprintf("%s/maintenance/gc/state.json", partitionPrefix(prefix, streamID, partition))
 }
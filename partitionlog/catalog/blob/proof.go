@@ -0,0 +1,63 @@
+package blob
+
+import (
+	"context"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+)
+
+var _ csession.SegmentProver = (*Catalog)(nil)
+
+// ProveSegment proves the segment covering lsn under the checkpoint of
+// treeSize, or under the head when treeSize is zero. The tree is built from
+// the head's TreeBase and the retained chained segments, so a proof reads
+// every retained catalog page up to the checkpoint.
+func (c *Catalog) ProveSegment(ctx context.Context, partition uint32, lsn, treeSize uint64) (proof.SegmentProof, error) {
+	tree, err := c.proofTree(ctx, partition, treeSize)
+	if err != nil {
+		return proof.SegmentProof{}, err
+	}
+	return tree.ProveSegment(lsn, treeSize)
+}
+
+// ProveConsistency proves that the checkpoint of toSize extends the
+// checkpoint of fromSize. Like ProveSegment it reads the retained catalog
+// pages up to toSize.
+func (c *Catalog) ProveConsistency(ctx context.Context, partition uint32, fromSize, toSize uint64) (proof.ConsistencyProof, error) {
+	tree, err := c.proofTree(ctx, partition, toSize)
+	if err != nil {
+		return proof.ConsistencyProof{}, err
+	}
+	return tree.ProveConsistency(fromSize, toSize)
+}
+
+// proofTree collects the retained chained segments of the visible head up to
+// treeSize leaves, or all of them when treeSize is zero.
+func (c *Catalog) proofTree(ctx context.Context, partition uint32, treeSize uint64) (csession.ProofTree, error) {
+	if err := ctx.Err(); err != nil {
+		return csession.ProofTree{}, err
+	}
+	head, err := c.loadVisibleHead(ctx, partition)
+	if err != nil {
+		return csession.ProofTree{}, err
+	}
+	tree := csession.ProofTree{Head: stateFromHead(head), Base: head.TreeBase}
+	if treeSize == 0 || treeSize > head.Tree.Size {
+		treeSize = head.Tree.Size
+	}
+	if !head.HasLastSegment || head.OldestLSN == head.NextLSN || treeSize <= head.TreeBase.Size {
+		return tree, nil
+	}
+	err = c.walkRetainedSegments(ctx, head, func(segment pmeta.SegmentRef) bool {
+		if !segment.ChainHash.IsZero() {
+			tree.Segments = append(tree.Segments, segment)
+		}
+		return head.TreeBase.Size+uint64(len(tree.Segments)) < treeSize
+	})
+	if err != nil {
+		return csession.ProofTree{}, err
+	}
+	return tree, nil
+}
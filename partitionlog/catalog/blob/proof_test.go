@@ -0,0 +1,128 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+)
+
+func TestBlobCatalogProveSegmentAcrossPagedHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	var prev pmeta.Hash
+	segments := make([]pmeta.SegmentRef, 0, 17)
+	for i := uint64(0); i < 17; i++ {
+		segment := testChainedSegmentRef(prev, 1, i*10, i*10+9, ws.Epoch())
+		if _, err := ws.AppendSegment(ctx, segment); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", i, err)
+		}
+		prev = segment.ChainHash
+		segments = append(segments, segment)
+	}
+	unlinked := testChainedSegmentRef(segments[0].ChainHash, 1, 170, 179, ws.Epoch())
+	if _, err := ws.AppendSegment(ctx, unlinked); !errors.Is(err, pcatalog.ErrInvalidSegment) {
+		t.Fatalf("AppendSegment(unlinked) error = %v, want %v", err, pcatalog.ErrInvalidSegment)
+	}
+
+	assertProvable := func(name string, from uint64) pmeta.PartitionHead {
+		t.Helper()
+		head, err := cat.LoadPartition(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s LoadPartition() error = %v", name, err)
+		}
+		cp := proof.CheckpointFromHead(head)
+		for lsn := from; lsn < head.NextLSN; lsn += 7 {
+			p, err := cat.ProveSegment(ctx, 1, lsn, 0)
+			if err != nil {
+				t.Fatalf("%s ProveSegment(%d) error = %v", name, lsn, err)
+			}
+			if want := segments[lsn/10]; p.Segment != want {
+				t.Fatalf("%s ProveSegment(%d) segment = %+v, want %+v", name, lsn, p.Segment, want)
+			}
+			if err := proof.VerifySegment(cp, p); err != nil {
+				t.Fatalf("%s VerifySegment(%d) error = %v", name, lsn, err)
+			}
+		}
+		return head
+	}
+	head := assertProvable("appended", 0)
+	if head.TreeSize != 17 {
+		t.Fatalf("head tree_size = %d, want 17", head.TreeSize)
+	}
+	// A checkpoint published after nine segments stays provable.
+	older, err := cat.ProveSegment(ctx, 1, 61, 9)
+	if err != nil {
+		t.Fatalf("ProveSegment(tree_size=9) error = %v", err)
+	}
+	if older.Checkpoint.TreeSize != 9 || older.Checkpoint.NextLSN != 90 || older.Segment != segments[6] {
+		t.Fatalf("ProveSegment(tree_size=9) = %+v, want segment 6 under nine segments", older)
+	}
+	if err := proof.VerifySegment(older.Checkpoint, older); err != nil {
+		t.Fatalf("VerifySegment(tree_size=9) error = %v", err)
+	}
+
+	replacement := testRedactedSegmentRef(segments[3])
+	redacted, err := ws.(pcatalog.RedactionWriterSession).ReplaceSegment(ctx, segments[3], replacement)
+	if err != nil {
+		t.Fatalf("ReplaceSegment() error = %v", err)
+	}
+	if redacted.TreeRoot != head.TreeRoot {
+		t.Fatalf("ReplaceSegment() tree root = %s, want unchanged %s", redacted.TreeRoot, head.TreeRoot)
+	}
+	segments[3] = replacement
+	assertProvable("redacted", 0)
+
+	request := pcatalog.RetentionRequest{Version: pcatalog.RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 55, CreatedUnixMS: 10}
+	if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	if _, err := ws.(pcatalog.RetentionWriterSession).ApplyPendingRetention(ctx); err != nil {
+		t.Fatalf("ApplyPendingRetention() error = %v", err)
+	}
+	retained := assertProvable("retained", 50)
+	if retained.TreeRoot != head.TreeRoot || retained.TreeSize != head.TreeSize {
+		t.Fatalf("retention changed the tree: %d %s, want %d %s", retained.TreeSize, retained.TreeRoot, head.TreeSize, head.TreeRoot)
+	}
+	if _, err := cat.ProveSegment(ctx, 1, 10, 0); !errors.Is(err, pcatalog.ErrSegmentNotFound) {
+		t.Fatalf("ProveSegment(expired) error = %v, want %v", err, pcatalog.ErrSegmentNotFound)
+	}
+	again, err := cat.ProveSegment(ctx, 1, 61, 9)
+	if err != nil || again.Checkpoint != older.Checkpoint {
+		t.Fatalf("ProveSegment(tree_size=9, retained) = %+v, %v; want checkpoint %+v", again.Checkpoint, err, older.Checkpoint)
+	}
+	if err := proof.VerifySegment(older.Checkpoint, again); err != nil {
+		t.Fatalf("VerifySegment(tree_size=9, retained) error = %v", err)
+	}
+
+	consistency, err := cat.ProveConsistency(ctx, 1, 9, 0)
+	if err != nil {
+		t.Fatalf("ProveConsistency(9, head) error = %v", err)
+	}
+	if err := proof.VerifyConsistency(older.Checkpoint, proof.CheckpointFromHead(retained), consistency); err != nil {
+		t.Fatalf("VerifyConsistency(9, head) error = %v", err)
+	}
+	if _, err := cat.ProveConsistency(ctx, 1, 3, 0); !errors.Is(err, pcatalog.ErrSegmentNotFound) {
+		t.Fatalf("ProveConsistency(retained away) error = %v, want %v", err, pcatalog.ErrSegmentNotFound)
+	}
+}
+
+// testChainedSegmentRef returns testSegmentRef with a records root and a
+// chain link to prev.
+func testChainedSegmentRef(prev pmeta.Hash, partition uint32, base, last, epoch uint64) pmeta.SegmentRef {
+	segment := testSegmentRef(partition, base, last, epoch)
+	segment.RecordsRoot = pmeta.Hash{byte(base), byte(last), 1}
+	return proof.Chain(prev, segment)
+}
@@ -15,7 +15,7 @@ func (c *Catalog) LoadPartition(ctx context.Context, partition uint32) (pmeta.Pa
 	if err := ctx.Err(); err != nil {
 		return pmeta.PartitionHead{}, err
 	}
-	head, _, err := c.loadHead(ctx, partition)
+	head, err := c.loadVisibleHead(ctx, partition)
 	if err != nil {
 		return pmeta.PartitionHead{}, err
 	}
@@ -26,7 +26,7 @@ func (c *Catalog) FindSegment(ctx context.Context, partition uint32, lsn uint64)
 	if err := ctx.Err(); err != nil {
 		return pmeta.SegmentRef{}, false, err
 	}
-	head, _, err := c.loadHead(ctx, partition)
+	head, err := c.loadVisibleHead(ctx, partition)
 	if err != nil {
 		return pmeta.SegmentRef{}, false, err
 	}
@@ -37,37 +37,40 @@ func (c *Catalog) LookupTimestamp(ctx context.Context, req csession.TimestampLoo
 	if err := ctx.Err(); err != nil {
 		return csession.TimestampLookupResult{}, err
 	}
-	head, _, err := c.loadHead(ctx, req.Partition)
+	head, err := c.loadVisibleHead(ctx, req.Partition)
 	if err != nil {
 		return csession.TimestampLookupResult{}, err
 	}
-	result := csession.TimestampLookupResult{Head: stateFromHead(head)}
-	if !head.HasLastSegment || head.OldestLSN == head.NextLSN || req.TimestampMS > head.LastSegment.MaxTimestampMS {
-		return result, nil
+	segment, found, err := c.lookupTimestampInHead(ctx, head, req.TimestampMS)
+	if err != nil {
+		return csession.TimestampLookupResult{}, err
+	}
+	return csession.TimestampLookupResult{Head: stateFromHead(head), Segment: segment, Found: found}, nil
+}
+
+func (c *Catalog) lookupTimestampInHead(ctx context.Context, head headFile, timestampMS int64) (pmeta.SegmentRef, bool, error) {
+	if !head.HasLastSegment || head.OldestLSN == head.NextLSN || timestampMS > head.LastSegment.MaxTimestampMS {
+		return pmeta.SegmentRef{}, false, nil
 	}
 
 	roots := reachableRoots(head)
-	i := firstPageRefAtOrAfterTimestamp(roots, req.TimestampMS)
+	i := firstPageRefAtOrAfterTimestamp(roots, timestampMS)
 	if i < len(roots) {
-		segment, found, err := c.findTimestampInPageRef(ctx, roots[i], head.StreamID, head.Partition, req.TimestampMS)
+		segment, found, err := c.findTimestampInPageRef(ctx, roots[i], head.StreamID, head.Partition, timestampMS)
 		if err != nil {
-			return csession.TimestampLookupResult{}, err
+			return pmeta.SegmentRef{}, false, err
 		}
 		if !found {
-			return csession.TimestampLookupResult{}, fmt.Errorf("%w: page range contains timestamp_ms=%d but no segment qualifies", ErrCorruptCatalog, req.TimestampMS)
+			return pmeta.SegmentRef{}, false, fmt.Errorf("%w: page range contains timestamp_ms=%d but no segment qualifies", ErrCorruptCatalog, timestampMS)
 		}
-		result.Segment = segment
-		result.Found = true
-		return result, nil
+		return segment, true, nil
 	}
 
-	i = firstSegmentAtOrAfterTimestamp(head.ActiveSegments, req.TimestampMS)
+	i = firstSegmentAtOrAfterTimestamp(head.ActiveSegments, timestampMS)
 	if i == len(head.ActiveSegments) {
-		return csession.TimestampLookupResult{}, fmt.Errorf("%w: head range contains timestamp_ms=%d but no segment qualifies", ErrCorruptCatalog, req.TimestampMS)
+		return pmeta.SegmentRef{}, false, fmt.Errorf("%w: head range contains timestamp_ms=%d but no segment qualifies", ErrCorruptCatalog, timestampMS)
 	}
-	result.Segment = head.ActiveSegments[i]
-	result.Found = true
-	return result, nil
+	return head.ActiveSegments[i], true, nil
 }
 
 func (c *Catalog) findSegmentInHead(ctx context.Context, head headFile, lsn uint64) (pmeta.SegmentRef, bool, error) {
@@ -89,7 +92,7 @@ func (c *Catalog) ListSegments(ctx context.Context, req csession.ListSegmentsReq
 	if err := ctx.Err(); err != nil {
 		return pmeta.SegmentPage{}, err
 	}
-	head, _, err := c.loadHead(ctx, req.Partition)
+	head, err := c.loadVisibleHead(ctx, req.Partition)
 	if err != nil {
 		return pmeta.SegmentPage{}, err
 	}
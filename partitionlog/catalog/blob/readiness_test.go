@@ -10,6 +10,7 @@ import (
 
 	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 )
 
@@ -73,7 +74,7 @@ func TestBlobCatalogMatchesMemoryCatalogAcrossLongHistory(t *testing.T) {
 		}
 
 		base := uint64(i * 7)
-		last = modelSegment(7, base, base+6, memoryWriter.Epoch(), writerID)
+		last = proof.Chain(last.ChainHash, modelSegment(7, base, base+6, memoryWriter.Epoch(), writerID))
 		memoryHead, err := memoryWriter.AppendSegment(ctx, last)
 		if err != nil {
 			t.Fatalf("memory AppendSegment(%d) error = %v", i, err)
@@ -0,0 +1,304 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+var _ csession.RedactionWriterSession = (*writerSession)(nil)
+
+// ReplaceSegment swaps the committed segment current for replacement under
+// the session fence. A segment in the head is replaced in place. A segment in
+// history is replaced copy-on-write along its one leaf-to-root page path, so
+// the cost is bounded by the index depth. The replaced pages become orphans
+// for the lifecycle scrub. The replaced segment object is recorded under
+// ReplacedSegmentPrefix before the swap, and lifecycle RunPartition deletes it
+// after the delete delay.
+func (s *writerSession) ReplaceSegment(ctx context.Context, current, replacement pmeta.SegmentRef) (pmeta.PartitionHead, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if err := csession.ValidateReplacementSegment(current, replacement); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	head, token, err := s.cat.loadHead(ctx, s.head.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if head.WriterEpoch != s.writerEpoch || head.WriterID != s.writerID {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: writer fence moved partition=%d", csession.ErrStaleWriter, head.Partition)
+	}
+	if head.Deleted {
+		return pmeta.PartitionHead{}, csession.DeletedError(head.Partition, head.Shredded)
+	}
+	if head.Sealed {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: partition=%d", csession.ErrPartitionSealed, head.Partition)
+	}
+	if current.Partition != head.Partition || current.StreamID != head.StreamID {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: segment is not in partition=%d", csession.ErrInvalidRequest, head.Partition)
+	}
+	s.head = head
+	s.token = token
+
+	committed, ok, err := s.cat.findSegmentInHead(ctx, head, current.BaseLSN)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if !ok || committed.BaseLSN != current.BaseLSN {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: no retained segment at base_lsn=%d", csession.ErrConflict, current.BaseLSN)
+	}
+	if committed == replacement {
+		return stateFromHead(head), nil
+	}
+	if committed != current {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: base_lsn=%d belongs to a different segment", csession.ErrConflict, current.BaseLSN)
+	}
+
+	generation, err := nextGeneration(head.Generation, head.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	next, err := s.cat.replaceInHead(ctx, head, current, replacement, generation)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if next, err = signHead(ctx, next, s.signer); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	body, err := marshalHead(next, s.cat.opts.StreamID, next.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if err := s.cat.recordReplacedSegment(ctx, current); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	return s.commitReplaceHead(ctx, head, next, replacement, body)
+}
+
+const replacedVersion uint16 = 1
+
+// replacedFile records a segment object that a redaction swap replaces. It
+// is written before the swap commits, so a marker whose object the catalog
+// still references belongs to a swap that never committed.
+type replacedFile struct {
+	Version   uint16 `json:"version"`
+	StreamID  string `json:"stream_id,omitempty"`
+	Partition uint32 `json:"partition"`
+	BaseLSN   uint64 `json:"base_lsn"`
+	URI       string `json:"uri"`
+	SizeBytes uint64 `json:"size_bytes"`
+}
+
+// ReplacedSegment is the segment object named by one replaced-segment
+// marker.
+type ReplacedSegment struct {
+	BaseLSN   uint64
+	URI       string
+	SizeBytes uint64
+}
+
+func (c *Catalog) recordReplacedSegment(ctx context.Context, segment pmeta.SegmentRef) error {
+	body, err := json.Marshal(replacedFile{
+		Version:   replacedVersion,
+		StreamID:  c.opts.StreamID,
+		Partition: segment.Partition,
+		BaseLSN:   segment.BaseLSN,
+		URI:       segment.URI,
+		SizeBytes: segment.SizeBytes,
+	})
+	if err != nil {
+		return err
+	}
+	path := ReplacedSegmentPath(c.opts.Prefix, c.opts.StreamID, segment.Partition, segment.BaseLSN, segment.SegmentUUID)
+	// A retried swap finds its own marker already in place.
+	if _, _, err := c.backend.CompareAndSwap(ctx, path, "", body); err != nil {
+		return fmt.Errorf("record replaced segment %s: %w", path, err)
+	}
+	return nil
+}
+
+// DecodeReplacedSegment decodes the replaced-segment marker body of one
+// stream partition.
+func DecodeReplacedSegment(body []byte, streamID string, partition uint32) (ReplacedSegment, error) {
+	var file replacedFile
+	if err := json.Unmarshal(body, &file); err != nil {
+		return ReplacedSegment{}, fmt.Errorf("%w: decode replaced segment: %v", ErrCorruptCatalog, err)
+	}
+	switch {
+	case file.Version != replacedVersion:
+		return ReplacedSegment{}, fmt.Errorf("%w: replaced segment version=%d", ErrCorruptCatalog, file.Version)
+	case file.StreamID != streamID || file.Partition != partition:
+		return ReplacedSegment{}, fmt.Errorf("%w: replaced segment has wrong scope", ErrCorruptCatalog)
+	case file.URI == "":
+		return ReplacedSegment{}, fmt.Errorf("%w: replaced segment without uri", ErrCorruptCatalog)
+	}
+	return ReplacedSegment{BaseLSN: file.BaseLSN, URI: file.URI, SizeBytes: file.SizeBytes}, nil
+}
+
+func (c *Catalog) replaceInHead(ctx context.Context, head headFile, current, replacement pmeta.SegmentRef, generation uint64) (headFile, error) {
+	next := head
+	next.Generation = generation
+	// Heads written before byte accounting start from zero, so saturate.
+	next.RetainedBytes = head.RetainedBytes - min(current.SizeBytes, head.RetainedBytes) + replacement.SizeBytes
+	if head.HasLastSegment && head.LastSegment == current {
+		next.LastSegment = replacement
+	}
+
+	if i := slices.Index(head.ActiveSegments, current); i >= 0 {
+		next.ActiveSegments = slices.Clone(head.ActiveSegments)
+		next.ActiveSegments[i] = replacement
+		return next, nil
+	}
+	if head.LeafFrontier != nil && covers(*head.LeafFrontier, current.BaseLSN) {
+		ref, err := c.replaceInPageRef(ctx, *head.LeafFrontier, head, current, replacement, generation)
+		if err != nil {
+			return headFile{}, err
+		}
+		next.LeafFrontier = &ref
+		return next, nil
+	}
+	for i, root := range head.IndexFrontier {
+		if root.Path == "" || !covers(root, current.BaseLSN) {
+			continue
+		}
+		ref, err := c.replaceInPageRef(ctx, root, head, current, replacement, generation)
+		if err != nil {
+			return headFile{}, err
+		}
+		next.IndexFrontier = cloneRefs(head.IndexFrontier)
+		next.IndexFrontier[i] = ref
+		return next, nil
+	}
+	return headFile{}, fmt.Errorf("%w: no page covers base_lsn=%d", ErrCorruptCatalog, current.BaseLSN)
+}
+
+// replaceInPageRef rewrites the page at ref and every page below it on the
+// path to current. Page ranges are unchanged because the replacement covers
+// the same LSN and timestamp range.
+func (c *Catalog) replaceInPageRef(ctx context.Context, ref pageRef, head headFile, current, replacement pmeta.SegmentRef, generation uint64) (pageRef, error) {
+	if ref.Level == 0 {
+		leaf, err := c.loadLeaf(ctx, ref, head.StreamID, head.Partition)
+		if err != nil {
+			return pageRef{}, err
+		}
+		i := slices.Index(leaf.Segments, current)
+		if i < 0 {
+			return pageRef{}, fmt.Errorf("%w: leaf %s does not hold base_lsn=%d", ErrCorruptCatalog, ref.Path, current.BaseLSN)
+		}
+		leaf = cloneLeafPage(leaf)
+		leaf.Segments[i] = replacement
+		leaf.Generation = generation
+		next, _, err := c.writeLeaf(ctx, leaf)
+		if err != nil {
+			return pageRef{}, err
+		}
+		return *next, nil
+	}
+
+	index, err := c.loadIndex(ctx, ref, head.StreamID, head.Partition)
+	if err != nil {
+		return pageRef{}, err
+	}
+	i := firstPageRefAtOrAfter(index.Refs, current.BaseLSN)
+	if i == len(index.Refs) || !covers(index.Refs[i], current.BaseLSN) {
+		return pageRef{}, fmt.Errorf("%w: index %s does not cover base_lsn=%d", ErrCorruptCatalog, ref.Path, current.BaseLSN)
+	}
+	child, err := c.replaceInPageRef(ctx, index.Refs[i], head, current, replacement, generation)
+	if err != nil {
+		return pageRef{}, err
+	}
+	index.Refs = slices.Clone(index.Refs)
+	index.Refs[i] = child
+	index.Generation = generation
+	next, err := c.writeIndex(ctx, index)
+	if err != nil {
+		return pageRef{}, err
+	}
+	return *next, nil
+}
+
+func (s *writerSession) commitReplaceHead(ctx context.Context, previous, next headFile, replacement pmeta.SegmentRef, body []byte) (pmeta.PartitionHead, error) {
+	path := HeadPath(s.cat.opts.Prefix, s.cat.opts.StreamID, previous.Partition)
+	expectedToken := s.token
+	backoff := s.cat.opts.WriterCommitInitialBackoff
+	var lastCASErr error
+
+	for attempt := 0; attempt < s.cat.opts.WriterCommitMaxAttempts; attempt++ {
+		obj, swapped, err := s.cat.backend.CompareAndSwap(ctx, path, expectedToken, body)
+		if err != nil {
+			lastCASErr = err
+		} else if swapped {
+			s.head = next
+			s.token = obj.Token
+			return stateFromHead(next), nil
+		} else {
+			current, err := decodeHead(obj.Body, s.cat.opts.StreamID, previous.Partition)
+			if err != nil {
+				return pmeta.PartitionHead{}, err
+			}
+			if state, ok, err := s.observeReplace(ctx, previous, current, replacement, obj.Token); ok || err != nil {
+				return state, err
+			}
+			expectedToken = obj.Token
+			lastCASErr = nil
+		}
+
+		if attempt+1 == s.cat.opts.WriterCommitMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			if lastCASErr != nil {
+				return pmeta.PartitionHead{}, indeterminateCommit(previous.Partition, errors.Join(lastCASErr, err))
+			}
+			return pmeta.PartitionHead{}, err
+		}
+		backoff = growBackoff(backoff, s.cat.opts.WriterCommitMaxBackoff)
+	}
+
+	current, token, err := s.cat.loadHead(ctx, previous.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, indeterminateCommit(previous.Partition, errors.Join(lastCASErr, err))
+	}
+	if state, ok, err := s.observeReplace(ctx, previous, current, replacement, token); ok || err != nil {
+		return state, err
+	}
+	if lastCASErr != nil {
+		return pmeta.PartitionHead{}, fmt.Errorf("replace segment partition=%d: %w", previous.Partition, lastCASErr)
+	}
+	return pmeta.PartitionHead{}, fmt.Errorf("%w: replace head CAS did not apply partition=%d", csession.ErrConflict, previous.Partition)
+}
+
+// observeReplace classifies a head observed after a failed replace CAS. It
+// reports ok when the replacement is already committed.
+func (s *writerSession) observeReplace(ctx context.Context, previous, current headFile, replacement pmeta.SegmentRef, token string) (pmeta.PartitionHead, bool, error) {
+	if current.WriterEpoch != previous.WriterEpoch || current.WriterID != previous.WriterID {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("%w: writer fence moved partition=%d", csession.ErrStaleWriter, previous.Partition)
+	}
+	if sameHeadState(current, previous) {
+		return pmeta.PartitionHead{}, false, nil
+	}
+	committed, ok, err := s.cat.findSegmentInHead(ctx, current, replacement.BaseLSN)
+	if err != nil {
+		return pmeta.PartitionHead{}, false, indeterminateCommit(previous.Partition, err)
+	}
+	if ok && committed == replacement {
+		s.head = current
+		s.token = token
+		return stateFromHead(current), true, nil
+	}
+	return pmeta.PartitionHead{}, false, fmt.Errorf("%w: head changed while replacing segment partition=%d", csession.ErrConflict, previous.Partition)
+}
+
+func covers(ref pageRef, lsn uint64) bool {
+	return lsn >= ref.SeqLo && lsn <= ref.SeqHi
+}
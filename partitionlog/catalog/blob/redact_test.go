@@ -0,0 +1,151 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+func TestBlobCatalogReplaceSegmentRewritesPagedHistory(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	segments := make([]pmeta.SegmentRef, 0, 9)
+	for i := uint64(0); i < 9; i++ {
+		segment := testSegmentRef(1, i*10, i*10+9, ws.Epoch())
+		if _, err := ws.AppendSegment(ctx, segment); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", i, err)
+		}
+		segments = append(segments, segment)
+	}
+	before, err := cat.LoadPartition(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadPartition() error = %v", err)
+	}
+
+	redactor := ws.(pcatalog.RedactionWriterSession)
+	// Segments 0 and 7 live in immutable pages; segment 8 is still active in
+	// the head.
+	for _, i := range []int{0, 7, 8} {
+		replacement := testRedactedSegmentRef(segments[i])
+		head, err := redactor.ReplaceSegment(ctx, segments[i], replacement)
+		if err != nil {
+			t.Fatalf("ReplaceSegment(%d) error = %v", i, err)
+		}
+		if head.NextLSN != before.NextLSN || head.OldestLSN != before.OldestLSN || head.SegmentCount != before.SegmentCount {
+			t.Fatalf("ReplaceSegment(%d) head = %+v, before %+v", i, head, before)
+		}
+		if head.RetainedBytes != before.RetainedBytes+replacement.SizeBytes-segments[i].SizeBytes {
+			t.Fatalf("ReplaceSegment(%d) retained bytes = %d", i, head.RetainedBytes)
+		}
+		before = head
+		found, ok, err := cat.FindSegment(ctx, 1, segments[i].BaseLSN+3)
+		if err != nil || !ok || found != replacement {
+			t.Fatalf("FindSegment(%d) = %+v, %v, %v; want replacement", i, found, ok, err)
+		}
+		again, err := redactor.ReplaceSegment(ctx, segments[i], replacement)
+		if err != nil || again != head {
+			t.Fatalf("ReplaceSegment(%d, again) = %+v, %v; want %+v", i, again, err, head)
+		}
+		marker, err := cat.backend.Get(ctx, ReplacedSegmentPath(cat.opts.Prefix, cat.opts.StreamID, 1, segments[i].BaseLSN, segments[i].SegmentUUID))
+		if err != nil {
+			t.Fatalf("Get(replaced marker %d) error = %v", i, err)
+		}
+		replaced, err := DecodeReplacedSegment(marker.Body, cat.opts.StreamID, 1)
+		if err != nil || replaced.URI != segments[i].URI || replaced.BaseLSN != segments[i].BaseLSN {
+			t.Fatalf("replaced marker %d = %+v, %v; want %s", i, replaced, err, segments[i].URI)
+		}
+		segments[i] = replacement
+	}
+	if last, _ := before.Last(); last != segments[8] {
+		t.Fatalf("LastSegment = %+v, want replacement", last)
+	}
+
+	page, err := cat.ListSegments(ctx, pcatalog.ListSegmentsRequest{Partition: 1, Limit: 100})
+	if err != nil {
+		t.Fatalf("ListSegments() error = %v", err)
+	}
+	if len(page.Segments) != len(segments) {
+		t.Fatalf("ListSegments() = %d segments, want %d", len(page.Segments), len(segments))
+	}
+	for i := range segments {
+		if page.Segments[i] != segments[i] {
+			t.Fatalf("ListSegments()[%d] = %+v, want %+v", i, page.Segments[i], segments[i])
+		}
+	}
+
+	// A later append still builds on the rewritten pages.
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 90, 99, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment(after replace) error = %v", err)
+	}
+	found, ok, err := cat.FindSegment(ctx, 1, 5)
+	if err != nil || !ok || found != segments[0] {
+		t.Fatalf("FindSegment(after append) = %+v, %v, %v; want replacement", found, ok, err)
+	}
+}
+
+func TestBlobCatalogReplaceSegmentRejectsStaleAndMismatchedSegments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	first, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	segment := testSegmentRef(1, 0, 9, first.Epoch())
+	if _, err := first.AppendSegment(ctx, segment); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	redactor := first.(pcatalog.RedactionWriterSession)
+
+	moved := testRedactedSegmentRef(segment)
+	moved.LastLSN = 8
+	moved.MaxTimestampMS = 8
+	moved.RecordCount = 9
+	if _, err := redactor.ReplaceSegment(ctx, segment, moved); !errors.Is(err, pcatalog.ErrInvalidRequest) {
+		t.Fatalf("ReplaceSegment(changed range) error = %v, want %v", err, pcatalog.ErrInvalidRequest)
+	}
+	unknown := testSegmentRef(1, 0, 9, first.Epoch())
+	unknown.URI += "-other"
+	unknown.SegmentUUID[15] = 0xee
+	if _, err := redactor.ReplaceSegment(ctx, unknown, testRedactedSegmentRef(unknown)); !errors.Is(err, pcatalog.ErrConflict) {
+		t.Fatalf("ReplaceSegment(not committed) error = %v, want %v", err, pcatalog.ErrConflict)
+	}
+
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{2}); err != nil {
+		t.Fatalf("OpenWriter(second) error = %v", err)
+	}
+	if _, err := redactor.ReplaceSegment(ctx, segment, testRedactedSegmentRef(segment)); !errors.Is(err, pcatalog.ErrStaleWriter) {
+		t.Fatalf("ReplaceSegment(stale) error = %v, want %v", err, pcatalog.ErrStaleWriter)
+	}
+	found, ok, err := cat.FindSegment(ctx, 1, 0)
+	if err != nil || !ok || found != segment {
+		t.Fatalf("FindSegment() = %+v, %v, %v; want original", found, ok, err)
+	}
+}
+
+func testRedactedSegmentRef(segment pmeta.SegmentRef) pmeta.SegmentRef {
+	segment.ChainedSegmentHash, segment.ChainedTrailerHash = segment.ChainedHashes()
+	segment.URI += "-redacted"
+	segment.SegmentUUID[15] = 0xff
+	segment.SizeBytes += 16
+	segment.SegmentHash += 1000
+	segment.TrailerHash += 1000
+	segment.RedactedUnixMS = 1_776_263_000_000
+	return segment
+}
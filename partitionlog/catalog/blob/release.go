@@ -0,0 +1,66 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+var _ csession.IdleWriterManager = (*Catalog)(nil)
+var _ csession.ReleasableWriterSession = (*writerSession)(nil)
+
+// ReleaseWriter marks the session's fence released so OpenIdleWriter may take
+// it. It returns nil without writing when another writer took the fence or the
+// partition was sealed or deleted.
+func (s *writerSession) ReleaseWriter(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := HeadPath(s.cat.opts.Prefix, s.cat.opts.StreamID, s.head.Partition)
+	backoff := s.cat.opts.WriterCommitInitialBackoff
+	var lastCASErr error
+	for attempt := 0; attempt < s.cat.opts.WriterCommitMaxAttempts; attempt++ {
+		current, token, err := s.cat.loadHead(ctx, s.head.Partition)
+		if err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		if current.WriterEpoch != s.writerEpoch || current.WriterID != s.writerID ||
+			current.Sealed || current.Deleted || current.WriterReleased {
+			return nil
+		}
+		next := current
+		next.WriterReleased = true
+		body, err := marshalHead(next, s.cat.opts.StreamID, next.Partition)
+		if err != nil {
+			return err
+		}
+		obj, swapped, err := s.cat.backend.CompareAndSwap(ctx, path, token, body)
+		if err != nil {
+			lastCASErr = err
+		} else if swapped {
+			s.head = next
+			s.token = obj.Token
+			return nil
+		} else {
+			lastCASErr = nil
+		}
+
+		if attempt+1 == s.cat.opts.WriterCommitMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, s.cat.opts.WriterCommitMaxBackoff)
+	}
+	if lastCASErr != nil {
+		return fmt.Errorf("release writer partition=%d: %w", s.head.Partition, lastCASErr)
+	}
+	return fmt.Errorf("%w: release head CAS did not apply partition=%d", csession.ErrConflict, s.head.Partition)
+}
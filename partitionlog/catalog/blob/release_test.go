@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+func TestBlobCatalogIdleWriterWaitsForRelease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	owner, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter(owner) error = %v", err)
+	}
+	if _, err := owner.AppendSegment(ctx, testSegmentRef(1, 0, 9, owner.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+
+	if _, err := cat.OpenIdleWriter(ctx, 1, [16]byte{2}, nil); !errors.Is(err, pcatalog.ErrWriterActive) {
+		t.Fatalf("OpenIdleWriter(open owner) error = %v, want %v", err, pcatalog.ErrWriterActive)
+	}
+	if _, err := owner.AppendSegment(ctx, testSegmentRef(1, 10, 19, owner.Epoch())); err != nil {
+		t.Fatalf("AppendSegment(after refused idle open) error = %v", err)
+	}
+
+	if err := owner.(pcatalog.ReleasableWriterSession).ReleaseWriter(ctx); err != nil {
+		t.Fatalf("ReleaseWriter() error = %v", err)
+	}
+	idle, err := cat.OpenIdleWriter(ctx, 1, [16]byte{2}, nil)
+	if err != nil {
+		t.Fatalf("OpenIdleWriter(released) error = %v", err)
+	}
+	if head := idle.Head(); head.WriterEpoch != 2 || head.NextLSN != 20 {
+		t.Fatalf("idle head = %+v", head)
+	}
+	if _, err := cat.OpenIdleWriter(ctx, 1, [16]byte{3}, nil); !errors.Is(err, pcatalog.ErrWriterActive) {
+		t.Fatalf("OpenIdleWriter(idle writer open) error = %v, want %v", err, pcatalog.ErrWriterActive)
+	}
+
+	// A fenced-out session's release leaves the new fence alone.
+	if err := owner.(pcatalog.ReleasableWriterSession).ReleaseWriter(ctx); err != nil {
+		t.Fatalf("ReleaseWriter(stale) error = %v", err)
+	}
+	if _, err := cat.OpenIdleWriter(ctx, 1, [16]byte{3}, nil); !errors.Is(err, pcatalog.ErrWriterActive) {
+		t.Fatalf("OpenIdleWriter(after stale release) error = %v, want %v", err, pcatalog.ErrWriterActive)
+	}
+}
@@ -9,18 +9,22 @@ import (
 
 	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 )
 
 var _ csession.RetentionManager = (*Catalog)(nil)
 var _ csession.RetentionWriterSession = (*writerSession)(nil)
 
 type retentionFile struct {
-	Version       uint16 `json:"version"`
-	StreamID      string `json:"stream_id,omitempty"`
-	Partition     uint32 `json:"partition"`
-	PolicyVersion uint64 `json:"policy_version"`
-	BeforeLSN     uint64 `json:"before_lsn"`
-	CreatedUnixMS int64  `json:"created_unix_ms"`
+	Version            uint16 `json:"version"`
+	StreamID           string `json:"stream_id,omitempty"`
+	Partition          uint32 `json:"partition"`
+	PolicyVersion      uint64 `json:"policy_version"`
+	BeforeLSN          uint64 `json:"before_lsn"`
+	BeforeTimestampMS  int64  `json:"before_timestamp_ms,omitempty"`
+	MaxRetainedBytes   uint64 `json:"max_retained_bytes,omitempty"`
+	MinRetainedRecords uint64 `json:"min_retained_records,omitempty"`
+	CreatedUnixMS      int64  `json:"created_unix_ms"`
 }
 
 func (c *Catalog) RequestRetention(ctx context.Context, partition uint32, request csession.RetentionRequest) (csession.RetentionRequest, error) {
@@ -43,12 +47,15 @@ func (c *Catalog) RequestRetention(ctx context.Context, partition uint32, reques
 	}
 
 	candidate := retentionFile{
-		Version:       request.Version,
-		StreamID:      c.opts.StreamID,
-		Partition:     partition,
-		PolicyVersion: request.PolicyVersion,
-		BeforeLSN:     request.BeforeLSN,
-		CreatedUnixMS: request.CreatedUnixMS,
+		Version:            request.Version,
+		StreamID:           c.opts.StreamID,
+		Partition:          partition,
+		PolicyVersion:      request.PolicyVersion,
+		BeforeLSN:          request.BeforeLSN,
+		BeforeTimestampMS:  request.BeforeTimestampMS,
+		MaxRetainedBytes:   request.MaxRetainedBytes,
+		MinRetainedRecords: request.MinRetainedRecords,
+		CreatedUnixMS:      request.CreatedUnixMS,
 	}
 	body, err := json.Marshal(candidate)
 	if err != nil {
@@ -144,24 +151,22 @@ func decodeRetentionFile(body []byte, streamID string, partition uint32) (retent
 
 func (f retentionFile) request() csession.RetentionRequest {
 	return csession.RetentionRequest{
-		Version:       f.Version,
-		PolicyVersion: f.PolicyVersion,
-		BeforeLSN:     f.BeforeLSN,
-		CreatedUnixMS: f.CreatedUnixMS,
+		Version:            f.Version,
+		PolicyVersion:      f.PolicyVersion,
+		BeforeLSN:          f.BeforeLSN,
+		BeforeTimestampMS:  f.BeforeTimestampMS,
+		MaxRetainedBytes:   f.MaxRetainedBytes,
+		MinRetainedRecords: f.MinRetainedRecords,
+		CreatedUnixMS:      f.CreatedUnixMS,
 	}
 }
 
 func compareRetentionRequest(current, requested csession.RetentionRequest) (csession.RetentionRequest, bool, error) {
-	switch {
-	case current.PolicyVersion == requested.PolicyVersion && current.BeforeLSN == requested.BeforeLSN:
-		return current, true, nil
-	case requested.PolicyVersion <= current.PolicyVersion:
-		return csession.RetentionRequest{}, true, fmt.Errorf("%w: policy_version=%d current=%d", csession.ErrRetentionRegression, requested.PolicyVersion, current.PolicyVersion)
-	case requested.BeforeLSN < current.BeforeLSN:
-		return csession.RetentionRequest{}, true, fmt.Errorf("%w: before_lsn=%d current=%d", csession.ErrRetentionRegression, requested.BeforeLSN, current.BeforeLSN)
-	default:
-		return csession.RetentionRequest{}, false, nil
+	done, err := csession.CheckRetentionAdvance(current, requested)
+	if !done || err != nil {
+		return csession.RetentionRequest{}, done, err
 	}
+	return current, true, nil
 }
 
 func (s *writerSession) ApplyPendingRetention(ctx context.Context) (csession.RetentionApplyResult, error) {
@@ -186,34 +191,66 @@ func (s *writerSession) ApplyPendingRetention(ctx context.Context) (csession.Ret
 	if err != nil {
 		return csession.RetentionApplyResult{}, err
 	}
-	if !found || request.PolicyVersion <= s.head.AppliedRetentionVersion {
-		return csession.RetentionApplyResult{Head: stateFromHead(s.head), Request: request}, nil
+	unchanged := csession.RetentionApplyResult{Head: stateFromHead(s.head), Request: request}
+	if !found || request.PolicyVersion < s.head.AppliedRetentionVersion {
+		return unchanged, nil
 	}
-	if request.BeforeLSN < s.head.AppliedRetentionLSN {
-		return csession.RetentionApplyResult{}, fmt.Errorf("%w: before_lsn=%d applied=%d", csession.ErrRetentionRegression, request.BeforeLSN, s.head.AppliedRetentionLSN)
+	// A byte cap is re-evaluated on every apply, since appends grow the
+	// partition past it again. The other rules apply once per version.
+	reapply := request.PolicyVersion == s.head.AppliedRetentionVersion
+	if reapply && !request.BySize() {
+		return unchanged, nil
 	}
-	generation, err := nextGeneration(s.head.Generation, s.head.Partition)
+	head, err := s.cat.accountRetainedBytes(ctx, s.head)
 	if err != nil {
 		return csession.RetentionApplyResult{}, err
 	}
-	target := request.BeforeLSN
-	if target > s.head.NextLSN {
-		target = s.head.NextLSN
+	backfilled := !s.head.RetainedBytesExact
+	target, err := s.cat.resolveRetentionTarget(ctx, head, request)
+	if err != nil {
+		return csession.RetentionApplyResult{}, err
+	}
+	if reapply && !backfilled && target <= head.OldestLSN {
+		return unchanged, nil
+	}
+	holds, err := s.cat.LoadLegalHolds(ctx, head.Partition)
+	if err != nil {
+		return csession.RetentionApplyResult{}, err
 	}
-	pages, effectiveOldest, err := s.cat.buildRetentionPageSet(ctx, s.head, target, generation)
+	if holds.Active() {
+		return csession.RetentionApplyResult{}, fmt.Errorf("%w: retention partition=%d", csession.ErrLegalHold, head.Partition)
+	}
+	generation, err := nextGeneration(head.Generation, head.Partition)
+	if err != nil {
+		return csession.RetentionApplyResult{}, err
+	}
+	pages, effectiveOldest, err := s.cat.buildRetentionPageSet(ctx, head, target, generation)
+	if err != nil {
+		return csession.RetentionApplyResult{}, err
+	}
+	if reapply && !backfilled && effectiveOldest == head.OldestLSN {
+		return unchanged, nil
+	}
+	retired, treeBase, err := s.cat.retireBelow(ctx, head, effectiveOldest)
 	if err != nil {
 		return csession.RetentionApplyResult{}, err
 	}
 
 	previous := s.head
-	next := previous
+	next := head
 	next.OldestLSN = effectiveOldest
+	next.RetainedBytes -= min(retired, next.RetainedBytes)
+	next.TreeBase = treeBase
 	next.AppliedRetentionLSN = target
 	next.AppliedRetentionVersion = request.PolicyVersion
 	next.IndexFrontier = pages.IndexFrontier
 	next.LeafFrontier = pages.LeafFrontier
 	next.ActiveSegments = pages.ActiveSegments
 	next.Generation = generation
+	next, err = signHead(ctx, next, s.signer)
+	if err != nil {
+		return csession.RetentionApplyResult{}, err
+	}
 	body, err := marshalHead(next, s.cat.opts.StreamID, next.Partition)
 	if err != nil {
 		return csession.RetentionApplyResult{}, err
@@ -295,6 +332,106 @@ func (s *writerSession) acceptObservedRetention(expected, observed headFile, tok
 	return stateFromHead(expected)
 }
 
+// resolveRetentionTarget turns a request into the LSN below which head retires
+// history. Timestamp and byte-cap rules resolve to whole-segment boundaries:
+// the first segment that still holds a record at or after the timestamp, and
+// the first segment after which the retained bytes fit the cap.
+func (c *Catalog) resolveRetentionTarget(ctx context.Context, head headFile, request csession.RetentionRequest) (uint64, error) {
+	timestampLSN := head.NextLSN
+	if request.ByTimestamp() {
+		segment, found, err := c.lookupTimestampInHead(ctx, head, request.BeforeTimestampMS)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			timestampLSN = segment.BaseLSN
+		}
+	}
+	sizeLSN := head.OldestLSN
+	if request.BySize() && head.RetainedBytes > request.MaxRetainedBytes {
+		excess := head.RetainedBytes - request.MaxRetainedBytes
+		sizeLSN = head.NextLSN
+		var dropped uint64
+		err := c.walkRetainedSegments(ctx, head, func(segment pmeta.SegmentRef) bool {
+			dropped += segment.SizeBytes
+			if dropped < excess {
+				return true
+			}
+			sizeLSN = segment.NextLSN()
+			return false
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return csession.ResolveRetentionTarget(stateFromHead(head), request, timestampLSN, sizeLSN)
+}
+
+// accountRetainedBytes returns head with RetainedBytesExact set. A head
+// written before byte accounting gets RetainedBytes summed from its segment
+// tree.
+func (c *Catalog) accountRetainedBytes(ctx context.Context, head headFile) (headFile, error) {
+	if head.RetainedBytesExact {
+		return head, nil
+	}
+	if head.OldestLSN < head.NextLSN {
+		var total uint64
+		err := c.walkRetainedSegments(ctx, head, func(segment pmeta.SegmentRef) bool {
+			total += segment.SizeBytes
+			return true
+		})
+		if err != nil {
+			return headFile{}, err
+		}
+		head.RetainedBytes = total
+	}
+	head.RetainedBytesExact = true
+	return head, nil
+}
+
+// retireBelow sums SizeBytes of the retained segments below lsn and returns
+// head's TreeBase advanced over the chained ones.
+func (c *Catalog) retireBelow(ctx context.Context, head headFile, lsn uint64) (uint64, proof.Range, error) {
+	if lsn <= head.OldestLSN {
+		return 0, head.TreeBase, nil
+	}
+	if lsn >= head.NextLSN {
+		return head.RetainedBytes, head.Tree, nil
+	}
+	var total uint64
+	base := head.TreeBase
+	err := c.walkRetainedSegments(ctx, head, func(segment pmeta.SegmentRef) bool {
+		if segment.BaseLSN >= lsn {
+			return false
+		}
+		total += segment.SizeBytes
+		base = csession.AppendSegmentTree(base, segment)
+		return true
+	})
+	return total, base, err
+}
+
+// walkRetainedSegments visits the retained segments of head in LSN order until
+// visit returns false.
+func (c *Catalog) walkRetainedSegments(ctx context.Context, head headFile, visit func(pmeta.SegmentRef) bool) error {
+	from := head.OldestLSN
+	for {
+		page, err := c.listSegmentsInHead(ctx, head, from, csession.MaxSegmentPageLimit)
+		if err != nil {
+			return err
+		}
+		for _, segment := range page.Segments {
+			if !visit(segment) {
+				return nil
+			}
+		}
+		if !page.HasMore {
+			return nil
+		}
+		from = page.NextLSN
+	}
+}
+
 func (c *Catalog) buildRetentionPageSet(ctx context.Context, head headFile, target, generation uint64) (nextPageSet, uint64, error) {
 	next := nextPageSet{
 		IndexFrontier:  cloneRefs(head.IndexFrontier),
@@ -72,6 +72,220 @@ func TestBlobCatalogRetentionTrimsMultiLevelHistory(t *testing.T) {
 	}
 }
 
+func TestBlobCatalogRetentionResolvesTimestampToSegmentBoundary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for base := uint64(0); base < 160; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	retention := ws.(pcatalog.RetentionWriterSession)
+	apply := func(request pcatalog.RetentionRequest) (pcatalog.RetentionApplyResult, error) {
+		t.Helper()
+		request.Version = pcatalog.RetentionRequestVersion
+		if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+			t.Fatalf("RequestRetention(%+v) error = %v", request, err)
+		}
+		return retention.ApplyPendingRetention(ctx)
+	}
+
+	result, err := apply(pcatalog.RetentionRequest{PolicyVersion: 1, BeforeTimestampMS: 75})
+	if err != nil {
+		t.Fatalf("ApplyPendingRetention(timestamp) error = %v", err)
+	}
+	if !result.Applied || result.Request.BeforeTimestampMS != 75 || result.Head.OldestLSN != 70 || result.Head.AppliedRetentionLSN != 70 {
+		t.Fatalf("timestamp retention result = %+v", result)
+	}
+	stored, found, err := cat.LoadRetentionRequest(ctx, 1)
+	if err != nil || !found || stored.BeforeTimestampMS != 75 || stored.BeforeLSN != 0 {
+		t.Fatalf("LoadRetentionRequest() = %+v found=%v err=%v", stored, found, err)
+	}
+
+	if _, err := cat.RequestRetention(ctx, 1, pcatalog.RetentionRequest{Version: pcatalog.RetentionRequestVersion, PolicyVersion: 2, BeforeTimestampMS: 74}); !errors.Is(err, pcatalog.ErrRetentionRegression) {
+		t.Fatalf("RequestRetention(older timestamp) error = %v, want %v", err, pcatalog.ErrRetentionRegression)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, pcatalog.RetentionRequest{Version: pcatalog.RetentionRequestVersion, PolicyVersion: 2, BeforeTimestampMS: -1}); !errors.Is(err, pcatalog.ErrInvalidRequest) {
+		t.Fatalf("RequestRetention(negative timestamp) error = %v, want %v", err, pcatalog.ErrInvalidRequest)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, pcatalog.RetentionRequest{Version: pcatalog.RetentionRequestVersion, PolicyVersion: 2, BeforeLSN: 90, BeforeTimestampMS: 90}); !errors.Is(err, pcatalog.ErrInvalidRequest) {
+		t.Fatalf("RequestRetention(both bounds) error = %v, want %v", err, pcatalog.ErrInvalidRequest)
+	}
+
+	if _, err := apply(pcatalog.RetentionRequest{PolicyVersion: 2, BeforeLSN: 50}); !errors.Is(err, pcatalog.ErrRetentionRegression) {
+		t.Fatalf("ApplyPendingRetention(lsn below applied) error = %v, want %v", err, pcatalog.ErrRetentionRegression)
+	}
+	result, err = apply(pcatalog.RetentionRequest{PolicyVersion: 3, BeforeLSN: 95})
+	if err != nil || result.Head.OldestLSN != 90 || result.Head.AppliedRetentionLSN != 95 {
+		t.Fatalf("ApplyPendingRetention(lsn) = %+v err=%v", result, err)
+	}
+	result, err = apply(pcatalog.RetentionRequest{PolicyVersion: 4, BeforeTimestampMS: 80})
+	if err != nil || !result.Applied || result.Head.OldestLSN != 90 || result.Head.AppliedRetentionLSN != 95 {
+		t.Fatalf("ApplyPendingRetention(timestamp below applied) = %+v err=%v, want boundary kept", result, err)
+	}
+	result, err = apply(pcatalog.RetentionRequest{PolicyVersion: 5, BeforeTimestampMS: 125, MaxRetainedBytes: 1 << 20})
+	if err != nil || result.Head.OldestLSN != 120 || result.Head.AppliedRetentionLSN != 120 {
+		t.Fatalf("ApplyPendingRetention(timestamp and size) = %+v err=%v, want timestamp boundary", result, err)
+	}
+	result, err = apply(pcatalog.RetentionRequest{PolicyVersion: 6, BeforeTimestampMS: 1_000, MinRetainedRecords: 15})
+	if err != nil || result.Head.OldestLSN != 140 || result.Head.AppliedRetentionLSN != 145 {
+		t.Fatalf("ApplyPendingRetention(min records) = %+v err=%v, want newest 15 records kept", result, err)
+	}
+	result, err = apply(pcatalog.RetentionRequest{PolicyVersion: 7, BeforeTimestampMS: 1_000})
+	if err != nil || result.Head.OldestLSN != 160 || result.Head.AppliedRetentionLSN != 160 {
+		t.Fatalf("ApplyPendingRetention(timestamp after history) = %+v err=%v", result, err)
+	}
+
+	after, _, err := cat.loadHead(ctx, 1)
+	if err != nil {
+		t.Fatalf("loadHead(after) error = %v", err)
+	}
+	if err := validateHeadFile(after, cat.opts.StreamID, 1); err != nil {
+		t.Fatalf("validateHeadFile(after) error = %v", err)
+	}
+}
+
+func TestBlobCatalogRetentionCapsRetainedBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	var retained uint64
+	for base := uint64(0); base < 160; base += 10 {
+		state, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch()))
+		if err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+		retained = state.RetainedBytes
+	}
+	if retained != 16*128 {
+		t.Fatalf("retained bytes after append = %d, want %d", retained, 16*128)
+	}
+	retention := ws.(pcatalog.RetentionWriterSession)
+	apply := func(request pcatalog.RetentionRequest) pcatalog.RetentionApplyResult {
+		t.Helper()
+		request.Version = pcatalog.RetentionRequestVersion
+		if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+			t.Fatalf("RequestRetention(%+v) error = %v", request, err)
+		}
+		result, err := retention.ApplyPendingRetention(ctx)
+		if err != nil {
+			t.Fatalf("ApplyPendingRetention(%+v) error = %v", request, err)
+		}
+		return result
+	}
+
+	result := apply(pcatalog.RetentionRequest{PolicyVersion: 1, MaxRetainedBytes: 1000})
+	if !result.Applied || result.Head.OldestLSN != 90 || result.Head.AppliedRetentionLSN != 90 || result.Head.RetainedBytes != 7*128 {
+		t.Fatalf("size retention result = %+v", result.Head)
+	}
+	result = apply(pcatalog.RetentionRequest{PolicyVersion: 2, BeforeLSN: 120, MaxRetainedBytes: 1000})
+	if result.Head.OldestLSN != 120 || result.Head.RetainedBytes != 4*128 {
+		t.Fatalf("lsn over size retention result = %+v", result.Head)
+	}
+	result = apply(pcatalog.RetentionRequest{PolicyVersion: 3, BeforeTimestampMS: 125, MaxRetainedBytes: 300})
+	if result.Head.OldestLSN != 140 || result.Head.AppliedRetentionLSN != 140 || result.Head.RetainedBytes != 2*128 {
+		t.Fatalf("size over timestamp retention result = %+v", result.Head)
+	}
+	result = apply(pcatalog.RetentionRequest{PolicyVersion: 4, MaxRetainedBytes: 1 << 20})
+	if result.Head.OldestLSN != 140 || result.Head.RetainedBytes != 2*128 {
+		t.Fatalf("loosened cap result = %+v, want boundary kept", result.Head)
+	}
+
+	appended, err := ws.AppendSegment(ctx, testSegmentRef(1, 160, 169, ws.Epoch()))
+	if err != nil {
+		t.Fatalf("AppendSegment(after retention) error = %v", err)
+	}
+	if appended.RetainedBytes != 3*128 {
+		t.Fatalf("retained bytes after append = %d, want %d", appended.RetainedBytes, 3*128)
+	}
+	loaded, err := cat.LoadPartition(ctx, 1)
+	if err != nil || loaded != appended {
+		t.Fatalf("LoadPartition() = %+v err=%v, want %+v", loaded, err, appended)
+	}
+}
+
+func TestBlobCatalogRetentionReappliesByteCapAndBackfillsLegacyHeads(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{LeafSegmentLimit: 2, IndexRefLimit: 2})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for base := uint64(0); base < 40; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	// Rewrite the head as one written before byte accounting.
+	head, token, err := cat.loadHead(ctx, 1)
+	if err != nil {
+		t.Fatalf("loadHead() error = %v", err)
+	}
+	if !head.RetainedBytesExact {
+		t.Fatal("head after appends is not exact")
+	}
+	head.RetainedBytes, head.RetainedBytesExact = 0, false
+	body, err := marshalHead(head, cat.opts.StreamID, 1)
+	if err != nil {
+		t.Fatalf("marshalHead() error = %v", err)
+	}
+	if _, swapped, err := cat.backend.CompareAndSwap(ctx, HeadPath(cat.opts.Prefix, cat.opts.StreamID, 1), token, body); err != nil || !swapped {
+		t.Fatalf("CompareAndSwap(legacy head) swapped=%v err=%v", swapped, err)
+	}
+
+	retention := ws.(pcatalog.RetentionWriterSession)
+	request := pcatalog.RetentionRequest{Version: pcatalog.RetentionRequestVersion, PolicyVersion: 1, MaxRetainedBytes: 300}
+	if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	result, err := retention.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.OldestLSN != 20 || result.Head.RetainedBytes != 2*128 {
+		t.Fatalf("ApplyPendingRetention(legacy) = %+v err=%v, want bytes backfilled and capped", result.Head, err)
+	}
+
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || result.Applied {
+		t.Fatalf("ApplyPendingRetention(within cap) = %+v err=%v, want no-op", result, err)
+	}
+	for base := uint64(40); base < 60; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegmentRef(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	// The same request applies again once appends grow the partition past it.
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.OldestLSN != 40 || result.Head.RetainedBytes != 2*128 ||
+		result.Head.AppliedRetentionVersion != 1 {
+		t.Fatalf("ApplyPendingRetention(grown past cap) = %+v err=%v", result.Head, err)
+	}
+	after, _, err := cat.loadHead(ctx, 1)
+	if err != nil || !after.RetainedBytesExact {
+		t.Fatalf("loadHead(after) exact=%v err=%v, want exact", after.RetainedBytesExact, err)
+	}
+}
+
 func TestBlobCatalogRetentionCanTrimEverythingAndContinueAppending(t *testing.T) {
 	t.Parallel()
 
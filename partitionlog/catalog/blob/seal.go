@@ -0,0 +1,119 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	csession "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+var _ csession.SealWriterSession = (*writerSession)(nil)
+
+// SealPartition marks the partition head sealed under the session fence.
+// Once sealed, OpenWriter and AppendSegment reject the partition, so the
+// sealed NextLSN is the final end of the timeline.
+func (s *writerSession) SealPartition(ctx context.Context) (pmeta.PartitionHead, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, token, err := s.cat.loadHead(ctx, s.head.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if current.WriterEpoch != s.writerEpoch || current.WriterID != s.writerID {
+		return pmeta.PartitionHead{}, fmt.Errorf("%w: writer fence moved partition=%d", csession.ErrStaleWriter, s.head.Partition)
+	}
+	s.head = current
+	s.token = token
+	if current.Sealed {
+		return stateFromHead(current), nil
+	}
+
+	generation, err := nextGeneration(current.Generation, current.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	next := current
+	next.Sealed = true
+	next.Generation = generation
+	if next, err = signHead(ctx, next, s.signer); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	body, err := marshalHead(next, s.cat.opts.StreamID, next.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	return s.commitSealHead(ctx, current, next, body)
+}
+
+func (s *writerSession) commitSealHead(ctx context.Context, previous, next headFile, body []byte) (pmeta.PartitionHead, error) {
+	path := HeadPath(s.cat.opts.Prefix, s.cat.opts.StreamID, previous.Partition)
+	expectedToken := s.token
+	backoff := s.cat.opts.WriterCommitInitialBackoff
+	var lastCASErr error
+
+	for attempt := 0; attempt < s.cat.opts.WriterCommitMaxAttempts; attempt++ {
+		obj, swapped, err := s.cat.backend.CompareAndSwap(ctx, path, expectedToken, body)
+		if err != nil {
+			lastCASErr = err
+		} else if swapped {
+			s.head = next
+			s.token = obj.Token
+			return stateFromHead(next), nil
+		} else {
+			current, err := decodeHead(obj.Body, s.cat.opts.StreamID, previous.Partition)
+			if err != nil {
+				return pmeta.PartitionHead{}, err
+			}
+			if state, ok, err := s.observeSeal(previous, current, obj.Token); ok || err != nil {
+				return state, err
+			}
+			expectedToken = obj.Token
+			lastCASErr = nil
+		}
+
+		if attempt+1 == s.cat.opts.WriterCommitMaxAttempts {
+			break
+		}
+		if err := sleepBackoff(ctx, backoff); err != nil {
+			return pmeta.PartitionHead{}, errors.Join(lastCASErr, err)
+		}
+		backoff = growBackoff(backoff, s.cat.opts.WriterCommitMaxBackoff)
+	}
+
+	current, token, err := s.cat.loadHead(ctx, previous.Partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, errors.Join(lastCASErr, err)
+	}
+	if state, ok, err := s.observeSeal(previous, current, token); ok || err != nil {
+		return state, err
+	}
+	if lastCASErr != nil {
+		return pmeta.PartitionHead{}, fmt.Errorf("seal partition=%d: %w", previous.Partition, lastCASErr)
+	}
+	return pmeta.PartitionHead{}, fmt.Errorf("%w: seal head CAS did not apply partition=%d", csession.ErrConflict, previous.Partition)
+}
+
+// observeSeal classifies a head observed after a failed seal CAS. It reports
+// ok when the seal is already durable, typically because an ambiguous CAS
+// error hid a successful write.
+func (s *writerSession) observeSeal(previous, current headFile, token string) (pmeta.PartitionHead, bool, error) {
+	if current.WriterEpoch != previous.WriterEpoch || current.WriterID != previous.WriterID {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("%w: writer fence moved partition=%d", csession.ErrStaleWriter, previous.Partition)
+	}
+	if current.Sealed {
+		s.head = current
+		s.token = token
+		return stateFromHead(current), true, nil
+	}
+	if !sameHeadState(current, previous) {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("%w: head changed while sealing partition=%d", csession.ErrConflict, previous.Partition)
+	}
+	return pmeta.PartitionHead{}, false, nil
+}
@@ -0,0 +1,106 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+)
+
+func TestBlobCatalogSealRejectsWritersAndAppends(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 0, 9, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+
+	sealer := ws.(pcatalog.SealWriterSession)
+	sealed, err := sealer.SealPartition(ctx)
+	if err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	if !sealed.Sealed || sealed.NextLSN != 10 || sealed.SegmentCount != 1 {
+		t.Fatalf("sealed head = %+v", sealed)
+	}
+	again, err := sealer.SealPartition(ctx)
+	if err != nil || again != sealed {
+		t.Fatalf("SealPartition(again) = %+v, %v; want %+v", again, err, sealed)
+	}
+	loaded, err := cat.LoadPartition(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadPartition() error = %v", err)
+	}
+	if loaded != sealed {
+		t.Fatalf("LoadPartition() = %+v, want %+v", loaded, sealed)
+	}
+
+	if _, err := ws.AppendSegment(ctx, testSegmentRef(1, 10, 19, ws.Epoch())); !errors.Is(err, pcatalog.ErrPartitionSealed) {
+		t.Fatalf("AppendSegment(sealed) error = %v, want %v", err, pcatalog.ErrPartitionSealed)
+	}
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{2}); !errors.Is(err, pcatalog.ErrPartitionSealed) {
+		t.Fatalf("OpenWriter(sealed) error = %v, want %v", err, pcatalog.ErrPartitionSealed)
+	}
+}
+
+func TestBlobCatalogSealIsFenced(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	first, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter(first) error = %v", err)
+	}
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{2}); err != nil {
+		t.Fatalf("OpenWriter(second) error = %v", err)
+	}
+	if _, err := first.(pcatalog.SealWriterSession).SealPartition(ctx); !errors.Is(err, pcatalog.ErrStaleWriter) {
+		t.Fatalf("SealPartition(stale) error = %v, want %v", err, pcatalog.ErrStaleWriter)
+	}
+	head, err := cat.LoadPartition(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadPartition() error = %v", err)
+	}
+	if head.Sealed {
+		t.Fatalf("stale seal changed head = %+v", head)
+	}
+}
+
+func TestBlobCatalogSealRecoversLostHeadCASResponse(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := &casFaultBackend{Backend: NewMemoryBackend()}
+	cat, err := New(backend, commitRecoveryTestOptions())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ws, err := cat.OpenWriter(ctx, 1, [16]byte{1})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	backend.arm(casFaultAfterApplyOnce, false, nil)
+	head, err := ws.(pcatalog.SealWriterSession).SealPartition(ctx)
+	if err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	if !head.Sealed {
+		t.Fatalf("SealPartition() head = %+v, want sealed", head)
+	}
+	if calls, _ := backend.stats(); calls != 2 {
+		t.Fatalf("head CAS calls = %d, want 2", calls)
+	}
+}
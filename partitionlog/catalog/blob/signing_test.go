@@ -0,0 +1,97 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	pcatalog "github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+func TestBlobCatalogSigningWriterSignsEveryHeadCommit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{7}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := signing.NewEd25519Signer("writer", private)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error = %v", err)
+	}
+	verifier, err := signing.NewEd25519Verifier(map[string]ed25519.PublicKey{"writer": public})
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier() error = %v", err)
+	}
+	cat, err := NewMemory(Options{})
+	if err != nil {
+		t.Fatalf("NewMemory() error = %v", err)
+	}
+	if _, err := cat.OpenSigningWriter(ctx, 1, [16]byte{1}, nil); !errors.Is(err, pcatalog.ErrInvalidRequest) {
+		t.Fatalf("OpenSigningWriter(nil signer) error = %v, want %v", err, pcatalog.ErrInvalidRequest)
+	}
+
+	assertSigned := func(name string, want bool) {
+		t.Helper()
+		head, err := cat.LoadPartition(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s LoadPartition() error = %v", name, err)
+		}
+		err = signing.VerifyHead(verifier, head)
+		switch {
+		case want && err != nil:
+			t.Fatalf("%s VerifyHead() error = %v", name, err)
+		case !want && !errors.Is(err, signing.ErrUnsigned):
+			t.Fatalf("%s VerifyHead() error = %v, want %v", name, err, signing.ErrUnsigned)
+		}
+	}
+
+	ws, err := cat.OpenSigningWriter(ctx, 1, [16]byte{1}, signer)
+	if err != nil {
+		t.Fatalf("OpenSigningWriter() error = %v", err)
+	}
+	first := testSegmentRef(1, 0, 9, ws.Epoch())
+	if _, err := ws.AppendSegment(ctx, first); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	assertSigned("appended", true)
+	if _, err := ws.(pcatalog.RedactionWriterSession).ReplaceSegment(ctx, first, testRedactedSegmentRef(first)); err != nil {
+		t.Fatalf("ReplaceSegment() error = %v", err)
+	}
+	assertSigned("redacted", true)
+
+	unsigned, err := cat.OpenWriter(ctx, 1, [16]byte{2})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	assertSigned("unsigned takeover", false)
+	if _, err := unsigned.AppendSegment(ctx, testSegmentRef(1, 10, 19, unsigned.Epoch())); err != nil {
+		t.Fatalf("AppendSegment(unsigned) error = %v", err)
+	}
+	assertSigned("unsigned append", false)
+
+	ws, err = cat.OpenSigningWriter(ctx, 1, [16]byte{3}, signer)
+	if err != nil {
+		t.Fatalf("OpenSigningWriter(again) error = %v", err)
+	}
+	assertSigned("signed takeover", true)
+	if _, err := ws.(pcatalog.SealWriterSession).SealPartition(ctx); err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	assertSigned("sealed", true)
+
+	if _, _, err := cat.DeletePartition(ctx, 1); !errors.Is(err, pcatalog.ErrSignerRequired) {
+		t.Fatalf("DeletePartition(signed head) error = %v, want %v", err, pcatalog.ErrSignerRequired)
+	}
+	tombstone, deleted, err := cat.DeleteSignedPartition(ctx, 1, signer)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteSignedPartition() = %v, %v", deleted, err)
+	}
+	if err := signing.VerifyHead(verifier, tombstone); err != nil {
+		t.Fatalf("VerifyHead(tombstone) error = %v", err)
+	}
+}
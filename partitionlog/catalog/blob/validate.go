@@ -36,6 +36,21 @@ func validateHeadFile(head headFile, streamID string, partition uint32) error {
 	if head.MaxIndexLevel > MaxIndexLevel {
 		return fmt.Errorf("%w: max_index_level=%d max=%d", ErrCorruptCatalog, head.MaxIndexLevel, MaxIndexLevel)
 	}
+	if (head.SignerKeyID == "") != head.Signature.IsZero() {
+		return fmt.Errorf("%w: head signer_key_id and signature must be set together", ErrCorruptCatalog)
+	}
+	if err := head.Tree.Validate(); err != nil {
+		return fmt.Errorf("%w: tree: %w", ErrCorruptCatalog, err)
+	}
+	if err := head.TreeBase.Validate(); err != nil {
+		return fmt.Errorf("%w: tree base: %w", ErrCorruptCatalog, err)
+	}
+	if head.TreeBase.Size > head.Tree.Size {
+		return fmt.Errorf("%w: tree base size=%d tree size=%d", ErrCorruptCatalog, head.TreeBase.Size, head.Tree.Size)
+	}
+	if head.LastSegment.ChainHash.IsZero() != (head.Tree.Size == 0) {
+		return fmt.Errorf("%w: tree size=%d does not match last segment chaining", ErrCorruptCatalog, head.Tree.Size)
+	}
 	if !head.HasLastSegment {
 		if head.OldestLSN != head.NextLSN || head.SegmentCount != 0 || head.LeafFrontier != nil || len(head.IndexFrontier) != 0 || len(head.ActiveSegments) != 0 {
 			return fmt.Errorf("%w: empty head carries segment state", ErrCorruptCatalog)
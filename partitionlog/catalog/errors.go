@@ -1,6 +1,9 @@
 package catalog
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidRequest       = errors.New("catalog: invalid request")
@@ -15,4 +18,25 @@ var (
 	ErrLSNExhausted         = errors.New("catalog: lsn exhausted")
 	ErrRetentionRegression  = errors.New("catalog: retention regression")
 	ErrRetentionUnsupported = errors.New("catalog: retention unsupported")
+	ErrPartitionSealed      = errors.New("catalog: partition sealed")
+	ErrSealUnsupported      = errors.New("catalog: seal unsupported")
+	ErrPartitionDeleted     = errors.New("catalog: partition deleted")
+	ErrPartitionShredded    = errors.New("catalog: partition shredded")
+	ErrLegalHold            = errors.New("catalog: legal hold active")
+	ErrLegalHoldUnsupported = errors.New("catalog: legal hold unsupported")
+	ErrRedactionUnsupported = errors.New("catalog: redaction unsupported")
+	ErrProofUnsupported     = errors.New("catalog: proof unsupported")
+	ErrSegmentNotFound      = errors.New("catalog: segment not found")
+	ErrSigningUnsupported   = errors.New("catalog: signing unsupported")
+	ErrSignerRequired       = errors.New("catalog: signer required")
+	ErrWriterActive         = errors.New("catalog: writer active")
 )
+
+// DeletedError reports a tombstoned partition. The error wraps
+// ErrPartitionDeleted, and ErrPartitionShredded too when shredded is set.
+func DeletedError(partition uint32, shredded bool) error {
+	if shredded {
+		return fmt.Errorf("%w: %w: partition=%d", ErrPartitionDeleted, ErrPartitionShredded, partition)
+	}
+	return fmt.Errorf("%w: partition=%d", ErrPartitionDeleted, partition)
+}
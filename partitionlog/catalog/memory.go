@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"slices"
 	"sort"
+	"strconv"
 	"sync"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 // MemoryCatalog is an in-process catalog implementation. It is intended for
@@ -18,6 +22,8 @@ type MemoryCatalog struct {
 	streamID   string
 	partitions map[uint32]*memoryPartition
 	retention  map[uint32]RetentionRequest
+	holds      map[uint32]LegalHold
+	streamHold LegalHold
 }
 
 type memoryPartition struct {
@@ -25,6 +31,13 @@ type memoryPartition struct {
 	writerID    [16]byte
 	headVersion uint64
 	segments    []pmeta.SegmentRef
+	// tree is the compact range over every chained segment committed, and
+	// treeBase the part of it that retention dropped from segments.
+	tree     proof.Range
+	treeBase proof.Range
+	deleted  bool
+	shredded bool
+	released bool
 }
 
 type memoryWriterSession struct {
@@ -65,6 +78,9 @@ func (c *MemoryCatalog) InitializePartition(ctx context.Context, partition uint3
 		c.partitions = make(map[uint32]*memoryPartition)
 	}
 	if data, ok := c.partitions[partition]; ok {
+		if data.deleted {
+			return pmeta.PartitionHead{}, false, DeletedError(partition, data.shredded)
+		}
 		return data.state, false, nil
 	}
 	state := pmeta.PartitionHead{
@@ -78,7 +94,21 @@ func (c *MemoryCatalog) InitializePartition(ctx context.Context, partition uint3
 }
 
 func (c *MemoryCatalog) OpenWriter(ctx context.Context, partition uint32, writerID [16]byte) (WriterSession, error) {
-	state, epoch, headVersion, err := c.acquireWriter(ctx, partition, writerID)
+	return c.openWriter(ctx, partition, writerID, false)
+}
+
+// OpenIdleWriter opens a writer session only when the partition has no writer
+// or its writer released the fence. MemoryCatalog does not sign heads, so
+// signer must be nil.
+func (c *MemoryCatalog) OpenIdleWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer) (WriterSession, error) {
+	if signer != nil {
+		return nil, ErrSigningUnsupported
+	}
+	return c.openWriter(ctx, partition, writerID, true)
+}
+
+func (c *MemoryCatalog) openWriter(ctx context.Context, partition uint32, writerID [16]byte, idle bool) (WriterSession, error) {
+	state, epoch, headVersion, err := c.acquireWriter(ctx, partition, writerID, idle)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +122,7 @@ func (c *MemoryCatalog) OpenWriter(ctx context.Context, partition uint32, writer
 	}, nil
 }
 
-func (c *MemoryCatalog) acquireWriter(ctx context.Context, partition uint32, writerID [16]byte) (pmeta.PartitionHead, uint64, uint64, error) {
+func (c *MemoryCatalog) acquireWriter(ctx context.Context, partition uint32, writerID [16]byte, idle bool) (pmeta.PartitionHead, uint64, uint64, error) {
 	if err := ctx.Err(); err != nil {
 		return pmeta.PartitionHead{}, 0, 0, err
 	}
@@ -106,9 +136,19 @@ func (c *MemoryCatalog) acquireWriter(ctx context.Context, partition uint32, wri
 		c.partitions = make(map[uint32]*memoryPartition)
 	}
 	data := c.getOrCreateLocked(partition)
+	if data.deleted {
+		return pmeta.PartitionHead{}, 0, 0, DeletedError(partition, data.shredded)
+	}
+	if data.state.Sealed {
+		return pmeta.PartitionHead{}, 0, 0, fmt.Errorf("%w: partition=%d", ErrPartitionSealed, partition)
+	}
+	if idle && data.state.WriterEpoch > 0 && !data.released {
+		return pmeta.PartitionHead{}, 0, 0, fmt.Errorf("%w: partition=%d epoch=%d", ErrWriterActive, partition, data.state.WriterEpoch)
+	}
 	data.state.StreamID = c.streamID
 	data.state.WriterEpoch++
 	data.writerID = writerID
+	data.released = false
 	data.headVersion++
 	return data.state, data.state.WriterEpoch, data.headVersion, nil
 }
@@ -124,6 +164,9 @@ func (c *MemoryCatalog) LoadPartition(ctx context.Context, partition uint32) (pm
 	if !ok {
 		return pmeta.PartitionHead{StreamID: c.streamID, Partition: partition}, nil
 	}
+	if data.deleted {
+		return pmeta.PartitionHead{}, DeletedError(partition, data.shredded)
+	}
 	return data.state, nil
 }
 
@@ -142,13 +185,12 @@ func (c *MemoryCatalog) RequestRetention(ctx context.Context, partition uint32,
 	}
 	current, ok := c.retention[partition]
 	if ok {
-		switch {
-		case request.PolicyVersion == current.PolicyVersion && request.BeforeLSN == current.BeforeLSN:
+		done, err := CheckRetentionAdvance(current, request)
+		if err != nil {
+			return RetentionRequest{}, err
+		}
+		if done {
 			return current, nil
-		case request.PolicyVersion <= current.PolicyVersion:
-			return RetentionRequest{}, fmt.Errorf("%w: policy_version=%d current=%d", ErrRetentionRegression, request.PolicyVersion, current.PolicyVersion)
-		case request.BeforeLSN < current.BeforeLSN:
-			return RetentionRequest{}, fmt.Errorf("%w: before_lsn=%d current=%d", ErrRetentionRegression, request.BeforeLSN, current.BeforeLSN)
 		}
 	}
 	c.retention[partition] = request
@@ -181,19 +223,51 @@ func (c *MemoryCatalog) applyPendingRetention(ctx context.Context, partition uin
 		return RetentionApplyResult{}, 0, fmt.Errorf("%w: writer fence moved", ErrStaleWriter)
 	}
 	request, ok := c.retention[partition]
-	if !ok || request.PolicyVersion <= state.AppliedRetentionVersion {
-		return RetentionApplyResult{Head: state, Request: request}, data.headVersion, nil
+	unchanged := RetentionApplyResult{Head: state, Request: request}
+	if !ok || request.PolicyVersion < state.AppliedRetentionVersion {
+		return unchanged, data.headVersion, nil
+	}
+	// A byte cap is re-evaluated on every apply, since appends grow the
+	// partition past it again. The other rules apply once per version.
+	reapply := request.PolicyVersion == state.AppliedRetentionVersion
+	if reapply && (!request.BySize() || state.RetainedBytes <= request.MaxRetainedBytes) {
+		return unchanged, data.headVersion, nil
+	}
+	timestampLSN := state.NextLSN
+	if request.ByTimestamp() {
+		if i := firstSegmentAtOrAfterTimestamp(data.segments, request.BeforeTimestampMS); i < len(data.segments) {
+			timestampLSN = data.segments[i].BaseLSN
+		}
 	}
-	if request.BeforeLSN < state.AppliedRetentionLSN {
-		return RetentionApplyResult{}, 0, fmt.Errorf("%w: before_lsn=%d applied=%d", ErrRetentionRegression, request.BeforeLSN, state.AppliedRetentionLSN)
+	sizeLSN := state.OldestLSN
+	if request.BySize() && state.RetainedBytes > request.MaxRetainedBytes {
+		excess := state.RetainedBytes - request.MaxRetainedBytes
+		sizeLSN = state.NextLSN
+		var dropped uint64
+		for _, segment := range data.segments {
+			dropped += segment.SizeBytes
+			if dropped >= excess {
+				sizeLSN = segment.NextLSN()
+				break
+			}
+		}
 	}
-
-	target := request.BeforeLSN
-	if target > state.NextLSN {
-		target = state.NextLSN
+	target, err := ResolveRetentionTarget(state, request, timestampLSN, sizeLSN)
+	if err != nil {
+		return RetentionApplyResult{}, 0, err
+	}
+	if reapply && firstSegmentAtOrAfter(data.segments, target) == 0 {
+		return unchanged, data.headVersion, nil
+	}
+	if c.legalHoldsLocked(partition).Active() {
+		return RetentionApplyResult{}, 0, fmt.Errorf("%w: retention partition=%d", ErrLegalHold, partition)
 	}
 	if target > state.OldestLSN && len(data.segments) > 0 {
 		start := firstSegmentAtOrAfter(data.segments, target)
+		for _, segment := range data.segments[:start] {
+			state.RetainedBytes -= segment.SizeBytes
+			data.treeBase = AppendSegmentTree(data.treeBase, segment)
+		}
 		if start == len(data.segments) {
 			data.segments = nil
 			state.OldestLSN = state.NextLSN
@@ -209,6 +283,79 @@ func (c *MemoryCatalog) applyPendingRetention(ctx context.Context, partition uin
 	return RetentionApplyResult{Head: state, Request: request, Applied: true}, data.headVersion, nil
 }
 
+func (c *MemoryCatalog) sealPartition(ctx context.Context, partition uint32, writerID [16]byte, writerEpoch uint64) (pmeta.PartitionHead, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.partitions == nil {
+		c.partitions = make(map[uint32]*memoryPartition)
+	}
+	data := c.getOrCreateLocked(partition)
+	if data.state.WriterEpoch != writerEpoch || data.writerID != writerID {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: writer fence moved", ErrStaleWriter)
+	}
+	if !data.state.Sealed {
+		data.state.Sealed = true
+		data.headVersion++
+	}
+	return data.state, data.headVersion, nil
+}
+
+func (c *MemoryCatalog) replaceSegment(ctx context.Context, partition uint32, writerID [16]byte, writerEpoch uint64, current, replacement pmeta.SegmentRef) (pmeta.PartitionHead, uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, 0, err
+	}
+	if err := ValidateReplacementSegment(current, replacement); err != nil {
+		return pmeta.PartitionHead{}, 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.partitions == nil {
+		c.partitions = make(map[uint32]*memoryPartition)
+	}
+	data := c.getOrCreateLocked(partition)
+	state := data.state
+	if state.WriterEpoch != writerEpoch || data.writerID != writerID {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: writer fence moved", ErrStaleWriter)
+	}
+	if data.deleted {
+		return pmeta.PartitionHead{}, 0, DeletedError(partition, data.shredded)
+	}
+	if state.Sealed {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: partition=%d", ErrPartitionSealed, partition)
+	}
+	if current.Partition != partition || current.StreamID != c.streamID {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: segment is not in partition=%d", ErrInvalidRequest, partition)
+	}
+	i := firstSegmentAtOrAfter(data.segments, current.BaseLSN)
+	if i == len(data.segments) || data.segments[i].BaseLSN != current.BaseLSN {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: no retained segment at base_lsn=%d", ErrConflict, current.BaseLSN)
+	}
+	if data.segments[i] == replacement {
+		return state, data.headVersion, nil
+	}
+	if data.segments[i] != current {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: base_lsn=%d belongs to a different segment", ErrConflict, current.BaseLSN)
+	}
+	if err := ensureUniqueSegment(data.segments, replacement); err != nil {
+		return pmeta.PartitionHead{}, 0, err
+	}
+
+	state.RetainedBytes = state.RetainedBytes - min(current.SizeBytes, state.RetainedBytes) + replacement.SizeBytes
+	if state.HasLastSegment && state.LastSegment == current {
+		state.LastSegment = replacement
+	}
+	data.segments = slices.Clone(data.segments)
+	data.segments[i] = replacement
+	data.state = state
+	data.headVersion++
+	return state, data.headVersion, nil
+}
+
 func (c *MemoryCatalog) appendSegment(ctx context.Context, partition uint32, writerID [16]byte, writerEpoch uint64, expectedNextLSN uint64, segment pmeta.SegmentRef, knownRetry bool) (pmeta.PartitionHead, uint64, error) {
 	if err := ctx.Err(); err != nil {
 		return pmeta.PartitionHead{}, 0, err
@@ -246,6 +393,12 @@ func (c *MemoryCatalog) appendSegment(ctx context.Context, partition uint32, wri
 	if writerID != data.writerID {
 		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: writer_id mismatch", ErrStaleWriter)
 	}
+	if data.deleted {
+		return pmeta.PartitionHead{}, 0, DeletedError(partition, data.shredded)
+	}
+	if state.Sealed {
+		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: partition=%d", ErrPartitionSealed, partition)
+	}
 	if expectedNextLSN != state.NextLSN {
 		return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: expected_next_lsn=%d current=%d", ErrConflict, expectedNextLSN, state.NextLSN)
 	}
@@ -257,10 +410,14 @@ func (c *MemoryCatalog) appendSegment(ctx context.Context, partition uint32, wri
 			return pmeta.PartitionHead{}, 0, fmt.Errorf("%w: segment min_ts=%d previous max_ts=%d", ErrTimestampOrder, segment.MinTimestampMS, last.MaxTimestampMS)
 		}
 	}
+	if err := ValidateSegmentChain(state.LastSegment.ChainHash, segment); err != nil {
+		return pmeta.PartitionHead{}, 0, err
+	}
 	if err := ensureUniqueSegment(data.segments, segment); err != nil {
 		return pmeta.PartitionHead{}, 0, err
 	}
 
+	state.Producers = state.ProducerState().Merge(segment.Producers)
 	state.NextLSN = segment.NextLSN()
 	if !state.HasLastSegment {
 		state.OldestLSN = segment.BaseLSN
@@ -268,10 +425,14 @@ func (c *MemoryCatalog) appendSegment(ctx context.Context, partition uint32, wri
 	state.LastSegment = segment
 	state.HasLastSegment = true
 	state.SegmentCount++
+	state.RetainedBytes += segment.SizeBytes
 
+	data.segments = append(data.segments, segment)
+	data.tree = AppendSegmentTree(data.tree, segment)
+	state.TreeSize = data.tree.Size
+	state.TreeRoot = data.tree.Root()
 	data.state = state
 	data.headVersion++
-	data.segments = append(data.segments, segment)
 	return state, data.headVersion, nil
 }
 
@@ -286,6 +447,9 @@ func (c *MemoryCatalog) FindSegment(ctx context.Context, partition uint32, lsn u
 	if !ok {
 		return pmeta.SegmentRef{}, false, nil
 	}
+	if data.deleted {
+		return pmeta.SegmentRef{}, false, DeletedError(partition, data.shredded)
+	}
 	i := firstSegmentAtOrAfter(data.segments, lsn)
 	if i == len(data.segments) {
 		return pmeta.SegmentRef{}, false, nil
@@ -297,6 +461,49 @@ func (c *MemoryCatalog) FindSegment(ctx context.Context, partition uint32, lsn u
 	return segment, true, nil
 }
 
+// ProveSegment proves the segment covering lsn under the checkpoint of
+// treeSize, or under the head when treeSize is zero.
+func (c *MemoryCatalog) ProveSegment(ctx context.Context, partition uint32, lsn, treeSize uint64) (proof.SegmentProof, error) {
+	tree, err := c.proofTree(ctx, partition)
+	if err != nil {
+		return proof.SegmentProof{}, err
+	}
+	return tree.ProveSegment(lsn, treeSize)
+}
+
+// ProveConsistency proves that the checkpoint of toSize extends the
+// checkpoint of fromSize.
+func (c *MemoryCatalog) ProveConsistency(ctx context.Context, partition uint32, fromSize, toSize uint64) (proof.ConsistencyProof, error) {
+	tree, err := c.proofTree(ctx, partition)
+	if err != nil {
+		return proof.ConsistencyProof{}, err
+	}
+	return tree.ProveConsistency(fromSize, toSize)
+}
+
+func (c *MemoryCatalog) proofTree(ctx context.Context, partition uint32) (ProofTree, error) {
+	if err := ctx.Err(); err != nil {
+		return ProofTree{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data, ok := c.partitions[partition]
+	if !ok {
+		return ProofTree{Head: pmeta.PartitionHead{StreamID: c.streamID, Partition: partition}}, nil
+	}
+	if data.deleted {
+		return ProofTree{}, DeletedError(partition, data.shredded)
+	}
+	tree := ProofTree{Head: data.state, Base: data.treeBase}
+	for _, segment := range data.segments {
+		if !segment.ChainHash.IsZero() {
+			tree.Segments = append(tree.Segments, segment)
+		}
+	}
+	return tree, nil
+}
+
 func (c *MemoryCatalog) LookupTimestamp(ctx context.Context, req TimestampLookupRequest) (TimestampLookupResult, error) {
 	if err := ctx.Err(); err != nil {
 		return TimestampLookupResult{}, err
@@ -310,6 +517,9 @@ func (c *MemoryCatalog) LookupTimestamp(ctx context.Context, req TimestampLookup
 			Head: pmeta.PartitionHead{StreamID: c.streamID, Partition: req.Partition},
 		}, nil
 	}
+	if data.deleted {
+		return TimestampLookupResult{}, DeletedError(req.Partition, data.shredded)
+	}
 	result := TimestampLookupResult{Head: data.state}
 	i := firstSegmentAtOrAfterTimestamp(data.segments, req.TimestampMS)
 	if i == len(data.segments) {
@@ -331,6 +541,9 @@ func (c *MemoryCatalog) ListSegments(ctx context.Context, req ListSegmentsReques
 	if !ok {
 		return pmeta.SegmentPage{}, nil
 	}
+	if data.deleted {
+		return pmeta.SegmentPage{}, DeletedError(req.Partition, data.shredded)
+	}
 	limit := req.NormalizedLimit()
 	start := firstSegmentAtOrAfter(data.segments, req.FromLSN)
 	if start == len(data.segments) {
@@ -350,6 +563,143 @@ func (c *MemoryCatalog) ListSegments(ctx context.Context, req ListSegmentsReques
 	return page, nil
 }
 
+// ListPartitions lists known partitions in numeric order. AfterKey is the
+// decimal partition number of the previous page's last entry.
+func (c *MemoryCatalog) ListPartitions(ctx context.Context, req ListPartitionsRequest) (PartitionPage, error) {
+	if err := ctx.Err(); err != nil {
+		return PartitionPage{}, err
+	}
+	after := int64(-1)
+	if req.AfterKey != "" {
+		parsed, err := strconv.ParseUint(req.AfterKey, 10, 32)
+		if err != nil {
+			return PartitionPage{}, fmt.Errorf("%w: partition cursor %q", ErrInvalidRequest, req.AfterKey)
+		}
+		after = int64(parsed)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	partitions := make([]uint32, 0, len(c.partitions))
+	for partition := range c.partitions {
+		if int64(partition) > after {
+			partitions = append(partitions, partition)
+		}
+	}
+	slices.Sort(partitions)
+
+	limit := req.NormalizedLimit()
+	page := PartitionPage{HasMore: len(partitions) > limit}
+	for _, partition := range partitions[:min(limit, len(partitions))] {
+		data := c.partitions[partition]
+		if data.deleted && !req.IncludeDeleted {
+			continue
+		}
+		info := PartitionInfo{Partition: partition}
+		if req.IncludeHeads {
+			info.Head = data.state
+		}
+		page.Partitions = append(page.Partitions, info)
+	}
+	if page.HasMore {
+		page.NextAfterKey = strconv.FormatUint(uint64(partitions[limit-1]), 10)
+	}
+	return page, nil
+}
+
+// DeletePartition tombstones a partition. The in-process catalog has no
+// physical reclamation, so the tombstone is permanent.
+func (c *MemoryCatalog) DeletePartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, bool, error) {
+	return c.deletePartition(ctx, partition, false)
+}
+
+// ShredPartition deletes like DeletePartition and marks the tombstone
+// shredded. The in-process catalog cannot sign, so signer must be nil.
+func (c *MemoryCatalog) ShredPartition(ctx context.Context, partition uint32, signer signing.Signer) (pmeta.PartitionHead, bool, error) {
+	if signer != nil {
+		return pmeta.PartitionHead{}, false, ErrSigningUnsupported
+	}
+	return c.deletePartition(ctx, partition, true)
+}
+
+func (c *MemoryCatalog) deletePartition(ctx context.Context, partition uint32, shred bool) (pmeta.PartitionHead, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return pmeta.PartitionHead{}, false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.partitions[partition]
+	if !ok {
+		return pmeta.PartitionHead{StreamID: c.streamID, Partition: partition}, false, nil
+	}
+	if data.deleted {
+		if !shred || data.shredded {
+			return data.state, false, nil
+		}
+		data.shredded = true
+		return data.state, true, nil
+	}
+	if c.legalHoldsLocked(partition).Active() {
+		return pmeta.PartitionHead{}, false, fmt.Errorf("%w: delete partition=%d", ErrLegalHold, partition)
+	}
+	data.deleted = true
+	data.shredded = shred
+	if data.state.WriterEpoch > 0 && data.state.WriterEpoch < math.MaxUint64 {
+		data.state.WriterEpoch++
+	}
+	data.headVersion++
+	return data.state, true, nil
+}
+
+// SetPartitionHold stores the legal hold of one partition.
+func (c *MemoryCatalog) SetPartitionHold(ctx context.Context, partition uint32, hold LegalHold) (LegalHold, error) {
+	if err := ctx.Err(); err != nil {
+		return LegalHold{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current := c.holds[partition]; sameLegalHold(current, hold) {
+		return current, nil
+	}
+	if c.holds == nil {
+		c.holds = make(map[uint32]LegalHold)
+	}
+	c.holds[partition] = hold
+	return hold, nil
+}
+
+// SetStreamHold stores the legal hold that covers every partition.
+func (c *MemoryCatalog) SetStreamHold(ctx context.Context, hold LegalHold) (LegalHold, error) {
+	if err := ctx.Err(); err != nil {
+		return LegalHold{}, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sameLegalHold(c.streamHold, hold) {
+		return c.streamHold, nil
+	}
+	c.streamHold = hold
+	return hold, nil
+}
+
+func (c *MemoryCatalog) LoadLegalHolds(ctx context.Context, partition uint32) (LegalHolds, error) {
+	if err := ctx.Err(); err != nil {
+		return LegalHolds{}, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.legalHoldsLocked(partition), nil
+}
+
+func sameLegalHold(current, requested LegalHold) bool {
+	return current.Active == requested.Active && current.Reason == requested.Reason
+}
+
+func (c *MemoryCatalog) legalHoldsLocked(partition uint32) LegalHolds {
+	return LegalHolds{Partition: c.holds[partition], Stream: c.streamHold}
+}
+
 func (c *MemoryCatalog) getOrCreateLocked(partition uint32) *memoryPartition {
 	data, ok := c.partitions[partition]
 	if ok {
@@ -459,3 +809,45 @@ func (s *memoryWriterSession) ApplyPendingRetention(ctx context.Context) (Retent
 	s.headVersion = headVersion
 	return result, nil
 }
+
+func (s *memoryWriterSession) SealPartition(ctx context.Context) (pmeta.PartitionHead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, headVersion, err := s.cat.sealPartition(ctx, s.partition, s.writerID, s.writerEpoch)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	s.state = state
+	s.headVersion = headVersion
+	return state, nil
+}
+
+func (s *memoryWriterSession) ReleaseWriter(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cat.mu.Lock()
+	defer s.cat.mu.Unlock()
+	data, ok := s.cat.partitions[s.partition]
+	if ok && data.state.WriterEpoch == s.writerEpoch && data.writerID == s.writerID {
+		data.released = true
+	}
+	return nil
+}
+
+func (s *memoryWriterSession) ReplaceSegment(ctx context.Context, current, replacement pmeta.SegmentRef) (pmeta.PartitionHead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, headVersion, err := s.cat.replaceSegment(ctx, s.partition, s.writerID, s.writerEpoch, current, replacement)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	s.state = state
+	s.headVersion = headVersion
+	return state, nil
+}
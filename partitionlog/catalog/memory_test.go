@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 )
 
@@ -57,6 +58,36 @@ func TestMemoryCatalogLoadMissingPartitionReturnsEmptyState(t *testing.T) {
 	}
 }
 
+func TestMemoryCatalogListPartitionsPagesInNumericOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	for _, partition := range []uint32{9, 2, 5} {
+		if _, _, err := cat.InitializePartition(ctx, partition, uint64(partition)); err != nil {
+			t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+		}
+	}
+
+	first, err := cat.ListPartitions(ctx, ListPartitionsRequest{Limit: 2, IncludeHeads: true})
+	if err != nil {
+		t.Fatalf("ListPartitions() error = %v", err)
+	}
+	if len(first.Partitions) != 2 || first.Partitions[0].Partition != 2 || first.Partitions[1].Head.NextLSN != 5 || !first.HasMore {
+		t.Fatalf("first page = %+v", first)
+	}
+	second, err := cat.ListPartitions(ctx, ListPartitionsRequest{AfterKey: first.NextAfterKey, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListPartitions(after) error = %v", err)
+	}
+	if len(second.Partitions) != 1 || second.Partitions[0].Partition != 9 || second.Partitions[0].Head.NextLSN != 0 || second.HasMore {
+		t.Fatalf("second page = %+v", second)
+	}
+	if _, err := cat.ListPartitions(ctx, ListPartitionsRequest{AfterKey: "x"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("ListPartitions(bad cursor) error = %v, want %v", err, ErrInvalidRequest)
+	}
+}
+
 func TestMemoryCatalogRejectsExpectedNextLSNMismatch(t *testing.T) {
 	t.Parallel()
 
@@ -192,6 +223,205 @@ func TestMemoryCatalogWriterEpochFence(t *testing.T) {
 	}
 }
 
+func TestMemoryCatalogSealRejectsWritersAndIsFenced(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	stale := mustOpenWriter(t, cat, 1, 1)
+	owner := mustOpenWriter(t, cat, 1, 2)
+	if _, err := owner.AppendSegment(ctx, testSegment(1, 0, 2, owner.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	if _, err := stale.(SealWriterSession).SealPartition(ctx); !errors.Is(err, ErrStaleWriter) {
+		t.Fatalf("SealPartition(stale) error = %v, want %v", err, ErrStaleWriter)
+	}
+
+	sealed, err := owner.(SealWriterSession).SealPartition(ctx)
+	if err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	if !sealed.Sealed || sealed.NextLSN != 3 {
+		t.Fatalf("sealed head = %+v", sealed)
+	}
+	if _, err := owner.AppendSegment(ctx, testSegment(1, 3, 4, owner.Epoch())); !errors.Is(err, ErrPartitionSealed) {
+		t.Fatalf("AppendSegment(sealed) error = %v, want %v", err, ErrPartitionSealed)
+	}
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{3}); !errors.Is(err, ErrPartitionSealed) {
+		t.Fatalf("OpenWriter(sealed) error = %v, want %v", err, ErrPartitionSealed)
+	}
+}
+
+func TestMemoryCatalogReplaceSegmentSaturatesLegacyRetainedBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	owner := mustOpenWriter(t, cat, 1, 1)
+	first := testSegment(1, 0, 2, owner.Epoch())
+	if _, err := owner.AppendSegment(ctx, first); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	// Heads written before byte accounting carry zero retained bytes.
+	cat.mu.Lock()
+	cat.partitions[1].state.RetainedBytes = 0
+	cat.mu.Unlock()
+
+	replacement := first
+	replacement.URI += "-redacted"
+	replacement.SegmentUUID[15] = 0xff
+	replacement.SizeBytes = 600
+	replacement.RedactedUnixMS = 1
+	replacement.ChainedSegmentHash, replacement.ChainedTrailerHash = first.ChainedHashes()
+	head, err := owner.(RedactionWriterSession).ReplaceSegment(ctx, first, replacement)
+	if err != nil {
+		t.Fatalf("ReplaceSegment() error = %v", err)
+	}
+	if head.RetainedBytes != replacement.SizeBytes {
+		t.Fatalf("RetainedBytes = %d, want %d", head.RetainedBytes, replacement.SizeBytes)
+	}
+}
+
+func TestMemoryCatalogReplaceSegmentSwapsRefInPlace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	stale := mustOpenWriter(t, cat, 1, 1)
+	owner := mustOpenWriter(t, cat, 1, 2)
+	first := testSegment(1, 0, 2, owner.Epoch())
+	second := testSegment(1, 3, 5, owner.Epoch())
+	for _, segment := range []pmeta.SegmentRef{first, second} {
+		if _, err := owner.AppendSegment(ctx, segment); err != nil {
+			t.Fatalf("AppendSegment() error = %v", err)
+		}
+	}
+
+	replacement := first
+	replacement.URI += "-redacted"
+	replacement.SegmentUUID[15] = 0xff
+	replacement.SizeBytes = 600
+	replacement.RedactedUnixMS = 1
+	replacement.ChainedSegmentHash, replacement.ChainedTrailerHash = first.ChainedHashes()
+	if _, err := stale.(RedactionWriterSession).ReplaceSegment(ctx, first, replacement); !errors.Is(err, ErrStaleWriter) {
+		t.Fatalf("ReplaceSegment(stale) error = %v, want %v", err, ErrStaleWriter)
+	}
+	unredacted := replacement
+	unredacted.RedactedUnixMS = 0
+	unredacted.ChainedSegmentHash, unredacted.ChainedTrailerHash = 0, 0
+	if _, err := owner.(RedactionWriterSession).ReplaceSegment(ctx, first, unredacted); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("ReplaceSegment(unstamped) error = %v, want %v", err, ErrInvalidRequest)
+	}
+
+	head, err := owner.(RedactionWriterSession).ReplaceSegment(ctx, first, replacement)
+	if err != nil {
+		t.Fatalf("ReplaceSegment() error = %v", err)
+	}
+	if head.NextLSN != 6 || head.SegmentCount != 2 || head.RetainedBytes != 1112 || head.LastSegment != second {
+		t.Fatalf("ReplaceSegment() head = %+v", head)
+	}
+	found, ok, err := cat.FindSegment(ctx, 1, 1)
+	if err != nil || !ok || found != replacement {
+		t.Fatalf("FindSegment() = %+v, %v, %v; want replacement", found, ok, err)
+	}
+	if again, err := owner.(RedactionWriterSession).ReplaceSegment(ctx, first, replacement); err != nil || again != head {
+		t.Fatalf("ReplaceSegment(again) = %+v, %v; want %+v", again, err, head)
+	}
+	other := replacement
+	other.URI += "-again"
+	other.SegmentUUID[14] = 0xff
+	if _, err := owner.(RedactionWriterSession).ReplaceSegment(ctx, first, other); !errors.Is(err, ErrConflict) {
+		t.Fatalf("ReplaceSegment(replaced) error = %v, want %v", err, ErrConflict)
+	}
+}
+
+func TestMemoryCatalogDeletePartitionHidesAndFencesPartition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	ws := mustOpenWriter(t, cat, 1, 1)
+	if _, err := ws.AppendSegment(ctx, testSegment(1, 0, 2, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	if _, deleted, err := cat.DeletePartition(ctx, 1); err != nil || !deleted {
+		t.Fatalf("DeletePartition() = %v, %v", deleted, err)
+	}
+	if _, deleted, err := cat.DeletePartition(ctx, 1); err != nil || deleted {
+		t.Fatalf("DeletePartition(again) = %v, %v; want false, nil", deleted, err)
+	}
+
+	if _, err := cat.LoadPartition(ctx, 1); !errors.Is(err, ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(deleted) error = %v, want %v", err, ErrPartitionDeleted)
+	}
+	if _, err := ws.AppendSegment(ctx, testSegment(1, 3, 4, ws.Epoch())); !errors.Is(err, ErrStaleWriter) {
+		t.Fatalf("AppendSegment(deleted) error = %v, want %v", err, ErrStaleWriter)
+	}
+	if _, err := cat.OpenWriter(ctx, 1, [16]byte{2}); !errors.Is(err, ErrPartitionDeleted) {
+		t.Fatalf("OpenWriter(deleted) error = %v, want %v", err, ErrPartitionDeleted)
+	}
+	page, err := cat.ListPartitions(ctx, ListPartitionsRequest{IncludeHeads: true})
+	if err != nil {
+		t.Fatalf("ListPartitions() error = %v", err)
+	}
+	if len(page.Partitions) != 0 {
+		t.Fatalf("ListPartitions(heads) = %+v, want deleted partition hidden", page.Partitions)
+	}
+}
+
+func TestMemoryCatalogLegalHoldBlocksRetentionAndDelete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	ws := mustOpenWriter(t, cat, 1, 1)
+	if _, err := ws.AppendSegment(ctx, testSegment(1, 0, 5, ws.Epoch())); err != nil {
+		t.Fatalf("AppendSegment() error = %v", err)
+	}
+	if _, err := cat.SetPartitionHold(ctx, 1, LegalHold{Active: true, Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold() error = %v", err)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{
+		Version:       RetentionRequestVersion,
+		PolicyVersion: 1,
+		BeforeLSN:     3,
+	}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	retentionWriter := ws.(RetentionWriterSession)
+	if _, err := retentionWriter.ApplyPendingRetention(ctx); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("ApplyPendingRetention(held) error = %v, want %v", err, ErrLegalHold)
+	}
+	if _, _, err := cat.DeletePartition(ctx, 1); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeletePartition(held) error = %v, want %v", err, ErrLegalHold)
+	}
+
+	if _, err := cat.SetStreamHold(ctx, LegalHold{Active: true}); err != nil {
+		t.Fatalf("SetStreamHold() error = %v", err)
+	}
+	if _, err := cat.SetPartitionHold(ctx, 1, LegalHold{Reason: "case-7"}); err != nil {
+		t.Fatalf("SetPartitionHold(clear) error = %v", err)
+	}
+	if _, _, err := cat.DeletePartition(ctx, 1); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeletePartition(stream held) error = %v, want %v", err, ErrLegalHold)
+	}
+	if _, err := cat.SetStreamHold(ctx, LegalHold{}); err != nil {
+		t.Fatalf("SetStreamHold(clear) error = %v", err)
+	}
+	holds, err := cat.LoadLegalHolds(ctx, 1)
+	if err != nil || holds.Active() || holds.Partition.Reason != "case-7" {
+		t.Fatalf("LoadLegalHolds() = %+v, %v", holds, err)
+	}
+
+	result, err := retentionWriter.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.AppliedRetentionLSN != 3 {
+		t.Fatalf("ApplyPendingRetention(released) = %+v, %v", result, err)
+	}
+	if _, deleted, err := cat.DeletePartition(ctx, 1); err != nil || !deleted {
+		t.Fatalf("DeletePartition(released) = %v, %v", deleted, err)
+	}
+}
+
 func TestMemoryCatalogIdempotentRetryOfLastAppend(t *testing.T) {
 	t.Parallel()
 
@@ -347,6 +577,103 @@ func TestMemoryCatalogFindSegment(t *testing.T) {
 	}
 }
 
+func TestMemoryCatalogProveSegmentAgainstCheckpoints(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	ws := mustOpenWriter(t, cat, 1, 1)
+	var prev pmeta.Hash
+	for i := uint64(0); i < 5; i++ {
+		segment := testChainedSegment(prev, 1, i*5, i*5+4, ws.Epoch())
+		if _, err := ws.AppendSegment(ctx, segment); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", i, err)
+		}
+		prev = segment.ChainHash
+	}
+
+	unlinked := testChainedSegment(pmeta.Hash{0xee}, 1, 25, 29, ws.Epoch())
+	if _, err := ws.AppendSegment(ctx, unlinked); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("AppendSegment(unlinked) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	forged := testChainedSegment(prev, 1, 25, 29, ws.Epoch())
+	forged.RecordsRoot[0] ^= 1
+	if _, err := ws.AppendSegment(ctx, forged); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("AppendSegment(forged chain) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	// Once chaining has started, a successor cannot drop out of the chain.
+	if _, err := ws.AppendSegment(ctx, testSegment(1, 25, 29, ws.Epoch())); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("AppendSegment(unchained successor) error = %v, want %v", err, ErrInvalidSegment)
+	}
+
+	head, err := cat.LoadPartition(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadPartition() error = %v", err)
+	}
+	if head.TreeRoot.IsZero() || head.TreeSize != 5 {
+		t.Fatalf("head tree = %d %s, want five chained segments", head.TreeSize, head.TreeRoot)
+	}
+	for lsn := uint64(0); lsn < head.NextLSN; lsn += 3 {
+		p, err := cat.ProveSegment(ctx, 1, lsn, 0)
+		if err != nil {
+			t.Fatalf("ProveSegment(%d) error = %v", lsn, err)
+		}
+		if p.Segment.BaseLSN > lsn || p.Segment.LastLSN < lsn {
+			t.Fatalf("ProveSegment(%d) segment = [%d,%d]", lsn, p.Segment.BaseLSN, p.Segment.LastLSN)
+		}
+		if err := proof.VerifySegment(proof.CheckpointFromHead(head), p); err != nil {
+			t.Fatalf("VerifySegment(%d) error = %v", lsn, err)
+		}
+	}
+	if _, err := cat.ProveSegment(ctx, 1, head.NextLSN, 0); !errors.Is(err, ErrSegmentNotFound) {
+		t.Fatalf("ProveSegment(next_lsn) error = %v, want %v", err, ErrSegmentNotFound)
+	}
+
+	// A checkpoint published earlier stays provable, and retention changes
+	// neither the root nor older proofs.
+	older, err := cat.ProveSegment(ctx, 1, 7, 2)
+	if err != nil {
+		t.Fatalf("ProveSegment(tree_size=2) error = %v", err)
+	}
+	if older.Checkpoint.TreeSize != 2 || older.Checkpoint.NextLSN != 10 {
+		t.Fatalf("checkpoint(2) = %+v, want two segments ending at 10", older.Checkpoint)
+	}
+	if _, err := cat.ProveSegment(ctx, 1, 12, 2); !errors.Is(err, ErrSegmentNotFound) {
+		t.Fatalf("ProveSegment(lsn past checkpoint) error = %v, want %v", err, ErrSegmentNotFound)
+	}
+	request := RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 5, CreatedUnixMS: 1}
+	if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	retained, err := ws.(RetentionWriterSession).ApplyPendingRetention(ctx)
+	if err != nil {
+		t.Fatalf("ApplyPendingRetention() error = %v", err)
+	}
+	if retained.Head.TreeRoot != head.TreeRoot || retained.Head.TreeSize != head.TreeSize {
+		t.Fatalf("retention changed the tree: %+v", retained.Head)
+	}
+	again, err := cat.ProveSegment(ctx, 1, 7, 2)
+	if err != nil || again.Checkpoint != older.Checkpoint {
+		t.Fatalf("ProveSegment(tree_size=2, retained) = %+v, %v; want checkpoint %+v", again.Checkpoint, err, older.Checkpoint)
+	}
+	if err := proof.VerifySegment(older.Checkpoint, again); err != nil {
+		t.Fatalf("VerifySegment(tree_size=2, retained) error = %v", err)
+	}
+	if _, err := cat.ProveSegment(ctx, 1, 2, 0); !errors.Is(err, ErrSegmentNotFound) {
+		t.Fatalf("ProveSegment(retained away) error = %v, want %v", err, ErrSegmentNotFound)
+	}
+	consistency, err := cat.ProveConsistency(ctx, 1, 2, 0)
+	if err != nil {
+		t.Fatalf("ProveConsistency(2, head) error = %v", err)
+	}
+	if err := proof.VerifyConsistency(older.Checkpoint, proof.CheckpointFromHead(head), consistency); err != nil {
+		t.Fatalf("VerifyConsistency(2, head) error = %v", err)
+	}
+	if _, err := cat.ProveConsistency(ctx, 1, 1, 0); !errors.Is(err, ErrSegmentNotFound) {
+		t.Fatalf("ProveConsistency(retained away) error = %v, want %v", err, ErrSegmentNotFound)
+	}
+}
+
 func TestMemoryCatalogListSegmentsIsPagedAndBounded(t *testing.T) {
 	t.Parallel()
 
@@ -491,6 +818,14 @@ func mustOpenWriter(t *testing.T, cat *MemoryCatalog, partition uint32, id byte)
 	return ws
 }
 
+// testChainedSegment returns testSegment with a records root and a chain
+// link to prev.
+func testChainedSegment(prev pmeta.Hash, partition uint32, baseLSN uint64, lastLSN uint64, epoch uint64) pmeta.SegmentRef {
+	segment := testSegment(partition, baseLSN, lastLSN, epoch)
+	segment.RecordsRoot = pmeta.Hash{byte(baseLSN), byte(lastLSN), 1}
+	return proof.Chain(prev, segment)
+}
+
 func testSegment(partition uint32, baseLSN uint64, lastLSN uint64, epoch uint64) pmeta.SegmentRef {
 	var uuid [16]byte
 	uuid[0] = byte(partition)
@@ -71,6 +71,97 @@ func TestMemoryCatalogRetentionIsMonotonicAndSegmentGranular(t *testing.T) {
 	}
 }
 
+func TestMemoryCatalogRetentionResolvesTimestampToSegmentBoundary(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	ws := mustOpenWriter(t, cat, 1, 1)
+	for base := uint64(0); base < 30; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegment(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	retention := ws.(RetentionWriterSession)
+
+	request := RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 1, BeforeTimestampMS: 20, CreatedUnixMS: 10}
+	if _, err := cat.RequestRetention(ctx, 1, request); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	result, err := retention.ApplyPendingRetention(ctx)
+	if err != nil {
+		t.Fatalf("ApplyPendingRetention() error = %v", err)
+	}
+	if !result.Applied || result.Head.OldestLSN != 20 || result.Head.AppliedRetentionLSN != 20 {
+		t.Fatalf("timestamp retention result = %+v", result)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 2, BeforeTimestampMS: 19}); !errors.Is(err, ErrRetentionRegression) {
+		t.Fatalf("older timestamp error = %v, want %v", err, ErrRetentionRegression)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 2, BeforeLSN: 25}); err != nil {
+		t.Fatalf("RequestRetention(lsn) error = %v", err)
+	}
+	if _, err := retention.ApplyPendingRetention(ctx); err != nil {
+		t.Fatalf("ApplyPendingRetention(lsn) error = %v", err)
+	}
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 3, BeforeTimestampMS: 21}); err != nil {
+		t.Fatalf("RequestRetention(timestamp) error = %v", err)
+	}
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.AppliedRetentionLSN != 25 || result.Head.OldestLSN != 20 {
+		t.Fatalf("ApplyPendingRetention(timestamp below applied) = %+v err=%v, want boundary kept", result, err)
+	}
+}
+
+func TestMemoryCatalogRetentionCapsRetainedBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := NewMemoryCatalog()
+	ws := mustOpenWriter(t, cat, 1, 1)
+	for base := uint64(0); base < 40; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegment(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	retention := ws.(RetentionWriterSession)
+
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 1, BeforeLSN: 5, MaxRetainedBytes: 1100}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	result, err := retention.ApplyPendingRetention(ctx)
+	if err != nil {
+		t.Fatalf("ApplyPendingRetention() error = %v", err)
+	}
+	if !result.Applied || result.Head.OldestLSN != 20 || result.Head.AppliedRetentionLSN != 20 || result.Head.RetainedBytes != 2*512 {
+		t.Fatalf("size retention result = %+v", result.Head)
+	}
+
+	if _, err := cat.RequestRetention(ctx, 1, RetentionRequest{Version: RetentionRequestVersion, PolicyVersion: 2, BeforeLSN: 35, MaxRetainedBytes: 1100}); err != nil {
+		t.Fatalf("RequestRetention(lsn) error = %v", err)
+	}
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || result.Head.OldestLSN != 30 || result.Head.AppliedRetentionLSN != 35 || result.Head.RetainedBytes != 512 {
+		t.Fatalf("lsn over size retention = %+v err=%v", result.Head, err)
+	}
+
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || result.Applied {
+		t.Fatalf("ApplyPendingRetention(within cap) = %+v err=%v, want no-op", result, err)
+	}
+	for base := uint64(40); base < 60; base += 10 {
+		if _, err := ws.AppendSegment(ctx, testSegment(1, base, base+9, ws.Epoch())); err != nil {
+			t.Fatalf("AppendSegment(%d) error = %v", base, err)
+		}
+	}
+	// The same request applies again once appends grow the partition past it.
+	result, err = retention.ApplyPendingRetention(ctx)
+	if err != nil || !result.Applied || result.Head.OldestLSN != 40 || result.Head.RetainedBytes != 2*512 ||
+		result.Head.AppliedRetentionVersion != 2 {
+		t.Fatalf("ApplyPendingRetention(grown past cap) = %+v err=%v", result.Head, err)
+	}
+}
+
 func TestMemoryCatalogRetentionRequestRejectsRegressionAndAcceptsRetry(t *testing.T) {
 	t.Parallel()
 
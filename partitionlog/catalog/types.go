@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 const (
@@ -13,6 +15,11 @@ const (
 	RetentionRequestVersion uint16 = 1
 )
 
+const (
+	DefaultPartitionPageLimit = 1000
+	MaxPartitionPageLimit     = 1000
+)
+
 // Reader exposes the bounded read-only catalog surface.
 type Reader interface {
 	LoadPartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, error)
@@ -38,6 +45,53 @@ type TimestampLookupResult struct {
 	Found   bool
 }
 
+// PartitionLister enumerates the partitions that exist in one stream. It is
+// implemented by catalogs that can discover partitions without an external
+// inventory.
+type PartitionLister interface {
+	ListPartitions(ctx context.Context, req ListPartitionsRequest) (PartitionPage, error)
+}
+
+// ListPartitionsRequest resumes partition discovery after AfterKey, the opaque
+// NextAfterKey of a previous page. An empty AfterKey starts from the
+// beginning. Deleted partitions are skipped, as LoadPartition rejects them,
+// unless IncludeDeleted is set; lifecycle workers set it to find the deleted
+// partitions that still await their purge. Purged partitions are never
+// listed. A page may hold fewer than Limit partitions while HasMore is set.
+type ListPartitionsRequest struct {
+	AfterKey       string
+	Limit          int
+	IncludeHeads   bool
+	IncludeDeleted bool
+}
+
+func (r ListPartitionsRequest) NormalizedLimit() int {
+	switch {
+	case r.Limit <= 0:
+		return DefaultPartitionPageLimit
+	case r.Limit > MaxPartitionPageLimit:
+		return MaxPartitionPageLimit
+	default:
+		return r.Limit
+	}
+}
+
+// PartitionInfo identifies one discovered partition. Head is populated only
+// when the request set IncludeHeads.
+type PartitionInfo struct {
+	Partition uint32
+	Head      pmeta.PartitionHead
+}
+
+// PartitionPage is one page of discovered partitions. Partition order is the
+// catalog's storage order, not numeric order. A page may hold fewer than the
+// requested limit while HasMore is true.
+type PartitionPage struct {
+	Partitions   []PartitionInfo
+	NextAfterKey string
+	HasMore      bool
+}
+
 // WriterManager owns the write-side catalog surface for one partition.
 type WriterManager interface {
 	// InitializePartition creates an empty partition head at a chosen next LSN
@@ -62,6 +116,38 @@ type WriterSession interface {
 	AppendSegment(ctx context.Context, segment pmeta.SegmentRef) (pmeta.PartitionHead, error)
 }
 
+// SigningWriterManager is implemented by catalogs that sign partition heads.
+// OpenSigningWriter behaves like OpenWriter, and every head the session
+// commits, including the fencing head written by the open itself, carries
+// signer's signature.
+type SigningWriterManager interface {
+	OpenSigningWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer) (WriterSession, error)
+}
+
+// IdleWriterManager is implemented by catalogs that record when a writer
+// releases its fence. OpenIdleWriter behaves like OpenWriter, or like
+// OpenSigningWriter when signer is set, but takes the fence only when no writer
+// holds it: the partition never had a writer, or its last writer released the
+// fence. Otherwise it fails with ErrWriterActive and leaves the head as it was.
+type IdleWriterManager interface {
+	OpenIdleWriter(ctx context.Context, partition uint32, writerID [16]byte, signer signing.Signer) (WriterSession, error)
+}
+
+// ReleasableWriterSession is implemented by writer sessions that can release
+// their fence when the writer closes. The head keeps its epoch, so a released
+// fence is taken over like any other, but OpenIdleWriter may now take it too.
+// Releasing a fence the session no longer holds does nothing.
+type ReleasableWriterSession interface {
+	ReleaseWriter(ctx context.Context) error
+}
+
+// SigningPartitionDeleter is implemented by catalogs that sign partition
+// heads. DeleteSignedPartition behaves like DeletePartition and signs the
+// tombstone head with signer.
+type SigningPartitionDeleter interface {
+	DeleteSignedPartition(ctx context.Context, partition uint32, signer signing.Signer) (pmeta.PartitionHead, bool, error)
+}
+
 // RetentionManager stores the latest desired retention boundary for each
 // partition. It never changes partition visibility itself.
 type RetentionManager interface {
@@ -76,14 +162,117 @@ type RetentionWriterSession interface {
 	ApplyPendingRetention(ctx context.Context) (RetentionApplyResult, error)
 }
 
+// PartitionDeleter is implemented by catalogs that can tombstone a whole
+// partition. A tombstoned partition is hidden from readers immediately and
+// fences out every writer; physical reclamation happens later. Deleting an
+// absent or already deleted partition reports deleted false.
+type PartitionDeleter interface {
+	DeletePartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, bool, error)
+}
+
+// PartitionShredder is implemented by catalogs that record crypto-shreds.
+// ShredPartition tombstones like DeletePartition and marks the tombstone
+// shredded, so later reads report ErrPartitionShredded as well as
+// ErrPartitionDeleted. Marking an existing tombstone shredded also reports
+// true. A nil signer is allowed for partitions whose head is unsigned.
+type PartitionShredder interface {
+	ShredPartition(ctx context.Context, partition uint32, signer signing.Signer) (pmeta.PartitionHead, bool, error)
+}
+
+// LegalHoldManager is implemented by catalogs that store durable legal holds
+// for single partitions and for the whole stream. While either hold covering a
+// partition is active, retention cannot advance its OldestLSN, the partition
+// cannot be deleted, and lifecycle workers delete none of its objects.
+type LegalHoldManager interface {
+	SetPartitionHold(ctx context.Context, partition uint32, hold LegalHold) (LegalHold, error)
+	SetStreamHold(ctx context.Context, hold LegalHold) (LegalHold, error)
+	LoadLegalHolds(ctx context.Context, partition uint32) (LegalHolds, error)
+}
+
+// LegalHold is one durable hold marker. Clearing a hold stores it inactive so
+// the last reason and update time stay visible.
+type LegalHold struct {
+	Active        bool
+	Reason        string
+	UpdatedUnixMS int64
+}
+
+// LegalHolds are the holds that cover one partition.
+type LegalHolds struct {
+	Partition LegalHold
+	Stream    LegalHold
+}
+
+// Active reports whether any hold covers the partition.
+func (h LegalHolds) Active() bool {
+	return h.Partition.Active || h.Stream.Active
+}
+
+// RedactionWriterSession is implemented by writer sessions that can swap one
+// committed segment for a rewritten copy through the fenced head mutation
+// path. The replacement covers the same LSN and timestamp range, so readers
+// see the same history with different record bytes. Replacing a segment with
+// the already committed replacement is idempotent.
+type RedactionWriterSession interface {
+	ReplaceSegment(ctx context.Context, current, replacement pmeta.SegmentRef) (pmeta.PartitionHead, error)
+}
+
+// SegmentProver is implemented by catalogs that can prove a retained segment
+// is committed under a partition checkpoint, and that a later checkpoint
+// extends an earlier one. Checkpoints are named by tree size; a zero size
+// names the current head. Sizes whose last segment retention already dropped
+// return ErrSegmentNotFound.
+type SegmentProver interface {
+	ProveSegment(ctx context.Context, partition uint32, lsn, treeSize uint64) (proof.SegmentProof, error)
+	ProveConsistency(ctx context.Context, partition uint32, fromSize, toSize uint64) (proof.ConsistencyProof, error)
+}
+
+// SealWriterSession is implemented by writer sessions that can permanently
+// seal their partition through the fenced head mutation path. Sealing is
+// idempotent for the session that sealed the partition.
+type SealWriterSession interface {
+	SealPartition(ctx context.Context) (pmeta.PartitionHead, error)
+}
+
 // RetentionRequest is the latest monotonic retention command for one
-// partition. BeforeLSN means records below that LSN should be retired at
-// immutable-segment granularity.
+// partition. Each rule retires history at immutable-segment granularity:
+//
+//   - BeforeLSN retires records below that LSN.
+//   - BeforeTimestampMS retires every segment whose records are all older than
+//     that timestamp. A request names at most one of BeforeLSN and
+//     BeforeTimestampMS.
+//   - MaxRetainedBytes retires the oldest segments until the partition's
+//     retained bytes are at most the cap. Unlike the other rules it is
+//     re-evaluated on every apply of the same PolicyVersion.
+//
+// The partition writer resolves the rules against its own fenced head and the
+// most restrictive one wins. MinRetainedRecords then caps the result so at
+// least that many of the newest records stay retained. A request without an
+// LSN rule leaves BeforeLSN zero.
 type RetentionRequest struct {
-	Version       uint16 `json:"version"`
-	PolicyVersion uint64 `json:"policy_version"`
-	BeforeLSN     uint64 `json:"before_lsn"`
-	CreatedUnixMS int64  `json:"created_unix_ms"`
+	Version            uint16 `json:"version"`
+	PolicyVersion      uint64 `json:"policy_version"`
+	BeforeLSN          uint64 `json:"before_lsn"`
+	BeforeTimestampMS  int64  `json:"before_timestamp_ms,omitempty"`
+	MaxRetainedBytes   uint64 `json:"max_retained_bytes,omitempty"`
+	MinRetainedRecords uint64 `json:"min_retained_records,omitempty"`
+	CreatedUnixMS      int64  `json:"created_unix_ms"`
+}
+
+// ByTimestamp reports whether the request names a timestamp boundary.
+func (r RetentionRequest) ByTimestamp() bool {
+	return r.BeforeTimestampMS > 0
+}
+
+// BySize reports whether the request caps retained bytes.
+func (r RetentionRequest) BySize() bool {
+	return r.MaxRetainedBytes > 0
+}
+
+// ByLSN reports whether the request names an LSN boundary. A zero BeforeLSN
+// is a boundary only when no other field is set.
+func (r RetentionRequest) ByLSN() bool {
+	return r.BeforeLSN > 0 || (!r.ByTimestamp() && !r.BySize() && r.MinRetainedRecords == 0)
 }
 
 func (r RetentionRequest) Validate() error {
@@ -96,9 +285,66 @@ func (r RetentionRequest) Validate() error {
 	if r.CreatedUnixMS < 0 {
 		return fmt.Errorf("%w: negative retention created_unix_ms=%d", ErrInvalidRequest, r.CreatedUnixMS)
 	}
+	if r.BeforeTimestampMS < 0 {
+		return fmt.Errorf("%w: negative retention before_timestamp_ms=%d", ErrInvalidRequest, r.BeforeTimestampMS)
+	}
+	if r.ByTimestamp() && r.BeforeLSN != 0 {
+		return fmt.Errorf("%w: retention sets both before_lsn=%d and before_timestamp_ms=%d", ErrInvalidRequest, r.BeforeLSN, r.BeforeTimestampMS)
+	}
 	return nil
 }
 
+// CheckRetentionAdvance compares a requested retention command with the stored
+// one. It reports done when requested is an idempotent repeat of current, and
+// ErrRetentionRegression when requested does not advance the policy. Only
+// boundaries both requests set are ordered here, and byte and record limits
+// may change freely; the partition writer keeps the applied boundary monotonic
+// when it resolves them.
+func CheckRetentionAdvance(current, requested RetentionRequest) (done bool, err error) {
+	switch {
+	case current.PolicyVersion == requested.PolicyVersion &&
+		current.BeforeLSN == requested.BeforeLSN &&
+		current.BeforeTimestampMS == requested.BeforeTimestampMS &&
+		current.MaxRetainedBytes == requested.MaxRetainedBytes &&
+		current.MinRetainedRecords == requested.MinRetainedRecords:
+		return true, nil
+	case requested.PolicyVersion <= current.PolicyVersion:
+		return true, fmt.Errorf("%w: policy_version=%d current=%d", ErrRetentionRegression, requested.PolicyVersion, current.PolicyVersion)
+	case current.ByTimestamp() && requested.ByTimestamp() && requested.BeforeTimestampMS < current.BeforeTimestampMS:
+		return true, fmt.Errorf("%w: before_timestamp_ms=%d current=%d", ErrRetentionRegression, requested.BeforeTimestampMS, current.BeforeTimestampMS)
+	case current.ByLSN() && requested.ByLSN() && requested.BeforeLSN < current.BeforeLSN:
+		return true, fmt.Errorf("%w: before_lsn=%d current=%d", ErrRetentionRegression, requested.BeforeLSN, current.BeforeLSN)
+	default:
+		return false, nil
+	}
+}
+
+// ResolveRetentionTarget combines the rules of request into the LSN below
+// which head retires history. timestampLSN and sizeLSN are the whole-segment
+// boundaries a catalog resolved for BeforeTimestampMS and MaxRetainedBytes;
+// they are ignored when the request does not set those rules. The target never
+// falls below the applied boundary, and an LSN rule below it is a regression.
+func ResolveRetentionTarget(head pmeta.PartitionHead, request RetentionRequest, timestampLSN, sizeLSN uint64) (uint64, error) {
+	target := head.AppliedRetentionLSN
+	if request.ByLSN() {
+		if request.BeforeLSN < head.AppliedRetentionLSN {
+			return 0, fmt.Errorf("%w: before_lsn=%d applied=%d", ErrRetentionRegression, request.BeforeLSN, head.AppliedRetentionLSN)
+		}
+		target = max(target, min(request.BeforeLSN, head.NextLSN))
+	}
+	if request.ByTimestamp() {
+		target = max(target, min(timestampLSN, head.NextLSN))
+	}
+	if request.BySize() {
+		target = max(target, min(sizeLSN, head.NextLSN))
+	}
+	if request.MinRetainedRecords > 0 {
+		limit := head.NextLSN - min(request.MinRetainedRecords, head.NextLSN)
+		target = max(min(target, limit), head.AppliedRetentionLSN)
+	}
+	return target, nil
+}
+
 type RetentionApplyResult struct {
 	Head    pmeta.PartitionHead
 	Request RetentionRequest
@@ -151,3 +397,160 @@ func ValidateAppendSegment(streamID string, partition uint32, expectedNextLSN ui
 	}
 	return nil
 }
+
+// ValidateSegmentChain checks that segment links to prevChainHash, the
+// ChainHash of the partition's current last segment, and that its ChainHash
+// commits to its own range, hashes and RecordsRoot. Unchained segments,
+// written before hash chaining, are accepted only while the partition's last
+// segment is unchained too; once chaining has started, every successor must
+// extend the chain.
+func ValidateSegmentChain(prevChainHash pmeta.Hash, segment pmeta.SegmentRef) error {
+	if segment.ChainHash.IsZero() && segment.PrevChainHash.IsZero() {
+		if !prevChainHash.IsZero() {
+			return fmt.Errorf("%w: unchained segment after chain_hash=%s", ErrInvalidSegment, prevChainHash)
+		}
+		return nil
+	}
+	switch {
+	case segment.PrevChainHash != prevChainHash:
+		return fmt.Errorf("%w: prev_chain_hash=%s want=%s", ErrInvalidSegment, segment.PrevChainHash, prevChainHash)
+	case segment.ChainHash != proof.ChainHash(segment):
+		return fmt.Errorf("%w: chain_hash does not match segment", ErrInvalidSegment)
+	}
+	return nil
+}
+
+// ProofTree is the retained part of one partition's segment tree: Base, the
+// compact range over the chained segments retention dropped, and Segments,
+// the retained chained segments in LSN order. Catalogs build one to answer
+// SegmentProver calls.
+type ProofTree struct {
+	Head     pmeta.PartitionHead
+	Base     proof.Range
+	Segments []pmeta.SegmentRef
+}
+
+// Checkpoint returns the checkpoint over the first treeSize segments, or the
+// head's checkpoint when treeSize is zero.
+func (t ProofTree) Checkpoint(treeSize uint64) (proof.Checkpoint, error) {
+	if treeSize == 0 || treeSize == t.Head.TreeSize {
+		return proof.CheckpointFromHead(t.Head), nil
+	}
+	if treeSize > t.Head.TreeSize || treeSize <= t.Base.Size || treeSize-t.Base.Size > uint64(len(t.Segments)) {
+		return proof.Checkpoint{}, fmt.Errorf("%w: partition=%d tree_size=%d retained=[%d,%d]", ErrSegmentNotFound, t.Head.Partition, treeSize, t.Base.Size+1, t.Head.TreeSize)
+	}
+	root, err := t.tree().Root(treeSize)
+	if err != nil {
+		return proof.Checkpoint{}, err
+	}
+	return proof.Checkpoint{
+		StreamID:  t.Head.StreamID,
+		Partition: t.Head.Partition,
+		NextLSN:   t.Segments[treeSize-t.Base.Size-1].NextLSN(),
+		TreeSize:  treeSize,
+		TreeRoot:  root,
+	}, nil
+}
+
+// ProveSegment proves the segment covering lsn under the checkpoint of
+// treeSize.
+func (t ProofTree) ProveSegment(lsn, treeSize uint64) (proof.SegmentProof, error) {
+	checkpoint, err := t.Checkpoint(treeSize)
+	if err != nil {
+		return proof.SegmentProof{}, err
+	}
+	if !t.Head.HasLastSegment || lsn < t.Head.OldestLSN || lsn >= checkpoint.NextLSN {
+		return proof.SegmentProof{}, fmt.Errorf("%w: partition=%d lsn=%d", ErrSegmentNotFound, t.Head.Partition, lsn)
+	}
+	i := firstSegmentAtOrAfter(t.Segments, lsn)
+	if i == len(t.Segments) || lsn < t.Segments[i].BaseLSN {
+		return proof.SegmentProof{}, fmt.Errorf("%w: partition=%d lsn=%d", proof.ErrUnchained, t.Head.Partition, lsn)
+	}
+	step, err := t.tree().InclusionStep(t.Base.Size+uint64(i), checkpoint.TreeSize)
+	if err != nil {
+		return proof.SegmentProof{}, err
+	}
+	return proof.SegmentProof{Checkpoint: checkpoint, Segment: t.Segments[i], Inclusion: step}, nil
+}
+
+// ProveConsistency proves that the checkpoint of toSize extends the
+// checkpoint of fromSize.
+func (t ProofTree) ProveConsistency(fromSize, toSize uint64) (proof.ConsistencyProof, error) {
+	if fromSize == 0 {
+		return proof.ConsistencyProof{}, fmt.Errorf("%w: consistency from empty tree", ErrInvalidRequest)
+	}
+	older, err := t.Checkpoint(fromSize)
+	if err != nil {
+		return proof.ConsistencyProof{}, err
+	}
+	newer, err := t.Checkpoint(toSize)
+	if err != nil {
+		return proof.ConsistencyProof{}, err
+	}
+	if older.TreeSize > newer.TreeSize {
+		return proof.ConsistencyProof{}, fmt.Errorf("%w: consistency from tree_size=%d to smaller %d", ErrInvalidRequest, older.TreeSize, newer.TreeSize)
+	}
+	path, err := t.tree().ConsistencyPath(older.TreeSize, newer.TreeSize)
+	if err != nil {
+		return proof.ConsistencyProof{}, err
+	}
+	return proof.ConsistencyProof{Old: older, New: newer, Path: path}, nil
+}
+
+func (t ProofTree) tree() proof.Tree {
+	leaves := make([]pmeta.Hash, len(t.Segments))
+	for i, segment := range t.Segments {
+		leaves[i] = proof.SegmentLeaf(segment.ChainHash)
+	}
+	return proof.Tree{Base: t.Base, Leaves: leaves}
+}
+
+// AppendSegmentTree returns tree with segment's leaf appended, or tree
+// unchanged for a segment written before hash chaining.
+func AppendSegmentTree(tree proof.Range, segment pmeta.SegmentRef) proof.Range {
+	if segment.ChainHash.IsZero() {
+		return tree
+	}
+	return tree.Append(proof.SegmentLeaf(segment.ChainHash))
+}
+
+func sameChainedHashes(a, b pmeta.SegmentRef) bool {
+	aSegment, aTrailer := a.ChainedHashes()
+	bSegment, bTrailer := b.ChainedHashes()
+	return aSegment == bSegment && aTrailer == bTrailer
+}
+
+// ValidateReplacementSegment checks that replacement may take the place of the
+// committed segment current. Both must cover the same stream, partition, LSN
+// range, timestamp range, and hash chain; the replacement must be a new
+// object.
+func ValidateReplacementSegment(current, replacement pmeta.SegmentRef) error {
+	if err := replacement.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSegment, err)
+	}
+	switch {
+	case replacement.StreamID != current.StreamID:
+		return fmt.Errorf("%w: replacement stream_id=%q current=%q", ErrInvalidRequest, replacement.StreamID, current.StreamID)
+	case replacement.Partition != current.Partition:
+		return fmt.Errorf("%w: replacement partition=%d current=%d", ErrInvalidRequest, replacement.Partition, current.Partition)
+	case replacement.BaseLSN != current.BaseLSN || replacement.LastLSN != current.LastLSN:
+		return fmt.Errorf("%w: replacement lsn range=[%d,%d] current=[%d,%d]", ErrInvalidRequest, replacement.BaseLSN, replacement.LastLSN, current.BaseLSN, current.LastLSN)
+	case replacement.MinTimestampMS != current.MinTimestampMS || replacement.MaxTimestampMS != current.MaxTimestampMS:
+		return fmt.Errorf("%w: replacement timestamp range differs", ErrInvalidRequest)
+	case replacement.WriterEpoch != current.WriterEpoch || replacement.WriterTag != current.WriterTag:
+		return fmt.Errorf("%w: replacement writer identity differs", ErrInvalidRequest)
+	case replacement.RecordsRoot != current.RecordsRoot || replacement.PrevChainHash != current.PrevChainHash || replacement.ChainHash != current.ChainHash:
+		return fmt.Errorf("%w: replacement changes the segment hash chain", ErrInvalidRequest)
+	case !sameChainedHashes(current, replacement):
+		return fmt.Errorf("%w: replacement changes the chained segment hashes", ErrInvalidRequest)
+	case !replacement.ChainHash.IsZero() && replacement.ChainHash != proof.ChainHash(replacement):
+		return fmt.Errorf("%w: replacement chain_hash does not match segment", ErrInvalidRequest)
+	case replacement.Producers != current.Producers:
+		return fmt.Errorf("%w: replacement changes producer state", ErrInvalidRequest)
+	case replacement.URI == current.URI || replacement.SegmentUUID == current.SegmentUUID:
+		return fmt.Errorf("%w: replacement reuses the current object", ErrInvalidRequest)
+	case replacement.RedactedUnixMS == 0:
+		return fmt.Errorf("%w: replacement has no redacted_unix_ms", ErrInvalidRequest)
+	}
+	return nil
+}
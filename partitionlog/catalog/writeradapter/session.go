@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
 	"github.com/ankur-anand/unijord/partitionlog/writer"
 )
 
@@ -19,6 +20,9 @@ type Session struct {
 
 var _ writer.Session = (*Session)(nil)
 var _ writer.RetentionSession = (*Session)(nil)
+var _ writer.RedactionSession = (*Session)(nil)
+var _ writer.SealSession = (*Session)(nil)
+var _ writer.ReleaseSession = (*Session)(nil)
 
 func New(inner catalog.WriterSession) (*Session, error) {
 	if inner == nil {
@@ -97,18 +101,86 @@ func (s *Session) ApplyPendingRetention(ctx context.Context) (writer.RetentionRe
 		},
 	}
 	return writer.RetentionResult{
-		Snapshot:      s.snapshot,
-		PolicyVersion: result.Request.PolicyVersion,
-		RequestedLSN:  result.Head.AppliedRetentionLSN,
-		Applied:       result.Applied,
+		Snapshot:             s.snapshot,
+		PolicyVersion:        result.Request.PolicyVersion,
+		RequestedLSN:         result.Head.AppliedRetentionLSN,
+		RequestedTimestampMS: result.Request.BeforeTimestampMS,
+		Applied:              result.Applied,
 	}, nil
 }
 
+func (s *Session) ReplaceSegment(ctx context.Context, current, replacement pmeta.SegmentRef) (writer.Snapshot, error) {
+	if s == nil || s.inner == nil {
+		return writer.Snapshot{}, fmt.Errorf("%w: nil catalog session", writer.ErrInvalidSession)
+	}
+	inner, ok := s.inner.(catalog.RedactionWriterSession)
+	if !ok {
+		return writer.Snapshot{}, writer.ErrRedactionUnsupported
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nextHead, err := inner.ReplaceSegment(ctx, current, replacement)
+	if err != nil {
+		return writer.Snapshot{}, mapRedactionError(err)
+	}
+	s.snapshot = writer.Snapshot{
+		Head: nextHead,
+		Identity: writer.WriterIdentity{
+			Epoch: s.snapshot.Identity.Epoch,
+			Tag:   s.snapshot.Identity.Tag,
+		},
+	}
+	return s.snapshot, nil
+}
+
+func (s *Session) SealPartition(ctx context.Context) (writer.Snapshot, error) {
+	if s == nil || s.inner == nil {
+		return writer.Snapshot{}, fmt.Errorf("%w: nil catalog session", writer.ErrInvalidSession)
+	}
+	inner, ok := s.inner.(catalog.SealWriterSession)
+	if !ok {
+		return writer.Snapshot{}, writer.ErrSealUnsupported
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nextHead, err := inner.SealPartition(ctx)
+	if err != nil {
+		return writer.Snapshot{}, mapSealError(err)
+	}
+	s.snapshot = writer.Snapshot{
+		Head: nextHead,
+		Identity: writer.WriterIdentity{
+			Epoch: s.snapshot.Identity.Epoch,
+			Tag:   s.snapshot.Identity.Tag,
+		},
+	}
+	return s.snapshot, nil
+}
+
+// ReleaseWriter releases the catalog fence when the catalog records releases.
+// Otherwise it does nothing: the fence simply stays with this writer.
+func (s *Session) ReleaseWriter(ctx context.Context) error {
+	if s == nil || s.inner == nil {
+		return fmt.Errorf("%w: nil catalog session", writer.ErrInvalidSession)
+	}
+	inner, ok := s.inner.(catalog.ReleasableWriterSession)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return inner.ReleaseWriter(ctx)
+}
+
 func mapCatalogError(err error) error {
 	if err == nil {
 		return nil
 	}
-	if errors.Is(err, catalog.ErrStaleWriter) {
+	if errors.Is(err, catalog.ErrStaleWriter) || errors.Is(err, catalog.ErrPartitionSealed) {
+		// A sealed partition never accepts this writer again.
 		return fmt.Errorf("%w: %w", writer.ErrStaleWriter, err)
 	}
 	if errors.Is(err, catalog.ErrCommitIndeterminate) {
@@ -129,3 +201,29 @@ func mapRetentionError(err error) error {
 	}
 	return fmt.Errorf("%w: %w", writer.ErrRetentionFailed, err)
 }
+
+func mapRedactionError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, catalog.ErrStaleWriter) {
+		return fmt.Errorf("%w: %w", writer.ErrStaleWriter, err)
+	}
+	if errors.Is(err, catalog.ErrRedactionUnsupported) {
+		return fmt.Errorf("%w: %w", writer.ErrRedactionUnsupported, err)
+	}
+	return fmt.Errorf("%w: %w", writer.ErrRedactionFailed, err)
+}
+
+func mapSealError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, catalog.ErrStaleWriter) {
+		return fmt.Errorf("%w: %w", writer.ErrStaleWriter, err)
+	}
+	if errors.Is(err, catalog.ErrSealUnsupported) {
+		return fmt.Errorf("%w: %w", writer.ErrSealUnsupported, err)
+	}
+	return fmt.Errorf("%w: %w", writer.ErrSealFailed, err)
+}
@@ -119,6 +119,24 @@ func TestSessionMapsIndeterminateCommit(t *testing.T) {
 	}
 }
 
+func TestSessionMapsSealedPartitionToStaleWriter(t *testing.T) {
+	t.Parallel()
+
+	inner := &errorWriterSession{
+		head: pmeta.PartitionHead{Partition: 7, WriterEpoch: 1},
+		id:   [16]byte{1},
+		err:  catalog.ErrPartitionSealed,
+	}
+	session, err := writeradapter.New(inner)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_, err = session.PublishSegment(context.Background(), writer.PublishRequest{})
+	if !errors.Is(err, writer.ErrStaleWriter) || !errors.Is(err, catalog.ErrPartitionSealed) {
+		t.Fatalf("PublishSegment() error = %v, want %v wrapping %v", err, writer.ErrStaleWriter, catalog.ErrPartitionSealed)
+	}
+}
+
 func TestSessionRejectsNil(t *testing.T) {
 	t.Parallel()
 
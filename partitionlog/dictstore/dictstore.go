@@ -0,0 +1,154 @@
+// Package dictstore holds the trained zstd dictionaries of one stream. Each
+// dictionary is an immutable object named by its dictionary ID, so a
+// zstd-dict segment stays readable for as long as its dictionary object is
+// kept. Objects hold the standard zstd dictionary encoding and can be used
+// directly by any zstd implementation.
+package dictstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+)
+
+var (
+	ErrInvalidOptions = errors.New("dictstore: invalid options")
+	ErrCorrupt        = errors.New("dictstore: corrupt dictionary")
+	// ErrConflict reports a different dictionary already published under the
+	// same ID. Published dictionaries are never replaced.
+	ErrConflict = errors.New("dictstore: dictionary id conflict")
+)
+
+type Object = blobstore.Object
+
+// Backend is the conditional object protocol used to store dictionaries.
+type Backend interface {
+	Get(ctx context.Context, key string) (Object, error)
+	CompareAndSwap(ctx context.Context, key string, expectedToken string, body []byte) (Object, bool, error)
+}
+
+type Options struct {
+	// StreamID identifies the stream whose dictionaries this store holds.
+	StreamID string
+	// CatalogPrefix is the object-catalog root. Dictionaries live beside the
+	// stream's catalog objects.
+	CatalogPrefix string
+}
+
+// Store is a segblock.DictionaryProvider backed by immutable objects.
+// Loaded dictionaries are cached for the life of the Store.
+type Store struct {
+	backend Backend
+	opts    Options
+
+	mu    sync.Mutex
+	dicts map[uint32]*segblock.Dictionary
+}
+
+var _ segblock.DictionaryProvider = (*Store)(nil)
+
+// New opens the compression dictionary store of opts.StreamID, whose
+// dictionaries are stored beside the catalog under opts.CatalogPrefix.
+func New(backend Backend, opts Options) (*Store, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("%w: nil backend", ErrInvalidOptions)
+	}
+	streamID, err := keylayout.CanonicalStreamID(opts.StreamID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	opts.StreamID = streamID
+	return &Store{backend: backend, opts: opts, dicts: make(map[uint32]*segblock.Dictionary)}, nil
+}
+
+// Publish stores raw, an encoded zstd dictionary, and returns its ID.
+// Publishing the same dictionary again is a no-op; publishing different
+// bytes under an existing ID reports ErrConflict.
+func (s *Store) Publish(ctx context.Context, raw []byte) (uint32, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	d, err := segblock.NewDictionary(raw)
+	if err != nil {
+		return 0, err
+	}
+	path := s.path(d.ID())
+	_, created, err := s.backend.CompareAndSwap(ctx, path, "", raw)
+	if err != nil {
+		return 0, fmt.Errorf("dictstore: publish %s: %w", path, err)
+	}
+	if !created {
+		obj, err := s.backend.Get(ctx, path)
+		if err != nil {
+			return 0, fmt.Errorf("dictstore: publish %s: %w", path, err)
+		}
+		if !bytes.Equal(obj.Body, raw) {
+			return 0, fmt.Errorf("%w: %s", ErrConflict, path)
+		}
+	}
+	s.cache(d)
+	return d.ID(), nil
+}
+
+// Train builds a dictionary of at most maxSize bytes from samples with
+// segblock.TrainDictionary and publishes it.
+func (s *Store) Train(ctx context.Context, samples [][]byte, maxSize int) (uint32, error) {
+	raw, err := segblock.TrainDictionary(samples, maxSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.Publish(ctx, raw)
+}
+
+// Dictionary loads the dictionary with id. A missing object reports
+// segblock.ErrUnknownDictionary.
+func (s *Store) Dictionary(ctx context.Context, id uint32) (*segblock.Dictionary, error) {
+	s.mu.Lock()
+	d, ok := s.dicts[id]
+	s.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path := s.path(id)
+	obj, err := s.backend.Get(ctx, path)
+	if errors.Is(err, blobstore.ErrObjectNotFound) {
+		return nil, fmt.Errorf("%w: %s", segblock.ErrUnknownDictionary, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	d, err = segblock.NewDictionary(obj.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrCorrupt, path, err)
+	}
+	if d.ID() != id {
+		return nil, fmt.Errorf("%w: %s holds id=%d", ErrCorrupt, path, d.ID())
+	}
+	return s.cache(d), nil
+}
+
+// cache stores d unless a dictionary with its ID is already cached, and
+// returns the cached one.
+func (s *Store) cache(d *segblock.Dictionary) *segblock.Dictionary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.dicts[d.ID()]; ok {
+		return cached
+	}
+	s.dicts[d.ID()] = d
+	return d
+}
+
+func (s *Store) path(id uint32) string {
+	return catalogblob.StreamDictionaryPath(s.opts.CatalogPrefix, s.opts.StreamID, id)
+}
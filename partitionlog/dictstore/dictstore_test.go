@@ -0,0 +1,101 @@
+package dictstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+)
+
+func TestStoreTrainPublishAndLoad(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	s := newTestStore(t, objects, "orders")
+
+	id, err := s.Train(ctx, testSamples(200), 8<<10)
+	if err != nil {
+		t.Fatalf("Train() error = %v", err)
+	}
+	obj, err := objects.Get(ctx, catalogblob.StreamDictionaryPath("catalog", "orders", id))
+	if err != nil {
+		t.Fatalf("Get(dictionary object) error = %v", err)
+	}
+	if again, err := s.Publish(ctx, obj.Body); err != nil || again != id {
+		t.Fatalf("Publish(same) = %d, %v; want %d", again, err, id)
+	}
+
+	// A second store over the same objects loads the published bytes.
+	other := newTestStore(t, objects, "orders")
+	d, err := other.Dictionary(ctx, id)
+	if err != nil {
+		t.Fatalf("Dictionary() error = %v", err)
+	}
+	if d.ID() != id || !bytes.Equal(d.Bytes(), obj.Body) {
+		t.Fatalf("Dictionary() id=%d, want %d with published bytes", d.ID(), id)
+	}
+	cached, err := other.Dictionary(ctx, id)
+	if err != nil || cached != d {
+		t.Fatalf("Dictionary(cached) = %p, %v; want %p", cached, err, d)
+	}
+
+	if _, err := other.Dictionary(ctx, id+1); !errors.Is(err, segblock.ErrUnknownDictionary) {
+		t.Fatalf("Dictionary(missing) error = %v, want %v", err, segblock.ErrUnknownDictionary)
+	}
+	if _, err := newTestStore(t, objects, "billing").Dictionary(ctx, id); !errors.Is(err, segblock.ErrUnknownDictionary) {
+		t.Fatalf("Dictionary(other stream) error = %v, want %v", err, segblock.ErrUnknownDictionary)
+	}
+}
+
+func TestStorePublishRejectsConflictAndCorruption(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	s := newTestStore(t, objects, "orders")
+
+	raw, err := segblock.TrainDictionary(testSamples(100), 4<<10)
+	if err != nil {
+		t.Fatalf("TrainDictionary() error = %v", err)
+	}
+	id, err := s.Publish(ctx, raw)
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	// Different content under the same ID must not replace the object.
+	changed := append([]byte(nil), raw...)
+	changed[len(changed)-1] ^= 0xff
+	if _, err := s.Publish(ctx, changed); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Publish(changed) error = %v, want %v", err, ErrConflict)
+	}
+	if _, err := s.Publish(ctx, []byte("not a dictionary")); !errors.Is(err, segblock.ErrInvalidDictionary) {
+		t.Fatalf("Publish(garbage) error = %v, want %v", err, segblock.ErrInvalidDictionary)
+	}
+
+	path := catalogblob.StreamDictionaryPath("catalog", "orders", id+1)
+	if _, _, err := objects.CompareAndSwap(ctx, path, "", raw); err != nil {
+		t.Fatalf("CompareAndSwap() error = %v", err)
+	}
+	if _, err := newTestStore(t, objects, "orders").Dictionary(ctx, id+1); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Dictionary(misplaced) error = %v, want %v", err, ErrCorrupt)
+	}
+}
+
+func newTestStore(t *testing.T, objects *blobmemory.Store, streamID string) *Store {
+	t.Helper()
+	s, err := New(objects, Options{StreamID: streamID, CatalogPrefix: "catalog"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return s
+}
+
+func testSamples(n int) [][]byte {
+	samples := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"event":"order_created","order_id":"ord-%05d","currency":"USD","amount_cents":%d}`, i, i*37%10000)))
+	}
+	return samples
+}
@@ -0,0 +1,171 @@
+// Package fs builds a complete partitionlog store on a local directory.
+//
+// The store is intended for development, tests, and single-host deployments.
+// Catalog metadata, segment objects, and lifecycle state all live below one
+// root directory using the same object keys as the cloud providers.
+package fs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+	segmentsink "github.com/ankur-anand/unijord/partitionlog/blob/sink"
+	fssink "github.com/ankur-anand/unijord/partitionlog/blob/sink/fs"
+	fssource "github.com/ankur-anand/unijord/partitionlog/blob/source/fs"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	fscatalog "github.com/ankur-anand/unijord/partitionlog/catalog/blob/fs"
+	"github.com/ankur-anand/unijord/partitionlog/dictstore"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/keyring"
+	"github.com/ankur-anand/unijord/partitionlog/reader"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+// Options configures a complete directory-backed partitionlog store.
+type Options struct {
+	// Root is the local directory that holds every object. It is created if
+	// it does not exist.
+	Root string
+
+	// Prefix is the common object key root. Catalog metadata defaults to
+	// <prefix>/catalog and segment objects default to <prefix>/segments.
+	Prefix string
+
+	// StreamID scopes catalog metadata and segment object keys to one stream.
+	StreamID string
+
+	// CatalogPrefix overrides the catalog metadata prefix.
+	CatalogPrefix string
+
+	// SegmentRootPrefix overrides the segment sink root. Final segment objects
+	// are placed under <segment-root>/segments.
+	SegmentRootPrefix string
+
+	// SegmentContentType is recorded for committed segment objects.
+	SegmentContentType string
+}
+
+// Store wires directory-backed catalog metadata, segment writes, and segment
+// reads.
+type Store struct {
+	catalog       *catalogblob.Catalog
+	sink          *segmentsink.Factory
+	source        *fssource.Store
+	admin         *fscatalog.Backend
+	streamID      string
+	catalogPrefix string
+}
+
+// New builds a complete directory-backed partitionlog store.
+func New(opts Options) (*Store, error) {
+	root := rootPrefix(opts.Prefix)
+	streamID, err := normalizeStreamID(opts.StreamID)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := fscatalog.NewBackend(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	catPrefix := catalogPrefix(root, opts.CatalogPrefix)
+	cat, err := catalogblob.New(admin, catalogblob.Options{
+		Prefix:   catPrefix,
+		StreamID: streamID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	multipartStore, err := fssink.NewStore(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	sinkFactory, err := segmentsink.New(multipartStore, segmentsink.Options{
+		Prefix:      segmentRootPrefix(root, opts.SegmentRootPrefix),
+		ContentType: opts.SegmentContentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := fssource.NewStore(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{catalog: cat, sink: sinkFactory, source: source, admin: admin, streamID: streamID, catalogPrefix: catPrefix}, nil
+}
+
+func normalizeStreamID(streamID string) (string, error) {
+	streamID, err := keylayout.CanonicalStreamID(streamID)
+	if err != nil {
+		return "", fmt.Errorf("partitionlog/fs: %w", err)
+	}
+	return streamID, nil
+}
+
+func (s *Store) WriterManager() catalog.WriterManager {
+	return s.catalog
+}
+
+func (s *Store) RetentionManager() catalog.RetentionManager {
+	return s.catalog
+}
+
+func (s *Store) ReaderCatalog() catalog.Reader {
+	return s.catalog
+}
+
+func (s *Store) SinkFactory() writer.SinkFactory {
+	return s.sink
+}
+
+func (s *Store) SegmentStore() reader.SegmentStore {
+	return s.source
+}
+
+// NewReclaimer creates an explicitly scheduled object lifecycle worker.
+func (s *Store) NewReclaimer(opts lifecycle.Options) (*lifecycle.Reclaimer, error) {
+	opts.StreamID = s.streamID
+	opts.CatalogPrefix = s.catalogPrefix
+	return lifecycle.New(s.admin, s.catalog, s.sink.Layout(), opts)
+}
+
+// NewKeyring opens this stream's keyring; see keyring.New.
+func (s *Store) NewKeyring(root segblock.KeyProvider) (*keyring.Keyring, error) {
+	return keyring.New(s.admin, root, keyring.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
+// NewDictionaryStore opens this stream's dictionary store; see dictstore.New.
+func (s *Store) NewDictionaryStore() (*dictstore.Store, error) {
+	return dictstore.New(s.admin, dictstore.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
+func rootPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return segmentsink.DefaultPrefix
+	}
+	return prefix
+}
+
+func catalogPrefix(root string, override string) string {
+	override = strings.Trim(override, "/")
+	if override != "" {
+		return override
+	}
+	return path.Join(root, "catalog")
+}
+
+func segmentRootPrefix(root string, override string) string {
+	override = strings.Trim(override, "/")
+	if override != "" {
+		return override
+	}
+	return root
+}
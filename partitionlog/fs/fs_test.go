@@ -0,0 +1,34 @@
+package fs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	plfs "github.com/ankur-anand/unijord/partitionlog/fs"
+	"github.com/ankur-anand/unijord/partitionlog/internal/lifecycletest"
+)
+
+func TestLifecycleConformance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	lifecycletest.Run(t, ctx, newStore(t), lifecycletest.Config{Partition: 801})
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	if _, err := plfs.New(plfs.Options{}); err == nil {
+		t.Fatal("New(empty root) error = nil, want error")
+	}
+}
+
+func newStore(t testing.TB) *plfs.Store {
+	t.Helper()
+	store, err := plfs.New(plfs.Options{
+		Root:     t.TempDir(),
+		StreamID: "local/lifecycle",
+	})
+	if err != nil {
+		t.Fatalf("fs.New() error = %v", err)
+	}
+	return store
+}
@@ -0,0 +1,49 @@
+package fs
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/registry"
+	fscatalog "github.com/ankur-anand/unijord/partitionlog/catalog/blob/fs"
+)
+
+// Namespace opens stream-scoped stores that share one root directory and key
+// prefix.
+type Namespace struct {
+	opts Options
+}
+
+var _ partitionlog.StoreProvider = (*Namespace)(nil)
+
+// NewNamespace returns a partitionlog.StoreProvider over opts.
+func NewNamespace(opts Options) (*Namespace, error) {
+	if opts.StreamID != "" {
+		return nil, fmt.Errorf("partitionlog/fs: namespace options set stream id %q", opts.StreamID)
+	}
+	if _, err := fscatalog.NewBackend(opts.Root); err != nil {
+		return nil, err
+	}
+	return &Namespace{opts: opts}, nil
+}
+
+// StreamStore implements partitionlog.StoreProvider.
+func (n *Namespace) StreamStore(streamID string) (partitionlog.Store, error) {
+	opts := n.opts
+	opts.StreamID = streamID
+	return New(opts)
+}
+
+// NewRegistry opens the namespace's timeline registry; see
+// partitionlog.StoreProvider.
+func (n *Namespace) NewRegistry(opts registry.Options) (*registry.Registry, error) {
+	backend, err := fscatalog.NewBackend(n.opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = path.Join(rootPrefix(n.opts.Prefix), "registry")
+	}
+	return registry.New(backend, opts)
+}
@@ -14,8 +14,11 @@ import (
 	"github.com/ankur-anand/unijord/partitionlog/catalog"
 	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
 	gcscatalog "github.com/ankur-anand/unijord/partitionlog/catalog/blob/gcs"
+	"github.com/ankur-anand/unijord/partitionlog/dictstore"
 	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/keyring"
 	"github.com/ankur-anand/unijord/partitionlog/reader"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/writer"
 )
 
@@ -128,6 +131,16 @@ func (s *Store) NewReclaimer(opts lifecycle.Options) (*lifecycle.Reclaimer, erro
 	return lifecycle.New(s.admin, s.catalog, s.sink.Layout(), opts)
 }
 
+// NewKeyring opens this stream's keyring; see keyring.New.
+func (s *Store) NewKeyring(root segblock.KeyProvider) (*keyring.Keyring, error) {
+	return keyring.New(s.admin, root, keyring.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
+// NewDictionaryStore opens this stream's dictionary store; see dictstore.New.
+func (s *Store) NewDictionaryStore() (*dictstore.Store, error) {
+	return dictstore.New(s.admin, dictstore.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
 func rootPrefix(prefix string) string {
 	prefix = strings.Trim(prefix, "/")
 	if prefix == "" {
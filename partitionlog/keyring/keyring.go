@@ -0,0 +1,381 @@
+// Package keyring holds one key encryption key per stream partition. Segment
+// data keys are wrapped under their partition key, and partition keys are
+// wrapped under a root KeyProvider, usually a KMS key per tenant.
+//
+// Destroying a partition key crypto-shreds every segment of that partition at
+// once, without rewriting or deleting objects. Destroying a root key in the
+// KMS shreds every partition wrapped under it once the keyrings that cached
+// those partition keys drop them, within Options.KeyCacheTTL.
+package keyring
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+var (
+	ErrInvalidOptions = errors.New("keyring: invalid options")
+	ErrCorruptKey     = errors.New("keyring: corrupt partition key")
+	ErrConflict       = errors.New("keyring: partition key contention")
+)
+
+const (
+	DefaultCASAttempts = 8
+	DefaultKeyCacheTTL = 5 * time.Minute
+	keyVersion         = 1
+	keyIDPrefix        = "partition/"
+)
+
+type Object = blobstore.Object
+
+// Backend is the conditional object protocol used to store partition keys.
+type Backend interface {
+	Get(ctx context.Context, key string) (Object, error)
+	CompareAndSwap(ctx context.Context, key string, expectedToken string, body []byte) (Object, bool, error)
+}
+
+type Options struct {
+	// StreamID identifies the stream whose partition keys this keyring holds.
+	StreamID string
+	// CatalogPrefix is the object-catalog root. Partition keys live beside the
+	// catalog objects of their partition.
+	CatalogPrefix string
+	CASAttempts   int
+	// KeyCacheTTL bounds how long an unwrapped partition key is reused
+	// without asking the root KeyProvider again, so a root key destroyed in
+	// the KMS takes effect within it. Zero selects DefaultKeyCacheTTL.
+	KeyCacheTTL time.Duration
+}
+
+// Keyring is a segblock.KeyProvider with one key encryption key per
+// partition. A partition key is created on the first WrapKey for the
+// partition. Every UnwrapKey reloads the stored key, so a destroy made by any
+// process takes effect on the next segment open.
+type Keyring struct {
+	backend Backend
+	root    segblock.KeyProvider
+	opts    Options
+	now     func() time.Time
+
+	mu sync.Mutex
+	// keys caches unwrapped partition keys by key ID for KeyCacheTTL. It saves
+	// root unwraps only; the stored key decides whether a key ID is still live.
+	keys map[string]cachedKey
+}
+
+type cachedKey struct {
+	aead    cipher.AEAD
+	expires time.Time
+}
+
+var _ segblock.KeyProvider = (*Keyring)(nil)
+
+// keyFile is one partition key object. Generation counts keys created for the
+// partition; every generation up to DestroyedGeneration is destroyed.
+type keyFile struct {
+	Version             int    `json:"version"`
+	StreamID            string `json:"stream_id,omitempty"`
+	Partition           uint32 `json:"partition"`
+	Generation          uint64 `json:"generation"`
+	RootKeyID           string `json:"root_key_id,omitempty"`
+	WrappedKey          []byte `json:"wrapped_key,omitempty"`
+	DestroyedGeneration uint64 `json:"destroyed_generation,omitempty"`
+	DestroyedUnixMS     int64  `json:"destroyed_unix_ms,omitempty"`
+}
+
+func (f keyFile) live() bool {
+	return f.Generation > f.DestroyedGeneration
+}
+
+// New returns the keyring of opts.StreamID. Partition keys are stored beside
+// the catalog under opts.CatalogPrefix and wrapped under root.
+func New(backend Backend, root segblock.KeyProvider, opts Options) (*Keyring, error) {
+	return newKeyring(backend, root, opts, time.Now)
+}
+
+func newKeyring(backend Backend, root segblock.KeyProvider, opts Options, now func() time.Time) (*Keyring, error) {
+	if backend == nil {
+		return nil, fmt.Errorf("%w: nil backend", ErrInvalidOptions)
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: nil root key provider", ErrInvalidOptions)
+	}
+	streamID, err := keylayout.CanonicalStreamID(opts.StreamID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
+	opts.StreamID = streamID
+	if opts.CASAttempts < 0 {
+		return nil, fmt.Errorf("%w: negative CAS attempts", ErrInvalidOptions)
+	}
+	if opts.CASAttempts == 0 {
+		opts.CASAttempts = DefaultCASAttempts
+	}
+	if opts.KeyCacheTTL < 0 {
+		return nil, fmt.Errorf("%w: negative key cache TTL", ErrInvalidOptions)
+	}
+	if opts.KeyCacheTTL == 0 {
+		opts.KeyCacheTTL = DefaultKeyCacheTTL
+	}
+	return &Keyring{backend: backend, root: root, opts: opts, now: now, keys: make(map[string]cachedKey)}, nil
+}
+
+// KeyID returns the key ID of one partition key generation.
+func KeyID(partition uint32, generation uint64) string {
+	return fmt.Sprintf("%s%d/%d", keyIDPrefix, partition, generation)
+}
+
+// ParseKeyID reverses KeyID.
+func ParseKeyID(keyID string) (partition uint32, generation uint64, err error) {
+	rest, ok := strings.CutPrefix(keyID, keyIDPrefix)
+	partText, genText, ok2 := strings.Cut(rest, "/")
+	if !ok || !ok2 {
+		return 0, 0, fmt.Errorf("%w: %q", segblock.ErrUnknownKey, keyID)
+	}
+	partition64, err := strconv.ParseUint(partText, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %q", segblock.ErrUnknownKey, keyID)
+	}
+	generation, err = strconv.ParseUint(genText, 10, 64)
+	if err != nil || generation == 0 || KeyID(uint32(partition64), generation) != keyID {
+		return 0, 0, fmt.Errorf("%w: %q", segblock.ErrUnknownKey, keyID)
+	}
+	return uint32(partition64), generation, nil
+}
+
+// WrapKey wraps dataKey under the live key of partition, creating a new
+// partition key generation when none is live.
+func (k *Keyring) WrapKey(ctx context.Context, partition uint32, dataKey []byte) (string, []byte, error) {
+	file, aead, err := k.liveKey(ctx, partition)
+	if err != nil {
+		return "", nil, err
+	}
+	keyID := KeyID(partition, file.Generation)
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("keyring: wrap nonce: %w", err)
+	}
+	return keyID, aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// UnwrapKey returns the data key wrapped under keyID. A destroyed partition
+// key reports segblock.ErrKeyDestroyed.
+func (k *Keyring) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	partition, generation, err := ParseKeyID(keyID)
+	if err != nil {
+		return nil, err
+	}
+	file, _, err := k.load(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	if generation <= file.DestroyedGeneration {
+		return nil, fmt.Errorf("%w: %q destroyed at %d", segblock.ErrKeyDestroyed, keyID, file.DestroyedUnixMS)
+	}
+	if generation != file.Generation {
+		return nil, fmt.Errorf("%w: %q current generation=%d", segblock.ErrUnknownKey, keyID, file.Generation)
+	}
+	aead, err := k.partitionAEAD(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: wrapped key too short", segformat.ErrIntegrityMismatch)
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap key %q: %v", segformat.ErrIntegrityMismatch, keyID, err)
+	}
+	return dataKey, nil
+}
+
+// DestroyPartitionKey destroys the live key of partition. Every segment
+// wrapped under it becomes unreadable, and the next WrapKey creates a new
+// generation. It reports whether this call destroyed a live key.
+func (k *Keyring) DestroyPartitionKey(ctx context.Context, partition uint32) (bool, error) {
+	path := k.path(partition)
+	for attempt := 0; attempt < k.opts.CASAttempts; attempt++ {
+		file, token, err := k.load(ctx, partition)
+		if err != nil {
+			return false, err
+		}
+		if !file.live() {
+			return false, nil
+		}
+		keyID := KeyID(partition, file.Generation)
+		file.DestroyedGeneration = file.Generation
+		file.DestroyedUnixMS = k.now().UnixMilli()
+		file.RootKeyID = ""
+		file.WrappedKey = nil
+		body, err := json.Marshal(file)
+		if err != nil {
+			return false, err
+		}
+		_, swapped, err := k.backend.CompareAndSwap(ctx, path, token, body)
+		if err != nil {
+			return false, fmt.Errorf("keyring: destroy %s: %w", path, err)
+		}
+		if swapped {
+			k.mu.Lock()
+			delete(k.keys, keyID)
+			k.mu.Unlock()
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("%w: %s", ErrConflict, path)
+}
+
+// PartitionKeyDestroyed reports whether partition has destroyed keys and no
+// live one.
+func (k *Keyring) PartitionKeyDestroyed(ctx context.Context, partition uint32) (bool, error) {
+	file, _, err := k.load(ctx, partition)
+	if err != nil {
+		return false, err
+	}
+	return file.DestroyedGeneration > 0 && !file.live(), nil
+}
+
+func (k *Keyring) liveKey(ctx context.Context, partition uint32) (keyFile, cipher.AEAD, error) {
+	path := k.path(partition)
+	for attempt := 0; attempt < k.opts.CASAttempts; attempt++ {
+		file, token, err := k.load(ctx, partition)
+		if err != nil {
+			return keyFile{}, nil, err
+		}
+		if file.live() {
+			aead, err := k.partitionAEAD(ctx, file)
+			return file, aead, err
+		}
+
+		partitionKey, err := segblock.NewDataKey()
+		if err != nil {
+			return keyFile{}, nil, err
+		}
+		rootKeyID, wrapped, err := k.root.WrapKey(ctx, partition, partitionKey)
+		if err != nil {
+			return keyFile{}, nil, fmt.Errorf("keyring: wrap partition=%d key: %w", partition, err)
+		}
+		file.Version = keyVersion
+		file.StreamID = k.opts.StreamID
+		file.Partition = partition
+		file.Generation++
+		file.RootKeyID = rootKeyID
+		file.WrappedKey = wrapped
+		body, err := json.Marshal(file)
+		if err != nil {
+			return keyFile{}, nil, err
+		}
+		_, swapped, err := k.backend.CompareAndSwap(ctx, path, token, body)
+		if err != nil {
+			return keyFile{}, nil, fmt.Errorf("keyring: create %s: %w", path, err)
+		}
+		if !swapped {
+			continue
+		}
+		aead, err := newAEAD(partitionKey)
+		if err != nil {
+			return keyFile{}, nil, err
+		}
+		k.cache(KeyID(partition, file.Generation), aead)
+		return file, aead, nil
+	}
+	return keyFile{}, nil, fmt.Errorf("%w: %s", ErrConflict, path)
+}
+
+func (k *Keyring) partitionAEAD(ctx context.Context, file keyFile) (cipher.AEAD, error) {
+	keyID := KeyID(file.Partition, file.Generation)
+	k.mu.Lock()
+	cached, ok := k.keys[keyID]
+	k.mu.Unlock()
+	if ok && k.now().Before(cached.expires) {
+		return cached.aead, nil
+	}
+	partitionKey, err := k.root.UnwrapKey(ctx, file.RootKeyID, file.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("keyring: unwrap %q under root key %q: %w", keyID, file.RootKeyID, err)
+	}
+	aead, err := newAEAD(partitionKey)
+	if err != nil {
+		return nil, err
+	}
+	k.cache(keyID, aead)
+	return aead, nil
+}
+
+// cache stores aead under keyID until KeyCacheTTL passes, dropping every
+// expired entry on the way so keys no longer opened do not stay in memory.
+func (k *Keyring) cache(keyID string, aead cipher.AEAD) {
+	now := k.now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for id, cached := range k.keys {
+		if !now.Before(cached.expires) {
+			delete(k.keys, id)
+		}
+	}
+	k.keys[keyID] = cachedKey{aead: aead, expires: now.Add(k.opts.KeyCacheTTL)}
+}
+
+// load returns the stored key of partition, or an empty key with an empty
+// token when none was ever created.
+func (k *Keyring) load(ctx context.Context, partition uint32) (keyFile, string, error) {
+	if err := ctx.Err(); err != nil {
+		return keyFile{}, "", err
+	}
+	path := k.path(partition)
+	obj, err := k.backend.Get(ctx, path)
+	if errors.Is(err, blobstore.ErrObjectNotFound) {
+		return keyFile{Partition: partition}, "", nil
+	}
+	if err != nil {
+		return keyFile{}, "", err
+	}
+	var file keyFile
+	if err := json.Unmarshal(obj.Body, &file); err != nil {
+		return keyFile{}, "", fmt.Errorf("%w: decode %s: %v", ErrCorruptKey, path, err)
+	}
+	switch {
+	case file.Version != keyVersion:
+		return keyFile{}, "", fmt.Errorf("%w: %s version=%d", ErrCorruptKey, path, file.Version)
+	case file.StreamID != k.opts.StreamID:
+		return keyFile{}, "", fmt.Errorf("%w: %s stream_id=%q want=%q", ErrCorruptKey, path, file.StreamID, k.opts.StreamID)
+	case file.Partition != partition:
+		return keyFile{}, "", fmt.Errorf("%w: %s partition=%d", ErrCorruptKey, path, file.Partition)
+	case file.DestroyedGeneration > file.Generation:
+		return keyFile{}, "", fmt.Errorf("%w: %s destroyed_generation=%d generation=%d", ErrCorruptKey, path, file.DestroyedGeneration, file.Generation)
+	case file.live() && len(file.WrappedKey) == 0:
+		return keyFile{}, "", fmt.Errorf("%w: %s live key has no wrapped key", ErrCorruptKey, path)
+	}
+	return file, obj.Token, nil
+}
+
+func (k *Keyring) path(partition uint32) string {
+	return catalogblob.PartitionKeyPath(k.opts.CatalogPrefix, k.opts.StreamID, partition)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != segformat.DataKeySize {
+		return nil, fmt.Errorf("keyring: partition key size=%d want=%d", len(key), segformat.DataKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,229 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+func TestKeyringWrapUnwrapPerPartition(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	k := newTestKeyring(t, objects)
+	other := newTestKeyring(t, objects)
+
+	dataKey := bytes.Repeat([]byte{7}, segformat.DataKeySize)
+	keyID, wrapped, err := k.WrapKey(ctx, 3, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+	if keyID != "partition/3/1" {
+		t.Fatalf("WrapKey() keyID = %q, want partition/3/1", keyID)
+	}
+	again, _, err := k.WrapKey(ctx, 3, dataKey)
+	if err != nil || again != keyID {
+		t.Fatalf("WrapKey(again) = %q, %v; want %q", again, err, keyID)
+	}
+	fourID, _, err := k.WrapKey(ctx, 4, dataKey)
+	if err != nil || fourID != "partition/4/1" {
+		t.Fatalf("WrapKey(partition 4) = %q, %v", fourID, err)
+	}
+
+	// A second keyring over the same objects unwraps through the root key.
+	got, err := other.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey() error = %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Fatal("UnwrapKey() returned a different data key")
+	}
+	if _, err := other.UnwrapKey(ctx, fourID, wrapped); !errors.Is(err, segformat.ErrIntegrityMismatch) {
+		t.Fatalf("UnwrapKey(wrong partition) error = %v, want %v", err, segformat.ErrIntegrityMismatch)
+	}
+	for _, bad := range []string{"kek-1", "partition/3", "partition/3/0", "partition/x/1", "partition/03/1"} {
+		if _, err := k.UnwrapKey(ctx, bad, wrapped); !errors.Is(err, segblock.ErrUnknownKey) {
+			t.Fatalf("UnwrapKey(%q) error = %v, want %v", bad, err, segblock.ErrUnknownKey)
+		}
+	}
+}
+
+func TestKeyringDestroyPartitionKeyShredsEveryProcess(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	k := newTestKeyring(t, objects)
+	other := newTestKeyring(t, objects)
+
+	dataKey := bytes.Repeat([]byte{7}, segformat.DataKeySize)
+	keyID, wrapped, err := k.WrapKey(ctx, 3, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+	survivorID, survivor, err := k.WrapKey(ctx, 4, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey(partition 4) error = %v", err)
+	}
+	// Warm the other process's cache before the destroy.
+	if _, err := other.UnwrapKey(ctx, keyID, wrapped); err != nil {
+		t.Fatalf("UnwrapKey() error = %v", err)
+	}
+
+	destroyed, err := k.DestroyPartitionKey(ctx, 3)
+	if err != nil || !destroyed {
+		t.Fatalf("DestroyPartitionKey() = %v, %v; want true", destroyed, err)
+	}
+	for name, ring := range map[string]*Keyring{"destroyer": k, "other": other} {
+		if _, err := ring.UnwrapKey(ctx, keyID, wrapped); !errors.Is(err, segblock.ErrKeyDestroyed) {
+			t.Fatalf("%s UnwrapKey(destroyed) error = %v, want %v", name, err, segblock.ErrKeyDestroyed)
+		}
+	}
+	if _, err := other.UnwrapKey(ctx, survivorID, survivor); err != nil {
+		t.Fatalf("UnwrapKey(other partition) error = %v", err)
+	}
+	body, err := objects.Get(ctx, catalogblob.PartitionKeyPath("catalog", "orders", 3))
+	if err != nil {
+		t.Fatalf("Get(key object) error = %v", err)
+	}
+	if bytes.Contains(body.Body, []byte("wrapped_key")) {
+		t.Fatalf("destroyed key object still holds key material: %s", body.Body)
+	}
+	shredded, err := other.PartitionKeyDestroyed(ctx, 3)
+	if err != nil || !shredded {
+		t.Fatalf("PartitionKeyDestroyed() = %v, %v; want true", shredded, err)
+	}
+
+	again, err := other.DestroyPartitionKey(ctx, 3)
+	if err != nil || again {
+		t.Fatalf("DestroyPartitionKey(again) = %v, %v; want false", again, err)
+	}
+
+	// A later write starts a new generation; the old one stays destroyed.
+	nextID, nextWrapped, err := other.WrapKey(ctx, 3, dataKey)
+	if err != nil || nextID != "partition/3/2" {
+		t.Fatalf("WrapKey(after destroy) = %q, %v; want partition/3/2", nextID, err)
+	}
+	if _, err := k.UnwrapKey(ctx, nextID, nextWrapped); err != nil {
+		t.Fatalf("UnwrapKey(new generation) error = %v", err)
+	}
+	if _, err := k.UnwrapKey(ctx, keyID, wrapped); !errors.Is(err, segblock.ErrKeyDestroyed) {
+		t.Fatalf("UnwrapKey(old generation) error = %v, want %v", err, segblock.ErrKeyDestroyed)
+	}
+	if shredded, err := k.PartitionKeyDestroyed(ctx, 3); err != nil || shredded {
+		t.Fatalf("PartitionKeyDestroyed(regenerated) = %v, %v; want false", shredded, err)
+	}
+}
+
+func TestKeyringRootKeyLossIsNotShredding(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	k := newTestKeyring(t, objects)
+	dataKey := bytes.Repeat([]byte{7}, segformat.DataKeySize)
+	keyID, wrapped, err := k.WrapKey(ctx, 3, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+
+	rotated, err := segblock.NewStaticKeyProvider("root-2", map[string][]byte{"root-2": bytes.Repeat([]byte{2}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	other, err := New(objects, rotated, Options{StreamID: "orders", CatalogPrefix: "catalog"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_, err = other.UnwrapKey(ctx, keyID, wrapped)
+	if !errors.Is(err, segblock.ErrUnknownKey) || errors.Is(err, segblock.ErrKeyDestroyed) {
+		t.Fatalf("UnwrapKey(missing root) error = %v, want %v", err, segblock.ErrUnknownKey)
+	}
+}
+
+func TestKeyringRootKeyDestroyTakesEffectAfterCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	objects := blobmemory.New()
+	root := &revocableRoot{KeyProvider: newTestRoot(t)}
+	now := time.UnixMilli(1_776_263_000_000)
+	k, err := newKeyring(objects, root, Options{StreamID: "orders", CatalogPrefix: "catalog", KeyCacheTTL: time.Minute}, func() time.Time {
+		return now
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	dataKey := bytes.Repeat([]byte{7}, segformat.DataKeySize)
+	keyID, wrapped, err := k.WrapKey(ctx, 3, dataKey)
+	if err != nil {
+		t.Fatalf("WrapKey() error = %v", err)
+	}
+
+	root.destroyed = true
+	now = now.Add(59 * time.Second)
+	if _, err := k.UnwrapKey(ctx, keyID, wrapped); err != nil {
+		t.Fatalf("UnwrapKey(cached) error = %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := k.UnwrapKey(ctx, keyID, wrapped); !errors.Is(err, segblock.ErrKeyDestroyed) {
+		t.Fatalf("UnwrapKey(after TTL) error = %v, want %v", err, segblock.ErrKeyDestroyed)
+	}
+	if _, _, err := k.WrapKey(ctx, 3, dataKey); !errors.Is(err, segblock.ErrKeyDestroyed) {
+		t.Fatalf("WrapKey(after TTL) error = %v, want %v", err, segblock.ErrKeyDestroyed)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	root := newTestRoot(t)
+	objects := blobmemory.New()
+	for name, tc := range map[string]struct {
+		backend Backend
+		root    segblock.KeyProvider
+		opts    Options
+	}{
+		"nil backend":  {root: root, opts: Options{StreamID: "orders"}},
+		"nil root":     {backend: objects, opts: Options{StreamID: "orders"}},
+		"empty stream": {backend: objects, root: root},
+		"negative cas": {backend: objects, root: root, opts: Options{StreamID: "orders", CASAttempts: -1}},
+		"negative ttl": {backend: objects, root: root, opts: Options{StreamID: "orders", KeyCacheTTL: -1}},
+	} {
+		if _, err := New(tc.backend, tc.root, tc.opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s: New() error = %v, want %v", name, err, ErrInvalidOptions)
+		}
+	}
+}
+
+func newTestKeyring(t *testing.T, objects *blobmemory.Store) *Keyring {
+	t.Helper()
+	k, err := newKeyring(objects, newTestRoot(t), Options{StreamID: "orders", CatalogPrefix: "catalog"}, func() time.Time {
+		return time.UnixMilli(1_776_263_000_000)
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return k
+}
+
+func newTestRoot(t *testing.T) *segblock.StaticKeyProvider {
+	t.Helper()
+	root, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	return root
+}
+
+// revocableRoot stands in for a KMS key that can be destroyed.
+type revocableRoot struct {
+	segblock.KeyProvider
+	destroyed bool
+}
+
+func (r *revocableRoot) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if r.destroyed {
+		return nil, fmt.Errorf("%w: root %q", segblock.ErrKeyDestroyed, keyID)
+	}
+	return r.KeyProvider.UnwrapKey(ctx, keyID, wrapped)
+}
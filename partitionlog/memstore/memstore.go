@@ -0,0 +1,245 @@
+// Package memstore builds a complete in-memory partitionlog store.
+//
+// The store is intended for application tests that embed partitionlog.Log.
+// Catalog metadata, staged multipart parts, committed segments, and lifecycle
+// state share one object map that uses the same keys as the cloud providers, so
+// tests can assert on the persisted layout and run the lifecycle Reclaimer
+// without faking a provider SDK. Nothing survives process exit.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+	segmentsink "github.com/ankur-anand/unijord/partitionlog/blob/sink"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	catalogblob "github.com/ankur-anand/unijord/partitionlog/catalog/blob"
+	"github.com/ankur-anand/unijord/partitionlog/dictstore"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/keyring"
+	"github.com/ankur-anand/unijord/partitionlog/reader"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+// Options configures a complete in-memory partitionlog store.
+type Options struct {
+	// Prefix is the common object key root. Catalog metadata defaults to
+	// <prefix>/catalog and segment objects default to <prefix>/segments.
+	Prefix string
+
+	// StreamID scopes catalog metadata and segment object keys to one stream.
+	StreamID string
+
+	// CatalogPrefix overrides the catalog metadata prefix.
+	CatalogPrefix string
+
+	// SegmentRootPrefix overrides the segment sink root. Final segment objects
+	// are placed under <segment-root>/segments.
+	SegmentRootPrefix string
+
+	// SegmentContentType is passed to the segment sink. The in-memory object
+	// map does not retain content types.
+	SegmentContentType string
+}
+
+// Object describes one raw object held by the store.
+type Object struct {
+	Key       string
+	SizeBytes int
+	CreatedAt time.Time
+}
+
+// Store wires in-memory catalog metadata, segment writes, and segment reads.
+type Store struct {
+	objects       *blobmemory.Store
+	catalog       *catalogblob.Catalog
+	sink          *segmentsink.Factory
+	source        *segmentSource
+	streamID      string
+	catalogPrefix string
+}
+
+// New builds a complete in-memory partitionlog store.
+func New(opts Options) (*Store, error) {
+	return newStore(blobmemory.New(), opts)
+}
+
+func newStore(objects *blobmemory.Store, opts Options) (*Store, error) {
+	root := rootPrefix(opts.Prefix)
+	streamID, err := normalizeStreamID(opts.StreamID)
+	if err != nil {
+		return nil, err
+	}
+
+	catPrefix := catalogPrefix(root, opts.CatalogPrefix)
+	cat, err := catalogblob.New(objects, catalogblob.Options{
+		Prefix:   catPrefix,
+		StreamID: streamID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sinkFactory, err := segmentsink.New(&multipartStore{objects: objects}, segmentsink.Options{
+		Prefix:      segmentRootPrefix(root, opts.SegmentRootPrefix),
+		ContentType: opts.SegmentContentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		objects:       objects,
+		catalog:       cat,
+		sink:          sinkFactory,
+		source:        &segmentSource{objects: objects},
+		streamID:      streamID,
+		catalogPrefix: catPrefix,
+	}, nil
+}
+
+func normalizeStreamID(streamID string) (string, error) {
+	streamID, err := keylayout.CanonicalStreamID(streamID)
+	if err != nil {
+		return "", fmt.Errorf("partitionlog/memstore: %w", err)
+	}
+	return streamID, nil
+}
+
+func (s *Store) WriterManager() catalog.WriterManager {
+	return s.catalog
+}
+
+func (s *Store) RetentionManager() catalog.RetentionManager {
+	return s.catalog
+}
+
+func (s *Store) ReaderCatalog() catalog.Reader {
+	return s.catalog
+}
+
+func (s *Store) SinkFactory() writer.SinkFactory {
+	return s.sink
+}
+
+func (s *Store) SegmentStore() reader.SegmentStore {
+	return s.source
+}
+
+// NewReclaimer creates an explicitly scheduled object lifecycle worker.
+func (s *Store) NewReclaimer(opts lifecycle.Options) (*lifecycle.Reclaimer, error) {
+	opts.StreamID = s.streamID
+	opts.CatalogPrefix = s.catalogPrefix
+	return lifecycle.New(s.objects, s.catalog, s.sink.Layout(), opts)
+}
+
+// NewKeyring opens this stream's keyring; see keyring.New.
+func (s *Store) NewKeyring(root segblock.KeyProvider) (*keyring.Keyring, error) {
+	return keyring.New(s.objects, root, keyring.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
+// NewDictionaryStore opens this stream's dictionary store; see dictstore.New.
+func (s *Store) NewDictionaryStore() (*dictstore.Store, error) {
+	return dictstore.New(s.objects, dictstore.Options{StreamID: s.streamID, CatalogPrefix: s.catalogPrefix})
+}
+
+// CatalogPrefix returns the key prefix that holds catalog metadata.
+func (s *Store) CatalogPrefix() string {
+	return s.catalogPrefix
+}
+
+// HeadKey returns the catalog head object key for partition.
+func (s *Store) HeadKey(partition uint32) string {
+	return catalogblob.HeadPath(s.catalogPrefix, s.streamID, partition)
+}
+
+// PartitionKeyKey returns the keyring object key for partition.
+func (s *Store) PartitionKeyKey(partition uint32) string {
+	return catalogblob.PartitionKeyPath(s.catalogPrefix, s.streamID, partition)
+}
+
+// CatalogPagePrefix returns the key prefix that holds catalog pages for
+// partition.
+func (s *Store) CatalogPagePrefix(partition uint32) string {
+	return catalogblob.PagePrefix(s.catalogPrefix, s.streamID, partition)
+}
+
+// SegmentPrefix returns the key prefix that holds committed segment objects for
+// partition.
+func (s *Store) SegmentPrefix(partition uint32) string {
+	return s.sink.Layout().SegmentPrefix(s.streamID, partition)
+}
+
+// StagingPrefix returns the key prefix that holds staged multipart parts for
+// partition.
+func (s *Store) StagingPrefix(partition uint32) string {
+	return s.sink.Layout().PartitionStagingPrefix(s.streamID, partition)
+}
+
+// ListObjects returns every object whose key starts with prefix, in key order.
+// An empty prefix lists the whole store.
+func (s *Store) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	return listObjects(ctx, s.objects, prefix)
+}
+
+// ReadObject returns a copy of the object stored at key.
+func (s *Store) ReadObject(ctx context.Context, key string) ([]byte, error) {
+	return readObject(ctx, s.objects, key)
+}
+
+func listObjects(ctx context.Context, objects *blobmemory.Store, prefix string) ([]Object, error) {
+	out := make([]Object, 0)
+	opts := blobstore.ListOptions{Prefix: prefix}
+	for {
+		page, err := objects.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Objects {
+			out = append(out, Object{Key: obj.Key, SizeBytes: obj.SizeBytes, CreatedAt: obj.CreatedAt})
+		}
+		if !page.HasMore {
+			return out, nil
+		}
+		opts.AfterKey = page.NextAfterKey
+	}
+}
+
+func readObject(ctx context.Context, objects *blobmemory.Store, key string) ([]byte, error) {
+	obj, err := objects.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("partitionlog/memstore: read %q: %w", key, err)
+	}
+	return obj.Body, nil
+}
+
+func rootPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return segmentsink.DefaultPrefix
+	}
+	return prefix
+}
+
+func catalogPrefix(root string, override string) string {
+	override = strings.Trim(override, "/")
+	if override != "" {
+		return override
+	}
+	return path.Join(root, "catalog")
+}
+
+func segmentRootPrefix(root string, override string) string {
+	override = strings.Trim(override, "/")
+	if override != "" {
+		return override
+	}
+	return root
+}
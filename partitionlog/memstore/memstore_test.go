@@ -0,0 +1,779 @@
+package memstore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+	"github.com/ankur-anand/unijord/partitionlog/blob/registry"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/internal/lifecycletest"
+	"github.com/ankur-anand/unijord/partitionlog/memstore"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+func TestLifecycleConformance(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	lifecycletest.Run(t, ctx, newStore(t), lifecycletest.Config{Partition: 901})
+}
+
+func TestListObjectsExposesLayout(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	log, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 3,
+		WriterID:  [16]byte{3},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	defer func() { _ = writer.Abort(context.Background()) }()
+	for i := 0; i < 2; i++ {
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: []byte("event")}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := writer.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	segments, err := store.ListObjects(ctx, store.SegmentPrefix(3))
+	if err != nil {
+		t.Fatalf("ListObjects(segments) error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("segment objects = %+v, want 2", segments)
+	}
+	for _, segment := range segments {
+		if !strings.HasSuffix(segment.Key, ".plseg") || segment.SizeBytes == 0 {
+			t.Fatalf("segment object = %+v", segment)
+		}
+	}
+	staged, err := store.ListObjects(ctx, store.StagingPrefix(3))
+	if err != nil {
+		t.Fatalf("ListObjects(staging) error = %v", err)
+	}
+	if len(staged) != 0 {
+		t.Fatalf("staged objects after Flush = %+v, want none", staged)
+	}
+	head, err := store.ReadObject(ctx, store.HeadKey(3))
+	if err != nil {
+		t.Fatalf("ReadObject(head) error = %v", err)
+	}
+	if !strings.Contains(string(head), `"next_lsn":2`) {
+		t.Fatalf("head = %s, want next_lsn 2", head)
+	}
+	catalogObjects, err := store.ListObjects(ctx, store.CatalogPrefix()+"/")
+	if err != nil {
+		t.Fatalf("ListObjects(catalog) error = %v", err)
+	}
+	if len(catalogObjects) == 0 || catalogObjects[0].Key != store.HeadKey(3) {
+		t.Fatalf("catalog objects = %+v, want head %q", catalogObjects, store.HeadKey(3))
+	}
+	if _, err := store.ReadObject(ctx, store.HeadKey(4)); err == nil {
+		t.Fatal("ReadObject(missing head) error = nil, want error")
+	}
+}
+
+func TestNamespaceRegistryResolvesWritableTimeline(t *testing.T) {
+	ctx := context.Background()
+	ns, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("NewNamespace() error = %v", err)
+	}
+	reg, err := ns.NewRegistry(registry.Options{StreamID: "agents/a"})
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	if reg.Prefix() != "partitionlog/registry" {
+		t.Fatalf("registry prefix = %q", reg.Prefix())
+	}
+	created, err := reg.CreateTimeline(ctx, registry.CreateRequest{Name: "sessions/42"})
+	if err != nil {
+		t.Fatalf("CreateTimeline() error = %v", err)
+	}
+
+	timeline, err := reg.Resolve(ctx, "sessions/42")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	store, err := ns.Store(timeline.StreamID)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, timeline.WriterOptions([16]byte{4}))
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: 1, Value: []byte("hello")}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := writer.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	got, err := log.Reader().Partition(created.Timeline.Partition).Read(ctx, partitionlog.ReadRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got.Records) != 1 || string(got.Records[0].Value) != "hello" {
+		t.Fatalf("Read() = %+v", got)
+	}
+}
+
+func TestShredPartitionDestroysKeyAndPurgesObjects(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	root, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	keys, err := store.NewKeyring(root)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Keys: keys})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 3,
+		WriterID:  [16]byte{3},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: []byte("secret")}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := writer.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	read, err := log.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 2})
+	if err != nil || len(read.Records) != 2 || string(read.Records[1].Value) != "secret" {
+		t.Fatalf("Read() = %+v, %v", read, err)
+	}
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 3, Limit: 4})
+	if err != nil || len(page.Segments) != 2 || page.Segments[0].KeyID == "" {
+		t.Fatalf("ListSegments() = %+v, %v; want 2 encrypted segments", page, err)
+	}
+
+	shred, err := log.ShredPartition(ctx, 3)
+	if err != nil || !shred.Deleted || !shred.KeyDestroyed {
+		t.Fatalf("ShredPartition() = %+v, %v", shred, err)
+	}
+	again, err := log.ShredPartition(ctx, 3)
+	if err != nil || again.Deleted || again.KeyDestroyed {
+		t.Fatalf("ShredPartition(again) = %+v, %v; want no-op", again, err)
+	}
+	if _, err := log.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 2, Freshness: partitionlog.FreshnessLatest}); !errors.Is(err, partitionlog.ErrShredded) {
+		t.Fatalf("Read(shredded) error = %v, want %v", err, partitionlog.ErrShredded)
+	}
+	if _, err := log.LoadPartition(ctx, 3); !errors.Is(err, partitionlog.ErrPartitionShredded) || !errors.Is(err, partitionlog.ErrPartitionDeleted) {
+		t.Fatalf("LoadPartition(shredded) error = %v, want %v and %v", err, partitionlog.ErrPartitionShredded, partitionlog.ErrPartitionDeleted)
+	}
+	// Segment bytes are still stored, but no key opens them.
+	segmentOptions := segreader.DefaultOptions()
+	segmentOptions.KeyProvider = keys
+	for _, segment := range page.Segments {
+		if _, err := segreader.Open(ctx, store.SegmentStore(), segment, segmentOptions); !errors.Is(err, segreader.ErrKeyDestroyed) {
+			t.Fatalf("segreader.Open(%s) error = %v, want %v", segment.URI, err, segreader.ErrKeyDestroyed)
+		}
+	}
+
+	reclaimer, err := store.NewReclaimer(lifecycle.Options{OwnerID: [16]byte{9}, DeleteDelay: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewReclaimer() error = %v", err)
+	}
+	if _, err := reclaimer.RunPartition(ctx, 3); err != nil {
+		t.Fatalf("RunPartition(observe) error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := reclaimer.RunPartition(ctx, 3); err != nil {
+		t.Fatalf("RunPartition(purge) error = %v", err)
+	}
+	segments, err := store.ListObjects(ctx, store.SegmentPrefix(3))
+	if err != nil || len(segments) != 0 {
+		t.Fatalf("segment objects after purge = %+v, %v; want none", segments, err)
+	}
+	if _, err := store.ReadObject(ctx, store.PartitionKeyKey(3)); err == nil {
+		t.Fatal("ReadObject(partition key) after purge error = nil, want error")
+	}
+}
+
+func TestReclaimerFinishesShredOfDestroyedPartitionKey(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	root, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	keys, err := store.NewKeyring(root)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Keys: keys})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	for _, partition := range []uint32{3, 4} {
+		writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+			Partition: partition,
+			WriterID:  [16]byte{byte(partition)},
+			Batch:     partitionlog.BatchPolicy{MaxRecords: 1},
+		})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Append(ctx, partitionlog.Record{Value: []byte("secret")}); err != nil {
+			t.Fatalf("Append(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%d) error = %v", partition, err)
+		}
+	}
+	// Partition 3 loses its key outside ShredPartition, so its head stays live.
+	if destroyed, err := keys.DestroyPartitionKey(ctx, 3); err != nil || !destroyed {
+		t.Fatalf("DestroyPartitionKey() = %v, %v", destroyed, err)
+	}
+
+	reclaimer, err := store.NewReclaimer(lifecycle.Options{OwnerID: [16]byte{9}, DeleteDelay: 5 * time.Millisecond, Keys: keys})
+	if err != nil {
+		t.Fatalf("NewReclaimer() error = %v", err)
+	}
+	kept, err := reclaimer.RunPartition(ctx, 4)
+	if err != nil || kept.Shredded {
+		t.Fatalf("RunPartition(live key) = %+v, %v; want no shred", kept, err)
+	}
+	shred, err := reclaimer.RunPartition(ctx, 3)
+	if err != nil || !shred.Shredded {
+		t.Fatalf("RunPartition(destroyed key) = %+v, %v; want shred", shred, err)
+	}
+	if _, err := log.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 1, Freshness: partitionlog.FreshnessLatest}); !errors.Is(err, partitionlog.ErrShredded) {
+		t.Fatalf("Read(shredded) error = %v, want %v", err, partitionlog.ErrShredded)
+	}
+	if _, err := reclaimer.RunPartition(ctx, 3); err != nil {
+		t.Fatalf("RunPartition(observe) error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	purged, err := reclaimer.RunPartition(ctx, 3)
+	if err != nil || !purged.Purged {
+		t.Fatalf("RunPartition(purge) = %+v, %v; want purged", purged, err)
+	}
+	if segments, err := store.ListObjects(ctx, store.SegmentPrefix(3)); err != nil || len(segments) != 0 {
+		t.Fatalf("segment objects after purge = %+v, %v; want none", segments, err)
+	}
+	if _, err := log.Reader().Partition(4).Read(ctx, partitionlog.ReadRequest{Limit: 1, Freshness: partitionlog.FreshnessLatest}); err != nil {
+		t.Fatalf("Read(partition 4) error = %v", err)
+	}
+}
+
+func TestRedactRewritesSegmentsWithoutLSNGaps(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	root, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	keys, err := store.NewKeyring(root)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Keys: keys})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 3,
+		WriterID:  [16]byte{3},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 3},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		record := partitionlog.Record{
+			TimestampMS: int64(i),
+			Headers:     []partitionlog.Header{{Key: []byte("tool"), Value: []byte("shell")}},
+			Value:       []byte("output"),
+		}
+		if i == 1 || i == 4 {
+			record.Value = []byte("token=secret")
+		}
+		if _, err := writer.Append(ctx, record); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	before, err := writer.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	result, err := writer.Redact(ctx, partitionlog.RedactRequest{LSNs: []uint64{4, 1, 1}, Reason: "leaked secret"})
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if result.Segments != 2 || result.Records != 2 {
+		t.Fatalf("Redact() = %+v, want 2 segments and 2 records", result)
+	}
+	if result.Snapshot.Head.NextLSN != before.Head.NextLSN || result.Snapshot.Head.SegmentCount != before.Head.SegmentCount {
+		t.Fatalf("Redact() head = %+v, before %+v", result.Snapshot.Head, before.Head)
+	}
+	if _, err := writer.Redact(ctx, partitionlog.RedactRequest{LSNs: []uint64{6}}); !errors.Is(err, partitionlog.ErrInvalidRedaction) {
+		t.Fatalf("Redact(uncommitted) error = %v, want %v", err, partitionlog.ErrInvalidRedaction)
+	}
+
+	read, err := log.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 6, Freshness: partitionlog.FreshnessLatest})
+	if err != nil || len(read.Records) != 6 {
+		t.Fatalf("Read() = %+v, %v; want 6 records", read, err)
+	}
+	for i, record := range read.Records {
+		if record.LSN != uint64(i) || record.TimestampMS != int64(i) {
+			t.Fatalf("record %d = lsn %d ts %d", i, record.LSN, record.TimestampMS)
+		}
+		reason, redacted := record.Redacted()
+		if i == 1 || i == 4 {
+			if !redacted || reason != "leaked secret" || string(record.Value) != partitionlog.RedactedValue {
+				t.Fatalf("record %d = %+v, want redaction tombstone", i, record)
+			}
+			continue
+		}
+		if redacted || string(record.Value) != "output" || len(record.Headers) != 1 || string(record.Headers[0].Key) != "tool" {
+			t.Fatalf("record %d = %+v, want original", i, record)
+		}
+	}
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 3, Limit: 4})
+	if err != nil || len(page.Segments) != 2 {
+		t.Fatalf("ListSegments() = %+v, %v; want 2 segments", page, err)
+	}
+	for _, segment := range page.Segments {
+		if segment.RedactedUnixMS == 0 || segment.KeyID == "" {
+			t.Fatalf("segment %+v, want encrypted redacted replacement", segment)
+		}
+	}
+
+	objects, err := store.ListObjects(ctx, store.SegmentPrefix(3))
+	if err != nil || len(objects) != 4 {
+		t.Fatalf("segment objects = %+v, %v; want originals and replacements", objects, err)
+	}
+	reclaimer, err := store.NewReclaimer(lifecycle.Options{OwnerID: [16]byte{9}, DeleteDelay: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewReclaimer() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err := reclaimer.ScrubPartition(ctx, 3); err != nil {
+		t.Fatalf("ScrubPartition() error = %v", err)
+	}
+	objects, err = store.ListObjects(ctx, store.SegmentPrefix(3))
+	if err != nil || len(objects) != 2 {
+		t.Fatalf("segment objects after scrub = %+v, %v; want replacements only", objects, err)
+	}
+	if _, err := log.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 6, Freshness: partitionlog.FreshnessLatest}); err != nil {
+		t.Fatalf("Read(after scrub) error = %v", err)
+	}
+}
+
+func TestProveRecordVerifiesAgainstPublishedCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	log, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 5,
+		WriterID:  [16]byte{5},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 3},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		record := partitionlog.Record{TimestampMS: int64(i), Value: []byte{byte('a' + i)}}
+		if _, err := writer.Append(ctx, record); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	snapshot, err := writer.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	published := proof.CheckpointFromHead(snapshot.Head)
+
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 5, Limit: 8})
+	if err != nil || len(page.Segments) != 3 {
+		t.Fatalf("ListSegments() = %+v, %v; want 3 segments", page, err)
+	}
+	for i, segment := range page.Segments {
+		if segment.RecordsRoot.IsZero() || segment.ChainHash != proof.ChainHash(segment) {
+			t.Fatalf("segment %d = %+v, want chained", i, segment)
+		}
+		if i > 0 && segment.PrevChainHash != page.Segments[i-1].ChainHash {
+			t.Fatalf("segment %d prev_chain_hash does not link to segment %d", i, i-1)
+		}
+	}
+
+	proofs := make([]partitionlog.RecordProof, 8)
+	for lsn := uint64(0); lsn < 8; lsn++ {
+		p, err := log.ProveRecord(ctx, 5, lsn, 0)
+		if err != nil {
+			t.Fatalf("ProveRecord(%d) error = %v", lsn, err)
+		}
+		if err := proof.VerifyRecord(published, p, nil, []byte{byte('a' + lsn)}); err != nil {
+			t.Fatalf("VerifyRecord(%d) error = %v", lsn, err)
+		}
+		if err := proof.VerifyRecord(published, p, nil, []byte("forged")); !errors.Is(err, partitionlog.ErrInvalidProof) {
+			t.Fatalf("VerifyRecord(%d, forged) error = %v, want %v", lsn, err, partitionlog.ErrInvalidProof)
+		}
+		proofs[lsn] = p
+	}
+	if _, err := log.ProveRecord(ctx, 5, 8, 0); !errors.Is(err, catalog.ErrSegmentNotFound) {
+		t.Fatalf("ProveRecord(next_lsn) error = %v, want %v", err, catalog.ErrSegmentNotFound)
+	}
+
+	redacted, err := writer.Redact(ctx, partitionlog.RedactRequest{LSNs: []uint64{4}, Reason: "gdpr"})
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if redacted.Snapshot.Head.TreeRoot != published.TreeRoot {
+		t.Fatalf("Redact() tree root = %s, want unchanged %s", redacted.Snapshot.Head.TreeRoot, published.TreeRoot)
+	}
+	read, err := log.Reader().Partition(5).Read(ctx, partitionlog.ReadRequest{StartLSN: 4, Limit: 1, Freshness: partitionlog.FreshnessLatest})
+	if err != nil || len(read.Records) != 1 {
+		t.Fatalf("Read(4) = %+v, %v; want one record", read, err)
+	}
+	tombstone := read.Records[0]
+	if _, ok := tombstone.Redacted(); !ok {
+		t.Fatalf("Read(4) = %+v, want redaction tombstone", tombstone)
+	}
+	if err := proof.VerifyRecord(published, proofs[4], tombstone.Headers, tombstone.Value); err != nil {
+		t.Fatalf("VerifyRecord(tombstone, old proof) error = %v", err)
+	}
+	again, err := log.ProveRecord(ctx, 5, 4, 0)
+	if err != nil {
+		t.Fatalf("ProveRecord(redacted) error = %v", err)
+	}
+	if err := proof.Verify(published, again); err != nil || again.Digest != proofs[4].Digest {
+		t.Fatalf("Verify(redacted) = %v, digest %s want %s", err, again.Digest, proofs[4].Digest)
+	}
+
+	// After more appends the published checkpoint is still provable, and
+	// the new head proves it extends that checkpoint.
+	for i := 8; i < 12; i++ {
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: []byte{byte('a' + i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	grown, err := writer.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush(grown) error = %v", err)
+	}
+	current := proof.CheckpointFromHead(grown.Head)
+	if current.TreeSize <= published.TreeSize {
+		t.Fatalf("tree_size = %d, want more than %d", current.TreeSize, published.TreeSize)
+	}
+	old, err := log.ProveRecord(ctx, 5, 2, published.TreeSize)
+	if err != nil {
+		t.Fatalf("ProveRecord(published) error = %v", err)
+	}
+	if err := proof.VerifyRecord(published, old, nil, []byte{'c'}); err != nil {
+		t.Fatalf("VerifyRecord(published) error = %v", err)
+	}
+	if _, err := log.ProveRecord(ctx, 5, 9, published.TreeSize); !errors.Is(err, catalog.ErrSegmentNotFound) {
+		t.Fatalf("ProveRecord(past published) error = %v, want %v", err, catalog.ErrSegmentNotFound)
+	}
+	consistency, err := log.ProveConsistency(ctx, 5, published.TreeSize, 0)
+	if err != nil {
+		t.Fatalf("ProveConsistency() error = %v", err)
+	}
+	if err := proof.VerifyConsistency(published, current, consistency); err != nil {
+		t.Fatalf("VerifyConsistency() error = %v", err)
+	}
+}
+
+func TestSignedWriterIsVerifiedByReaders(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	signer, trusted := newTestSigningKeys(t, "writer", 1)
+	_, untrusted := newTestSigningKeys(t, "writer", 2)
+	log, err := partitionlog.Open(partitionlog.Options{
+		Store:  store,
+		Reader: partitionlog.ReaderOptions{Verifier: trusted},
+	})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 7,
+		WriterID:  [16]byte{7},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 2},
+		Signer:    signer,
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: []byte{byte('a' + i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := writer.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if _, err := writer.Redact(ctx, partitionlog.RedactRequest{LSNs: []uint64{1}, Reason: "gdpr"}); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 7, Limit: 8})
+	if err != nil || len(page.Segments) != 3 {
+		t.Fatalf("ListSegments() = %+v, %v; want 3 segments", page, err)
+	}
+	for i, segment := range page.Segments {
+		if err := signing.VerifySegment(trusted, segment); err != nil {
+			t.Fatalf("VerifySegment(%d) error = %v", i, err)
+		}
+	}
+	read, err := log.Reader().Partition(7).Read(ctx, partitionlog.ReadRequest{Limit: 8, Freshness: partitionlog.FreshnessLatest})
+	if err != nil || len(read.Records) != 5 {
+		t.Fatalf("Read(signed) = %+v, %v; want 5 records", read, err)
+	}
+	if _, err := log.ProveRecord(ctx, 7, 3, 0); err != nil {
+		t.Fatalf("ProveRecord(signed) error = %v", err)
+	}
+
+	other, err := log.NewReader(partitionlog.ReaderOptions{Verifier: untrusted})
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer other.Close()
+	if _, err := other.Partition(7).Read(ctx, partitionlog.ReadRequest{Limit: 8, Freshness: partitionlog.FreshnessLatest}); !errors.Is(err, partitionlog.ErrInvalidSignature) {
+		t.Fatalf("Read(untrusted key) error = %v, want %v", err, partitionlog.ErrInvalidSignature)
+	}
+
+	unsigned, err := log.OpenWriter(ctx, partitionlog.WriterOptions{Partition: 8, WriterID: [16]byte{8}})
+	if err != nil {
+		t.Fatalf("OpenWriter(unsigned) error = %v", err)
+	}
+	if _, err := unsigned.Append(ctx, partitionlog.Record{Value: []byte("x")}); err != nil {
+		t.Fatalf("Append(unsigned) error = %v", err)
+	}
+	if _, err := unsigned.Flush(ctx); err != nil {
+		t.Fatalf("Flush(unsigned) error = %v", err)
+	}
+	if _, err := log.Reader().Partition(8).Read(ctx, partitionlog.ReadRequest{Limit: 8, Freshness: partitionlog.FreshnessLatest}); !errors.Is(err, partitionlog.ErrUnsigned) {
+		t.Fatalf("Read(unsigned) error = %v, want %v", err, partitionlog.ErrUnsigned)
+	}
+}
+
+func TestLogSealAndDeleteKeepSignedHeadsSigned(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	signer, trusted := newTestSigningKeys(t, "writer", 1)
+	unsignedLog, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open(unsigned) error = %v", err)
+	}
+	signedLog, err := partitionlog.Open(partitionlog.Options{Store: store, Signer: signer})
+	if err != nil {
+		t.Fatalf("partitionlog.Open(signed) error = %v", err)
+	}
+	for _, partition := range []uint32{7, 8} {
+		writer, err := signedLog.OpenWriter(ctx, partitionlog.WriterOptions{
+			Partition: partition,
+			WriterID:  [16]byte{byte(partition)},
+			Signer:    signer,
+		})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Append(ctx, partitionlog.Record{Value: []byte("a")}); err != nil {
+			t.Fatalf("Append(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%d) error = %v", partition, err)
+		}
+	}
+
+	if _, err := unsignedLog.SealPartition(ctx, partitionlog.SealPartition{Partition: 7, WriterID: [16]byte{9}}); !errors.Is(err, partitionlog.ErrSignerRequired) {
+		t.Fatalf("SealPartition(no signer) error = %v, want %v", err, partitionlog.ErrSignerRequired)
+	}
+	if _, err := unsignedLog.DeletePartition(ctx, 8); !errors.Is(err, partitionlog.ErrSignerRequired) {
+		t.Fatalf("DeletePartition(no signer) error = %v, want %v", err, partitionlog.ErrSignerRequired)
+	}
+
+	sealed, err := signedLog.SealPartition(ctx, partitionlog.SealPartition{Partition: 7, WriterID: [16]byte{9}})
+	if err != nil || !sealed.Sealed {
+		t.Fatalf("SealPartition(signed) = %+v, %v", sealed, err)
+	}
+	if err := signing.VerifyHead(trusted, sealed.Head); err != nil {
+		t.Fatalf("VerifyHead(sealed) error = %v", err)
+	}
+	if deleted, err := signedLog.DeletePartition(ctx, 8); err != nil || !deleted.Deleted {
+		t.Fatalf("DeletePartition(signed) = %+v, %v", deleted, err)
+	} else if err := signing.VerifyHead(trusted, deleted.Head); err != nil {
+		t.Fatalf("VerifyHead(tombstone) error = %v", err)
+	}
+}
+
+func newTestSigningKeys(t *testing.T, keyID string, seed byte) (*signing.Ed25519Signer, *signing.Ed25519Verifier) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{seed}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := signing.NewEd25519Signer(keyID, private)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error = %v", err)
+	}
+	verifier, err := signing.NewEd25519Verifier(map[string]ed25519.PublicKey{keyID: public})
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier() error = %v", err)
+	}
+	return signer, verifier
+}
+
+func TestTrainedDictionaryCompressesSmallRecords(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	dicts, err := store.NewDictionaryStore()
+	if err != nil {
+		t.Fatalf("NewDictionaryStore() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Dictionaries: dicts})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	event := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"event":"tool_call","session":"s-%03d","tool":"search","status":"ok","latency_ms":%d}`, i%41, i*7%300))
+	}
+
+	// Train on partition 1, then write partition 3 with the dictionary.
+	appendEvents(t, log, 1, partitionlog.WriterOptions{Batch: partitionlog.BatchPolicy{MaxRecords: 100}}, 0, 300, event)
+	trained, err := log.TrainDictionary(ctx, partitionlog.TrainDictionary{Partition: 1, MaxBytes: 8 << 10})
+	if err != nil {
+		t.Fatalf("TrainDictionary() error = %v", err)
+	}
+	if trained.DictionaryID == 0 || trained.Samples != 300 {
+		t.Fatalf("TrainDictionary() = %+v, want 300 samples", trained)
+	}
+	opts := partitionlog.WriterOptions{
+		Batch:    partitionlog.BatchPolicy{MaxRecords: 20},
+		Pipeline: partitionlog.WriterPipelineOptions{DictionaryID: trained.DictionaryID},
+	}
+	appendEvents(t, log, 3, opts, 1000, 40, event)
+	plainOpts := partitionlog.WriterOptions{Batch: partitionlog.BatchPolicy{MaxRecords: 20}}
+	appendEvents(t, log, 4, plainOpts, 1000, 40, event)
+
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 3, Limit: 4})
+	if err != nil || len(page.Segments) != 2 {
+		t.Fatalf("ListSegments() = %+v, %v; want 2 segments", page, err)
+	}
+	plain, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 4, Limit: 4})
+	if err != nil || len(plain.Segments) != 2 {
+		t.Fatalf("ListSegments(plain) = %+v, %v; want 2 segments", plain, err)
+	}
+	for i, segment := range page.Segments {
+		if segment.Codec != segformat.CodecZstdDict || segment.DictionaryID != trained.DictionaryID {
+			t.Fatalf("segment %d codec=%s dictionary=%d, want zstd-dict %d", i, segment.Codec, segment.DictionaryID, trained.DictionaryID)
+		}
+		if segment.SizeBytes >= plain.Segments[i].SizeBytes {
+			t.Fatalf("segment %d size=%d, plain zstd size=%d; want smaller", i, segment.SizeBytes, plain.Segments[i].SizeBytes)
+		}
+	}
+
+	// A fresh Log loads the dictionary from the store to read.
+	reopened, err := partitionlog.Open(partitionlog.Options{Store: store, Dictionaries: dicts})
+	if err != nil {
+		t.Fatalf("partitionlog.Open(reopened) error = %v", err)
+	}
+	read, err := reopened.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 40})
+	if err != nil || len(read.Records) != 40 {
+		t.Fatalf("Read() = %d records, %v; want 40", len(read.Records), err)
+	}
+	for i, record := range read.Records {
+		if !bytes.Equal(record.Value, event(1000+i)) {
+			t.Fatalf("record %d value = %q", i, record.Value)
+		}
+	}
+
+	withoutDicts, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open(without dictionaries) error = %v", err)
+	}
+	if _, err := withoutDicts.Reader().Partition(3).Read(ctx, partitionlog.ReadRequest{Limit: 1}); !errors.Is(err, partitionlog.ErrDictionaryUnavailable) {
+		t.Fatalf("Read(without dictionaries) error = %v, want %v", err, partitionlog.ErrDictionaryUnavailable)
+	}
+	if _, err := withoutDicts.OpenWriter(ctx, partitionlog.WriterOptions{Partition: 5, WriterID: [16]byte{5}, Pipeline: opts.Pipeline}); !errors.Is(err, partitionlog.ErrDictionaryUnavailable) {
+		t.Fatalf("OpenWriter(without dictionaries) error = %v, want %v", err, partitionlog.ErrDictionaryUnavailable)
+	}
+	if _, err := withoutDicts.TrainDictionary(ctx, partitionlog.TrainDictionary{Partition: 1}); !errors.Is(err, partitionlog.ErrDictionaryUnsupported) {
+		t.Fatalf("TrainDictionary(without dictionaries) error = %v, want %v", err, partitionlog.ErrDictionaryUnsupported)
+	}
+}
+
+// appendEvents writes n records with values event(first)... to partition
+// and closes the writer.
+func appendEvents(t *testing.T, log *partitionlog.Log, partition uint32, opts partitionlog.WriterOptions, first, n int, event func(int) []byte) {
+	t.Helper()
+	ctx := context.Background()
+	opts.Partition = partition
+	opts.WriterID = [16]byte{byte(partition)}
+	writer, err := log.OpenWriter(ctx, opts)
+	if err != nil {
+		t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+	}
+	for i := first; i < first+n; i++ {
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: event(i)}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := writer.Close(ctx); err != nil {
+		t.Fatalf("Close(%d) error = %v", partition, err)
+	}
+}
+
+func TestSegmentStoreRejectsBadRanges(t *testing.T) {
+	ctx := context.Background()
+	store := newStore(t)
+	if _, err := store.SegmentStore().ReadAt(ctx, "", 0, 1); err == nil {
+		t.Fatal("ReadAt(empty key) error = nil, want error")
+	}
+	if _, err := store.SegmentStore().ReadAt(ctx, "missing", 0, 1); err == nil {
+		t.Fatal("ReadAt(missing key) error = nil, want error")
+	}
+	if _, err := store.SegmentStore().ReadAt(ctx, "missing", ^uint64(0), 2); err == nil {
+		t.Fatal("ReadAt(overflow range) error = nil, want error")
+	}
+}
+
+func newStore(t testing.TB) *memstore.Store {
+	t.Helper()
+	store, err := memstore.New(memstore.Options{StreamID: "memstore/test"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	return store
+}
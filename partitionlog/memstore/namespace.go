@@ -0,0 +1,61 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/registry"
+)
+
+// Namespace opens stream-scoped stores that share one in-memory object map and
+// key prefix.
+type Namespace struct {
+	objects *blobmemory.Store
+	opts    Options
+}
+
+var _ partitionlog.StoreProvider = (*Namespace)(nil)
+
+// NewNamespace returns an empty in-memory partitionlog.StoreProvider.
+func NewNamespace(opts Options) (*Namespace, error) {
+	if opts.StreamID != "" {
+		return nil, fmt.Errorf("partitionlog/memstore: namespace options set stream id %q", opts.StreamID)
+	}
+	return &Namespace{objects: blobmemory.New(), opts: opts}, nil
+}
+
+// StreamStore implements partitionlog.StoreProvider.
+func (n *Namespace) StreamStore(streamID string) (partitionlog.Store, error) {
+	return n.Store(streamID)
+}
+
+// Store is StreamStore with the concrete return type, for tests that need
+// NewReclaimer or the layout helpers.
+func (n *Namespace) Store(streamID string) (*Store, error) {
+	opts := n.opts
+	opts.StreamID = streamID
+	return newStore(n.objects, opts)
+}
+
+// ListObjects returns every object in the namespace whose key starts with
+// prefix, in key order.
+func (n *Namespace) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	return listObjects(ctx, n.objects, prefix)
+}
+
+// ReadObject returns a copy of the object stored at key.
+func (n *Namespace) ReadObject(ctx context.Context, key string) ([]byte, error) {
+	return readObject(ctx, n.objects, key)
+}
+
+// NewRegistry opens the namespace's timeline registry; see
+// partitionlog.StoreProvider.
+func (n *Namespace) NewRegistry(opts registry.Options) (*registry.Registry, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = path.Join(rootPrefix(n.opts.Prefix), "registry")
+	}
+	return registry.New(n.objects, opts)
+}
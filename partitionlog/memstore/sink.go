@@ -0,0 +1,209 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ankur-anand/unijord/internal/blobstore"
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	"github.com/ankur-anand/unijord/partitionlog/blob/sink/multipart"
+)
+
+// multipartStore stages each part as an object under the upload staging prefix
+// and creates the final object only if its key is still absent, matching the
+// provider sinks closely enough for lifecycle staging cleanup to be exercised.
+type multipartStore struct {
+	objects *blobmemory.Store
+}
+
+var _ multipart.Store = (*multipartStore)(nil)
+
+func (s *multipartStore) BeginMultipart(ctx context.Context, key string, opts multipart.Options) (multipart.Upload, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	opts, err := multipart.NormalizeOptions(key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &upload{
+		objects: s.objects,
+		key:     key,
+		opts:    opts,
+		parts:   make(map[int]stagedPart),
+	}, nil
+}
+
+type upload struct {
+	mu        sync.Mutex
+	objects   *blobmemory.Store
+	key       string
+	opts      multipart.Options
+	parts     map[int]stagedPart
+	aborted   bool
+	completed bool
+}
+
+type stagedPart struct {
+	key   string
+	token string
+}
+
+func (u *upload) UploadPart(ctx context.Context, part multipart.Part) (multipart.Receipt, error) {
+	if err := multipart.ValidatePart(part); err != nil {
+		return multipart.Receipt{}, err
+	}
+
+	partKey := multipart.StagingPartKey(u.opts.StagingPrefix, part.Number)
+	if err := u.reservePart(part.Number, partKey); err != nil {
+		return multipart.Receipt{}, err
+	}
+	obj, err := u.objects.Put(ctx, partKey, part.Bytes)
+	if err != nil {
+		u.dropPart(part.Number)
+		return multipart.Receipt{}, mapError(err)
+	}
+	staged := stagedPart{key: partKey, token: obj.Token}
+
+	u.mu.Lock()
+	if u.aborted {
+		delete(u.parts, part.Number)
+		u.mu.Unlock()
+		_ = u.objects.Delete(ctx, staged.key)
+		return multipart.Receipt{}, multipart.ErrAborted
+	}
+	u.parts[part.Number] = staged
+	u.mu.Unlock()
+
+	return multipart.Receipt{
+		Number:    part.Number,
+		Token:     staged.token,
+		SizeBytes: uint64(len(part.Bytes)),
+	}, nil
+}
+
+func (u *upload) Complete(ctx context.Context, receipts []multipart.Receipt) (multipart.ObjectAttrs, error) {
+	if err := multipart.ValidateReceipts(receipts); err != nil {
+		return multipart.ObjectAttrs{}, err
+	}
+
+	sources, err := u.sourcesFor(receipts)
+	if err != nil {
+		return multipart.ObjectAttrs{}, err
+	}
+	var body bytes.Buffer
+	for _, source := range sources {
+		obj, err := u.objects.Get(ctx, source.key)
+		if err != nil {
+			return multipart.ObjectAttrs{}, mapError(err)
+		}
+		body.Write(obj.Body)
+	}
+	final, created, err := u.objects.CompareAndSwap(ctx, u.key, "", body.Bytes())
+	if err != nil {
+		return multipart.ObjectAttrs{}, mapError(err)
+	}
+	if !created {
+		return multipart.ObjectAttrs{}, fmt.Errorf("%w: %s exists", multipart.ErrPreconditionFailed, u.key)
+	}
+
+	u.mu.Lock()
+	u.completed = true
+	u.mu.Unlock()
+	for _, source := range sources {
+		_ = u.objects.Delete(ctx, source.key)
+	}
+
+	return multipart.ObjectAttrs{
+		Key:       u.key,
+		SizeBytes: uint64(body.Len()),
+		Token:     final.Token,
+	}, nil
+}
+
+func (u *upload) Abort(ctx context.Context) error {
+	u.mu.Lock()
+	if u.completed || u.aborted {
+		u.mu.Unlock()
+		return nil
+	}
+	u.aborted = true
+	cleanup := make([]stagedPart, 0, len(u.parts))
+	for _, part := range u.parts {
+		cleanup = append(cleanup, part)
+	}
+	u.parts = nil
+	u.mu.Unlock()
+
+	var firstErr error
+	for _, part := range cleanup {
+		if part.key == "" {
+			continue
+		}
+		if err := u.objects.Delete(ctx, part.key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (u *upload) reservePart(number int, key string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.aborted {
+		return multipart.ErrAborted
+	}
+	if u.completed {
+		return multipart.ErrCompleted
+	}
+	if _, exists := u.parts[number]; exists {
+		return fmt.Errorf("%w: duplicate part %d", multipart.ErrInvalidStore, number)
+	}
+	u.parts[number] = stagedPart{key: key}
+	return nil
+}
+
+func (u *upload) dropPart(number int) {
+	u.mu.Lock()
+	delete(u.parts, number)
+	u.mu.Unlock()
+}
+
+func (u *upload) sourcesFor(receipts []multipart.Receipt) ([]stagedPart, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.aborted {
+		return nil, multipart.ErrAborted
+	}
+	if u.completed {
+		return nil, multipart.ErrCompleted
+	}
+
+	sources := make([]stagedPart, 0, len(receipts))
+	for _, receipt := range receipts {
+		part, ok := u.parts[receipt.Number]
+		if !ok || part.token == "" {
+			return nil, fmt.Errorf("%w: missing memory part %d", multipart.ErrInvalidStore, receipt.Number)
+		}
+		if receipt.Token != "" && receipt.Token != part.token {
+			return nil, fmt.Errorf("%w: token mismatch for memory part %d", multipart.ErrInvalidStore, receipt.Number)
+		}
+		sources = append(sources, part)
+	}
+	return sources, nil
+}
+
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, blobstore.ErrObjectNotFound):
+		return fmt.Errorf("%w: %w", multipart.ErrAborted, err)
+	case errors.Is(err, blobstore.ErrImmutableConflict):
+		return fmt.Errorf("%w: %w", multipart.ErrPreconditionFailed, err)
+	case errors.Is(err, blobstore.ErrInvalidRequest):
+		return fmt.Errorf("%w: %w", multipart.ErrInvalidStore, err)
+	}
+	return err
+}
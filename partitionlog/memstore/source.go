@@ -0,0 +1,41 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	blobmemory "github.com/ankur-anand/unijord/internal/blobstore/memory"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
+)
+
+type segmentSource struct {
+	objects *blobmemory.Store
+}
+
+var _ segreader.SegmentStore = (*segmentSource)(nil)
+
+func (s *segmentSource) ReadAt(ctx context.Context, key string, off uint64, n uint64) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("partitionlog/memstore: empty key")
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	if off > math.MaxInt64 || n >= math.MaxInt64 || off > uint64(math.MaxInt64)-(n-1) {
+		return nil, fmt.Errorf("partitionlog/memstore: range overflows int64 offset=%d length=%d", off, n)
+	}
+	obj, err := s.objects.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("partitionlog/memstore: read %q: %w", key, err)
+	}
+	size := uint64(len(obj.Body))
+	if off > size || n > size-off {
+		return nil, fmt.Errorf("partitionlog/memstore: range offset=%d length=%d exceeds object size=%d", off, n, size)
+	}
+	// Get already returned a private copy of the body.
+	return obj.Body[off : off+n : off+n], nil
+}
@@ -17,6 +17,8 @@ const (
 	MetricWriterClose           MetricName = "writer.close"
 	MetricWriterAbort           MetricName = "writer.abort"
 	MetricWriterRetention       MetricName = "writer.retention"
+	MetricWriterRedact          MetricName = "writer.redact"
+	MetricWriterSeal            MetricName = "writer.seal"
 	MetricWriterSegmentFinalize MetricName = "writer.segment_finalize"
 	MetricWriterSegmentPublish  MetricName = "writer.segment_publish"
 
@@ -32,7 +34,9 @@ const (
 // Metric is a generic event emitted by public reader/writer operations and by
 // background segment finalize/publish work.
 type Metric struct {
-	Name      MetricName
+	Name MetricName
+	// StreamID is set for events from logs opened through a Namespace.
+	StreamID  string
 	Partition uint32
 
 	LSN      uint64
@@ -0,0 +1,470 @@
+package partitionlog
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog/dictstore"
+	"github.com/ankur-anand/unijord/partitionlog/keylayout"
+	"github.com/ankur-anand/unijord/partitionlog/keyring"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
+	"github.com/ankur-anand/unijord/partitionlog/segwriter"
+	lowwriter "github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+var ErrNamespaceClosed = errors.New("partitionlog: namespace closed")
+
+// StoreProvider opens the Store for one stream of a namespace. Provider
+// packages return one from NewNamespace; every Store it returns shares the
+// provider client, bucket, and key prefix. NewNamespace options must leave
+// StreamID empty, since each stream is chosen by StreamStore.
+//
+// Provider namespaces also open the timeline registry through NewRegistry,
+// stored beside their streams. An empty registry.Options.Prefix there uses
+// "<Prefix>/registry".
+type StoreProvider interface {
+	StreamStore(streamID string) (Store, error)
+}
+
+// StoreProviderFunc adapts a function to StoreProvider.
+type StoreProviderFunc func(streamID string) (Store, error)
+
+func (f StoreProviderFunc) StreamStore(streamID string) (Store, error) {
+	return f(streamID)
+}
+
+// StreamKeys returns the KeyProvider of one stream of a namespace, given the
+// Store the StoreProvider opened for it.
+type StreamKeys func(streamID string, store Store) (KeyProvider, error)
+
+// StreamDictionaries returns the DictionaryProvider of one stream of a
+// namespace, given the Store the StoreProvider opened for it.
+type StreamDictionaries func(streamID string, store Store) (DictionaryProvider, error)
+
+// StreamSigner returns the Signer of one stream of a namespace, given the
+// Store the StoreProvider opened for it.
+type StreamSigner func(streamID string, store Store) (Signer, error)
+
+// StreamVerifier returns the Verifier of one stream of a namespace, given the
+// Store the StoreProvider opened for it.
+type StreamVerifier func(streamID string, store Store) (Verifier, error)
+
+// Keyrings returns StreamKeys that creates each stream's keyring.Keyring,
+// wrapped under root, through the NewKeyring method of its provider Store.
+// Logs opened with it can ShredPartition. A stream whose Store has no such
+// method fails to open.
+func Keyrings(root KeyProvider) StreamKeys {
+	return func(streamID string, store Store) (KeyProvider, error) {
+		opener, ok := store.(interface {
+			NewKeyring(root KeyProvider) (*keyring.Keyring, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("partitionlog: store for stream %q cannot create a keyring", streamID)
+		}
+		return opener.NewKeyring(root)
+	}
+}
+
+// DictionaryStores returns StreamDictionaries that opens each stream's
+// dictstore.Store through the NewDictionaryStore method of its provider
+// Store. A stream whose Store has no such method fails to open.
+func DictionaryStores() StreamDictionaries {
+	return func(streamID string, store Store) (DictionaryProvider, error) {
+		opener, ok := store.(interface {
+			NewDictionaryStore() (*dictstore.Store, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("partitionlog: store for stream %q cannot open a dictionary store", streamID)
+		}
+		return opener.NewDictionaryStore()
+	}
+}
+
+// NamespaceOptions configures a Namespace.
+type NamespaceOptions struct {
+	Stores StoreProvider
+
+	// Reader configures the default reader of every stream. RangeCacheBytes,
+	// OpenSegmentReaders, and Refresh.MaxConcurrentRefreshes are namespace-wide
+	// budgets shared by all open streams rather than per-stream limits.
+	// Reader.Verifier checks every stream that Verifiers does not cover.
+	Reader  ReaderOptions
+	Metrics Metrics
+	Clock   Clock
+
+	// Keys, when set, returns the key provider of every stream, used as its
+	// Options.Keys. Keyrings gives each stream its own keyring. Nil opens
+	// streams that cannot write or read encrypted segments.
+	Keys StreamKeys
+	// Dictionaries, when set, returns the dictionary provider of every
+	// stream, used as its Options.Dictionaries. Nil opens streams that cannot
+	// write or read zstd-dict segments.
+	Dictionaries StreamDictionaries
+	// Signers, when set, returns the signer of every stream, used as its
+	// Options.Signer for the heads the Log commits itself. Writers take their
+	// own WriterOptions.Signer.
+	Signers StreamSigner
+	// Verifiers, when set, returns the verifier of every stream, used in
+	// place of Reader.Verifier by its default reader and ProveRecord.
+	Verifiers StreamVerifier
+
+	// MaxConcurrentUploads bounds multipart part uploads across writers of all
+	// streams. Writers that set Pipeline.UploadLimiter keep their own limiter.
+	// Zero leaves uploads unbounded by the namespace.
+	MaxConcurrentUploads int
+
+	// MaxIdleStreams bounds released streams kept open for reuse. Releasing a
+	// stream beyond the bound closes the least recently released idle stream.
+	// Zero keeps every idle stream until EvictIdle or Close.
+	MaxIdleStreams int
+
+	// IdleTimeout is how long a released stream stays open before EvictIdle
+	// closes it. Zero lets EvictIdle close every released stream.
+	IdleTimeout time.Duration
+}
+
+// Namespace opens one Log per stream on demand over a shared provider client.
+// Stream logs share reader caches, catalog refresh limits, and the upload
+// limiter, so thousands of streams do not each carry private budgets.
+//
+// Acquire returns a reference-counted Stream. Streams with no outstanding
+// references are idle; idle streams are closed by EvictIdle, by the
+// MaxIdleStreams bound, or by Close. Writers opened from a stream's Log are
+// owned by the caller and are not closed by eviction.
+type Namespace struct {
+	stores        StoreProvider
+	keys          StreamKeys
+	dictionaries  StreamDictionaries
+	signers       StreamSigner
+	verifiers     StreamVerifier
+	readerOpts    ReaderOptions
+	metrics       Metrics
+	clock         lowwriter.Clock
+	runtime       readerRuntime
+	uploadLimiter segwriter.UploadLimiter
+	maxIdle       int
+	idleTimeout   time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*namespaceStream
+	// idle orders released streams from most to least recently released.
+	idle   list.List
+	closed bool
+}
+
+type namespaceStream struct {
+	id         string
+	log        *Log
+	refs       int
+	releasedAt time.Time
+	idleElem   *list.Element
+}
+
+// OpenNamespace validates opts and prepares the shared reader runtime.
+func OpenNamespace(opts NamespaceOptions) (*Namespace, error) {
+	switch {
+	case opts.Stores == nil:
+		return nil, fmt.Errorf("partitionlog: nil namespace store provider")
+	case opts.MaxConcurrentUploads < 0:
+		return nil, fmt.Errorf("partitionlog: negative max concurrent uploads %d", opts.MaxConcurrentUploads)
+	case opts.MaxIdleStreams < 0:
+		return nil, fmt.Errorf("partitionlog: negative max idle streams %d", opts.MaxIdleStreams)
+	case opts.IdleTimeout < 0:
+		return nil, fmt.Errorf("partitionlog: negative idle timeout %s", opts.IdleTimeout)
+	}
+	runtime, err := newReaderRuntime(opts.Reader)
+	if err != nil {
+		return nil, err
+	}
+	clock := opts.Clock
+	if clock == nil {
+		clock = lowwriter.SystemClock{}
+	}
+	ns := &Namespace{
+		stores:       opts.Stores,
+		keys:         opts.Keys,
+		dictionaries: opts.Dictionaries,
+		signers:      opts.Signers,
+		verifiers:    opts.Verifiers,
+		readerOpts:   opts.Reader,
+		metrics:      opts.Metrics,
+		clock:        clock,
+		runtime:      runtime,
+		maxIdle:      opts.MaxIdleStreams,
+		idleTimeout:  opts.IdleTimeout,
+		streams:      make(map[string]*namespaceStream),
+	}
+	if opts.MaxConcurrentUploads > 0 {
+		limiter, err := segwriter.NewSemaphoreUploadLimiter(opts.MaxConcurrentUploads)
+		if err != nil {
+			return nil, err
+		}
+		ns.uploadLimiter = limiter
+	}
+	return ns, nil
+}
+
+// Stream is one reference to an open stream Log. Release it when the caller
+// no longer needs the Log.
+type Stream struct {
+	ns       *Namespace
+	entry    *namespaceStream
+	released sync.Once
+}
+
+// ID returns the canonical stream ID.
+func (s *Stream) ID() string {
+	return s.entry.id
+}
+
+// Log returns the stream Log. It remains usable until Release.
+func (s *Stream) Log() *Log {
+	return s.entry.log
+}
+
+// Release drops this reference. It is safe to call more than once.
+func (s *Stream) Release() {
+	s.released.Do(func() {
+		s.ns.release(s.entry)
+	})
+}
+
+// Acquire opens streamID if needed and returns a new reference to its Log.
+func (n *Namespace) Acquire(streamID string) (*Stream, error) {
+	if n == nil {
+		return nil, fmt.Errorf("partitionlog: nil namespace")
+	}
+	id, err := keylayout.CanonicalStreamID(streamID)
+	if err != nil {
+		return nil, fmt.Errorf("partitionlog: %w", err)
+	}
+	if stream, ok, err := n.acquireOpen(id); ok || err != nil {
+		return stream, err
+	}
+
+	log, err := n.openLog(id)
+	if err != nil {
+		return nil, err
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		_ = log.Close()
+		return nil, ErrNamespaceClosed
+	}
+	entry, ok := n.streams[id]
+	if !ok {
+		entry = &namespaceStream{id: id, log: log}
+		n.streams[id] = entry
+		log = nil
+	}
+	n.refLocked(entry)
+	n.mu.Unlock()
+	if log != nil {
+		// Another caller opened the stream first.
+		_ = log.Close()
+	}
+	return &Stream{ns: n, entry: entry}, nil
+}
+
+// EvictIdle closes idle streams released at least IdleTimeout ago and returns
+// how many it closed.
+func (n *Namespace) EvictIdle() (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	now := n.clock.Now()
+	n.mu.Lock()
+	var evicted []*namespaceStream
+	for elem := n.idle.Back(); elem != nil; {
+		entry := elem.Value.(*namespaceStream)
+		if now.Sub(entry.releasedAt) < n.idleTimeout {
+			break
+		}
+		elem = elem.Prev()
+		n.removeLocked(entry)
+		evicted = append(evicted, entry)
+	}
+	n.mu.Unlock()
+	return len(evicted), closeStreams(evicted)
+}
+
+// OpenStreams returns the number of streams with an open Log, including idle
+// streams.
+func (n *Namespace) OpenStreams() int {
+	if n == nil {
+		return 0
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.streams)
+}
+
+// Close closes every stream Log, including streams that still have
+// references. Callers must stop using acquired streams before Close. Close
+// does not close writers that were already opened.
+func (n *Namespace) Close() error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil
+	}
+	n.closed = true
+	entries := make([]*namespaceStream, 0, len(n.streams))
+	for _, entry := range n.streams {
+		entries = append(entries, entry)
+	}
+	n.streams = nil
+	n.idle.Init()
+	n.mu.Unlock()
+	return closeStreams(entries)
+}
+
+func (n *Namespace) acquireOpen(id string) (*Stream, bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return nil, false, ErrNamespaceClosed
+	}
+	entry, ok := n.streams[id]
+	if !ok {
+		return nil, false, nil
+	}
+	n.refLocked(entry)
+	return &Stream{ns: n, entry: entry}, true, nil
+}
+
+func (n *Namespace) openLog(id string) (*Log, error) {
+	store, err := n.stores.StreamStore(id)
+	if err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, fmt.Errorf("partitionlog: nil store for stream %q", id)
+	}
+	var keys KeyProvider
+	if n.keys != nil {
+		if keys, err = n.keys(id, store); err != nil {
+			return nil, fmt.Errorf("partitionlog: keys for stream %q: %w", id, err)
+		}
+		if keys == nil {
+			return nil, fmt.Errorf("partitionlog: nil key provider for stream %q", id)
+		}
+	}
+	var (
+		dictionaries DictionaryProvider
+		dicts        *segreader.DictionaryCache
+	)
+	if n.dictionaries != nil {
+		if dictionaries, err = n.dictionaries(id, store); err != nil {
+			return nil, fmt.Errorf("partitionlog: dictionaries for stream %q: %w", id, err)
+		}
+		if dictionaries == nil {
+			return nil, fmt.Errorf("partitionlog: nil dictionary provider for stream %q", id)
+		}
+		dicts = segreader.NewDictionaryCache(dictionaries)
+	}
+	var signer Signer
+	if n.signers != nil {
+		if signer, err = n.signers(id, store); err != nil {
+			return nil, fmt.Errorf("partitionlog: signer for stream %q: %w", id, err)
+		}
+		if signer == nil {
+			return nil, fmt.Errorf("partitionlog: nil signer for stream %q", id)
+		}
+	}
+	readerOpts := n.readerOpts
+	if n.verifiers != nil {
+		if readerOpts.Verifier, err = n.verifiers(id, store); err != nil {
+			return nil, fmt.Errorf("partitionlog: verifier for stream %q: %w", id, err)
+		}
+		if readerOpts.Verifier == nil {
+			return nil, fmt.Errorf("partitionlog: nil verifier for stream %q", id)
+		}
+	}
+	var metrics Metrics
+	if n.metrics != nil {
+		metrics = streamMetrics{metrics: n.metrics, streamID: id}
+	}
+	r, err := newReader(store, readerOpts, metrics, n.runtime, keys, dicts)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{
+		store:         store,
+		metrics:       metrics,
+		reader:        r,
+		clock:         n.clock,
+		keys:          keys,
+		dicts:         dicts,
+		dictPublisher: dictionaries,
+		verifier:      readerOpts.Verifier,
+		signer:        signer,
+		uploadLimiter: n.uploadLimiter,
+	}, nil
+}
+
+func (n *Namespace) refLocked(entry *namespaceStream) {
+	entry.refs++
+	if entry.idleElem != nil {
+		n.idle.Remove(entry.idleElem)
+		entry.idleElem = nil
+	}
+}
+
+func (n *Namespace) release(entry *namespaceStream) {
+	n.mu.Lock()
+	if n.closed || n.streams[entry.id] != entry {
+		n.mu.Unlock()
+		return
+	}
+	entry.refs--
+	if entry.refs > 0 {
+		n.mu.Unlock()
+		return
+	}
+	entry.releasedAt = n.clock.Now()
+	entry.idleElem = n.idle.PushFront(entry)
+	var evicted []*namespaceStream
+	for n.maxIdle > 0 && n.idle.Len() > n.maxIdle {
+		oldest := n.idle.Back().Value.(*namespaceStream)
+		n.removeLocked(oldest)
+		evicted = append(evicted, oldest)
+	}
+	n.mu.Unlock()
+	_ = closeStreams(evicted)
+}
+
+func (n *Namespace) removeLocked(entry *namespaceStream) {
+	if entry.idleElem != nil {
+		n.idle.Remove(entry.idleElem)
+		entry.idleElem = nil
+	}
+	delete(n.streams, entry.id)
+}
+
+func closeStreams(entries []*namespaceStream) error {
+	var errs []error
+	for _, entry := range entries {
+		if err := entry.log.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("partitionlog: close stream %q: %w", entry.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type streamMetrics struct {
+	metrics  Metrics
+	streamID string
+}
+
+func (m streamMetrics) Observe(metric Metric) {
+	metric.StreamID = m.streamID
+	m.metrics.Observe(metric)
+}
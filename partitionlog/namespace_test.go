@@ -0,0 +1,459 @@
+package partitionlog_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/memstore"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+func TestNamespaceOpensIndependentStreamsOnDemand(t *testing.T) {
+	ctx := context.Background()
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	metrics := &streamMetricsRecorder{}
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores:               stores,
+		Metrics:              metrics,
+		MaxConcurrentUploads: 2,
+		Reader: partitionlog.ReaderOptions{
+			RangeCacheBytes:    1 << 20,
+			OpenSegmentReaders: 8,
+			Refresh:            partitionlog.RefreshPolicy{MaxConcurrentRefreshes: 2},
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+
+	for _, streamID := range []string{"agents/a", "agents/b"} {
+		stream, err := ns.Acquire(streamID)
+		if err != nil {
+			t.Fatalf("Acquire(%q) error = %v", streamID, err)
+		}
+		writer, err := stream.Log().OpenWriter(ctx, partitionlog.WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+		if err != nil {
+			t.Fatalf("OpenWriter(%q) error = %v", streamID, err)
+		}
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: 1, Value: []byte(streamID)}); err != nil {
+			t.Fatalf("Append(%q) error = %v", streamID, err)
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%q) error = %v", streamID, err)
+		}
+		stream.Release()
+	}
+
+	for _, streamID := range []string{"agents/a", "agents/b"} {
+		stream, err := ns.Acquire(streamID)
+		if err != nil {
+			t.Fatalf("Acquire(%q) error = %v", streamID, err)
+		}
+		got, err := stream.Log().Reader().Partition(1).Read(ctx, partitionlog.ReadRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("Read(%q) error = %v", streamID, err)
+		}
+		if len(got.Records) != 1 || string(got.Records[0].Value) != streamID {
+			t.Fatalf("Read(%q) = %+v, want the stream's own record", streamID, got)
+		}
+		stream.Release()
+	}
+	if got := ns.OpenStreams(); got != 2 {
+		t.Fatalf("OpenStreams() = %d, want 2", got)
+	}
+	for _, streamID := range []string{"agents/a", "agents/b"} {
+		if !metrics.saw(streamID) {
+			t.Fatalf("metrics did not observe stream %q", streamID)
+		}
+	}
+}
+
+func TestNamespaceSharesLogAndEvictsIdleStreams(t *testing.T) {
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	var clockMu sync.Mutex
+	now := time.Unix(1_700_000_000, 0)
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: stores,
+		Clock: partitionlog.ClockFunc(func() time.Time {
+			clockMu.Lock()
+			defer clockMu.Unlock()
+			return now
+		}),
+		IdleTimeout:    time.Minute,
+		MaxIdleStreams: 2,
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+
+	first, err := ns.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire(first) error = %v", err)
+	}
+	second, err := ns.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire(second) error = %v", err)
+	}
+	if first.Log() != second.Log() {
+		t.Fatal("Acquire returned different logs for one stream")
+	}
+	first.Release()
+	first.Release()
+	clockMu.Lock()
+	now = now.Add(2 * time.Minute)
+	clockMu.Unlock()
+	if evicted, err := ns.EvictIdle(); err != nil || evicted != 0 {
+		t.Fatalf("EvictIdle(referenced) = %d, %v, want 0", evicted, err)
+	}
+	second.Release()
+	if evicted, err := ns.EvictIdle(); err != nil || evicted != 0 {
+		t.Fatalf("EvictIdle(fresh) = %d, %v, want 0", evicted, err)
+	}
+	clockMu.Lock()
+	now = now.Add(2 * time.Minute)
+	clockMu.Unlock()
+	if evicted, err := ns.EvictIdle(); err != nil || evicted != 1 {
+		t.Fatalf("EvictIdle(expired) = %d, %v, want 1", evicted, err)
+	}
+	if _, err := second.Log().Reader().Partition(1).Head(context.Background()); !errors.Is(err, partitionlog.ErrReaderClosed) {
+		t.Fatalf("evicted reader Head() error = %v, want %v", err, partitionlog.ErrReaderClosed)
+	}
+
+	for _, streamID := range []string{"agents/a", "agents/b", "agents/c"} {
+		stream, err := ns.Acquire(streamID)
+		if err != nil {
+			t.Fatalf("Acquire(%q) error = %v", streamID, err)
+		}
+		stream.Release()
+	}
+	if got := ns.OpenStreams(); got != 2 {
+		t.Fatalf("OpenStreams() = %d, want MaxIdleStreams", got)
+	}
+
+	if err := ns.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := ns.Acquire("agents/a"); !errors.Is(err, partitionlog.ErrNamespaceClosed) {
+		t.Fatalf("Acquire(after close) error = %v, want %v", err, partitionlog.ErrNamespaceClosed)
+	}
+}
+
+func TestNamespaceOpensStreamsWithKeysAndDictionaries(t *testing.T) {
+	ctx := context.Background()
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	keys, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	var keyStreams []string
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: stores,
+		Keys: func(streamID string, _ partitionlog.Store) (partitionlog.KeyProvider, error) {
+			keyStreams = append(keyStreams, streamID)
+			return keys, nil
+		},
+		Dictionaries: partitionlog.DictionaryStores(),
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+	stream, err := ns.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer stream.Release()
+	log := stream.Log()
+	event := func(i int) []byte {
+		return []byte(fmt.Sprintf(`{"event":"tool_call","session":"s-%03d","status":"ok"}`, i%41))
+	}
+	write := func(partition uint32, pipeline partitionlog.WriterPipelineOptions, n int) {
+		t.Helper()
+		writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{Partition: partition, WriterID: [16]byte{byte(partition)}, Pipeline: pipeline})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		for i := 0; i < n; i++ {
+			if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: event(i)}); err != nil {
+				t.Fatalf("Append(%d) error = %v", partition, err)
+			}
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%d) error = %v", partition, err)
+		}
+	}
+	write(1, partitionlog.WriterPipelineOptions{}, 200)
+	trained, err := log.TrainDictionary(ctx, partitionlog.TrainDictionary{Partition: 1, MaxBytes: 8 << 10})
+	if err != nil {
+		t.Fatalf("TrainDictionary() error = %v", err)
+	}
+	write(2, partitionlog.WriterPipelineOptions{DictionaryID: trained.DictionaryID}, 10)
+	if len(keyStreams) != 1 || keyStreams[0] != "agents/a" {
+		t.Fatalf("Keys called for %q, want agents/a once", keyStreams)
+	}
+
+	store, err := stores.Store("agents/a")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	page, err := store.ReaderCatalog().ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 2, Limit: 1})
+	if err != nil || len(page.Segments) != 1 {
+		t.Fatalf("ListSegments() = %+v, %v; want one segment", page, err)
+	}
+	if segment := page.Segments[0]; segment.KeyID == "" || segment.Codec != segformat.CodecZstdDict {
+		t.Fatalf("segment key=%q codec=%s, want encrypted zstd-dict", segment.KeyID, segment.Codec)
+	}
+	read, err := log.Reader().Partition(2).Read(ctx, partitionlog.ReadRequest{Limit: 10})
+	if err != nil || len(read.Records) != 10 || !bytes.Equal(read.Records[9].Value, event(9)) {
+		t.Fatalf("Read() = %+v, %v; want the 10 records", read, err)
+	}
+
+	plain, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{Stores: stores})
+	if err != nil {
+		t.Fatalf("OpenNamespace(plain) error = %v", err)
+	}
+	defer plain.Close()
+	plainStream, err := plain.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire(plain) error = %v", err)
+	}
+	defer plainStream.Release()
+	if _, err := plainStream.Log().Reader().Partition(2).Read(ctx, partitionlog.ReadRequest{Limit: 1}); !errors.Is(err, segreader.ErrKeyUnavailable) {
+		t.Fatalf("Read(without keys) error = %v, want %v", err, segreader.ErrKeyUnavailable)
+	}
+
+	unwired, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: stores,
+		Keys: func(string, partitionlog.Store) (partitionlog.KeyProvider, error) {
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace(unwired) error = %v", err)
+	}
+	defer unwired.Close()
+	if _, err := unwired.Acquire("agents/a"); err == nil {
+		t.Fatal("Acquire(nil key provider) error = nil, want error")
+	}
+}
+
+func TestNamespaceKeyringsShredOneStream(t *testing.T) {
+	ctx := context.Background()
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	root, err := segblock.NewStaticKeyProvider("root-1", map[string][]byte{"root-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{Stores: stores, Keys: partitionlog.Keyrings(root)})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+	for _, streamID := range []string{"agents/a", "agents/b"} {
+		stream, err := ns.Acquire(streamID)
+		if err != nil {
+			t.Fatalf("Acquire(%q) error = %v", streamID, err)
+		}
+		writer, err := stream.Log().OpenWriter(ctx, partitionlog.WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+		if err != nil {
+			t.Fatalf("OpenWriter(%q) error = %v", streamID, err)
+		}
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: 1, Value: []byte("secret")}); err != nil {
+			t.Fatalf("Append(%q) error = %v", streamID, err)
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%q) error = %v", streamID, err)
+		}
+		stream.Release()
+	}
+
+	shredded, err := ns.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire(agents/a) error = %v", err)
+	}
+	defer shredded.Release()
+	if result, err := shredded.Log().ShredPartition(ctx, 1); err != nil || !result.KeyDestroyed {
+		t.Fatalf("ShredPartition() = %+v, %v; want the key destroyed", result, err)
+	}
+	if _, err := shredded.Log().Reader().Partition(1).Read(ctx, partitionlog.ReadRequest{Limit: 1, Freshness: partitionlog.FreshnessLatest}); !errors.Is(err, partitionlog.ErrShredded) {
+		t.Fatalf("Read(shredded) error = %v, want %v", err, partitionlog.ErrShredded)
+	}
+	kept, err := ns.Acquire("agents/b")
+	if err != nil {
+		t.Fatalf("Acquire(agents/b) error = %v", err)
+	}
+	defer kept.Release()
+	read, err := kept.Log().Reader().Partition(1).Read(ctx, partitionlog.ReadRequest{Limit: 1})
+	if err != nil || len(read.Records) != 1 || string(read.Records[0].Value) != "secret" {
+		t.Fatalf("Read(other stream) = %+v, %v; want its record", read, err)
+	}
+
+	// A Store without NewKeyring is refused rather than opened without keys.
+	bare, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: partitionlog.StoreProviderFunc(func(streamID string) (partitionlog.Store, error) {
+			store, err := stores.StreamStore(streamID)
+			return struct{ partitionlog.Store }{store}, err
+		}),
+		Keys: partitionlog.Keyrings(root),
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace(bare) error = %v", err)
+	}
+	defer bare.Close()
+	if _, err := bare.Acquire("agents/b"); err == nil {
+		t.Fatal("Acquire(store without keyring) error = nil, want error")
+	}
+}
+
+func TestNamespaceSignsAndVerifiesPerStream(t *testing.T) {
+	ctx := context.Background()
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	signers := make(map[string]*signing.Ed25519Signer)
+	verifiers := make(map[string]*signing.Ed25519Verifier)
+	for i, streamID := range []string{"agents/a", "agents/b"} {
+		public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{byte(i + 1)}, ed25519.SeedSize)))
+		if err != nil {
+			t.Fatalf("GenerateKey() error = %v", err)
+		}
+		if signers[streamID], err = signing.NewEd25519Signer(streamID, private); err != nil {
+			t.Fatalf("NewEd25519Signer() error = %v", err)
+		}
+		if verifiers[streamID], err = signing.NewEd25519Verifier(map[string]ed25519.PublicKey{streamID: public}); err != nil {
+			t.Fatalf("NewEd25519Verifier() error = %v", err)
+		}
+	}
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: stores,
+		Signers: func(streamID string, _ partitionlog.Store) (partitionlog.Signer, error) {
+			return signers[streamID], nil
+		},
+		Verifiers: func(streamID string, _ partitionlog.Store) (partitionlog.Verifier, error) {
+			return verifiers[streamID], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+	stream, err := ns.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer stream.Release()
+	log := stream.Log()
+	for _, partition := range []uint32{1, 2} {
+		writer, err := log.OpenWriter(ctx, partitionlog.WriterOptions{Partition: partition, WriterID: [16]byte{byte(partition)}, Signer: signers["agents/a"]})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Append(ctx, partitionlog.Record{TimestampMS: 1, Value: []byte("signed")}); err != nil {
+			t.Fatalf("Append(%d) error = %v", partition, err)
+		}
+		if _, err := writer.Close(ctx); err != nil {
+			t.Fatalf("Close(%d) error = %v", partition, err)
+		}
+	}
+	if _, err := log.Reader().Partition(1).Read(ctx, partitionlog.ReadRequest{Limit: 1}); err != nil {
+		t.Fatalf("Read(signed) error = %v", err)
+	}
+	// The stream's Log signs the heads it commits itself.
+	sealed, err := log.SealPartition(ctx, partitionlog.SealPartition{Partition: 2, WriterID: [16]byte{9}})
+	if err != nil || !sealed.Sealed {
+		t.Fatalf("SealPartition() = %+v, %v", sealed, err)
+	}
+	if err := signing.VerifyHead(verifiers["agents/a"], sealed.Head); err != nil {
+		t.Fatalf("VerifyHead(sealed) error = %v", err)
+	}
+
+	// A namespace that trusts another stream's key rejects this one.
+	mixed, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{
+		Stores: stores,
+		Verifiers: func(string, partitionlog.Store) (partitionlog.Verifier, error) {
+			return verifiers["agents/b"], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("OpenNamespace(mixed) error = %v", err)
+	}
+	defer mixed.Close()
+	other, err := mixed.Acquire("agents/a")
+	if err != nil {
+		t.Fatalf("Acquire(mixed) error = %v", err)
+	}
+	defer other.Release()
+	if _, err := other.Log().Reader().Partition(1).Read(ctx, partitionlog.ReadRequest{Limit: 1}); !errors.Is(err, partitionlog.ErrInvalidSignature) {
+		t.Fatalf("Read(untrusted key) error = %v, want %v", err, partitionlog.ErrInvalidSignature)
+	}
+	if _, err := other.Log().ProveRecord(ctx, 1, 0, 0); !errors.Is(err, partitionlog.ErrInvalidSignature) {
+		t.Fatalf("ProveRecord(untrusted key) error = %v, want %v", err, partitionlog.ErrInvalidSignature)
+	}
+}
+
+func TestNamespaceRejectsBadOptions(t *testing.T) {
+	if _, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{}); err == nil {
+		t.Fatal("OpenNamespace(nil stores) error = nil, want error")
+	}
+	if _, err := memstore.NewNamespace(memstore.Options{StreamID: "fixed"}); err == nil {
+		t.Fatal("memstore.NewNamespace(stream id) error = nil, want error")
+	}
+	stores, err := memstore.NewNamespace(memstore.Options{})
+	if err != nil {
+		t.Fatalf("memstore.NewNamespace() error = %v", err)
+	}
+	ns, err := partitionlog.OpenNamespace(partitionlog.NamespaceOptions{Stores: stores})
+	if err != nil {
+		t.Fatalf("OpenNamespace() error = %v", err)
+	}
+	defer ns.Close()
+	if _, err := ns.Acquire(""); err == nil {
+		t.Fatal("Acquire(empty stream) error = nil, want error")
+	}
+}
+
+type streamMetricsRecorder struct {
+	mu      sync.Mutex
+	streams map[string]bool
+}
+
+func (m *streamMetricsRecorder) Observe(metric partitionlog.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.streams == nil {
+		m.streams = make(map[string]bool)
+	}
+	m.streams[metric.StreamID] = true
+}
+
+func (m *streamMetricsRecorder) saw(streamID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.streams[streamID]
+}
@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
 	blobcache "github.com/ankur-anand/unijord/partitionlog/blob/cache"
 	"github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/catalog/writeradapter"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 	"github.com/ankur-anand/unijord/partitionlog/reader"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 	lowwriter "github.com/ankur-anand/unijord/partitionlog/writer"
 )
 
@@ -28,9 +34,144 @@ type Options struct {
 	// Clock supplies timestamps and timers for durable metadata and age-based
 	// writer rolling. Nil uses the system clock.
 	Clock Clock
+	// Keys encrypts every segment written through the Log and decrypts every
+	// segment it reads. Nil writes plaintext segments and cannot read
+	// encrypted ones. A keyring.Keyring also enables ShredPartition.
+	Keys KeyProvider
+	// Dictionaries loads the trained compression dictionaries that zstd-dict
+	// segments name, and that WriterPipelineOptions.DictionaryID selects.
+	// Nil cannot read or write zstd-dict segments. A dictstore.Store also
+	// enables TrainDictionary.
+	Dictionaries DictionaryProvider
+	// Signer, when set, signs the heads the Log commits itself:
+	// SealPartition, DeletePartition, ShredPartition and DeleteStream. Without
+	// it those calls refuse a partition whose head is signed with
+	// ErrSignerRequired. Writers take their own WriterOptions.Signer.
+	Signer Signer
 }
 
-var ErrLogClosed = errors.New("partitionlog: log closed")
+// KeyProvider wraps and unwraps per-segment data keys.
+type KeyProvider = segblock.KeyProvider
+
+// DictionaryProvider loads trained compression dictionaries by ID.
+type DictionaryProvider = segblock.DictionaryProvider
+
+// DictionaryPublisher is a DictionaryProvider that can store new
+// dictionaries. dictstore.Store implements it.
+type DictionaryPublisher interface {
+	Publish(ctx context.Context, raw []byte) (uint32, error)
+}
+
+// PartitionKeyDestroyer is a KeyProvider that can destroy every key of one
+// partition. keyring.Keyring implements it.
+type PartitionKeyDestroyer interface {
+	DestroyPartitionKey(ctx context.Context, partition uint32) (bool, error)
+}
+
+var (
+	ErrLogClosed = errors.New("partitionlog: log closed")
+	// ErrPartitionListUnsupported reports a store whose catalog cannot
+	// enumerate partitions.
+	ErrPartitionListUnsupported = errors.New("partitionlog: partition listing unsupported")
+	// ErrPartitionSealed reports a write or writer open against a sealed
+	// partition.
+	ErrPartitionSealed = catalog.ErrPartitionSealed
+	// ErrSealUnsupported reports a store whose catalog cannot seal partitions.
+	ErrSealUnsupported = catalog.ErrSealUnsupported
+	// ErrSealFailed reports a Writer.Seal whose catalog seal did not apply.
+	ErrSealFailed = lowwriter.ErrSealFailed
+	// ErrPartitionDeleted reports a read, write or writer open against a
+	// deleted partition.
+	ErrPartitionDeleted = catalog.ErrPartitionDeleted
+	// ErrDeleteUnsupported reports a store whose catalog cannot delete
+	// partitions.
+	ErrDeleteUnsupported = errors.New("partitionlog: partition delete unsupported")
+	// ErrRetentionRegression reports a retention request that does not advance
+	// the stored policy, or an LSN boundary below the applied one.
+	ErrRetentionRegression = catalog.ErrRetentionRegression
+	// ErrLegalHold reports a retention apply or delete refused because a legal
+	// hold covers the partition.
+	ErrLegalHold = catalog.ErrLegalHold
+	// ErrLegalHoldUnsupported reports a store whose catalog cannot store legal
+	// holds.
+	ErrLegalHoldUnsupported = catalog.ErrLegalHoldUnsupported
+	// ErrShredUnsupported reports a Log whose key provider cannot destroy
+	// partition keys.
+	ErrShredUnsupported = errors.New("partitionlog: shred unsupported")
+	// ErrShredded reports a read of a segment whose key was destroyed, or of a
+	// partition that ShredPartition tombstoned.
+	ErrShredded = reader.ErrShredded
+	// ErrPartitionShredded comes with ErrPartitionDeleted from catalog calls
+	// against a partition that ShredPartition tombstoned.
+	ErrPartitionShredded = catalog.ErrPartitionShredded
+	// ErrReservedRecord reports an appended record that uses
+	// RedactionHeaderKey, RedactionDigestHeaderKey or RedactedValue, which
+	// only Writer.Redact may write.
+	ErrReservedRecord = lowwriter.ErrReservedRecord
+	// ErrInvalidRedaction reports a redaction of LSNs outside the writer's
+	// committed range.
+	ErrInvalidRedaction = errors.New("partitionlog: invalid redaction")
+	// ErrRedactionUnsupported reports a store whose catalog cannot replace
+	// committed segments.
+	ErrRedactionUnsupported = lowwriter.ErrRedactionUnsupported
+	// ErrDuplicateSequence reports a producer Sequence below the producer's
+	// last appended Sequence. That record was already appended.
+	ErrDuplicateSequence = lowwriter.ErrDuplicateSequence
+	// ErrSequenceGap reports a producer Sequence more than one past the
+	// producer's last appended Sequence.
+	ErrSequenceGap = lowwriter.ErrSequenceGap
+	// ErrUnknownProducer reports a Sequence other than FirstSequence from a
+	// producer the partition does not track, either new or forgotten past
+	// MaxProducers. Nothing was appended.
+	ErrUnknownProducer = lowwriter.ErrUnknownProducer
+	// ErrLSNConflict reports a conditional append whose expected next LSN is
+	// not the writer's. Nothing was appended.
+	ErrLSNConflict = lowwriter.ErrLSNConflict
+	// ErrEmptyBatch reports a batch append without records.
+	ErrEmptyBatch = lowwriter.ErrEmptyBatch
+	// ErrNotAppended reports a commit wait for an LSN the writer has not
+	// assigned.
+	ErrNotAppended = lowwriter.ErrNotAppended
+	// ErrWALFailed reports a WAL that could not store or replay records. A
+	// failed store fails the writer.
+	ErrWALFailed = lowwriter.ErrWALFailed
+	// ErrWALMismatch reports a WAL written for another stream or partition,
+	// or by a newer writer of this one. OpenWriter refuses it.
+	ErrWALMismatch = lowwriter.ErrWALMismatch
+	// ErrProofUnsupported reports a store whose catalog cannot prove
+	// segments.
+	ErrProofUnsupported = catalog.ErrProofUnsupported
+	// ErrInvalidProof reports a proof that does not verify, or a segment
+	// whose records do not match its committed records root.
+	ErrInvalidProof = proof.ErrInvalidProof
+	// ErrSigningUnsupported reports a store whose catalog cannot sign
+	// partition heads.
+	ErrSigningUnsupported = catalog.ErrSigningUnsupported
+	// ErrSignerRequired reports an unsigned head commit refused because the
+	// partition head is signed.
+	ErrSignerRequired = catalog.ErrSignerRequired
+	// ErrWriterActive reports an idle-only OpenWriter refused because another
+	// writer holds the partition and has not closed.
+	ErrWriterActive = catalog.ErrWriterActive
+	// ErrIdleWriterUnsupported reports a store whose catalog does not record
+	// when writers close, so it cannot open idle-only writers.
+	ErrIdleWriterUnsupported = errors.New("partitionlog: idle-only writer unsupported")
+	// ErrReleaseFailed reports a Writer.Close that published every record but
+	// could not release the partition fence.
+	ErrReleaseFailed = lowwriter.ErrReleaseFailed
+	// ErrUnsigned reports a segment or head without a signature read through
+	// a Verifier.
+	ErrUnsigned = signing.ErrUnsigned
+	// ErrInvalidSignature reports a segment or head whose signature does not
+	// verify under a trusted key.
+	ErrInvalidSignature = signing.ErrInvalidSignature
+	// ErrDictionaryUnavailable reports a zstd-dict segment or writer whose
+	// dictionary cannot be loaded.
+	ErrDictionaryUnavailable = segreader.ErrDictionaryUnavailable
+	// ErrDictionaryUnsupported reports a Log whose dictionary provider cannot
+	// publish dictionaries.
+	ErrDictionaryUnsupported = errors.New("partitionlog: dictionary publishing unsupported")
+)
 
 // ReaderOptions configures the default reader created by Open.
 type ReaderOptions struct {
@@ -49,6 +190,10 @@ type ReaderOptions struct {
 	OpenSegmentReaders int
 
 	Refresh RefreshPolicy
+
+	// Verifier, when set, rejects partition heads and segments that are not
+	// signed by a trusted writer key with ErrUnsigned or ErrInvalidSignature.
+	Verifier Verifier
 }
 
 // WriterOptions configures one per-partition writer opened from a Log.
@@ -61,6 +206,28 @@ type WriterOptions struct {
 	Batch        BatchPolicy
 	Backpressure BackpressurePolicy
 	Pipeline     WriterPipelineOptions
+
+	// Signer, when set, signs every segment ref and partition head this
+	// writer commits. The store's catalog must support signing.
+	Signer Signer
+
+	// MaxProducers bounds the idempotent producers tracked for the
+	// partition. When a new producer exceeds it, the producer whose last
+	// append is oldest is forgotten and its later appends fail with
+	// ErrUnknownProducer. 0 uses the default of 256.
+	MaxProducers int
+
+	// WAL, when set, stores every append locally before it returns, and
+	// opening the writer commits the records a previous writer acknowledged
+	// but did not publish. See filewal for a WAL in a local directory.
+	WAL WAL
+
+	// IdleOnly opens the writer only when the partition never had a writer or
+	// its last writer released the fence in Close. Otherwise OpenWriter fails
+	// with ErrWriterActive and no writer is fenced out. A writer that stopped
+	// without Close keeps its fence from idle-only writers until its owner
+	// reopens the partition.
+	IdleOnly bool
 }
 
 // Log is one partitionlog client over one configured store.
@@ -70,7 +237,21 @@ type Log struct {
 	metrics Metrics
 	reader  *Reader
 	clock   lowwriter.Clock
-	closed  bool
+	keys    KeyProvider
+	// dicts caches the dictionaries of Options.Dictionaries for every reader
+	// and writer of the Log; dictPublisher is Options.Dictionaries itself.
+	dicts         *segreader.DictionaryCache
+	dictPublisher DictionaryProvider
+	// verifier checks the segments ProveRecord reads, as the default
+	// reader does.
+	verifier Verifier
+	// signer signs the heads of seals and tombstones.
+	signer Signer
+	closed bool
+
+	// uploadLimiter is the default for writers that do not set one. Logs
+	// opened from a Namespace share it with their siblings.
+	uploadLimiter segwriter.UploadLimiter
 }
 
 // Open validates a complete Store and prepares the default reader runtime.
@@ -78,7 +259,11 @@ func Open(opts Options) (*Log, error) {
 	if opts.Store == nil {
 		return nil, fmt.Errorf("partitionlog: nil store")
 	}
-	r, err := newReader(opts.Store, opts.Reader, opts.Metrics)
+	var dicts *segreader.DictionaryCache
+	if opts.Dictionaries != nil {
+		dicts = segreader.NewDictionaryCache(opts.Dictionaries)
+	}
+	r, err := newReader(opts.Store, opts.Reader, opts.Metrics, readerRuntime{}, opts.Keys, dicts)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +271,17 @@ func Open(opts Options) (*Log, error) {
 	if clock == nil {
 		clock = lowwriter.SystemClock{}
 	}
-	return &Log{store: opts.Store, metrics: opts.Metrics, reader: r, clock: clock}, nil
+	return &Log{
+		store:         opts.Store,
+		metrics:       opts.Metrics,
+		reader:        r,
+		clock:         clock,
+		keys:          opts.Keys,
+		dicts:         dicts,
+		dictPublisher: opts.Dictionaries,
+		verifier:      opts.Reader.Verifier,
+		signer:        opts.Signer,
+	}, nil
 }
 
 // Close releases the default Reader runtime. Callers must stop using the Log
@@ -147,7 +342,7 @@ func (l *Log) NewReader(opts ReaderOptions) (*Reader, error) {
 	if l.store == nil {
 		return nil, fmt.Errorf("partitionlog: nil log")
 	}
-	return newReader(l.store, opts, l.metrics)
+	return newReader(l.store, opts, l.metrics, readerRuntime{}, l.keys, l.dicts)
 }
 
 // InitializePartition creates an empty partition at a chosen next LSN only
@@ -167,6 +362,442 @@ func (l *Log) InitializePartition(ctx context.Context, opts InitializePartition)
 	return InitializePartitionResult{Head: head, Created: created}, nil
 }
 
+// LoadPartition reads the durable head of one partition directly from the
+// catalog, bypassing the reader head cache. It returns ErrPartitionDeleted for
+// a deleted partition.
+func (l *Log) LoadPartition(ctx context.Context, partition uint32) (PartitionHead, error) {
+	if err := l.checkOpen(); err != nil {
+		return PartitionHead{}, err
+	}
+	readerCatalog := l.store.ReaderCatalog()
+	if readerCatalog == nil {
+		return PartitionHead{}, fmt.Errorf("partitionlog: nil reader catalog")
+	}
+	return readerCatalog.LoadPartition(ctx, partition)
+}
+
+// FindSegment reads the retained segment that holds lsn directly from the
+// catalog. Found is false when lsn is not retained.
+func (l *Log) FindSegment(ctx context.Context, partition uint32, lsn uint64) (segment SegmentRef, found bool, err error) {
+	if err := l.checkOpen(); err != nil {
+		return SegmentRef{}, false, err
+	}
+	readerCatalog := l.store.ReaderCatalog()
+	if readerCatalog == nil {
+		return SegmentRef{}, false, fmt.Errorf("partitionlog: nil reader catalog")
+	}
+	return readerCatalog.FindSegment(ctx, partition, lsn)
+}
+
+// ListPartitions returns one page of the partitions that exist in the stream.
+// Deleted partitions are skipped unless req.IncludeDeleted is set, and purged
+// ones are never listed. Walk a whole stream by passing each page's
+// NextAfterKey until HasMore is false. Order follows catalog storage, not
+// partition number.
+func (l *Log) ListPartitions(ctx context.Context, req ListPartitionsRequest) (PartitionPage, error) {
+	if err := l.checkOpen(); err != nil {
+		return PartitionPage{}, err
+	}
+	lister, ok := l.store.ReaderCatalog().(catalog.PartitionLister)
+	if !ok {
+		return PartitionPage{}, ErrPartitionListUnsupported
+	}
+	return lister.ListPartitions(ctx, req)
+}
+
+// SealPartition makes a partition permanently read-only. It is for partitions
+// without a live owner: it takes the writer fence itself, so a live writer's
+// records that were accepted but not yet published are lost and its later
+// publishes fail as stale. A partition owner seals with Writer.Seal instead,
+// which publishes every accepted record first. Readers that reach the sealed
+// tail get ErrEndOfTimeline. Sealing an already sealed partition returns its
+// head with Sealed false.
+func (l *Log) SealPartition(ctx context.Context, req SealPartition) (SealPartitionResult, error) {
+	if err := l.checkOpen(); err != nil {
+		return SealPartitionResult{}, err
+	}
+	manager := l.store.WriterManager()
+	if manager == nil {
+		return SealPartitionResult{}, fmt.Errorf("partitionlog: nil writer catalog")
+	}
+	head, err := l.store.ReaderCatalog().LoadPartition(ctx, req.Partition)
+	if err != nil {
+		return SealPartitionResult{}, err
+	}
+	if head.Sealed {
+		return SealPartitionResult{Head: head}, nil
+	}
+
+	var session catalog.WriterSession
+	if l.signer != nil {
+		signingManager, ok := manager.(catalog.SigningWriterManager)
+		if !ok {
+			return SealPartitionResult{}, ErrSigningUnsupported
+		}
+		session, err = signingManager.OpenSigningWriter(ctx, req.Partition, req.WriterID, l.signer)
+	} else if head.SignerKeyID != "" {
+		return SealPartitionResult{}, fmt.Errorf("%w: seal partition=%d", ErrSignerRequired, req.Partition)
+	} else {
+		session, err = manager.OpenWriter(ctx, req.Partition, req.WriterID)
+	}
+	if errors.Is(err, catalog.ErrPartitionSealed) {
+		// Another caller sealed the partition after the head was loaded.
+		head, err = l.store.ReaderCatalog().LoadPartition(ctx, req.Partition)
+		if err != nil {
+			return SealPartitionResult{}, err
+		}
+		return SealPartitionResult{Head: head}, nil
+	}
+	if err != nil {
+		return SealPartitionResult{}, err
+	}
+	sealer, ok := session.(catalog.SealWriterSession)
+	if !ok {
+		return SealPartitionResult{}, ErrSealUnsupported
+	}
+	head, err = sealer.SealPartition(ctx)
+	if err != nil {
+		return SealPartitionResult{}, err
+	}
+	return SealPartitionResult{Head: head, Sealed: true}, nil
+}
+
+// ProveRecord returns an inclusion proof for the record at lsn against the
+// partition checkpoint of treeSize, or against the current head when treeSize
+// is zero. Pass the TreeSize of a checkpoint published earlier to prove
+// against it after the partition grew. It reads the record's segment to build
+// the record step, so it needs the same keys as a read. Verify proofs offline
+// with proof.Verify. Segments written before hash chaining return
+// proof.ErrUnchained.
+func (l *Log) ProveRecord(ctx context.Context, partition uint32, lsn, treeSize uint64) (RecordProof, error) {
+	if err := l.checkOpen(); err != nil {
+		return RecordProof{}, err
+	}
+	prover, ok := l.store.ReaderCatalog().(catalog.SegmentProver)
+	if !ok {
+		return RecordProof{}, ErrProofUnsupported
+	}
+	segmentProof, err := prover.ProveSegment(ctx, partition, lsn, treeSize)
+	if err != nil {
+		return RecordProof{}, err
+	}
+	segment := segmentProof.Segment
+	if segment.RecordsRoot.IsZero() {
+		return RecordProof{}, fmt.Errorf("%w: base_lsn=%d", proof.ErrUnchained, segment.BaseLSN)
+	}
+
+	opts := segreader.DefaultOptions()
+	opts.KeyProvider = l.keys
+	opts.Dictionaries = l.dictionaries()
+	opts.Verifier = l.verifier
+	sr, err := segreader.Open(ctx, l.store.SegmentStore(), segment, opts)
+	if err != nil {
+		return RecordProof{}, err
+	}
+	scanner, err := sr.Scan(ctx, segment.BaseLSN)
+	if err != nil {
+		return RecordProof{}, err
+	}
+	defer scanner.Close()
+
+	result := RecordProof{LSN: lsn, SegmentProof: segmentProof}
+	leaves := make([]pmeta.Hash, 0, segment.RecordCount)
+	for {
+		record, ok, err := scanner.Next(ctx)
+		if err != nil {
+			return RecordProof{}, err
+		}
+		if !ok {
+			break
+		}
+		digest := proof.LeafDigest(record.Headers, record.Value)
+		if record.LSN == lsn {
+			result.TimestampMS = record.TimestampMS
+			result.Digest = digest
+		}
+		leaves = append(leaves, proof.RecordLeaf(record.LSN, record.TimestampMS, digest))
+	}
+	if proof.Root(leaves) != segment.RecordsRoot {
+		return RecordProof{}, fmt.Errorf("%w: segment %s records do not match records_root", ErrInvalidProof, segment.URI)
+	}
+	result.Record, err = proof.NewStep(leaves, int(lsn-segment.BaseLSN))
+	if err != nil {
+		return RecordProof{}, err
+	}
+	return result, nil
+}
+
+// ProveConsistency returns a proof that the partition checkpoint of toSize
+// extends the checkpoint of fromSize, or that the current head does when
+// toSize is zero. A client that verified records against an older published
+// checkpoint checks it with proof.VerifyConsistency before trusting a newer
+// one.
+func (l *Log) ProveConsistency(ctx context.Context, partition uint32, fromSize, toSize uint64) (ConsistencyProof, error) {
+	if err := l.checkOpen(); err != nil {
+		return ConsistencyProof{}, err
+	}
+	prover, ok := l.store.ReaderCatalog().(catalog.SegmentProver)
+	if !ok {
+		return ConsistencyProof{}, ErrProofUnsupported
+	}
+	return prover.ProveConsistency(ctx, partition, fromSize, toSize)
+}
+
+// DeletePartition tombstones a whole partition. Readers stop seeing it and
+// every writer is fenced out immediately; the store's lifecycle reclaimer
+// removes its objects after the delete delay. Deleting an absent or already
+// deleted partition returns Deleted false. Options.Signer signs the
+// tombstone.
+func (l *Log) DeletePartition(ctx context.Context, partition uint32) (DeletePartitionResult, error) {
+	if err := l.checkOpen(); err != nil {
+		return DeletePartitionResult{}, err
+	}
+	head, deleted, err := l.deletePartition(ctx, partition)
+	if err != nil {
+		return DeletePartitionResult{}, err
+	}
+	return DeletePartitionResult{Head: head, Deleted: deleted}, nil
+}
+
+// deletePartition tombstones partition, signing the tombstone when the Log
+// has a signer.
+func (l *Log) deletePartition(ctx context.Context, partition uint32) (PartitionHead, bool, error) {
+	manager := l.store.WriterManager()
+	if l.signer != nil {
+		deleter, ok := manager.(catalog.SigningPartitionDeleter)
+		if !ok {
+			return PartitionHead{}, false, ErrSigningUnsupported
+		}
+		return deleter.DeleteSignedPartition(ctx, partition, l.signer)
+	}
+	deleter, ok := manager.(catalog.PartitionDeleter)
+	if !ok {
+		return PartitionHead{}, false, ErrDeleteUnsupported
+	}
+	return deleter.DeletePartition(ctx, partition)
+}
+
+// ShredPartition crypto-shreds a partition. It tombstones the partition like
+// DeletePartition and marks the tombstone shredded, so reads report
+// ErrShredded, then destroys its partition key, which makes every segment
+// written under that key unreadable at once. Readers that already hold an open
+// segment may serve it until they drop it. The lifecycle reclaimer removes the
+// objects after the delete delay. A legal hold refuses the shred with
+// ErrLegalHold before any key is destroyed. Retrying after an error is safe.
+func (l *Log) ShredPartition(ctx context.Context, partition uint32) (ShredPartitionResult, error) {
+	if err := l.checkOpen(); err != nil {
+		return ShredPartitionResult{}, err
+	}
+	destroyer, ok := l.keys.(PartitionKeyDestroyer)
+	if !ok {
+		return ShredPartitionResult{}, ErrShredUnsupported
+	}
+	shredder, ok := l.store.WriterManager().(catalog.PartitionShredder)
+	if !ok {
+		return ShredPartitionResult{}, ErrShredUnsupported
+	}
+	head, deleted, err := shredder.ShredPartition(ctx, partition, l.signer)
+	if err != nil {
+		return ShredPartitionResult{}, err
+	}
+	destroyed, err := destroyer.DestroyPartitionKey(ctx, partition)
+	if err != nil {
+		return ShredPartitionResult{}, fmt.Errorf("partitionlog: destroy partition=%d key: %w", partition, err)
+	}
+	return ShredPartitionResult{Head: head, Deleted: deleted, KeyDestroyed: destroyed}, nil
+}
+
+// DefaultDictionarySamples is the number of records TrainDictionary samples
+// when MaxSamples is zero.
+const DefaultDictionarySamples = 10_000
+
+// TrainDictionary trains a zstd dictionary on record values read from one
+// partition and publishes it through Options.Dictionaries, which must be a
+// DictionaryPublisher. Writers select the result with
+// WriterPipelineOptions.DictionaryID. Retraining on the same records yields
+// an equivalent dictionary, possibly under a new ID.
+func (l *Log) TrainDictionary(ctx context.Context, req TrainDictionary) (TrainDictionaryResult, error) {
+	if err := l.checkOpen(); err != nil {
+		return TrainDictionaryResult{}, err
+	}
+	publisher, ok := l.dictPublisher.(DictionaryPublisher)
+	if !ok {
+		return TrainDictionaryResult{}, ErrDictionaryUnsupported
+	}
+	if req.MaxSamples < 0 {
+		return TrainDictionaryResult{}, fmt.Errorf("partitionlog: negative max samples %d", req.MaxSamples)
+	}
+	maxSamples := req.MaxSamples
+	if maxSamples == 0 {
+		maxSamples = DefaultDictionarySamples
+	}
+	head, err := l.LoadPartition(ctx, req.Partition)
+	if err != nil {
+		return TrainDictionaryResult{}, err
+	}
+	next := max(req.StartLSN, head.OldestLSN)
+	samples := make([][]byte, 0, min(maxSamples, 1024))
+	for len(samples) < maxSamples && next < head.NextLSN {
+		result, err := l.reader.Consume(ctx, reader.ConsumeRequest{
+			Partition: req.Partition,
+			StartLSN:  next,
+			Limit:     maxSamples - len(samples),
+		})
+		if err != nil {
+			return TrainDictionaryResult{}, err
+		}
+		if len(result.Records) == 0 {
+			break
+		}
+		for _, record := range result.Records {
+			if len(record.Value) > 0 {
+				samples = append(samples, record.Value)
+			}
+		}
+		next = result.NextLSN
+	}
+	raw, err := segblock.TrainDictionary(samples, req.MaxBytes)
+	if err != nil {
+		return TrainDictionaryResult{}, err
+	}
+	id, err := publisher.Publish(ctx, raw)
+	if err != nil {
+		return TrainDictionaryResult{}, err
+	}
+	return TrainDictionaryResult{DictionaryID: id, Samples: len(samples)}, nil
+}
+
+// dictionaries returns the Log's dictionary cache as a provider, or nil.
+func (l *Log) dictionaries() DictionaryProvider {
+	if l.dicts == nil {
+		return nil
+	}
+	return l.dicts
+}
+
+// DeleteStream tombstones every partition of the stream. It first checks the
+// stream hold and the hold of every partition, and returns ErrLegalHold
+// without deleting anything if any is active. A hold placed while the deletes
+// run still stops the partitions it covers. DeleteStream may be retried after
+// an error; partitions deleted by an earlier attempt are skipped.
+func (l *Log) DeleteStream(ctx context.Context) (DeleteStreamResult, error) {
+	if err := l.checkOpen(); err != nil {
+		return DeleteStreamResult{}, err
+	}
+	if _, ok := l.store.WriterManager().(catalog.PartitionDeleter); !ok {
+		return DeleteStreamResult{}, ErrDeleteUnsupported
+	}
+	lister, ok := l.store.ReaderCatalog().(catalog.PartitionLister)
+	if !ok {
+		return DeleteStreamResult{}, ErrPartitionListUnsupported
+	}
+	partitions, err := l.unheldPartitions(ctx, lister)
+	if err != nil {
+		return DeleteStreamResult{}, err
+	}
+
+	var result DeleteStreamResult
+	for _, partition := range partitions {
+		_, deleted, err := l.deletePartition(ctx, partition)
+		if err != nil {
+			return result, fmt.Errorf("partitionlog: delete partition=%d: %w", partition, err)
+		}
+		if deleted {
+			result.Partitions++
+		}
+	}
+	return result, nil
+}
+
+// unheldPartitions lists the live partitions of the stream and returns
+// ErrLegalHold if a hold covers any of them.
+func (l *Log) unheldPartitions(ctx context.Context, lister catalog.PartitionLister) ([]uint32, error) {
+	holds, _ := l.store.RetentionManager().(catalog.LegalHoldManager)
+	var partitions []uint32
+	req := ListPartitionsRequest{IncludeHeads: true}
+	for {
+		page, err := lister.ListPartitions(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range page.Partitions {
+			if holds != nil {
+				current, err := holds.LoadLegalHolds(ctx, info.Partition)
+				if err != nil {
+					return nil, err
+				}
+				if current.Active() {
+					return nil, fmt.Errorf("%w: delete stream partition=%d", ErrLegalHold, info.Partition)
+				}
+			}
+			partitions = append(partitions, info.Partition)
+		}
+		if !page.HasMore {
+			return partitions, nil
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+}
+
+// SetLegalHold durably places a legal hold. While a hold covers a partition,
+// Writer.ApplyRetention returns ErrLegalHold instead of advancing OldestLSN,
+// DeletePartition and DeleteStream return ErrLegalHold, and lifecycle
+// reclaimers delete none of the partition's objects. Setting an identical
+// active hold returns the stored one.
+func (l *Log) SetLegalHold(ctx context.Context, req SetLegalHold) (LegalHold, error) {
+	manager, err := l.legalHoldManager()
+	if err != nil {
+		return LegalHold{}, err
+	}
+	hold := LegalHold{Active: true, Reason: req.Reason, UpdatedUnixMS: l.clock.Now().UTC().UnixMilli()}
+	if req.Stream {
+		return manager.SetStreamHold(ctx, hold)
+	}
+	return manager.SetPartitionHold(ctx, req.Partition, hold)
+}
+
+// ClearLegalHold releases a partition or stream hold. The other scope's hold,
+// if any, still applies. The cleared hold keeps its reason.
+func (l *Log) ClearLegalHold(ctx context.Context, req ClearLegalHold) (LegalHold, error) {
+	manager, err := l.legalHoldManager()
+	if err != nil {
+		return LegalHold{}, err
+	}
+	holds, err := manager.LoadLegalHolds(ctx, req.Partition)
+	if err != nil {
+		return LegalHold{}, err
+	}
+	current := holds.Partition
+	if req.Stream {
+		current = holds.Stream
+	}
+	hold := LegalHold{Reason: current.Reason, UpdatedUnixMS: l.clock.Now().UTC().UnixMilli()}
+	if req.Stream {
+		return manager.SetStreamHold(ctx, hold)
+	}
+	return manager.SetPartitionHold(ctx, req.Partition, hold)
+}
+
+// LoadLegalHolds returns the partition and stream holds that cover partition.
+func (l *Log) LoadLegalHolds(ctx context.Context, partition uint32) (LegalHolds, error) {
+	manager, err := l.legalHoldManager()
+	if err != nil {
+		return LegalHolds{}, err
+	}
+	return manager.LoadLegalHolds(ctx, partition)
+}
+
+func (l *Log) legalHoldManager() (catalog.LegalHoldManager, error) {
+	if err := l.checkOpen(); err != nil {
+		return nil, err
+	}
+	manager, ok := l.store.RetentionManager().(catalog.LegalHoldManager)
+	if !ok {
+		return nil, ErrLegalHoldUnsupported
+	}
+	return manager, nil
+}
+
 // RequestRetention stores a monotonic retention request. It does not change
 // reader visibility until the active partition writer applies it.
 func (l *Log) RequestRetention(ctx context.Context, request RetentionRequest) (RetentionRequestState, error) {
@@ -178,20 +809,47 @@ func (l *Log) RequestRetention(ctx context.Context, request RetentionRequest) (R
 		return RetentionRequestState{}, fmt.Errorf("partitionlog: nil retention catalog")
 	}
 	durable, err := manager.RequestRetention(ctx, request.Partition, catalog.RetentionRequest{
-		Version:       catalog.RetentionRequestVersion,
-		PolicyVersion: request.PolicyVersion,
-		BeforeLSN:     request.BeforeLSN,
-		CreatedUnixMS: l.clock.Now().UTC().UnixMilli(),
+		Version:            catalog.RetentionRequestVersion,
+		PolicyVersion:      request.PolicyVersion,
+		BeforeLSN:          request.BeforeLSN,
+		BeforeTimestampMS:  request.BeforeTimestampMS,
+		MaxRetainedBytes:   request.MaxRetainedBytes,
+		MinRetainedRecords: request.MinRetainedRecords,
+		CreatedUnixMS:      l.clock.Now().UTC().UnixMilli(),
 	})
 	if err != nil {
 		return RetentionRequestState{}, err
 	}
+	return retentionRequestState(request.Partition, durable), nil
+}
+
+// LoadRetentionRequest returns the latest stored retention request for
+// partition. Found is false when no request was ever stored.
+func (l *Log) LoadRetentionRequest(ctx context.Context, partition uint32) (state RetentionRequestState, found bool, err error) {
+	if err := l.checkOpen(); err != nil {
+		return RetentionRequestState{}, false, err
+	}
+	manager := l.store.RetentionManager()
+	if manager == nil {
+		return RetentionRequestState{}, false, fmt.Errorf("partitionlog: nil retention catalog")
+	}
+	durable, found, err := manager.LoadRetentionRequest(ctx, partition)
+	if err != nil || !found {
+		return RetentionRequestState{}, false, err
+	}
+	return retentionRequestState(partition, durable), true, nil
+}
+
+func retentionRequestState(partition uint32, durable catalog.RetentionRequest) RetentionRequestState {
 	return RetentionRequestState{
-		Partition:     request.Partition,
-		PolicyVersion: durable.PolicyVersion,
-		BeforeLSN:     durable.BeforeLSN,
-		CreatedUnixMS: durable.CreatedUnixMS,
-	}, nil
+		Partition:          partition,
+		PolicyVersion:      durable.PolicyVersion,
+		BeforeLSN:          durable.BeforeLSN,
+		BeforeTimestampMS:  durable.BeforeTimestampMS,
+		MaxRetainedBytes:   durable.MaxRetainedBytes,
+		MinRetainedRecords: durable.MinRetainedRecords,
+		CreatedUnixMS:      durable.CreatedUnixMS,
+	}
 }
 
 // OpenWriter opens one fenced writer for one partition.
@@ -202,6 +860,9 @@ func (l *Log) OpenWriter(ctx context.Context, opts WriterOptions) (*Writer, erro
 	if err := validateWriterOptions(opts); err != nil {
 		return nil, err
 	}
+	if opts.Pipeline.UploadLimiter == nil {
+		opts.Pipeline.UploadLimiter = l.uploadLimiter
+	}
 
 	catalogWriterManager := l.store.WriterManager()
 	if catalogWriterManager == nil {
@@ -228,14 +889,54 @@ func (l *Log) OpenWriter(ctx context.Context, opts WriterOptions) (*Writer, erro
 	if opts.Backpressure.MaxPendingBytes > 0 {
 		wopts.Queue.MaxInflightBytes = opts.Backpressure.MaxPendingBytes
 	}
+	if opts.MaxProducers != 0 {
+		wopts.MaxProducers = opts.MaxProducers
+	}
+	wopts.WAL = opts.WAL
 	if err := applyWriterPipelineOptions(&wopts, opts.Partition, opts.Pipeline); err != nil {
 		return nil, err
 	}
+	if l.keys != nil {
+		if wopts.SegmentOptions == (segwriter.Options{}) {
+			wopts.SegmentOptions = segwriter.DefaultOptions(opts.Partition)
+		}
+		wopts.SegmentOptions.KeyProvider = l.keys
+	}
+	if opts.Pipeline.DictionaryID != 0 {
+		if l.dicts == nil {
+			return nil, fmt.Errorf("%w: dictionary_id=%d: no dictionary provider", ErrDictionaryUnavailable, opts.Pipeline.DictionaryID)
+		}
+		dict, err := l.dicts.Dictionary(ctx, opts.Pipeline.DictionaryID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: dictionary_id=%d: %w", ErrDictionaryUnavailable, opts.Pipeline.DictionaryID, err)
+		}
+		wopts.SegmentOptions.Codec = segformat.CodecZstdDict
+		wopts.SegmentOptions.Dictionary = dict
+	}
 	if l.metrics != nil {
 		wopts.Observer = writerMetricsAdapter{metrics: l.metrics}
 	}
 
-	catalogSession, err := catalogWriterManager.OpenWriter(ctx, opts.Partition, opts.WriterID)
+	var catalogSession catalog.WriterSession
+	var err error
+	if opts.Signer != nil {
+		wopts.Signer = opts.Signer
+	}
+	if opts.IdleOnly {
+		idleManager, ok := catalogWriterManager.(catalog.IdleWriterManager)
+		if !ok {
+			return nil, ErrIdleWriterUnsupported
+		}
+		catalogSession, err = idleManager.OpenIdleWriter(ctx, opts.Partition, opts.WriterID, opts.Signer)
+	} else if opts.Signer != nil {
+		signingManager, ok := catalogWriterManager.(catalog.SigningWriterManager)
+		if !ok {
+			return nil, ErrSigningUnsupported
+		}
+		catalogSession, err = signingManager.OpenSigningWriter(ctx, opts.Partition, opts.WriterID, opts.Signer)
+	} else {
+		catalogSession, err = catalogWriterManager.OpenWriter(ctx, opts.Partition, opts.WriterID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -248,7 +949,7 @@ func (l *Log) OpenWriter(ctx context.Context, opts WriterOptions) (*Writer, erro
 	if err != nil {
 		return nil, err
 	}
-	return &Writer{inner: inner, partition: opts.Partition, metrics: l.metrics}, nil
+	return &Writer{inner: inner, partition: opts.Partition, metrics: l.metrics, store: l.store, keys: l.keys, dicts: l.dictionaries()}, nil
 }
 
 // Writer appends records to one fenced partition. Calls that mutate the writer
@@ -258,10 +959,38 @@ type Writer struct {
 	inner     *lowwriter.Writer
 	partition uint32
 	metrics   Metrics
+	store     Store
+	keys      KeyProvider
+	dicts     DictionaryProvider
 }
 
 // Append assigns the next LSN and appends record to this writer's partition.
-func (w *Writer) Append(ctx context.Context, record Record) (result AppendResult, err error) {
+func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error) {
+	return w.append(record, func(record lowwriter.Record) (lowwriter.AppendResult, error) {
+		return w.inner.Append(ctx, record)
+	})
+}
+
+// AppendAsync is Append that also returns a future for the record's commit.
+// Futures are resolved by the writer as segments are published, so waiting on
+// many of them costs no goroutine per record.
+func (w *Writer) AppendAsync(ctx context.Context, record Record) (*AppendFuture, error) {
+	var future *lowwriter.AppendFuture
+	_, err := w.append(record, func(record lowwriter.Record) (lowwriter.AppendResult, error) {
+		f, err := w.inner.AppendAsync(ctx, record)
+		if err != nil {
+			return lowwriter.AppendResult{}, err
+		}
+		future = f
+		return f.Result(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AppendFuture{inner: future}, nil
+}
+
+func (w *Writer) append(record Record, appendLow func(lowwriter.Record) (lowwriter.AppendResult, error)) (result AppendResult, err error) {
 	start := time.Now()
 	recordSize, _ := segformat.RecordSize(record.Headers, record.Value)
 	recordBytes := uint64(0)
@@ -279,18 +1008,128 @@ func (w *Writer) Append(ctx context.Context, record Record) (result AppendResult
 			Err:       err,
 		})
 	}()
-	innerResult, err := w.inner.Append(ctx, lowwriter.Record{
+	innerResult, err := appendLow(lowwriter.Record{
 		TimestampMS: record.TimestampMS,
 		Headers:     record.Headers,
 		Value:       record.Value,
+		ProducerID:  record.ProducerID,
+		Sequence:    record.Sequence,
 	})
 	if err != nil {
 		return AppendResult{}, err
 	}
-	result = AppendResult{LSN: innerResult.LSN}
+	result = AppendResult{LSN: innerResult.LSN, Duplicate: innerResult.Duplicate}
 	return result, nil
 }
 
+// AppendFuture resolves when the record of one AppendAsync is committed, or
+// fails with the writer's terminal error if the writer fails first.
+type AppendFuture struct {
+	inner *lowwriter.AppendFuture
+}
+
+// Result returns the LSN assigned by AppendAsync. It is known before the
+// record is committed.
+func (f *AppendFuture) Result() AppendResult {
+	result := f.inner.Result()
+	return AppendResult{LSN: result.LSN, Duplicate: result.Duplicate}
+}
+
+// Done returns a channel that is closed once the future resolves. Select on
+// it to wait for many futures from one goroutine, then call Wait.
+func (f *AppendFuture) Done() <-chan struct{} {
+	return f.inner.Done()
+}
+
+// Wait blocks until the future resolves and returns the first committed
+// snapshot that covers the record, or the writer's terminal error.
+func (f *AppendFuture) Wait(ctx context.Context) (Snapshot, error) {
+	snapshot, err := f.inner.Wait(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return snapshotFromWriter(snapshot), nil
+}
+
+// AppendIf appends record only if it is assigned expectedLSN, the writer's
+// OptimisticNextLSN. Otherwise it fails with LSNConflictError and appends
+// nothing.
+func (w *Writer) AppendIf(ctx context.Context, expectedLSN uint64, record Record) (AppendResult, error) {
+	result, err := w.AppendBatchAt(ctx, expectedLSN, []Record{record})
+	if err != nil {
+		return AppendResult{}, err
+	}
+	return AppendResult{LSN: result.FirstLSN}, nil
+}
+
+// AppendBatch appends records at a contiguous LSN range. The batch lands in
+// one segment, so readers see all of it or none of it; a batch larger than
+// the batch policy gets a segment of its own. It is validated and observed
+// once, as one MetricWriterAppend event.
+//
+// Producer sequences must each be exactly the next one; a batch is never
+// acknowledged as a Duplicate.
+func (w *Writer) AppendBatch(ctx context.Context, records []Record) (BatchResult, error) {
+	return w.appendBatch(records, func(records []lowwriter.Record) (lowwriter.BatchResult, error) {
+		return w.inner.AppendBatch(ctx, records)
+	})
+}
+
+// AppendBatchAt is AppendBatch only if the first record is assigned
+// expectedNextLSN, the writer's OptimisticNextLSN. Otherwise it fails with
+// LSNConflictError and appends nothing. Use WaitCommitted with LastLSN to
+// wait for the batch's commit.
+func (w *Writer) AppendBatchAt(ctx context.Context, expectedNextLSN uint64, records []Record) (BatchResult, error) {
+	return w.appendBatch(records, func(records []lowwriter.Record) (lowwriter.BatchResult, error) {
+		return w.inner.AppendBatchAt(ctx, expectedNextLSN, records)
+	})
+}
+
+func (w *Writer) appendBatch(records []Record, appendLow func([]lowwriter.Record) (lowwriter.BatchResult, error)) (result BatchResult, err error) {
+	start := time.Now()
+	var recordBytes uint64
+	lowRecords := make([]lowwriter.Record, len(records))
+	for i, record := range records {
+		lowRecords[i] = lowwriter.Record{
+			TimestampMS: record.TimestampMS,
+			Headers:     record.Headers,
+			Value:       record.Value,
+			ProducerID:  record.ProducerID,
+			Sequence:    record.Sequence,
+		}
+	}
+	defer func() {
+		w.observe(Metric{
+			Name:      MetricWriterAppend,
+			Partition: w.partition,
+			LSN:       result.FirstLSN,
+			Records:   len(records),
+			Bytes:     recordBytes,
+			Duration:  time.Since(start),
+			Err:       err,
+		})
+	}()
+	innerResult, err := appendLow(lowRecords)
+	if err != nil {
+		return BatchResult{}, err
+	}
+	recordBytes = innerResult.RawBytes
+	return BatchResult{FirstLSN: innerResult.FirstLSN, LastLSN: innerResult.LastLSN}, nil
+}
+
+// WaitCommitted blocks until the record at lsn is committed and returns the
+// first committed snapshot that covers it. It fails with the writer's error
+// if the writer becomes terminal first, and with ErrNotAppended if lsn has
+// not been assigned. Records are committed once the batch policy, Cut, Flush,
+// or Close publishes their segment.
+func (w *Writer) WaitCommitted(ctx context.Context, lsn uint64) (Snapshot, error) {
+	snapshot, err := w.inner.WaitCommitted(ctx, lsn)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return snapshotFromWriter(snapshot), nil
+}
+
 // Cut rotates the current active segment if it contains records.
 func (w *Writer) Cut(ctx context.Context) (err error) {
 	start := time.Now()
@@ -313,7 +1152,8 @@ func (w *Writer) Flush(ctx context.Context) (result Snapshot, err error) {
 	return snapshotFromWriter(snapshot), nil
 }
 
-// Close flushes and closes the writer.
+// Close flushes and closes the writer, then releases the partition fence so
+// an IdleOnly writer may take it.
 func (w *Writer) Close(ctx context.Context) (result Snapshot, err error) {
 	start := time.Now()
 	defer func() {
@@ -326,6 +1166,25 @@ func (w *Writer) Close(ctx context.Context) (result Snapshot, err error) {
 	return snapshotFromWriter(snapshot), nil
 }
 
+// Seal flushes and closes the writer, then seals its partition through the
+// writer's fence, so every record accepted before Seal is in the sealed
+// timeline. A failed seal leaves the writer closed; retry with
+// Log.SealPartition.
+func (w *Writer) Seal(ctx context.Context) (result Snapshot, err error) {
+	start := time.Now()
+	defer func() {
+		w.observeWriterSnapshotOperation(MetricWriterSeal, result, time.Since(start), err)
+	}()
+	snapshot, err := w.inner.Seal(ctx)
+	if errors.Is(err, lowwriter.ErrSealUnsupported) {
+		return Snapshot{}, fmt.Errorf("%w: %w", ErrSealUnsupported, err)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return snapshotFromWriter(snapshot), nil
+}
+
 func (w *Writer) Abort(ctx context.Context) (err error) {
 	start := time.Now()
 	defer func() {
@@ -362,13 +1221,122 @@ func (w *Writer) ApplyRetention(ctx context.Context) (result RetentionResult, er
 		return RetentionResult{}, err
 	}
 	return RetentionResult{
-		Snapshot:      snapshotFromWriter(inner.Snapshot),
-		PolicyVersion: inner.PolicyVersion,
-		RequestedLSN:  inner.RequestedLSN,
-		Applied:       inner.Applied,
+		Snapshot:             snapshotFromWriter(inner.Snapshot),
+		PolicyVersion:        inner.PolicyVersion,
+		RequestedLSN:         inner.RequestedLSN,
+		RequestedTimestampMS: inner.RequestedTimestampMS,
+		Applied:              inner.Applied,
 	}, nil
 }
 
+// Redact replaces the values of the committed records at req.LSNs with a
+// redaction tombstone that keeps the record's LSN and timestamp and carries
+// only the RedactionHeaderKey header. Each affected segment is rewritten once
+// and swapped into the catalog through this writer's fence, so readers see
+// tombstones rather than LSN gaps. Replaced segment objects are reclaimed by
+// lifecycle after its delete delay.
+func (w *Writer) Redact(ctx context.Context, req RedactRequest) (result RedactResult, err error) {
+	start := time.Now()
+	defer func() {
+		w.observeWriterSnapshotOperation(MetricWriterRedact, result.Snapshot, time.Since(start), err)
+	}()
+	if len(req.LSNs) == 0 {
+		return RedactResult{}, fmt.Errorf("%w: no lsns", ErrInvalidRedaction)
+	}
+	cat := w.store.ReaderCatalog()
+	if cat == nil {
+		return RedactResult{}, fmt.Errorf("partitionlog: nil reader catalog")
+	}
+	segmentStore := w.store.SegmentStore()
+	if segmentStore == nil {
+		return RedactResult{}, fmt.Errorf("partitionlog: nil segment store")
+	}
+
+	lsns := slices.Clone(req.LSNs)
+	slices.Sort(lsns)
+	lsns = slices.Compact(lsns)
+	committed := w.inner.State().Snapshot
+	result.Snapshot = snapshotFromWriter(committed)
+	for len(lsns) > 0 {
+		if lsns[0] < committed.Head.OldestLSN || lsns[0] >= committed.Head.NextLSN {
+			return result, fmt.Errorf("%w: lsn=%d outside committed range [%d,%d)", ErrInvalidRedaction, lsns[0], committed.Head.OldestLSN, committed.Head.NextLSN)
+		}
+		segment, ok, err := cat.FindSegment(ctx, w.partition, lsns[0])
+		if err != nil {
+			return result, err
+		}
+		if !ok || lsns[0] < segment.BaseLSN || lsns[0] > segment.LastLSN {
+			return result, fmt.Errorf("%w: no segment holds lsn=%d", ErrInvalidRedaction, lsns[0])
+		}
+		n := 1
+		for n < len(lsns) && lsns[n] <= segment.LastLSN {
+			n++
+		}
+		records, err := w.redactedSegmentRecords(ctx, segmentStore, segment, lsns[:n], req.Reason)
+		if err != nil {
+			return result, err
+		}
+		snapshot, err := w.inner.RewriteSegment(ctx, lowwriter.RewriteRequest{
+			Segment: segment,
+			Records: records,
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Snapshot = snapshotFromWriter(snapshot)
+		result.Segments++
+		result.Records += n
+		lsns = lsns[n:]
+	}
+	return result, nil
+}
+
+// redactedSegmentRecords reads every record of segment and replaces the ones
+// at lsns, which must be sorted and inside the segment, with tombstones.
+func (w *Writer) redactedSegmentRecords(ctx context.Context, store reader.SegmentStore, segment SegmentRef, lsns []uint64, reason string) ([]lowwriter.Record, error) {
+	opts := segreader.DefaultOptions()
+	opts.KeyProvider = w.keys
+	opts.Dictionaries = w.dicts
+	sr, err := segreader.Open(ctx, store, segment, opts)
+	if err != nil {
+		return nil, err
+	}
+	scanner, err := sr.Scan(ctx, segment.BaseLSN)
+	if err != nil {
+		return nil, err
+	}
+	defer scanner.Close()
+
+	records := make([]lowwriter.Record, 0, segment.RecordCount)
+	for {
+		record, ok, err := scanner.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if len(lsns) > 0 && record.LSN == lsns[0] {
+			digest := proof.LeafDigest(record.Headers, record.Value)
+			tombstone := segformat.RedactedRecord(record.TimestampMS, reason, digest[:])
+			records = append(records, lowwriter.Record{
+				TimestampMS: tombstone.TimestampMS,
+				Headers:     tombstone.Headers,
+				Value:       tombstone.Value,
+			})
+			lsns = lsns[1:]
+			continue
+		}
+		record = record.Clone()
+		records = append(records, lowwriter.Record{
+			TimestampMS: record.TimestampMS,
+			Headers:     record.Headers,
+			Value:       record.Value,
+		})
+	}
+	return records, nil
+}
+
 func (w *Writer) observeWriterOperation(name MetricName, duration time.Duration, err error) {
 	w.observeWriterSnapshotOperation(name, Snapshot{}, duration, err)
 }
@@ -397,7 +1365,51 @@ func (w *Writer) observe(metric Metric) {
 	w.metrics.Observe(metric)
 }
 
-func newReader(store Store, opts ReaderOptions, metrics Metrics) (*Reader, error) {
+// readerRuntime holds reader caches and limits owned by a Namespace. Zero
+// fields make newReader build private ones from ReaderOptions.
+type readerRuntime struct {
+	rangeCache     blobcache.Cache
+	segmentCache   *reader.SegmentReaderCache
+	refreshLimiter *reader.RefreshLimiter
+}
+
+func newReaderRuntime(opts ReaderOptions) (readerRuntime, error) {
+	var runtime readerRuntime
+	if opts.RangeCacheBytes > 0 {
+		runtime.rangeCache = sharedRangeCache{lru: blobcache.NewLRU(opts.RangeCacheBytes)}
+	}
+	if opts.OpenSegmentReaders > 0 {
+		segmentCache, err := reader.NewSegmentReaderCache(opts.OpenSegmentReaders)
+		if err != nil {
+			return readerRuntime{}, err
+		}
+		runtime.segmentCache = segmentCache
+	}
+	if opts.Refresh.MaxConcurrentRefreshes > 0 {
+		limiter, err := reader.NewRefreshLimiter(opts.Refresh.MaxConcurrentRefreshes)
+		if err != nil {
+			return readerRuntime{}, err
+		}
+		runtime.refreshLimiter = limiter
+	}
+	return runtime, nil
+}
+
+// sharedRangeCache hides LRU.Clear so closing one stream's Reader does not
+// drop ranges cached for its siblings.
+type sharedRangeCache struct {
+	lru *blobcache.LRU
+}
+
+func (c sharedRangeCache) Get(key blobcache.Key) ([]byte, bool) {
+	return c.lru.Get(key)
+}
+
+func (c sharedRangeCache) Set(key blobcache.Key, value []byte) {
+	c.lru.Set(key, value)
+}
+
+func newReader(store Store, opts ReaderOptions, metrics Metrics, runtime readerRuntime, keys KeyProvider, dicts *segreader.DictionaryCache) (*Reader, error) {
 	cat := store.ReaderCatalog()
 	if cat == nil {
 		return nil, fmt.Errorf("partitionlog: nil reader catalog")
@@ -411,18 +1423,38 @@ func newReader(store Store, opts ReaderOptions, metrics Metrics) (*Reader, error
 		MaxRecordsPerBatch:      opts.MaxRecordsPerBatch,
 		MaxCachedPartitionHeads: opts.MaxCachedPartitionHeads,
 		Refresh:                 opts.Refresh,
+		RefreshLimiter:          runtime.refreshLimiter,
+		Verifier:                opts.Verifier,
+	}
+	if keys != nil || dicts != nil {
+		ropts.SegmentOptions = segreader.DefaultOptions()
+		ropts.SegmentOptions.KeyProvider = keys
+		if dicts != nil {
+			ropts.SegmentOptions.Dictionaries = dicts
+		}
 	}
 	if metrics != nil {
 		ropts.Observer = readerMetricsAdapter{metrics: metrics}
 	}
-	if opts.RangeCacheBytes > 0 {
+	switch {
+	case runtime.rangeCache != nil:
+		cachedStore, err := blobcache.NewStore(segmentStore, runtime.rangeCache)
+		if err != nil {
+			return nil, err
+		}
+		segmentStore = cachedStore
+	case opts.RangeCacheBytes > 0:
 		cachedStore, err := blobcache.NewStore(segmentStore, blobcache.NewLRU(opts.RangeCacheBytes))
 		if err != nil {
 			return nil, err
 		}
 		segmentStore = cachedStore
 	}
-	if opts.OpenSegmentReaders > 0 {
+	switch {
+	case runtime.segmentCache != nil:
+		ropts.SegmentCache = runtime.segmentCache
+		ropts.SharedSegmentCache = true
+	case opts.OpenSegmentReaders > 0:
 		segmentCache, err := reader.NewSegmentReaderCache(opts.OpenSegmentReaders)
 		if err != nil {
 			return nil, err
@@ -469,6 +1501,17 @@ func applyWriterPipelineOptions(wopts *lowwriter.Options, partition uint32, opts
 	if opts.UploadLimiter != nil {
 		segment.UploadLimiter = opts.UploadLimiter
 	}
+	if codec, ok := opts.Compression.codec(); ok {
+		segment.Codec = codec
+	}
+	segment.ZstdLevel = opts.ZstdLevel
+	if opts.DictionaryID != 0 {
+		// OpenWriter resolves the dictionary and switches to zstd-dict.
+		segment.Codec = segformat.CodecZstd
+	}
+	if opts.CompactRecords {
+		segment.RecordFormat = segformat.RecordFormatV2
+	}
 	wopts.SegmentOptions = segment
 	return nil
 }
@@ -480,7 +1523,11 @@ func hasWriterPipelineOptions(opts WriterPipelineOptions) bool {
 		opts.BlockBuffers != 0 ||
 		opts.UploadParallelism != 0 ||
 		opts.UploadQueueSize != 0 ||
-		opts.UploadLimiter != nil
+		opts.UploadLimiter != nil ||
+		opts.Compression != CompressionDefault ||
+		opts.ZstdLevel != 0 ||
+		opts.DictionaryID != 0 ||
+		opts.CompactRecords
 }
 
 func validateWriterPipelineOptions(opts WriterPipelineOptions) error {
@@ -497,6 +1544,14 @@ func validateWriterPipelineOptions(opts WriterPipelineOptions) error {
 		return fmt.Errorf("partitionlog: negative upload parallelism %d", opts.UploadParallelism)
 	case opts.UploadQueueSize < 0:
 		return fmt.Errorf("partitionlog: negative upload queue size %d", opts.UploadQueueSize)
+	case opts.Compression > CompressionS2:
+		return fmt.Errorf("partitionlog: unknown compression %s", opts.Compression)
+	case opts.ZstdLevel < 0 || opts.ZstdLevel > segblock.MaxZstdLevel:
+		return fmt.Errorf("partitionlog: zstd level %d outside [0,%d]", opts.ZstdLevel, segblock.MaxZstdLevel)
+	case opts.ZstdLevel != 0 && opts.Compression != CompressionDefault && opts.Compression != CompressionZstd:
+		return fmt.Errorf("partitionlog: zstd level %d with %s compression", opts.ZstdLevel, opts.Compression)
+	case opts.DictionaryID != 0 && opts.Compression != CompressionDefault && opts.Compression != CompressionZstd:
+		return fmt.Errorf("partitionlog: dictionary %d with %s compression", opts.DictionaryID, opts.Compression)
 	default:
 		return nil
 	}
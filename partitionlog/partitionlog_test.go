@@ -1,6 +1,7 @@
 package partitionlog
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,7 +12,9 @@ import (
 	segmentsink "github.com/ankur-anand/unijord/partitionlog/blob/sink"
 	"github.com/ankur-anand/unijord/partitionlog/blob/sink/multipart"
 	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/writer"
+	"github.com/ankur-anand/unijord/partitionlog/writer/filewal"
 )
 
 func TestLogWriteAndReadWithMemoryStore(t *testing.T) {
@@ -129,6 +132,101 @@ func TestLogRetentionRequestIsAppliedByPartitionWriter(t *testing.T) {
 	}
 }
 
+func TestLogTimestampRetentionRetiresWholeSegments(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{
+		Partition: 1,
+		WriterID:  [16]byte{1},
+		Batch:     BatchPolicy{MaxRecords: 2},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		if _, err := w.Append(ctx, Record{TimestampMS: int64(100 + 10*i), Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	request, err := log.RequestRetention(ctx, RetentionRequest{
+		Partition:         1,
+		PolicyVersion:     1,
+		BeforeTimestampMS: 125,
+	})
+	if err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	if request.BeforeTimestampMS != 125 || request.BeforeLSN != 0 {
+		t.Fatalf("retention request = %+v", request)
+	}
+	result, err := w.ApplyRetention(ctx)
+	if err != nil {
+		t.Fatalf("ApplyRetention() error = %v", err)
+	}
+	// Segments hold records {100,110}, {120,130} and {140,150}; 125 falls in
+	// the second segment, so only the first is retired.
+	if !result.Applied || result.RequestedTimestampMS != 125 || result.RequestedLSN != 2 || result.Snapshot.Head.OldestLSN != 2 {
+		t.Fatalf("retention result = %+v", result)
+	}
+	if _, err := log.RequestRetention(ctx, RetentionRequest{
+		Partition:         1,
+		PolicyVersion:     2,
+		BeforeTimestampMS: 124,
+	}); !errors.Is(err, ErrRetentionRegression) {
+		t.Fatalf("RequestRetention(older timestamp) error = %v, want %v", err, ErrRetentionRegression)
+	}
+}
+
+func TestLogSizeRetentionCapsRetainedBytes(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{
+		Partition: 1,
+		WriterID:  [16]byte{1},
+		Batch:     BatchPolicy{MaxRecords: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := w.Append(ctx, Record{TimestampMS: int64(i), Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	flushed, err := w.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	total := flushed.Head.RetainedBytes
+	if total == 0 || flushed.Head.LastSegment.SizeBytes*4 != total {
+		t.Fatalf("retained bytes = %d, last segment = %d", total, flushed.Head.LastSegment.SizeBytes)
+	}
+
+	if _, err := log.RequestRetention(ctx, RetentionRequest{
+		Partition:        1,
+		PolicyVersion:    1,
+		MaxRetainedBytes: total / 2,
+	}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	result, err := w.ApplyRetention(ctx)
+	if err != nil {
+		t.Fatalf("ApplyRetention() error = %v", err)
+	}
+	if !result.Applied || result.Snapshot.Head.OldestLSN != 2 || result.Snapshot.Head.RetainedBytes != total/2 {
+		t.Fatalf("retention result = %+v", result)
+	}
+}
 func TestLogInitializePartitionAtCheckpoint(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore(t)
@@ -401,6 +499,295 @@ func TestWatchTailerWaitsForPublishedRecords(t *testing.T) {
 	}
 }
 
+func TestLogSealPartitionEndsTimeline(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i, value := range []string{"a", "b"} {
+		if _, err := w.Append(ctx, Record{TimestampMS: int64(10 + i), Value: []byte(value)}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	watch, err := log.Reader().Watch(ctx, WatchOptions{Partitions: []uint32{1}})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	defer func() { _ = watch.Close() }()
+	tailer, err := watch.Tail(TailOptions{Partition: 1, StartLSN: 2, Limit: 10})
+	if err != nil {
+		t.Fatalf("Tail() error = %v", err)
+	}
+	defer func() { _ = tailer.Close() }()
+	errCh := make(chan error, 1)
+	nextCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	go func() {
+		_, err := tailer.Next(nextCtx)
+		errCh <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	sealed, err := log.SealPartition(ctx, SealPartition{Partition: 1, WriterID: [16]byte{2}})
+	if err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	if !sealed.Sealed || !sealed.Head.Sealed || sealed.Head.NextLSN != 2 {
+		t.Fatalf("SealPartition() = %+v", sealed)
+	}
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, ErrEndOfTimeline) {
+			t.Fatalf("Tailer.Next() error = %v, want %v", err, ErrEndOfTimeline)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for tailer end of timeline")
+	}
+
+	cursor, err := log.Reader().Partition(1).Cursor(CursorOptions{StartLSN: 0, Limit: 10})
+	if err != nil {
+		t.Fatalf("Cursor() error = %v", err)
+	}
+	got, err := cursor.Next(ctx)
+	if err != nil || len(got.Records) != 2 {
+		t.Fatalf("Next(0) = %d records, %v; want 2 records", len(got.Records), err)
+	}
+	if _, err := cursor.Next(ctx); !errors.Is(err, ErrEndOfTimeline) {
+		t.Fatalf("Next(sealed tail) error = %v, want %v", err, ErrEndOfTimeline)
+	}
+
+	if _, err := w.Append(ctx, Record{TimestampMS: 20, Value: []byte("c")}); err != nil {
+		t.Fatalf("Append(after seal) error = %v", err)
+	}
+	if _, err := w.Flush(ctx); !errors.Is(err, writer.ErrStaleWriter) {
+		t.Fatalf("Flush(after seal) error = %v, want %v", err, writer.ErrStaleWriter)
+	}
+	if _, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{3}}); !errors.Is(err, ErrPartitionSealed) {
+		t.Fatalf("OpenWriter(sealed) error = %v, want %v", err, ErrPartitionSealed)
+	}
+	again, err := log.SealPartition(ctx, SealPartition{Partition: 1, WriterID: [16]byte{4}})
+	if err != nil {
+		t.Fatalf("SealPartition(again) error = %v", err)
+	}
+	if again.Sealed || again.Head != sealed.Head {
+		t.Fatalf("SealPartition(again) = %+v, want unchanged %+v", again, sealed.Head)
+	}
+}
+
+func TestWriterSealPublishesAcceptedRecordsFirst(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i, value := range []string{"a", "b", "c"} {
+		if _, err := w.Append(ctx, Record{TimestampMS: int64(10 + i), Value: []byte(value)}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+
+	sealed, err := w.Seal(ctx)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if !sealed.Head.Sealed || sealed.Head.NextLSN != 3 {
+		t.Fatalf("Seal() = %+v, want sealed head with 3 records", sealed)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 20, Value: []byte("d")}); !errors.Is(err, writer.ErrClosed) {
+		t.Fatalf("Append(after seal) error = %v, want %v", err, writer.ErrClosed)
+	}
+	head, err := log.LoadPartition(ctx, 1)
+	if err != nil || !head.Sealed || head.NextLSN != 3 {
+		t.Fatalf("LoadPartition() = %+v, %v; want sealed head with 3 records", head, err)
+	}
+	if _, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{2}}); !errors.Is(err, ErrPartitionSealed) {
+		t.Fatalf("OpenWriter(sealed) error = %v, want %v", err, ErrPartitionSealed)
+	}
+}
+
+func TestLogDeleteStreamTombstonesEveryPartition(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 10, Value: []byte("a")}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	for _, partition := range []uint32{2, 3} {
+		if _, err := log.InitializePartition(ctx, InitializePartition{Partition: partition}); err != nil {
+			t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+		}
+	}
+
+	one, err := log.DeletePartition(ctx, 3)
+	if err != nil || !one.Deleted {
+		t.Fatalf("DeletePartition(3) = %+v, %v", one, err)
+	}
+	stream, err := log.DeleteStream(ctx)
+	if err != nil {
+		t.Fatalf("DeleteStream() error = %v", err)
+	}
+	if stream.Partitions != 2 {
+		t.Fatalf("DeleteStream() = %+v, want 2 newly deleted partitions", stream)
+	}
+	for _, partition := range []uint32{1, 2, 3} {
+		if _, err := log.Reader().Partition(partition).Head(ctx); !errors.Is(err, ErrPartitionDeleted) {
+			t.Fatalf("Head(%d) error = %v, want %v", partition, err, ErrPartitionDeleted)
+		}
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 20, Value: []byte("b")}); err != nil {
+		t.Fatalf("Append(after delete) error = %v", err)
+	}
+	if _, err := w.Flush(ctx); !errors.Is(err, writer.ErrStaleWriter) {
+		t.Fatalf("Flush(after delete) error = %v, want %v", err, writer.ErrStaleWriter)
+	}
+	if _, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{2}}); !errors.Is(err, ErrPartitionDeleted) {
+		t.Fatalf("OpenWriter(deleted) error = %v, want %v", err, ErrPartitionDeleted)
+	}
+	again, err := log.DeletePartition(ctx, 1)
+	if err != nil || again.Deleted {
+		t.Fatalf("DeletePartition(again) = %+v, %v; want not deleted", again, err)
+	}
+}
+
+func TestLogLegalHoldBlocksRetentionAndDelete(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{
+		Partition: 1,
+		WriterID:  [16]byte{1},
+		Batch:     BatchPolicy{MaxRecords: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := w.Append(ctx, Record{TimestampMS: int64(i), Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	hold, err := log.SetLegalHold(ctx, SetLegalHold{Partition: 1, Reason: "case-7"})
+	if err != nil || !hold.Active || hold.Reason != "case-7" || hold.UpdatedUnixMS <= 0 {
+		t.Fatalf("SetLegalHold() = %+v, %v", hold, err)
+	}
+	if _, err := log.RequestRetention(ctx, RetentionRequest{Partition: 1, PolicyVersion: 1, BeforeLSN: 2}); err != nil {
+		t.Fatalf("RequestRetention() error = %v", err)
+	}
+	if _, err := w.ApplyRetention(ctx); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("ApplyRetention(held) error = %v, want %v", err, ErrLegalHold)
+	}
+	if _, err := log.DeletePartition(ctx, 1); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeletePartition(held) error = %v, want %v", err, ErrLegalHold)
+	}
+	if _, err := log.SetLegalHold(ctx, SetLegalHold{Stream: true, Reason: "litigation"}); err != nil {
+		t.Fatalf("SetLegalHold(stream) error = %v", err)
+	}
+	if _, err := log.ClearLegalHold(ctx, ClearLegalHold{Partition: 1}); err != nil {
+		t.Fatalf("ClearLegalHold(partition) error = %v", err)
+	}
+	if _, err := log.DeleteStream(ctx); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeleteStream(held) error = %v, want %v", err, ErrLegalHold)
+	}
+
+	// The writer stays usable while a hold refuses retention.
+	if _, err := w.Append(ctx, Record{TimestampMS: 3, Value: []byte{3}}); err != nil {
+		t.Fatalf("Append(held) error = %v", err)
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush(held) error = %v", err)
+	}
+
+	cleared, err := log.ClearLegalHold(ctx, ClearLegalHold{Stream: true})
+	if err != nil || cleared.Active || cleared.Reason != "litigation" {
+		t.Fatalf("ClearLegalHold(stream) = %+v, %v", cleared, err)
+	}
+	holds, err := log.LoadLegalHolds(ctx, 1)
+	if err != nil || holds.Active() || holds.Partition.Reason != "case-7" {
+		t.Fatalf("LoadLegalHolds() = %+v, %v", holds, err)
+	}
+	result, err := w.ApplyRetention(ctx)
+	if err != nil || !result.Applied || result.Snapshot.Head.OldestLSN != 2 {
+		t.Fatalf("ApplyRetention(released) = %+v, %v", result, err)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if deleted, err := log.DeletePartition(ctx, 1); err != nil || !deleted.Deleted {
+		t.Fatalf("DeletePartition(released) = %+v, %v", deleted, err)
+	}
+}
+
+func TestLogDeleteStreamChecksEveryHoldBeforeDeleting(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	for _, partition := range []uint32{1, 2, 3} {
+		if _, err := log.InitializePartition(ctx, InitializePartition{Partition: partition}); err != nil {
+			t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+		}
+	}
+	if _, err := log.SetLegalHold(ctx, SetLegalHold{Partition: 3, Reason: "case-7"}); err != nil {
+		t.Fatalf("SetLegalHold() error = %v", err)
+	}
+
+	result, err := log.DeleteStream(ctx)
+	if !errors.Is(err, ErrLegalHold) || result.Partitions != 0 {
+		t.Fatalf("DeleteStream(held) = %+v, %v; want %v and nothing deleted", result, err, ErrLegalHold)
+	}
+	for _, partition := range []uint32{1, 2, 3} {
+		if _, err := log.Reader().Partition(partition).Head(ctx); err != nil {
+			t.Fatalf("Head(%d) error = %v, want live partition", partition, err)
+		}
+	}
+}
+
+func TestLogShredPartitionRequiresKeyDestroyer(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := log.InitializePartition(ctx, InitializePartition{Partition: 1}); err != nil {
+		t.Fatalf("InitializePartition() error = %v", err)
+	}
+	if _, err := log.ShredPartition(ctx, 1); !errors.Is(err, ErrShredUnsupported) {
+		t.Fatalf("ShredPartition(no keys) error = %v, want %v", err, ErrShredUnsupported)
+	}
+	if _, err := log.LoadPartition(ctx, 1); err != nil {
+		t.Fatalf("LoadPartition() error = %v; refused shred must not tombstone", err)
+	}
+}
+
 func TestLogWriterBatchMaxDelayMakesLowVolumeRecordVisible(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore(t)
@@ -455,6 +842,20 @@ func TestLogOpenWriterRejectsInvalidPublicWriterOptions(t *testing.T) {
 	}); err == nil {
 		t.Fatal("OpenWriter(negative upload parallelism) error = nil, want error")
 	}
+	for _, pipeline := range []WriterPipelineOptions{
+		{Compression: CompressionS2 + 1},
+		{ZstdLevel: 23},
+		{Compression: CompressionLZ4, ZstdLevel: 3},
+		{Compression: CompressionS2, DictionaryID: 40000},
+	} {
+		if _, err := log.OpenWriter(context.Background(), WriterOptions{
+			Partition: 1,
+			WriterID:  [16]byte{1},
+			Pipeline:  pipeline,
+		}); err == nil {
+			t.Fatalf("OpenWriter(%+v) error = nil, want error", pipeline)
+		}
+	}
 }
 
 func TestLogWriterPipelineOptionsAreAccepted(t *testing.T) {
@@ -485,6 +886,330 @@ func TestLogWriterPipelineOptionsAreAccepted(t *testing.T) {
 	}
 }
 
+func TestLogWriterCompressionSelectsSegmentCodec(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+	log, err := Open(Options{Store: store})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	for i, tc := range []struct {
+		pipeline WriterPipelineOptions
+		want     segformat.Codec
+	}{
+		{pipeline: WriterPipelineOptions{}, want: segformat.CodecZstd},
+		{pipeline: WriterPipelineOptions{ZstdLevel: 19}, want: segformat.CodecZstd},
+		{pipeline: WriterPipelineOptions{Compression: CompressionNone}, want: segformat.CodecNone},
+		{pipeline: WriterPipelineOptions{Compression: CompressionLZ4}, want: segformat.CodecLZ4},
+		{pipeline: WriterPipelineOptions{Compression: CompressionS2}, want: segformat.CodecS2},
+	} {
+		partition := uint32(i + 1)
+		w, err := log.OpenWriter(ctx, WriterOptions{Partition: partition, WriterID: [16]byte{byte(partition)}, Pipeline: tc.pipeline})
+		if err != nil {
+			t.Fatalf("OpenWriter(%+v) error = %v", tc.pipeline, err)
+		}
+		for lsn := 0; lsn < 3; lsn++ {
+			if _, err := w.Append(ctx, Record{TimestampMS: int64(lsn), Value: bytes.Repeat([]byte{'a' + byte(lsn)}, 64)}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		snapshot, err := w.Close(ctx)
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if got := snapshot.Head.LastSegment.Codec; got != tc.want {
+			t.Fatalf("%+v segment codec = %s, want %s", tc.pipeline, got, tc.want)
+		}
+		got, err := log.Reader().Partition(partition).Read(ctx, ReadRequest{Limit: 10})
+		if err != nil || len(got.Records) != 3 {
+			t.Fatalf("Read(%s) = %+v, %v; want 3 records", tc.want, got, err)
+		}
+		if !bytes.Equal(got.Records[2].Value, bytes.Repeat([]byte{'c'}, 64)) {
+			t.Fatalf("Read(%s) record[2] = %q", tc.want, got.Records[2].Value)
+		}
+	}
+}
+
+func TestLogWriterCompactRecordsShrinkSmallRecords(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	sizes := make(map[bool]uint64)
+	for i, compact := range []bool{false, true} {
+		partition := uint32(i + 1)
+		pipeline := WriterPipelineOptions{Compression: CompressionNone, CompactRecords: compact}
+		w, err := log.OpenWriter(ctx, WriterOptions{Partition: partition, WriterID: [16]byte{byte(partition)}, Pipeline: pipeline})
+		if err != nil {
+			t.Fatalf("OpenWriter(compact=%t) error = %v", compact, err)
+		}
+		for lsn := 0; lsn < 50; lsn++ {
+			if _, err := w.Append(ctx, Record{TimestampMS: 1_776_263_000_000 + int64(lsn), Value: []byte{byte(lsn)}}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+		}
+		snapshot, err := w.Close(ctx)
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		sizes[compact] = snapshot.Head.LastSegment.SizeBytes
+		got, err := log.Reader().Partition(partition).Read(ctx, ReadRequest{Limit: 100})
+		if err != nil || len(got.Records) != 50 {
+			t.Fatalf("Read(compact=%t) = %d records, %v; want 50", compact, len(got.Records), err)
+		}
+		if last := got.Records[49]; last.TimestampMS != 1_776_263_000_049 || !bytes.Equal(last.Value, []byte{49}) {
+			t.Fatalf("Read(compact=%t) record[49] = %+v", compact, last)
+		}
+	}
+	if sizes[true] >= sizes[false] {
+		t.Fatalf("compact segment size = %d, default size = %d; want compact smaller", sizes[true], sizes[false])
+	}
+}
+
+func TestLogReplacementWriterAcknowledgesProducerRetry(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	producer := [16]byte{0xbe, 0xef}
+
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	for seq := uint64(1); seq <= 3; seq++ {
+		if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte{byte(seq)}, ProducerID: producer, Sequence: seq}); err != nil {
+			t.Fatalf("Append(seq=%d) error = %v", seq, err)
+		}
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// The producer did not see the ack for sequence 3 and retries it against
+	// the writer that fenced the first one.
+	replacement, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{2}})
+	if err != nil {
+		t.Fatalf("OpenWriter(replacement) error = %v", err)
+	}
+	result, err := replacement.Append(ctx, Record{TimestampMS: 1, Value: []byte{3}, ProducerID: producer, Sequence: 3})
+	if err != nil || !result.Duplicate || result.LSN != 2 {
+		t.Fatalf("Append(retry) = %+v, %v; want duplicate of lsn 2", result, err)
+	}
+	if _, err := replacement.Append(ctx, Record{TimestampMS: 1, ProducerID: producer, Sequence: 5}); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("Append(gap) error = %v, want %v", err, ErrSequenceGap)
+	}
+	snapshot, err := replacement.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 3 {
+		t.Fatalf("NextLSN = %d, want 3", snapshot.Head.NextLSN)
+	}
+}
+
+func TestLogWriterReplaysLocalWALAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	dir := t.TempDir()
+	wal, err := filewal.Open(dir, filewal.Options{})
+	if err != nil {
+		t.Fatalf("filewal.Open() error = %v", err)
+	}
+
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}, WAL: wal})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("a")}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := w.AppendBatch(ctx, []Record{{TimestampMS: 1, Value: []byte("b")}, {TimestampMS: 1, Value: []byte("c")}}); err != nil {
+		t.Fatalf("AppendBatch() error = %v", err)
+	}
+	// The process dies after acknowledging the records but before publishing
+	// them.
+	if err := w.Abort(ctx); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	if err := wal.Close(); err != nil {
+		t.Fatalf("wal.Close() error = %v", err)
+	}
+
+	wal, err = filewal.Open(dir, filewal.Options{})
+	if err != nil {
+		t.Fatalf("filewal.Open(restart) error = %v", err)
+	}
+	defer wal.Close()
+	restarted, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{2}, WAL: wal})
+	if err != nil {
+		t.Fatalf("OpenWriter(restart) error = %v", err)
+	}
+	if next := restarted.State().OptimisticNextLSN; next != 3 {
+		t.Fatalf("OptimisticNextLSN after replay = %d, want 3", next)
+	}
+	if _, err := restarted.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	got, err := log.Reader().Partition(1).Read(ctx, ReadRequest{StartLSN: 0, Limit: 10})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got.Records) != 3 {
+		t.Fatalf("len(records) = %d, want 3", len(got.Records))
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if got.Records[i].LSN != uint64(i) || string(got.Records[i].Value) != want {
+			t.Fatalf("record[%d] = %+v, want lsn=%d value=%s", i, got.Records[i], i, want)
+		}
+	}
+}
+
+func TestLogWriterAppendBatchAtDetectsConcurrentDecision(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+
+	// Two command handlers read the aggregate at the same next LSN.
+	decidedAt := w.State().OptimisticNextLSN
+	result, err := w.AppendBatchAt(ctx, decidedAt, []Record{
+		{TimestampMS: 1, Value: []byte("order-placed")},
+		{TimestampMS: 1, Value: []byte("stock-reserved")},
+	})
+	if err != nil || result != (BatchResult{FirstLSN: 0, LastLSN: 1}) {
+		t.Fatalf("AppendBatchAt() = %+v, %v; want [0,1]", result, err)
+	}
+	var conflict LSNConflictError
+	if _, err := w.AppendIf(ctx, decidedAt, Record{TimestampMS: 1, Value: []byte("order-cancelled")}); !errors.As(err, &conflict) || !errors.Is(err, ErrLSNConflict) {
+		t.Fatalf("AppendIf(stale) error = %v, want LSNConflictError", err)
+	}
+	if conflict.Expected != decidedAt || conflict.Next != 2 {
+		t.Fatalf("LSNConflictError = %+v, want expected=%d next=2", conflict, decidedAt)
+	}
+
+	if err := w.Cut(ctx); err != nil {
+		t.Fatalf("Cut() error = %v", err)
+	}
+	snapshot, err := w.WaitCommitted(ctx, result.LastLSN)
+	if err != nil {
+		t.Fatalf("WaitCommitted() error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 2 {
+		t.Fatalf("WaitCommitted() NextLSN = %d, want 2", snapshot.Head.NextLSN)
+	}
+	got, err := log.Reader().Partition(1).Read(ctx, ReadRequest{Limit: 10})
+	if err != nil || len(got.Records) != 2 || string(got.Records[1].Value) != "stock-reserved" {
+		t.Fatalf("Read() = %+v, %v; want both batch records", got.Records, err)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestLogWriterAppendBatchCommitsWholeBatchInOneSegment(t *testing.T) {
+	ctx := context.Background()
+	metrics := &recordingMetrics{}
+	log, err := Open(Options{Store: newTestStore(t), Metrics: metrics})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}, Batch: BatchPolicy{MaxRecords: 2}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("user-message")}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	result, err := w.AppendBatch(ctx, []Record{
+		{TimestampMS: 2, Value: []byte("tool-called")},
+		{TimestampMS: 2, Value: []byte("tool-arguments")},
+		{TimestampMS: 2, Value: []byte("tool-result")},
+	})
+	if err != nil || result != (BatchResult{FirstLSN: 1, LastLSN: 3}) {
+		t.Fatalf("AppendBatch() = %+v, %v; want [1,3]", result, err)
+	}
+	snapshot, err := w.WaitCommitted(ctx, result.LastLSN)
+	if err != nil {
+		t.Fatalf("WaitCommitted() error = %v", err)
+	}
+	if last, _ := snapshot.Head.Last(); last.BaseLSN != 1 || last.LastLSN != 3 {
+		t.Fatalf("last segment = [%d,%d], want the batch alone at [1,3]", last.BaseLSN, last.LastLSN)
+	}
+	if _, err := w.AppendBatch(ctx, nil); !errors.Is(err, ErrEmptyBatch) {
+		t.Fatalf("AppendBatch(empty) error = %v, want %v", err, ErrEmptyBatch)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var batchEvents int
+	for _, event := range metrics.events() {
+		if event.Name == MetricWriterAppend && event.Records == 3 {
+			batchEvents++
+			if event.LSN != 1 || event.Bytes == 0 {
+				t.Fatalf("batch append metric = %+v, want lsn 1 with bytes", event)
+			}
+		}
+	}
+	if batchEvents != 1 {
+		t.Fatalf("batch append metrics = %d, want 1", batchEvents)
+	}
+}
+
+func TestLogWriterAppendAsyncFuturesResolveAsSegmentsPublish(t *testing.T) {
+	ctx := context.Background()
+	log, err := Open(Options{Store: newTestStore(t)})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, WriterOptions{Partition: 1, WriterID: [16]byte{1}, Batch: BatchPolicy{MaxRecords: 10}})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	futures := make([]*AppendFuture, 25)
+	for i := range futures {
+		if futures[i], err = w.AppendAsync(ctx, Record{TimestampMS: 1, Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("AppendAsync(%d) error = %v", i, err)
+		}
+	}
+
+	// The batch policy publishes the first two segments without a Flush.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	for i := 0; i < 20; i++ {
+		snapshot, err := futures[i].Wait(waitCtx)
+		if err != nil || snapshot.Head.NextLSN <= futures[i].Result().LSN {
+			t.Fatalf("Wait(%d) = next_lsn %d, %v", i, snapshot.Head.NextLSN, err)
+		}
+	}
+	select {
+	case <-futures[24].Done():
+		t.Fatal("future of an unpublished record resolved")
+	default:
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	snapshot, err := futures[24].Wait(waitCtx)
+	if err != nil || snapshot.Head.NextLSN != 25 {
+		t.Fatalf("Wait(last) = next_lsn %d, %v; want 25", snapshot.Head.NextLSN, err)
+	}
+}
+
 func TestLogMetricsObserverReceivesPublicAndBackgroundEvents(t *testing.T) {
 	ctx := context.Background()
 	metrics := &recordingMetrics{}
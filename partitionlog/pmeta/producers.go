@@ -0,0 +1,160 @@
+package pmeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+const (
+	// MaxProducers bounds the producers one SegmentRef or PartitionHead
+	// tracks.
+	MaxProducers = 1024
+
+	producerEntrySize = 16 + 8 + 8
+)
+
+// ProducerState is the last append of one idempotent producer: the
+// producer's Sequence for it and the LSN it was assigned.
+type ProducerState struct {
+	ProducerID [16]byte
+	Sequence   uint64
+	LSN        uint64
+}
+
+// Producers is an immutable set of ProducerState keyed by ProducerID. It is
+// comparable, so SegmentRef and PartitionHead stay comparable. The zero value
+// is the empty set.
+type Producers struct {
+	// enc holds producerEntrySize-byte entries sorted by ProducerID.
+	enc string
+}
+
+// NewProducers returns the set of states. ProducerIDs must be nonzero and
+// distinct, and there may be at most MaxProducers states.
+func NewProducers(states []ProducerState) (Producers, error) {
+	if len(states) > MaxProducers {
+		return Producers{}, fmt.Errorf("pmeta: producers=%d max=%d", len(states), MaxProducers)
+	}
+	sorted := slices.Clone(states)
+	slices.SortFunc(sorted, func(a, b ProducerState) int {
+		return bytes.Compare(a.ProducerID[:], b.ProducerID[:])
+	})
+	enc := make([]byte, 0, len(sorted)*producerEntrySize)
+	for i, state := range sorted {
+		if state.ProducerID == ([16]byte{}) {
+			return Producers{}, fmt.Errorf("pmeta: empty producer_id")
+		}
+		if i > 0 && sorted[i-1].ProducerID == state.ProducerID {
+			return Producers{}, fmt.Errorf("pmeta: duplicate producer_id %x", state.ProducerID)
+		}
+		enc = append(enc, state.ProducerID[:]...)
+		enc = binary.BigEndian.AppendUint64(enc, state.Sequence)
+		enc = binary.BigEndian.AppendUint64(enc, state.LSN)
+	}
+	return Producers{enc: string(enc)}, nil
+}
+
+func (p Producers) IsZero() bool {
+	return p.enc == ""
+}
+
+func (p Producers) Len() int {
+	return len(p.enc) / producerEntrySize
+}
+
+// Get returns the state of producer id.
+func (p Producers) Get(id [16]byte) (ProducerState, bool) {
+	n := p.Len()
+	i := sort.Search(n, func(i int) bool {
+		return p.enc[i*producerEntrySize:i*producerEntrySize+16] >= string(id[:])
+	})
+	if i == n {
+		return ProducerState{}, false
+	}
+	state := p.at(i)
+	return state, state.ProducerID == id
+}
+
+// All returns every state ordered by ProducerID.
+func (p Producers) All() []ProducerState {
+	states := make([]ProducerState, p.Len())
+	for i := range states {
+		states[i] = p.at(i)
+	}
+	return states
+}
+
+// Merge returns p updated with the states of delta, which win for the
+// producers in both. Past MaxProducers, the producers with the lowest LSN are
+// dropped, the same producers a writer tracking them in LSN order forgets.
+func (p Producers) Merge(delta Producers) Producers {
+	if delta.IsZero() {
+		return p
+	}
+	if p.IsZero() {
+		return delta
+	}
+	merged := make(map[[16]byte]ProducerState, p.Len()+delta.Len())
+	for _, state := range p.All() {
+		merged[state.ProducerID] = state
+	}
+	for _, state := range delta.All() {
+		merged[state.ProducerID] = state
+	}
+	states := make([]ProducerState, 0, len(merged))
+	for _, state := range merged {
+		states = append(states, state)
+	}
+	if len(states) > MaxProducers {
+		slices.SortFunc(states, func(a, b ProducerState) int {
+			if a.LSN != b.LSN {
+				if a.LSN > b.LSN {
+					return -1
+				}
+				return 1
+			}
+			return bytes.Compare(a.ProducerID[:], b.ProducerID[:])
+		})
+		states = states[:MaxProducers]
+	}
+	// Both inputs hold distinct nonzero IDs and the count is bounded above.
+	out, _ := NewProducers(states)
+	return out
+}
+
+func (p Producers) at(i int) ProducerState {
+	entry := p.enc[i*producerEntrySize : (i+1)*producerEntrySize]
+	var state ProducerState
+	copy(state.ProducerID[:], entry[:16])
+	state.Sequence = binary.BigEndian.Uint64([]byte(entry[16:24]))
+	state.LSN = binary.BigEndian.Uint64([]byte(entry[24:32]))
+	return state
+}
+
+// MarshalBinary returns the canonical encoding: each state as its 16-byte
+// ProducerID, then Sequence and LSN as big-endian u64, ordered by
+// ProducerID.
+func (p Producers) MarshalBinary() ([]byte, error) {
+	return []byte(p.enc), nil
+}
+
+func (p Producers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.All())
+}
+
+func (p *Producers) UnmarshalJSON(data []byte) error {
+	var states []ProducerState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return fmt.Errorf("pmeta: producers: %w", err)
+	}
+	decoded, err := NewProducers(states)
+	if err != nil {
+		return err
+	}
+	*p = decoded
+	return nil
+}
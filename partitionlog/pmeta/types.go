@@ -1,6 +1,7 @@
 package pmeta
 
 import (
+	"encoding/hex"
 	"fmt"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
@@ -27,8 +28,27 @@ type PartitionHead struct {
 	AppliedRetentionVersion uint64
 	WriterEpoch             uint64
 	SegmentCount            uint64
-	LastSegment             SegmentRef
-	HasLastSegment          bool
+	// RetainedBytes is the total SizeBytes of the segments at or above
+	// OldestLSN.
+	RetainedBytes  uint64
+	LastSegment    SegmentRef
+	HasLastSegment bool
+	// Sealed marks the partition permanently read-only. No writer can fence
+	// in after it is set, so NextLSN is final.
+	Sealed bool
+	// TreeSize counts the hash-chained segments the partition committed, and
+	// TreeRoot is the RFC 9162 Merkle root over their ChainHashes in LSN
+	// order. Retention does not change either; proofs verify against them.
+	TreeSize uint64
+	TreeRoot Hash
+	// Producers is the last append of every idempotent producer, up to
+	// MaxProducers with the highest LSNs. It is stored once here; each
+	// SegmentRef carries only the producers that appended inside it.
+	Producers Producers
+	// SignerKeyID names the writer key that signed this head, and Signature
+	// covers every other head field. Both are empty for unsigned heads.
+	SignerKeyID string
+	Signature   Signature
 }
 
 func (h PartitionHead) Last() (SegmentRef, bool) {
@@ -38,6 +58,72 @@ func (h PartitionHead) Last() (SegmentRef, bool) {
 	return h.LastSegment, true
 }
 
+// ProducerState returns the head's producer state. A head written before
+// PartitionHead.Producers existed keeps it in its last segment's cumulative
+// Producers instead.
+func (h PartitionHead) ProducerState() Producers {
+	if h.Producers.IsZero() && h.HasLastSegment {
+		return h.LastSegment.Producers
+	}
+	return h.Producers
+}
+
+// Hash is a SHA-256 digest in tamper-evident timeline metadata. It encodes as
+// lowercase hex text.
+type Hash [32]byte
+
+func (h Hash) IsZero() bool {
+	return h == Hash{}
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	if len(text) != 2*len(h) {
+		return fmt.Errorf("pmeta: hash length=%d want=%d", len(text), 2*len(h))
+	}
+	var decoded Hash
+	if _, err := hex.Decode(decoded[:], text); err != nil {
+		return fmt.Errorf("pmeta: hash: %w", err)
+	}
+	*h = decoded
+	return nil
+}
+
+// Signature is an ed25519 signature in signed timeline metadata. It encodes
+// as lowercase hex text.
+type Signature [64]byte
+
+func (s Signature) IsZero() bool {
+	return s == Signature{}
+}
+
+func (s Signature) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func (s Signature) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Signature) UnmarshalText(text []byte) error {
+	if len(text) != 2*len(s) {
+		return fmt.Errorf("pmeta: signature length=%d want=%d", len(text), 2*len(s))
+	}
+	var decoded Signature
+	if _, err := hex.Decode(decoded[:], text); err != nil {
+		return fmt.Errorf("pmeta: signature: %w", err)
+	}
+	*s = decoded
+	return nil
+}
+
 // SegmentRef is the durable metadata for one committed segment object.
 type SegmentRef struct {
 	URI              string
@@ -59,6 +145,41 @@ type SegmentRef struct {
 	HashAlgo         segformat.HashAlgo
 	SegmentHash      uint64
 	TrailerHash      uint64
+	// DictionaryID names the compression dictionary of a zstd-dict segment.
+	// It is zero, and omitted from JSON, for every other codec.
+	DictionaryID uint32 `json:",omitempty"`
+	// KeyID names the provider key that wraps the data key of an encrypted
+	// segment. It is empty for unencrypted segments, and omitted from JSON
+	// then so existing catalog objects keep their encoding.
+	KeyID string `json:",omitempty"`
+	// RedactedUnixMS is when a redaction rewrite replaced an earlier object
+	// for this LSN range. Zero means the segment was never rewritten.
+	RedactedUnixMS int64 `json:",omitempty"`
+	// ChainedSegmentHash and ChainedTrailerHash are the SegmentHash and
+	// TrailerHash of the segment as first written. Only a redaction rewrite
+	// sets them, so its ChainHash still verifies; see ChainedHashes.
+	ChainedSegmentHash uint64 `json:",omitempty"`
+	ChainedTrailerHash uint64 `json:",omitempty"`
+	// RecordsRoot is the Merkle root over the segment's record leaves.
+	// PrevChainHash is the ChainHash of the partition's previous segment, and
+	// ChainHash commits to PrevChainHash, this segment's range, RecordsRoot
+	// and ChainedHashes. Redaction keeps all of them. They are zero for
+	// segments written before chaining.
+	RecordsRoot   Hash `json:",omitzero"`
+	PrevChainHash Hash `json:",omitzero"`
+	ChainHash     Hash `json:",omitzero"`
+	// SegmentDigest is the trailer's full segment digest. It is set exactly
+	// when HashAlgo is cryptographic.
+	SegmentDigest Hash `json:",omitzero"`
+	// SignerKeyID names the writer key that signed this ref, and Signature
+	// covers every other field. Both are empty for unsigned segments.
+	SignerKeyID string    `json:",omitempty"`
+	Signature   Signature `json:",omitzero"`
+	// Producers is the last append of every idempotent producer whose last
+	// append falls inside this segment. Catalogs merge it into
+	// PartitionHead.Producers, the state a replacement writer deduplicates
+	// retries with. Refs written before that carry the cumulative state.
+	Producers Producers `json:",omitzero"`
 }
 
 func (s SegmentRef) Validate() error {
@@ -96,12 +217,45 @@ func (s SegmentRef) Validate() error {
 	if err := s.Codec.Validate(); err != nil {
 		return fmt.Errorf("pmeta: %w", err)
 	}
+	if s.Codec.UsesDictionary() == (s.DictionaryID == 0) {
+		return fmt.Errorf("pmeta: dictionary_id=%d does not match codec=%s", s.DictionaryID, s.Codec)
+	}
 	if err := s.HashAlgo.Validate(); err != nil {
 		return fmt.Errorf("pmeta: %w", err)
 	}
+	if s.HashAlgo.Cryptographic() == s.SegmentDigest.IsZero() {
+		return fmt.Errorf("pmeta: segment_digest does not match hash_algo=%s", s.HashAlgo)
+	}
+	if s.RedactedUnixMS < 0 {
+		return fmt.Errorf("pmeta: redacted_unix_ms=%d", s.RedactedUnixMS)
+	}
+	if s.RedactedUnixMS == 0 && (s.ChainedSegmentHash != 0 || s.ChainedTrailerHash != 0) {
+		return fmt.Errorf("pmeta: chained hashes set on a segment that was never redacted")
+	}
+	if len(s.KeyID) > segformat.MaxKeyIDLen {
+		return fmt.Errorf("pmeta: key_id_len=%d max=%d", len(s.KeyID), segformat.MaxKeyIDLen)
+	}
+	if (s.SignerKeyID == "") != s.Signature.IsZero() {
+		return fmt.Errorf("pmeta: signer_key_id and signature must be set together")
+	}
+	for _, producer := range s.Producers.All() {
+		if producer.LSN > s.LastLSN {
+			return fmt.Errorf("pmeta: producer %x lsn=%d after last_lsn=%d", producer.ProducerID, producer.LSN, s.LastLSN)
+		}
+	}
 	return nil
 }
 
+// ChainedHashes returns the SegmentHash and TrailerHash that ChainHash
+// commits to. They are the segment's own, except after a redaction rewrite,
+// whose object hashes differ from the bytes the chain was built over.
+func (s SegmentRef) ChainedHashes() (segmentHash, trailerHash uint64) {
+	if s.RedactedUnixMS != 0 {
+		return s.ChainedSegmentHash, s.ChainedTrailerHash
+	}
+	return s.SegmentHash, s.TrailerHash
+}
+
 func (s SegmentRef) NextLSN() uint64 {
 	return s.LastLSN + 1
 }
@@ -1,6 +1,7 @@
 package pmeta
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
@@ -25,6 +26,59 @@ func TestSegmentRefLSNBoundary(t *testing.T) {
 	})
 }
 
+func TestProducersCanonicalSetAndJSONRoundTrip(t *testing.T) {
+	a, b := [16]byte{2}, [16]byte{1}
+	producers, err := NewProducers([]ProducerState{
+		{ProducerID: a, Sequence: 7, LSN: 9},
+		{ProducerID: b, Sequence: 3, LSN: 4},
+	})
+	if err != nil {
+		t.Fatalf("NewProducers() error = %v", err)
+	}
+	reordered, err := NewProducers([]ProducerState{
+		{ProducerID: b, Sequence: 3, LSN: 4},
+		{ProducerID: a, Sequence: 7, LSN: 9},
+	})
+	if err != nil || reordered != producers {
+		t.Fatalf("NewProducers(reordered) = %v, %v; want the same set", reordered, err)
+	}
+	if state, ok := producers.Get(a); !ok || state.Sequence != 7 || state.LSN != 9 {
+		t.Fatalf("Get(a) = %+v, %v", state, ok)
+	}
+	if _, ok := producers.Get([16]byte{3}); ok {
+		t.Fatal("Get(unknown) found a producer")
+	}
+
+	segment := validBoundarySegment(0, 9, 10)
+	segment.Producers = producers
+	data, err := json.Marshal(segment)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var decoded SegmentRef
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded != segment {
+		t.Fatalf("Unmarshal() = %+v, want %+v", decoded, segment)
+	}
+	if err := segment.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	segment.LastLSN = 8
+	segment.RecordCount = 9
+	if err := segment.Validate(); err == nil {
+		t.Fatal("Validate() error = nil, want producer lsn after last_lsn rejection")
+	}
+
+	if _, err := NewProducers([]ProducerState{{ProducerID: a}, {ProducerID: a}}); err == nil {
+		t.Fatal("NewProducers(duplicate) error = nil")
+	}
+	if _, err := NewProducers([]ProducerState{{}}); err == nil {
+		t.Fatal("NewProducers(zero id) error = nil")
+	}
+}
+
 func validBoundarySegment(baseLSN, lastLSN uint64, recordCount uint32) SegmentRef {
 	return SegmentRef{
 		URI:              "memory://lsn-boundary",
@@ -42,3 +96,60 @@ func validBoundarySegment(baseLSN, lastLSN uint64, recordCount uint32) SegmentRe
 		HashAlgo:         segformat.HashXXH64,
 	}
 }
+
+func TestProducersMergeKeepsNewestStates(t *testing.T) {
+	a, b, c := [16]byte{1}, [16]byte{2}, [16]byte{3}
+	base, err := NewProducers([]ProducerState{
+		{ProducerID: a, Sequence: 1, LSN: 1},
+		{ProducerID: b, Sequence: 1, LSN: 2},
+	})
+	if err != nil {
+		t.Fatalf("NewProducers(base) error = %v", err)
+	}
+	delta, err := NewProducers([]ProducerState{
+		{ProducerID: b, Sequence: 2, LSN: 5},
+		{ProducerID: c, Sequence: 1, LSN: 6},
+	})
+	if err != nil {
+		t.Fatalf("NewProducers(delta) error = %v", err)
+	}
+	merged := base.Merge(delta)
+	if merged.Len() != 3 {
+		t.Fatalf("Merge().Len() = %d, want 3", merged.Len())
+	}
+	if state, ok := merged.Get(b); !ok || state.Sequence != 2 || state.LSN != 5 {
+		t.Fatalf("Merge().Get(b) = %+v, %v; want the delta state", state, ok)
+	}
+	if state, ok := merged.Get(a); !ok || state.LSN != 1 {
+		t.Fatalf("Merge().Get(a) = %+v, %v; want the base state", state, ok)
+	}
+	if base.Merge(Producers{}) != base || (Producers{}).Merge(delta) != delta {
+		t.Fatal("Merge() with an empty side changed the other side")
+	}
+
+	full := make([]ProducerState, MaxProducers)
+	for i := range full {
+		full[i] = ProducerState{ProducerID: [16]byte{0xff, byte(i >> 8), byte(i)}, Sequence: 1, LSN: uint64(i + 10)}
+	}
+	fullSet, err := NewProducers(full)
+	if err != nil {
+		t.Fatalf("NewProducers(full) error = %v", err)
+	}
+	newer, err := NewProducers([]ProducerState{{ProducerID: c, Sequence: 2, LSN: 5000}})
+	if err != nil {
+		t.Fatalf("NewProducers(newer) error = %v", err)
+	}
+	merged = fullSet.Merge(newer)
+	if merged.Len() != MaxProducers {
+		t.Fatalf("Merge(past max).Len() = %d, want %d", merged.Len(), MaxProducers)
+	}
+	if _, ok := merged.Get(full[0].ProducerID); ok {
+		t.Fatal("Merge(past max) kept the producer with the lowest lsn")
+	}
+	if _, ok := merged.Get(c); !ok {
+		t.Fatal("Merge(past max) dropped the newest producer")
+	}
+	if _, ok := merged.Get(full[1].ProducerID); !ok {
+		t.Fatal("Merge(past max) dropped more than the oldest producer")
+	}
+}
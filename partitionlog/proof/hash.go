@@ -0,0 +1,115 @@
+package proof
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+// RecordDigest returns the SHA-256 content digest of one record's headers and
+// value. Lengths are framed so distinct records never share an encoding.
+func RecordDigest(headers []segformat.Header, value []byte) pmeta.Hash {
+	h := sha256.New()
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(len(headers)))
+	h.Write(n[:])
+	for _, header := range headers {
+		binary.BigEndian.PutUint64(n[:], uint64(len(header.Key)))
+		h.Write(n[:])
+		h.Write(header.Key)
+		binary.BigEndian.PutUint64(n[:], uint64(len(header.Value)))
+		h.Write(n[:])
+		h.Write(header.Value)
+	}
+	binary.BigEndian.PutUint64(n[:], uint64(len(value)))
+	h.Write(n[:])
+	h.Write(value)
+	var digest pmeta.Hash
+	h.Sum(digest[:0])
+	return digest
+}
+
+// LeafDigest returns the digest a record contributes to its segment's records
+// root. A redaction tombstone contributes the original digest it carries, so
+// redaction does not change the root.
+func LeafDigest(headers []segformat.Header, value []byte) pmeta.Hash {
+	if carried, ok := segformat.RedactionDigest(headers, value); ok && len(carried) == sha256.Size {
+		return pmeta.Hash(carried)
+	}
+	return RecordDigest(headers, value)
+}
+
+// RecordLeaf returns the Merkle leaf hash of the record at lsn.
+func RecordLeaf(lsn uint64, timestampMS int64, digest pmeta.Hash) pmeta.Hash {
+	var buf [1 + 8 + 8 + sha256.Size]byte
+	buf[0] = leafPrefix
+	binary.BigEndian.PutUint64(buf[1:], lsn)
+	binary.BigEndian.PutUint64(buf[9:], uint64(timestampMS))
+	copy(buf[17:], digest[:])
+	return sha256.Sum256(buf[:])
+}
+
+// SegmentLeaf returns the leaf hash a segment contributes to the partition's
+// segment tree.
+func SegmentLeaf(chainHash pmeta.Hash) pmeta.Hash {
+	var buf [1 + sha256.Size]byte
+	buf[0] = leafPrefix
+	copy(buf[1:], chainHash[:])
+	return sha256.Sum256(buf[:])
+}
+
+// ChainHash returns the chain hash of segment from its PrevChainHash, stream,
+// partition, LSN and timestamp range, RecordsRoot, and the SegmentHash and
+// TrailerHash of its bytes as first written. A redaction rewrite carries
+// those in ChainedSegmentHash and ChainedTrailerHash, so it keeps the chain
+// intact while its own object hashes change; its new object is bound by the
+// unchanged RecordsRoot and by the catalog that references it.
+func ChainHash(segment pmeta.SegmentRef) pmeta.Hash {
+	h := sha256.New()
+	var buf [8]byte
+	h.Write([]byte{chainPrefix})
+	h.Write(segment.PrevChainHash[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(len(segment.StreamID)))
+	h.Write(buf[:])
+	h.Write([]byte(segment.StreamID))
+	binary.BigEndian.PutUint32(buf[:4], segment.Partition)
+	h.Write(buf[:4])
+	segmentHash, trailerHash := segment.ChainedHashes()
+	for _, v := range []uint64{segment.BaseLSN, segment.LastLSN, uint64(segment.MinTimestampMS), uint64(segment.MaxTimestampMS), segmentHash, trailerHash} {
+		binary.BigEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	h.Write(segment.RecordsRoot[:])
+	var chain pmeta.Hash
+	h.Sum(chain[:0])
+	return chain
+}
+
+// Chain links segment to the previous segment's chain hash.
+func Chain(prev pmeta.Hash, segment pmeta.SegmentRef) pmeta.SegmentRef {
+	segment.PrevChainHash = prev
+	segment.ChainHash = ChainHash(segment)
+	return segment
+}
+
+// RecordsRootBuilder accumulates record leaves in LSN order.
+type RecordsRootBuilder struct {
+	leaves []pmeta.Hash
+}
+
+// Add appends the leaf of the record at lsn.
+func (b *RecordsRootBuilder) Add(lsn uint64, timestampMS int64, headers []segformat.Header, value []byte) {
+	b.leaves = append(b.leaves, RecordLeaf(lsn, timestampMS, LeafDigest(headers, value)))
+}
+
+// Root returns the records root over every added leaf.
+func (b *RecordsRootBuilder) Root() pmeta.Hash {
+	return Root(b.leaves)
+}
+
+// Reset drops every added leaf.
+func (b *RecordsRootBuilder) Reset() {
+	b.leaves = b.leaves[:0]
+}
@@ -0,0 +1,136 @@
+package proof
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+// Domain prefixes keep leaf, interior node, and chain hashes from colliding.
+const (
+	leafPrefix  = 0x00
+	nodePrefix  = 0x01
+	chainPrefix = 0x02
+)
+
+// Root returns the RFC 9162 Merkle tree hash over leaves, which must already
+// be leaf hashes.
+func Root(leaves []pmeta.Hash) pmeta.Hash {
+	switch len(leaves) {
+	case 0:
+		return sha256.Sum256(nil)
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return nodeHash(Root(leaves[:k]), Root(leaves[k:]))
+}
+
+// Step proves that a leaf is at Index of a Merkle tree with Size leaves.
+// Path lists the sibling hashes from the leaf up.
+type Step struct {
+	Index uint64       `json:"index"`
+	Size  uint64       `json:"size"`
+	Path  []pmeta.Hash `json:"path,omitempty"`
+}
+
+// NewStep returns the audit path for leaves[index].
+func NewStep(leaves []pmeta.Hash, index int) (Step, error) {
+	if index < 0 || index >= len(leaves) {
+		return Step{}, fmt.Errorf("%w: index=%d size=%d", ErrInvalidProof, index, len(leaves))
+	}
+	return Step{Index: uint64(index), Size: uint64(len(leaves)), Path: auditPath(leaves, index)}, nil
+}
+
+// Root recomputes the tree root from leaf and the audit path.
+func (s Step) Root(leaf pmeta.Hash) (pmeta.Hash, error) {
+	if s.Index >= s.Size {
+		return pmeta.Hash{}, fmt.Errorf("%w: index=%d size=%d", ErrInvalidProof, s.Index, s.Size)
+	}
+	fn, sn := s.Index, s.Size-1
+	root := leaf
+	for _, sibling := range s.Path {
+		if sn == 0 {
+			return pmeta.Hash{}, fmt.Errorf("%w: audit path too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			root = nodeHash(sibling, root)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			root = nodeHash(root, sibling)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return pmeta.Hash{}, fmt.Errorf("%w: audit path too short", ErrInvalidProof)
+	}
+	return root, nil
+}
+
+// verifyConsistencyPath runs the RFC 9162 section 2.1.4.2 verification for
+// 0 < first < second.
+func verifyConsistencyPath(first, second uint64, firstRoot, secondRoot pmeta.Hash, path []pmeta.Hash) bool {
+	if len(path) == 0 {
+		return false
+	}
+	if first&(first-1) == 0 {
+		path = append([]pmeta.Hash{firstRoot}, path...)
+	}
+	fn, sn := first-1, second-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := path[0], path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && fr == firstRoot && sr == secondRoot
+}
+
+func auditPath(leaves []pmeta.Hash, index int) []pmeta.Hash {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(auditPath(leaves[:k], index), Root(leaves[k:]))
+	}
+	return append(auditPath(leaves[k:], index-k), Root(leaves[:k]))
+}
+
+// splitPoint returns the largest power of two smaller than n.
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+func nodeHash(left, right pmeta.Hash) pmeta.Hash {
+	var buf [1 + 2*sha256.Size]byte
+	buf[0] = nodePrefix
+	copy(buf[1:], left[:])
+	copy(buf[1+sha256.Size:], right[:])
+	return sha256.Sum256(buf[:])
+}
@@ -0,0 +1,175 @@
+// Package proof builds and verifies inclusion and consistency proofs for
+// tamper-evident partition timelines.
+//
+// Every segment carries a RecordsRoot, the Merkle root over its record
+// leaves, and a ChainHash that links it to the previous segment's ChainHash.
+// The partition head's TreeRoot is the RFC 9162 Merkle tree hash over the
+// chain hashes of every chained segment the partition committed, in LSN
+// order, and TreeSize counts them. The root depends only on log content, not
+// on how a catalog lays out its pages, and retention does not change it.
+//
+// A RecordProof walks from one record leaf to the segment's RecordsRoot,
+// recomputes the segment's ChainHash, and then walks the segment tree to the
+// TreeRoot of a Checkpoint. A ConsistencyProof shows that a later Checkpoint
+// extends an earlier one, so a proof against the earlier checkpoint still
+// holds. Verification needs only the proofs and checkpoints published out of
+// band, so it does not trust the bucket that served them.
+package proof
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+var (
+	// ErrInvalidProof reports a proof that does not verify against its
+	// checkpoint.
+	ErrInvalidProof = errors.New("proof: invalid proof")
+	// ErrUnchained reports a segment written before hash chaining, which no
+	// proof can cover.
+	ErrUnchained = errors.New("proof: segment not hash chained")
+	// ErrPruned reports a proof that needs segment tree leaves retention
+	// already dropped.
+	ErrPruned = errors.New("proof: tree leaves no longer retained")
+)
+
+// Checkpoint is the root of one partition timeline over its first TreeSize
+// chained segments, which end at NextLSN. Publish it out of band and verify
+// proofs against the published copy; a checkpoint stays provable after the
+// partition grows.
+type Checkpoint struct {
+	StreamID  string     `json:"stream_id"`
+	Partition uint32     `json:"partition"`
+	NextLSN   uint64     `json:"next_lsn"`
+	TreeSize  uint64     `json:"tree_size"`
+	TreeRoot  pmeta.Hash `json:"tree_root"`
+}
+
+// CheckpointFromHead returns the checkpoint of head.
+func CheckpointFromHead(head pmeta.PartitionHead) Checkpoint {
+	return Checkpoint{
+		StreamID:  head.StreamID,
+		Partition: head.Partition,
+		NextLSN:   head.NextLSN,
+		TreeSize:  head.TreeSize,
+		TreeRoot:  head.TreeRoot,
+	}
+}
+
+// SegmentProof proves that Segment is committed under Checkpoint.TreeRoot.
+// Inclusion is the audit path from the segment's leaf to the root.
+type SegmentProof struct {
+	Checkpoint Checkpoint       `json:"checkpoint"`
+	Segment    pmeta.SegmentRef `json:"segment"`
+	Inclusion  Step             `json:"inclusion"`
+}
+
+// RecordProof proves that the record at LSN with content digest Digest is
+// committed under Checkpoint.TreeRoot.
+type RecordProof struct {
+	LSN         uint64     `json:"lsn"`
+	TimestampMS int64      `json:"timestamp_ms"`
+	Digest      pmeta.Hash `json:"digest"`
+	Record      Step       `json:"record"`
+	SegmentProof
+}
+
+// Verify checks p against the published checkpoint.
+func Verify(published Checkpoint, p RecordProof) error {
+	segment := p.Segment
+	switch {
+	case p.LSN < segment.BaseLSN || p.LSN > segment.LastLSN:
+		return fmt.Errorf("%w: lsn=%d outside segment [%d,%d]", ErrInvalidProof, p.LSN, segment.BaseLSN, segment.LastLSN)
+	case p.Record.Index != p.LSN-segment.BaseLSN || p.Record.Size != segment.LastLSN-segment.BaseLSN+1:
+		return fmt.Errorf("%w: record position does not match lsn", ErrInvalidProof)
+	}
+	recordsRoot, err := p.Record.Root(RecordLeaf(p.LSN, p.TimestampMS, p.Digest))
+	if err != nil {
+		return err
+	}
+	if recordsRoot != segment.RecordsRoot {
+		return fmt.Errorf("%w: record does not match segment records root", ErrInvalidProof)
+	}
+	return VerifySegment(published, p.SegmentProof)
+}
+
+// VerifySegment checks that p's segment is committed under the published
+// checkpoint.
+func VerifySegment(published Checkpoint, p SegmentProof) error {
+	if p.Checkpoint != published {
+		return fmt.Errorf("%w: proof checkpoint does not match the published checkpoint", ErrInvalidProof)
+	}
+	segment := p.Segment
+	switch {
+	case segment.StreamID != published.StreamID || segment.Partition != published.Partition:
+		return fmt.Errorf("%w: segment is not in the checkpoint partition", ErrInvalidProof)
+	case segment.LastLSN >= published.NextLSN:
+		return fmt.Errorf("%w: segment last_lsn=%d at or past next_lsn=%d", ErrInvalidProof, segment.LastLSN, published.NextLSN)
+	case segment.ChainHash.IsZero():
+		return fmt.Errorf("%w: %w", ErrInvalidProof, ErrUnchained)
+	case ChainHash(segment) != segment.ChainHash:
+		return fmt.Errorf("%w: segment chain hash mismatch", ErrInvalidProof)
+	}
+	if p.Inclusion.Size != published.TreeSize {
+		return fmt.Errorf("%w: inclusion tree size=%d checkpoint tree_size=%d", ErrInvalidProof, p.Inclusion.Size, published.TreeSize)
+	}
+	root, err := p.Inclusion.Root(SegmentLeaf(segment.ChainHash))
+	if err != nil {
+		return err
+	}
+	if root != published.TreeRoot {
+		return fmt.Errorf("%w: segment path does not reach tree root", ErrInvalidProof)
+	}
+	return nil
+}
+
+// VerifyRecord checks p against the published checkpoint and checks that
+// headers and value are the proven record. A redaction tombstone matches the
+// digest of the record it replaced.
+func VerifyRecord(published Checkpoint, p RecordProof, headers []segformat.Header, value []byte) error {
+	if LeafDigest(headers, value) != p.Digest {
+		return fmt.Errorf("%w: record content does not match proof digest", ErrInvalidProof)
+	}
+	return Verify(published, p)
+}
+
+// ConsistencyProof proves that the segment tree of New extends the tree of
+// Old: New was built by appending segments to Old, so everything proven
+// under Old is still committed under New. Path is the RFC 9162 consistency
+// proof between the two tree sizes.
+type ConsistencyProof struct {
+	Old  Checkpoint   `json:"old"`
+	New  Checkpoint   `json:"new"`
+	Path []pmeta.Hash `json:"path,omitempty"`
+}
+
+// VerifyConsistency checks that p proves the published checkpoint newer
+// extends the published checkpoint older.
+func VerifyConsistency(older, newer Checkpoint, p ConsistencyProof) error {
+	if p.Old != older || p.New != newer {
+		return fmt.Errorf("%w: proof checkpoints do not match the published checkpoints", ErrInvalidProof)
+	}
+	switch {
+	case older.StreamID != newer.StreamID || older.Partition != newer.Partition:
+		return fmt.Errorf("%w: checkpoints are for different partitions", ErrInvalidProof)
+	case older.TreeSize > newer.TreeSize || older.NextLSN > newer.NextLSN:
+		return fmt.Errorf("%w: old checkpoint is ahead of new checkpoint", ErrInvalidProof)
+	case older.TreeSize == 0:
+		if len(p.Path) != 0 || !older.TreeRoot.IsZero() {
+			return fmt.Errorf("%w: empty tree carries a root or path", ErrInvalidProof)
+		}
+		return nil
+	case older.TreeSize == newer.TreeSize:
+		if len(p.Path) != 0 || older.TreeRoot != newer.TreeRoot || older.NextLSN != newer.NextLSN {
+			return fmt.Errorf("%w: equal tree sizes with different checkpoints", ErrInvalidProof)
+		}
+		return nil
+	}
+	if !verifyConsistencyPath(older.TreeSize, newer.TreeSize, older.TreeRoot, newer.TreeRoot, p.Path) {
+		return fmt.Errorf("%w: consistency path does not reach both tree roots", ErrInvalidProof)
+	}
+	return nil
+}
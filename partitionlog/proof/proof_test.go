@@ -0,0 +1,181 @@
+package proof
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+func TestStepRootsEveryLeafOfEveryTreeSize(t *testing.T) {
+	t.Parallel()
+
+	for size := 1; size <= 33; size++ {
+		leaves := make([]pmeta.Hash, size)
+		for i := range leaves {
+			leaves[i] = SegmentLeaf(pmeta.Hash{byte(i), byte(size)})
+		}
+		want := Root(leaves)
+		for i := range leaves {
+			step, err := NewStep(leaves, i)
+			if err != nil {
+				t.Fatalf("NewStep(size=%d, %d) error = %v", size, i, err)
+			}
+			got, err := step.Root(leaves[i])
+			if err != nil || got != want {
+				t.Fatalf("Root(size=%d, %d) = %s, %v; want %s", size, i, got, err, want)
+			}
+			if size > 1 {
+				if got, _ := step.Root(leaves[(i+1)%size]); got == want {
+					t.Fatalf("Root(size=%d, %d) accepted the wrong leaf", size, i)
+				}
+				short := step
+				short.Path = short.Path[:len(short.Path)-1]
+				if _, err := short.Root(leaves[i]); !errors.Is(err, ErrInvalidProof) {
+					t.Fatalf("Root(size=%d, %d, short path) error = %v, want %v", size, i, err, ErrInvalidProof)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyRecordProofAndDetectTampering(t *testing.T) {
+	t.Parallel()
+
+	p, cp, records := testRecordProof(t, 2, 3)
+	if err := Verify(cp, p); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	record := records[p.LSN-p.Segment.BaseLSN]
+	if err := VerifyRecord(cp, p, record.Headers, record.Value); err != nil {
+		t.Fatalf("VerifyRecord() error = %v", err)
+	}
+	if err := VerifyRecord(cp, p, record.Headers, []byte("forged")); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("VerifyRecord(forged value) error = %v, want %v", err, ErrInvalidProof)
+	}
+
+	redacted := segformat.RedactedRecord(record.TimestampMS, "gdpr", p.Digest[:])
+	if err := VerifyRecord(cp, p, redacted.Headers, redacted.Value); err != nil {
+		t.Fatalf("VerifyRecord(tombstone) error = %v", err)
+	}
+
+	// Each case verifies against the proof's own checkpoint, so a changed
+	// tree root stands for a forged publication.
+	tampered := []struct {
+		name   string
+		mutate func(*RecordProof)
+	}{
+		{"tree root", func(p *RecordProof) { p.Checkpoint.TreeRoot[0] ^= 1 }},
+		{"digest", func(p *RecordProof) { p.Digest[0] ^= 1 }},
+		{"timestamp", func(p *RecordProof) { p.TimestampMS++ }},
+		{"lsn", func(p *RecordProof) { p.LSN++; p.Record.Index++ }},
+		{"records root", func(p *RecordProof) { p.Segment.RecordsRoot[0] ^= 1 }},
+		{"prev chain", func(p *RecordProof) { p.Segment.PrevChainHash[0] ^= 1 }},
+		{"segment range", func(p *RecordProof) { p.Segment.MaxTimestampMS++ }},
+		{"segment hash", func(p *RecordProof) { p.Segment.SegmentHash++ }},
+		{"trailer hash", func(p *RecordProof) { p.Segment.TrailerHash++ }},
+		{"chained hash", func(p *RecordProof) {
+			p.Segment.RedactedUnixMS = 1
+			p.Segment.ChainedSegmentHash, p.Segment.ChainedTrailerHash = p.Segment.SegmentHash+1, p.Segment.TrailerHash
+		}},
+		{"segment path", func(p *RecordProof) { p.Inclusion.Path[0][0] ^= 1 }},
+		{"tree size", func(p *RecordProof) { p.Checkpoint.TreeSize++; p.Inclusion.Size++ }},
+		{"record path", func(p *RecordProof) { p.Record.Path[0][0] ^= 1 }},
+	}
+	for _, tt := range tampered {
+		p, _, _ := testRecordProof(t, 2, 3)
+		tt.mutate(&p)
+		if err := Verify(p.Checkpoint, p); !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("Verify(tampered %s) error = %v, want %v", tt.name, err, ErrInvalidProof)
+		}
+	}
+
+	// A redaction rewrite has new object hashes and carries the ones the
+	// chain was built over.
+	p, cp, _ = testRecordProof(t, 2, 3)
+	p.Segment.ChainedSegmentHash, p.Segment.ChainedTrailerHash = p.Segment.ChainedHashes()
+	p.Segment.RedactedUnixMS = 1
+	p.Segment.SegmentHash++
+	p.Segment.TrailerHash++
+	if err := Verify(cp, p); err != nil {
+		t.Fatalf("Verify(redacted rewrite) error = %v", err)
+	}
+
+	p, cp, _ = testRecordProof(t, 2, 3)
+	p.Segment.ChainHash = pmeta.Hash{}
+	if err := Verify(cp, p); !errors.Is(err, ErrUnchained) {
+		t.Fatalf("Verify(unchained) error = %v, want %v", err, ErrUnchained)
+	}
+}
+
+// testRecordProof builds a four-segment chain of five records each and
+// proves record rec of segment seg under the checkpoint of all four.
+func testRecordProof(t *testing.T, seg, rec int) (RecordProof, Checkpoint, []segformat.RawRecord) {
+	t.Helper()
+
+	var (
+		prev     pmeta.Hash
+		segments []pmeta.SegmentRef
+		records  [][]segformat.RawRecord
+		leaves   [][]pmeta.Hash
+	)
+	for s := 0; s < 4; s++ {
+		segment := pmeta.SegmentRef{
+			StreamID:       "orders",
+			Partition:      3,
+			BaseLSN:        uint64(s * 5),
+			LastLSN:        uint64(s*5 + 4),
+			MinTimestampMS: int64(s * 5),
+			MaxTimestampMS: int64(s*5 + 4),
+			SegmentHash:    uint64(s + 1),
+			TrailerHash:    uint64(s + 101),
+		}
+		var builder RecordsRootBuilder
+		var raw []segformat.RawRecord
+		var segmentLeaves []pmeta.Hash
+		for lsn := segment.BaseLSN; lsn <= segment.LastLSN; lsn++ {
+			record := segformat.RawRecord{
+				TimestampMS: int64(lsn),
+				Headers:     []segformat.Header{{Key: []byte("k"), Value: []byte(fmt.Sprint(lsn))}},
+				Value:       []byte(fmt.Sprintf("value-%d", lsn)),
+			}
+			builder.Add(lsn, record.TimestampMS, record.Headers, record.Value)
+			raw = append(raw, record)
+			segmentLeaves = append(segmentLeaves, RecordLeaf(lsn, record.TimestampMS, LeafDigest(record.Headers, record.Value)))
+		}
+		segment.RecordsRoot = builder.Root()
+		segment = Chain(prev, segment)
+		prev = segment.ChainHash
+		segments = append(segments, segment)
+		records = append(records, raw)
+		leaves = append(leaves, segmentLeaves)
+	}
+
+	segmentLeaves := make([]pmeta.Hash, len(segments))
+	for i, segment := range segments {
+		segmentLeaves[i] = SegmentLeaf(segment.ChainHash)
+	}
+	cp := Checkpoint{StreamID: "orders", Partition: 3, NextLSN: 20, TreeSize: 4, TreeRoot: Root(segmentLeaves)}
+	segmentStep, err := NewStep(segmentLeaves, seg)
+	if err != nil {
+		t.Fatalf("NewStep(segment) error = %v", err)
+	}
+	recordStep, err := NewStep(leaves[seg], rec)
+	if err != nil {
+		t.Fatalf("NewStep(record) error = %v", err)
+	}
+	record := records[seg][rec]
+	return RecordProof{
+		LSN:         segments[seg].BaseLSN + uint64(rec),
+		TimestampMS: record.TimestampMS,
+		Digest:      LeafDigest(record.Headers, record.Value),
+		Record:      recordStep,
+		SegmentProof: SegmentProof{
+			Checkpoint: cp,
+			Segment:    segments[seg],
+			Inclusion:  segmentStep,
+		},
+	}, cp, records[seg]
+}
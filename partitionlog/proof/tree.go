@@ -0,0 +1,208 @@
+package proof
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+// Range is the compact range of an RFC 9162 Merkle tree over its first Size
+// leaves: the roots of the perfect subtrees that cover them, largest first.
+// It is enough to append leaves and to compute the root without the leaves.
+type Range struct {
+	Size  uint64       `json:"size,omitempty"`
+	Peaks []pmeta.Hash `json:"peaks,omitempty"`
+}
+
+// Append returns r with leaf appended. It does not modify r.
+func (r Range) Append(leaf pmeta.Hash) Range {
+	peaks := append(slices.Clip(r.Peaks), leaf)
+	for size := r.Size; size&1 == 1; size >>= 1 {
+		n := len(peaks)
+		peaks = append(peaks[:n-2], nodeHash(peaks[n-2], peaks[n-1]))
+	}
+	return Range{Size: r.Size + 1, Peaks: peaks}
+}
+
+// Root returns the tree hash over the covered leaves, or the zero hash for an
+// empty range.
+func (r Range) Root() pmeta.Hash {
+	if len(r.Peaks) == 0 {
+		return pmeta.Hash{}
+	}
+	root := r.Peaks[len(r.Peaks)-1]
+	for i := len(r.Peaks) - 2; i >= 0; i-- {
+		root = nodeHash(r.Peaks[i], root)
+	}
+	return root
+}
+
+// Validate checks that r has one peak per set bit of Size.
+func (r Range) Validate() error {
+	if len(r.Peaks) != bits.OnesCount64(r.Size) {
+		return fmt.Errorf("%w: range size=%d has %d peaks", ErrInvalidProof, r.Size, len(r.Peaks))
+	}
+	return nil
+}
+
+// Equal reports whether r and other cover the same leaves.
+func (r Range) Equal(other Range) bool {
+	return r.Size == other.Size && slices.Equal(r.Peaks, other.Peaks)
+}
+
+// peak returns the peak that covers exactly leaves [lo, hi).
+func (r Range) peak(lo, hi uint64) (pmeta.Hash, bool) {
+	var pos uint64
+	remaining := r.Size
+	for _, peak := range r.Peaks {
+		width := uint64(1) << (63 - bits.LeadingZeros64(remaining))
+		if pos == lo && pos+width == hi {
+			return peak, true
+		}
+		pos += width
+		remaining -= width
+	}
+	return pmeta.Hash{}, false
+}
+
+// Tree is the part of an RFC 9162 Merkle tree a catalog still holds: Base,
+// the compact range over leaves it no longer keeps, and Leaves, the leaf
+// hashes that follow them. Proofs for leaves at or after Base.Size, and
+// between trees that both end after Base.Size, need nothing else.
+type Tree struct {
+	Base   Range
+	Leaves []pmeta.Hash
+}
+
+// Size returns the number of leaves in t.
+func (t Tree) Size() uint64 {
+	return t.Base.Size + uint64(len(t.Leaves))
+}
+
+// Root returns the tree hash over the first size leaves of t.
+func (t Tree) Root(size uint64) (pmeta.Hash, error) {
+	if size == 0 || size > t.Size() {
+		return pmeta.Hash{}, fmt.Errorf("%w: tree size=%d held=%d", ErrInvalidProof, size, t.Size())
+	}
+	return t.subtree(0, size)
+}
+
+// InclusionStep returns the audit path of leaf index in the tree over the
+// first size leaves of t.
+func (t Tree) InclusionStep(index, size uint64) (Step, error) {
+	if index >= size || size > t.Size() {
+		return Step{}, fmt.Errorf("%w: index=%d size=%d held=%d", ErrInvalidProof, index, size, t.Size())
+	}
+	path, err := t.auditPath(index, 0, size)
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{Index: index, Size: size, Path: path}, nil
+}
+
+// ConsistencyPath returns the RFC 9162 consistency proof that the tree over
+// the first from leaves of t is a prefix of the tree over the first to.
+func (t Tree) ConsistencyPath(from, to uint64) ([]pmeta.Hash, error) {
+	if from > to || to > t.Size() {
+		return nil, fmt.Errorf("%w: consistency from=%d to=%d held=%d", ErrInvalidProof, from, to, t.Size())
+	}
+	if from == 0 {
+		return nil, nil
+	}
+	return t.subproof(from, 0, to, true)
+}
+
+func (t Tree) auditPath(index, lo, hi uint64) ([]pmeta.Hash, error) {
+	if hi-lo <= 1 {
+		return nil, nil
+	}
+	k := lo + largestPowerBelow(hi-lo)
+	var (
+		path    []pmeta.Hash
+		sibling pmeta.Hash
+		err     error
+	)
+	if index < k {
+		if path, err = t.auditPath(index, lo, k); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtree(k, hi)
+	} else {
+		if path, err = t.auditPath(index, k, hi); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtree(lo, k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(path, sibling), nil
+}
+
+// subproof is SUBPROOF from RFC 9162 section 2.1.4.1 over leaves [lo, hi),
+// with m counted from lo.
+func (t Tree) subproof(m, lo, hi uint64, whole bool) ([]pmeta.Hash, error) {
+	if m == hi-lo {
+		if whole {
+			return nil, nil
+		}
+		root, err := t.subtree(lo, hi)
+		if err != nil {
+			return nil, err
+		}
+		return []pmeta.Hash{root}, nil
+	}
+	k := largestPowerBelow(hi - lo)
+	var (
+		path    []pmeta.Hash
+		sibling pmeta.Hash
+		err     error
+	)
+	if m <= k {
+		if path, err = t.subproof(m, lo, lo+k, whole); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtree(lo+k, hi)
+	} else {
+		if path, err = t.subproof(m-k, lo+k, hi, false); err != nil {
+			return nil, err
+		}
+		sibling, err = t.subtree(lo, lo+k)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(path, sibling), nil
+}
+
+// subtree returns the tree hash over leaves [lo, hi). Leaves below Base.Size
+// are reached only through the base peaks.
+func (t Tree) subtree(lo, hi uint64) (pmeta.Hash, error) {
+	base := t.Base.Size
+	if lo >= base {
+		return Root(t.Leaves[lo-base : hi-base]), nil
+	}
+	if peak, ok := t.Base.peak(lo, hi); ok {
+		return peak, nil
+	}
+	if hi-lo == 1 {
+		return pmeta.Hash{}, fmt.Errorf("%w: leaf %d", ErrPruned, lo)
+	}
+	k := lo + largestPowerBelow(hi-lo)
+	left, err := t.subtree(lo, k)
+	if err != nil {
+		return pmeta.Hash{}, err
+	}
+	right, err := t.subtree(k, hi)
+	if err != nil {
+		return pmeta.Hash{}, err
+	}
+	return nodeHash(left, right), nil
+}
+
+// largestPowerBelow returns the largest power of two smaller than n.
+func largestPowerBelow(n uint64) uint64 {
+	return uint64(1) << (63 - bits.LeadingZeros64(n-1))
+}
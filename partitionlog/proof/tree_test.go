@@ -0,0 +1,134 @@
+package proof
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+func TestRangeMatchesRootForEveryTreeSize(t *testing.T) {
+	t.Parallel()
+
+	leaves := testLeaves(70)
+	var r Range
+	if !r.Root().IsZero() {
+		t.Fatalf("empty Range.Root() = %s, want zero", r.Root())
+	}
+	for size := 1; size <= len(leaves); size++ {
+		r = r.Append(leaves[size-1])
+		if err := r.Validate(); err != nil {
+			t.Fatalf("Validate(size=%d) error = %v", size, err)
+		}
+		if got, want := r.Root(), Root(leaves[:size]); got != want {
+			t.Fatalf("Root(size=%d) = %s, want %s", size, got, want)
+		}
+	}
+	bad := r
+	bad.Size++
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("Validate(wrong peaks) error = %v, want %v", err, ErrInvalidProof)
+	}
+}
+
+func TestTreeProvesFromEveryRetainedBase(t *testing.T) {
+	t.Parallel()
+
+	leaves := testLeaves(21)
+	for base := 0; base <= len(leaves); base++ {
+		tree := testTree(leaves, base)
+		for size := uint64(max(base, 1)); size <= uint64(len(leaves)); size++ {
+			root, err := tree.Root(size)
+			if err != nil || root != Root(leaves[:size]) {
+				t.Fatalf("Root(base=%d, size=%d) = %s, %v; want %s", base, size, root, err, Root(leaves[:size]))
+			}
+			for index := uint64(base); index < size; index++ {
+				step, err := tree.InclusionStep(index, size)
+				if err != nil {
+					t.Fatalf("InclusionStep(base=%d, %d, %d) error = %v", base, index, size, err)
+				}
+				if got, err := step.Root(leaves[index]); err != nil || got != root {
+					t.Fatalf("InclusionStep(base=%d, %d, %d) reaches %s, %v; want %s", base, index, size, got, err, root)
+				}
+			}
+			for from := uint64(max(base, 1)); from < size; from++ {
+				path, err := tree.ConsistencyPath(from, size)
+				if err != nil {
+					t.Fatalf("ConsistencyPath(base=%d, %d, %d) error = %v", base, from, size, err)
+				}
+				if !verifyConsistencyPath(from, size, Root(leaves[:from]), root, path) {
+					t.Fatalf("ConsistencyPath(base=%d, %d, %d) does not verify", base, from, size)
+				}
+			}
+		}
+	}
+
+	// With six leaves dropped, leaf 1 sits inside the peak over [0, 4).
+	tree := testTree(leaves, 6)
+	if _, err := tree.InclusionStep(1, uint64(len(leaves))); !errors.Is(err, ErrPruned) {
+		t.Fatalf("InclusionStep(dropped leaf) error = %v, want %v", err, ErrPruned)
+	}
+	if _, err := tree.ConsistencyPath(3, uint64(len(leaves))); !errors.Is(err, ErrPruned) {
+		t.Fatalf("ConsistencyPath(dropped size) error = %v, want %v", err, ErrPruned)
+	}
+}
+
+func TestVerifyConsistencyAndDetectTampering(t *testing.T) {
+	t.Parallel()
+
+	leaves := testLeaves(13)
+	tree := testTree(leaves, 0)
+	checkpoint := func(size uint64) Checkpoint {
+		return Checkpoint{StreamID: "orders", Partition: 3, NextLSN: size * 10, TreeSize: size, TreeRoot: Root(leaves[:size])}
+	}
+	for _, sizes := range [][2]uint64{{1, 13}, {4, 13}, {6, 7}, {8, 8}, {5, 12}} {
+		older, newer := checkpoint(sizes[0]), checkpoint(sizes[1])
+		path, err := tree.ConsistencyPath(older.TreeSize, newer.TreeSize)
+		if err != nil {
+			t.Fatalf("ConsistencyPath(%v) error = %v", sizes, err)
+		}
+		p := ConsistencyProof{Old: older, New: newer, Path: path}
+		if err := VerifyConsistency(older, newer, p); err != nil {
+			t.Fatalf("VerifyConsistency(%v) error = %v", sizes, err)
+		}
+		if len(path) == 0 {
+			continue
+		}
+		forged := p
+		forged.Path = append([]pmeta.Hash(nil), path...)
+		forged.Path[len(path)-1][0] ^= 1
+		if err := VerifyConsistency(older, newer, forged); !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("VerifyConsistency(%v, forged path) error = %v, want %v", sizes, err, ErrInvalidProof)
+		}
+		forked := newer
+		forked.TreeRoot[0] ^= 1
+		p.New = forked
+		if err := VerifyConsistency(older, forked, p); !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("VerifyConsistency(%v, forked root) error = %v, want %v", sizes, err, ErrInvalidProof)
+		}
+	}
+	older, newer := checkpoint(3), checkpoint(9)
+	if err := VerifyConsistency(older, checkpoint(10), ConsistencyProof{Old: older, New: newer}); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("VerifyConsistency(other checkpoint) error = %v, want %v", err, ErrInvalidProof)
+	}
+	if err := VerifyConsistency(newer, older, ConsistencyProof{Old: newer, New: older}); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("VerifyConsistency(shrinking) error = %v, want %v", err, ErrInvalidProof)
+	}
+}
+
+func testLeaves(n int) []pmeta.Hash {
+	leaves := make([]pmeta.Hash, n)
+	for i := range leaves {
+		leaves[i] = SegmentLeaf(pmeta.Hash{byte(i), 0x5a})
+	}
+	return leaves
+}
+
+// testTree keeps leaves from base on and folds the rest into the base range.
+func testTree(leaves []pmeta.Hash, base int) Tree {
+	var r Range
+	for _, leaf := range leaves[:base] {
+		r = r.Append(leaf)
+	}
+	return Tree{Base: r, Leaves: leaves[base:]}
+}
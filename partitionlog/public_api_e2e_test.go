@@ -34,6 +34,15 @@ func TestPublicAPIRetentionAcrossBlobStores(t *testing.T) {
 	}
 }
 
+func TestPublicAPIPartitionDiscoveryAcrossBlobStores(t *testing.T) {
+	for _, tc := range publicAPIStoreCases() {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			runPublicAPIPartitionDiscovery(t, tc.open(t, "partitionlog-discovery-"+tc.name))
+		})
+	}
+}
+
 func TestPublicAPIWriterAndPhysicalReclamationAcrossBlobStores(t *testing.T) {
 	for _, tc := range publicAPIStoreCases() {
 		tc := tc
@@ -223,6 +232,55 @@ func runPublicAPIStaleWriterOrphanScrub(t *testing.T, store reclaimingStore) {
 	}
 }
 
+func runPublicAPIPartitionDiscovery(t *testing.T, store partitionlog.Store) {
+	t.Helper()
+	ctx := context.Background()
+	log, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("partitionlog.Open() error = %v", err)
+	}
+	want := map[uint32]uint64{301: 2, 302: 1, 303: 0}
+	for partition, records := range want {
+		if records == 0 {
+			if _, err := log.InitializePartition(ctx, partitionlog.InitializePartition{Partition: partition}); err != nil {
+				t.Fatalf("InitializePartition(%d) error = %v", partition, err)
+			}
+			continue
+		}
+		w, err := log.OpenWriter(ctx, partitionlog.WriterOptions{Partition: partition, WriterID: [16]byte{3, 0, byte(partition)}})
+		if err != nil {
+			t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+		}
+		for i := range records {
+			if _, err := w.Append(ctx, partitionlog.Record{TimestampMS: int64(i), Value: []byte{byte(i)}}); err != nil {
+				t.Fatalf("Append(%d) error = %v", partition, err)
+			}
+		}
+		if _, err := w.Close(ctx); err != nil {
+			t.Fatalf("Close(%d) error = %v", partition, err)
+		}
+	}
+
+	got := make(map[uint32]uint64)
+	req := partitionlog.ListPartitionsRequest{Limit: 1, IncludeHeads: true}
+	for {
+		page, err := log.ListPartitions(ctx, req)
+		if err != nil {
+			t.Fatalf("ListPartitions() error = %v", err)
+		}
+		for _, info := range page.Partitions {
+			got[info.Partition] = info.Head.NextLSN
+		}
+		if !page.HasMore {
+			break
+		}
+		req.AfterKey = page.NextAfterKey
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("ListPartitions() next LSNs = %v, want %v", got, want)
+	}
+}
+
 func runPublicAPIRetention(t *testing.T, store partitionlog.Store) {
 	t.Helper()
 	ctx := context.Background()
@@ -197,7 +197,8 @@ type Cursor struct {
 }
 
 // Next reads from the current cursor position and advances only when records
-// are returned.
+// are returned. Once the cursor has consumed a sealed partition it returns the
+// final result with ErrEndOfTimeline.
 func (c *Cursor) Next(ctx context.Context) (ReadResult, error) {
 	if c.closed {
 		return ReadResult{}, fmt.Errorf("%w: cursor closed", ErrInvalidRequest)
@@ -215,6 +216,8 @@ func (c *Cursor) Next(ctx context.Context) (ReadResult, error) {
 	}
 	if len(result.Records) > 0 {
 		c.nextLSN = result.NextLSN
+	} else if endOfTimeline(result.Head, c.nextLSN) {
+		return result, ErrEndOfTimeline
 	}
 	return result, nil
 }
@@ -396,7 +399,8 @@ type Tailer struct {
 
 // Next returns available records immediately. If the tailer is at the
 // committed tail, it waits until the Watch observes the partition head advance
-// or ctx is cancelled.
+// or ctx is cancelled. At the tail of a sealed partition it returns
+// ErrEndOfTimeline instead of waiting.
 func (t *Tailer) Next(ctx context.Context) (result ReadResult, err error) {
 	start := time.Now()
 	startLSN := t.nextLSN
@@ -429,6 +433,9 @@ func (t *Tailer) Next(ctx context.Context) (result ReadResult, err error) {
 			t.nextLSN = result.NextLSN
 			return result, nil
 		}
+		if endOfTimeline(result.Head, t.nextLSN) {
+			return result, ErrEndOfTimeline
+		}
 
 		head, generation, ok := t.watch.reader.refresh.snapshot(t.partition)
 		if ok && head.NextLSN > t.nextLSN {
@@ -451,6 +458,12 @@ func (t *Tailer) Close() error {
 	return nil
 }
 
+// endOfTimeline reports whether nextLSN has consumed every record a sealed
+// partition will ever hold.
+func endOfTimeline(head pmeta.PartitionHead, nextLSN uint64) bool {
+	return head.Sealed && nextLSN >= head.NextLSN
+}
+
 func (w *Watch) waitForAdvance(ctx context.Context, partition uint32, generation uint64) error {
 	membershipChanged, err := w.partitionMembership(partition)
 	if err != nil {
@@ -492,6 +505,7 @@ func (w *Watch) partitionMembershipError(partition uint32) error {
 type refreshCoordinator struct {
 	catalog  catalog.Reader
 	policy   RefreshPolicy
+	limiter  *RefreshLimiter
 	observer Observer
 	group    singleflight.Group
 
@@ -524,10 +538,11 @@ type headSnapshot struct {
 	generation uint64
 }
 
-func newRefreshCoordinator(cat catalog.Reader, policy RefreshPolicy, maxCachedPartitionHeads int, observer Observer) *refreshCoordinator {
+func newRefreshCoordinator(cat catalog.Reader, policy RefreshPolicy, maxCachedPartitionHeads int, limiter *RefreshLimiter, observer Observer) *refreshCoordinator {
 	return &refreshCoordinator{
 		catalog:                 cat,
 		policy:                  normalizeRefreshPolicy(policy, RefreshPolicy{}),
+		limiter:                 limiter,
 		observer:                observer,
 		cachedHeads:             make(map[uint32]*cachedPartitionHead),
 		maxCachedPartitionHeads: maxCachedPartitionHeads,
@@ -614,6 +629,11 @@ func (c *refreshCoordinator) refresh(ctx context.Context, partition uint32) (pme
 		}()
 		workCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.policy.RefreshTimeout)
 		defer cancel()
+		if err := c.limiter.acquire(workCtx); err != nil {
+			refreshErr = err
+			return headSnapshot{}, err
+		}
+		defer c.limiter.release()
 		head, err := c.catalog.LoadPartition(workCtx, partition)
 		if err != nil {
 			refreshErr = err
@@ -16,6 +16,10 @@ var (
 	ErrCheckpointInvalid  = errors.New("partitionlog/reader: invalid cursor checkpoint")
 	ErrCheckpointMismatch = errors.New("partitionlog/reader: cursor checkpoint mismatch")
 	ErrCheckpointAhead    = errors.New("partitionlog/reader: cursor checkpoint is ahead of head")
+	ErrEndOfTimeline      = errors.New("partitionlog/reader: end of timeline")
+	// ErrShredded reports a segment whose encryption key was destroyed. Its
+	// records are permanently unreadable; this is not data corruption.
+	ErrShredded = errors.New("partitionlog/reader: segment shredded")
 )
 
 type LSNExpiredError struct {
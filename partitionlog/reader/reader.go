@@ -23,11 +23,15 @@ func New(cat catalog.Reader, store SegmentStore, opts Options) (*Reader, error)
 	if err != nil {
 		return nil, err
 	}
+	cat = shredCatalog{Reader: cat}
+	if normalized.Verifier != nil {
+		cat = verifyingCatalog{Reader: cat, verifier: normalized.Verifier}
+	}
 	return &Reader{
 		catalog: cat,
 		store:   store,
 		opts:    normalized,
-		refresh: newRefreshCoordinator(cat, normalized.Refresh, normalized.MaxCachedPartitionHeads, normalized.Observer),
+		refresh: newRefreshCoordinator(cat, normalized.Refresh, normalized.MaxCachedPartitionHeads, normalized.RefreshLimiter, normalized.Observer),
 		watches: make(map[*Watch]struct{}),
 	}, nil
 }
@@ -59,7 +63,7 @@ func (r *Reader) Close() error {
 		}
 	}
 	r.refresh.close()
-	if r.opts.SegmentCache != nil {
+	if r.opts.SegmentCache != nil && !r.opts.SharedSegmentCache {
 		r.opts.SegmentCache.Clear()
 	}
 	if cache, ok := r.store.(interface{ ClearRangeCache() }); ok {
@@ -500,6 +504,9 @@ func normalizeOptions(opts Options) (Options, error) {
 	if opts.SegmentOptions == (segreader.Options{}) {
 		opts.SegmentOptions = segreader.DefaultOptions()
 	}
+	if opts.Verifier != nil {
+		opts.SegmentOptions.Verifier = opts.Verifier
+	}
 	return opts, nil
 }
 
@@ -531,6 +538,9 @@ func mapSegmentError(err error) error {
 	if errors.Is(err, segreader.ErrStoreRead) {
 		return fmt.Errorf("%w: %w", ErrStoreRead, err)
 	}
+	if errors.Is(err, segreader.ErrKeyDestroyed) {
+		return fmt.Errorf("%w: %w", ErrShredded, err)
+	}
 	if errors.Is(err, segreader.ErrCorruptData) || errors.Is(err, segreader.ErrInvalidSegment) {
 		return fmt.Errorf("%w: %w", ErrCorruptData, err)
 	}
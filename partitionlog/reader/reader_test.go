@@ -6,12 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/segreader"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
 )
 
@@ -235,6 +238,38 @@ func TestConsumeEmptyAndPastHead(t *testing.T) {
 	}
 }
 
+func TestCursorReportsEndOfSealedTimeline(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fixture := newReaderFixture(t)
+	fixture.appendSegment(t, 0, 3)
+	r := fixture.openReader(t, Options{})
+	defer func() { _ = r.Close() }()
+
+	cursor, err := r.Partition(fixture.partition).Cursor(CursorOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("Cursor() error = %v", err)
+	}
+	if result, err := cursor.Next(ctx); err != nil || len(result.Records) != 3 {
+		t.Fatalf("Next() = %d records, %v; want 3 records", len(result.Records), err)
+	}
+	if result, err := cursor.Next(ctx); err != nil || len(result.Records) != 0 {
+		t.Fatalf("Next(open tail) = %d records, %v; want empty", len(result.Records), err)
+	}
+
+	if _, err := fixture.session.(catalog.SealWriterSession).SealPartition(ctx); err != nil {
+		t.Fatalf("SealPartition() error = %v", err)
+	}
+	result, err := cursor.Next(ctx)
+	if !errors.Is(err, ErrEndOfTimeline) {
+		t.Fatalf("Next(sealed tail) error = %v, want %v", err, ErrEndOfTimeline)
+	}
+	if !result.Head.Sealed || result.NextLSN != 3 || cursor.Position() != 3 {
+		t.Fatalf("Next(sealed tail) = %+v position=%d", result, cursor.Position())
+	}
+}
+
 func TestConsumeRejectsExpiredLSN(t *testing.T) {
 	t.Parallel()
 
@@ -324,6 +359,34 @@ func TestConsumeMapsCorruptSegment(t *testing.T) {
 	}
 }
 
+func TestConsumeMapsDestroyedKeyToShredded(t *testing.T) {
+	t.Parallel()
+
+	static, err := segblock.NewStaticKeyProvider("kek-1", map[string][]byte{"kek-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	keys := &shreddableKeyProvider{inner: static}
+	fixture := newReaderFixture(t)
+	fixture.keys = keys
+	segment := fixture.appendSegment(t, 0, 5)
+	segmentOptions := segreader.DefaultOptions()
+	segmentOptions.KeyProvider = keys
+	r := fixture.openReader(t, Options{SegmentOptions: segmentOptions})
+
+	result, err := r.Consume(context.Background(), ConsumeRequest{Partition: segment.Partition, StartLSN: 0, Limit: 5})
+	if err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	assertRecordsEqual(t, result.Records, fixture.records)
+
+	keys.destroyed.Store(true)
+	_, err = r.Consume(context.Background(), ConsumeRequest{Partition: segment.Partition, StartLSN: 0, Limit: 1})
+	if !errors.Is(err, ErrShredded) || errors.Is(err, ErrCorruptData) {
+		t.Fatalf("Consume(shredded) error = %v, want %v only", err, ErrShredded)
+	}
+}
+
 func TestConsumeUsesInitialHeadSnapshot(t *testing.T) {
 	t.Parallel()
 
@@ -670,6 +733,50 @@ func TestSegmentReaderCacheEvictsByEntryLimit(t *testing.T) {
 	}
 }
 
+func TestSegmentReaderCacheKeysProvidersByIdentity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fixture := newReaderFixture(t)
+	segment := fixture.appendSegment(t, 0, 5)
+	store := newCountingSegmentStore(fixture.store)
+	cache := MustNewSegmentReaderCache(8)
+	open := func(keys segblock.KeyProvider) {
+		t.Helper()
+		opts := segreader.DefaultOptions()
+		opts.KeyProvider = keys
+		if _, err := cache.Open(ctx, store, segment, opts); err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+	}
+
+	first, second := &segblock.StaticKeyProvider{}, &segblock.StaticKeyProvider{}
+	open(first)
+	open(first)
+	open(second)
+	if cache.Len() != 2 || store.metadataReads(segment) != 6 {
+		t.Fatalf("cache len = %d metadata reads = %d, want one entry per provider", cache.Len(), store.metadataReads(segment))
+	}
+
+	// A slice-backed provider cannot key a map, so its opens bypass the cache
+	// instead of panicking.
+	open(sliceKeyProvider{"k"})
+	open(sliceKeyProvider{"k"})
+	if cache.Len() != 2 || store.metadataReads(segment) != 12 {
+		t.Fatalf("cache len = %d metadata reads = %d, want uncached opens", cache.Len(), store.metadataReads(segment))
+	}
+}
+
+type sliceKeyProvider []string
+
+func (sliceKeyProvider) WrapKey(context.Context, uint32, []byte) (string, []byte, error) {
+	return "", nil, errors.New("unsupported")
+}
+
+func (sliceKeyProvider) UnwrapKey(context.Context, string, []byte) ([]byte, error) {
+	return nil, errors.New("unsupported")
+}
+
 type readerFixture struct {
 	partition uint32
 	writerID  [16]byte
@@ -679,6 +786,7 @@ type readerFixture struct {
 	objects   map[string][]byte
 	records   []Record
 	nextURI   int
+	keys      segblock.KeyProvider
 }
 
 func newReaderFixture(t *testing.T) *readerFixture {
@@ -726,6 +834,7 @@ func (f *readerFixture) appendSegment(t *testing.T, baseLSN uint64, count int) p
 	opts.SegmentUUID = [16]byte{1, 2, 3, byte(f.nextURI)}
 	opts.WriterTag = f.writerID
 	opts.CreatedUnixMS = 1_776_263_000_000 + int64(f.nextURI)
+	opts.KeyProvider = f.keys
 
 	w, err := segwriter.New(opts, sink)
 	if err != nil {
@@ -762,6 +871,7 @@ func (f *readerFixture) appendSegment(t *testing.T, baseLSN uint64, count int) p
 		HashAlgo:         result.Metadata.HashAlgo,
 		SegmentHash:      result.Metadata.SegmentHash,
 		TrailerHash:      result.Metadata.TrailerHash,
+		KeyID:            result.Metadata.KeyID,
 	}
 	if _, err := f.session.AppendSegment(context.Background(), segment); err != nil {
 		t.Fatalf("AppendSegment() error = %v", err)
@@ -770,6 +880,23 @@ func (f *readerFixture) appendSegment(t *testing.T, baseLSN uint64, count int) p
 	return segment
 }
 
+// shreddableKeyProvider reports every key destroyed once destroyed is set.
+type shreddableKeyProvider struct {
+	inner     *segblock.StaticKeyProvider
+	destroyed atomic.Bool
+}
+
+func (p *shreddableKeyProvider) WrapKey(ctx context.Context, partition uint32, dataKey []byte) (string, []byte, error) {
+	return p.inner.WrapKey(ctx, partition, dataKey)
+}
+
+func (p *shreddableKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if p.destroyed.Load() {
+		return nil, fmt.Errorf("%w: %q", segblock.ErrKeyDestroyed, keyID)
+	}
+	return p.inner.UnwrapKey(ctx, keyID, wrapped)
+}
+
 func (f *readerFixture) storeObject(uri string) []byte {
 	body, ok := f.objects[uri]
 	if !ok {
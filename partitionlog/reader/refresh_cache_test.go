@@ -28,7 +28,7 @@ func TestNormalizeOptionsBoundsCachedPartitionHeads(t *testing.T) {
 
 func TestRefreshCoordinatorBoundsPassiveHeadsByLRU(t *testing.T) {
 	cat := newHeadCacheCatalog()
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 2, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 2, nil, nil)
 
 	loadHead(t, coordinator, 1)
 	loadHead(t, coordinator, 2)
@@ -50,7 +50,7 @@ func TestRefreshCoordinatorBoundsPassiveHeadsByLRU(t *testing.T) {
 
 func TestRefreshCoordinatorPinsWatchedHeadOutsidePassiveLimit(t *testing.T) {
 	cat := newHeadCacheCatalog()
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil, nil)
 	coordinator.watchPartition(7)
 	defer coordinator.unwatchPartition(7)
 
@@ -84,7 +84,7 @@ func TestRefreshCoordinatorPinsWatchedHeadOutsidePassiveLimit(t *testing.T) {
 
 func TestRefreshCoordinatorFinalUnwatchWakesWaiterAndReleasesHead(t *testing.T) {
 	cat := newHeadCacheCatalog()
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil, nil)
 	coordinator.watchPartition(7)
 	loadHead(t, coordinator, 7)
 
@@ -116,7 +116,7 @@ func TestRefreshCoordinatorFinalUnwatchWakesWaiterAndReleasesHead(t *testing.T)
 
 func TestRefreshCoordinatorKeepsStateUntilFinalUnwatch(t *testing.T) {
 	cat := newHeadCacheCatalog()
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, 1, nil, nil)
 	coordinator.watchPartition(7)
 	coordinator.watchPartition(7)
 	loadHead(t, coordinator, 7)
@@ -144,7 +144,7 @@ func TestRefreshCoordinatorKeepsStateUntilFinalUnwatch(t *testing.T) {
 }
 
 func TestRefreshCoordinatorWaitChannelDoesNotCreateState(t *testing.T) {
-	coordinator := newRefreshCoordinator(newHeadCacheCatalog(), RefreshPolicy{}, 1, nil)
+	coordinator := newRefreshCoordinator(newHeadCacheCatalog(), RefreshPolicy{}, 1, nil, nil)
 
 	if _, wait := coordinator.waitChannel(7, 0); wait {
 		t.Fatal("waitChannel() created notification state for an unwatched partition")
@@ -23,7 +23,7 @@ func TestRefreshCallerCancellationDoesNotPoisonJoinedCaller(t *testing.T) {
 		entered: make(chan struct{}),
 		release: make(chan struct{}),
 	}
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, DefaultMaxCachedPartitionHeads, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, DefaultMaxCachedPartitionHeads, nil, nil)
 
 	winnerCtx, cancelWinner := context.WithCancel(context.Background())
 	winnerDone := make(chan refreshCallResult, 1)
@@ -91,7 +91,7 @@ func TestRefreshJoinedCallerCanCancelWithoutStoppingSharedWork(t *testing.T) {
 		entered: make(chan struct{}),
 		release: make(chan struct{}),
 	}
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, DefaultMaxCachedPartitionHeads, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{}, DefaultMaxCachedPartitionHeads, nil, nil)
 
 	winnerDone := make(chan refreshCallResult, 1)
 	go func() {
@@ -155,7 +155,7 @@ func TestRefreshSharedWorkHonorsRefreshTimeout(t *testing.T) {
 		entered: make(chan struct{}),
 		release: make(chan struct{}),
 	}
-	coordinator := newRefreshCoordinator(cat, RefreshPolicy{RefreshTimeout: 20 * time.Millisecond}, DefaultMaxCachedPartitionHeads, nil)
+	coordinator := newRefreshCoordinator(cat, RefreshPolicy{RefreshTimeout: 20 * time.Millisecond}, DefaultMaxCachedPartitionHeads, nil, nil)
 
 	_, err := coordinator.refresh(context.Background(), 7)
 	if !errors.Is(err, context.DeadlineExceeded) {
@@ -171,6 +171,45 @@ type refreshCallResult struct {
 	err  error
 }
 
+func TestRefreshLimiterBoundsLoadsAcrossCoordinators(t *testing.T) {
+	limiter, err := NewRefreshLimiter(1)
+	if err != nil {
+		t.Fatalf("NewRefreshLimiter() error = %v", err)
+	}
+	blocked := &blockingRefreshCatalog{
+		head:    pmeta.PartitionHead{Partition: 1, WriterEpoch: 1, NextLSN: 1},
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	first := newRefreshCoordinator(blocked, RefreshPolicy{}, DefaultMaxCachedPartitionHeads, limiter, nil)
+	second := newRefreshCoordinator(newHeadCacheCatalog(), RefreshPolicy{RefreshTimeout: 20 * time.Millisecond}, DefaultMaxCachedPartitionHeads, limiter, nil)
+
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := first.refresh(context.Background(), 1)
+		firstDone <- err
+	}()
+	select {
+	case <-blocked.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for first catalog refresh")
+	}
+
+	if _, err := second.refresh(context.Background(), 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second refresh error = %v, want %v while limiter is held", err, context.DeadlineExceeded)
+	}
+	close(blocked.release)
+	if err := <-firstDone; err != nil {
+		t.Fatalf("first refresh error = %v", err)
+	}
+	if _, err := second.refresh(context.Background(), 2); err != nil {
+		t.Fatalf("second refresh after release error = %v", err)
+	}
+	if _, err := NewRefreshLimiter(0); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("NewRefreshLimiter(0) error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
 type blockingRefreshCatalog struct {
 	head pmeta.PartitionHead
 
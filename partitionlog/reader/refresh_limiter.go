@@ -0,0 +1,46 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+)
+
+// RefreshLimiter bounds concurrent catalog head loads across every Reader that
+// shares it. A nil limiter does not bound loads.
+type RefreshLimiter struct {
+	slots chan struct{}
+}
+
+func NewRefreshLimiter(maxConcurrent int) (*RefreshLimiter, error) {
+	if maxConcurrent <= 0 {
+		return nil, fmt.Errorf("%w: refresh limiter max_concurrent=%d", ErrInvalidOptions, maxConcurrent)
+	}
+	return &RefreshLimiter{slots: make(chan struct{}, maxConcurrent)}, nil
+}
+
+// MaxConcurrent returns the configured number of concurrent head loads.
+func (l *RefreshLimiter) MaxConcurrent() int {
+	if l == nil {
+		return 0
+	}
+	return cap(l.slots)
+}
+
+func (l *RefreshLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *RefreshLimiter) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
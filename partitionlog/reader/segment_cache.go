@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
@@ -15,6 +16,12 @@ import (
 // It avoids repeatedly fetching/parsing the trailer, file preamble, and block
 // index for hot segments. Segment objects are immutable, so entries do not need
 // invalidation; eviction is by entry count.
+//
+// Entries are keyed by the segment ref, the scalar open options, and the
+// identity of each provider in the options, so readers with different key
+// providers, dictionaries, or verifiers never share an opened segment. An open
+// whose provider value cannot key a map, such as a func or a struct holding a
+// slice, bypasses the cache.
 type SegmentReaderCache struct {
 	mu         sync.Mutex
 	maxEntries int
@@ -24,8 +31,33 @@ type SegmentReaderCache struct {
 }
 
 type segmentCacheKey struct {
-	Ref  pmeta.SegmentRef
-	Opts segreader.Options
+	Ref                 pmeta.SegmentRef
+	ValidateSegmentHash bool
+	MaxBlockBytes       uint64
+	MaxIndexBytes       uint64
+	KeyProvider         any
+	Dictionaries        any
+	Verifier            any
+}
+
+// newSegmentCacheKey reports false when a provider in opts is not comparable;
+// using it in a map key would panic.
+func newSegmentCacheKey(ref pmeta.SegmentRef, opts segreader.Options) (segmentCacheKey, bool) {
+	key := segmentCacheKey{
+		Ref:                 ref,
+		ValidateSegmentHash: opts.ValidateSegmentHash,
+		MaxBlockBytes:       opts.MaxBlockBytes,
+		MaxIndexBytes:       opts.MaxIndexBytes,
+		KeyProvider:         opts.KeyProvider,
+		Dictionaries:        opts.Dictionaries,
+		Verifier:            opts.Verifier,
+	}
+	for _, provider := range []any{key.KeyProvider, key.Dictionaries, key.Verifier} {
+		if provider != nil && !reflect.ValueOf(provider).Comparable() {
+			return segmentCacheKey{}, false
+		}
+	}
+	return key, true
 }
 
 type segmentCacheEntry struct {
@@ -66,7 +98,10 @@ func (c *SegmentReaderCache) Open(ctx context.Context, store segreader.SegmentSt
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	key := segmentCacheKey{Ref: ref, Opts: opts}
+	key, ok := newSegmentCacheKey(ref, opts)
+	if !ok {
+		return segreader.Open(ctx, store, ref, opts)
+	}
 	if reader, ok := c.get(key); ok {
 		return reader, nil
 	}
@@ -0,0 +1,43 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+// shredCatalog reports the tombstone of a crypto-shredded partition as
+// ErrShredded, the error a segment open reports once its key is destroyed.
+type shredCatalog struct {
+	catalog.Reader
+}
+
+func (c shredCatalog) LoadPartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, error) {
+	head, err := c.Reader.LoadPartition(ctx, partition)
+	return head, mapCatalogError(err)
+}
+
+func (c shredCatalog) FindSegment(ctx context.Context, partition uint32, lsn uint64) (pmeta.SegmentRef, bool, error) {
+	segment, found, err := c.Reader.FindSegment(ctx, partition, lsn)
+	return segment, found, mapCatalogError(err)
+}
+
+func (c shredCatalog) LookupTimestamp(ctx context.Context, req catalog.TimestampLookupRequest) (catalog.TimestampLookupResult, error) {
+	result, err := c.Reader.LookupTimestamp(ctx, req)
+	return result, mapCatalogError(err)
+}
+
+func (c shredCatalog) ListSegments(ctx context.Context, req catalog.ListSegmentsRequest) (pmeta.SegmentPage, error) {
+	page, err := c.Reader.ListSegments(ctx, req)
+	return page, mapCatalogError(err)
+}
+
+func mapCatalogError(err error) error {
+	if err != nil && errors.Is(err, catalog.ErrPartitionShredded) {
+		return fmt.Errorf("%w: %w", ErrShredded, err)
+	}
+	return err
+}
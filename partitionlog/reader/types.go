@@ -8,6 +8,7 @@ import (
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segreader"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 const (
@@ -22,11 +23,20 @@ type Options struct {
 	MaxRecordsPerBatch      int
 	MaxCachedPartitionHeads int
 	SegmentOptions          segreader.Options
-	// SegmentCache is cleared by Reader.Close. Do not share it with a Reader
-	// whose lifecycle is independent.
+	// SegmentCache is cleared by Reader.Close unless SharedSegmentCache is set.
+	// Do not share it with a Reader whose lifecycle is independent.
 	SegmentCache *SegmentReaderCache
-	Refresh      RefreshPolicy
-	Observer     Observer
+	// SharedSegmentCache leaves SegmentCache intact on Close. Set it when the
+	// cache is owned by a caller that shares it across Readers.
+	SharedSegmentCache bool
+	// RefreshLimiter optionally bounds catalog head loads across every Reader
+	// that shares it, in addition to this Reader's RefreshPolicy.
+	RefreshLimiter *RefreshLimiter
+	Refresh        RefreshPolicy
+	Observer       Observer
+	// Verifier, when set, requires every partition head and segment ref to
+	// carry a valid writer signature. It overrides SegmentOptions.Verifier.
+	Verifier signing.Verifier
 }
 
 type Reader struct {
@@ -48,6 +58,12 @@ type Record struct {
 	Value       []byte
 }
 
+// Redacted reports whether the record is a redaction tombstone and returns
+// the redaction reason.
+func (r Record) Redacted() (string, bool) {
+	return segformat.RedactionReason(r.Headers, r.Value)
+}
+
 type MetricName string
 
 const (
@@ -0,0 +1,38 @@
+package reader
+
+import (
+	"context"
+
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+// verifyingCatalog rejects partition heads that are not signed by a trusted
+// writer key. Segment refs are verified by segreader when they are opened.
+type verifyingCatalog struct {
+	catalog.Reader
+	verifier signing.Verifier
+}
+
+func (c verifyingCatalog) LoadPartition(ctx context.Context, partition uint32) (pmeta.PartitionHead, error) {
+	head, err := c.Reader.LoadPartition(ctx, partition)
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	if err := signing.VerifyHead(c.verifier, head); err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	return head, nil
+}
+
+func (c verifyingCatalog) LookupTimestamp(ctx context.Context, req catalog.TimestampLookupRequest) (catalog.TimestampLookupResult, error) {
+	result, err := c.Reader.LookupTimestamp(ctx, req)
+	if err != nil {
+		return catalog.TimestampLookupResult{}, err
+	}
+	if err := signing.VerifyHead(c.verifier, result.Head); err != nil {
+		return catalog.TimestampLookupResult{}, err
+	}
+	return result, nil
+}
@@ -71,4 +71,5 @@ var (
 	ErrCheckpointInvalid   = plreader.ErrCheckpointInvalid
 	ErrCheckpointMismatch  = plreader.ErrCheckpointMismatch
 	ErrCheckpointAhead     = plreader.ErrCheckpointAhead
+	ErrEndOfTimeline       = plreader.ErrEndOfTimeline
 )
@@ -0,0 +1,480 @@
+package retention
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+)
+
+// Controller drives retention for one Log. RunOnce and Run must not be
+// called concurrently; Status may be called from any goroutine.
+type Controller struct {
+	log       *partitionlog.Log
+	opts      Options
+	scheduler *lifecycle.Scheduler
+
+	run sync.Mutex
+
+	mu         sync.Mutex
+	partitions map[uint32]*partitionState
+	lastPass   PassResult
+	lastErr    error
+}
+
+// partitionState tracks one managed partition across passes. lastNextLSN and
+// lastEpoch detect owner activity; controller writers update them so their
+// own epoch bumps do not count as activity.
+type partitionState struct {
+	status      PartitionStatus
+	observed    bool
+	lastNextLSN uint64
+	lastEpoch   uint64
+	lastChange  time.Time
+}
+
+func New(log *partitionlog.Log, opts Options) (*Controller, error) {
+	if log == nil {
+		return nil, fmt.Errorf("%w: nil log", ErrInvalidOptions)
+	}
+	if err := opts.Stream.validate(); err != nil {
+		return nil, fmt.Errorf("%w: stream: %w", ErrInvalidPolicy, err)
+	}
+	for partition, policy := range opts.Partitions {
+		if err := policy.validate(); err != nil {
+			return nil, fmt.Errorf("%w: partition=%d: %w", ErrInvalidPolicy, partition, err)
+		}
+	}
+	if opts.Interval < 0 {
+		return nil, fmt.Errorf("%w: negative interval", ErrInvalidOptions)
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.IdleAfter == 0 {
+		opts.IdleAfter = DefaultIdleAfter
+	}
+	if opts.ListLimit < 0 {
+		return nil, fmt.Errorf("%w: negative list limit", ErrInvalidOptions)
+	}
+	if opts.ListLimit == 0 {
+		opts.ListLimit = DefaultListLimit
+	}
+	if opts.NewWriterID == nil {
+		opts.NewWriterID = randomWriterID
+	}
+	if opts.Clock == nil {
+		opts.Clock = partitionlog.SystemClock{}
+	}
+	opts.Partitions = cloneOverrides(opts.Partitions)
+
+	c := &Controller{log: log, opts: opts, partitions: make(map[uint32]*partitionState)}
+	if opts.Reclaimer != nil {
+		schedulerOpts := opts.Scheduler
+		observer := schedulerOpts.Observer
+		schedulerOpts.Observer = lifecycle.SchedulerObserverFunc(func(event lifecycle.SchedulerEvent) {
+			c.observeReclaim(event)
+			if observer != nil {
+				observer.Observe(event)
+			}
+		})
+		scheduler, err := lifecycle.NewScheduler(opts.Reclaimer, schedulerOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+		}
+		c.scheduler = scheduler
+	}
+	return c, nil
+}
+
+// Run calls RunOnce every Interval until ctx is canceled. A failed pass is
+// recorded for LastPass and does not stop the loop.
+func (c *Controller) Run(ctx context.Context) error {
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		timer := c.opts.Clock.NewTimer(c.opts.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// RunOnce makes one pass over every managed partition: it stores a new
+// request when the policy changed under a pending request or applying the
+// policy to the head would retire history, applies pending requests of idle
+// partitions whose writer closed, and then runs reclaim. Per-partition failures are reported through Status; the
+// returned error covers partition discovery and reclaim scheduling.
+func (c *Controller) RunOnce(ctx context.Context) (result PassResult, err error) {
+	c.run.Lock()
+	defer c.run.Unlock()
+	defer func() {
+		result.Finished = c.opts.Clock.Now()
+		c.mu.Lock()
+		c.lastPass, c.lastErr = result, err
+		c.mu.Unlock()
+	}()
+
+	policies, err := c.managedPartitions(ctx)
+	if err != nil {
+		return PassResult{}, err
+	}
+	var reclaim []lifecycle.Task
+	for _, partition := range sortedPartitions(policies) {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		outcome, err := c.passPartition(ctx, partition, policies[partition])
+		if errors.Is(err, partitionlog.ErrPartitionDeleted) {
+			c.forget(partition)
+			continue
+		}
+		result.Partitions++
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		if outcome.requested {
+			result.Requested++
+		}
+		if outcome.applied {
+			result.Applied++
+		}
+		if outcome.pending {
+			result.Pending++
+		}
+		reclaim = append(reclaim, lifecycle.Task{Partition: partition, Operation: lifecycle.OperationReclaim})
+	}
+	if c.scheduler == nil || len(reclaim) == 0 {
+		return result, nil
+	}
+	result.Reclaim, err = c.scheduler.Run(ctx, reclaim)
+	if err != nil && ctx.Err() != nil {
+		return result, err
+	}
+	// Reclaim task failures are already recorded per partition.
+	return result, nil
+}
+
+// Status returns the latest status of one managed partition.
+func (c *Controller) Status(partition uint32) (PartitionStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.partitions[partition]
+	if !ok {
+		return PartitionStatus{}, false
+	}
+	return state.status, true
+}
+
+// Statuses returns the latest status of every managed partition in partition
+// order.
+func (c *Controller) Statuses() []PartitionStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	statuses := make([]PartitionStatus, 0, len(c.partitions))
+	for _, state := range c.partitions {
+		statuses = append(statuses, state.status)
+	}
+	slices.SortFunc(statuses, func(a, b PartitionStatus) int {
+		return cmp.Compare(a.Partition, b.Partition)
+	})
+	return statuses
+}
+
+// LastPass returns the result and error of the latest RunOnce.
+func (c *Controller) LastPass() (PassResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastPass, c.lastErr
+}
+
+func (c *Controller) managedPartitions(ctx context.Context) (map[uint32]Policy, error) {
+	policies := make(map[uint32]Policy)
+	if c.opts.Stream.enabled() {
+		req := partitionlog.ListPartitionsRequest{Limit: c.opts.ListLimit}
+		for {
+			page, err := c.log.ListPartitions(ctx, req)
+			if err != nil {
+				return nil, fmt.Errorf("retention: list partitions: %w", err)
+			}
+			for _, info := range page.Partitions {
+				policies[info.Partition] = c.opts.Stream
+			}
+			if !page.HasMore {
+				break
+			}
+			req.AfterKey = page.NextAfterKey
+		}
+	}
+	for partition, policy := range c.opts.Partitions {
+		if policy.enabled() {
+			policies[partition] = policy
+		} else {
+			delete(policies, partition)
+		}
+	}
+	return policies, nil
+}
+
+type partitionOutcome struct {
+	requested bool
+	applied   bool
+	pending   bool
+}
+
+func (c *Controller) passPartition(ctx context.Context, partition uint32, policy Policy) (partitionOutcome, error) {
+	now := c.opts.Clock.Now()
+	state := c.state(partition)
+	status := state.status
+	status.Policy = policy
+	status.LastPass = now
+	status.Err = nil
+	status.Pending = false
+
+	outcome, err := c.reconcile(ctx, partition, policy, state, &status, now)
+	if err != nil {
+		status.Err = fmt.Errorf("retention: partition=%d: %w", partition, err)
+	}
+	c.mu.Lock()
+	state.status = status
+	c.mu.Unlock()
+	return outcome, err
+}
+
+func (c *Controller) reconcile(ctx context.Context, partition uint32, policy Policy, state *partitionState, status *PartitionStatus, now time.Time) (partitionOutcome, error) {
+	var outcome partitionOutcome
+	head, err := c.log.LoadPartition(ctx, partition)
+	if err != nil {
+		return outcome, err
+	}
+	status.Head = head
+	if !state.observed || head.NextLSN != state.lastNextLSN || head.WriterEpoch != state.lastEpoch {
+		state.observed = true
+		state.lastNextLSN, state.lastEpoch = head.NextLSN, head.WriterEpoch
+		state.lastChange = now
+	}
+	if head.Sealed || (head.NextLSN == 0 && head.WriterEpoch == 0) {
+		// Nothing to retire yet, or no writer can ever apply a request.
+		return outcome, nil
+	}
+
+	stored, found, err := c.log.LoadRetentionRequest(ctx, partition)
+	if err != nil {
+		return outcome, err
+	}
+	desired := desiredRequest(partition, policy, stored, found, now, c.opts.Interval)
+	if desired.BeforeLSN == 0 && desired.BeforeTimestampMS == 0 && desired.MaxRetainedBytes == 0 {
+		// An age cutoff before the Unix epoch has nothing to retire.
+		return outcome, nil
+	}
+	applied := found && head.AppliedRetentionVersion >= stored.PolicyVersion
+	var request bool
+	if found && !applied {
+		// A pending request waits for its apply. Only a changed policy
+		// replaces it; an age cutoff that merely advanced waits its turn.
+		request = !sameRulesExceptAge(stored, desired)
+	} else if request, err = c.wouldRetire(ctx, partition, head, desired); err != nil {
+		return outcome, err
+	}
+	if request {
+		desired.PolicyVersion = stored.PolicyVersion + 1
+		stored, err = c.log.RequestRetention(ctx, desired)
+		if err != nil {
+			return outcome, err
+		}
+		found, applied = true, false
+		outcome.requested = true
+		status.AppliedBy = AppliedNone
+	}
+	if !found {
+		return outcome, nil
+	}
+	status.Request = stored
+	if applied {
+		if status.AppliedBy == AppliedNone {
+			status.AppliedBy = AppliedByOwner
+		}
+		return outcome, nil
+	}
+
+	if c.opts.IdleAfter < 0 || now.Sub(state.lastChange) < c.opts.IdleAfter {
+		status.Pending = true
+		outcome.pending = true
+		return outcome, nil
+	}
+	if c.opts.Signer == nil && head.SignerKeyID != "" {
+		status.Pending = true
+		outcome.pending = true
+		return outcome, fmt.Errorf("%w: apply idle partition", partitionlog.ErrSignerRequired)
+	}
+	snapshot, err := c.applyIdle(ctx, partition)
+	if errors.Is(err, partitionlog.ErrWriterActive) {
+		// The owner is still open; it applies the request itself.
+		status.Pending = true
+		outcome.pending = true
+		return outcome, nil
+	}
+	if err != nil {
+		return outcome, err
+	}
+	status.Head = snapshot.Head
+	state.lastNextLSN, state.lastEpoch = snapshot.Head.NextLSN, snapshot.Head.WriterEpoch
+	if snapshot.Head.AppliedRetentionVersion >= stored.PolicyVersion {
+		status.AppliedBy = AppliedByController
+		outcome.applied = true
+	} else {
+		// A newer request raced in after our load; the next pass applies it.
+		status.Pending = true
+		outcome.pending = true
+	}
+	return outcome, nil
+}
+
+// applyIdle applies the pending request through a short-lived idle-only
+// writer. It fails with ErrWriterActive instead of fencing out an owner that
+// has not closed, and closing the writer releases the fence again.
+func (c *Controller) applyIdle(ctx context.Context, partition uint32) (partitionlog.Snapshot, error) {
+	writerID, err := c.opts.NewWriterID()
+	if err != nil {
+		return partitionlog.Snapshot{}, fmt.Errorf("writer id: %w", err)
+	}
+	w, err := c.log.OpenWriter(ctx, partitionlog.WriterOptions{Partition: partition, WriterID: writerID, Signer: c.opts.Signer, IdleOnly: true})
+	if err != nil {
+		return partitionlog.Snapshot{}, err
+	}
+	if _, err := w.ApplyRetention(ctx); err != nil {
+		return partitionlog.Snapshot{}, errors.Join(err, w.Abort(ctx))
+	}
+	return w.Close(ctx)
+}
+
+func (c *Controller) observeReclaim(event lifecycle.SchedulerEvent) {
+	if !event.Final || event.Task.Operation != lifecycle.OperationReclaim {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.partitions[event.Task.Partition]
+	if !ok {
+		return
+	}
+	state.status.Reclaim = ReclaimStatus{
+		Ran:      true,
+		Result:   event.Result,
+		Err:      event.Err,
+		Deferred: event.Deferred,
+		Attempts: event.Attempt,
+	}
+}
+
+func (c *Controller) state(partition uint32) *partitionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state, ok := c.partitions[partition]
+	if !ok {
+		state = &partitionState{status: PartitionStatus{Partition: partition}}
+		c.partitions[partition] = state
+	}
+	return state
+}
+
+func (c *Controller) forget(partition uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.partitions, partition)
+}
+
+// desiredRequest derives the request for policy at now. The age cutoff is
+// rounded down to a multiple of interval so every pass within one interval
+// derives the same request. Rules never move behind the stored request, so a
+// shortened policy cannot regress it.
+func desiredRequest(partition uint32, policy Policy, stored partitionlog.RetentionRequestState, found bool, now time.Time, interval time.Duration) partitionlog.RetentionRequest {
+	desired := partitionlog.RetentionRequest{
+		Partition:          partition,
+		BeforeLSN:          policy.LSNFloor,
+		MaxRetainedBytes:   policy.MaxBytes,
+		MinRetainedRecords: policy.MinRecords,
+	}
+	if policy.MaxAge > 0 {
+		cutoff := max(now.Add(-policy.MaxAge).UnixMilli(), 0)
+		desired.BeforeTimestampMS = cutoff - cutoff%max(interval.Milliseconds(), 1)
+	}
+	if !found {
+		return desired
+	}
+	if desired.BeforeLSN > 0 {
+		desired.BeforeLSN = max(desired.BeforeLSN, stored.BeforeLSN)
+	}
+	if desired.BeforeTimestampMS > 0 {
+		desired.BeforeTimestampMS = max(desired.BeforeTimestampMS, stored.BeforeTimestampMS)
+	}
+	return desired
+}
+
+func sameRulesExceptAge(stored partitionlog.RetentionRequestState, desired partitionlog.RetentionRequest) bool {
+	return stored.BeforeLSN == desired.BeforeLSN &&
+		(stored.BeforeTimestampMS > 0) == (desired.BeforeTimestampMS > 0) &&
+		stored.MaxRetainedBytes == desired.MaxRetainedBytes &&
+		stored.MinRetainedRecords == desired.MinRetainedRecords
+}
+
+// wouldRetire reports whether applying desired to head retires any history,
+// for example because appends grew it past MaxBytes or its oldest segment
+// aged past the cutoff. MinRecords may leave nothing to retire at all.
+func (c *Controller) wouldRetire(ctx context.Context, partition uint32, head partitionlog.PartitionHead, desired partitionlog.RetentionRequest) (bool, error) {
+	floor := max(head.OldestLSN, head.AppliedRetentionLSN)
+	limit := head.NextLSN - min(desired.MinRetainedRecords, head.NextLSN)
+	switch {
+	case limit <= floor:
+		return false, nil
+	case desired.BeforeLSN > floor:
+		return true, nil
+	case desired.MaxRetainedBytes > 0 && head.RetainedBytes > desired.MaxRetainedBytes:
+		return true, nil
+	case desired.BeforeTimestampMS == 0:
+		return false, nil
+	}
+	oldest, found, err := c.log.FindSegment(ctx, partition, head.OldestLSN)
+	if err != nil {
+		return false, err
+	}
+	return found && oldest.MaxTimestampMS < desired.BeforeTimestampMS, nil
+}
+
+func sortedPartitions(policies map[uint32]Policy) []uint32 {
+	partitions := make([]uint32, 0, len(policies))
+	for partition := range policies {
+		partitions = append(partitions, partition)
+	}
+	slices.Sort(partitions)
+	return partitions
+}
+
+func cloneOverrides(overrides map[uint32]Policy) map[uint32]Policy {
+	if len(overrides) == 0 {
+		return nil
+	}
+	cloned := make(map[uint32]Policy, len(overrides))
+	for partition, policy := range overrides {
+		cloned[partition] = policy
+	}
+	return cloned
+}
+
+func randomWriterID() ([16]byte, error) {
+	var id [16]byte
+	_, err := rand.Read(id[:])
+	return id, err
+}
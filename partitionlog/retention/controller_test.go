@@ -0,0 +1,393 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+	"github.com/ankur-anand/unijord/partitionlog/memstore"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+)
+
+func TestControllerAppliesIdlePartitionsAndLeavesActiveOnesToOwners(t *testing.T) {
+	ctx := context.Background()
+	now := time.UnixMilli(1_800_000_000_000).UTC()
+	clock := partitionlog.ClockFunc(func() time.Time { return now })
+	store, err := memstore.New(memstore.Options{StreamID: "orders"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Clock: clock})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	reclaimer, err := store.NewReclaimer(lifecycle.Options{})
+	if err != nil {
+		t.Fatalf("NewReclaimer() error = %v", err)
+	}
+
+	idle := openTestWriter(t, log, 1, 1)
+	appendRecords(t, idle, 0, 6)
+	if _, err := idle.Close(ctx); err != nil {
+		t.Fatalf("Close(idle) error = %v", err)
+	}
+	active := openTestWriter(t, log, 2, 2)
+	appendRecords(t, active, 0, 6)
+
+	controller, err := New(log, Options{
+		Stream:    Policy{LSNFloor: 4, MinRecords: 3},
+		IdleAfter: time.Minute,
+		Reclaimer: reclaimer,
+		// Retired segments wait out the reclaimer's delete delay, so every
+		// reclaim task would otherwise spin to the default pass limit.
+		Scheduler: lifecycle.SchedulerOptions{MaxPassesPerTask: 2},
+		Clock:     clock,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	first, err := controller.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce(first) error = %v", err)
+	}
+	if first.Partitions != 2 || first.Requested != 2 || first.Pending != 2 || first.Applied != 0 {
+		t.Fatalf("first pass = %+v, want two pending requests", first)
+	}
+
+	now = now.Add(2 * time.Minute)
+	appendRecords(t, active, 6, 1)
+	second, err := controller.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce(second) error = %v", err)
+	}
+	if second.Requested != 0 || second.Applied != 1 || second.Pending != 1 {
+		t.Fatalf("second pass = %+v, want idle apply and active pending", second)
+	}
+	status, ok := controller.Status(1)
+	if !ok || status.AppliedBy != AppliedByController || status.Pending || status.Err != nil {
+		t.Fatalf("idle status = %+v, %v", status, ok)
+	}
+	if status.Request.PolicyVersion != 1 || status.Head.AppliedRetentionLSN != 3 || status.Head.OldestLSN != 3 {
+		t.Fatalf("idle status = %+v, want MinRecords to cap the floor at 3", status)
+	}
+	if !status.Reclaim.Ran || status.Reclaim.Err != nil {
+		t.Fatalf("idle reclaim = %+v", status.Reclaim)
+	}
+	status, _ = controller.Status(2)
+	if !status.Pending || status.AppliedBy != AppliedNone || status.Head.WriterEpoch != 1 {
+		t.Fatalf("active status = %+v, want request left to the owner", status)
+	}
+
+	if _, err := active.ApplyRetention(ctx); err != nil {
+		t.Fatalf("ApplyRetention(active) error = %v", err)
+	}
+	third, err := controller.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce(third) error = %v", err)
+	}
+	if third.Pending != 0 || third.Applied != 0 {
+		t.Fatalf("third pass = %+v", third)
+	}
+	status, _ = controller.Status(2)
+	if status.Pending || status.AppliedBy != AppliedByOwner || status.Head.OldestLSN != 4 {
+		t.Fatalf("owner status = %+v", status)
+	}
+	if _, err := active.Flush(ctx); err != nil {
+		t.Fatalf("active writer fenced by controller: %v", err)
+	}
+
+	// Appends move NextLSN past the floor's MinRecords cap on the idle
+	// partition, so the applied head violates its policy again.
+	late := openTestWriter(t, log, 1, 3)
+	appendRecords(t, late, 6, 4)
+	if _, err := late.Close(ctx); err != nil {
+		t.Fatalf("Close(late) error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := controller.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(fourth) error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := controller.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(fifth) error = %v", err)
+	}
+	status, _ = controller.Status(1)
+	if status.Request.PolicyVersion != 2 || status.AppliedBy != AppliedByController || status.Head.OldestLSN != 4 {
+		t.Fatalf("re-requested status = %+v", status)
+	}
+}
+
+func TestControllerAgePolicyAdvancesWithClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.UnixMilli(10_000).UTC()
+	clock := partitionlog.ClockFunc(func() time.Time { return now })
+	store, err := memstore.New(memstore.Options{StreamID: "orders"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Clock: clock})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	w := openTestWriter(t, log, 1, 1)
+	appendRecords(t, w, 0, 4)
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	apply := func(id byte) {
+		t.Helper()
+		owner := openTestWriter(t, log, 1, id)
+		if _, err := owner.ApplyRetention(ctx); err != nil {
+			t.Fatalf("ApplyRetention() error = %v", err)
+		}
+		if _, err := owner.Close(ctx); err != nil {
+			t.Fatalf("Close(owner) error = %v", err)
+		}
+	}
+
+	controller, err := New(log, Options{
+		Partitions: map[uint32]Policy{1: {MaxAge: 9 * time.Second}, 2: {}},
+		Interval:   2 * time.Second,
+		IdleAfter:  -1,
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := controller.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	// The cutoff 10s - 9s rounds down to the 2s interval, and no record is
+	// older than 0, so no request is stored.
+	status, ok := controller.Status(1)
+	if !ok || status.Pending || status.Request.PolicyVersion != 0 {
+		t.Fatalf("status = %+v, %v, want no request while nothing is old enough", status, ok)
+	}
+	if _, ok := controller.Status(2); ok {
+		t.Fatal("zero policy partition is managed")
+	}
+
+	now = now.Add(2 * time.Second)
+	result, err := controller.RunOnce(ctx)
+	if err != nil {
+		t.Fatalf("RunOnce(later) error = %v", err)
+	}
+	status, _ = controller.Status(1)
+	if result.Requested != 1 || status.Request.BeforeTimestampMS != 2_000 || status.Request.PolicyVersion != 1 || !status.Pending {
+		t.Fatalf("advanced status = %+v, pass = %+v", status, result)
+	}
+
+	// A pending request waits for its apply while the cutoff advances.
+	now = now.Add(2 * time.Second)
+	if result, err = controller.RunOnce(ctx); err != nil || result.Requested != 0 {
+		t.Fatalf("RunOnce(pending) = %+v err=%v, want no new request", result, err)
+	}
+	if status.Head.WriterEpoch != 1 {
+		t.Fatalf("head = %+v, want no controller writer when IdleAfter is negative", status.Head)
+	}
+
+	apply(2)
+	if result, err = controller.RunOnce(ctx); err != nil || result.Requested != 1 {
+		t.Fatalf("RunOnce(applied) = %+v err=%v, want the advanced cutoff requested", result, err)
+	}
+	status, _ = controller.Status(1)
+	if status.Request.BeforeTimestampMS != 4_000 || status.Request.PolicyVersion != 2 {
+		t.Fatalf("re-requested status = %+v", status)
+	}
+
+	// Once applied, passes within the same interval store nothing.
+	apply(3)
+	now = now.Add(time.Second)
+	for pass := range 3 {
+		if result, err = controller.RunOnce(ctx); err != nil || result.Requested != 0 {
+			t.Fatalf("RunOnce(stable %d) = %+v err=%v, want no new request", pass, result, err)
+		}
+	}
+	status, _ = controller.Status(1)
+	if status.Request.PolicyVersion != 2 || status.Pending || status.Head.OldestLSN != 4 {
+		t.Fatalf("stable status = %+v", status)
+	}
+}
+
+func TestControllerNeverFencesOpenWriter(t *testing.T) {
+	ctx := context.Background()
+	now := time.UnixMilli(1_800_000_000_000).UTC()
+	clock := partitionlog.ClockFunc(func() time.Time { return now })
+	store, err := memstore.New(memstore.Options{StreamID: "orders"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Clock: clock})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	owner := openTestWriter(t, log, 1, 1)
+	appendRecords(t, owner, 0, 4)
+
+	controller, err := New(log, Options{
+		Partitions: map[uint32]Policy{1: {LSNFloor: 2}},
+		IdleAfter:  time.Minute,
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for pass := range 3 {
+		if _, err := controller.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce(%d) error = %v", pass, err)
+		}
+		now = now.Add(10 * time.Minute)
+	}
+	status, _ := controller.Status(1)
+	if !status.Pending || status.Err != nil || status.Head.WriterEpoch != 1 {
+		t.Fatalf("status = %+v, want request left to the open owner", status)
+	}
+	appendRecords(t, owner, 4, 1)
+
+	if _, err := owner.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := controller.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(observe close) error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := controller.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(closed) error = %v", err)
+	}
+	status, _ = controller.Status(1)
+	if status.AppliedBy != AppliedByController || status.Head.OldestLSN != 2 {
+		t.Fatalf("status = %+v, want controller apply after the owner closed", status)
+	}
+}
+
+func TestControllerSignsIdleAppliesOfSignedPartitions(t *testing.T) {
+	ctx := context.Background()
+	now := time.UnixMilli(1_800_000_000_000).UTC()
+	clock := partitionlog.ClockFunc(func() time.Time { return now })
+	store, err := memstore.New(memstore.Options{StreamID: "orders"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store, Clock: clock})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := signing.NewEd25519Signer("writer", private)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error = %v", err)
+	}
+	verifier, err := signing.NewEd25519Verifier(map[string]ed25519.PublicKey{"writer": public})
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier() error = %v", err)
+	}
+	w, err := log.OpenWriter(ctx, partitionlog.WriterOptions{
+		Partition: 1,
+		WriterID:  [16]byte{1},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 1},
+		Signer:    signer,
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter() error = %v", err)
+	}
+	appendRecords(t, w, 0, 4)
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	policy := map[uint32]Policy{1: {LSNFloor: 2}}
+	unsigned, err := New(log, Options{Partitions: policy, IdleAfter: time.Minute, Clock: clock})
+	if err != nil {
+		t.Fatalf("New(unsigned) error = %v", err)
+	}
+	if _, err := unsigned.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(observe) error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := unsigned.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(unsigned) error = %v", err)
+	}
+	status, _ := unsigned.Status(1)
+	if !errors.Is(status.Err, partitionlog.ErrSignerRequired) || status.Head.OldestLSN != 0 {
+		t.Fatalf("unsigned status = %+v, want %v and nothing applied", status, partitionlog.ErrSignerRequired)
+	}
+
+	signed, err := New(log, Options{Partitions: policy, IdleAfter: time.Minute, Clock: clock, Signer: signer})
+	if err != nil {
+		t.Fatalf("New(signed) error = %v", err)
+	}
+	if _, err := signed.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(observe signed) error = %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := signed.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce(signed) error = %v", err)
+	}
+	status, _ = signed.Status(1)
+	if status.Err != nil || status.AppliedBy != AppliedByController || status.Head.OldestLSN != 2 {
+		t.Fatalf("signed status = %+v, want controller apply", status)
+	}
+	if err := signing.VerifyHead(verifier, status.Head); err != nil {
+		t.Fatalf("VerifyHead() error = %v", err)
+	}
+}
+
+func TestNewRejectsInvalidOptions(t *testing.T) {
+	store, err := memstore.New(memstore.Options{StreamID: "orders"})
+	if err != nil {
+		t.Fatalf("memstore.New() error = %v", err)
+	}
+	log, err := partitionlog.Open(partitionlog.Options{Store: store})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := New(nil, Options{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(nil log) error = %v, want %v", err, ErrInvalidOptions)
+	}
+	if _, err := New(log, Options{Interval: -time.Second}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(negative interval) error = %v, want %v", err, ErrInvalidOptions)
+	}
+	if _, err := New(log, Options{Partitions: map[uint32]Policy{3: {MaxAge: -time.Second}}}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("New(negative age) error = %v, want %v", err, ErrInvalidPolicy)
+	}
+	if _, err := New(log, Options{Stream: Policy{MaxAge: time.Hour, LSNFloor: 10}}); !errors.Is(err, ErrInvalidPolicy) {
+		t.Fatalf("New(age and floor) error = %v, want %v", err, ErrInvalidPolicy)
+	}
+}
+
+func openTestWriter(t testing.TB, log *partitionlog.Log, partition uint32, id byte) *partitionlog.Writer {
+	t.Helper()
+	w, err := log.OpenWriter(context.Background(), partitionlog.WriterOptions{
+		Partition: partition,
+		WriterID:  [16]byte{id},
+		Batch:     partitionlog.BatchPolicy{MaxRecords: 1},
+	})
+	if err != nil {
+		t.Fatalf("OpenWriter(%d) error = %v", partition, err)
+	}
+	return w
+}
+
+// appendRecords appends count one-record segments whose timestamps are their
+// position times one second.
+func appendRecords(t testing.TB, w *partitionlog.Writer, first, count int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := first; i < first+count; i++ {
+		if _, err := w.Append(ctx, partitionlog.Record{TimestampMS: int64(i) * 1_000, Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+}
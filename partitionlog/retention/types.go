@@ -0,0 +1,168 @@
+// Package retention runs declarative retention policies over a partitionlog
+// stream.
+//
+// A Controller turns per-stream and per-partition policies into monotonic
+// retention requests, bumping PolicyVersion itself. The writer that owns a
+// partition still applies each request through its fence with
+// Writer.ApplyRetention. When a partition has been idle for IdleAfter and its
+// last writer closed, the controller applies the request through a
+// short-lived writer of its own. After each pass, an optional lifecycle Runner
+// reclaims the retired segment objects.
+//
+// The controller never fences out a writer that is still open: it opens its
+// writers with WriterOptions.IdleOnly, so a partition whose owner is open, or
+// stopped without Close, waits for the owner. Age cutoffs are rounded down to
+// a multiple of Interval, and the controller stores a new request only when it
+// would retire something, so a stable partition sees no new requests.
+package retention
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/lifecycle"
+)
+
+var (
+	ErrInvalidOptions = errors.New("retention: invalid options")
+	ErrInvalidPolicy  = errors.New("retention: invalid policy")
+)
+
+const (
+	DefaultInterval  = time.Minute
+	DefaultIdleAfter = 5 * time.Minute
+	DefaultListLimit = 100
+)
+
+// Policy is the declarative retention for one partition. Zero fields are
+// unset and a zero Policy disables retention. MaxAge, MaxBytes and LSNFloor
+// retire whole segments and the most restrictive one wins; MinRecords then
+// keeps at least that many of the newest records regardless of the others.
+// MaxAge and LSNFloor are exclusive, as a retention request names at most one
+// boundary.
+type Policy struct {
+	// MaxAge retires segments whose records are all older than now minus
+	// MaxAge.
+	MaxAge time.Duration
+	// MaxBytes caps the segment bytes a partition retains.
+	MaxBytes uint64
+	// LSNFloor retires segments that end below this LSN.
+	LSNFloor uint64
+	// MinRecords is the number of newest records never retired.
+	MinRecords uint64
+}
+
+func (p Policy) enabled() bool {
+	return p.MaxAge > 0 || p.MaxBytes > 0 || p.LSNFloor > 0
+}
+
+func (p Policy) validate() error {
+	if p.MaxAge < 0 {
+		return errors.New("negative max age")
+	}
+	if p.MaxAge > 0 && p.LSNFloor > 0 {
+		return errors.New("max age and lsn floor are exclusive")
+	}
+	return nil
+}
+
+type Options struct {
+	// Stream applies to every partition the log lists. A zero Stream policy
+	// limits the controller to the partitions named in Partitions.
+	Stream Policy
+	// Partitions overrides Stream for individual partitions. A zero Policy
+	// exempts the partition from retention.
+	Partitions map[uint32]Policy
+	// Interval is the delay between passes in Run.
+	Interval time.Duration
+	// IdleAfter is how long a partition's head must stay unchanged before the
+	// controller applies pending requests itself, and then only when the
+	// partition's last writer closed. Negative disables it.
+	IdleAfter time.Duration
+	// ListLimit is the page size used to discover stream partitions.
+	ListLimit int
+	// NewWriterID returns the writer ID for each short-lived controller
+	// writer. The default draws random IDs.
+	NewWriterID func() ([16]byte, error)
+	// Signer signs the heads that controller writers commit. Without it the
+	// controller leaves idle partitions whose head is signed to their owners
+	// and reports partitionlog.ErrSignerRequired for them.
+	Signer partitionlog.Signer
+	// Reclaimer, when set, receives one reclaim task per managed partition
+	// after every pass.
+	Reclaimer lifecycle.Runner
+	// Scheduler configures the reclaim scheduler. Its Observer still receives
+	// every event.
+	Scheduler lifecycle.SchedulerOptions
+	// Clock supplies policy time and the Run interval timer.
+	Clock partitionlog.Clock
+}
+
+// Applier reports who incorporated a partition's latest request.
+type Applier uint8
+
+const (
+	// AppliedNone means the latest request has not been applied yet.
+	AppliedNone Applier = iota
+	// AppliedByOwner means the partition's own writer applied it.
+	AppliedByOwner
+	// AppliedByController means a controller writer applied it while the
+	// partition was idle.
+	AppliedByController
+)
+
+func (a Applier) String() string {
+	switch a {
+	case AppliedNone:
+		return "none"
+	case AppliedByOwner:
+		return "owner"
+	case AppliedByController:
+		return "controller"
+	default:
+		return "unknown"
+	}
+}
+
+// ReclaimStatus is the final scheduler event of the latest reclaim task.
+type ReclaimStatus struct {
+	Ran      bool
+	Result   lifecycle.Result
+	Err      error
+	Deferred bool
+	Attempts int
+}
+
+// PartitionStatus is the controller's view of one managed partition after
+// its latest pass.
+type PartitionStatus struct {
+	Partition uint32
+	Policy    Policy
+	// Request is the latest stored request; zero until one is stored.
+	Request partitionlog.RetentionRequestState
+	Head    partitionlog.PartitionHead
+	// Pending is true while Request waits for the partition owner.
+	Pending   bool
+	AppliedBy Applier
+	Reclaim   ReclaimStatus
+	LastPass  time.Time
+	// Err is the error that stopped the latest pass for this partition.
+	Err error
+}
+
+// PassResult summarizes one RunOnce call.
+type PassResult struct {
+	// Partitions is the number of managed partitions visited.
+	Partitions int
+	// Requested is the number of new retention requests stored.
+	Requested int
+	// Applied is the number of requests applied by controller writers.
+	Applied int
+	// Pending is the number of requests left for active owners.
+	Pending int
+	// Failed is the number of partitions whose pass returned an error.
+	Failed   int
+	Reclaim  lifecycle.ScheduleResult
+	Finished time.Time
+}
@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/ankur-anand/unijord/partitionlog"
+	"github.com/ankur-anand/unijord/partitionlog/blob/registry"
+	s3catalog "github.com/ankur-anand/unijord/partitionlog/catalog/blob/s3"
+)
+
+// Namespace opens stream-scoped stores that share one client, bucket, and key
+// prefix.
+type Namespace struct {
+	opts Options
+}
+
+var _ partitionlog.StoreProvider = (*Namespace)(nil)
+
+// NewNamespace returns a partitionlog.StoreProvider over opts.
+func NewNamespace(opts Options) (*Namespace, error) {
+	if opts.StreamID != "" {
+		return nil, fmt.Errorf("partitionlog/s3: namespace options set stream id %q", opts.StreamID)
+	}
+	if _, err := s3catalog.NewBackend(opts.Client, opts.Bucket); err != nil {
+		return nil, err
+	}
+	return &Namespace{opts: opts}, nil
+}
+
+// StreamStore implements partitionlog.StoreProvider.
+func (n *Namespace) StreamStore(streamID string) (partitionlog.Store, error) {
+	opts := n.opts
+	opts.StreamID = streamID
+	return New(opts)
+}
+
+// NewRegistry opens the namespace's timeline registry; see
+// partitionlog.StoreProvider.
+func (n *Namespace) NewRegistry(opts registry.Options) (*registry.Registry, error) {
+	backend, err := s3catalog.NewBackend(n.opts.Client, n.opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = path.Join(rootPrefix(n.opts.Prefix), "registry")
+	}
+	return registry.New(backend, opts)
+}
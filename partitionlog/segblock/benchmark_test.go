@@ -22,6 +22,8 @@ func BenchmarkSeal(b *testing.B) {
 		{name: "none_4k_x_128b", codec: segformat.CodecNone, records: 4 * 1024, valueSize: 128},
 		{name: "zstd_4k_x_128b", codec: segformat.CodecZstd, records: 4 * 1024, valueSize: 128},
 		{name: "zstd_16k_x_128b", codec: segformat.CodecZstd, records: 16 * 1024, valueSize: 128},
+		{name: "lz4_4k_x_128b", codec: segformat.CodecLZ4, records: 4 * 1024, valueSize: 128},
+		{name: "s2_4k_x_128b", codec: segformat.CodecS2, records: 4 * 1024, valueSize: 128},
 	} {
 		b.Run(tc.name, func(b *testing.B) {
 			raw, meta := makeBenchRawBlock(b, tc.records, tc.valueSize)
@@ -49,6 +51,8 @@ func BenchmarkOpen(b *testing.B) {
 		{name: "none_4k_x_128b", codec: segformat.CodecNone, records: 4 * 1024, valueSize: 128},
 		{name: "zstd_4k_x_128b", codec: segformat.CodecZstd, records: 4 * 1024, valueSize: 128},
 		{name: "zstd_16k_x_128b", codec: segformat.CodecZstd, records: 16 * 1024, valueSize: 128},
+		{name: "lz4_4k_x_128b", codec: segformat.CodecLZ4, records: 4 * 1024, valueSize: 128},
+		{name: "s2_4k_x_128b", codec: segformat.CodecS2, records: 4 * 1024, valueSize: 128},
 	} {
 		b.Run(tc.name, func(b *testing.B) {
 			raw, meta := makeBenchRawBlock(b, tc.records, tc.valueSize)
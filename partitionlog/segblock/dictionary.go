@@ -0,0 +1,172 @@
+package segblock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// MaxDictionarySize bounds the encoded size of a Dictionary.
+	MaxDictionarySize = 1 << 20
+
+	// DefaultDictionarySize is the TrainDictionary size used when maxSize is 0.
+	DefaultDictionarySize = 64 << 10
+
+	// minDictionaryID is the first dictionary ID outside the range the zstd
+	// format reserves for registered dictionaries.
+	minDictionaryID = 32768
+)
+
+var (
+	// ErrInvalidDictionary reports bytes that are not a usable zstd
+	// dictionary.
+	ErrInvalidDictionary = errors.New("segblock: invalid dictionary")
+	// ErrUnknownDictionary reports a dictionary ID a DictionaryProvider does
+	// not hold.
+	ErrUnknownDictionary = errors.New("segblock: unknown dictionary")
+)
+
+// DictionaryProvider resolves the dictionary a CodecZstdDict segment names in
+// its trailer.
+type DictionaryProvider interface {
+	// Dictionary returns the dictionary with id, or an error wrapping
+	// ErrUnknownDictionary when no such dictionary exists.
+	Dictionary(ctx context.Context, id uint32) (*Dictionary, error)
+}
+
+// Dictionary is a parsed zstd dictionary. It is immutable and pools its own
+// encoders and decoders, so callers should share one Dictionary per ID.
+type Dictionary struct {
+	id  uint32
+	raw []byte
+
+	encoderPools [zstd.SpeedBestCompression + 1]sync.Pool
+	decoderPool  sync.Pool
+}
+
+// NewDictionary parses raw as a zstd dictionary. The dictionary must carry a
+// nonzero ID, because segments reference dictionaries only by ID.
+func NewDictionary(raw []byte) (*Dictionary, error) {
+	if len(raw) == 0 || len(raw) > MaxDictionarySize {
+		return nil, fmt.Errorf("%w: size=%d max=%d", ErrInvalidDictionary, len(raw), MaxDictionarySize)
+	}
+	info, err := zstd.InspectDictionary(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDictionary, err)
+	}
+	if info.ID() == 0 {
+		return nil, fmt.Errorf("%w: dictionary id must be nonzero", ErrInvalidDictionary)
+	}
+	d := &Dictionary{id: info.ID(), raw: append([]byte(nil), raw...)}
+	for level := zstd.SpeedFastest; level <= zstd.SpeedBestCompression; level++ {
+		d.encoderPools[level].New = func() any {
+			enc, err := d.newEncoder(level)
+			if err != nil {
+				panic(fmt.Errorf("segblock: zstd dictionary encoder init: %w", err))
+			}
+			return enc
+		}
+	}
+	d.decoderPool.New = func() any {
+		dec, err := d.newDecoder()
+		if err != nil {
+			panic(fmt.Errorf("segblock: zstd dictionary decoder init: %w", err))
+		}
+		return dec
+	}
+	// Build the first encoder and decoder eagerly so a dictionary zstd cannot
+	// load fails here instead of panicking inside a pool.
+	enc, err := d.newEncoder(zstd.SpeedDefault)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDictionary, err)
+	}
+	dec, err := d.newDecoder()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDictionary, err)
+	}
+	d.encoderPools[zstd.SpeedDefault].Put(enc)
+	d.decoderPool.Put(dec)
+	return d, nil
+}
+
+func (d *Dictionary) newEncoder(level zstd.EncoderLevel) (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil,
+		zstd.WithEncoderConcurrency(1),
+		zstd.WithZeroFrames(true),
+		zstd.WithEncoderCRC(false),
+		zstd.WithEncoderLevel(level),
+		zstd.WithEncoderDict(d.raw),
+	)
+}
+
+func (d *Dictionary) newDecoder() (*zstd.Decoder, error) {
+	return zstd.NewReader(nil,
+		zstd.WithDecoderConcurrency(1),
+		zstd.WithDecoderMaxMemory(uint64(segformat.MaxRawBlockSize*2)),
+		zstd.WithDecoderDicts(d.raw),
+	)
+}
+
+// ID returns the dictionary ID recorded in segment trailers.
+func (d *Dictionary) ID() uint32 {
+	return d.id
+}
+
+// Bytes returns a copy of the encoded dictionary.
+func (d *Dictionary) Bytes() []byte {
+	return append([]byte(nil), d.raw...)
+}
+
+func (d *Dictionary) encode(level int, raw []byte) []byte {
+	l := zstdEncoderLevel(level)
+	enc := d.encoderPools[l].Get().(*zstd.Encoder)
+	defer d.encoderPools[l].Put(enc)
+	return enc.EncodeAll(raw, nil)
+}
+
+func (d *Dictionary) decode(stored []byte, rawSize uint32) ([]byte, error) {
+	dec := d.decoderPool.Get().(*zstd.Decoder)
+	defer d.decoderPool.Put(dec)
+	return dec.DecodeAll(stored, make([]byte, 0, rawSize))
+}
+
+// TrainDictionary builds a zstd dictionary of at most maxSize bytes from
+// samples, typically record values. 0 selects DefaultDictionarySize. The
+// dictionary ID is derived from the dictionary content, so equal
+// dictionaries share an ID and different ones do not in practice.
+func TrainDictionary(samples [][]byte, maxSize int) ([]byte, error) {
+	if maxSize == 0 {
+		maxSize = DefaultDictionarySize
+	}
+	if maxSize < 0 || maxSize > MaxDictionarySize {
+		return nil, fmt.Errorf("%w: max size=%d outside [1,%d]", ErrInvalidDictionary, maxSize, MaxDictionarySize)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%w: no samples", ErrInvalidDictionary)
+	}
+	raw, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   6,
+		ZstdDictID:  minDictionaryID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: train: %v", ErrInvalidDictionary, err)
+	}
+	// A zstd dictionary starts with a 4-byte magic and the 4-byte
+	// little-endian ID; everything after it is content.
+	if len(raw) <= 8 {
+		return nil, fmt.Errorf("%w: trained dictionary too short", ErrInvalidDictionary)
+	}
+	sum := sha256.Sum256(raw[8:])
+	id := minDictionaryID + binary.LittleEndian.Uint32(sum[:4])%(1<<31-minDictionaryID)
+	binary.LittleEndian.PutUint32(raw[4:8], id)
+	return raw, nil
+}
@@ -0,0 +1,124 @@
+package segblock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+func TestTrainDictionaryRoundTripSmallRecords(t *testing.T) {
+	samples := dictionarySamples(0, 400)
+	raw, err := TrainDictionary(samples, 16<<10)
+	if err != nil {
+		t.Fatalf("TrainDictionary() error = %v", err)
+	}
+	d, err := NewDictionary(raw)
+	if err != nil {
+		t.Fatalf("NewDictionary() error = %v", err)
+	}
+	if d.ID() < minDictionaryID {
+		t.Fatalf("ID() = %d, want >= %d", d.ID(), minDictionaryID)
+	}
+
+	records := make([]segformat.RawRecord, 0, 8)
+	for i, value := range dictionarySamples(1000, 8) {
+		records = append(records, segformat.RawRecord{TimestampMS: int64(100 + i), Value: value})
+	}
+	block, err := segformat.EncodeRawBlock(records)
+	if err != nil {
+		t.Fatalf("EncodeRawBlock() error = %v", err)
+	}
+	meta := Meta{BaseLSN: 7, RecordCount: uint32(len(records)), MinTimestampMS: 100, MaxTimestampMS: int64(100 + len(records) - 1)}
+	comp := Compression{Codec: segformat.CodecZstdDict, Dictionary: d}
+	sealed, err := SealCompressed(comp, segformat.HashXXH64, nil, append([]byte(nil), block...), meta)
+	if err != nil {
+		t.Fatalf("SealCompressed() error = %v", err)
+	}
+	plain, err := SealCompressed(Compression{Codec: segformat.CodecZstd}, segformat.HashXXH64, nil, append([]byte(nil), block...), meta)
+	if err != nil {
+		t.Fatalf("SealCompressed(zstd) error = %v", err)
+	}
+	if len(sealed.Stored) >= len(plain.Stored) {
+		t.Fatalf("zstd-dict stored size = %d, zstd stored size = %d; want dictionary to help", len(sealed.Stored), len(plain.Stored))
+	}
+	opened, err := OpenCompressed(comp, segformat.HashXXH64, nil, sealed.Preamble, sealed.Stored)
+	if err != nil {
+		t.Fatalf("OpenCompressed() error = %v", err)
+	}
+	if !bytes.Equal(opened, block) {
+		t.Fatal("OpenCompressed() raw bytes mismatch")
+	}
+	if _, err := Open(segformat.CodecZstdDict, segformat.HashXXH64, sealed.Preamble, sealed.Stored); !errors.Is(err, ErrInvalidCompression) {
+		t.Fatalf("Open(no dictionary) error = %v, want %v", err, ErrInvalidCompression)
+	}
+
+	otherRaw, err := TrainDictionary(dictionarySamples(5000, 50), 4<<10)
+	if err != nil {
+		t.Fatalf("TrainDictionary(other) error = %v", err)
+	}
+	other, err := NewDictionary(otherRaw)
+	if err != nil {
+		t.Fatalf("NewDictionary(other) error = %v", err)
+	}
+	if other.ID() == d.ID() {
+		t.Fatal("different samples produced the same dictionary ID")
+	}
+	if _, err := OpenCompressed(Compression{Codec: segformat.CodecZstdDict, Dictionary: other}, segformat.HashXXH64, nil, sealed.Preamble, sealed.Stored); err == nil {
+		t.Fatal("OpenCompressed(wrong dictionary) error = nil")
+	}
+}
+
+func TestNewDictionaryRejectsInvalidInput(t *testing.T) {
+	for name, raw := range map[string][]byte{
+		"empty":    nil,
+		"garbage":  []byte("not a zstd dictionary"),
+		"oversize": make([]byte, MaxDictionarySize+1),
+	} {
+		if _, err := NewDictionary(raw); !errors.Is(err, ErrInvalidDictionary) {
+			t.Fatalf("NewDictionary(%s) error = %v, want %v", name, err, ErrInvalidDictionary)
+		}
+	}
+	if _, err := TrainDictionary(nil, 0); !errors.Is(err, ErrInvalidDictionary) {
+		t.Fatalf("TrainDictionary(no samples) error = %v, want %v", err, ErrInvalidDictionary)
+	}
+	if _, err := TrainDictionary([][]byte{[]byte("x")}, MaxDictionarySize+1); !errors.Is(err, ErrInvalidDictionary) {
+		t.Fatalf("TrainDictionary(oversize) error = %v, want %v", err, ErrInvalidDictionary)
+	}
+}
+
+func TestCompressionValidateDictionary(t *testing.T) {
+	raw, err := TrainDictionary(dictionarySamples(0, 100), 4<<10)
+	if err != nil {
+		t.Fatalf("TrainDictionary() error = %v", err)
+	}
+	d, err := NewDictionary(raw)
+	if err != nil {
+		t.Fatalf("NewDictionary() error = %v", err)
+	}
+	if err := (Compression{Codec: segformat.CodecZstdDict, ZstdLevel: 19, Dictionary: d}).Validate(); err != nil {
+		t.Fatalf("Validate(zstd-dict) error = %v", err)
+	}
+	for _, comp := range []Compression{
+		{Codec: segformat.CodecZstdDict},
+		{Codec: segformat.CodecZstd, Dictionary: d},
+	} {
+		if err := comp.Validate(); !errors.Is(err, ErrInvalidCompression) {
+			t.Fatalf("Validate(%s dictionary=%t) error = %v, want %v", comp.Codec, comp.Dictionary != nil, err, ErrInvalidCompression)
+		}
+	}
+}
+
+// dictionarySamples returns n small JSON events sharing most of their
+// structure, the workload dictionaries are meant for.
+func dictionarySamples(start, n int) [][]byte {
+	samples := make([][]byte, 0, n)
+	for i := start; i < start+n; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(
+			`{"event":"tool_call","session_id":"sess-%04d","tool":"search","status":"ok","latency_ms":%d,"region":"us-east-1"}`,
+			i%97, i*13%500)))
+	}
+	return samples
+}
@@ -0,0 +1,124 @@
+package segblock
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+var (
+	// ErrUnknownKey reports a key ID the provider does not hold.
+	ErrUnknownKey = errors.New("segblock: unknown key")
+	// ErrKeyDestroyed reports a key ID whose key was destroyed on purpose.
+	// Every segment wrapped under it is permanently unreadable.
+	ErrKeyDestroyed = errors.New("segblock: key destroyed")
+)
+
+// KeyProvider wraps and unwraps per-segment data keys. Implementations usually
+// front a KMS, so the wrapping keys never leave the provider. Both methods may
+// be called concurrently. Reader caches key on options that hold a provider,
+// so implementations must be comparable; a pointer type is.
+type KeyProvider interface {
+	// WrapKey wraps the data key of one new segment of partition and returns
+	// the ID of the key that wrapped it.
+	WrapKey(ctx context.Context, partition uint32, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey returns the data key that keyID wrapped. It returns an error
+	// wrapping ErrKeyDestroyed when keyID was destroyed.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeyProvider wraps data keys with AES-256-GCM under in-memory key
+// encryption keys. New segments use the active key; every other key stays
+// available for reads, which lets callers rotate keys.
+type StaticKeyProvider struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+var _ KeyProvider = (*StaticKeyProvider)(nil)
+
+// NewStaticKeyProvider builds a provider from 32-byte key encryption keys.
+func NewStaticKeyProvider(activeID string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, activeID)
+	}
+	p := &StaticKeyProvider{activeID: activeID, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > segformat.MaxKeyIDLen {
+			return nil, fmt.Errorf("segblock: invalid key id %q", id)
+		}
+		aead, err := newAES256GCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("segblock: key %q: %w", id, err)
+		}
+		p.keys[id] = aead
+	}
+	return p, nil
+}
+
+// KeyIDs returns the IDs of every held key in sorted order.
+func (p *StaticKeyProvider) KeyIDs() []string {
+	ids := make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (p *StaticKeyProvider) WrapKey(ctx context.Context, _ uint32, dataKey []byte) (string, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+	aead := p.keys[p.activeID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("segblock: wrap nonce: %w", err)
+	}
+	return p.activeID, aead.Seal(nonce, nonce, dataKey, []byte(p.activeID)), nil
+}
+
+func (p *StaticKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: wrapped key too short", segformat.ErrIntegrityMismatch)
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap key %q: %v", segformat.ErrIntegrityMismatch, keyID, err)
+	}
+	return dataKey, nil
+}
+
+// NewDataKey returns a random data key for one segment.
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, segformat.DataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("segblock: data key: %w", err)
+	}
+	return key, nil
+}
+
+func newAES256GCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != segformat.DataKeySize {
+		return nil, fmt.Errorf("key size=%d want=%d", len(key), segformat.DataKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
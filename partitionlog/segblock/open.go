@@ -4,10 +4,23 @@ import (
 	"fmt"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/klauspost/compress/s2"
+	"github.com/pierrec/lz4/v4"
 )
 
 func Open(codec segformat.Codec, hashAlgo segformat.HashAlgo, preamble segformat.BlockPreamble, stored []byte) ([]byte, error) {
-	if err := codec.Validate(); err != nil {
+	return open(Compression{Codec: codec}, hashAlgo, nil, preamble, stored)
+}
+
+// OpenCompressed is the inverse of SealCompressed. comp.ZstdLevel is ignored;
+// comp.Dictionary must be the dictionary the block was sealed with.
+func OpenCompressed(comp Compression, hashAlgo segformat.HashAlgo, c *BlockCipher, preamble segformat.BlockPreamble, stored []byte) ([]byte, error) {
+	comp.ZstdLevel = 0
+	return open(comp, hashAlgo, c, preamble, stored)
+}
+
+func open(comp Compression, hashAlgo segformat.HashAlgo, c *BlockCipher, preamble segformat.BlockPreamble, stored []byte) ([]byte, error) {
+	if err := comp.Validate(); err != nil {
 		return nil, err
 	}
 	if err := hashAlgo.Validate(); err != nil {
@@ -26,7 +39,15 @@ func Open(codec segformat.Codec, hashAlgo segformat.HashAlgo, preamble segformat
 	if gotHash != preamble.BlockHash {
 		return nil, fmt.Errorf("%w: block hash got=%x want=%x", segformat.ErrIntegrityMismatch, gotHash, preamble.BlockHash)
 	}
-	raw, err := decodeStored(codec, stored, preamble.RawSize)
+	storedOwned := false
+	if c != nil {
+		stored, err = c.decrypt(stored, preamble)
+		if err != nil {
+			return nil, err
+		}
+		storedOwned = true
+	}
+	raw, err := decodeStored(comp, stored, preamble.RawSize, storedOwned)
 	if err != nil {
 		return nil, err
 	}
@@ -36,9 +57,12 @@ func Open(codec segformat.Codec, hashAlgo segformat.HashAlgo, preamble segformat
 	return raw, nil
 }
 
-func decodeStored(codec segformat.Codec, stored []byte, rawSize uint32) ([]byte, error) {
-	switch codec {
+func decodeStored(comp Compression, stored []byte, rawSize uint32, storedOwned bool) ([]byte, error) {
+	switch comp.Codec {
 	case segformat.CodecNone:
+		if storedOwned {
+			return stored, nil
+		}
 		return append([]byte(nil), stored...), nil
 	case segformat.CodecZstd:
 		dec := getZstdDecoder()
@@ -48,7 +72,35 @@ func decodeStored(codec segformat.Codec, stored []byte, rawSize uint32) ([]byte,
 			return nil, fmt.Errorf("decode zstd block: %w", err)
 		}
 		return raw, nil
+	case segformat.CodecZstdDict:
+		raw, err := comp.Dictionary.decode(stored, rawSize)
+		if err != nil {
+			return nil, fmt.Errorf("decode zstd-dict block: %w", err)
+		}
+		return raw, nil
+	case segformat.CodecLZ4:
+		raw := make([]byte, rawSize)
+		n, err := lz4.UncompressBlock(stored, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode lz4 block: %w", segformat.ErrInvalidSegment, err)
+		}
+		return raw[:n], nil
+	case segformat.CodecS2:
+		// Check the declared length first so a hostile block cannot make
+		// Decode allocate more than raw_size.
+		n, err := s2.DecodedLen(stored)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode s2 block: %w", segformat.ErrInvalidSegment, err)
+		}
+		if n != int(rawSize) {
+			return nil, fmt.Errorf("%w: s2 decoded_len=%d want=%d", segformat.ErrInvalidSegment, n, rawSize)
+		}
+		raw, err := s2.Decode(make([]byte, rawSize), stored)
+		if err != nil {
+			return nil, fmt.Errorf("%w: decode s2 block: %w", segformat.ErrInvalidSegment, err)
+		}
+		return raw, nil
 	default:
-		return nil, fmt.Errorf("%w: %d", segformat.ErrUnsupportedCodec, uint16(codec))
+		return nil, fmt.Errorf("%w: %d", segformat.ErrUnsupportedCodec, uint16(comp.Codec))
 	}
 }
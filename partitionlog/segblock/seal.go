@@ -4,21 +4,30 @@ import (
 	"fmt"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/klauspost/compress/s2"
+	"github.com/pierrec/lz4/v4"
 )
 
 func Seal(codec segformat.Codec, hashAlgo segformat.HashAlgo, raw []byte, meta Meta) (Sealed, error) {
-	return seal(codec, hashAlgo, raw, meta, false)
+	return seal(Compression{Codec: codec}, hashAlgo, nil, raw, meta, false)
 }
 
 // SealOwned is equivalent to Seal, except the caller transfers ownership of
 // raw to segblock. For CodecNone this lets the sealed block reuse raw as the
 // stored payload instead of copying it.
 func SealOwned(codec segformat.Codec, hashAlgo segformat.HashAlgo, raw []byte, meta Meta) (Sealed, error) {
-	return seal(codec, hashAlgo, raw, meta, true)
+	return seal(Compression{Codec: codec}, hashAlgo, nil, raw, meta, true)
 }
 
-func seal(codec segformat.Codec, hashAlgo segformat.HashAlgo, raw []byte, meta Meta, rawOwned bool) (Sealed, error) {
-	if err := codec.Validate(); err != nil {
+// SealCompressed seals raw, which the caller transfers to segblock, with an
+// explicit Compression. c is optional; when set the stored bytes are
+// encrypted as by SealEncrypted.
+func SealCompressed(comp Compression, hashAlgo segformat.HashAlgo, c *BlockCipher, raw []byte, meta Meta) (Sealed, error) {
+	return seal(comp, hashAlgo, c, raw, meta, true)
+}
+
+func seal(comp Compression, hashAlgo segformat.HashAlgo, c *BlockCipher, raw []byte, meta Meta, rawOwned bool) (Sealed, error) {
+	if err := comp.Validate(); err != nil {
 		return Sealed{}, err
 	}
 	if err := hashAlgo.Validate(); err != nil {
@@ -28,10 +37,20 @@ func seal(codec segformat.Codec, hashAlgo segformat.HashAlgo, raw []byte, meta M
 		return Sealed{}, err
 	}
 
-	stored, err := encodeStored(codec, raw, rawOwned)
+	stored, err := encodeStored(comp, raw, rawOwned || c != nil)
 	if err != nil {
 		return Sealed{}, err
 	}
+	preamble := segformat.BlockPreamble{
+		RawSize:        uint32(len(raw)),
+		RecordCount:    meta.RecordCount,
+		BaseLSN:        meta.BaseLSN,
+		MinTimestampMS: meta.MinTimestampMS,
+		MaxTimestampMS: meta.MaxTimestampMS,
+	}
+	if c != nil {
+		stored = c.encrypt(stored, preamble)
+	}
 	if len(stored) > segformat.MaxStoredBlockSize {
 		return Sealed{}, fmt.Errorf("%w: stored_size=%d max=%d", segformat.ErrBlockTooLarge, len(stored), segformat.MaxStoredBlockSize)
 	}
@@ -40,34 +59,41 @@ func seal(codec segformat.Codec, hashAlgo segformat.HashAlgo, raw []byte, meta M
 	if err != nil {
 		return Sealed{}, err
 	}
-	preamble := segformat.BlockPreamble{
-		StoredSize:     uint32(len(stored)),
-		RawSize:        uint32(len(raw)),
-		RecordCount:    meta.RecordCount,
-		BaseLSN:        meta.BaseLSN,
-		MinTimestampMS: meta.MinTimestampMS,
-		MaxTimestampMS: meta.MaxTimestampMS,
-		BlockHash:      blockHash,
-	}
+	preamble.StoredSize = uint32(len(stored))
+	preamble.BlockHash = blockHash
 	if err := preamble.Validate(); err != nil {
 		return Sealed{}, err
 	}
 	return Sealed{Preamble: preamble, Stored: stored}, nil
 }
 
-func encodeStored(codec segformat.Codec, raw []byte, rawOwned bool) ([]byte, error) {
-	switch codec {
+func encodeStored(comp Compression, raw []byte, rawOwned bool) ([]byte, error) {
+	switch comp.Codec {
 	case segformat.CodecNone:
 		if rawOwned {
 			return raw, nil
 		}
 		return append([]byte(nil), raw...), nil
 	case segformat.CodecZstd:
-		enc := getZstdEncoder()
-		defer putZstdEncoder(enc)
+		level := zstdEncoderLevel(comp.ZstdLevel)
+		enc := getZstdEncoder(level)
+		defer putZstdEncoder(level, enc)
 		return enc.EncodeAll(raw, nil), nil
+	case segformat.CodecZstdDict:
+		return comp.Dictionary.encode(comp.ZstdLevel, raw), nil
+	case segformat.CodecLZ4:
+		// A destination of CompressBlockBound bytes lets incompressible input
+		// be stored as literals instead of failing.
+		stored := make([]byte, lz4.CompressBlockBound(len(raw)))
+		n, err := lz4.CompressBlock(raw, stored, nil)
+		if err != nil {
+			return nil, fmt.Errorf("encode lz4 block: %w", err)
+		}
+		return stored[:n], nil
+	case segformat.CodecS2:
+		return s2.Encode(nil, raw), nil
 	default:
-		return nil, fmt.Errorf("%w: %d", segformat.ErrUnsupportedCodec, uint16(codec))
+		return nil, fmt.Errorf("%w: %d", segformat.ErrUnsupportedCodec, uint16(comp.Codec))
 	}
 }
 
@@ -85,7 +111,14 @@ func validateMeta(meta Meta, rawSize int) error {
 	if meta.BaseLSN > segformat.MaxRecordLSN-delta {
 		return fmt.Errorf("%w: lsn range exceeds max record lsn=%d", segformat.ErrInvalidSegment, segformat.MaxRecordLSN)
 	}
-	if int64(meta.RecordCount) > int64(rawSize/segformat.RecordHeaderSize) {
+	format := meta.RecordFormat
+	if format == 0 {
+		format = segformat.DefaultRecordFormat
+	}
+	if err := format.Validate(); err != nil {
+		return err
+	}
+	if int64(meta.RecordCount) > int64(rawSize)/int64(format.MinRecordSize()) {
 		return fmt.Errorf("%w: record_count=%d cannot fit in raw_size=%d", segformat.ErrInvalidSegment, meta.RecordCount, rawSize)
 	}
 	if meta.MaxTimestampMS < meta.MinTimestampMS {
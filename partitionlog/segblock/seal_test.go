@@ -2,6 +2,7 @@ package segblock
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"testing"
 
@@ -93,6 +94,90 @@ func TestSealOpenRoundTripZstd(t *testing.T) {
 	}
 }
 
+func TestSealCompressedRoundTripEveryCodec(t *testing.T) {
+	compressible, err := segformat.EncodeRawBlock([]segformat.RawRecord{
+		{TimestampMS: 100, Value: bytes.Repeat([]byte(`{"event":"tool_call","status":"ok"}`), 16)},
+		{TimestampMS: 101, Value: bytes.Repeat([]byte(`{"event":"tool_result","status":"ok"}`), 16)},
+	})
+	if err != nil {
+		t.Fatalf("EncodeRawBlock() error = %v", err)
+	}
+	noise := make([]byte, 512)
+	for i := range noise {
+		noise[i] = byte(i*131 + i*i*7)
+	}
+	incompressible, err := segformat.EncodeRawBlock([]segformat.RawRecord{{TimestampMS: 100, Value: noise}, {TimestampMS: 101, Value: noise[:17]}})
+	if err != nil {
+		t.Fatalf("EncodeRawBlock() error = %v", err)
+	}
+	meta := Meta{BaseLSN: 30, RecordCount: 2, MinTimestampMS: 100, MaxTimestampMS: 101}
+
+	for _, comp := range []Compression{
+		{Codec: segformat.CodecLZ4},
+		{Codec: segformat.CodecS2},
+		{Codec: segformat.CodecZstd, ZstdLevel: 1},
+		{Codec: segformat.CodecZstd, ZstdLevel: 19},
+	} {
+		for name, raw := range map[string][]byte{"compressible": compressible, "incompressible": incompressible} {
+			sealed, err := SealCompressed(comp, segformat.HashXXH64, nil, append([]byte(nil), raw...), meta)
+			if err != nil {
+				t.Fatalf("SealCompressed(%s level=%d, %s) error = %v", comp.Codec, comp.ZstdLevel, name, err)
+			}
+			if name == "compressible" && len(sealed.Stored) >= len(raw) {
+				t.Fatalf("%s stored size = %d, raw size = %d; want compression", comp.Codec, len(sealed.Stored), len(raw))
+			}
+			opened, err := Open(comp.Codec, segformat.HashXXH64, sealed.Preamble, sealed.Stored)
+			if err != nil {
+				t.Fatalf("Open(%s, %s) error = %v", comp.Codec, name, err)
+			}
+			if !bytes.Equal(opened, raw) {
+				t.Fatalf("Open(%s, %s) raw bytes mismatch", comp.Codec, name)
+			}
+		}
+
+		// A validly hashed block that decodes to the wrong size is rejected.
+		other, err := SealCompressed(comp, segformat.HashXXH64, nil, singleRawBlock(t), Meta{BaseLSN: 30, RecordCount: 1, MinTimestampMS: 100, MaxTimestampMS: 100})
+		if err != nil {
+			t.Fatalf("SealCompressed(other) error = %v", err)
+		}
+		sealed, err := SealCompressed(comp, segformat.HashXXH64, nil, append([]byte(nil), compressible...), meta)
+		if err != nil {
+			t.Fatalf("SealCompressed() error = %v", err)
+		}
+		preamble := sealed.Preamble
+		preamble.StoredSize = uint32(len(other.Stored))
+		preamble.BlockHash = other.Preamble.BlockHash
+		if _, err := Open(comp.Codec, segformat.HashXXH64, preamble, other.Stored); err == nil {
+			t.Fatalf("Open(%s, wrong raw size) error = nil", comp.Codec)
+		}
+	}
+}
+
+func TestCompressionValidate(t *testing.T) {
+	for _, comp := range []Compression{
+		{Codec: segformat.CodecNone},
+		{Codec: segformat.CodecLZ4},
+		{Codec: segformat.CodecZstd, ZstdLevel: 1},
+		{Codec: segformat.CodecZstd, ZstdLevel: MaxZstdLevel},
+	} {
+		if err := comp.Validate(); err != nil {
+			t.Fatalf("Validate(%+v) error = %v", comp, err)
+		}
+	}
+	for _, comp := range []Compression{
+		{Codec: segformat.CodecS2, ZstdLevel: 3},
+		{Codec: segformat.CodecZstd, ZstdLevel: -1},
+		{Codec: segformat.CodecZstd, ZstdLevel: MaxZstdLevel + 1},
+	} {
+		if err := comp.Validate(); !errors.Is(err, ErrInvalidCompression) {
+			t.Fatalf("Validate(%+v) error = %v, want %v", comp, err, ErrInvalidCompression)
+		}
+	}
+	if err := (Compression{Codec: segformat.Codec(99)}).Validate(); !errors.Is(err, segformat.ErrUnsupportedCodec) {
+		t.Fatalf("Validate(unknown codec) error = %v, want %v", err, segformat.ErrUnsupportedCodec)
+	}
+}
+
 func TestOpenRejectsCorruptStoredBytes(t *testing.T) {
 	raw := singleRawBlock(t)
 	sealed, err := Seal(segformat.CodecNone, segformat.HashCRC32C, raw, Meta{
@@ -198,3 +283,109 @@ func TestSealRejectsLSNOverflow(t *testing.T) {
 		t.Fatalf("Seal() error = %v, want %v", err, segformat.ErrInvalidSegment)
 	}
 }
+
+func TestSealEncryptedOpenRoundTrip(t *testing.T) {
+	for _, codec := range []segformat.Codec{segformat.CodecNone, segformat.CodecZstd} {
+		t.Run(codec.String(), func(t *testing.T) {
+			raw, err := segformat.EncodeRawBlock([]segformat.RawRecord{
+				{TimestampMS: 100, Value: bytes.Repeat([]byte("a"), 256)},
+				{TimestampMS: 101, Value: bytes.Repeat([]byte("b"), 256)},
+			})
+			if err != nil {
+				t.Fatalf("EncodeRawBlock() error = %v", err)
+			}
+			dataKey, err := NewDataKey()
+			if err != nil {
+				t.Fatalf("NewDataKey() error = %v", err)
+			}
+			c, err := NewBlockCipher(segformat.CipherAES256GCM, dataKey, [16]byte{1})
+			if err != nil {
+				t.Fatalf("NewBlockCipher() error = %v", err)
+			}
+			meta := Meta{BaseLSN: 20, RecordCount: 2, MinTimestampMS: 100, MaxTimestampMS: 101}
+			sealed, err := SealEncrypted(codec, segformat.HashXXH64, c, raw, meta)
+			if err != nil {
+				t.Fatalf("SealEncrypted() error = %v", err)
+			}
+			if bytes.Contains(sealed.Stored, []byte("aaaaaaaa")) {
+				t.Fatal("stored bytes contain plaintext")
+			}
+			if got, err := segformat.HashBytes(segformat.HashXXH64, sealed.Stored); err != nil || got != sealed.Preamble.BlockHash {
+				t.Fatalf("HashBytes(ciphertext) = %x, %v; want %x", got, err, sealed.Preamble.BlockHash)
+			}
+			opened, err := OpenEncrypted(codec, segformat.HashXXH64, c, sealed.Preamble, sealed.Stored)
+			if err != nil {
+				t.Fatalf("OpenEncrypted() error = %v", err)
+			}
+			if !bytes.Equal(opened, raw) {
+				t.Fatal("opened raw bytes mismatch")
+			}
+
+			otherKey, err := NewDataKey()
+			if err != nil {
+				t.Fatalf("NewDataKey() error = %v", err)
+			}
+			wrongKey, err := NewBlockCipher(segformat.CipherAES256GCM, otherKey, [16]byte{1})
+			if err != nil {
+				t.Fatalf("NewBlockCipher() error = %v", err)
+			}
+			if _, err := OpenEncrypted(codec, segformat.HashXXH64, wrongKey, sealed.Preamble, sealed.Stored); !errors.Is(err, segformat.ErrIntegrityMismatch) {
+				t.Fatalf("OpenEncrypted(wrong key) error = %v, want %v", err, segformat.ErrIntegrityMismatch)
+			}
+			otherSegment, err := NewBlockCipher(segformat.CipherAES256GCM, dataKey, [16]byte{2})
+			if err != nil {
+				t.Fatalf("NewBlockCipher() error = %v", err)
+			}
+			if _, err := OpenEncrypted(codec, segformat.HashXXH64, otherSegment, sealed.Preamble, sealed.Stored); !errors.Is(err, segformat.ErrIntegrityMismatch) {
+				t.Fatalf("OpenEncrypted(other segment) error = %v, want %v", err, segformat.ErrIntegrityMismatch)
+			}
+		})
+	}
+}
+
+func TestStaticKeyProviderWrapUnwrapRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKEK := bytes.Repeat([]byte{1}, segformat.DataKeySize)
+	newKEK := bytes.Repeat([]byte{2}, segformat.DataKeySize)
+	dataKey, err := NewDataKey()
+	if err != nil {
+		t.Fatalf("NewDataKey() error = %v", err)
+	}
+
+	before, err := NewStaticKeyProvider("old", map[string][]byte{"old": oldKEK})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	keyID, wrapped, err := before.WrapKey(ctx, 1, dataKey)
+	if err != nil || keyID != "old" {
+		t.Fatalf("WrapKey() = %q, %v; want old", keyID, err)
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Fatal("wrapped key contains data key")
+	}
+
+	after, err := NewStaticKeyProvider("new", map[string][]byte{"old": oldKEK, "new": newKEK})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider(rotated) error = %v", err)
+	}
+	if got := after.KeyIDs(); len(got) != 2 || got[0] != "new" || got[1] != "old" {
+		t.Fatalf("KeyIDs() = %v", got)
+	}
+	got, err := after.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil || !bytes.Equal(got, dataKey) {
+		t.Fatalf("UnwrapKey(old) = %x, %v; want %x", got, err, dataKey)
+	}
+	if newID, _, err := after.WrapKey(ctx, 1, dataKey); err != nil || newID != "new" {
+		t.Fatalf("WrapKey(rotated) = %q, %v; want new", newID, err)
+	}
+
+	if _, err := after.UnwrapKey(ctx, "new", wrapped); !errors.Is(err, segformat.ErrIntegrityMismatch) {
+		t.Fatalf("UnwrapKey(wrong key id) error = %v, want %v", err, segformat.ErrIntegrityMismatch)
+	}
+	if _, err := after.UnwrapKey(ctx, "missing", wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("UnwrapKey(missing) error = %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err := NewStaticKeyProvider("absent", map[string][]byte{"old": oldKEK}); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("NewStaticKeyProvider(absent active) error = %v, want %v", err, ErrUnknownKey)
+	}
+}
@@ -1,15 +1,62 @@
 package segblock
 
-import "github.com/ankur-anand/unijord/partitionlog/segformat"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+// MaxZstdLevel is the highest accepted Compression.ZstdLevel.
+const MaxZstdLevel = 22
+
+// ErrInvalidCompression reports a Compression whose settings do not apply to
+// its codec.
+var ErrInvalidCompression = errors.New("segblock: invalid compression")
 
 type Meta struct {
 	BaseLSN        uint64
 	RecordCount    uint32
 	MinTimestampMS int64
 	MaxTimestampMS int64
+	// RecordFormat is the raw block's record encoding. It only bounds the
+	// record count the raw size can hold; 0 means
+	// segformat.DefaultRecordFormat.
+	RecordFormat segformat.RecordFormat
 }
 
 type Sealed struct {
 	Preamble segformat.BlockPreamble
 	Stored   []byte
 }
+
+// Compression selects a block codec and its encoder settings. Decoding needs
+// only the codec and, for CodecZstdDict, the dictionary.
+type Compression struct {
+	Codec segformat.Codec
+	// ZstdLevel is a zstd compression level from 1 to 22, mapped to the
+	// nearest encoder speed. 0 selects the default level. It must be 0 for
+	// codecs other than zstd and zstd-dict.
+	ZstdLevel int
+	// Dictionary is required for CodecZstdDict and must be nil otherwise.
+	Dictionary *Dictionary
+}
+
+func (c Compression) Validate() error {
+	if err := c.Codec.Validate(); err != nil {
+		return err
+	}
+	if c.Codec.UsesDictionary() != (c.Dictionary != nil) {
+		return fmt.Errorf("%w: codec %s dictionary=%t", ErrInvalidCompression, c.Codec, c.Dictionary != nil)
+	}
+	if c.ZstdLevel == 0 {
+		return nil
+	}
+	if c.Codec != segformat.CodecZstd && c.Codec != segformat.CodecZstdDict {
+		return fmt.Errorf("%w: zstd level=%d for codec %s", ErrInvalidCompression, c.ZstdLevel, c.Codec)
+	}
+	if c.ZstdLevel < 1 || c.ZstdLevel > MaxZstdLevel {
+		return fmt.Errorf("%w: zstd level=%d outside [1,%d]", ErrInvalidCompression, c.ZstdLevel, MaxZstdLevel)
+	}
+	return nil
+}
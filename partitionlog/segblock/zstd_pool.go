@@ -9,17 +9,9 @@ import (
 )
 
 var (
-	zstdEncoderPool = sync.Pool{New: func() any {
-		enc, err := zstd.NewWriter(nil,
-			zstd.WithEncoderConcurrency(1),
-			zstd.WithZeroFrames(true),
-			zstd.WithEncoderCRC(false),
-		)
-		if err != nil {
-			panic(fmt.Errorf("segblock: zstd encoder init: %w", err))
-		}
-		return enc
-	}}
+	// zstdEncoderPools holds one pool per encoder speed, indexed by
+	// zstd.EncoderLevel. init fills in each pool's New.
+	zstdEncoderPools [zstd.SpeedBestCompression + 1]sync.Pool
 
 	zstdDecoderPool = sync.Pool{New: func() any {
 		dec, err := zstd.NewReader(nil,
@@ -33,12 +25,38 @@ var (
 	}}
 )
 
-func getZstdEncoder() *zstd.Encoder {
-	return zstdEncoderPool.Get().(*zstd.Encoder)
+// zstdEncoderLevel maps a Compression.ZstdLevel to an encoder speed. 0
+// selects the default.
+func zstdEncoderLevel(level int) zstd.EncoderLevel {
+	if level == 0 {
+		return zstd.SpeedDefault
+	}
+	return zstd.EncoderLevelFromZstd(level)
 }
 
-func putZstdEncoder(enc *zstd.Encoder) {
-	zstdEncoderPool.Put(enc)
+func getZstdEncoder(level zstd.EncoderLevel) *zstd.Encoder {
+	return zstdEncoderPools[level].Get().(*zstd.Encoder)
+}
+
+func putZstdEncoder(level zstd.EncoderLevel, enc *zstd.Encoder) {
+	zstdEncoderPools[level].Put(enc)
+}
+
+func init() {
+	for level := zstd.SpeedFastest; level <= zstd.SpeedBestCompression; level++ {
+		zstdEncoderPools[level].New = func() any {
+			enc, err := zstd.NewWriter(nil,
+				zstd.WithEncoderConcurrency(1),
+				zstd.WithZeroFrames(true),
+				zstd.WithEncoderCRC(false),
+				zstd.WithEncoderLevel(level),
+			)
+			if err != nil {
+				panic(fmt.Errorf("segblock: zstd encoder init: %w", err))
+			}
+			return enc
+		}
+	}
 }
 
 func getZstdDecoder() *zstd.Decoder {
@@ -11,6 +11,7 @@ const (
 	BlockIndexEntrySize     = 64
 	TrailerSize             = 192
 	RecordHeaderSize        = 16
+	MinRecordSize           = 3
 	MaxRawBlockSize         = 16 << 20
 	MaxStoredBlockSize      = 20 << 20
 	MaxRecordHeaders        = 64
@@ -22,18 +23,33 @@ const (
 	MaxRecordCount          = 1<<32 - 1
 	DefaultRecordFormat     = RecordFormatV1
 	DefaultHashAlgorithm    = HashXXH64
+
+	KeyEnvelopePreambleSize = 64
+	MaxKeyIDLen             = 1024
+	MaxWrappedKeyLen        = 4096
+	DataKeySize             = 32
+	BlockNonceSize          = 12
+	BlockTagSize            = 16
+	BlockAADSize            = 48
+	SegmentDigestSize       = 32
 )
 
+// FlagEncrypted marks a segment whose stored blocks are encrypted under a data
+// key carried in the key envelope. It is set in both the file preamble and the
+// trailer.
+const FlagEncrypted uint32 = 1 << 0
+
 const (
 	MaxRecordLSN uint64 = ^uint64(0) - 1
 	ReservedLSN  uint64 = ^uint64(0)
 )
 
 var (
-	fileMagic    = [4]byte{'P', 'L', 'S', 'G'}
-	blockMagic   = [4]byte{'P', 'L', 'B', 'K'}
-	indexMagic   = [4]byte{'P', 'L', 'I', 'X'}
-	trailerMagic = [4]byte{'P', 'L', 'F', 'T'}
+	fileMagic     = [4]byte{'P', 'L', 'S', 'G'}
+	blockMagic    = [4]byte{'P', 'L', 'B', 'K'}
+	indexMagic    = [4]byte{'P', 'L', 'I', 'X'}
+	trailerMagic  = [4]byte{'P', 'L', 'F', 'T'}
+	envelopeMagic = [4]byte{'P', 'L', 'K', 'E'}
 )
 
 type Codec uint16
@@ -41,6 +57,13 @@ type Codec uint16
 const (
 	CodecNone Codec = 0
 	CodecZstd Codec = 1
+	// CodecLZ4 and CodecS2 trade compression ratio for lower encode and
+	// decode latency than CodecZstd.
+	CodecLZ4 Codec = 2
+	CodecS2  Codec = 3
+	// CodecZstdDict is CodecZstd with a trained dictionary named by the
+	// trailer's DictionaryID. It suits small, repetitive records.
+	CodecZstdDict Codec = 4
 )
 
 func (c Codec) String() string {
@@ -49,6 +72,12 @@ func (c Codec) String() string {
 		return "none"
 	case CodecZstd:
 		return "zstd"
+	case CodecLZ4:
+		return "lz4"
+	case CodecS2:
+		return "s2"
+	case CodecZstdDict:
+		return "zstd-dict"
 	default:
 		return fmt.Sprintf("unknown(%d)", uint16(c))
 	}
@@ -56,18 +85,27 @@ func (c Codec) String() string {
 
 func (c Codec) Validate() error {
 	switch c {
-	case CodecNone, CodecZstd:
+	case CodecNone, CodecZstd, CodecLZ4, CodecS2, CodecZstdDict:
 		return nil
 	default:
 		return fmt.Errorf("%w: %d", ErrUnsupportedCodec, uint16(c))
 	}
 }
 
+// UsesDictionary reports whether blocks of codec c need the segment's
+// dictionary to decode.
+func (c Codec) UsesDictionary() bool {
+	return c == CodecZstdDict
+}
+
 type HashAlgo uint16
 
 const (
 	HashCRC32C HashAlgo = 0
 	HashXXH64  HashAlgo = 1
+	// HashSHA256 uses the first eight bytes of SHA-256, big endian, for the
+	// 64-bit hash fields and also stores the full body digest in the trailer.
+	HashSHA256 HashAlgo = 2
 )
 
 func (h HashAlgo) String() string {
@@ -76,6 +114,8 @@ func (h HashAlgo) String() string {
 		return "crc32c"
 	case HashXXH64:
 		return "xxh64"
+	case HashSHA256:
+		return "sha256"
 	default:
 		return fmt.Sprintf("unknown(%d)", uint16(h))
 	}
@@ -83,23 +123,35 @@ func (h HashAlgo) String() string {
 
 func (h HashAlgo) Validate() error {
 	switch h {
-	case HashCRC32C, HashXXH64:
+	case HashCRC32C, HashXXH64, HashSHA256:
 		return nil
 	default:
 		return fmt.Errorf("%w: %d", ErrUnsupportedHashAlgo, uint16(h))
 	}
 }
 
+// Cryptographic reports whether h resists deliberate tampering. Segments
+// hashed with a cryptographic algorithm carry a full SegmentDigest.
+func (h HashAlgo) Cryptographic() bool {
+	return h == HashSHA256
+}
+
 type RecordFormat uint16
 
 const (
+	// RecordFormatV1 spends a fixed RecordHeaderSize on every record.
 	RecordFormatV1 RecordFormat = 1
+	// RecordFormatV2 encodes lengths as varints and timestamps as deltas from
+	// the block's MinTimestampMS. Its smallest record is MinRecordSize bytes.
+	RecordFormatV2 RecordFormat = 2
 )
 
 func (f RecordFormat) String() string {
 	switch f {
 	case RecordFormatV1:
 		return "v1"
+	case RecordFormatV2:
+		return "v2"
 	default:
 		return fmt.Sprintf("unknown(%d)", uint16(f))
 	}
@@ -107,9 +159,47 @@ func (f RecordFormat) String() string {
 
 func (f RecordFormat) Validate() error {
 	switch f {
-	case RecordFormatV1:
+	case RecordFormatV1, RecordFormatV2:
 		return nil
 	default:
 		return fmt.Errorf("%w: %d", ErrUnsupportedRecord, uint16(f))
 	}
 }
+
+// MinRecordSize is the smallest encoded record in format f, which bounds
+// the record count a raw block of a given size can hold.
+func (f RecordFormat) MinRecordSize() uint32 {
+	if f == RecordFormatV2 {
+		return MinRecordSize
+	}
+	return RecordHeaderSize
+}
+
+type Cipher uint16
+
+const (
+	CipherNone      Cipher = 0
+	CipherAES256GCM Cipher = 1
+)
+
+func (c Cipher) String() string {
+	switch c {
+	case CipherNone:
+		return "none"
+	case CipherAES256GCM:
+		return "aes-256-gcm"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint16(c))
+	}
+}
+
+// Validate accepts only ciphers usable for stored blocks. CipherNone is the
+// absence of encryption and never appears in a key envelope.
+func (c Cipher) Validate() error {
+	switch c {
+	case CipherAES256GCM:
+		return nil
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedCipher, uint16(c))
+	}
+}
@@ -0,0 +1,114 @@
+package segformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+func (e KeyEnvelope) Validate() error {
+	if e.Flags != 0 {
+		return fmt.Errorf("%w: key envelope flags must be zero", ErrInvalidSegment)
+	}
+	if err := e.Cipher.Validate(); err != nil {
+		return err
+	}
+	if len(e.KeyID) == 0 || len(e.KeyID) > MaxKeyIDLen {
+		return fmt.Errorf("%w: key_id_len=%d max=%d", ErrInvalidSegment, len(e.KeyID), MaxKeyIDLen)
+	}
+	if len(e.WrappedKey) == 0 || len(e.WrappedKey) > MaxWrappedKeyLen {
+		return fmt.Errorf("%w: wrapped_key_len=%d max=%d", ErrInvalidSegment, len(e.WrappedKey), MaxWrappedKeyLen)
+	}
+	return nil
+}
+
+// MarshalKeyEnvelope encodes e and returns it with the derived EnvelopeHash
+// populated.
+func MarshalKeyEnvelope(e KeyEnvelope, algo HashAlgo) ([]byte, KeyEnvelope, error) {
+	if err := e.Validate(); err != nil {
+		return nil, KeyEnvelope{}, err
+	}
+	buf := make([]byte, e.Size())
+	body := buf[KeyEnvelopePreambleSize:]
+	copy(body, e.KeyID)
+	copy(body[len(e.KeyID):], e.WrappedKey)
+	envelopeHash, err := HashBytes(algo, body)
+	if err != nil {
+		return nil, KeyEnvelope{}, err
+	}
+	copy(buf[0:4], envelopeMagic[:])
+	binary.BigEndian.PutUint16(buf[4:6], KeyEnvelopePreambleSize)
+	binary.BigEndian.PutUint16(buf[6:8], e.Flags)
+	binary.BigEndian.PutUint16(buf[8:10], uint16(e.Cipher))
+	binary.BigEndian.PutUint16(buf[10:12], uint16(len(e.KeyID)))
+	binary.BigEndian.PutUint32(buf[12:16], uint32(len(e.WrappedKey)))
+	binary.BigEndian.PutUint64(buf[16:24], envelopeHash)
+	e.WrappedKey = append([]byte(nil), e.WrappedKey...)
+	e.EnvelopeHash = envelopeHash
+	return buf, e, nil
+}
+
+// ParseKeyEnvelope decodes the whole envelope region. The returned WrappedKey
+// does not alias buf.
+func ParseKeyEnvelope(buf []byte, algo HashAlgo) (KeyEnvelope, error) {
+	var e KeyEnvelope
+	if len(buf) < KeyEnvelopePreambleSize {
+		return e, fmt.Errorf("%w: key envelope too small", ErrInvalidSegment)
+	}
+	if !bytes.Equal(buf[0:4], envelopeMagic[:]) {
+		return e, fmt.Errorf("%w: bad key envelope magic=%q", ErrInvalidSegment, buf[0:4])
+	}
+	if n := binary.BigEndian.Uint16(buf[4:6]); n != KeyEnvelopePreambleSize {
+		return e, fmt.Errorf("%w: key envelope preamble length=%d want=%d", ErrInvalidSegment, n, KeyEnvelopePreambleSize)
+	}
+	if !zero(buf[24:KeyEnvelopePreambleSize]) {
+		return e, fmt.Errorf("%w: key envelope reserved0 must be zero", ErrInvalidSegment)
+	}
+	e.Flags = binary.BigEndian.Uint16(buf[6:8])
+	e.Cipher = Cipher(binary.BigEndian.Uint16(buf[8:10]))
+	keyIDLen := int(binary.BigEndian.Uint16(buf[10:12]))
+	wrappedLen := uint64(binary.BigEndian.Uint32(buf[12:16]))
+	e.EnvelopeHash = binary.BigEndian.Uint64(buf[16:24])
+
+	wantLen := uint64(KeyEnvelopePreambleSize) + uint64(keyIDLen) + wrappedLen
+	if uint64(len(buf)) != wantLen {
+		return KeyEnvelope{}, fmt.Errorf("%w: key envelope size=%d want=%d", ErrInvalidSegment, len(buf), wantLen)
+	}
+	body := buf[KeyEnvelopePreambleSize:]
+	gotHash, err := HashBytes(algo, body)
+	if err != nil {
+		return KeyEnvelope{}, err
+	}
+	if gotHash != e.EnvelopeHash {
+		return KeyEnvelope{}, fmt.Errorf("%w: key envelope hash got=%x want=%x", ErrIntegrityMismatch, gotHash, e.EnvelopeHash)
+	}
+	e.KeyID = string(body[:keyIDLen])
+	e.WrappedKey = append([]byte(nil), body[keyIDLen:]...)
+	if err := e.Validate(); err != nil {
+		return KeyEnvelope{}, err
+	}
+	return e, nil
+}
+
+// BlockNonce returns the AEAD nonce of the block starting at baseLSN. Block
+// LSN ranges never overlap inside a segment, and every segment has its own
+// data key, so the nonce is never reused under one key.
+func BlockNonce(baseLSN uint64) [BlockNonceSize]byte {
+	var nonce [BlockNonceSize]byte
+	binary.BigEndian.PutUint64(nonce[0:8], baseLSN)
+	return nonce
+}
+
+// BlockAAD returns the additional data authenticated with an encrypted block.
+// It binds the ciphertext to its segment and to the block preamble fields
+// known before sealing, so a block cannot be replayed at another position.
+func BlockAAD(segmentUUID [16]byte, p BlockPreamble) [BlockAADSize]byte {
+	var aad [BlockAADSize]byte
+	copy(aad[0:16], segmentUUID[:])
+	binary.BigEndian.PutUint64(aad[16:24], p.BaseLSN)
+	binary.BigEndian.PutUint32(aad[24:28], p.RecordCount)
+	binary.BigEndian.PutUint32(aad[28:32], p.RawSize)
+	binary.BigEndian.PutUint64(aad[32:40], uint64(p.MinTimestampMS))
+	binary.BigEndian.PutUint64(aad[40:48], uint64(p.MaxTimestampMS))
+	return aad
+}
@@ -8,6 +8,7 @@ var (
 	ErrUnsupportedCodec    = errors.New("segformat: unsupported codec")
 	ErrUnsupportedHashAlgo = errors.New("segformat: unsupported hash algorithm")
 	ErrUnsupportedRecord   = errors.New("segformat: unsupported record format")
+	ErrUnsupportedCipher   = errors.New("segformat: unsupported cipher")
 	ErrRecordTooLarge      = errors.New("segformat: record too large")
 	ErrBlockTooLarge       = errors.New("segformat: block too large")
 	ErrIntegrityMismatch   = errors.New("segformat: integrity mismatch")
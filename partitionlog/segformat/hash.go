@@ -1,6 +1,8 @@
 package segformat
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"hash"
 	"hash/crc32"
@@ -20,7 +22,68 @@ func HashBytes(algo HashAlgo, data []byte) (uint64, error) {
 		return uint64(crc32.Checksum(data, crc32cTable)), nil
 	case HashXXH64:
 		return xxhash.Sum64(data), nil
+	case HashSHA256:
+		sum := sha256.Sum256(data)
+		return binary.BigEndian.Uint64(sum[:8]), nil
 	default:
 		return 0, fmt.Errorf("%w: %d", ErrUnsupportedHashAlgo, uint16(algo))
 	}
 }
+
+// Hasher streams a segment body. Sum64 is the value stored in 64-bit hash
+// fields; Digest is the full digest of a cryptographic algorithm and zero
+// otherwise.
+type Hasher interface {
+	hash.Hash
+	Sum64() uint64
+	Digest() [SegmentDigestSize]byte
+}
+
+// NewHasher returns a streaming Hasher for algo.
+func NewHasher(algo HashAlgo) (Hasher, error) {
+	switch algo {
+	case HashCRC32C:
+		return crc32Hasher{Hash32: NewCRC32C()}, nil
+	case HashXXH64:
+		return xxh64Hasher{Hash64: xxhash.New()}, nil
+	case HashSHA256:
+		return sha256Hasher{Hash: sha256.New()}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedHashAlgo, uint16(algo))
+	}
+}
+
+type crc32Hasher struct {
+	hash.Hash32
+}
+
+func (h crc32Hasher) Sum64() uint64 {
+	return uint64(h.Sum32())
+}
+
+func (crc32Hasher) Digest() [SegmentDigestSize]byte {
+	return [SegmentDigestSize]byte{}
+}
+
+type xxh64Hasher struct {
+	hash.Hash64
+}
+
+func (xxh64Hasher) Digest() [SegmentDigestSize]byte {
+	return [SegmentDigestSize]byte{}
+}
+
+type sha256Hasher struct {
+	hash.Hash
+}
+
+func (h sha256Hasher) Sum64() uint64 {
+	digest := h.Digest()
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+func (h sha256Hasher) Digest() [SegmentDigestSize]byte {
+	var digest [SegmentDigestSize]byte
+	h.Sum(digest[:0])
+	return digest
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
 )
@@ -25,14 +26,16 @@ type corpusManifest struct {
 }
 
 type corpusVector struct {
-	Name             string         `json:"name"`
-	File             string         `json:"file"`
-	FileSHA256       string         `json:"file_sha256"`
-	WriterByteStable bool           `json:"writer_byte_stable"`
-	Writer           writerSpec     `json:"writer"`
-	SegmentRef       segmentRefSpec `json:"segment_ref"`
-	Blocks           []blockSpec    `json:"blocks"`
-	Records          []recordSpec   `json:"records"`
+	Name             string          `json:"name"`
+	File             string          `json:"file"`
+	FileSHA256       string          `json:"file_sha256"`
+	WriterByteStable bool            `json:"writer_byte_stable"`
+	Writer           writerSpec      `json:"writer"`
+	SegmentRef       segmentRefSpec  `json:"segment_ref"`
+	Encryption       *encryptionSpec `json:"encryption,omitempty"`
+	Dictionary       *dictionarySpec `json:"dictionary,omitempty"`
+	Blocks           []blockSpec     `json:"blocks"`
+	Records          []recordSpec    `json:"records"`
 }
 
 type writerSpec struct {
@@ -63,6 +66,24 @@ type segmentRefSpec struct {
 	RecordFormat     enumSpec `json:"record_format"`
 	SegmentHash      string   `json:"segment_hash"`
 	TrailerHash      string   `json:"trailer_hash"`
+	DictionaryID     uint32   `json:"dictionary_id,omitempty"`
+	KeyID            string   `json:"key_id,omitempty"`
+	SegmentDigest    string   `json:"segment_digest,omitempty"`
+}
+
+// encryptionSpec carries the unwrapped data key of an encrypted vector. Key
+// wrapping is provider-defined, so other readers decrypt with data_key directly.
+type encryptionSpec struct {
+	Cipher  enumSpec `json:"cipher"`
+	KeyID   string   `json:"key_id"`
+	DataKey string   `json:"data_key"`
+}
+
+// dictionarySpec names the zstd dictionary file of a zstd-dict vector.
+type dictionarySpec struct {
+	ID         uint32 `json:"id"`
+	File       string `json:"file"`
+	FileSHA256 string `json:"file_sha256"`
 }
 
 type enumSpec struct {
@@ -101,6 +122,24 @@ type vectorDefinition struct {
 	writerByteStable bool
 	opts             segwriter.Options
 	records          []segwriter.Record
+	keys             *recordingKeyProvider
+	dictionaryFile   string
+}
+
+// recordingKeyProvider wraps data keys under a fixed corpus key and remembers
+// the last data key so the manifest can publish it.
+type recordingKeyProvider struct {
+	inner   *segblock.StaticKeyProvider
+	dataKey []byte
+}
+
+func (p *recordingKeyProvider) WrapKey(ctx context.Context, partition uint32, dataKey []byte) (string, []byte, error) {
+	p.dataKey = append([]byte(nil), dataKey...)
+	return p.inner.WrapKey(ctx, partition, dataKey)
+}
+
+func (p *recordingKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	return p.inner.UnwrapKey(ctx, keyID, wrapped)
 }
 
 func main() {
@@ -128,6 +167,11 @@ func main() {
 		if err := os.WriteFile(filepath.Join(*out, definition.file), object, 0o644); err != nil {
 			fatalf("write %s: %v", definition.file, err)
 		}
+		if definition.dictionaryFile != "" {
+			if err := os.WriteFile(filepath.Join(*out, definition.dictionaryFile), definition.opts.Dictionary.Bytes(), 0o644); err != nil {
+				fatalf("write %s: %v", definition.dictionaryFile, err)
+			}
+		}
 		manifest.Vectors = append(manifest.Vectors, vector)
 	}
 
@@ -168,6 +212,52 @@ func definitions() []vectorDefinition {
 	zstd.WriterTag = id16(0xa0)
 	zstd.CreatedUnixMS = 1_776_263_100_000
 
+	kek := make([]byte, segformat.DataKeySize)
+	for i := range kek {
+		kek[i] = byte(0xc0 + i)
+	}
+	static, err := segblock.NewStaticKeyProvider("corpus-kek-1", map[string][]byte{"corpus-kek-1": kek})
+	if err != nil {
+		fatalf("key provider: %v", err)
+	}
+	noneKeys := &recordingKeyProvider{inner: static}
+	encryptedNone := none
+	encryptedNone.SegmentUUID = id16(0xb0)
+	encryptedNone.KeyProvider = noneKeys
+	zstdKeys := &recordingKeyProvider{inner: static}
+	encryptedZstd := zstd
+	encryptedZstd.SegmentUUID = id16(0xd0)
+	encryptedZstd.KeyProvider = zstdKeys
+
+	sha := none
+	sha.HashAlgo = segformat.HashSHA256
+	sha.SegmentUUID = id16(0xe0)
+
+	lz4 := zstd
+	lz4.Codec = segformat.CodecLZ4
+	lz4.SegmentUUID = id16(0xe8)
+
+	s2 := zstd
+	s2.Codec = segformat.CodecS2
+	s2.SegmentUUID = id16(0xf0)
+
+	compact := none
+	compact.RecordFormat = segformat.RecordFormatV2
+	compact.SegmentUUID = id16(0x30)
+
+	rawDict, err := segblock.TrainDictionary(dictionarySamples(), 4<<10)
+	if err != nil {
+		fatalf("train dictionary: %v", err)
+	}
+	dict, err := segblock.NewDictionary(rawDict)
+	if err != nil {
+		fatalf("dictionary: %v", err)
+	}
+	zstdDict := zstd
+	zstdDict.Codec = segformat.CodecZstdDict
+	zstdDict.Dictionary = dict
+	zstdDict.SegmentUUID = id16(0xf8)
+
 	return []vectorDefinition{
 		{
 			name:             "v2-none-crc32c",
@@ -176,26 +266,7 @@ func definitions() []vectorDefinition {
 			writerEpoch:      9,
 			writerByteStable: true,
 			opts:             none,
-			records: []segwriter.Record{
-				{LSN: 9_007_199_254_740_993, TimestampMS: 1_776_263_000_001, Value: []byte("alpha")},
-				{
-					LSN: 9_007_199_254_740_994, TimestampMS: 1_776_263_000_001,
-					Headers: []segformat.Header{
-						{Key: []byte("content-type"), Value: []byte("application/json")},
-						{Key: []byte{0x00, 0x7f, 0xff}, Value: []byte{0x00, 0x01, 0xfe, 0xff}},
-					},
-					Value: []byte(`{"ok":true}`),
-				},
-				{
-					LSN: 9_007_199_254_740_995, TimestampMS: 1_776_263_000_010,
-					Headers: []segformat.Header{{Key: []byte("empty"), Value: nil}},
-					Value:   nil,
-				},
-				{
-					LSN: 9_007_199_254_740_996, TimestampMS: 1_776_263_000_011,
-					Value: []byte{0x00, 0x01, 0x02, 0x7f, 0x80, 0xfe, 0xff},
-				},
-			},
+			records:          noneRecords(),
 		},
 		{
 			name:             "v2-zstd-xxh64",
@@ -206,6 +277,95 @@ func definitions() []vectorDefinition {
 			opts:             zstd,
 			records:          zstdRecords(),
 		},
+		{
+			name:             "v2-none-crc32c-aes256gcm",
+			file:             "v2-none-crc32c-aes256gcm.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      11,
+			writerByteStable: false,
+			opts:             encryptedNone,
+			records:          noneRecords(),
+			keys:             noneKeys,
+		},
+		{
+			name:             "v2-zstd-xxh64-aes256gcm",
+			file:             "v2-zstd-xxh64-aes256gcm.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      12,
+			writerByteStable: false,
+			opts:             encryptedZstd,
+			records:          zstdRecords(),
+			keys:             zstdKeys,
+		},
+		{
+			name:             "v2-none-sha256",
+			file:             "v2-none-sha256.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      13,
+			writerByteStable: true,
+			opts:             sha,
+			records:          noneRecords(),
+		},
+		{
+			name:             "v2-lz4-xxh64",
+			file:             "v2-lz4-xxh64.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      14,
+			writerByteStable: false,
+			opts:             lz4,
+			records:          zstdRecords(),
+		},
+		{
+			name:             "v2-s2-xxh64",
+			file:             "v2-s2-xxh64.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      15,
+			writerByteStable: false,
+			opts:             s2,
+			records:          zstdRecords(),
+		},
+		{
+			name:             "v2-zstddict-xxh64",
+			file:             "v2-zstddict-xxh64.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      16,
+			writerByteStable: false,
+			opts:             zstdDict,
+			records:          dictionaryRecords(),
+			dictionaryFile:   "v2-zstddict-xxh64.zdict",
+		},
+		{
+			name:             "v2-none-crc32c-recordv2",
+			file:             "v2-none-crc32c-recordv2.plseg",
+			streamID:         "compatibility/v2",
+			writerEpoch:      17,
+			writerByteStable: true,
+			opts:             compact,
+			records:          noneRecords(),
+		},
+	}
+}
+
+func noneRecords() []segwriter.Record {
+	return []segwriter.Record{
+		{LSN: 9_007_199_254_740_993, TimestampMS: 1_776_263_000_001, Value: []byte("alpha")},
+		{
+			LSN: 9_007_199_254_740_994, TimestampMS: 1_776_263_000_001,
+			Headers: []segformat.Header{
+				{Key: []byte("content-type"), Value: []byte("application/json")},
+				{Key: []byte{0x00, 0x7f, 0xff}, Value: []byte{0x00, 0x01, 0xfe, 0xff}},
+			},
+			Value: []byte(`{"ok":true}`),
+		},
+		{
+			LSN: 9_007_199_254_740_995, TimestampMS: 1_776_263_000_010,
+			Headers: []segformat.Header{{Key: []byte("empty"), Value: nil}},
+			Value:   nil,
+		},
+		{
+			LSN: 9_007_199_254_740_996, TimestampMS: 1_776_263_000_011,
+			Value: []byte{0x00, 0x01, 0x02, 0x7f, 0x80, 0xfe, 0xff},
+		},
 	}
 }
 
@@ -229,6 +389,33 @@ func zstdRecords() []segwriter.Record {
 	return records
 }
 
+// dictionarySamples are small, similar events of the kind trained
+// dictionaries help with.
+func dictionarySamples() [][]byte {
+	samples := make([][]byte, 200)
+	for i := range samples {
+		samples[i] = dictionaryEvent(i)
+	}
+	return samples
+}
+
+func dictionaryRecords() []segwriter.Record {
+	records := make([]segwriter.Record, 8)
+	for i := range records {
+		records[i] = segwriter.Record{
+			LSN:         900 + uint64(i),
+			TimestampMS: 1_776_263_200_001 + int64(i),
+			Headers:     []segformat.Header{{Key: []byte("kind"), Value: []byte("event")}},
+			Value:       dictionaryEvent(1000 + i),
+		}
+	}
+	return records
+}
+
+func dictionaryEvent(i int) []byte {
+	return []byte(fmt.Sprintf(`{"event":"tool_call","session":"s-%03d","tool":"search","status":"ok","latency_ms":%d}`, i%41, i*7%300))
+}
+
 func buildVector(def vectorDefinition) (corpusVector, []byte, error) {
 	object, metadata, err := segwriter.Encode(context.Background(), def.records, def.opts)
 	if err != nil {
@@ -277,10 +464,30 @@ func buildVector(def vectorDefinition) (corpusVector, []byte, error) {
 			RecordFormat: enumSpec{
 				ID: uint16(trailer.RecordFormat), Name: trailer.RecordFormat.String(),
 			},
-			SegmentHash: hashHex(metadata.SegmentHash),
-			TrailerHash: hashHex(metadata.TrailerHash),
+			SegmentHash:  hashHex(metadata.SegmentHash),
+			TrailerHash:  hashHex(metadata.TrailerHash),
+			DictionaryID: metadata.DictionaryID,
+			KeyID:        metadata.KeyID,
 		},
 	}
+	if metadata.HashAlgo.Cryptographic() {
+		vector.SegmentRef.SegmentDigest = hex.EncodeToString(metadata.SegmentDigest[:])
+	}
+	if def.keys != nil {
+		vector.Encryption = &encryptionSpec{
+			Cipher:  enumSpec{ID: uint16(segformat.CipherAES256GCM), Name: segformat.CipherAES256GCM.String()},
+			KeyID:   metadata.KeyID,
+			DataKey: hex.EncodeToString(def.keys.dataKey),
+		}
+	}
+	if def.dictionaryFile != "" {
+		dictSum := sha256.Sum256(def.opts.Dictionary.Bytes())
+		vector.Dictionary = &dictionarySpec{
+			ID:         def.opts.Dictionary.ID(),
+			File:       def.dictionaryFile,
+			FileSHA256: hex.EncodeToString(dictSum[:]),
+		}
+	}
 	for _, entry := range entries {
 		vector.Blocks = append(vector.Blocks, blockSpec{
 			Offset:         decimal(entry.BlockOffset),
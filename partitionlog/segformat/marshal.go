@@ -202,11 +202,14 @@ func (t Trailer) MarshalBinary() ([]byte, error) {
 	binary.BigEndian.PutUint32(buf[60:64], t.BlockCount)
 	binary.BigEndian.PutUint64(buf[64:72], t.BlockIndexOffset)
 	binary.BigEndian.PutUint32(buf[72:76], t.BlockIndexLength)
+	binary.BigEndian.PutUint32(buf[76:80], t.DictionaryID)
 	binary.BigEndian.PutUint64(buf[80:88], t.TotalSize)
 	binary.BigEndian.PutUint64(buf[88:96], uint64(t.CreatedUnixMS))
 	copy(buf[96:112], t.SegmentUUID[:])
 	copy(buf[112:128], t.WriterTag[:])
 	binary.BigEndian.PutUint64(buf[128:136], t.SegmentHash)
+	binary.BigEndian.PutUint32(buf[144:148], t.KeyEnvelopeLength)
+	copy(buf[148:180], t.SegmentDigest[:])
 
 	trailerHash, err := HashBytes(t.HashAlgo, buf)
 	if err != nil {
@@ -244,7 +247,7 @@ func ParseTrailer(buf []byte, objectSize uint64) (Trailer, error) {
 	if v := binary.BigEndian.Uint16(buf[6:8]); v != Version {
 		return t, fmt.Errorf("%w: version=%d", ErrUnsupportedVersion, v)
 	}
-	if !zero(buf[22:24]) || !zero(buf[76:80]) || !zero(buf[144:192]) {
+	if !zero(buf[22:24]) || !zero(buf[180:192]) {
 		return t, fmt.Errorf("%w: trailer reserved fields must be zero", ErrInvalidSegment)
 	}
 
@@ -261,12 +264,15 @@ func ParseTrailer(buf []byte, objectSize uint64) (Trailer, error) {
 	t.BlockCount = binary.BigEndian.Uint32(buf[60:64])
 	t.BlockIndexOffset = binary.BigEndian.Uint64(buf[64:72])
 	t.BlockIndexLength = binary.BigEndian.Uint32(buf[72:76])
+	t.DictionaryID = binary.BigEndian.Uint32(buf[76:80])
 	t.TotalSize = binary.BigEndian.Uint64(buf[80:88])
 	t.CreatedUnixMS = int64(binary.BigEndian.Uint64(buf[88:96]))
 	copy(t.SegmentUUID[:], buf[96:112])
 	copy(t.WriterTag[:], buf[112:128])
 	t.SegmentHash = binary.BigEndian.Uint64(buf[128:136])
 	t.TrailerHash = binary.BigEndian.Uint64(buf[136:144])
+	t.KeyEnvelopeLength = binary.BigEndian.Uint32(buf[144:148])
+	copy(t.SegmentDigest[:], buf[148:180])
 
 	if err := t.Validate(objectSize); err != nil {
 		return Trailer{}, err
@@ -11,18 +11,26 @@ import (
 type RawBlockScanner struct {
 	raw             []byte
 	block           BlockPreamble
+	format          RecordFormat
 	offset          int
 	recordIndex     uint32
 	prevTimestampMS int64
 }
 
-// NewRawBlockScanner validates the block envelope and returns a scanner at the
-// first record. Record-envelope validation remains incremental as Next advances.
+// NewRawBlockScanner returns a scanner over a RecordFormatV1 block. See
+// NewRawBlockScannerFormat.
 func NewRawBlockScanner(raw []byte, block BlockPreamble) (RawBlockScanner, error) {
-	if err := validateRawBlockEnvelope(raw, block); err != nil {
+	return NewRawBlockScannerFormat(RecordFormatV1, raw, block)
+}
+
+// NewRawBlockScannerFormat validates the block envelope and returns a scanner
+// at the first record of a block encoded in format. Record-envelope
+// validation remains incremental as Next advances.
+func NewRawBlockScannerFormat(format RecordFormat, raw []byte, block BlockPreamble) (RawBlockScanner, error) {
+	if err := validateRawBlockEnvelope(format, raw, block); err != nil {
 		return RawBlockScanner{}, err
 	}
-	return RawBlockScanner{raw: raw, block: block}, nil
+	return RawBlockScanner{raw: raw, block: block, format: format}, nil
 }
 
 // Next returns the next record. The returned headers and value alias the raw
@@ -37,10 +45,7 @@ func (s *RawBlockScanner) Next() (Record, bool, error) {
 		}
 		return Record{}, false, nil
 	}
-	if len(s.raw)-s.offset < RecordHeaderSize {
-		return Record{}, false, fmt.Errorf("%w: truncated raw record header", ErrInvalidSegment)
-	}
-	rawRecord, next, err := scanRawRecord(s.raw, s.offset)
+	rawRecord, next, err := scanRecord(s.format, s.raw, s.offset, s.block)
 	if err != nil {
 		return Record{}, false, err
 	}
@@ -67,20 +72,32 @@ func (s *RawBlockScanner) Next() (Record, bool, error) {
 	return record, true, nil
 }
 
+// EncodeRawBlock encodes records as a RecordFormatV1 block.
 func EncodeRawBlock(records []RawRecord) ([]byte, error) {
+	return EncodeRawBlockFormat(RecordFormatV1, records)
+}
+
+// EncodeRawBlockFormat encodes records in format. The block's
+// MinTimestampMS is the first record's timestamp, because timestamps must
+// not decrease within a block.
+func EncodeRawBlockFormat(format RecordFormat, records []RawRecord) ([]byte, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
 	if len(records) == 0 {
 		return nil, fmt.Errorf("%w: raw block must contain at least one record", ErrInvalidSegment)
 	}
+	minTimestampMS := records[0].TimestampMS
 	total := 0
 	prevTS := records[0].TimestampMS
 	for i, r := range records {
-		size, err := RawRecordSize(r)
-		if err != nil {
-			return nil, err
-		}
 		if i > 0 && r.TimestampMS < prevTS {
 			return nil, fmt.Errorf("%w: timestamp regression at record %d", ErrInvalidSegment, i)
 		}
+		size, err := format.RawRecordSize(minTimestampMS, r)
+		if err != nil {
+			return nil, err
+		}
 		prevTS = r.TimestampMS
 		total += size
 		if total > MaxRawBlockSize {
@@ -90,7 +107,7 @@ func EncodeRawBlock(records []RawRecord) ([]byte, error) {
 	raw := make([]byte, 0, total)
 	for _, r := range records {
 		var err error
-		raw, err = AppendRawRecord(raw, r)
+		raw, err = format.AppendRawRecord(raw, minTimestampMS, r)
 		if err != nil {
 			return nil, err
 		}
@@ -98,18 +115,21 @@ func EncodeRawBlock(records []RawRecord) ([]byte, error) {
 	return raw, nil
 }
 
+// DecodeRawBlock decodes a RecordFormatV1 block.
 func DecodeRawBlock(raw []byte, block BlockPreamble) ([]Record, error) {
-	if err := validateRawBlockEnvelope(raw, block); err != nil {
+	return DecodeRawBlockFormat(RecordFormatV1, raw, block)
+}
+
+// DecodeRawBlockFormat decodes every record of a block encoded in format.
+func DecodeRawBlockFormat(format RecordFormat, raw []byte, block BlockPreamble) ([]Record, error) {
+	if err := validateRawBlockEnvelope(format, raw, block); err != nil {
 		return nil, err
 	}
 	records := make([]Record, 0, block.RecordCount)
 	offset := 0
 	var prevTimestampMS int64
 	for i := uint32(0); i < block.RecordCount; i++ {
-		if len(raw)-offset < RecordHeaderSize {
-			return nil, fmt.Errorf("%w: truncated raw record header", ErrInvalidSegment)
-		}
-		rawRecord, next, err := scanRawRecord(raw, offset)
+		rawRecord, next, err := scanRecord(format, raw, offset, block)
 		if err != nil {
 			return nil, err
 		}
@@ -135,7 +155,10 @@ func DecodeRawBlock(raw []byte, block BlockPreamble) ([]Record, error) {
 	return records, nil
 }
 
-func validateRawBlockEnvelope(raw []byte, block BlockPreamble) error {
+func validateRawBlockEnvelope(format RecordFormat, raw []byte, block BlockPreamble) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
 	if err := block.Validate(); err != nil {
 		return err
 	}
@@ -145,16 +168,18 @@ func validateRawBlockEnvelope(raw []byte, block BlockPreamble) error {
 	if len(raw) != int(block.RawSize) {
 		return fmt.Errorf("%w: raw_size=%d want=%d", ErrInvalidSegment, len(raw), block.RawSize)
 	}
-	if block.RecordCount > uint32(len(raw)/RecordHeaderSize) {
+	if block.RecordCount > uint32(len(raw))/format.MinRecordSize() {
 		return fmt.Errorf("%w: record_count=%d cannot fit in raw_size=%d", ErrInvalidSegment, block.RecordCount, len(raw))
 	}
 	return nil
 }
 
+// RecordSize returns the RecordFormatV1 encoded size of a record.
 func RecordSize(headers []Header, value []byte) (int, error) {
 	return RawRecordSize(RawRecord{Headers: headers, Value: value})
 }
 
+// RawRecordSize returns the RecordFormatV1 encoded size of record.
 func RawRecordSize(record RawRecord) (int, error) {
 	if len(record.Value) > MaxRecordValueLen {
 		return 0, fmt.Errorf("%w: value_len=%d max=%d", ErrRecordTooLarge, len(record.Value), MaxRecordValueLen)
@@ -166,6 +191,7 @@ func RawRecordSize(record RawRecord) (int, error) {
 	return RecordHeaderSize + headersLen + len(record.Value), nil
 }
 
+// AppendRawRecord appends the RecordFormatV1 encoding of record to dst.
 func AppendRawRecord(dst []byte, record RawRecord) ([]byte, error) {
 	size, err := RawRecordSize(record)
 	if err != nil {
@@ -183,6 +209,43 @@ func AppendRawRecord(dst []byte, record RawRecord) ([]byte, error) {
 	return dst, nil
 }
 
+// RawRecordSize returns the size of record encoded in format f, in a block
+// whose MinTimestampMS is minTimestampMS. RecordFormatV1 ignores
+// minTimestampMS.
+func (f RecordFormat) RawRecordSize(minTimestampMS int64, record RawRecord) (int, error) {
+	switch f {
+	case RecordFormatV1:
+		return RawRecordSize(record)
+	case RecordFormatV2:
+		return rawRecordSizeV2(minTimestampMS, record)
+	default:
+		return 0, f.Validate()
+	}
+}
+
+// AppendRawRecord appends record encoded in format f to dst, for a block
+// whose MinTimestampMS is minTimestampMS.
+func (f RecordFormat) AppendRawRecord(dst []byte, minTimestampMS int64, record RawRecord) ([]byte, error) {
+	switch f {
+	case RecordFormatV1:
+		return AppendRawRecord(dst, record)
+	case RecordFormatV2:
+		return appendRawRecordV2(dst, minTimestampMS, record)
+	default:
+		return nil, f.Validate()
+	}
+}
+
+func scanRecord(format RecordFormat, raw []byte, off int, block BlockPreamble) (RawRecord, int, error) {
+	if format == RecordFormatV2 {
+		return scanRawRecordV2(raw, off, block.MinTimestampMS, block.MaxTimestampMS)
+	}
+	if len(raw)-off < RecordHeaderSize {
+		return RawRecord{}, 0, fmt.Errorf("%w: truncated raw record header", ErrInvalidSegment)
+	}
+	return scanRawRecord(raw, off)
+}
+
 func scanRawRecord(raw []byte, off int) (RawRecord, int, error) {
 	ts := int64(binary.BigEndian.Uint64(raw[off : off+8]))
 	headersLen := binary.BigEndian.Uint32(raw[off+8 : off+12])
@@ -0,0 +1,152 @@
+package segformat
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RecordFormatV2 record layout, every integer an unsigned LEB128 varint in
+// its shortest encoding:
+//
+//	timestamp_delta  timestamp_ms - block.min_timestamp_ms
+//	header_count
+//	header_count * (key_len, value_len, key, value)
+//	value_len
+//	value
+//
+// Header limits match RecordFormatV1: the RecordFormatV1 encoded length of
+// the headers must not exceed MaxRecordHeaderBytes.
+
+func rawRecordSizeV2(minTimestampMS int64, record RawRecord) (int, error) {
+	if record.TimestampMS < minTimestampMS {
+		return 0, fmt.Errorf("%w: timestamp=%d before block min timestamp=%d", ErrInvalidSegment, record.TimestampMS, minTimestampMS)
+	}
+	if len(record.Value) > MaxRecordValueLen {
+		return 0, fmt.Errorf("%w: value_len=%d max=%d", ErrRecordTooLarge, len(record.Value), MaxRecordValueLen)
+	}
+	if _, err := encodedHeadersLen(record.Headers); err != nil {
+		return 0, err
+	}
+	size := uvarintLen(timestampDelta(minTimestampMS, record.TimestampMS)) + uvarintLen(uint64(len(record.Headers)))
+	for _, h := range record.Headers {
+		size += uvarintLen(uint64(len(h.Key))) + uvarintLen(uint64(len(h.Value))) + len(h.Key) + len(h.Value)
+	}
+	return size + uvarintLen(uint64(len(record.Value))) + len(record.Value), nil
+}
+
+func appendRawRecordV2(dst []byte, minTimestampMS int64, record RawRecord) ([]byte, error) {
+	if _, err := rawRecordSizeV2(minTimestampMS, record); err != nil {
+		return nil, err
+	}
+	dst = binary.AppendUvarint(dst, timestampDelta(minTimestampMS, record.TimestampMS))
+	dst = binary.AppendUvarint(dst, uint64(len(record.Headers)))
+	for _, h := range record.Headers {
+		dst = binary.AppendUvarint(dst, uint64(len(h.Key)))
+		dst = binary.AppendUvarint(dst, uint64(len(h.Value)))
+		dst = append(dst, h.Key...)
+		dst = append(dst, h.Value...)
+	}
+	dst = binary.AppendUvarint(dst, uint64(len(record.Value)))
+	return append(dst, record.Value...), nil
+}
+
+func scanRawRecordV2(raw []byte, off int, minTimestampMS, maxTimestampMS int64) (RawRecord, int, error) {
+	delta, off, err := readUvarint(raw, off)
+	if err != nil {
+		return RawRecord{}, 0, err
+	}
+	if delta > timestampDelta(minTimestampMS, maxTimestampMS) {
+		return RawRecord{}, 0, fmt.Errorf("%w: timestamp delta=%d outside block range", ErrInvalidSegment, delta)
+	}
+	count, off, err := readUvarint(raw, off)
+	if err != nil {
+		return RawRecord{}, 0, err
+	}
+	if count > MaxRecordHeaders {
+		return RawRecord{}, 0, fmt.Errorf("%w: header_count=%d max=%d", ErrRecordTooLarge, count, MaxRecordHeaders)
+	}
+	var headers []Header
+	if count > 0 {
+		headers = make([]Header, count)
+		headersLen := uint64(2)
+		for i := range headers {
+			var keyLen, valueLen uint64
+			keyLen, off, err = readUvarint(raw, off)
+			if err != nil {
+				return RawRecord{}, 0, err
+			}
+			valueLen, off, err = readUvarint(raw, off)
+			if err != nil {
+				return RawRecord{}, 0, err
+			}
+			if keyLen == 0 {
+				return RawRecord{}, 0, fmt.Errorf("%w: header %d key is empty", ErrInvalidSegment, i)
+			}
+			if keyLen > MaxRecordHeaderKeyLen {
+				return RawRecord{}, 0, fmt.Errorf("%w: header %d key_len=%d max=%d", ErrRecordTooLarge, i, keyLen, MaxRecordHeaderKeyLen)
+			}
+			if valueLen > MaxRecordHeaderValueLen {
+				return RawRecord{}, 0, fmt.Errorf("%w: header %d value_len=%d max=%d", ErrRecordTooLarge, i, valueLen, MaxRecordHeaderValueLen)
+			}
+			headersLen += 4 + keyLen + valueLen
+			if headersLen > MaxRecordHeaderBytes {
+				return RawRecord{}, 0, fmt.Errorf("%w: headers_len=%d max=%d", ErrRecordTooLarge, headersLen, MaxRecordHeaderBytes)
+			}
+			if uint64(len(raw)-off) < keyLen+valueLen {
+				return RawRecord{}, 0, fmt.Errorf("%w: truncated record header bytes", ErrInvalidSegment)
+			}
+			keyEnd := off + int(keyLen)
+			valueEnd := keyEnd + int(valueLen)
+			headers[i] = Header{
+				Key:   raw[off:keyEnd:keyEnd],
+				Value: raw[keyEnd:valueEnd:valueEnd],
+			}
+			off = valueEnd
+		}
+	}
+	valueLen, off, err := readUvarint(raw, off)
+	if err != nil {
+		return RawRecord{}, 0, err
+	}
+	if valueLen > MaxRecordValueLen {
+		return RawRecord{}, 0, fmt.Errorf("%w: value_len=%d max=%d", ErrRecordTooLarge, valueLen, MaxRecordValueLen)
+	}
+	if uint64(len(raw)-off) < valueLen {
+		return RawRecord{}, 0, fmt.Errorf("%w: truncated raw record", ErrInvalidSegment)
+	}
+	next := off + int(valueLen)
+	return RawRecord{
+		TimestampMS: int64(uint64(minTimestampMS) + delta),
+		Headers:     headers,
+		Value:       raw[off:next:next],
+	}, next, nil
+}
+
+// timestampDelta returns ts - minTS for ts >= minTS. The unsigned
+// subtraction is exact even when the signed one would overflow.
+func timestampDelta(minTS, ts int64) uint64 {
+	return uint64(ts) - uint64(minTS)
+}
+
+// readUvarint reads the varint at raw[off:] and returns it with the offset
+// after it. Non-shortest encodings are rejected so every record has exactly
+// one valid encoding.
+func readUvarint(raw []byte, off int) (uint64, int, error) {
+	v, n := binary.Uvarint(raw[off:])
+	if n == 0 {
+		return 0, 0, fmt.Errorf("%w: truncated raw record varint", ErrInvalidSegment)
+	}
+	if n < 0 || n != uvarintLen(v) {
+		return 0, 0, fmt.Errorf("%w: malformed raw record varint", ErrInvalidSegment)
+	}
+	return v, off + n, nil
+}
+
+func uvarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
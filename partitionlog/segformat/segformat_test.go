@@ -2,6 +2,7 @@ package segformat
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
 	"errors"
 	"math"
@@ -93,6 +94,97 @@ func TestTrailerValidateRejectsRangeOverflow(t *testing.T) {
 	}
 }
 
+func TestSHA256TrailerCarriesSegmentDigest(t *testing.T) {
+	t.Parallel()
+
+	indexOffset := uint64(FilePreambleSize + BlockPreambleSize + 128)
+	indexLength := uint32(IndexPreambleSize + BlockIndexEntrySize)
+	in := Trailer{
+		Codec:            CodecNone,
+		HashAlgo:         HashSHA256,
+		RecordFormat:     RecordFormatV1,
+		BaseLSN:          7,
+		LastLSN:          7,
+		RecordCount:      1,
+		BlockCount:       1,
+		BlockIndexOffset: indexOffset,
+		BlockIndexLength: indexLength,
+		TotalSize:        indexOffset + uint64(indexLength) + TrailerSize,
+		SegmentHash:      0x0102030405060708,
+		SegmentDigest:    sha256.Sum256([]byte("segment body")),
+	}
+	buf, out, err := MarshalTrailer(in)
+	if err != nil {
+		t.Fatalf("MarshalTrailer() error = %v", err)
+	}
+	if !bytes.Equal(buf[148:180], in.SegmentDigest[:]) {
+		t.Fatalf("segment_digest = %x, want %x", buf[148:180], in.SegmentDigest)
+	}
+	if out.SegmentDigest != in.SegmentDigest {
+		t.Fatalf("parsed segment_digest = %x, want %x", out.SegmentDigest, in.SegmentDigest)
+	}
+	want := sha256.Sum256(append(append([]byte(nil), buf[:136]...), append(make([]byte, 8), buf[144:]...)...))
+	if out.TrailerHash != binary.BigEndian.Uint64(want[:8]) {
+		t.Fatalf("trailer_hash = %x, want sha256 prefix %x", out.TrailerHash, want[:8])
+	}
+
+	buf[150] ^= 0x1
+	if _, err := ParseTrailer(buf, in.TotalSize); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("ParseTrailer(corrupt digest) error = %v, want %v", err, ErrIntegrityMismatch)
+	}
+	missing := in
+	missing.SegmentDigest = [SegmentDigestSize]byte{}
+	if err := missing.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(sha256 without digest) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	xxh := in
+	xxh.HashAlgo = HashXXH64
+	if err := xxh.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(xxh64 with digest) error = %v, want %v", err, ErrInvalidSegment)
+	}
+}
+
+func TestZstdDictTrailerCarriesDictionaryID(t *testing.T) {
+	t.Parallel()
+
+	indexOffset := uint64(FilePreambleSize + BlockPreambleSize + 128)
+	indexLength := uint32(IndexPreambleSize + BlockIndexEntrySize)
+	in := Trailer{
+		Codec:            CodecZstdDict,
+		HashAlgo:         HashXXH64,
+		RecordFormat:     RecordFormatV1,
+		DictionaryID:     0x8001_0203,
+		BaseLSN:          7,
+		LastLSN:          7,
+		RecordCount:      1,
+		BlockCount:       1,
+		BlockIndexOffset: indexOffset,
+		BlockIndexLength: indexLength,
+		TotalSize:        indexOffset + uint64(indexLength) + TrailerSize,
+	}
+	buf, out, err := MarshalTrailer(in)
+	if err != nil {
+		t.Fatalf("MarshalTrailer() error = %v", err)
+	}
+	if got := binary.BigEndian.Uint32(buf[76:80]); got != in.DictionaryID {
+		t.Fatalf("dictionary_id = %x, want %x", got, in.DictionaryID)
+	}
+	if out.DictionaryID != in.DictionaryID {
+		t.Fatalf("parsed dictionary_id = %x, want %x", out.DictionaryID, in.DictionaryID)
+	}
+
+	missing := in
+	missing.DictionaryID = 0
+	if err := missing.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(zstd-dict without dictionary) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	zstd := in
+	zstd.Codec = CodecZstd
+	if err := zstd.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(zstd with dictionary) error = %v, want %v", err, ErrInvalidSegment)
+	}
+}
+
 func TestBlockPreambleMarshalParse(t *testing.T) {
 	in := BlockPreamble{
 		StoredSize:     123,
@@ -383,6 +475,119 @@ func TestRawBlockScannerReportsLaterTimestampRegression(t *testing.T) {
 	}
 }
 
+func TestRecordFormatV2RoundTripIsSmallerThanV1(t *testing.T) {
+	in := []RawRecord{
+		{
+			TimestampMS: 1_776_263_000_000,
+			Headers:     []Header{{Key: []byte("kind"), Value: []byte("created")}, {Key: []byte{0x00, 0xff}, Value: nil}},
+			Value:       []byte("alpha"),
+		},
+		{TimestampMS: 1_776_263_000_000, Value: nil},
+		{TimestampMS: 1_776_263_000_250, Value: bytes.Repeat([]byte("v"), 200)},
+	}
+	v1, err := EncodeRawBlock(in)
+	if err != nil {
+		t.Fatalf("EncodeRawBlock() error = %v", err)
+	}
+	v2, err := EncodeRawBlockFormat(RecordFormatV2, in)
+	if err != nil {
+		t.Fatalf("EncodeRawBlockFormat(v2) error = %v", err)
+	}
+	if len(v2) >= len(v1) {
+		t.Fatalf("v2 size = %d, v1 size = %d; want v2 smaller", len(v2), len(v1))
+	}
+	block := BlockPreamble{
+		StoredSize:     uint32(len(v2)),
+		RawSize:        uint32(len(v2)),
+		RecordCount:    uint32(len(in)),
+		BaseLSN:        50,
+		MinTimestampMS: in[0].TimestampMS,
+		MaxTimestampMS: in[2].TimestampMS,
+		BlockHash:      1,
+	}
+	records, err := DecodeRawBlockFormat(RecordFormatV2, v2, block)
+	if err != nil {
+		t.Fatalf("DecodeRawBlockFormat(v2) error = %v", err)
+	}
+	scanner, err := NewRawBlockScannerFormat(RecordFormatV2, v2, block)
+	if err != nil {
+		t.Fatalf("NewRawBlockScannerFormat(v2) error = %v", err)
+	}
+	for i, want := range in {
+		scanned, ok, err := scanner.Next()
+		if err != nil || !ok {
+			t.Fatalf("Next(%d) ok=%v error=%v", i, ok, err)
+		}
+		for _, got := range []Record{records[i], scanned} {
+			if got.LSN != 50+uint64(i) || got.TimestampMS != want.TimestampMS || !bytes.Equal(got.Value, want.Value) || len(got.Headers) != len(want.Headers) {
+				t.Fatalf("record %d = %+v, want %+v", i, got, want)
+			}
+			for j := range want.Headers {
+				if !bytes.Equal(got.Headers[j].Key, want.Headers[j].Key) || !bytes.Equal(got.Headers[j].Value, want.Headers[j].Value) {
+					t.Fatalf("record %d header %d = %+v, want %+v", i, j, got.Headers[j], want.Headers[j])
+				}
+			}
+		}
+	}
+	if _, ok, err := scanner.Next(); err != nil || ok {
+		t.Fatalf("Next(end) ok=%v error=%v", ok, err)
+	}
+
+	// A V1 block is not a valid V2 block, and the reverse.
+	if _, err := DecodeRawBlockFormat(RecordFormatV2, v1, BlockPreamble{
+		StoredSize: uint32(len(v1)), RawSize: uint32(len(v1)), RecordCount: block.RecordCount,
+		BaseLSN: 50, MinTimestampMS: block.MinTimestampMS, MaxTimestampMS: block.MaxTimestampMS, BlockHash: 1,
+	}); err == nil {
+		t.Fatal("DecodeRawBlockFormat(v2, v1 bytes) error = nil")
+	}
+	if _, err := DecodeRawBlock(v2, block); err == nil {
+		t.Fatal("DecodeRawBlock(v2 bytes) error = nil")
+	}
+}
+
+func TestRecordFormatV2RejectsMalformedRecords(t *testing.T) {
+	block := func(raw []byte) BlockPreamble {
+		return BlockPreamble{
+			StoredSize:     uint32(len(raw)),
+			RawSize:        uint32(len(raw)),
+			RecordCount:    1,
+			BaseLSN:        1,
+			MinTimestampMS: 100,
+			MaxTimestampMS: 105,
+			BlockHash:      1,
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		raw  []byte
+		want error
+	}{
+		{name: "valid", raw: []byte{0x05, 0x00, 0x01, 'x'}},
+		{name: "delta_past_max", raw: []byte{0x06, 0x00, 0x01, 'x'}, want: ErrInvalidSegment},
+		{name: "non_shortest_varint", raw: []byte{0x85, 0x00, 0x00, 0x01, 'x'}, want: ErrInvalidSegment},
+		{name: "truncated_value", raw: []byte{0x00, 0x00, 0x02, 'x'}, want: ErrInvalidSegment},
+		{name: "empty_header_key", raw: []byte{0x00, 0x01, 0x00, 0x00, 0x00}, want: ErrInvalidSegment},
+		{name: "too_many_headers", raw: []byte{0x00, 0x41, 0x00}, want: ErrRecordTooLarge},
+		{name: "trailing_bytes", raw: []byte{0x00, 0x00, 0x00, 0x00}, want: ErrInvalidSegment},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeRawBlockFormat(RecordFormatV2, tc.raw, block(tc.raw))
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("DecodeRawBlockFormat() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("DecodeRawBlockFormat() error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+	if _, err := EncodeRawBlockFormat(RecordFormat(9), []RawRecord{{TimestampMS: 1}}); !errors.Is(err, ErrUnsupportedRecord) {
+		t.Fatalf("EncodeRawBlockFormat(unknown) error = %v, want %v", err, ErrUnsupportedRecord)
+	}
+}
+
 func TestRecordCloneDetachesValue(t *testing.T) {
 	in := Record{
 		LSN:         7,
@@ -807,3 +1012,110 @@ func TestValidatePreambleTrailer(t *testing.T) {
 		t.Fatalf("ValidatePreambleTrailer(mismatch) error = %v, want %v", err, ErrInvalidSegment)
 	}
 }
+
+func TestKeyEnvelopeMarshalParse(t *testing.T) {
+	t.Parallel()
+
+	in := KeyEnvelope{
+		Cipher:     CipherAES256GCM,
+		KeyID:      "kek-2026",
+		WrappedKey: []byte("wrapped-data-key-bytes"),
+	}
+	buf, out, err := MarshalKeyEnvelope(in, HashXXH64)
+	if err != nil {
+		t.Fatalf("MarshalKeyEnvelope() error = %v", err)
+	}
+	if len(buf) != in.Size() {
+		t.Fatalf("len(buf) = %d, want %d", len(buf), in.Size())
+	}
+	if string(buf[0:4]) != "PLKE" {
+		t.Fatalf("magic = %q", buf[0:4])
+	}
+	if got := Cipher(binary.BigEndian.Uint16(buf[8:10])); got != CipherAES256GCM {
+		t.Fatalf("cipher = %v, want %v", got, CipherAES256GCM)
+	}
+	if out.EnvelopeHash == 0 {
+		t.Fatal("envelope_hash = 0, want non-zero")
+	}
+
+	parsed, err := ParseKeyEnvelope(buf, HashXXH64)
+	if err != nil {
+		t.Fatalf("ParseKeyEnvelope() error = %v", err)
+	}
+	if parsed.KeyID != in.KeyID || !bytes.Equal(parsed.WrappedKey, in.WrappedKey) || parsed.EnvelopeHash != out.EnvelopeHash {
+		t.Fatalf("parsed envelope = %+v, want %+v", parsed, out)
+	}
+
+	corrupt := bytes.Clone(buf)
+	corrupt[len(corrupt)-1] ^= 0x1
+	if _, err := ParseKeyEnvelope(corrupt, HashXXH64); !errors.Is(err, ErrIntegrityMismatch) {
+		t.Fatalf("ParseKeyEnvelope(corrupt) error = %v, want %v", err, ErrIntegrityMismatch)
+	}
+	reserved := bytes.Clone(buf)
+	reserved[40] = 1
+	if _, err := ParseKeyEnvelope(reserved, HashXXH64); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("ParseKeyEnvelope(reserved) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	if _, err := ParseKeyEnvelope(buf[:len(buf)-1], HashXXH64); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("ParseKeyEnvelope(short) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	if _, _, err := MarshalKeyEnvelope(KeyEnvelope{Cipher: CipherNone, KeyID: "k", WrappedKey: []byte{1}}, HashXXH64); !errors.Is(err, ErrUnsupportedCipher) {
+		t.Fatalf("MarshalKeyEnvelope(cipher none) error = %v, want %v", err, ErrUnsupportedCipher)
+	}
+}
+
+func TestEncryptedTrailerMarshalParse(t *testing.T) {
+	t.Parallel()
+
+	envelopeLength := uint32(KeyEnvelopePreambleSize + 40)
+	indexOffset := uint64(FilePreambleSize) + uint64(envelopeLength) + BlockPreambleSize + 128
+	indexLength := uint32(IndexPreambleSize + BlockIndexEntrySize)
+	in := Trailer{
+		Partition:         2,
+		Codec:             CodecZstd,
+		HashAlgo:          HashXXH64,
+		RecordFormat:      RecordFormatV1,
+		Flags:             FlagEncrypted,
+		BaseLSN:           100,
+		LastLSN:           100,
+		RecordCount:       1,
+		BlockCount:        1,
+		BlockIndexOffset:  indexOffset,
+		BlockIndexLength:  indexLength,
+		TotalSize:         indexOffset + uint64(indexLength) + TrailerSize,
+		KeyEnvelopeLength: envelopeLength,
+	}
+	if got, want := in.FirstBlockOffset(), uint64(FilePreambleSize)+uint64(envelopeLength); got != want {
+		t.Fatalf("FirstBlockOffset() = %d, want %d", got, want)
+	}
+	buf, out, err := MarshalTrailer(in)
+	if err != nil {
+		t.Fatalf("MarshalTrailer() error = %v", err)
+	}
+	if got := binary.BigEndian.Uint32(buf[144:148]); got != envelopeLength {
+		t.Fatalf("key_envelope_len = %d, want %d", got, envelopeLength)
+	}
+	parsed, err := ParseTrailer(buf, in.TotalSize)
+	if err != nil {
+		t.Fatalf("ParseTrailer() error = %v", err)
+	}
+	if parsed != out {
+		t.Fatalf("parsed trailer = %+v, want %+v", parsed, out)
+	}
+
+	plain := in
+	plain.Flags = 0
+	if err := plain.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(envelope without flag) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	missing := in
+	missing.KeyEnvelopeLength = 0
+	if err := missing.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(flag without envelope) error = %v, want %v", err, ErrInvalidSegment)
+	}
+	unknown := in
+	unknown.Flags = 1 << 5
+	if err := unknown.Validate(in.TotalSize); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Validate(unknown flag) error = %v, want %v", err, ErrInvalidSegment)
+	}
+}
@@ -67,6 +67,36 @@ type Trailer struct {
 	WriterTag        [16]byte
 	SegmentHash      uint64
 	TrailerHash      uint64
+	// KeyEnvelopeLength is the size of the key envelope region after the file
+	// preamble. It is zero unless Flags has FlagEncrypted.
+	KeyEnvelopeLength uint32
+	// SegmentDigest is the full digest over the same bytes as SegmentHash. It
+	// is set only when HashAlgo is cryptographic and zero otherwise.
+	SegmentDigest [SegmentDigestSize]byte
+	// DictionaryID names the compression dictionary of a CodecZstdDict
+	// segment. It is zero for every other codec.
+	DictionaryID uint32
+}
+
+// FirstBlockOffset is the absolute offset of the first block preamble.
+func (t Trailer) FirstBlockOffset() uint64 {
+	return FilePreambleSize + uint64(t.KeyEnvelopeLength)
+}
+
+// KeyEnvelope is the region after the file preamble of an encrypted segment.
+// It names the provider key that wraps the segment data key and carries the
+// wrapped key itself.
+type KeyEnvelope struct {
+	Flags        uint16
+	Cipher       Cipher
+	KeyID        string
+	WrappedKey   []byte
+	EnvelopeHash uint64
+}
+
+// Size is the encoded length of the envelope region.
+func (e KeyEnvelope) Size() int {
+	return KeyEnvelopePreambleSize + len(e.KeyID) + len(e.WrappedKey)
 }
 
 // Header is optional record metadata encoded with the record envelope. Key and
@@ -76,6 +106,79 @@ type Header struct {
 	Value []byte
 }
 
+const (
+	// RedactionHeaderKey is the first header of a redacted record. Its value
+	// is the redaction reason, which may be empty.
+	RedactionHeaderKey = "partitionlog-redacted"
+	// RedactionDigestHeaderKey is the optional second header of a redacted
+	// record. Its value is the content digest of the original record, so a
+	// tamper-evident segment keeps its records root after redaction.
+	RedactionDigestHeaderKey = "partitionlog-redacted-digest"
+	// RedactedValue is the tombstone that replaces a redacted record's value.
+	RedactedValue = "\x00partitionlog:redacted"
+)
+
+// RedactedRecord returns the tombstone record that replaces a redacted record
+// with the given timestamp. digest is the original record's content digest
+// and may be empty.
+func RedactedRecord(timestampMS int64, reason string, digest []byte) RawRecord {
+	headers := []Header{{Key: []byte(RedactionHeaderKey), Value: []byte(reason)}}
+	if len(digest) > 0 {
+		headers = append(headers, Header{Key: []byte(RedactionDigestHeaderKey), Value: append([]byte(nil), digest...)})
+	}
+	return RawRecord{
+		TimestampMS: timestampMS,
+		Headers:     headers,
+		Value:       []byte(RedactedValue),
+	}
+}
+
+// RedactionReason reports whether headers and value form a redaction
+// tombstone and returns the recorded reason.
+func RedactionReason(headers []Header, value []byte) (string, bool) {
+	if !isRedaction(headers, value) {
+		return "", false
+	}
+	return string(headers[0].Value), true
+}
+
+// RedactionDigest returns the original content digest carried by a redaction
+// tombstone.
+func RedactionDigest(headers []Header, value []byte) ([]byte, bool) {
+	if !isRedaction(headers, value) || len(headers) != 2 {
+		return nil, false
+	}
+	return headers[1].Value, true
+}
+
+// ReservedRecord reports whether a record carries a redaction header key or
+// the RedactedValue marker. Only redaction writes them; a caller record that
+// did could pass for a redaction tombstone.
+func ReservedRecord(headers []Header, value []byte) bool {
+	if string(value) == RedactedValue {
+		return true
+	}
+	for _, header := range headers {
+		if key := string(header.Key); key == RedactionHeaderKey || key == RedactionDigestHeaderKey {
+			return true
+		}
+	}
+	return false
+}
+
+func isRedaction(headers []Header, value []byte) bool {
+	switch {
+	case string(value) != RedactedValue:
+		return false
+	case len(headers) == 1:
+		return string(headers[0].Key) == RedactionHeaderKey
+	case len(headers) == 2:
+		return string(headers[0].Key) == RedactionHeaderKey && string(headers[1].Key) == RedactionDigestHeaderKey
+	default:
+		return false
+	}
+}
+
 // RawRecord is the record shape encoded inside one uncompressed block.
 type RawRecord struct {
 	TimestampMS int64
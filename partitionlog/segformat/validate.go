@@ -3,8 +3,8 @@ package segformat
 import "fmt"
 
 func (p FilePreamble) Validate() error {
-	if p.Flags != 0 {
-		return fmt.Errorf("%w: file preamble flags must be zero", ErrInvalidSegment)
+	if p.Flags&^FlagEncrypted != 0 {
+		return fmt.Errorf("%w: file preamble flags=%x", ErrInvalidSegment, p.Flags)
 	}
 	if p.BaseLSN > MaxRecordLSN {
 		return fmt.Errorf("%w: base_lsn=%d is reserved", ErrInvalidSegment, p.BaseLSN)
@@ -40,7 +40,7 @@ func (p BlockPreamble) Validate() error {
 	if p.RecordCount == 0 {
 		return fmt.Errorf("%w: record_count must be positive", ErrInvalidSegment)
 	}
-	if p.RecordCount > p.RawSize/RecordHeaderSize {
+	if p.RecordCount > p.RawSize/MinRecordSize {
 		return fmt.Errorf("%w: record_count=%d cannot fit in raw_size=%d", ErrInvalidSegment, p.RecordCount, p.RawSize)
 	}
 	if _, err := lastLSN(p.BaseLSN, p.RecordCount); err != nil {
@@ -84,7 +84,7 @@ func (e BlockIndexEntry) Validate() error {
 	if e.RecordCount == 0 {
 		return fmt.Errorf("%w: index record_count must be positive", ErrInvalidSegment)
 	}
-	if e.RecordCount > e.RawSize/RecordHeaderSize {
+	if e.RecordCount > e.RawSize/MinRecordSize {
 		return fmt.Errorf("%w: index record_count=%d cannot fit in raw_size=%d", ErrInvalidSegment, e.RecordCount, e.RawSize)
 	}
 	if _, err := lastLSN(e.BaseLSN, e.RecordCount); err != nil {
@@ -97,15 +97,28 @@ func (e BlockIndexEntry) Validate() error {
 }
 
 func (t Trailer) Validate(objectSize uint64) error {
-	if t.Flags != 0 {
-		return fmt.Errorf("%w: trailer flags must be zero", ErrInvalidSegment)
+	if t.Flags&^FlagEncrypted != 0 {
+		return fmt.Errorf("%w: trailer flags=%x", ErrInvalidSegment, t.Flags)
+	}
+	if t.Flags&FlagEncrypted == 0 {
+		if t.KeyEnvelopeLength != 0 {
+			return fmt.Errorf("%w: key_envelope_length=%d without encryption", ErrInvalidSegment, t.KeyEnvelopeLength)
+		}
+	} else if t.KeyEnvelopeLength < KeyEnvelopePreambleSize+2 || t.KeyEnvelopeLength > KeyEnvelopePreambleSize+MaxKeyIDLen+MaxWrappedKeyLen {
+		return fmt.Errorf("%w: key_envelope_length=%d", ErrInvalidSegment, t.KeyEnvelopeLength)
 	}
 	if err := t.Codec.Validate(); err != nil {
 		return err
 	}
+	if t.Codec.UsesDictionary() == (t.DictionaryID == 0) {
+		return fmt.Errorf("%w: dictionary_id=%d does not match codec=%s", ErrInvalidSegment, t.DictionaryID, t.Codec)
+	}
 	if err := t.HashAlgo.Validate(); err != nil {
 		return err
 	}
+	if t.HashAlgo.Cryptographic() == zero(t.SegmentDigest[:]) {
+		return fmt.Errorf("%w: segment_digest does not match hash_algo=%s", ErrInvalidSegment, t.HashAlgo)
+	}
 	if err := t.RecordFormat.Validate(); err != nil {
 		return err
 	}
@@ -138,7 +151,7 @@ func (t Trailer) Validate(objectSize uint64) error {
 	if t.BlockIndexLength != uint32(expectedIndexLength) {
 		return fmt.Errorf("%w: block_index_length=%d want=%d", ErrInvalidSegment, t.BlockIndexLength, expectedIndexLength)
 	}
-	if t.BlockIndexOffset < FilePreambleSize+BlockPreambleSize {
+	if t.BlockIndexOffset < t.FirstBlockOffset()+BlockPreambleSize {
 		return fmt.Errorf("%w: block_index_offset=%d too small", ErrInvalidSegment, t.BlockIndexOffset)
 	}
 	if t.BlockIndexOffset > ^uint64(0)-uint64(t.BlockIndexLength) {
@@ -165,14 +178,17 @@ func ValidateIndex(entries []BlockIndexEntry, trailer Trailer) error {
 	if uint32(len(entries)) != trailer.BlockCount {
 		return fmt.Errorf("%w: entries=%d block_count=%d", ErrBlockIndexMismatch, len(entries), trailer.BlockCount)
 	}
-	if entries[0].BlockOffset != FilePreambleSize {
-		return fmt.Errorf("%w: first block_offset=%d want=%d", ErrBlockIndexMismatch, entries[0].BlockOffset, FilePreambleSize)
+	if entries[0].BlockOffset != trailer.FirstBlockOffset() {
+		return fmt.Errorf("%w: first block_offset=%d want=%d", ErrBlockIndexMismatch, entries[0].BlockOffset, trailer.FirstBlockOffset())
 	}
 	var recordCount uint64
 	for i := range entries {
 		if err := entries[i].Validate(); err != nil {
 			return err
 		}
+		if entries[i].RecordCount > entries[i].RawSize/trailer.RecordFormat.MinRecordSize() {
+			return fmt.Errorf("%w: index record_count=%d cannot fit in %s raw_size=%d", ErrInvalidSegment, entries[i].RecordCount, trailer.RecordFormat, entries[i].RawSize)
+		}
 		recordCount += uint64(entries[i].RecordCount)
 		if i == 0 {
 			if entries[i].BaseLSN != trailer.BaseLSN {
@@ -233,7 +249,8 @@ func ValidatePreambleTrailer(p FilePreamble, t Trailer) error {
 	if err := t.Validate(t.TotalSize); err != nil {
 		return err
 	}
-	if p.Partition != t.Partition ||
+	if p.Flags != t.Flags ||
+		p.Partition != t.Partition ||
 		p.Codec != t.Codec ||
 		p.HashAlgo != t.HashAlgo ||
 		p.RecordFormat != t.RecordFormat ||
@@ -7,6 +7,8 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -14,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
 )
@@ -27,14 +30,16 @@ type compatibilityManifest struct {
 }
 
 type compatibilityVector struct {
-	Name             string                    `json:"name"`
-	File             string                    `json:"file"`
-	FileSHA256       string                    `json:"file_sha256"`
-	WriterByteStable bool                      `json:"writer_byte_stable"`
-	Writer           compatibilityWriterSpec   `json:"writer"`
-	SegmentRef       compatibilitySegmentSpec  `json:"segment_ref"`
-	Blocks           []compatibilityBlockSpec  `json:"blocks"`
-	Records          []compatibilityRecordSpec `json:"records"`
+	Name             string                       `json:"name"`
+	File             string                       `json:"file"`
+	FileSHA256       string                       `json:"file_sha256"`
+	WriterByteStable bool                         `json:"writer_byte_stable"`
+	Writer           compatibilityWriterSpec      `json:"writer"`
+	SegmentRef       compatibilitySegmentSpec     `json:"segment_ref"`
+	Encryption       *compatibilityEncryptionSpec `json:"encryption"`
+	Dictionary       *compatibilityDictionarySpec `json:"dictionary"`
+	Blocks           []compatibilityBlockSpec     `json:"blocks"`
+	Records          []compatibilityRecordSpec    `json:"records"`
 }
 
 type compatibilityWriterSpec struct {
@@ -65,6 +70,21 @@ type compatibilitySegmentSpec struct {
 	RecordFormat     compatibilityEnumSpec `json:"record_format"`
 	SegmentHash      string                `json:"segment_hash"`
 	TrailerHash      string                `json:"trailer_hash"`
+	DictionaryID     uint32                `json:"dictionary_id"`
+	KeyID            string                `json:"key_id"`
+	SegmentDigest    string                `json:"segment_digest"`
+}
+
+type compatibilityEncryptionSpec struct {
+	Cipher  compatibilityEnumSpec `json:"cipher"`
+	KeyID   string                `json:"key_id"`
+	DataKey string                `json:"data_key"`
+}
+
+type compatibilityDictionarySpec struct {
+	ID         uint32 `json:"id"`
+	File       string `json:"file"`
+	FileSHA256 string `json:"file_sha256"`
 }
 
 type compatibilityEnumSpec struct {
@@ -113,7 +133,22 @@ func TestSegmentCompatibilityCorpus(t *testing.T) {
 			object := loadCompatibilityObject(t, vector)
 			ref := compatibilityRef(t, vector.SegmentRef)
 			store := newMemoryStore(map[string][]byte{ref.URI: object})
-			reader, err := Open(context.Background(), store, ref, DefaultOptions())
+			opts := DefaultOptions()
+			if vector.Encryption != nil {
+				if _, err := Open(context.Background(), store, ref, opts); !errors.Is(err, ErrKeyUnavailable) {
+					t.Fatalf("Open(no key provider) error = %v, want %v", err, ErrKeyUnavailable)
+				}
+				opts.KeyProvider = compatibilityKeyProvider(t, *vector.Encryption)
+			}
+			if vector.Dictionary != nil {
+				if _, err := Open(context.Background(), store, ref, opts); !errors.Is(err, ErrDictionaryUnavailable) {
+					t.Fatalf("Open(no dictionary provider) error = %v, want %v", err, ErrDictionaryUnavailable)
+				}
+				dicts := NewDictionaryCache(nil)
+				dicts.Add(loadCompatibilityDictionary(t, *vector.Dictionary))
+				opts.Dictionaries = dicts
+			}
+			reader, err := Open(context.Background(), store, ref, opts)
 			if err != nil {
 				t.Fatalf("Open() error = %v", err)
 			}
@@ -152,21 +187,39 @@ func loadCompatibilityManifest(t testing.TB) compatibilityManifest {
 
 func loadCompatibilityObject(t testing.TB, vector compatibilityVector) []byte {
 	t.Helper()
-	body, err := os.ReadFile(filepath.Join(compatibilityCorpusDir, vector.File))
+	return loadCompatibilityFile(t, vector.File, vector.FileSHA256)
+}
+
+func loadCompatibilityFile(t testing.TB, file string, fileSHA256 string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join(compatibilityCorpusDir, file))
 	if err != nil {
-		t.Fatalf("read compatibility object %q: %v", vector.File, err)
+		t.Fatalf("read compatibility file %q: %v", file, err)
 	}
-	want, err := hex.DecodeString(vector.FileSHA256)
+	want, err := hex.DecodeString(fileSHA256)
 	if err != nil || len(want) != sha256.Size {
-		t.Fatalf("invalid file_sha256 %q", vector.FileSHA256)
+		t.Fatalf("invalid file_sha256 %q", fileSHA256)
 	}
 	got := sha256.Sum256(body)
 	if !bytes.Equal(got[:], want) {
-		t.Fatalf("sha256(%s) = %x, want %x", vector.File, got, want)
+		t.Fatalf("sha256(%s) = %x, want %x", file, got, want)
 	}
 	return body
 }
 
+func loadCompatibilityDictionary(t testing.TB, spec compatibilityDictionarySpec) *segblock.Dictionary {
+	t.Helper()
+	body := loadCompatibilityFile(t, spec.File, spec.FileSHA256)
+	dict, err := segblock.NewDictionary(body)
+	if err != nil {
+		t.Fatalf("NewDictionary(%s) error = %v", spec.File, err)
+	}
+	if dict.ID() != spec.ID {
+		t.Fatalf("dictionary id = %d, want %d", dict.ID(), spec.ID)
+	}
+	return dict
+}
+
 func compatibilityRef(t testing.TB, spec compatibilitySegmentSpec) pmeta.SegmentRef {
 	t.Helper()
 	ref := pmeta.SegmentRef{
@@ -189,6 +242,15 @@ func compatibilityRef(t testing.TB, spec compatibilitySegmentSpec) pmeta.Segment
 		HashAlgo:         segformat.HashAlgo(spec.HashAlgorithm.ID),
 		SegmentHash:      parseHash(t, "segment_hash", spec.SegmentHash),
 		TrailerHash:      parseHash(t, "trailer_hash", spec.TrailerHash),
+		DictionaryID:     spec.DictionaryID,
+		KeyID:            spec.KeyID,
+	}
+	if spec.SegmentDigest != "" {
+		digest, err := hex.DecodeString(spec.SegmentDigest)
+		if err != nil || len(digest) != segformat.SegmentDigestSize {
+			t.Fatalf("segment_digest=%q is not a %d-byte hex value", spec.SegmentDigest, segformat.SegmentDigestSize)
+		}
+		ref.SegmentDigest = pmeta.Hash(digest)
 	}
 	if err := ref.Validate(); err != nil {
 		t.Fatalf("SegmentRef.Validate() error = %v", err)
@@ -196,6 +258,36 @@ func compatibilityRef(t testing.TB, spec compatibilitySegmentSpec) pmeta.Segment
 	return ref
 }
 
+// corpusKeyProvider hands out the published data key of one corpus vector.
+// The corpus leaves key wrapping provider-defined.
+type corpusKeyProvider struct {
+	keyID   string
+	dataKey []byte
+}
+
+func compatibilityKeyProvider(t testing.TB, spec compatibilityEncryptionSpec) *corpusKeyProvider {
+	t.Helper()
+	if segformat.Cipher(spec.Cipher.ID) != segformat.CipherAES256GCM || spec.Cipher.Name != segformat.CipherAES256GCM.String() {
+		t.Fatalf("cipher = %+v, want %s", spec.Cipher, segformat.CipherAES256GCM)
+	}
+	dataKey, err := hex.DecodeString(spec.DataKey)
+	if err != nil || len(dataKey) != segformat.DataKeySize {
+		t.Fatalf("data_key=%q is not a %d-byte hex value", spec.DataKey, segformat.DataKeySize)
+	}
+	return &corpusKeyProvider{keyID: spec.KeyID, dataKey: dataKey}
+}
+
+func (p *corpusKeyProvider) WrapKey(context.Context, uint32, []byte) (string, []byte, error) {
+	return "", nil, errors.New("corpus key provider cannot wrap keys")
+}
+
+func (p *corpusKeyProvider) UnwrapKey(_ context.Context, keyID string, _ []byte) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, fmt.Errorf("%w: %q", segblock.ErrUnknownKey, keyID)
+	}
+	return append([]byte(nil), p.dataKey...), nil
+}
+
 func assertCompatibilityMetadata(t testing.TB, reader *Reader, vector compatibilityVector) {
 	t.Helper()
 	ref := compatibilityRef(t, vector.SegmentRef)
@@ -206,6 +298,9 @@ func assertCompatibilityMetadata(t testing.TB, reader *Reader, vector compatibil
 		preamble.Codec != ref.Codec || preamble.HashAlgo != ref.HashAlgo {
 		t.Fatalf("preamble = %+v, does not match segment ref", preamble)
 	}
+	if reader.KeyID() != ref.KeyID || (trailer.Flags&segformat.FlagEncrypted != 0) != (vector.Encryption != nil) {
+		t.Fatalf("key_id = %q flags = %#x, want key_id %q", reader.KeyID(), trailer.Flags, ref.KeyID)
+	}
 	if trailer.CreatedUnixMS != vector.Writer.CreatedUnixMS {
 		t.Fatalf("created_unix_ms = %d, want %d", trailer.CreatedUnixMS, vector.Writer.CreatedUnixMS)
 	}
@@ -268,6 +363,7 @@ func assertStableWriterBytes(t testing.TB, vector compatibilityVector, want []by
 	opts := segwriter.DefaultOptions(ref.Partition)
 	opts.Codec = ref.Codec
 	opts.HashAlgo = ref.HashAlgo
+	opts.RecordFormat = segformat.RecordFormat(vector.SegmentRef.RecordFormat.ID)
 	opts.TargetBlockSize = vector.Writer.TargetBlockSize
 	opts.PartSize = 128
 	opts.SealParallelism = 1
@@ -375,6 +471,7 @@ func compatibilityRefFromObject(object []byte) (pmeta.SegmentRef, bool) {
 		HashAlgo:         trailer.HashAlgo,
 		SegmentHash:      trailer.SegmentHash,
 		TrailerHash:      trailer.TrailerHash,
+		DictionaryID:     trailer.DictionaryID,
 	}
 	if preamble.Partition != ref.Partition || preamble.SegmentUUID != ref.SegmentUUID || preamble.WriterTag != ref.WriterTag {
 		return pmeta.SegmentRef{}, false
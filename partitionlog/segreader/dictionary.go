@@ -0,0 +1,75 @@
+package segreader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
+)
+
+// DictionaryCache is a DictionaryProvider that remembers every dictionary
+// its underlying provider returns. Dictionaries are immutable once
+// published, so entries never expire. It is safe for concurrent use.
+type DictionaryCache struct {
+	provider segblock.DictionaryProvider
+
+	mu    sync.Mutex
+	dicts map[uint32]*segblock.Dictionary
+}
+
+var _ segblock.DictionaryProvider = (*DictionaryCache)(nil)
+
+// NewDictionaryCache wraps provider. Dictionaries may also be added
+// directly with Add, in which case provider may be nil.
+func NewDictionaryCache(provider segblock.DictionaryProvider) *DictionaryCache {
+	return &DictionaryCache{provider: provider, dicts: make(map[uint32]*segblock.Dictionary)}
+}
+
+// Add makes d available without consulting the underlying provider.
+func (c *DictionaryCache) Add(d *segblock.Dictionary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dicts[d.ID()] = d
+}
+
+func (c *DictionaryCache) Dictionary(ctx context.Context, id uint32) (*segblock.Dictionary, error) {
+	c.mu.Lock()
+	d, ok := c.dicts[id]
+	c.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+	if c.provider == nil {
+		return nil, fmt.Errorf("%w: id=%d", segblock.ErrUnknownDictionary, id)
+	}
+	d, err := c.provider.Dictionary(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.ID() != id {
+		return nil, fmt.Errorf("%w: provider returned id=%d want=%d", segblock.ErrInvalidDictionary, d.ID(), id)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.dicts[id]; ok {
+		return cached, nil
+	}
+	c.dicts[id] = d
+	return d, nil
+}
+
+// loadDictionary resolves the dictionary a zstd-dict trailer names.
+func loadDictionary(ctx context.Context, provider segblock.DictionaryProvider, id uint32) (*segblock.Dictionary, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("%w: dictionary_id=%d: no dictionary provider", ErrDictionaryUnavailable, id)
+	}
+	d, err := provider.Dictionary(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: dictionary_id=%d: %w", ErrDictionaryUnavailable, id, err)
+	}
+	if d.ID() != id {
+		return nil, fmt.Errorf("%w: dictionary_id=%d provider returned id=%d", ErrDictionaryUnavailable, id, d.ID())
+	}
+	return d, nil
+}
@@ -7,4 +7,15 @@ var (
 	ErrInvalidSegment = errors.New("partitionlog/segreader: invalid segment")
 	ErrStoreRead      = errors.New("partitionlog/segreader: store read failed")
 	ErrCorruptData    = errors.New("partitionlog/segreader: corrupt data")
+	// ErrKeyUnavailable reports an encrypted segment whose data key cannot be
+	// unwrapped, either because no KeyProvider is configured or because the
+	// provider refused the key.
+	ErrKeyUnavailable = errors.New("partitionlog/segreader: segment key unavailable")
+	// ErrKeyDestroyed reports an encrypted segment whose key was destroyed on
+	// purpose. Unlike ErrKeyUnavailable it is permanent.
+	ErrKeyDestroyed = errors.New("partitionlog/segreader: segment key destroyed")
+	// ErrDictionaryUnavailable reports a zstd-dict segment whose dictionary
+	// cannot be loaded, either because no DictionaryProvider is configured or
+	// because the provider does not hold it.
+	ErrDictionaryUnavailable = errors.New("partitionlog/segreader: segment dictionary unavailable")
 )
@@ -3,10 +3,8 @@ package segreader
 import (
 	"context"
 	"fmt"
-	"hash"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
-	"github.com/cespare/xxhash/v2"
 )
 
 const segmentHashChunkBytes = uint64(8 << 20)
@@ -18,8 +16,9 @@ func validateSegmentHash(
 	length uint64,
 	algo segformat.HashAlgo,
 	want uint64,
+	wantDigest [segformat.SegmentDigestSize]byte,
 ) error {
-	return validateSegmentHashChunks(ctx, store, uri, length, algo, want, segmentHashChunkBytes)
+	return validateSegmentHashChunks(ctx, store, uri, length, algo, want, wantDigest, segmentHashChunkBytes)
 }
 
 func validateSegmentHashChunks(
@@ -29,12 +28,13 @@ func validateSegmentHashChunks(
 	length uint64,
 	algo segformat.HashAlgo,
 	want uint64,
+	wantDigest [segformat.SegmentDigestSize]byte,
 	chunkBytes uint64,
 ) error {
 	if chunkBytes == 0 {
 		return fmt.Errorf("%w: hash chunk bytes must be positive", ErrInvalidOptions)
 	}
-	hasher, sum, err := segmentHasher(algo)
+	hasher, err := segformat.NewHasher(algo)
 	if err != nil {
 		return fmt.Errorf("%w: hash segment: %w", ErrCorruptData, err)
 	}
@@ -49,21 +49,11 @@ func validateSegmentHashChunks(
 		}
 		off += n
 	}
-	if got := sum(); got != want {
+	if got := hasher.Sum64(); got != want {
 		return fmt.Errorf("%w: segment hash got=%x want=%x", ErrCorruptData, got, want)
 	}
-	return nil
-}
-
-func segmentHasher(algo segformat.HashAlgo) (hash.Hash, func() uint64, error) {
-	switch algo {
-	case segformat.HashCRC32C:
-		hasher := segformat.NewCRC32C()
-		return hasher, func() uint64 { return uint64(hasher.Sum32()) }, nil
-	case segformat.HashXXH64:
-		hasher := xxhash.New()
-		return hasher, hasher.Sum64, nil
-	default:
-		return nil, nil, fmt.Errorf("unsupported hash algorithm: %d", algo)
+	if got := hasher.Digest(); got != wantDigest {
+		return fmt.Errorf("%w: segment digest got=%x want=%x", ErrCorruptData, got, wantDigest)
 	}
+	return nil
 }
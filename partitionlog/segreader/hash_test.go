@@ -2,6 +2,7 @@ package segreader
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"testing"
 
@@ -12,7 +13,7 @@ func TestValidateSegmentHashUsesBoundedRanges(t *testing.T) {
 	t.Parallel()
 
 	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
-	for _, algo := range []segformat.HashAlgo{segformat.HashCRC32C, segformat.HashXXH64} {
+	for _, algo := range []segformat.HashAlgo{segformat.HashCRC32C, segformat.HashXXH64, segformat.HashSHA256} {
 		algo := algo
 		t.Run(algo.String(), func(t *testing.T) {
 			t.Parallel()
@@ -20,6 +21,10 @@ func TestValidateSegmentHashUsesBoundedRanges(t *testing.T) {
 			if err != nil {
 				t.Fatalf("HashBytes() error = %v", err)
 			}
+			var wantDigest [segformat.SegmentDigestSize]byte
+			if algo.Cryptographic() {
+				wantDigest = sha256.Sum256(data)
+			}
 			reads := 0
 			store := SegmentStoreFunc(func(ctx context.Context, uri string, off, n uint64) ([]byte, error) {
 				reads++
@@ -28,12 +33,16 @@ func TestValidateSegmentHashUsesBoundedRanges(t *testing.T) {
 				}
 				return append([]byte(nil), data[off:off+n]...), nil
 			})
-			if err := validateSegmentHashChunks(context.Background(), store, "segment", uint64(len(data)), algo, want, 7); err != nil {
+			if err := validateSegmentHashChunks(context.Background(), store, "segment", uint64(len(data)), algo, want, wantDigest, 7); err != nil {
 				t.Fatalf("validateSegmentHashChunks() error = %v", err)
 			}
 			if reads != 6 {
 				t.Fatalf("reads = %d, want 6", reads)
 			}
+			wantDigest[0] ^= 1
+			if err := validateSegmentHashChunks(context.Background(), store, "segment", uint64(len(data)), algo, want, wantDigest, 7); !errors.Is(err, ErrCorruptData) {
+				t.Fatalf("validateSegmentHashChunks(wrong digest) error = %v, want %v", err, ErrCorruptData)
+			}
 		})
 	}
 }
@@ -48,7 +57,7 @@ func TestValidateSegmentHashHonorsCancellationBetweenRanges(t *testing.T) {
 		cancel()
 		return []byte("abcd"), nil
 	})
-	err := validateSegmentHashChunks(ctx, store, "segment", 8, segformat.HashXXH64, 0, 4)
+	err := validateSegmentHashChunks(ctx, store, "segment", 8, segformat.HashXXH64, 0, [segformat.SegmentDigestSize]byte{}, 4)
 	if !errors.Is(err, context.Canceled) {
 		t.Fatalf("validateSegmentHashChunks() error = %v, want %v", err, context.Canceled)
 	}
@@ -3,7 +3,9 @@ package segreader
 import (
 	"fmt"
 
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 const (
@@ -12,13 +14,28 @@ const (
 )
 
 type Options struct {
-	// ValidateSegmentHash verifies the trailer segment hash during Open. It is
+	// ValidateSegmentHash verifies the trailer segment hash, and the full
+	// segment digest of a cryptographic hash algorithm, during Open. It is
 	// off by default because it requires reading the whole segment body in
 	// bounded range requests.
 	ValidateSegmentHash bool
 	MaxBlockBytes       uint64
 	// MaxIndexBytes bounds the block-index metadata fetched and held by Open.
 	MaxIndexBytes uint64
+	// KeyProvider unwraps the data keys of encrypted segments. Open returns
+	// ErrKeyUnavailable for an encrypted segment when it is nil.
+	KeyProvider segblock.KeyProvider
+	// Dictionaries loads the compression dictionaries of zstd-dict segments.
+	// Open returns ErrDictionaryUnavailable for such a segment when it is
+	// nil. Wrap a remote provider in a DictionaryCache so each dictionary is
+	// fetched once.
+	Dictionaries segblock.DictionaryProvider
+	// Verifier, when set, requires a valid writer signature on the segment
+	// ref. Open returns signing.ErrUnsigned or signing.ErrInvalidSignature
+	// otherwise. The signature covers the ref's segment hash and digest, so
+	// pair it with ValidateSegmentHash and a cryptographic hash algorithm to
+	// authenticate the segment body as well.
+	Verifier signing.Verifier
 }
 
 func DefaultOptions() Options {
@@ -2,12 +2,14 @@ package segreader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
 	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 // Reader reads one immutable segment object. It is safe for concurrent Read or
@@ -20,6 +22,9 @@ type Reader struct {
 	preamble segformat.FilePreamble
 	trailer  segformat.Trailer
 	index    []segformat.BlockIndexEntry
+	keyID    string
+	cipher   *segblock.BlockCipher
+	dict     *segblock.Dictionary
 }
 
 func Open(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, opts Options) (*Reader, error) {
@@ -33,6 +38,11 @@ func Open(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, opts Op
 	if err := ref.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrInvalidSegment, err)
 	}
+	if normalized.Verifier != nil {
+		if err := signing.VerifySegment(normalized.Verifier, ref); err != nil {
+			return nil, err
+		}
+	}
 	if ref.SizeBytes < segformat.FilePreambleSize+segformat.TrailerSize {
 		return nil, fmt.Errorf("%w: object too small: size=%d", ErrInvalidSegment, ref.SizeBytes)
 	}
@@ -64,6 +74,23 @@ func Open(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, opts Op
 	if err := segformat.ValidatePreambleTrailer(preamble, trailer); err != nil {
 		return nil, fmt.Errorf("%w: validate preamble/trailer: %w", ErrCorruptData, err)
 	}
+	var keyID string
+	var blockCipher *segblock.BlockCipher
+	if trailer.Flags&segformat.FlagEncrypted != 0 {
+		keyID, blockCipher, err = openKeyEnvelope(ctx, store, ref, trailer, normalized.KeyProvider)
+		if err != nil {
+			return nil, err
+		}
+	} else if ref.KeyID != "" {
+		return nil, fmt.Errorf("%w: segment ref key_id=%q for unencrypted segment", ErrInvalidSegment, ref.KeyID)
+	}
+	var dict *segblock.Dictionary
+	if trailer.Codec.UsesDictionary() {
+		dict, err = loadDictionary(ctx, normalized.Dictionaries, trailer.DictionaryID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	indexBytes, err := readAtExact(ctx, store, ref.URI, trailer.BlockIndexOffset, uint64(trailer.BlockIndexLength))
 	if err != nil {
@@ -79,7 +106,7 @@ func Open(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, opts Op
 
 	if normalized.ValidateSegmentHash {
 		indexEnd := trailer.BlockIndexOffset + uint64(trailer.BlockIndexLength)
-		if err := validateSegmentHash(ctx, store, ref.URI, indexEnd, trailer.HashAlgo, trailer.SegmentHash); err != nil {
+		if err := validateSegmentHash(ctx, store, ref.URI, indexEnd, trailer.HashAlgo, trailer.SegmentHash, trailer.SegmentDigest); err != nil {
 			return nil, err
 		}
 	}
@@ -91,9 +118,46 @@ func Open(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, opts Op
 		preamble: preamble,
 		trailer:  trailer,
 		index:    append([]segformat.BlockIndexEntry(nil), index...),
+		keyID:    keyID,
+		cipher:   blockCipher,
+		dict:     dict,
 	}, nil
 }
 
+// openKeyEnvelope reads the key envelope of an encrypted segment and unwraps
+// its data key.
+func openKeyEnvelope(ctx context.Context, store SegmentStore, ref pmeta.SegmentRef, trailer segformat.Trailer, provider segblock.KeyProvider) (string, *segblock.BlockCipher, error) {
+	envelopeBytes, err := readAtExact(ctx, store, ref.URI, segformat.FilePreambleSize, uint64(trailer.KeyEnvelopeLength))
+	if err != nil {
+		return "", nil, err
+	}
+	envelope, err := segformat.ParseKeyEnvelope(envelopeBytes, trailer.HashAlgo)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: parse key envelope: %w", ErrCorruptData, err)
+	}
+	if ref.KeyID != envelope.KeyID {
+		return "", nil, fmt.Errorf("%w: segment ref key_id=%q envelope key_id=%q", ErrInvalidSegment, ref.KeyID, envelope.KeyID)
+	}
+	if provider == nil {
+		return "", nil, fmt.Errorf("%w: key_id=%q: no key provider", ErrKeyUnavailable, envelope.KeyID)
+	}
+	dataKey, err := provider.UnwrapKey(ctx, envelope.KeyID, envelope.WrappedKey)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", nil, err
+		}
+		if errors.Is(err, segblock.ErrKeyDestroyed) {
+			return "", nil, fmt.Errorf("%w: key_id=%q: %w", ErrKeyDestroyed, envelope.KeyID, err)
+		}
+		return "", nil, fmt.Errorf("%w: key_id=%q: %w", ErrKeyUnavailable, envelope.KeyID, err)
+	}
+	blockCipher, err := segblock.NewBlockCipher(envelope.Cipher, dataKey, trailer.SegmentUUID)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: key_id=%q: %w", ErrKeyUnavailable, envelope.KeyID, err)
+	}
+	return envelope.KeyID, blockCipher, nil
+}
+
 func (r *Reader) Ref() pmeta.SegmentRef {
 	return r.ref
 }
@@ -106,6 +170,12 @@ func (r *Reader) Trailer() segformat.Trailer {
 	return r.trailer
 }
 
+// KeyID names the provider key that wraps the segment data key. It is empty
+// for unencrypted segments.
+func (r *Reader) KeyID() string {
+	return r.keyID
+}
+
 func (r *Reader) BlockIndex() []segformat.BlockIndexEntry {
 	return append([]segformat.BlockIndexEntry(nil), r.index...)
 }
@@ -222,11 +292,12 @@ func (r *Reader) openBlockScanner(ctx context.Context, idx int) (segformat.RawBl
 	if err := segformat.MatchBlockIndexEntry(blockPreamble, entry); err != nil {
 		return segformat.RawBlockScanner{}, fmt.Errorf("%w: block/index mismatch: %w", ErrCorruptData, err)
 	}
-	raw, err := segblock.Open(r.trailer.Codec, r.trailer.HashAlgo, blockPreamble, blockBytes[segformat.BlockPreambleSize:])
+	comp := segblock.Compression{Codec: r.trailer.Codec, Dictionary: r.dict}
+	raw, err := segblock.OpenCompressed(comp, r.trailer.HashAlgo, r.cipher, blockPreamble, blockBytes[segformat.BlockPreambleSize:])
 	if err != nil {
 		return segformat.RawBlockScanner{}, fmt.Errorf("%w: open block: %w", ErrCorruptData, err)
 	}
-	scanner, err := segformat.NewRawBlockScanner(raw, blockPreamble)
+	scanner, err := segformat.NewRawBlockScannerFormat(r.trailer.RecordFormat, raw, blockPreamble)
 	if err != nil {
 		return segformat.RawBlockScanner{}, fmt.Errorf("%w: decode raw block: %w", ErrCorruptData, err)
 	}
@@ -252,8 +323,10 @@ func validateRefTrailer(ref pmeta.SegmentRef, trailer segformat.Trailer) error {
 		ref.BlockIndexLength != trailer.BlockIndexLength ||
 		ref.Codec != trailer.Codec ||
 		ref.HashAlgo != trailer.HashAlgo ||
+		ref.DictionaryID != trailer.DictionaryID ||
 		ref.SegmentHash != trailer.SegmentHash ||
-		ref.TrailerHash != trailer.TrailerHash {
+		ref.TrailerHash != trailer.TrailerHash ||
+		ref.SegmentDigest != pmeta.Hash(trailer.SegmentDigest) {
 		return fmt.Errorf("%w: segment ref does not match trailer", ErrInvalidSegment)
 	}
 	return nil
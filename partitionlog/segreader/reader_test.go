@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
 )
@@ -232,6 +233,37 @@ func TestOpenValidateSegmentHashCatchesBodyCorruption(t *testing.T) {
 	}
 }
 
+func TestOpenValidatesSHA256SegmentDigest(t *testing.T) {
+	t.Parallel()
+
+	fixture := buildSegment(t, segformat.CodecNone, segformat.HashSHA256, 16, 1, 1, 24)
+	if fixture.ref.SegmentDigest.IsZero() {
+		t.Fatal("SegmentDigest is zero for a sha256 segment")
+	}
+	opts := DefaultOptions()
+	opts.ValidateSegmentHash = true
+	reader := openFixture(t, fixture, opts)
+	records, err := reader.Read(context.Background(), fixture.ref.BaseLSN, 0)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	assertRecordsEqual(t, records, fixture.records)
+
+	forged := fixture.ref
+	forged.SegmentDigest[0] ^= 1
+	store := newMemoryStore(map[string][]byte{fixture.ref.URI: fixture.object})
+	if _, err := Open(context.Background(), store, forged, opts); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Open(forged digest) error = %v, want %v", err, ErrInvalidSegment)
+	}
+
+	object := append([]byte(nil), fixture.object...)
+	object[segformat.FilePreambleSize+segformat.BlockPreambleSize] ^= 0xff
+	store = newMemoryStore(map[string][]byte{fixture.ref.URI: object})
+	if _, err := Open(context.Background(), store, fixture.ref, opts); !errors.Is(err, ErrCorruptData) {
+		t.Fatalf("Open(corrupt body) error = %v, want %v", err, ErrCorruptData)
+	}
+}
+
 func TestOpenDoesNotFetchWholeObjectByDefault(t *testing.T) {
 	t.Parallel()
 
@@ -292,6 +324,78 @@ func TestOpenRejectsIndexOverLimitBeforeStoreRead(t *testing.T) {
 	}
 }
 
+func TestOpenReadEncryptedSegment(t *testing.T) {
+	t.Parallel()
+
+	keys := newTestKeyProvider(t)
+	fixture := buildEncryptedSegment(t, keys, segformat.CodecZstd, segformat.HashXXH64, 48, 100, 1_000, 64)
+	if fixture.ref.KeyID != "kek-1" {
+		t.Fatalf("ref.KeyID = %q, want kek-1", fixture.ref.KeyID)
+	}
+	if bytes.Contains(fixture.object, fixture.records[0].Value) {
+		t.Fatal("encrypted object contains plaintext record value")
+	}
+	opts := DefaultOptions()
+	opts.KeyProvider = keys
+	opts.ValidateSegmentHash = true
+	reader := openFixture(t, fixture, opts)
+	if reader.KeyID() != "kek-1" || reader.Trailer().Flags&segformat.FlagEncrypted == 0 {
+		t.Fatalf("KeyID() = %q flags = %#x, want encrypted kek-1", reader.KeyID(), reader.Trailer().Flags)
+	}
+	records, err := reader.Read(context.Background(), fixture.ref.BaseLSN, 0)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	assertRecordsEqual(t, records, fixture.records)
+	lsn, ok, err := reader.FindLSNByTimestamp(context.Background(), fixture.records[10].TimestampMS)
+	if err != nil || !ok || lsn != fixture.records[10].LSN {
+		t.Fatalf("FindLSNByTimestamp() = %d, %v, %v; want %d", lsn, ok, err, fixture.records[10].LSN)
+	}
+}
+
+func TestOpenEncryptedSegmentRequiresKey(t *testing.T) {
+	t.Parallel()
+
+	fixture := buildEncryptedSegment(t, newTestKeyProvider(t), segformat.CodecNone, segformat.HashXXH64, 8, 1, 1, 16)
+	store := newMemoryStore(map[string][]byte{fixture.ref.URI: fixture.object})
+
+	if _, err := Open(context.Background(), store, fixture.ref, DefaultOptions()); !errors.Is(err, ErrKeyUnavailable) {
+		t.Fatalf("Open(no provider) error = %v, want %v", err, ErrKeyUnavailable)
+	}
+	other, err := segblock.NewStaticKeyProvider("kek-1", map[string][]byte{"kek-1": bytes.Repeat([]byte{9}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	opts := DefaultOptions()
+	opts.KeyProvider = other
+	if _, err := Open(context.Background(), store, fixture.ref, opts); !errors.Is(err, ErrKeyUnavailable) {
+		t.Fatalf("Open(wrong key) error = %v, want %v", err, ErrKeyUnavailable)
+	}
+
+	mismatch := fixture.ref
+	mismatch.KeyID = "kek-2"
+	opts.KeyProvider = newTestKeyProvider(t)
+	if _, err := Open(context.Background(), store, mismatch, opts); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Open(key id mismatch) error = %v, want %v", err, ErrInvalidSegment)
+	}
+
+	plain := buildSegment(t, segformat.CodecNone, segformat.HashXXH64, 8, 1, 1, 16)
+	plain.ref.KeyID = "kek-1"
+	plainStore := newMemoryStore(map[string][]byte{plain.ref.URI: plain.object})
+	if _, err := Open(context.Background(), plainStore, plain.ref, opts); !errors.Is(err, ErrInvalidSegment) {
+		t.Fatalf("Open(key id on plaintext) error = %v, want %v", err, ErrInvalidSegment)
+	}
+}
+
+func newTestKeyProvider(t *testing.T) *segblock.StaticKeyProvider {
+	t.Helper()
+	keys, err := segblock.NewStaticKeyProvider("kek-1", map[string][]byte{"kek-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	return keys
+}
+
 type segmentFixture struct {
 	ref     pmeta.SegmentRef
 	object  []byte
@@ -299,6 +403,11 @@ type segmentFixture struct {
 }
 
 func buildSegment(t *testing.T, codec segformat.Codec, hashAlgo segformat.HashAlgo, count int, baseLSN uint64, baseTS int64, valueSize int) segmentFixture {
+	t.Helper()
+	return buildEncryptedSegment(t, nil, codec, hashAlgo, count, baseLSN, baseTS, valueSize)
+}
+
+func buildEncryptedSegment(t *testing.T, keys segblock.KeyProvider, codec segformat.Codec, hashAlgo segformat.HashAlgo, count int, baseLSN uint64, baseTS int64, valueSize int) segmentFixture {
 	t.Helper()
 	writerRecords := makeWriterRecords(count, baseLSN, baseTS, valueSize)
 
@@ -316,6 +425,7 @@ func buildSegment(t *testing.T, codec segformat.Codec, hashAlgo segformat.HashAl
 	opts.SegmentUUID = [16]byte{1, 2, 3, 4}
 	opts.WriterTag = [16]byte{5, 6, 7, 8}
 	opts.CreatedUnixMS = 1_776_263_000_000
+	opts.KeyProvider = keys
 
 	w, err := segwriter.New(opts, sink)
 	if err != nil {
@@ -350,6 +460,8 @@ func buildSegment(t *testing.T, codec segformat.Codec, hashAlgo segformat.HashAl
 		HashAlgo:         result.Metadata.HashAlgo,
 		SegmentHash:      result.Metadata.SegmentHash,
 		TrailerHash:      result.Metadata.TrailerHash,
+		KeyID:            result.Metadata.KeyID,
+		SegmentDigest:    result.Metadata.SegmentDigest,
 	}
 	if err := ref.Validate(); err != nil {
 		t.Fatalf("SegmentRef.Validate() error = %v", err)
@@ -18,4 +18,5 @@ var (
 	ErrSinkContract     = errors.New("segwriter: sink contract violation")
 	ErrTxnAborted       = errors.New("segwriter: transaction aborted")
 	ErrTxnCompleted     = errors.New("segwriter: transaction completed")
+	ErrKeyWrap          = errors.New("segwriter: wrap data key failed")
 )
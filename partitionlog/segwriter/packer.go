@@ -3,12 +3,10 @@ package segwriter
 import (
 	"context"
 	"fmt"
-	"hash"
 	"sort"
 	"sync"
 
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
-	"github.com/cespare/xxhash/v2"
 )
 
 type packerOptions struct {
@@ -45,7 +43,7 @@ type packer struct {
 	txnAborted bool
 	partsClose bool
 
-	hasher digest64
+	hasher segformat.Hasher
 }
 
 type uploadResult struct {
@@ -53,19 +51,6 @@ type uploadResult struct {
 	err     error
 }
 
-type digest64 interface {
-	hash.Hash
-	Sum64() uint64
-}
-
-type crc32Digest struct {
-	hash.Hash32
-}
-
-func (d crc32Digest) Sum64() uint64 {
-	return uint64(d.Sum32())
-}
-
 func newPacker(ctx context.Context, txn Txn, opts packerOptions) (*packer, error) {
 	if txn == nil {
 		return nil, fmt.Errorf("%w: txn is nil", ErrInvalidOptions)
@@ -82,7 +67,7 @@ func newPacker(ctx context.Context, txn Txn, opts packerOptions) (*packer, error
 	if err := opts.HashAlgo.Validate(); err != nil {
 		return nil, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
 	}
-	hasher, err := newDigest(opts.HashAlgo)
+	hasher, err := segformat.NewHasher(opts.HashAlgo)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +105,13 @@ func (p *packer) BodyHash() uint64 {
 	return p.hasher.Sum64()
 }
 
+// BodyDigest seals the body like BodyHash and returns the full body digest,
+// which is zero unless the hash algorithm is cryptographic.
+func (p *packer) BodyDigest() [segformat.SegmentDigestSize]byte {
+	p.bodySealed = true
+	return p.hasher.Digest()
+}
+
 func (p *packer) WriteFinal(ctx context.Context, b []byte) error {
 	if !p.bodySealed {
 		return ErrBodyNotSealed
@@ -389,14 +381,3 @@ func (p *packer) closeParts() {
 	close(p.parts)
 	p.partsClose = true
 }
-
-func newDigest(algo segformat.HashAlgo) (digest64, error) {
-	switch algo {
-	case segformat.HashCRC32C:
-		return crc32Digest{Hash32: segformat.NewCRC32C()}, nil
-	case segformat.HashXXH64:
-		return xxhash.New(), nil
-	default:
-		return nil, fmt.Errorf("%w: %d", segformat.ErrUnsupportedHashAlgo, uint16(algo))
-	}
-}
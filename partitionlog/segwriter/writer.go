@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 )
@@ -25,6 +27,16 @@ type Options struct {
 
 	Codec    segformat.Codec
 	HashAlgo segformat.HashAlgo
+	// RecordFormat is the raw record encoding. 0 selects
+	// segformat.DefaultRecordFormat.
+	RecordFormat segformat.RecordFormat
+	// ZstdLevel is the zstd compression level, from 1 to 22, for CodecZstd
+	// and CodecZstdDict. 0 selects the default level. It must be 0 for other
+	// codecs.
+	ZstdLevel int
+	// Dictionary is the trained dictionary for CodecZstdDict. Its ID is
+	// recorded in the trailer. It must be nil for other codecs.
+	Dictionary *segblock.Dictionary
 
 	TargetBlockSize   int
 	PartSize          int
@@ -34,6 +46,11 @@ type Options struct {
 	UploadQueueSize   int
 	UploadLimiter     UploadLimiter
 
+	// KeyProvider, when set, encrypts every stored block under a fresh
+	// per-segment data key that the provider wraps. The wrapped key and its
+	// key ID are written into the segment's key envelope.
+	KeyProvider segblock.KeyProvider
+
 	SegmentUUID   [16]byte
 	WriterTag     [16]byte
 	CreatedUnixMS int64
@@ -62,6 +79,17 @@ type Metadata struct {
 	HashAlgo         segformat.HashAlgo
 	SegmentHash      uint64
 	TrailerHash      uint64
+	// DictionaryID names the compression dictionary. It is zero unless Codec
+	// is CodecZstdDict.
+	DictionaryID uint32
+	// KeyID names the provider key that wraps the segment data key. It is
+	// empty for unencrypted segments.
+	KeyID string
+	// RecordsRoot is the Merkle root over the segment's record leaves.
+	RecordsRoot pmeta.Hash
+	// SegmentDigest is the trailer's full segment digest. It is zero unless
+	// HashAlgo is cryptographic.
+	SegmentDigest pmeta.Hash
 }
 
 type Result struct {
@@ -99,6 +127,14 @@ type Writer struct {
 
 	active *blockBuffer
 
+	// cipher, envelope and keyID are set before the first block is queued
+	// for sealing when the segment is encrypted.
+	cipher   *segblock.BlockCipher
+	envelope []byte
+	keyID    string
+
+	records proof.RecordsRootBuilder
+
 	hasRecords bool
 	closed     bool
 	aborted    bool
@@ -114,6 +150,7 @@ type Writer struct {
 }
 
 type blockBuffer struct {
+	Format         segformat.RecordFormat
 	Seq            uint64
 	Raw            []byte
 	BaseLSN        uint64
@@ -173,7 +210,7 @@ func New(opts Options, sink Sink) (*Writer, error) {
 		emitted:     make(chan emitResult, 1),
 	}
 	for i := 0; i < normalized.BlockBufferCount; i++ {
-		w.freeBuffers <- &blockBuffer{}
+		w.freeBuffers <- &blockBuffer{Format: normalized.RecordFormat}
 	}
 	if err := w.takeFreeBuffer(ctx); err != nil {
 		cancel()
@@ -201,10 +238,15 @@ func (w *Writer) Append(ctx context.Context, r Record) error {
 		return w.abortWith(ctx, err, true)
 	}
 
-	recordSize, err := segformat.RecordSize(r.Headers, r.Value)
+	recordSize, err := w.active.RecordSize(r)
 	if err != nil {
 		return w.abortWith(ctx, err, true)
 	}
+	if w.opts.KeyProvider != nil && w.cipher == nil {
+		if err := w.initEncryption(ctx); err != nil {
+			return w.abortWith(ctx, err, true)
+		}
+	}
 	if w.active.Len() > 0 && !w.active.CanAppend(recordSize, w.opts.TargetBlockSize) {
 		if err := w.enqueueActive(ctx); err != nil {
 			return w.abortWith(ctx, err, true)
@@ -227,6 +269,7 @@ func (w *Writer) Append(ctx context.Context, r Record) error {
 	w.maxTimestampMS = r.TimestampMS
 	w.nextLSN = r.LSN + 1
 	w.recordCount++
+	w.records.Add(r.LSN, r.TimestampMS, r.Headers, r.Value)
 	return nil
 }
 
@@ -281,6 +324,7 @@ func (w *Writer) Close(ctx context.Context) (Result, error) {
 		return Result{}, w.abortWith(ctx, err, false)
 	}
 	trailer.SegmentHash = p.BodyHash()
+	trailer.SegmentDigest = p.BodyDigest()
 	trailerBytes, sealedTrailer, err := segformat.MarshalTrailer(trailer)
 	if err != nil {
 		return Result{}, w.abortWith(ctx, err, false)
@@ -296,13 +340,45 @@ func (w *Writer) Close(ctx context.Context) (Result, error) {
 
 	w.closed = true
 	w.cancel()
+	metadata := metadataFromTrailer(trailer)
+	metadata.KeyID = w.keyID
+	metadata.RecordsRoot = w.records.Root()
 	return Result{
-		Metadata: metadataFromTrailer(trailer),
+		Metadata: metadata,
 		Object:   object,
 		Trailer:  trailer,
 	}, nil
 }
 
+// initEncryption wraps a new data key through the key provider. It runs on
+// the first Append, before any block can reach a seal worker.
+func (w *Writer) initEncryption(ctx context.Context) error {
+	dataKey, err := segblock.NewDataKey()
+	if err != nil {
+		return err
+	}
+	keyID, wrapped, err := w.opts.KeyProvider.WrapKey(ctx, w.opts.Partition, dataKey)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyWrap, err)
+	}
+	envelope, _, err := segformat.MarshalKeyEnvelope(segformat.KeyEnvelope{
+		Cipher:     segformat.CipherAES256GCM,
+		KeyID:      keyID,
+		WrappedKey: wrapped,
+	}, w.opts.HashAlgo)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrKeyWrap, err)
+	}
+	blockCipher, err := segblock.NewBlockCipher(segformat.CipherAES256GCM, dataKey, w.opts.SegmentUUID)
+	if err != nil {
+		return err
+	}
+	w.cipher = blockCipher
+	w.envelope = envelope
+	w.keyID = keyID
+	return nil
+}
+
 func (w *Writer) Abort(ctx context.Context) error {
 	if w.closed || w.aborted {
 		return nil
@@ -381,7 +457,8 @@ func (w *Writer) enqueueActive(ctx context.Context) error {
 func (w *Writer) sealWorker() {
 	defer w.sealWG.Done()
 	for buf := range w.sealJobs {
-		sealed, err := segblock.SealOwned(w.opts.Codec, w.opts.HashAlgo, buf.Raw, buf.Meta())
+		comp := w.opts.compression()
+		sealed, err := segblock.SealCompressed(comp, w.opts.HashAlgo, w.cipher, buf.Raw, buf.Meta())
 		if err != nil {
 			w.setFirstErr(err)
 		}
@@ -508,10 +585,11 @@ func (w *Writer) ensurePacker(ctx context.Context) (*packer, error) {
 		return nil, err
 	}
 	preamble, err := (segformat.FilePreamble{
+		Flags:        w.flags(),
 		Partition:    w.opts.Partition,
 		Codec:        w.opts.Codec,
 		HashAlgo:     w.opts.HashAlgo,
-		RecordFormat: segformat.DefaultRecordFormat,
+		RecordFormat: w.opts.RecordFormat,
 		BaseLSN:      w.baseLSN,
 		SegmentUUID:  w.opts.SegmentUUID,
 		WriterTag:    w.opts.WriterTag,
@@ -524,6 +602,12 @@ func (w *Writer) ensurePacker(ctx context.Context) (*packer, error) {
 		abortPackerBestEffort(p)
 		return nil, err
 	}
+	if len(w.envelope) > 0 {
+		if err := p.WriteBody(ctx, w.envelope); err != nil {
+			abortPackerBestEffort(p)
+			return nil, err
+		}
+	}
 	w.packer = p
 	return p, nil
 }
@@ -541,29 +625,46 @@ func checkedBlockCount(n int) (uint32, error) {
 	return uint32(n), nil
 }
 
+func (w *Writer) dictionaryID() uint32 {
+	if w.opts.Dictionary == nil {
+		return 0
+	}
+	return w.opts.Dictionary.ID()
+}
+
 func (w *Writer) trailer(indexOffset uint64, blockCount uint32, segmentHash uint64) segformat.Trailer {
 	indexLength := uint64(segformat.IndexPreambleSize) + uint64(blockCount)*uint64(segformat.BlockIndexEntrySize)
 	return segformat.Trailer{
-		Partition:        w.opts.Partition,
-		Codec:            w.opts.Codec,
-		HashAlgo:         w.opts.HashAlgo,
-		RecordFormat:     segformat.DefaultRecordFormat,
-		BaseLSN:          w.baseLSN,
-		LastLSN:          w.nextLSN - 1,
-		MinTimestampMS:   w.minTimestampMS,
-		MaxTimestampMS:   w.maxTimestampMS,
-		RecordCount:      w.recordCount,
-		BlockCount:       blockCount,
-		BlockIndexOffset: indexOffset,
-		BlockIndexLength: uint32(indexLength),
-		TotalSize:        indexOffset + indexLength + uint64(segformat.TrailerSize),
-		CreatedUnixMS:    w.opts.CreatedUnixMS,
-		SegmentUUID:      w.opts.SegmentUUID,
-		WriterTag:        w.opts.WriterTag,
-		SegmentHash:      segmentHash,
+		Flags:             w.flags(),
+		KeyEnvelopeLength: uint32(len(w.envelope)),
+		Partition:         w.opts.Partition,
+		Codec:             w.opts.Codec,
+		HashAlgo:          w.opts.HashAlgo,
+		RecordFormat:      w.opts.RecordFormat,
+		DictionaryID:      w.dictionaryID(),
+		BaseLSN:           w.baseLSN,
+		LastLSN:           w.nextLSN - 1,
+		MinTimestampMS:    w.minTimestampMS,
+		MaxTimestampMS:    w.maxTimestampMS,
+		RecordCount:       w.recordCount,
+		BlockCount:        blockCount,
+		BlockIndexOffset:  indexOffset,
+		BlockIndexLength:  uint32(indexLength),
+		TotalSize:         indexOffset + indexLength + uint64(segformat.TrailerSize),
+		CreatedUnixMS:     w.opts.CreatedUnixMS,
+		SegmentUUID:       w.opts.SegmentUUID,
+		WriterTag:         w.opts.WriterTag,
+		SegmentHash:       segmentHash,
 	}
 }
 
+func (w *Writer) flags() uint32 {
+	if w.cipher != nil {
+		return segformat.FlagEncrypted
+	}
+	return 0
+}
+
 func (w *Writer) abortWith(ctx context.Context, err error, drainPipeline bool) error {
 	if err == nil {
 		return nil
@@ -661,12 +762,18 @@ func (w *Writer) emitContext() (context.Context, context.CancelFunc) {
 }
 
 func normalizeOptions(opts Options) (Options, error) {
-	if err := opts.Codec.Validate(); err != nil {
+	if err := opts.compression().Validate(); err != nil {
 		return Options{}, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
 	}
 	if err := opts.HashAlgo.Validate(); err != nil {
 		return Options{}, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
 	}
+	if opts.RecordFormat == 0 {
+		opts.RecordFormat = segformat.DefaultRecordFormat
+	}
+	if err := opts.RecordFormat.Validate(); err != nil {
+		return Options{}, fmt.Errorf("%w: %w", ErrInvalidOptions, err)
+	}
 	if opts.TargetBlockSize <= 0 {
 		opts.TargetBlockSize = DefaultTargetBlockSize
 	}
@@ -702,6 +809,10 @@ func normalizeOptions(opts Options) (Options, error) {
 	return opts, nil
 }
 
+func (o Options) compression() segblock.Compression {
+	return segblock.Compression{Codec: o.Codec, ZstdLevel: o.ZstdLevel, Dictionary: o.Dictionary}
+}
+
 func isZero16(v [16]byte) bool {
 	for _, b := range v {
 		if b != 0 {
@@ -739,6 +850,8 @@ func metadataFromTrailer(t segformat.Trailer) Metadata {
 		HashAlgo:         t.HashAlgo,
 		SegmentHash:      t.SegmentHash,
 		TrailerHash:      t.TrailerHash,
+		DictionaryID:     t.DictionaryID,
+		SegmentDigest:    pmeta.Hash(t.SegmentDigest),
 	}
 }
 
@@ -787,8 +900,19 @@ func (b *blockBuffer) CanAppend(recordSize int, targetBlockSize int) bool {
 	return nextSize <= targetBlockSize
 }
 
+// RecordSize returns the encoded size of r if it is appended to b. A
+// RecordFormatV2 record is sized against the block's first timestamp, which
+// is r's own timestamp when b is empty.
+func (b *blockBuffer) RecordSize(r Record) (int, error) {
+	minTimestampMS := b.MinTimestampMS
+	if b.RecordCount == 0 {
+		minTimestampMS = r.TimestampMS
+	}
+	return b.format().RawRecordSize(minTimestampMS, b.rawRecord(r))
+}
+
 func (b *blockBuffer) Append(r Record, targetBlockSize int) error {
-	recordSize, err := segformat.RecordSize(r.Headers, r.Value)
+	recordSize, err := b.RecordSize(r)
 	if err != nil {
 		return err
 	}
@@ -806,11 +930,7 @@ func (b *blockBuffer) Append(r Record, targetBlockSize int) error {
 	}
 	b.MaxTimestampMS = r.TimestampMS
 
-	b.Raw, err = segformat.AppendRawRecord(b.Raw, segformat.RawRecord{
-		TimestampMS: r.TimestampMS,
-		Headers:     r.Headers,
-		Value:       r.Value,
-	})
+	b.Raw, err = b.format().AppendRawRecord(b.Raw, b.MinTimestampMS, b.rawRecord(r))
 	if err != nil {
 		return err
 	}
@@ -818,12 +938,29 @@ func (b *blockBuffer) Append(r Record, targetBlockSize int) error {
 	return nil
 }
 
+// format returns b.Format, or segformat.DefaultRecordFormat when unset.
+func (b *blockBuffer) format() segformat.RecordFormat {
+	if b.Format == 0 {
+		return segformat.DefaultRecordFormat
+	}
+	return b.Format
+}
+
+func (b *blockBuffer) rawRecord(r Record) segformat.RawRecord {
+	return segformat.RawRecord{
+		TimestampMS: r.TimestampMS,
+		Headers:     r.Headers,
+		Value:       r.Value,
+	}
+}
+
 func (b *blockBuffer) Meta() segblock.Meta {
 	return segblock.Meta{
 		BaseLSN:        b.BaseLSN,
 		RecordCount:    b.RecordCount,
 		MinTimestampMS: b.MinTimestampMS,
 		MaxTimestampMS: b.MaxTimestampMS,
+		RecordFormat:   b.format(),
 	}
 }
 
@@ -61,6 +61,53 @@ func TestWriterEndToEndCodecNone(t *testing.T) {
 	}
 }
 
+func TestWriterEndToEndRecordFormatV2(t *testing.T) {
+	t.Parallel()
+
+	records := makeWriterRecords(64, 1<<40, 1_776_263_000_000, 12)
+	records[3].Headers = []segformat.Header{{Key: []byte("trace-id"), Value: []byte("trace-003")}}
+	write := func(format segformat.RecordFormat) (Result, []byte) {
+		sink := NewMemorySink("memory://records")
+		opts := testWriterOptions(segformat.CodecNone)
+		opts.RecordFormat = format
+		opts.TargetBlockSize = 256
+		w, err := New(opts, sink)
+		if err != nil {
+			t.Fatalf("New(%s) error = %v", format, err)
+		}
+		for _, record := range records {
+			if err := w.Append(context.Background(), record); err != nil {
+				t.Fatalf("Append(%s) error = %v", format, err)
+			}
+		}
+		result, err := w.Close(context.Background())
+		if err != nil {
+			t.Fatalf("Close(%s) error = %v", format, err)
+		}
+		return result, sink.Bytes()
+	}
+
+	v1Result, _ := write(0)
+	v2Result, object := write(segformat.RecordFormatV2)
+	if v1Result.Trailer.RecordFormat != segformat.RecordFormatV1 {
+		t.Fatalf("default record format = %s, want %s", v1Result.Trailer.RecordFormat, segformat.RecordFormatV1)
+	}
+	decoded := decodeSegmentForTest(t, object)
+	assertRecordsEqual(t, decoded.records, records)
+	if decoded.preamble.RecordFormat != segformat.RecordFormatV2 || decoded.trailer.RecordFormat != segformat.RecordFormatV2 {
+		t.Fatalf("record format preamble=%s trailer=%s, want %s", decoded.preamble.RecordFormat, decoded.trailer.RecordFormat, segformat.RecordFormatV2)
+	}
+	if v2Result.Metadata.SizeBytes >= v1Result.Metadata.SizeBytes {
+		t.Fatalf("v2 size = %d, v1 size = %d; want v2 smaller", v2Result.Metadata.SizeBytes, v1Result.Metadata.SizeBytes)
+	}
+
+	opts := testWriterOptions(segformat.CodecNone)
+	opts.RecordFormat = segformat.RecordFormat(9)
+	if _, err := New(opts, NewMemorySink("memory://invalid")); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(unknown record format) error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
 func TestWriterEndToEndZstd(t *testing.T) {
 	t.Parallel()
 
@@ -659,7 +706,7 @@ func decodeSegmentForTest(t *testing.T, object []byte) decodedSegment {
 		if err != nil {
 			t.Fatalf("segblock.Open() error = %v", err)
 		}
-		blockRecords, err := segformat.DecodeRawBlock(raw, blockPreamble)
+		blockRecords, err := segformat.DecodeRawBlockFormat(trailer.RecordFormat, raw, blockPreamble)
 		if err != nil {
 			t.Fatalf("DecodeRawBlock() error = %v", err)
 		}
@@ -983,3 +1030,73 @@ func (t *cleanupAwareTxn) Abort(ctx context.Context) error {
 	})
 	return nil
 }
+
+func TestWriterEncryptsBlocksWithKeyProvider(t *testing.T) {
+	t.Parallel()
+
+	keys, err := segblock.NewStaticKeyProvider("kek-1", map[string][]byte{"kek-1": bytes.Repeat([]byte{1}, segformat.DataKeySize)})
+	if err != nil {
+		t.Fatalf("NewStaticKeyProvider() error = %v", err)
+	}
+	opts := testWriterOptions(segformat.CodecNone)
+	opts.KeyProvider = keys
+	records := makeWriterRecords(16, 1, 1, 40)
+	object, meta, err := Encode(context.Background(), records, opts)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if meta.KeyID != "kek-1" {
+		t.Fatalf("Metadata.KeyID = %q, want kek-1", meta.KeyID)
+	}
+	if bytes.Contains(object, records[0].Value) {
+		t.Fatal("encrypted object contains plaintext record value")
+	}
+	preamble, err := segformat.ParseFilePreamble(object[:segformat.FilePreambleSize])
+	if err != nil {
+		t.Fatalf("ParseFilePreamble() error = %v", err)
+	}
+	trailer, err := segformat.ParseTrailer(object[len(object)-segformat.TrailerSize:], uint64(len(object)))
+	if err != nil {
+		t.Fatalf("ParseTrailer() error = %v", err)
+	}
+	if preamble.Flags != segformat.FlagEncrypted || trailer.Flags != segformat.FlagEncrypted {
+		t.Fatalf("flags preamble=%#x trailer=%#x, want %#x", preamble.Flags, trailer.Flags, segformat.FlagEncrypted)
+	}
+	envelope, err := segformat.ParseKeyEnvelope(object[segformat.FilePreambleSize:trailer.FirstBlockOffset()], opts.HashAlgo)
+	if err != nil {
+		t.Fatalf("ParseKeyEnvelope() error = %v", err)
+	}
+	if envelope.KeyID != "kek-1" || envelope.Cipher != segformat.CipherAES256GCM {
+		t.Fatalf("envelope = %+v, want kek-1 aes-256-gcm", envelope)
+	}
+}
+
+func TestWriterAbortsWhenKeyWrapFails(t *testing.T) {
+	t.Parallel()
+
+	opts := testWriterOptions(segformat.CodecNone)
+	opts.KeyProvider = failingKeyProvider{err: errors.New("kms down")}
+	w, err := New(opts, NewMemorySink("memory://key-wrap"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	err = w.Append(context.Background(), Record{LSN: 1, TimestampMS: 1, Value: []byte("a")})
+	if !errors.Is(err, ErrKeyWrap) {
+		t.Fatalf("Append() error = %v, want %v", err, ErrKeyWrap)
+	}
+	if _, err := w.Close(context.Background()); !errors.Is(err, ErrWriterAborted) {
+		t.Fatalf("Close() after failed Append = %v, want %v", err, ErrWriterAborted)
+	}
+}
+
+type failingKeyProvider struct {
+	err error
+}
+
+func (p failingKeyProvider) WrapKey(context.Context, uint32, []byte) (string, []byte, error) {
+	return "", nil, p.err
+}
+
+func (p failingKeyProvider) UnwrapKey(context.Context, string, []byte) ([]byte, error) {
+	return nil, p.err
+}
@@ -0,0 +1,112 @@
+package signing
+
+import (
+	"encoding/binary"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+const (
+	segmentDomain = "partitionlog/signing/segment-ref/v1"
+	headDomain    = "partitionlog/signing/partition-head/v1"
+)
+
+// SegmentMessage returns the canonical bytes a segment signature covers:
+// every SegmentRef field except Signature, length-prefixed and big endian.
+func SegmentMessage(segment pmeta.SegmentRef) []byte {
+	var m message
+	m.string(segmentDomain)
+	m.segment(segment)
+	return m.buf
+}
+
+// HeadMessage returns the canonical bytes a head signature covers: every
+// PartitionHead field except Signature. The last segment is covered together
+// with its own signature.
+func HeadMessage(head pmeta.PartitionHead) []byte {
+	var m message
+	m.string(headDomain)
+	m.string(head.StreamID)
+	m.u32(head.Partition)
+	m.u64(head.NextLSN)
+	m.u64(head.OldestLSN)
+	m.u64(head.AppliedRetentionLSN)
+	m.u64(head.AppliedRetentionVersion)
+	m.u64(head.WriterEpoch)
+	m.u64(head.SegmentCount)
+	m.u64(head.RetainedBytes)
+	m.bool(head.HasLastSegment)
+	m.segment(head.LastSegment)
+	m.bytes(head.LastSegment.Signature[:])
+	m.bool(head.Sealed)
+	m.u64(head.TreeSize)
+	m.bytes(head.TreeRoot[:])
+	producers, _ := head.Producers.MarshalBinary()
+	m.bytes(producers)
+	m.string(head.SignerKeyID)
+	return m.buf
+}
+
+type message struct {
+	buf []byte
+}
+
+func (m *message) segment(s pmeta.SegmentRef) {
+	m.string(s.URI)
+	m.string(s.StreamID)
+	m.u32(s.Partition)
+	m.u64(s.WriterEpoch)
+	m.bytes(s.SegmentUUID[:])
+	m.bytes(s.WriterTag[:])
+	m.u64(s.BaseLSN)
+	m.u64(s.LastLSN)
+	m.u64(uint64(s.MinTimestampMS))
+	m.u64(uint64(s.MaxTimestampMS))
+	m.u32(s.RecordCount)
+	m.u32(s.BlockCount)
+	m.u64(s.SizeBytes)
+	m.u64(s.BlockIndexOffset)
+	m.u32(s.BlockIndexLength)
+	m.u32(uint32(s.Codec))
+	m.u32(uint32(s.HashAlgo))
+	m.u32(s.DictionaryID)
+	m.u64(s.SegmentHash)
+	m.u64(s.TrailerHash)
+	m.string(s.KeyID)
+	m.u64(uint64(s.RedactedUnixMS))
+	m.u64(s.ChainedSegmentHash)
+	m.u64(s.ChainedTrailerHash)
+	m.bytes(s.RecordsRoot[:])
+	m.bytes(s.PrevChainHash[:])
+	m.bytes(s.ChainHash[:])
+	m.bytes(s.SegmentDigest[:])
+	producers, _ := s.Producers.MarshalBinary()
+	m.bytes(producers)
+	m.string(s.SignerKeyID)
+}
+
+func (m *message) u32(v uint32) {
+	m.buf = binary.BigEndian.AppendUint32(m.buf, v)
+}
+
+func (m *message) u64(v uint64) {
+	m.buf = binary.BigEndian.AppendUint64(m.buf, v)
+}
+
+func (m *message) bool(v bool) {
+	if v {
+		m.buf = append(m.buf, 1)
+		return
+	}
+	m.buf = append(m.buf, 0)
+}
+
+func (m *message) bytes(b []byte) {
+	m.u64(uint64(len(b)))
+	m.buf = append(m.buf, b...)
+}
+
+func (m *message) string(s string) {
+	m.u64(uint64(len(s)))
+	m.buf = append(m.buf, s...)
+}
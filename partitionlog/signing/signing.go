@@ -0,0 +1,178 @@
+// Package signing signs segment refs and partition heads with writer keys,
+// so a reader can check that a timeline was produced by an authorized writer
+// identity and not by anyone with write access to the bucket.
+//
+// A writer signs every SegmentRef it publishes, and a catalog that supports
+// it signs every head the writer commits. Signatures cover the canonical
+// encoding of every other field, including the chain hash and segment hashes,
+// so a verified ref pins the segment object through its trailer. Signatures
+// prove authorship, not freshness: a replayed older head still verifies.
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+)
+
+var (
+	// ErrInvalidKey reports a signer or verifier built from an unusable key.
+	ErrInvalidKey = errors.New("signing: invalid key")
+	// ErrUnsigned reports metadata that carries no signature where one is
+	// required.
+	ErrUnsigned = errors.New("signing: unsigned")
+	// ErrInvalidSignature reports a signature that does not verify, including
+	// one made by a key the verifier does not trust.
+	ErrInvalidSignature = errors.New("signing: invalid signature")
+)
+
+// Signer signs timeline metadata with one writer key.
+type Signer interface {
+	// KeyID names the key. It is stored beside each signature so verifiers can
+	// pick the matching public key.
+	KeyID() string
+	// Sign returns the 64-byte ed25519 signature of message.
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// Verifier checks signatures made by trusted writer keys.
+type Verifier interface {
+	// Verify returns ErrInvalidSignature unless signature is a valid
+	// signature of message by the trusted key keyID.
+	Verify(keyID string, message, signature []byte) error
+}
+
+// Ed25519Signer is a Signer over an in-process ed25519 private key.
+type Ed25519Signer struct {
+	keyID string
+	key   ed25519.PrivateKey
+}
+
+var _ Signer = (*Ed25519Signer)(nil)
+
+// NewEd25519Signer returns a Signer that signs with key under keyID.
+func NewEd25519Signer(keyID string, key ed25519.PrivateKey) (*Ed25519Signer, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("%w: empty key id", ErrInvalidKey)
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%w: key %q size=%d want=%d", ErrInvalidKey, keyID, len(key), ed25519.PrivateKeySize)
+	}
+	return &Ed25519Signer{keyID: keyID, key: append(ed25519.PrivateKey(nil), key...)}, nil
+}
+
+func (s *Ed25519Signer) KeyID() string {
+	return s.keyID
+}
+
+func (s *Ed25519Signer) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(s.key, message), nil
+}
+
+// Ed25519Verifier trusts a fixed set of ed25519 public keys by key ID.
+type Ed25519Verifier struct {
+	keys map[string]ed25519.PublicKey
+}
+
+var _ Verifier = (*Ed25519Verifier)(nil)
+
+// NewEd25519Verifier returns a Verifier that trusts exactly keys.
+func NewEd25519Verifier(keys map[string]ed25519.PublicKey) (*Ed25519Verifier, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no trusted keys", ErrInvalidKey)
+	}
+	for keyID, key := range keys {
+		if keyID == "" {
+			return nil, fmt.Errorf("%w: empty key id", ErrInvalidKey)
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: key %q size=%d want=%d", ErrInvalidKey, keyID, len(key), ed25519.PublicKeySize)
+		}
+	}
+	return &Ed25519Verifier{keys: maps.Clone(keys)}, nil
+}
+
+func (v *Ed25519Verifier) Verify(keyID string, message, signature []byte) error {
+	key, ok := v.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: untrusted key %q", ErrInvalidSignature, keyID)
+	}
+	if !ed25519.Verify(key, message, signature) {
+		return fmt.Errorf("%w: key %q", ErrInvalidSignature, keyID)
+	}
+	return nil
+}
+
+// SignSegment returns segment signed by signer.
+func SignSegment(ctx context.Context, signer Signer, segment pmeta.SegmentRef) (pmeta.SegmentRef, error) {
+	segment.SignerKeyID = signer.KeyID()
+	signature, err := sign(ctx, signer, SegmentMessage(segment))
+	if err != nil {
+		return pmeta.SegmentRef{}, err
+	}
+	segment.Signature = signature
+	return segment, nil
+}
+
+// VerifySegment checks segment's signature against verifier. An unsigned
+// segment returns ErrUnsigned.
+func VerifySegment(verifier Verifier, segment pmeta.SegmentRef) error {
+	if segment.SignerKeyID == "" || segment.Signature.IsZero() {
+		return fmt.Errorf("%w: segment base_lsn=%d", ErrUnsigned, segment.BaseLSN)
+	}
+	if err := verifier.Verify(segment.SignerKeyID, SegmentMessage(segment), segment.Signature[:]); err != nil {
+		return fmt.Errorf("segment base_lsn=%d: %w", segment.BaseLSN, err)
+	}
+	return nil
+}
+
+// SignHead returns head signed by signer.
+func SignHead(ctx context.Context, signer Signer, head pmeta.PartitionHead) (pmeta.PartitionHead, error) {
+	head.SignerKeyID = signer.KeyID()
+	signature, err := sign(ctx, signer, HeadMessage(head))
+	if err != nil {
+		return pmeta.PartitionHead{}, err
+	}
+	head.Signature = signature
+	return head, nil
+}
+
+// VerifyHead checks head's signature against verifier. A head that commits
+// no segment may be unsigned, because partitions are created and absent
+// partitions are reported before any writer has signed; a head with segments
+// must be signed.
+func VerifyHead(verifier Verifier, head pmeta.PartitionHead) error {
+	if head.SignerKeyID == "" && head.Signature.IsZero() {
+		if head.HasLastSegment {
+			return fmt.Errorf("%w: partition=%d head", ErrUnsigned, head.Partition)
+		}
+		return nil
+	}
+	if err := verifier.Verify(head.SignerKeyID, HeadMessage(head), head.Signature[:]); err != nil {
+		return fmt.Errorf("partition=%d head: %w", head.Partition, err)
+	}
+	return nil
+}
+
+func sign(ctx context.Context, signer Signer, message []byte) (pmeta.Signature, error) {
+	if signer.KeyID() == "" {
+		return pmeta.Signature{}, fmt.Errorf("%w: empty key id", ErrInvalidKey)
+	}
+	raw, err := signer.Sign(ctx, message)
+	if err != nil {
+		return pmeta.Signature{}, err
+	}
+	var signature pmeta.Signature
+	if len(raw) != len(signature) {
+		return pmeta.Signature{}, fmt.Errorf("%w: signature size=%d want=%d", ErrInvalidKey, len(raw), len(signature))
+	}
+	copy(signature[:], raw)
+	return signature, nil
+}
@@ -0,0 +1,171 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+)
+
+func TestSignAndVerifySegmentAndHead(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	signer, verifier := newTestKeys(t, "writer-1", 1)
+	segment, err := SignSegment(ctx, signer, testSegment())
+	if err != nil {
+		t.Fatalf("SignSegment() error = %v", err)
+	}
+	if segment.SignerKeyID != "writer-1" || segment.Signature.IsZero() {
+		t.Fatalf("SignSegment() key=%q signature=%s", segment.SignerKeyID, segment.Signature)
+	}
+	if err := segment.Validate(); err != nil {
+		t.Fatalf("signed segment Validate() error = %v", err)
+	}
+	if err := VerifySegment(verifier, segment); err != nil {
+		t.Fatalf("VerifySegment() error = %v", err)
+	}
+
+	head := pmeta.PartitionHead{
+		Partition:      segment.Partition,
+		NextLSN:        segment.NextLSN(),
+		WriterEpoch:    segment.WriterEpoch,
+		LastSegment:    segment,
+		HasLastSegment: true,
+		SegmentCount:   1,
+		TreeRoot:       pmeta.Hash{9},
+	}
+	if err := VerifyHead(verifier, head); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("VerifyHead(unsigned) error = %v, want %v", err, ErrUnsigned)
+	}
+	if err := VerifyHead(verifier, pmeta.PartitionHead{Partition: 1}); err != nil {
+		t.Fatalf("VerifyHead(empty unsigned) error = %v", err)
+	}
+	head, err = SignHead(ctx, signer, head)
+	if err != nil {
+		t.Fatalf("SignHead() error = %v", err)
+	}
+	if err := VerifyHead(verifier, head); err != nil {
+		t.Fatalf("VerifyHead() error = %v", err)
+	}
+
+	tamperedHead := head
+	tamperedHead.LastSegment.SegmentHash++
+	if err := VerifyHead(verifier, tamperedHead); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("VerifyHead(tampered) error = %v, want %v", err, ErrInvalidSignature)
+	}
+	tamperedHead = head
+	tamperedHead.TreeRoot[0] ^= 1
+	if err := VerifyHead(verifier, tamperedHead); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("VerifyHead(tree root) error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	for name, mutate := range map[string]func(*pmeta.SegmentRef){
+		"uri":            func(s *pmeta.SegmentRef) { s.URI += "x" },
+		"last_lsn":       func(s *pmeta.SegmentRef) { s.LastLSN++ },
+		"segment_digest": func(s *pmeta.SegmentRef) { s.SegmentDigest[0] ^= 1 },
+		"chain_hash":     func(s *pmeta.SegmentRef) { s.ChainHash[0] ^= 1 },
+		"redacted":       func(s *pmeta.SegmentRef) { s.RedactedUnixMS = 1 },
+		"producers": func(s *pmeta.SegmentRef) {
+			s.Producers, _ = pmeta.NewProducers([]pmeta.ProducerState{{ProducerID: [16]byte{1}, Sequence: 1, LSN: s.BaseLSN}})
+		},
+		"signature": func(s *pmeta.SegmentRef) { s.Signature[0] ^= 1 },
+	} {
+		tampered := segment
+		mutate(&tampered)
+		if err := VerifySegment(verifier, tampered); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("VerifySegment(%s) error = %v, want %v", name, err, ErrInvalidSignature)
+		}
+	}
+	if err := VerifySegment(verifier, testSegment()); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("VerifySegment(unsigned) error = %v, want %v", err, ErrUnsigned)
+	}
+
+	otherSigner, _ := newTestKeys(t, "writer-2", 2)
+	foreign, err := SignSegment(ctx, otherSigner, testSegment())
+	if err != nil {
+		t.Fatalf("SignSegment(other) error = %v", err)
+	}
+	if err := VerifySegment(verifier, foreign); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("VerifySegment(untrusted key) error = %v, want %v", err, ErrInvalidSignature)
+	}
+	impostor, err := NewEd25519Signer("writer-1", otherSigner.key)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error = %v", err)
+	}
+	forged, err := SignSegment(ctx, impostor, testSegment())
+	if err != nil {
+		t.Fatalf("SignSegment(impostor) error = %v", err)
+	}
+	if err := VerifySegment(verifier, forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("VerifySegment(impostor) error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestNewEd25519RejectsInvalidKeys(t *testing.T) {
+	t.Parallel()
+
+	public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{1}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	if _, err := NewEd25519Signer("", private); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("NewEd25519Signer(empty id) error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := NewEd25519Signer("k", private[:10]); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("NewEd25519Signer(short key) error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := NewEd25519Verifier(nil); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("NewEd25519Verifier(nil) error = %v, want %v", err, ErrInvalidKey)
+	}
+	if _, err := NewEd25519Verifier(map[string]ed25519.PublicKey{"k": public[:10]}); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("NewEd25519Verifier(short key) error = %v, want %v", err, ErrInvalidKey)
+	}
+}
+
+func newTestKeys(t *testing.T, keyID string, seed byte) (*Ed25519Signer, *Ed25519Verifier) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{seed}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := NewEd25519Signer(keyID, private)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error = %v", err)
+	}
+	verifier, err := NewEd25519Verifier(map[string]ed25519.PublicKey{keyID: public})
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier() error = %v", err)
+	}
+	return signer, verifier
+}
+
+func testSegment() pmeta.SegmentRef {
+	return pmeta.SegmentRef{
+		URI:              "object://p00000001/0",
+		Partition:        1,
+		WriterEpoch:      2,
+		SegmentUUID:      [16]byte{1},
+		WriterTag:        [16]byte{2},
+		BaseLSN:          10,
+		LastLSN:          19,
+		MinTimestampMS:   100,
+		MaxTimestampMS:   200,
+		RecordCount:      10,
+		BlockCount:       1,
+		SizeBytes:        256,
+		BlockIndexOffset: 128,
+		BlockIndexLength: 64,
+		Codec:            segformat.CodecNone,
+		HashAlgo:         segformat.HashSHA256,
+		SegmentHash:      7,
+		TrailerHash:      8,
+		SegmentDigest:    [32]byte{3},
+		RecordsRoot:      pmeta.Hash{4},
+		ChainHash:        pmeta.Hash{5},
+	}
+}
@@ -10,7 +10,8 @@ import (
 //
 // Provider packages such as partitionlog/s3, partitionlog/gcs, and
 // partitionlog/azure implement this interface by wiring together a catalog,
-// segment sink, and segment source.
+// segment sink, and segment source. Their NewNamespace constructors return a
+// StoreProvider that builds one Store per stream for a Namespace.
 type Store interface {
 	WriterManager() catalog.WriterManager
 	RetentionManager() catalog.RetentionManager
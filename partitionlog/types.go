@@ -1,11 +1,16 @@
 package partitionlog
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/ankur-anand/unijord/partitionlog/catalog"
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
+	lowwriter "github.com/ankur-anand/unijord/partitionlog/writer"
 )
 
 // Header is one record header key/value pair.
@@ -17,17 +22,63 @@ type PartitionHead = pmeta.PartitionHead
 // SegmentRef is the durable catalog reference for one committed segment.
 type SegmentRef = pmeta.SegmentRef
 
+// ListPartitionsRequest pages the partitions that exist in a stream. AfterKey
+// is the NextAfterKey of the previous page.
+type ListPartitionsRequest = catalog.ListPartitionsRequest
+
+// PartitionInfo is one discovered partition and, when requested, its head.
+type PartitionInfo = catalog.PartitionInfo
+
+// PartitionPage is one page of discovered partitions in storage order.
+type PartitionPage = catalog.PartitionPage
+
 // Record is appended to one partition writer.
 type Record struct {
 	TimestampMS int64
 	Headers     []Header
 	Value       []byte
+
+	// ProducerID, when nonzero, makes the append idempotent. Sequence must
+	// then be one more than the producer's last appended Sequence, or
+	// FirstSequence for the first append of a producer the partition does
+	// not track.
+	// The last Sequence of each producer is committed with the segment, so a
+	// replacement writer still recognizes a retry after a restart.
+	ProducerID [16]byte
+	Sequence   uint64
 }
 
+// FirstSequence is the Sequence of a producer's first append.
+const FirstSequence = lowwriter.FirstSequence
+
 type AppendResult struct {
 	LSN uint64
+	// Duplicate reports a retry of the producer's last append. LSN is then
+	// the LSN the original append was assigned, and nothing was appended.
+	Duplicate bool
+}
+
+// BatchResult is the contiguous LSN range assigned to one batch.
+type BatchResult struct {
+	FirstLSN uint64
+	LastLSN  uint64
 }
 
+// LSNConflictError reports a conditional append whose expected next LSN is
+// not the writer's. It wraps ErrLSNConflict.
+type LSNConflictError = lowwriter.LSNConflictError
+
+// WAL is a local write-ahead log for one partition's acknowledged but
+// uncommitted records.
+type WAL = lowwriter.WAL
+
+// WALHeader identifies the partition and writer epoch a WAL file was
+// created for.
+type WALHeader = lowwriter.WALHeader
+
+// WALRecord is one record stored in a WAL.
+type WALRecord = lowwriter.WALRecord
+
 type WriterIdentity struct {
 	Epoch uint64
 	Tag   [16]byte
@@ -52,29 +103,166 @@ type InitializePartitionResult struct {
 	Created bool
 }
 
+// SealPartition permanently closes a partition to writers. WriterID fences the
+// seal the same way it fences OpenWriter and must not be reused by a live
+// writer.
+type SealPartition struct {
+	Partition uint32
+	WriterID  [16]byte
+}
+
+// SealPartitionResult reports the sealed head and whether this call sealed it.
+type SealPartitionResult struct {
+	Head   PartitionHead
+	Sealed bool
+}
+
+// DeletePartitionResult reports the head at deletion time and whether this
+// call wrote the tombstone.
+type DeletePartitionResult struct {
+	Head    PartitionHead
+	Deleted bool
+}
+
+// ShredPartitionResult reports the tombstone like DeletePartitionResult and
+// whether this call destroyed the live partition key.
+type ShredPartitionResult struct {
+	Head         PartitionHead
+	Deleted      bool
+	KeyDestroyed bool
+}
+
+// TrainDictionary samples record values from one partition to train a
+// zstd dictionary for its stream.
+type TrainDictionary struct {
+	Partition uint32
+	// StartLSN is the first sampled LSN. Zero starts at the oldest retained
+	// record.
+	StartLSN uint64
+	// MaxSamples bounds the sampled records. Zero uses
+	// DefaultDictionarySamples.
+	MaxSamples int
+	// MaxBytes bounds the dictionary size. Zero uses
+	// segblock.DefaultDictionarySize.
+	MaxBytes int
+}
+
+// TrainDictionaryResult reports the published dictionary ID, which
+// WriterPipelineOptions.DictionaryID selects, and the samples it was trained
+// on.
+type TrainDictionaryResult struct {
+	DictionaryID uint32
+	Samples      int
+}
+
+// RedactRequest names committed records to replace with redaction
+// tombstones. Reason is stored as the RedactionHeaderKey header value.
+type RedactRequest struct {
+	LSNs   []uint64
+	Reason string
+}
+
+// RedactResult reports the committed snapshot after the last rewritten
+// segment and how many segments and records were redacted.
+type RedactResult struct {
+	Snapshot Snapshot
+	Segments int
+	Records  int
+}
+
+// RedactionHeaderKey is the first header on a redacted record. Its value is
+// the redaction reason. A second header carries the content digest of the
+// record it replaced, which keeps inclusion proofs valid after redaction.
+const RedactionHeaderKey = segformat.RedactionHeaderKey
+
+// RedactionDigestHeaderKey is the second header on a redacted record.
+const RedactionDigestHeaderKey = segformat.RedactionDigestHeaderKey
+
+// RedactedValue is the tombstone value of a redacted record.
+const RedactedValue = segformat.RedactedValue
+
+// RecordProof proves that one record is committed under a partition's
+// TreeRoot. Check it offline with proof.Verify or proof.VerifyRecord.
+type RecordProof = proof.RecordProof
+
+// ProofCheckpoint is the published root a RecordProof verifies against.
+type ProofCheckpoint = proof.Checkpoint
+
+// ConsistencyProof proves that one partition checkpoint extends an earlier
+// one. Check it offline with proof.VerifyConsistency.
+type ConsistencyProof = proof.ConsistencyProof
+
+// Signer signs segment refs and partition heads for one writer key.
+// signing.Ed25519Signer implements it.
+type Signer = signing.Signer
+
+// Verifier checks writer signatures against trusted keys.
+// signing.Ed25519Verifier implements it.
+type Verifier = signing.Verifier
+
+// DeleteStreamResult reports how many partitions this call tombstoned.
+type DeleteStreamResult struct {
+	Partitions int
+}
+
+// LegalHold is one durable legal hold marker. A cleared hold stays stored
+// inactive with its last reason.
+type LegalHold = catalog.LegalHold
+
+// LegalHolds are the partition and stream holds that cover one partition.
+type LegalHolds = catalog.LegalHolds
+
+// SetLegalHold places a legal hold on one partition, or on every partition of
+// the stream when Stream is set.
+type SetLegalHold struct {
+	Partition uint32
+	Stream    bool
+	Reason    string
+}
+
+// ClearLegalHold releases a hold placed with SetLegalHold.
+type ClearLegalHold struct {
+	Partition uint32
+	Stream    bool
+}
+
 // RetentionRequest asks the partition owner to retire complete segments below
-// BeforeLSN. PolicyVersion must increase for every changed request.
+// BeforeLSN, complete segments whose records are all older than
+// BeforeTimestampMS, and the oldest complete segments until the partition
+// retains at most MaxRetainedBytes segment bytes. Unset rules are ignored and
+// the most restrictive rule wins. MinRetainedRecords then keeps at least that
+// many of the newest records. PolicyVersion must increase for every changed
+// request.
 type RetentionRequest struct {
-	Partition     uint32
-	PolicyVersion uint64
-	BeforeLSN     uint64
+	Partition          uint32
+	PolicyVersion      uint64
+	BeforeLSN          uint64
+	BeforeTimestampMS  int64
+	MaxRetainedBytes   uint64
+	MinRetainedRecords uint64
 }
 
 // RetentionRequestState is the durable retention intent stored by the catalog.
 type RetentionRequestState struct {
-	Partition     uint32
-	PolicyVersion uint64
-	BeforeLSN     uint64
-	CreatedUnixMS int64
+	Partition          uint32
+	PolicyVersion      uint64
+	BeforeLSN          uint64
+	BeforeTimestampMS  int64
+	MaxRetainedBytes   uint64
+	MinRetainedRecords uint64
+	CreatedUnixMS      int64
 }
 
 // RetentionResult reports whether the active writer incorporated a pending
-// request into partition visibility.
+// request into partition visibility. RequestedLSN is the applied LSN boundary;
+// for a timestamp request it is the boundary resolved from
+// RequestedTimestampMS.
 type RetentionResult struct {
-	Snapshot      Snapshot
-	PolicyVersion uint64
-	RequestedLSN  uint64
-	Applied       bool
+	Snapshot             Snapshot
+	PolicyVersion        uint64
+	RequestedLSN         uint64
+	RequestedTimestampMS int64
+	Applied              bool
 }
 
 type WriterState struct {
@@ -137,4 +325,64 @@ type WriterPipelineOptions struct {
 
 	// UploadLimiter optionally coordinates upload concurrency across writers.
 	UploadLimiter segwriter.UploadLimiter
+
+	// Compression selects the block codec. The zero value keeps the default,
+	// zstd. LZ4 and S2 cost more storage than zstd but less CPU and latency.
+	Compression Compression
+
+	// ZstdLevel is the zstd compression level, from 1 to 22. Zero keeps the
+	// default level. It applies only to zstd compression.
+	ZstdLevel int
+
+	// DictionaryID selects a trained dictionary from Options.Dictionaries.
+	// Blocks are then compressed with zstd primed by the dictionary, which
+	// helps most when records are small and alike. Compression must be
+	// default or zstd. Zero compresses without a dictionary.
+	DictionaryID uint32
+
+	// CompactRecords writes the compact record format, which encodes record
+	// lengths as varints and timestamps as block-relative deltas instead of a
+	// fixed 16-byte header per record. It saves the most on small records.
+	// Readers decode both formats.
+	CompactRecords bool
+}
+
+// Compression selects the codec that compresses segment blocks.
+type Compression uint8
+
+const (
+	CompressionDefault Compression = iota
+	CompressionNone
+	CompressionZstd
+	CompressionLZ4
+	CompressionS2
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionDefault:
+		return "default"
+	case CompressionNone, CompressionZstd, CompressionLZ4, CompressionS2:
+		codec, _ := c.codec()
+		return codec.String()
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// codec returns the segment codec for c. ok is false for CompressionDefault
+// and unknown values.
+func (c Compression) codec() (codec segformat.Codec, ok bool) {
+	switch c {
+	case CompressionNone:
+		return segformat.CodecNone, true
+	case CompressionZstd:
+		return segformat.CodecZstd, true
+	case CompressionLZ4:
+		return segformat.CodecLZ4, true
+	case CompressionS2:
+		return segformat.CodecS2, true
+	default:
+		return 0, false
+	}
 }
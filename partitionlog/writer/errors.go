@@ -1,6 +1,9 @@
 package writer
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidOptions       = errors.New("writer: invalid options")
@@ -17,4 +20,48 @@ var (
 	ErrInvalidPublishResult = errors.New("writer: invalid publish result")
 	ErrRetentionUnsupported = errors.New("writer: retention unsupported")
 	ErrRetentionFailed      = errors.New("writer: retention failed")
+	ErrRedactionUnsupported = errors.New("writer: redaction unsupported")
+	ErrRedactionFailed      = errors.New("writer: redaction failed")
+	ErrSealUnsupported      = errors.New("writer: seal unsupported")
+	ErrSealFailed           = errors.New("writer: seal failed")
+	ErrReleaseFailed        = errors.New("writer: release failed")
+	// ErrDuplicateSequence reports a producer Sequence below the producer's
+	// last appended Sequence. That record was already appended.
+	ErrDuplicateSequence = errors.New("writer: duplicate producer sequence")
+	// ErrSequenceGap reports a producer Sequence more than one past the
+	// producer's last appended Sequence.
+	ErrSequenceGap = errors.New("writer: producer sequence gap")
+	// ErrUnknownProducer reports a Sequence other than FirstSequence from a
+	// producer the writer does not track, either new or forgotten past
+	// MaxProducers. Nothing was appended; the producer must be fenced or
+	// start over at FirstSequence.
+	ErrUnknownProducer = errors.New("writer: unknown producer")
+	// ErrLSNConflict reports a conditional append whose expected next LSN is
+	// not the writer's. Nothing was appended. See LSNConflictError.
+	ErrLSNConflict = errors.New("writer: lsn conflict")
+	ErrEmptyBatch  = errors.New("writer: empty batch")
+	// ErrReservedRecord reports a record that uses a redaction header key or
+	// the redacted value marker. Nothing was appended.
+	ErrReservedRecord = errors.New("writer: reserved record")
+	// ErrNotAppended reports a wait for an LSN this writer has not assigned.
+	ErrNotAppended = errors.New("writer: lsn not appended")
+	ErrWALFailed   = errors.New("writer: wal failed")
+	// ErrWALMismatch reports a WAL whose files were written for another
+	// stream or partition, or by a newer writer of this one. Nothing was
+	// replayed.
+	ErrWALMismatch = errors.New("writer: wal mismatch")
 )
+
+// LSNConflictError is the ErrLSNConflict of one conditional append.
+type LSNConflictError struct {
+	Expected uint64
+	Next     uint64
+}
+
+func (e LSNConflictError) Error() string {
+	return fmt.Sprintf("%v: expected_next=%d next=%d", ErrLSNConflict, e.Expected, e.Next)
+}
+
+func (e LSNConflictError) Unwrap() error {
+	return ErrLSNConflict
+}
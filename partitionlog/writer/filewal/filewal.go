@@ -0,0 +1,716 @@
+// Package filewal is a writer.WAL in a local directory. Each Append writes
+// one checksummed frame to the current file, and Sync fsyncs every frame
+// written before it. Files rotate at Options.MaxFileBytes, and Trim deletes
+// the oldest files once every record in them is committed.
+//
+// One directory holds the WAL of one partition. Open locks it, so a second
+// WAL on the same directory fails with ErrLocked until the first is closed.
+// Every file starts with a header naming the stream, partition and writer
+// epoch it was created for, which the writer checks before replay.
+package filewal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+const (
+	DefaultMaxFileBytes int64 = 64 << 20
+
+	fileSuffix      = ".wal"
+	lockName        = "LOCK"
+	fileMagic       = "UWAL"
+	frameHeaderSize = 8
+	// fileHeaderPrefixSize is the partition and epoch of a file header.
+	fileHeaderPrefixSize = 12
+	// framePrefixSize is the epoch, first LSN and record count.
+	framePrefixSize = 20
+	dirMode         = 0o755
+	fileMode        = 0o644
+)
+
+var (
+	ErrInvalidOptions = errors.New("filewal: invalid options")
+	ErrCorrupt        = errors.New("filewal: corrupt wal")
+	ErrClosed         = errors.New("filewal: wal closed")
+	// ErrLocked reports a directory another WAL has open.
+	ErrLocked = errors.New("filewal: wal locked")
+	// ErrNotBound reports a call before Bind.
+	ErrNotBound = errors.New("filewal: wal not bound")
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+type Options struct {
+	// MaxFileBytes starts a new file once the current one reaches it. 0
+	// selects DefaultMaxFileBytes.
+	MaxFileBytes int64
+}
+
+type WAL struct {
+	dir    string
+	opts   Options
+	unlock func()
+
+	mu sync.Mutex
+	// files are ordered by sequence number; once bound, the last one is
+	// active.
+	files      []walFile
+	header     writer.WALHeader
+	active     *os.File
+	activeSize int64
+	// failed is set when a failed Append may have left a partial frame or a
+	// failed fsync may have dropped written frames.
+	failed error
+	closed bool
+}
+
+type walFile struct {
+	seq        uint64
+	header     writer.WALHeader
+	hasRecords bool
+	maxLSN     uint64
+}
+
+type frame struct {
+	epoch    uint64
+	firstLSN uint64
+	records  []writer.Record
+}
+
+var _ writer.WAL = (*WAL)(nil)
+
+// Open opens and locks the WAL in dir, creating it if needed. A frame or file
+// header torn by a crash at the end of the newest file is discarded. An
+// invalid frame anywhere else fails with ErrCorrupt, since frames after it
+// may have been acknowledged. The WAL is usable once bound.
+func Open(dir string, opts Options) (*WAL, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%w: empty dir", ErrInvalidOptions)
+	}
+	if opts.MaxFileBytes == 0 {
+		opts.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if opts.MaxFileBytes < 0 {
+		return nil, fmt.Errorf("%w: negative max file bytes %d", ErrInvalidOptions, opts.MaxFileBytes)
+	}
+	if err := os.MkdirAll(dir, dirMode); err != nil {
+		return nil, fmt.Errorf("filewal: create %s: %w", dir, err)
+	}
+	unlock, err := lockFile(filepath.Join(dir, lockName))
+	if err != nil {
+		return nil, err
+	}
+	w := &WAL{dir: dir, opts: opts, unlock: unlock}
+	if err := w.load(); err != nil {
+		unlock()
+		return nil, err
+	}
+	return w, nil
+}
+
+// load scans the stored files, discarding a torn tail of the newest one.
+func (w *WAL) load() error {
+	seqs, err := listFiles(w.dir)
+	if err != nil {
+		return err
+	}
+	for i, seq := range seqs {
+		path := w.path(seq)
+		header, frames, valid, size, err := scanFile(path)
+		if err != nil {
+			return err
+		}
+		newest := i == len(seqs)-1
+		if valid == 0 {
+			// The file header itself is torn; no frame follows it.
+			if !newest {
+				return fmt.Errorf("%w: %s: invalid file header", ErrCorrupt, path)
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("filewal: remove torn file: %w", err)
+			}
+			syncDir(w.dir)
+			break
+		}
+		if valid < size {
+			if !newest {
+				return fmt.Errorf("%w: %s: invalid frame at offset %d", ErrCorrupt, path, valid)
+			}
+			if err := truncateFile(path, valid); err != nil {
+				return err
+			}
+		}
+		file := walFile{seq: seq, header: header}
+		for _, f := range frames {
+			file.observe(f.firstLSN + uint64(len(f.records)) - 1)
+		}
+		w.files = append(w.files, file)
+	}
+	return nil
+}
+
+// Bind returns the headers of the stored files and starts a new file with
+// header, to which every later Append writes.
+func (w *WAL) Bind(ctx context.Context, header writer.WALHeader) ([]writer.WALHeader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+	if w.failed != nil {
+		return nil, w.failed
+	}
+	headers := make([]writer.WALHeader, 0, len(w.files))
+	for _, file := range w.files {
+		headers = append(headers, file.header)
+	}
+	w.header = header
+	if w.active != nil {
+		return headers, w.rotateLocked()
+	}
+	seq := uint64(1)
+	if len(w.files) > 0 {
+		seq = w.files[len(w.files)-1].seq + 1
+	}
+	return headers, w.createFileLocked(seq)
+}
+
+// Append writes records as one frame. They are durable once a later Sync
+// returns nil.
+func (w *WAL) Append(ctx context.Context, epoch, firstLSN uint64, records []writer.Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(records) == 0 || uint64(len(records))-1 > math.MaxUint64-firstLSN {
+		return fmt.Errorf("filewal: invalid append lsn=%d records=%d", firstLSN, len(records))
+	}
+	body, err := encodeFrame(frame{epoch: epoch, firstLSN: firstLSN, records: records})
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.usableLocked(); err != nil {
+		return err
+	}
+	if _, err := w.active.Write(body); err != nil {
+		return w.undoLocked(fmt.Errorf("filewal: write: %w", err))
+	}
+	w.activeSize += int64(len(body))
+	w.files[len(w.files)-1].observe(firstLSN + uint64(len(records)) - 1)
+	if w.activeSize >= w.opts.MaxFileBytes {
+		return w.rotateLocked()
+	}
+	return nil
+}
+
+// Sync fsyncs the active file, making every frame written before it durable.
+// Files rotated out were fsynced when they were rotated. It runs without the
+// WAL's lock, so Appends continue while it waits for the disk. A failed fsync
+// fails the WAL: a later one could report success over frames it dropped.
+func (w *WAL) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	if err := w.usableLocked(); err != nil {
+		w.mu.Unlock()
+		return err
+	}
+	active := w.active
+	w.mu.Unlock()
+
+	err := active.Sync()
+	if err == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if active != w.active && errors.Is(err, os.ErrClosed) {
+		// A rotation fsynced and closed the file first.
+		return nil
+	}
+	err = fmt.Errorf("filewal: sync: %w", err)
+	if w.failed == nil {
+		w.failed = err
+	}
+	return err
+}
+
+// Replay returns the records at or above fromLSN. A frame supersedes the
+// records of earlier frames at or above its first LSN.
+func (w *WAL) Replay(ctx context.Context, fromLSN uint64) ([]writer.WALRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.usableLocked(); err != nil {
+		return nil, err
+	}
+	var stored []writer.WALRecord
+	for _, file := range w.files {
+		_, frames, _, _, err := scanFile(w.path(file.seq))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range frames {
+			keep, _ := slices.BinarySearchFunc(stored, f.firstLSN, func(r writer.WALRecord, lsn uint64) int {
+				switch {
+				case r.LSN < lsn:
+					return -1
+				case r.LSN > lsn:
+					return 1
+				}
+				return 0
+			})
+			stored = stored[:keep]
+			for i, record := range f.records {
+				stored = append(stored, writer.WALRecord{Epoch: f.epoch, LSN: f.firstLSN + uint64(i), Record: record})
+			}
+		}
+	}
+	start := 0
+	for start < len(stored) && stored[start].LSN < fromLSN {
+		start++
+	}
+	return stored[start:], nil
+}
+
+// Trim deletes the oldest files whose records are all below lsn, starting a
+// new file first if that includes the active one.
+func (w *WAL) Trim(ctx context.Context, lsn uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.usableLocked(); err != nil {
+		return err
+	}
+	n := 0
+	for n < len(w.files) && w.files[n].below(lsn) {
+		n++
+	}
+	if n == len(w.files) {
+		if !w.files[n-1].hasRecords {
+			n--
+		} else if err := w.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	for _, file := range w.files[:n] {
+		if err := os.Remove(w.path(file.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("filewal: trim: %w", err)
+		}
+	}
+	w.files = slices.Delete(w.files, 0, n)
+	syncDir(w.dir)
+	return nil
+}
+
+// Close closes the active file and unlocks the directory.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.unlock()
+	if w.active == nil {
+		return nil
+	}
+	return w.active.Close()
+}
+
+func (w *WAL) usableLocked() error {
+	switch {
+	case w.closed:
+		return ErrClosed
+	case w.failed != nil:
+		return w.failed
+	case w.active == nil:
+		return ErrNotBound
+	}
+	return nil
+}
+
+// undoLocked truncates a partial frame left by a failed write. If that fails
+// too, the WAL refuses further use.
+func (w *WAL) undoLocked(err error) error {
+	if truncErr := w.active.Truncate(w.activeSize); truncErr != nil {
+		w.failed = fmt.Errorf("filewal: unrecovered partial frame: %w", err)
+	}
+	return err
+}
+
+// rotateLocked fsyncs the active file and starts the next one.
+func (w *WAL) rotateLocked() error {
+	old := w.active
+	if err := old.Sync(); err != nil {
+		err = fmt.Errorf("filewal: sync: %w", err)
+		w.failed = err
+		return err
+	}
+	if err := w.createFileLocked(w.files[len(w.files)-1].seq + 1); err != nil {
+		return err
+	}
+	_ = old.Close()
+	return nil
+}
+
+// createFileLocked starts file seq with the bound header. The header is
+// durable with the first Sync of the file.
+func (w *WAL) createFileLocked(seq uint64) error {
+	path := w.path(seq)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, fileMode)
+	if err != nil {
+		return fmt.Errorf("filewal: create: %w", err)
+	}
+	header := encodeFileHeader(w.header)
+	if _, err := f.Write(header); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("filewal: write header: %w", err)
+	}
+	syncDir(w.dir)
+	w.active = f
+	w.activeSize = int64(len(header))
+	w.files = append(w.files, walFile{seq: seq, header: w.header})
+	return nil
+}
+
+func (w *WAL) path(seq uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seq, fileSuffix))
+}
+
+func (f *walFile) observe(lastLSN uint64) {
+	if !f.hasRecords || lastLSN > f.maxLSN {
+		f.maxLSN = lastLSN
+	}
+	f.hasRecords = true
+}
+
+func (f walFile) below(lsn uint64) bool {
+	return !f.hasRecords || f.maxLSN < lsn
+}
+
+func listFiles(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("filewal: list %s: %w", dir, err)
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), fileSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil || seq == 0 {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrCorrupt, entry.Name())
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+	return seqs, nil
+}
+
+// scanFile decodes the header and frames of one file and returns them with
+// the length of the valid prefix and the file size. A torn frame at the end
+// of the file ends the valid prefix, and a torn header leaves it empty; an
+// invalid header or frame before the end fails with ErrCorrupt.
+func scanFile(path string) (writer.WALHeader, []frame, int64, int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return writer.WALHeader{}, nil, 0, 0, fmt.Errorf("filewal: read: %w", err)
+	}
+	header, off, ok := nextFileHeader(data)
+	if !ok {
+		if !tornHeader(data) {
+			return writer.WALHeader{}, nil, 0, 0, fmt.Errorf("%w: %s: invalid file header", ErrCorrupt, path)
+		}
+		return writer.WALHeader{}, nil, 0, int64(len(data)), nil
+	}
+	var frames []frame
+	for off < len(data) {
+		f, n, ok := nextFrame(data[off:])
+		if !ok {
+			if !tornTail(data[off:]) {
+				return writer.WALHeader{}, nil, 0, 0, fmt.Errorf("%w: %s: invalid frame at offset %d", ErrCorrupt, path, off)
+			}
+			break
+		}
+		frames = append(frames, f)
+		off += n
+	}
+	return header, frames, int64(off), int64(len(data)), nil
+}
+
+// nextFileHeader decodes the file header at the start of data and returns its
+// encoded length.
+func nextFileHeader(data []byte) (writer.WALHeader, int, bool) {
+	if !bytes.HasPrefix(data, []byte(fileMagic)) {
+		return writer.WALHeader{}, 0, false
+	}
+	payload, n, ok := nextPayload(data[len(fileMagic):])
+	if !ok || len(payload) < fileHeaderPrefixSize {
+		return writer.WALHeader{}, 0, false
+	}
+	header := writer.WALHeader{
+		Partition: binary.BigEndian.Uint32(payload),
+		Epoch:     binary.BigEndian.Uint64(payload[4:]),
+		StreamID:  string(payload[fileHeaderPrefixSize:]),
+	}
+	return header, len(fileMagic) + n, true
+}
+
+// nextFrame decodes the frame at the start of data and returns its encoded
+// length. It reports false for a short, mismatched or undecodable frame.
+func nextFrame(data []byte) (frame, int, bool) {
+	payload, n, ok := nextPayload(data)
+	if !ok {
+		return frame{}, 0, false
+	}
+	f, err := decodeFrame(payload)
+	if err != nil {
+		return frame{}, 0, false
+	}
+	return f, n, true
+}
+
+// nextPayload returns the checksummed payload at the start of data and its
+// encoded length. It reports false for a short or mismatched payload.
+func nextPayload(data []byte) ([]byte, int, bool) {
+	if len(data) < frameHeaderSize {
+		return nil, 0, false
+	}
+	length := int(binary.BigEndian.Uint32(data))
+	sum := binary.BigEndian.Uint32(data[4:])
+	if length > len(data)-frameHeaderSize {
+		return nil, 0, false
+	}
+	payload := data[frameHeaderSize : frameHeaderSize+length]
+	if crc32.Checksum(payload, castagnoliTable) != sum {
+		return nil, 0, false
+	}
+	return payload, frameHeaderSize + length, true
+}
+
+// tornHeader reports whether data, which does not start with a valid file
+// header, is a header torn by a crash, as tornTail reports for frames.
+func tornHeader(data []byte) bool {
+	if len(data) < len(fileMagic) {
+		return true
+	}
+	if string(data[:len(fileMagic)]) != fileMagic {
+		return !slices.ContainsFunc(data, func(b byte) bool { return b != 0 })
+	}
+	return tornTail(data[len(fileMagic):])
+}
+
+// tornTail reports whether the invalid frame at the start of rest is the
+// last write, torn by a crash: its header or its declared length runs to the
+// end of the file, or only zeros follow, as when the file grew but its pages
+// were never written.
+func tornTail(rest []byte) bool {
+	if len(rest) < frameHeaderSize {
+		return true
+	}
+	if int64(binary.BigEndian.Uint32(rest)) >= int64(len(rest)-frameHeaderSize) {
+		return true
+	}
+	for _, b := range rest {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func truncateFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, fileMode)
+	if err != nil {
+		return fmt.Errorf("filewal: open: %w", err)
+	}
+	defer f.Close()
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("filewal: truncate torn frame: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("filewal: sync: %w", err)
+	}
+	return nil
+}
+
+// encodeFileHeader returns the header that starts every file:
+//
+//	magic "UWAL" | payload_len u32 | crc32c(payload) u32 | payload
+//	payload:    partition u32 | epoch u64 | stream_id
+//
+// Integers are big-endian.
+func encodeFileHeader(header writer.WALHeader) []byte {
+	buf := make([]byte, 0, len(fileMagic)+frameHeaderSize+fileHeaderPrefixSize+len(header.StreamID))
+	buf = append(buf, fileMagic...)
+	buf = append(buf, make([]byte, frameHeaderSize)...)
+	buf = binary.BigEndian.AppendUint32(buf, header.Partition)
+	buf = binary.BigEndian.AppendUint64(buf, header.Epoch)
+	buf = append(buf, header.StreamID...)
+	payload := buf[len(fileMagic)+frameHeaderSize:]
+	binary.BigEndian.PutUint32(buf[len(fileMagic):], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[len(fileMagic)+4:], crc32.Checksum(payload, castagnoliTable))
+	return buf
+}
+
+// encodeFrame returns the frame:
+//
+//	payload_len u32 | crc32c(payload) u32 | payload
+//	payload:    epoch u64 | first_lsn u64 | record_count u32 | records
+//	record:     timestamp_ms i64 | producer_id [16] | sequence u64 |
+//	            header_count u32 | (key_len u32, key, value_len u32, value)* |
+//	            value_len u32 | value
+//
+// Integers are big-endian.
+func encodeFrame(f frame) ([]byte, error) {
+	buf := make([]byte, frameHeaderSize, frameHeaderSize+framePrefixSize)
+	buf = binary.BigEndian.AppendUint64(buf, f.epoch)
+	buf = binary.BigEndian.AppendUint64(buf, f.firstLSN)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(f.records)))
+	for _, record := range f.records {
+		if _, err := segformat.RecordSize(record.Headers, record.Value); err != nil {
+			return nil, err
+		}
+		buf = binary.BigEndian.AppendUint64(buf, uint64(record.TimestampMS))
+		buf = append(buf, record.ProducerID[:]...)
+		buf = binary.BigEndian.AppendUint64(buf, record.Sequence)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(record.Headers)))
+		for _, h := range record.Headers {
+			buf = appendBytes(buf, h.Key)
+			buf = appendBytes(buf, h.Value)
+		}
+		buf = appendBytes(buf, record.Value)
+	}
+	payload := buf[frameHeaderSize:]
+	if len(payload) > math.MaxUint32 {
+		return nil, fmt.Errorf("filewal: frame of %d bytes is too large", len(payload))
+	}
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, castagnoliTable))
+	return buf, nil
+}
+
+func decodeFrame(payload []byte) (frame, error) {
+	r := frameReader{buf: payload}
+	f := frame{epoch: r.uint64(), firstLSN: r.uint64()}
+	count := r.uint32()
+	if r.err != nil || count == 0 || uint64(count)-1 > math.MaxUint64-f.firstLSN {
+		return frame{}, fmt.Errorf("%w: frame prefix", ErrCorrupt)
+	}
+	f.records = make([]writer.Record, 0, min(int(count), len(payload)/40))
+	for range count {
+		record := writer.Record{TimestampMS: int64(r.uint64())}
+		copy(record.ProducerID[:], r.bytes(16))
+		record.Sequence = r.uint64()
+		if headers := r.uint32(); headers > 0 && r.err == nil {
+			if headers > segformat.MaxRecordHeaders {
+				return frame{}, fmt.Errorf("%w: header_count=%d", ErrCorrupt, headers)
+			}
+			record.Headers = make([]segformat.Header, headers)
+			for i := range record.Headers {
+				record.Headers[i].Key = r.lenBytes()
+				record.Headers[i].Value = r.lenBytes()
+			}
+		}
+		record.Value = r.lenBytes()
+		if r.err != nil {
+			return frame{}, r.err
+		}
+		f.records = append(f.records, record)
+	}
+	if len(r.buf) != 0 {
+		return frame{}, fmt.Errorf("%w: %d trailing frame bytes", ErrCorrupt, len(r.buf))
+	}
+	return f, nil
+}
+
+func appendBytes(dst, b []byte) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(b)))
+	return append(dst, b...)
+}
+
+// frameReader reads big-endian fields and records the first short read.
+type frameReader struct {
+	buf []byte
+	err error
+}
+
+func (r *frameReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.buf) < n {
+		r.err = fmt.Errorf("%w: truncated frame", ErrCorrupt)
+		return nil
+	}
+	b := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *frameReader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *frameReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *frameReader) lenBytes() []byte {
+	n := r.uint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(n) > uint64(len(r.buf)) {
+		r.err = fmt.Errorf("%w: truncated frame", ErrCorrupt)
+		return nil
+	}
+	return bytes.Clone(r.bytes(int(n)))
+}
+
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = f.Sync()
+	_ = f.Close()
+}
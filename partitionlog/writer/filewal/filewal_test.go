@@ -0,0 +1,377 @@
+package filewal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ankur-anand/unijord/partitionlog/segformat"
+	"github.com/ankur-anand/unijord/partitionlog/writer"
+)
+
+func TestWALReplaysRecordsAcrossReopenAndSupersedes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	first := writer.Record{
+		TimestampMS: 10,
+		ProducerID:  [16]byte{1},
+		Sequence:    7,
+		Headers:     []segformat.Header{{Key: []byte("k"), Value: []byte("v")}},
+		Value:       []byte("alpha"),
+	}
+	if err := w.Append(ctx, 1, 5, []writer.Record{first, {TimestampMS: 11, Value: []byte("beta")}}); err != nil {
+		t.Fatalf("Append(5) error = %v", err)
+	}
+	if err := w.Append(ctx, 1, 7, []writer.Record{{TimestampMS: 12, Value: []byte("gamma")}}); err != nil {
+		t.Fatalf("Append(7) error = %v", err)
+	}
+	// A later epoch rewrites LSN 6 onwards; the old 6 and 7 are superseded.
+	if err := w.Append(ctx, 2, 6, []writer.Record{{TimestampMS: 13, Value: []byte("delta")}}); err != nil {
+		t.Fatalf("Append(6) error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	w, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open(reopen) error = %v", err)
+	}
+	bind(t, w, 1)
+	defer w.Close()
+	records, err := w.Replay(ctx, 0)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Replay() records = %d want 2", len(records))
+	}
+	got := records[0]
+	if got.Epoch != 1 || got.LSN != 5 || got.Record.TimestampMS != 10 || got.Record.ProducerID != first.ProducerID ||
+		got.Record.Sequence != 7 || len(got.Record.Headers) != 1 || string(got.Record.Headers[0].Value) != "v" ||
+		!bytes.Equal(got.Record.Value, first.Value) {
+		t.Fatalf("Replay()[0] = %+v want epoch 1 lsn 5 %+v", got, first)
+	}
+	if got := records[1]; got.Epoch != 2 || got.LSN != 6 || string(got.Record.Value) != "delta" {
+		t.Fatalf("Replay()[1] = %+v want epoch 2 lsn 6 delta", got)
+	}
+
+	records, err = w.Replay(ctx, 6)
+	if err != nil {
+		t.Fatalf("Replay(6) error = %v", err)
+	}
+	if len(records) != 1 || records[0].LSN != 6 {
+		t.Fatalf("Replay(6) = %+v want only lsn 6", records)
+	}
+}
+
+func TestWALDiscardsTornTailOnOpen(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	if err := w.Append(ctx, 1, 0, []writer.Record{{TimestampMS: 1, Value: []byte("kept")}}); err != nil {
+		t.Fatalf("Append(0) error = %v", err)
+	}
+	if err := w.Append(ctx, 1, 1, []writer.Record{{TimestampMS: 2, Value: []byte("torn")}}); err != nil {
+		t.Fatalf("Append(1) error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	path := filepath.Join(dir, "00000000000000000001.wal")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if err := os.Truncate(path, info.Size()-3); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+
+	w, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open(torn) error = %v", err)
+	}
+	bind(t, w, 1)
+	defer w.Close()
+	records, err := w.Replay(ctx, 0)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(records) != 1 || string(records[0].Record.Value) != "kept" {
+		t.Fatalf("Replay() = %+v want only the kept record", records)
+	}
+	if err := w.Append(ctx, 1, 1, []writer.Record{{TimestampMS: 3, Value: []byte("again")}}); err != nil {
+		t.Fatalf("Append(after torn) error = %v", err)
+	}
+	records, err = w.Replay(ctx, 0)
+	if err != nil {
+		t.Fatalf("Replay(after append) error = %v", err)
+	}
+	if len(records) != 2 || string(records[1].Record.Value) != "again" {
+		t.Fatalf("Replay(after append) = %+v want kept, again", records)
+	}
+}
+
+func TestWALRejectsCorruptionBeforeNewestFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{MaxFileBytes: 1})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	for lsn := range uint64(2) {
+		if err := w.Append(ctx, 1, lsn, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", lsn, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	path := filepath.Join(dir, "00000000000000000001.wal")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := Open(dir, Options{}); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open(corrupt) error = %v want ErrCorrupt", err)
+	}
+}
+
+func TestWALRejectsCorruptFrameBeforeValidFrames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	for lsn := range uint64(3) {
+		if err := w.Append(ctx, 1, lsn, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", lsn, err)
+		}
+	}
+	if err := w.Sync(ctx); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	path := filepath.Join(dir, "00000000000000000001.wal")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	// Flip the last byte of the first frame; the two after it are intact.
+	headerSize := len(encodeFileHeader(testHeader(1)))
+	data[headerSize+(len(data)-headerSize)/3-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := Open(dir, Options{}); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Open(corrupt mid-file) error = %v want ErrCorrupt", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || len(got) != len(data) {
+		t.Fatalf("file after Open = %d bytes, %v; want it left untouched at %d", len(got), err, len(data))
+	}
+}
+
+func TestWALFailsAfterFailedSync(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w, err := Open(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	defer w.Close()
+	if err := w.Append(ctx, 1, 0, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	// Closing the file under the WAL makes the next fsync fail.
+	if err := w.active.Close(); err != nil {
+		t.Fatalf("Close(active) error = %v", err)
+	}
+	if err := w.Sync(ctx); err == nil {
+		t.Fatal("Sync() error = nil")
+	}
+	if err := w.Append(ctx, 1, 1, []writer.Record{{TimestampMS: 1}}); err == nil {
+		t.Fatal("Append(after failed sync) error = nil")
+	}
+	if err := w.Sync(ctx); err == nil {
+		t.Fatal("Sync(after failed sync) error = nil")
+	}
+}
+
+func TestWALTrimDeletesCommittedFiles(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{MaxFileBytes: 1})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	bind(t, w, 1)
+	defer w.Close()
+	for lsn := range uint64(3) {
+		if err := w.Append(ctx, 1, lsn, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+			t.Fatalf("Append(%d) error = %v", lsn, err)
+		}
+	}
+	// Each append filled a file, so there are three full files and an empty
+	// active one.
+	assertFiles(t, dir, 4)
+
+	if err := w.Trim(ctx, 2); err != nil {
+		t.Fatalf("Trim(2) error = %v", err)
+	}
+	assertFiles(t, dir, 2)
+	records, err := w.Replay(ctx, 0)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(records) != 1 || records[0].LSN != 2 {
+		t.Fatalf("Replay() = %+v want only lsn 2", records)
+	}
+
+	if err := w.Trim(ctx, 3); err != nil {
+		t.Fatalf("Trim(3) error = %v", err)
+	}
+	assertFiles(t, dir, 1)
+	if err := w.Append(ctx, 1, 3, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+		t.Fatalf("Append(3) error = %v", err)
+	}
+	records, err = w.Replay(ctx, 0)
+	if err != nil {
+		t.Fatalf("Replay(after trim) error = %v", err)
+	}
+	if len(records) != 1 || records[0].LSN != 3 {
+		t.Fatalf("Replay(after trim) = %+v want only lsn 3", records)
+	}
+}
+
+func assertFiles(t *testing.T, dir string, want int) {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+fileSuffix))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if len(matches) != want {
+		t.Fatalf("wal files = %v want %d", matches, want)
+	}
+}
+
+func TestWALLocksDirectory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := Open(dir, Options{}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Open(second) error = %v want ErrLocked", err)
+	}
+	if err := w.Append(context.Background(), 1, 0, []writer.Record{{TimestampMS: 1}}); !errors.Is(err, ErrNotBound) {
+		t.Fatalf("Append(unbound) error = %v want ErrNotBound", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	w, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open(after close) error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(again) error = %v", err)
+	}
+}
+
+func TestWALRecordsFileHeaders(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if headers := bind(t, w, 1); len(headers) != 0 {
+		t.Fatalf("Bind(empty) headers = %+v want none", headers)
+	}
+	if err := w.Append(ctx, 1, 0, []writer.Record{{TimestampMS: 1, Value: []byte("v")}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// A crash right after a file was created leaves a torn header.
+	torn := filepath.Join(dir, "00000000000000000002.wal")
+	if err := os.WriteFile(torn, []byte(fileMagic[:2]), 0o644); err != nil {
+		t.Fatalf("WriteFile(torn) error = %v", err)
+	}
+
+	w, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatalf("Open(reopen) error = %v", err)
+	}
+	defer w.Close()
+	headers := bind(t, w, 2)
+	if len(headers) != 1 || headers[0] != testHeader(1) {
+		t.Fatalf("Bind() headers = %+v want %+v", headers, testHeader(1))
+	}
+	// Binding replaced the torn file with one under the new header.
+	data, err := os.ReadFile(torn)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if header, _, ok := nextFileHeader(data); !ok || header != testHeader(2) {
+		t.Fatalf("file header = %+v, %v want %+v", header, ok, testHeader(2))
+	}
+	records, err := w.Replay(ctx, 0)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Replay() = %+v, %v want one record", records, err)
+	}
+}
+
+func testHeader(epoch uint64) writer.WALHeader {
+	return writer.WALHeader{StreamID: "agents", Partition: 3, Epoch: epoch}
+}
+
+// bind binds w to testHeader(epoch) and returns the stored headers.
+func bind(t *testing.T, w *WAL, epoch uint64) []writer.WALHeader {
+	t.Helper()
+	headers, err := w.Bind(context.Background(), testHeader(epoch))
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	return headers
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package filewal
+
+// lockFile is a no-op where advisory file locks are unavailable. Opening one
+// directory from two WALs at once is unsupported on these platforms.
+func lockFile(string) (func(), error) {
+	return func() {}, nil
+}
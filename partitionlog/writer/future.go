@@ -0,0 +1,101 @@
+package writer
+
+import "context"
+
+// AppendFuture resolves when the record of one AppendAsync is committed, or
+// when the writer becomes terminal first. Waiting costs no goroutine: the
+// writer resolves its futures as each publish advances the committed head.
+type AppendFuture struct {
+	result AppendResult
+	done   chan struct{}
+
+	// snapshot and err are written once, before done is closed.
+	snapshot Snapshot
+	err      error
+}
+
+// Result returns the LSN assigned by AppendAsync. It is known before the
+// record is committed.
+func (f *AppendFuture) Result() AppendResult {
+	return f.result
+}
+
+// Done returns a channel that is closed once the future resolves.
+func (f *AppendFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the future resolves and returns the first committed
+// snapshot that covers the record, or the writer's terminal error.
+func (f *AppendFuture) Wait(ctx context.Context) (Snapshot, error) {
+	select {
+	case <-f.done:
+		return f.snapshot, f.err
+	case <-ctx.Done():
+		return Snapshot{}, ctx.Err()
+	}
+}
+
+func (f *AppendFuture) resolve(snapshot Snapshot, err error) {
+	f.snapshot = snapshot
+	f.err = err
+	close(f.done)
+}
+
+// AppendAsync is Append that also returns a future for the record's commit.
+func (w *Writer) AppendAsync(ctx context.Context, record Record) (*AppendFuture, error) {
+	result, err := w.Append(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	f := &AppendFuture{result: result, done: make(chan struct{})}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.committed.Head.NextLSN > result.LSN:
+		f.resolve(w.committed, nil)
+	case w.commitClosed:
+		f.resolve(Snapshot{}, w.commitWaitErrLocked())
+	default:
+		w.futures = append(w.futures, f)
+	}
+	return f, nil
+}
+
+// resolveFuturesLocked resolves the futures whose records are committed.
+// Once the writer is terminal it fails the rest.
+func (w *Writer) resolveFuturesLocked() {
+	pending := w.futures[:0]
+	for _, f := range w.futures {
+		if w.committed.Head.NextLSN > f.result.LSN {
+			f.resolve(w.committed, nil)
+		} else {
+			pending = append(pending, f)
+		}
+	}
+	clear(w.futures[len(pending):])
+	w.futures = pending
+	if !w.commitClosed || len(w.futures) == 0 {
+		return
+	}
+	err := w.commitWaitErrLocked()
+	for _, f := range w.futures {
+		f.resolve(Snapshot{}, err)
+	}
+	w.futures = nil
+}
+
+// commitWaitErrLocked returns why records not yet committed never will be
+// by this writer, or nil while it can still commit them.
+func (w *Writer) commitWaitErrLocked() error {
+	switch {
+	case w.firstErr != nil:
+		return w.firstErr
+	case w.aborted:
+		return ErrAborted
+	case w.closed || w.commitClosed:
+		return ErrClosed
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 )
 
 const (
@@ -14,8 +15,12 @@ const (
 	DefaultMaxSegmentRawBytes  uint64 = 64 << 20
 	DefaultMaxInflightSegments int    = 4
 	DefaultMaxInflightBytes    uint64 = 256 << 20
+	DefaultMaxProducers        int    = 256
 )
 
+// FirstSequence is the Sequence of a producer's first append.
+const FirstSequence uint64 = 1
+
 type UUIDGen func() ([16]byte, error)
 
 // Timer is a stoppable clock notification used by age-based segment rolling.
@@ -98,10 +103,39 @@ type RetentionSession interface {
 }
 
 type RetentionResult struct {
-	Snapshot      Snapshot
-	PolicyVersion uint64
-	RequestedLSN  uint64
-	Applied       bool
+	Snapshot             Snapshot
+	PolicyVersion        uint64
+	RequestedLSN         uint64
+	RequestedTimestampMS int64
+	Applied              bool
+}
+
+// RedactionSession is implemented by sessions that can swap one committed
+// segment for a rewritten copy of the same LSN range.
+type RedactionSession interface {
+	ReplaceSegment(ctx context.Context, current, replacement pmeta.SegmentRef) (Snapshot, error)
+}
+
+// SealSession is implemented by sessions that can seal the partition under
+// the writer's fence.
+type SealSession interface {
+	SealPartition(ctx context.Context) (Snapshot, error)
+}
+
+// ReleaseSession is implemented by sessions that can release the writer's
+// fence once the writer has closed, so an idle-only opener may take it.
+type ReleaseSession interface {
+	ReleaseWriter(ctx context.Context) error
+}
+
+// RewriteRequest replaces the committed segment Segment with a new object
+// holding Records. Records must contain exactly one record per LSN of the
+// segment, in LSN order, with the original timestamps. RedactedUnixMS stamps
+// the replacement reference; zero uses the writer clock.
+type RewriteRequest struct {
+	Segment        pmeta.SegmentRef
+	Records        []Record
+	RedactedUnixMS int64
 }
 
 type RollPolicy struct {
@@ -126,16 +160,50 @@ type Options struct {
 
 	Clock   Clock
 	UUIDGen UUIDGen
+
+	// Signer signs every published segment ref, including redaction
+	// replacements. Nil publishes unsigned refs.
+	Signer signing.Signer
+
+	// MaxProducers bounds the idempotent producers the writer tracks, up to
+	// pmeta.MaxProducers. When a new producer exceeds it, the producer whose
+	// last append has the lowest LSN is forgotten, and its later appends fail
+	// with ErrUnknownProducer until it starts over at FirstSequence. Set it
+	// above the number of producers that append concurrently. 0 selects
+	// DefaultMaxProducers.
+	MaxProducers int
+
+	// WAL, when set, makes appends locally durable before they return, and
+	// New replays the records it holds past the committed head. Nil appends
+	// to memory only until publish.
+	WAL WAL
 }
 
 type Record struct {
 	TimestampMS int64
 	Headers     []segformat.Header
 	Value       []byte
+
+	// ProducerID, when nonzero, makes the append idempotent. Sequence must
+	// then be one more than the producer's last appended Sequence, or
+	// FirstSequence for the first append of an untracked producer.
+	ProducerID [16]byte
+	Sequence   uint64
+}
+
+// BatchResult is the contiguous LSN range assigned to one batch.
+type BatchResult struct {
+	FirstLSN uint64
+	LastLSN  uint64
+	// RawBytes is the uncompressed record size of the batch.
+	RawBytes uint64
 }
 
 type AppendResult struct {
 	LSN uint64
+	// Duplicate reports a retry of the producer's last append. LSN is then
+	// the LSN the original append was assigned, and nothing was appended.
+	Duplicate bool
 }
 
 type MetricName string
@@ -179,6 +247,47 @@ type SinkFactory interface {
 	NewSegmentSink(ctx context.Context, info SegmentInfo) (segwriter.Sink, error)
 }
 
+// WAL is a local write-ahead log for one partition. It holds records that
+// are acknowledged but not yet committed, so a writer that restarts on the
+// same host can commit them. Catalog publication remains the point at which
+// records are durable for every reader and host.
+type WAL interface {
+	// Bind makes header the header of every file the WAL creates from now
+	// on, and returns the headers of the files it already stores, oldest
+	// first. New binds the WAL before any other call and refuses it if a
+	// stored header names another partition or a newer writer.
+	Bind(ctx context.Context, header WALHeader) ([]WALHeader, error)
+	// Append stores records, assigned consecutive LSNs from firstLSN by a
+	// writer with epoch. They supersede every stored record at or above
+	// firstLSN, all at once: after a crash Replay returns either all of them
+	// or the records they superseded. Records are durable once a later Sync
+	// returns nil.
+	Append(ctx context.Context, epoch, firstLSN uint64, records []Record) error
+	// Sync makes every record appended before it durable. The writer calls
+	// it outside its lock, so one Sync covers the appends that raced it, and
+	// may call it concurrently with Append and Trim.
+	Sync(ctx context.Context) error
+	// Replay returns the stored records at or above fromLSN in LSN order.
+	Replay(ctx context.Context, fromLSN uint64) ([]WALRecord, error)
+	// Trim lets the WAL discard records below lsn. They are committed.
+	Trim(ctx context.Context, lsn uint64) error
+}
+
+// WALHeader identifies the partition whose records a WAL file holds and the
+// epoch of the writer that created the file.
+type WALHeader struct {
+	StreamID  string
+	Partition uint32
+	Epoch     uint64
+}
+
+// WALRecord is one record stored in a WAL.
+type WALRecord struct {
+	Epoch  uint64
+	LSN    uint64
+	Record Record
+}
+
 type SinkFactoryFunc func(ctx context.Context, info SegmentInfo) (segwriter.Sink, error)
 
 func (f SinkFactoryFunc) NewSegmentSink(ctx context.Context, info SegmentInfo) (segwriter.Sink, error) {
@@ -1,16 +1,22 @@
 package writer
 
 import (
+	"cmp"
+	"container/list"
 	"context"
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/ankur-anand/unijord/partitionlog/pmeta"
+	"github.com/ankur-anand/unijord/partitionlog/proof"
+	"github.com/ankur-anand/unijord/partitionlog/segblock"
 	"github.com/ankur-anand/unijord/partitionlog/segformat"
 	"github.com/ankur-anand/unijord/partitionlog/segwriter"
+	"github.com/ankur-anand/unijord/partitionlog/signing"
 	"github.com/google/uuid"
 )
 
@@ -39,6 +45,12 @@ type Writer struct {
 	hasTimestamp  bool
 	lastTimestamp int64
 
+	// producers is the last append of each idempotent producer, including
+	// appends not yet committed. Its elements hold pmeta.ProducerState in
+	// producerOrder, which runs from the lowest last LSN to the highest.
+	producers     map[[16]byte]*list.Element
+	producerOrder *list.List
+
 	inflightSegments int
 	inflightBytes    uint64
 
@@ -57,6 +69,18 @@ type Writer struct {
 	publishWake      chan struct{}
 	ageWake          chan struct{}
 
+	// futures are the unresolved AppendAsync futures.
+	futures []*AppendFuture
+
+	// walMu guards the WAL group commit. walWritten is the LSN after the last
+	// record passed to WAL.Append and walSynced the LSN after the last one a
+	// WAL.Sync made durable. walSyncing is closed when the running Sync ends.
+	walMu      sync.Mutex
+	walWritten uint64
+	walSynced  uint64
+	walSyncing chan struct{}
+	walErr     error
+
 	workerCtx    context.Context
 	workerCancel context.CancelFunc
 	workersWG    sync.WaitGroup
@@ -77,6 +101,9 @@ type detachedSegment struct {
 	rawBytes uint64
 	estBytes uint64
 	writer   *segwriter.Writer
+	// producers is the state of the producers that appended in the segment,
+	// as of its last record.
+	producers pmeta.Producers
 }
 
 type readySegment struct {
@@ -98,8 +125,9 @@ func DefaultOptions(factory SinkFactory) Options {
 			MaxInflightSegments: DefaultMaxInflightSegments,
 			MaxInflightBytes:    DefaultMaxInflightBytes,
 		},
-		Clock:   SystemClock{},
-		UUIDGen: randomUUID,
+		Clock:        SystemClock{},
+		UUIDGen:      randomUUID,
+		MaxProducers: DefaultMaxProducers,
 	}
 }
 
@@ -126,6 +154,10 @@ func New(opts Options) (*Writer, error) {
 		finalizeWake:      make(chan struct{}, 1),
 		publishWake:       make(chan struct{}, 1),
 		ageWake:           make(chan struct{}, 1),
+		producers:         make(map[[16]byte]*list.Element),
+		producerOrder:     list.New(),
+		walWritten:        snapshot.Head.NextLSN,
+		walSynced:         snapshot.Head.NextLSN,
 		workerCtx:         workerCtx,
 		workerCancel:      workerCancel,
 	}
@@ -133,6 +165,13 @@ func New(opts Options) (*Writer, error) {
 		w.hasTimestamp = true
 		w.lastTimestamp = last.MaxTimestampMS
 	}
+	states := snapshot.Head.ProducerState().All()
+	slices.SortFunc(states, func(a, b pmeta.ProducerState) int {
+		return cmp.Compare(a.LSN, b.LSN)
+	})
+	for _, state := range states {
+		w.trackProducerLocked(state)
+	}
 	w.workersWG.Add(2)
 	go w.finalizeLoop()
 	go w.publishLoop()
@@ -140,15 +179,168 @@ func New(opts Options) (*Writer, error) {
 		w.workersWG.Add(1)
 		go w.ageLoop()
 	}
+	if normalized.WAL != nil {
+		if err := w.replayWAL(context.Background()); err != nil {
+			_ = w.Abort(context.Background())
+			return nil, err
+		}
+	}
 	return w, nil
 }
 
+// replayWAL binds the WAL to this writer and appends its records past the
+// committed head, which a previous writer acknowledged but did not commit.
+// Records of an epoch older than the last committed segment were superseded
+// by a later writer and are not replayed.
+func (w *Writer) replayWAL(ctx context.Context) error {
+	head := w.committed.Head
+	headers, err := w.opts.WAL.Bind(ctx, WALHeader{StreamID: head.StreamID, Partition: head.Partition, Epoch: w.identity.Epoch})
+	if err != nil {
+		return fmt.Errorf("%w: bind: %w", ErrWALFailed, err)
+	}
+	for _, header := range headers {
+		if header.StreamID != head.StreamID || header.Partition != head.Partition {
+			return fmt.Errorf("%w: wal stream=%q partition=%d, writer stream=%q partition=%d", ErrWALMismatch, header.StreamID, header.Partition, head.StreamID, head.Partition)
+		}
+		if header.Epoch > w.identity.Epoch {
+			return fmt.Errorf("%w: wal epoch=%d, writer epoch=%d", ErrWALMismatch, header.Epoch, w.identity.Epoch)
+		}
+	}
+	stored, err := w.opts.WAL.Replay(ctx, head.NextLSN)
+	if err != nil {
+		return fmt.Errorf("%w: replay: %w", ErrWALFailed, err)
+	}
+	var lastEpoch uint64
+	if last, ok := head.Last(); ok {
+		lastEpoch = last.WriterEpoch
+	}
+	records := make([]Record, 0, len(stored))
+	for i, entry := range stored {
+		if entry.Epoch < lastEpoch {
+			break
+		}
+		if want := head.NextLSN + uint64(i); entry.LSN != want {
+			return fmt.Errorf("%w: replay lsn=%d want=%d", ErrWALFailed, entry.LSN, want)
+		}
+		records = append(records, entry.Record)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	// Restamp the records with this writer's epoch, so they are replayed
+	// again if this writer commits other segments and then fails.
+	if err := w.opts.WAL.Append(ctx, w.identity.Epoch, head.NextLSN, records); err != nil {
+		return fmt.Errorf("%w: %w", ErrWALFailed, err)
+	}
+	if err := w.opts.WAL.Sync(ctx); err != nil {
+		return fmt.Errorf("%w: sync: %w", ErrWALFailed, err)
+	}
+	// Append one at a time so the records are cut into segments as usual.
+	for i := range records {
+		if _, err := w.appendBatch(ctx, records[i:i+1], 0, false, true); err != nil {
+			return err
+		}
+	}
+	w.walMu.Lock()
+	w.walWritten = head.NextLSN + uint64(len(records))
+	w.walSynced = w.walWritten
+	w.walMu.Unlock()
+	return nil
+}
+
+// syncWAL waits until the records below next are durable in the WAL. One
+// caller at a time runs WAL.Sync for every record written so far, and the
+// callers that arrive meanwhile wait for it and then sync what it did not
+// cover. A failed Sync fails the writer.
+func (w *Writer) syncWAL(ctx context.Context, next uint64) error {
+	for {
+		w.walMu.Lock()
+		if w.walErr != nil {
+			err := w.walErr
+			w.walMu.Unlock()
+			return err
+		}
+		if w.walSynced >= next {
+			w.walMu.Unlock()
+			return nil
+		}
+		if done := w.walSyncing; done != nil {
+			w.walMu.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan struct{})
+		w.walSyncing = done
+		target := w.walWritten
+		w.walMu.Unlock()
+
+		// The waiters depend on this Sync, so the caller's cancellation
+		// must not cut it short.
+		err := w.opts.WAL.Sync(context.WithoutCancel(ctx))
+
+		w.walMu.Lock()
+		w.walSyncing = nil
+		if err != nil {
+			w.walErr = fmt.Errorf("%w: sync: %w", ErrWALFailed, err)
+			err = w.walErr
+		} else if target > w.walSynced {
+			w.walSynced = target
+		}
+		close(done)
+		w.walMu.Unlock()
+		if err != nil {
+			w.mu.Lock()
+			active, detached := w.failLocked(err)
+			w.mu.Unlock()
+			w.abortSegmentsBestEffort(active, detached)
+			return err
+		}
+	}
+}
+
+// noteWALWrittenLocked records that the records below next were passed to
+// WAL.Append.
+func (w *Writer) noteWALWrittenLocked(next uint64) {
+	w.walMu.Lock()
+	w.walWritten = next
+	w.walMu.Unlock()
+}
+
 func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error) {
+	if err := checkReserved(record, 0); err != nil {
+		return AppendResult{}, err
+	}
 	w.mu.Lock()
 	if err := w.waitActiveTransitionLocked(ctx); err != nil {
 		w.mu.Unlock()
 		return AppendResult{}, err
 	}
+	if record.ProducerID != ([16]byte{}) {
+		state, ok := w.producerLocked(record.ProducerID)
+		if !ok && record.Sequence != FirstSequence {
+			w.mu.Unlock()
+			return AppendResult{}, fmt.Errorf("%w: producer=%x sequence=%d", ErrUnknownProducer, record.ProducerID, record.Sequence)
+		}
+		if ok {
+			switch {
+			case record.Sequence == state.Sequence:
+				w.mu.Unlock()
+				// The original append may still be waiting for its Sync.
+				if w.opts.WAL != nil {
+					if err := w.syncWAL(ctx, state.LSN+1); err != nil {
+						return AppendResult{}, err
+					}
+				}
+				return AppendResult{LSN: state.LSN, Duplicate: true}, nil
+			case record.Sequence < state.Sequence:
+				w.mu.Unlock()
+				return AppendResult{}, fmt.Errorf("%w: producer=%x sequence=%d last=%d lsn=%d", ErrDuplicateSequence, record.ProducerID, record.Sequence, state.Sequence, state.LSN)
+			case record.Sequence-state.Sequence > 1:
+				w.mu.Unlock()
+				return AppendResult{}, fmt.Errorf("%w: producer=%x sequence=%d last=%d", ErrSequenceGap, record.ProducerID, record.Sequence, state.Sequence)
+			}
+		}
+	}
 	if w.optimisticNextLSN == math.MaxUint64 {
 		err := fmt.Errorf("%w: next_lsn=%d", ErrLSNExhausted, w.optimisticNextLSN)
 		active, detached := w.failLocked(err)
@@ -172,7 +364,7 @@ func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error
 		return AppendResult{}, err
 	}
 	recordSize := uint64(recordSizeInt)
-	if w.shouldCutBeforeLocked(recordSize) {
+	if w.shouldCutBeforeLocked(1, recordSize) {
 		if err := w.cutLocked(ctx); err != nil {
 			w.mu.Unlock()
 			return AppendResult{}, err
@@ -198,6 +390,16 @@ func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error
 		w.abortSegmentsBestEffort(active, detached)
 		return AppendResult{}, err
 	}
+	if w.opts.WAL != nil {
+		if err := w.opts.WAL.Append(ctx, w.identity.Epoch, lsn, []Record{record}); err != nil {
+			err = fmt.Errorf("%w: %w", ErrWALFailed, err)
+			active, detached := w.failLocked(err)
+			w.mu.Unlock()
+			w.abortSegmentsBestEffort(active, detached)
+			return AppendResult{}, err
+		}
+		w.noteWALWrittenLocked(lsn + 1)
+	}
 
 	firstRecordInSegment := w.active.records == 0
 	w.optimisticNextLSN++
@@ -205,6 +407,13 @@ func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error
 	w.lastTimestamp = record.TimestampMS
 	w.active.records++
 	w.active.rawBytes += recordSize
+	if record.ProducerID != ([16]byte{}) {
+		w.trackProducerLocked(pmeta.ProducerState{
+			ProducerID: record.ProducerID,
+			Sequence:   record.Sequence,
+			LSN:        lsn,
+		})
+	}
 	if firstRecordInSegment {
 		w.active.firstRecordAt = w.opts.Clock.Now()
 		w.signalAgeLocked()
@@ -215,9 +424,246 @@ func (w *Writer) Append(ctx context.Context, record Record) (AppendResult, error
 	}
 
 	w.mu.Unlock()
+	if w.opts.WAL != nil {
+		if err := w.syncWAL(ctx, lsn+1); err != nil {
+			return AppendResult{}, err
+		}
+	}
 	return AppendResult{LSN: lsn}, nil
 }
 
+// AppendIf appends record only if it is assigned expectedLSN. Otherwise it
+// fails with LSNConflictError and appends nothing.
+func (w *Writer) AppendIf(ctx context.Context, expectedLSN uint64, record Record) (AppendResult, error) {
+	result, err := w.AppendBatchAt(ctx, expectedLSN, []Record{record})
+	if err != nil {
+		return AppendResult{}, err
+	}
+	return AppendResult{LSN: result.FirstLSN}, nil
+}
+
+// AppendBatch appends records at consecutive LSNs. The batch is appended to
+// one segment, cutting the active segment first if the batch does not fit
+// beside it, so the batch is committed as a whole. A batch larger than the
+// roll policy gets a segment of its own.
+//
+// Producer sequences must each be exactly the next one, so a batch retry
+// fails with ErrDuplicateSequence rather than being acknowledged.
+func (w *Writer) AppendBatch(ctx context.Context, records []Record) (BatchResult, error) {
+	return w.appendBatch(ctx, records, 0, false, false)
+}
+
+// AppendBatchAt is AppendBatch only if the first record is assigned
+// expectedNextLSN. Otherwise it fails with LSNConflictError and appends
+// nothing.
+func (w *Writer) AppendBatchAt(ctx context.Context, expectedNextLSN uint64, records []Record) (BatchResult, error) {
+	return w.appendBatch(ctx, records, expectedNextLSN, true, false)
+}
+
+// checkReserved rejects a caller record that redaction's markers could be
+// confused with. index is the record's place in its batch.
+func checkReserved(record Record, index int) error {
+	if segformat.ReservedRecord(record.Headers, record.Value) {
+		return fmt.Errorf("%w: record=%d", ErrReservedRecord, index)
+	}
+	return nil
+}
+
+// appendBatch appends records, if conditional only at expectedNextLSN. A
+// replay appends records that are already in the WAL and whose producer
+// sequences were checked when they were first appended.
+func (w *Writer) appendBatch(ctx context.Context, records []Record, expectedNextLSN uint64, conditional, replay bool) (BatchResult, error) {
+	if len(records) == 0 {
+		return BatchResult{}, ErrEmptyBatch
+	}
+	if !replay {
+		for i, record := range records {
+			if err := checkReserved(record, i); err != nil {
+				return BatchResult{}, err
+			}
+		}
+	}
+	w.mu.Lock()
+	if err := w.waitActiveTransitionLocked(ctx); err != nil {
+		w.mu.Unlock()
+		return BatchResult{}, err
+	}
+	if conditional && w.optimisticNextLSN != expectedNextLSN {
+		err := LSNConflictError{Expected: expectedNextLSN, Next: w.optimisticNextLSN}
+		w.mu.Unlock()
+		return BatchResult{}, err
+	}
+	if !replay {
+		if err := w.checkBatchProducersLocked(records); err != nil {
+			w.mu.Unlock()
+			return BatchResult{}, err
+		}
+	}
+	if uint64(len(records)) > math.MaxUint64-w.optimisticNextLSN {
+		err := fmt.Errorf("%w: next_lsn=%d batch=%d", ErrLSNExhausted, w.optimisticNextLSN, len(records))
+		active, detached := w.failLocked(err)
+		w.mu.Unlock()
+		w.abortSegmentsBestEffort(active, detached)
+		return BatchResult{}, err
+	}
+	batchSize, err := w.batchSizeLocked(records)
+	if err != nil {
+		active, detached := w.failLocked(err)
+		w.mu.Unlock()
+		w.abortSegmentsBestEffort(active, detached)
+		return BatchResult{}, err
+	}
+	if w.shouldCutBeforeLocked(len(records), batchSize) {
+		if err := w.cutLocked(ctx); err != nil {
+			w.mu.Unlock()
+			return BatchResult{}, err
+		}
+	}
+	if w.active == nil {
+		if err := w.startSegmentLocked(ctx); err != nil {
+			w.mu.Unlock()
+			return BatchResult{}, err
+		}
+	}
+
+	// Nothing can be cut between the records below, and a failed record
+	// fails the writer before the segment holding the batch is published.
+	first := w.optimisticNextLSN
+	for _, record := range records {
+		lsn := w.optimisticNextLSN
+		if err := w.active.writer.Append(ctx, segwriter.Record{
+			LSN:         lsn,
+			TimestampMS: record.TimestampMS,
+			Headers:     record.Headers,
+			Value:       record.Value,
+		}); err != nil {
+			err = wrapSegmentWrite(err)
+			active, detached := w.failLocked(err)
+			w.mu.Unlock()
+			w.abortSegmentsBestEffort(active, detached)
+			return BatchResult{}, err
+		}
+		if w.active.records == 0 {
+			w.active.firstRecordAt = w.opts.Clock.Now()
+			w.signalAgeLocked()
+		}
+		w.optimisticNextLSN++
+		w.hasTimestamp = true
+		w.lastTimestamp = record.TimestampMS
+		w.active.records++
+		if record.ProducerID != ([16]byte{}) {
+			w.trackProducerLocked(pmeta.ProducerState{
+				ProducerID: record.ProducerID,
+				Sequence:   record.Sequence,
+				LSN:        lsn,
+			})
+		}
+	}
+	w.active.rawBytes += batchSize
+	if w.opts.WAL != nil && !replay {
+		if err := w.opts.WAL.Append(ctx, w.identity.Epoch, first, records); err != nil {
+			err = fmt.Errorf("%w: %w", ErrWALFailed, err)
+			active, detached := w.failLocked(err)
+			w.mu.Unlock()
+			w.abortSegmentsBestEffort(active, detached)
+			return BatchResult{}, err
+		}
+		w.noteWALWrittenLocked(w.optimisticNextLSN)
+	}
+
+	if w.shouldCutAfterLocked() {
+		w.tryCutAfterAppendLocked(ctx)
+	}
+
+	result := BatchResult{FirstLSN: first, LastLSN: w.optimisticNextLSN - 1, RawBytes: batchSize}
+	w.mu.Unlock()
+	if w.opts.WAL != nil && !replay {
+		if err := w.syncWAL(ctx, result.LastLSN+1); err != nil {
+			return BatchResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// checkBatchProducersLocked rejects records whose producer Sequence is not
+// the next one, counting the earlier records of the batch.
+func (w *Writer) checkBatchProducersLocked(records []Record) error {
+	var next map[[16]byte]uint64
+	for _, record := range records {
+		if record.ProducerID == ([16]byte{}) {
+			continue
+		}
+		if next == nil {
+			next = make(map[[16]byte]uint64)
+		}
+		want, ok := next[record.ProducerID]
+		if !ok {
+			state, tracked := w.producerLocked(record.ProducerID)
+			want, ok = state.Sequence+1, tracked
+		}
+		switch {
+		case !ok && record.Sequence != FirstSequence:
+			return fmt.Errorf("%w: producer=%x sequence=%d", ErrUnknownProducer, record.ProducerID, record.Sequence)
+		case !ok:
+		case record.Sequence < want:
+			return fmt.Errorf("%w: producer=%x sequence=%d want=%d", ErrDuplicateSequence, record.ProducerID, record.Sequence, want)
+		case record.Sequence > want:
+			return fmt.Errorf("%w: producer=%x sequence=%d want=%d", ErrSequenceGap, record.ProducerID, record.Sequence, want)
+		}
+		next[record.ProducerID] = record.Sequence + 1
+	}
+	return nil
+}
+
+// batchSizeLocked validates the timestamps and sizes of records and returns
+// their total raw size.
+func (w *Writer) batchSizeLocked(records []Record) (uint64, error) {
+	hasTimestamp, lastTimestamp := w.hasTimestamp, w.lastTimestamp
+	var total uint64
+	for _, record := range records {
+		if hasTimestamp && record.TimestampMS < lastTimestamp {
+			return 0, fmt.Errorf("%w: got=%d previous=%d", ErrTimestampOrder, record.TimestampMS, lastTimestamp)
+		}
+		hasTimestamp, lastTimestamp = true, record.TimestampMS
+		size, err := segformat.RecordSize(record.Headers, record.Value)
+		if err != nil {
+			return 0, err
+		}
+		total += uint64(size)
+	}
+	return total, nil
+}
+
+// WaitCommitted blocks until the record at lsn is committed and returns the
+// first committed snapshot that covers it. It fails with the writer's error
+// if the writer becomes terminal first, and with ErrNotAppended if lsn has
+// not been assigned. Records are committed once a roll policy, Cut, Flush,
+// or Close publishes their segment.
+func (w *Writer) WaitCommitted(ctx context.Context, lsn uint64) (Snapshot, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		if w.committed.Head.NextLSN > lsn {
+			return w.committed, nil
+		}
+		if err := w.commitWaitErrLocked(); err != nil {
+			return Snapshot{}, err
+		}
+		if lsn >= w.optimisticNextLSN {
+			return Snapshot{}, fmt.Errorf("%w: lsn=%d next_lsn=%d", ErrNotAppended, lsn, w.optimisticNextLSN)
+		}
+		changed := w.committedChanged
+		w.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			w.mu.Lock()
+			return Snapshot{}, ctx.Err()
+		}
+		w.mu.Lock()
+	}
+}
+
 func (w *Writer) Cut(ctx context.Context) error {
 	w.mu.Lock()
 	if err := w.foregroundErrLocked(); err != nil {
@@ -263,7 +709,27 @@ func (w *Writer) Flush(ctx context.Context) (Snapshot, error) {
 	return snapshot, nil
 }
 
+// Close publishes every accepted record and stops the writer. When the session
+// implements ReleaseSession, Close then releases the fence; a failed release
+// returns ErrReleaseFailed with every record already published.
 func (w *Writer) Close(ctx context.Context) (Snapshot, error) {
+	snapshot, err := w.close(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	session, ok := w.opts.Session.(ReleaseSession)
+	if !ok {
+		return snapshot, nil
+	}
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+	if err := session.ReleaseWriter(ctx); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: %w", ErrReleaseFailed, err)
+	}
+	return snapshot, nil
+}
+
+func (w *Writer) close(ctx context.Context) (Snapshot, error) {
 	var emptyActive *activeSegment
 
 	w.mu.Lock()
@@ -405,6 +871,198 @@ func (w *Writer) ApplyPendingRetention(ctx context.Context) (RetentionResult, er
 	return result, nil
 }
 
+// Seal closes the writer like Close, which publishes every accepted record,
+// and then seals the partition through this writer's fenced catalog session.
+// A seal that fails leaves the writer closed and the partition unsealed.
+func (w *Writer) Seal(ctx context.Context) (Snapshot, error) {
+	session, ok := w.opts.Session.(SealSession)
+	if !ok {
+		return Snapshot{}, ErrSealUnsupported
+	}
+	// Seal keeps the fence until the seal commits; releasing it first would let
+	// an idle-only opener fence the seal out.
+	current, err := w.close(ctx)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	w.sessionMu.Lock()
+	defer w.sessionMu.Unlock()
+	next, err := session.SealPartition(ctx)
+	if err != nil {
+		return Snapshot{}, normalizeSealErr(err)
+	}
+	if !next.Head.Sealed || next.Head.NextLSN != current.Head.NextLSN || next.Identity != current.Identity {
+		return Snapshot{}, fmt.Errorf("%w: sealed head next_lsn=%d sealed=%t want next_lsn=%d", ErrSealFailed, next.Head.NextLSN, next.Head.Sealed, current.Head.NextLSN)
+	}
+
+	w.mu.Lock()
+	w.committed = next
+	w.signalStateLocked()
+	w.signalCommittedLocked()
+	w.mu.Unlock()
+	return next, nil
+}
+
+// RewriteSegment writes req.Records as a new segment object and swaps it for
+// the committed req.Segment through this writer's fenced catalog session. The
+// replacement keeps the segment's LSN range, timestamps, writer epoch, codec,
+// and hash algorithm, so the partition head other than the segment reference
+// is unchanged. The old object is left for lifecycle reclamation.
+func (w *Writer) RewriteSegment(ctx context.Context, req RewriteRequest) (Snapshot, error) {
+	session, ok := w.opts.Session.(RedactionSession)
+	if !ok {
+		return Snapshot{}, ErrRedactionUnsupported
+	}
+	segment := req.Segment
+	if err := segment.Validate(); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: %w", ErrRedactionFailed, err)
+	}
+	if segment.StreamID != w.streamID || segment.Partition != w.partition {
+		return Snapshot{}, fmt.Errorf("%w: segment is not in partition=%d", ErrRedactionFailed, w.partition)
+	}
+	if want := segment.LastLSN - segment.BaseLSN + 1; uint64(len(req.Records)) != want {
+		return Snapshot{}, fmt.Errorf("%w: records=%d want=%d", ErrRedactionFailed, len(req.Records), want)
+	}
+	if req.RedactedUnixMS < 0 {
+		return Snapshot{}, fmt.Errorf("%w: negative redacted_unix_ms=%d", ErrRedactionFailed, req.RedactedUnixMS)
+	}
+
+	w.mu.Lock()
+	if err := w.foregroundErrLocked(); err != nil {
+		w.mu.Unlock()
+		return Snapshot{}, err
+	}
+	head := w.committed.Head
+	w.mu.Unlock()
+	if segment.BaseLSN < head.OldestLSN || segment.LastLSN >= head.NextLSN {
+		return Snapshot{}, fmt.Errorf("%w: segment base_lsn=%d last_lsn=%d outside committed range [%d,%d)", ErrRedactionFailed, segment.BaseLSN, segment.LastLSN, head.OldestLSN, head.NextLSN)
+	}
+
+	replacement, err := w.writeReplacementSegment(ctx, segment, req.Records)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	replacement.RedactedUnixMS = req.RedactedUnixMS
+	if replacement.RedactedUnixMS == 0 {
+		replacement.RedactedUnixMS = w.opts.Clock.Now().UnixMilli()
+	}
+	if w.opts.Signer != nil {
+		if replacement, err = signing.SignSegment(ctx, w.opts.Signer, replacement); err != nil {
+			return Snapshot{}, fmt.Errorf("%w: %w", ErrRedactionFailed, err)
+		}
+	}
+
+	w.sessionMu.Lock()
+	w.mu.Lock()
+	if err := w.foregroundErrLocked(); err != nil {
+		w.mu.Unlock()
+		w.sessionMu.Unlock()
+		return Snapshot{}, err
+	}
+	current := w.committed
+	w.mu.Unlock()
+
+	next, err := session.ReplaceSegment(ctx, segment, replacement)
+	if err != nil {
+		w.sessionMu.Unlock()
+		err = normalizeRedactionErr(err)
+		if errors.Is(err, ErrStaleWriter) {
+			w.noteAsyncErr(err)
+		}
+		return Snapshot{}, err
+	}
+	if err := validateRewriteSnapshot(current, next, segment, replacement); err != nil {
+		w.sessionMu.Unlock()
+		w.noteAsyncErr(err)
+		return Snapshot{}, err
+	}
+
+	w.mu.Lock()
+	w.committed = next
+	w.signalStateLocked()
+	if next != current {
+		w.signalCommittedLocked()
+	}
+	w.mu.Unlock()
+	w.sessionMu.Unlock()
+	return next, nil
+}
+
+func (w *Writer) writeReplacementSegment(ctx context.Context, segment pmeta.SegmentRef, records []Record) (pmeta.SegmentRef, error) {
+	segmentUUID, err := w.opts.UUIDGen()
+	if err != nil {
+		return pmeta.SegmentRef{}, wrapSegmentStart(err)
+	}
+	createdUnixMS := w.opts.Clock.Now().UnixMilli()
+	sink, err := w.opts.SinkFactory.NewSegmentSink(ctx, SegmentInfo{
+		StreamID:      w.streamID,
+		Partition:     w.partition,
+		BaseLSN:       segment.BaseLSN,
+		WriterEpoch:   segment.WriterEpoch,
+		WriterTag:     segment.WriterTag,
+		SegmentUUID:   segmentUUID,
+		CreatedUnixMS: createdUnixMS,
+	})
+	if err != nil {
+		return pmeta.SegmentRef{}, wrapSegmentStart(err)
+	}
+	segmentOptions := w.opts.SegmentOptions
+	segmentOptions.Partition = w.partition
+	segmentOptions.SegmentUUID = segmentUUID
+	segmentOptions.WriterTag = segment.WriterTag
+	segmentOptions.CreatedUnixMS = createdUnixMS
+	if segmentOptions.Codec != segment.Codec || dictionaryID(segmentOptions.Dictionary) != segment.DictionaryID {
+		segmentOptions.Codec = segment.Codec
+		segmentOptions.ZstdLevel = 0
+		segmentOptions.Dictionary = nil
+		if segment.Codec.UsesDictionary() {
+			// The writer holds only its own dictionary, so a replacement for a
+			// segment compressed with another one falls back to plain zstd.
+			segmentOptions.Codec = segformat.CodecZstd
+		}
+	}
+	segmentOptions.HashAlgo = segment.HashAlgo
+
+	sw, err := segwriter.New(segmentOptions, sink)
+	if err != nil {
+		return pmeta.SegmentRef{}, wrapSegmentStart(err)
+	}
+	for i, record := range records {
+		if err := sw.Append(ctx, segwriter.Record{
+			LSN:         segment.BaseLSN + uint64(i),
+			TimestampMS: record.TimestampMS,
+			Headers:     record.Headers,
+			Value:       record.Value,
+		}); err != nil {
+			abortWriterBestEffort(sw)
+			return pmeta.SegmentRef{}, wrapSegmentWrite(err)
+		}
+	}
+	result, err := sw.Close(ctx)
+	if err != nil {
+		return pmeta.SegmentRef{}, wrapSegmentWrite(err)
+	}
+	replacement := segmentRefFromResult(result, w.streamID, WriterIdentity{
+		Epoch: segment.WriterEpoch,
+		Tag:   segment.WriterTag,
+	})
+	// Tombstones carry the digest of the record they replace, so the records
+	// root is unchanged, and the replacement keeps the hashes the chain was
+	// built over. Segments written before chaining stay unchained.
+	switch {
+	case segment.RecordsRoot.IsZero():
+		replacement.RecordsRoot = pmeta.Hash{}
+	case replacement.RecordsRoot != segment.RecordsRoot:
+		return pmeta.SegmentRef{}, fmt.Errorf("%w: rewritten records change records_root", ErrRedactionFailed)
+	}
+	replacement.PrevChainHash = segment.PrevChainHash
+	replacement.ChainHash = segment.ChainHash
+	replacement.ChainedSegmentHash, replacement.ChainedTrailerHash = segment.ChainedHashes()
+	replacement.Producers = segment.Producers
+	return replacement, nil
+}
+
 func (w *Writer) finalizeLoop() {
 	defer w.workersWG.Done()
 
@@ -450,6 +1108,7 @@ func (w *Writer) finalizeLoop() {
 			return
 		}
 		segment := segmentRefFromResult(result, w.streamID, w.identity)
+		segment.Producers = item.producers
 		if err := segment.Validate(); err != nil {
 			w.observe(MetricEvent{
 				Name:      MetricSegmentFinalize,
@@ -528,6 +1187,18 @@ func (w *Writer) publishLoop() {
 		w.mu.Lock()
 		current := w.committed
 		w.mu.Unlock()
+		// Chain to the committed tail. Publication is serialized under
+		// sessionMu, so current is the head this segment lands on.
+		item.segment = proof.Chain(current.Head.LastSegment.ChainHash, item.segment)
+		if w.opts.Signer != nil {
+			signed, err := signing.SignSegment(w.workerCtx, w.opts.Signer, item.segment)
+			if err != nil {
+				w.sessionMu.Unlock()
+				w.noteAsyncErr(err)
+				return
+			}
+			item.segment = signed
+		}
 		start := time.Now()
 		next, err := w.opts.Session.PublishSegment(w.workerCtx, PublishRequest{
 			ExpectedNextLSN: item.expectedNextLSN,
@@ -594,6 +1265,11 @@ func (w *Writer) publishLoop() {
 		stop := w.workerCtx.Err() != nil && w.inflightSegments == 0
 		w.mu.Unlock()
 		w.sessionMu.Unlock()
+		if w.opts.WAL != nil {
+			// Trimming only reclaims space: replay starts at the committed
+			// head. A failed trim is retried after the next publish.
+			_ = w.opts.WAL.Trim(w.workerCtx, next.Head.NextLSN)
+		}
 		if stop {
 			return
 		}
@@ -666,12 +1342,13 @@ func (w *Writer) cutLocked(ctx context.Context) error {
 		return err
 	}
 	w.detached = append(w.detached, detachedSegment{
-		seq:      w.nextCutSeq,
-		baseLSN:  old.baseLSN,
-		records:  old.records,
-		rawBytes: old.rawBytes,
-		estBytes: estBytes,
-		writer:   old.writer,
+		seq:       w.nextCutSeq,
+		baseLSN:   old.baseLSN,
+		records:   old.records,
+		rawBytes:  old.rawBytes,
+		estBytes:  estBytes,
+		writer:    old.writer,
+		producers: w.producerDeltaLocked(old.baseLSN),
 	})
 	w.nextCutSeq++
 	w.signalFinalizeLocked()
@@ -703,18 +1380,64 @@ func (w *Writer) detachActiveLocked(ctx context.Context) error {
 	}
 	w.active = nil
 	w.detached = append(w.detached, detachedSegment{
-		seq:      w.nextCutSeq,
-		baseLSN:  old.baseLSN,
-		records:  old.records,
-		rawBytes: old.rawBytes,
-		estBytes: estBytes,
-		writer:   old.writer,
+		seq:       w.nextCutSeq,
+		baseLSN:   old.baseLSN,
+		records:   old.records,
+		rawBytes:  old.rawBytes,
+		estBytes:  estBytes,
+		writer:    old.writer,
+		producers: w.producerDeltaLocked(old.baseLSN),
 	})
 	w.nextCutSeq++
 	w.signalFinalizeLocked()
 	return nil
 }
 
+// producerLocked returns the last append of a tracked producer.
+func (w *Writer) producerLocked(id [16]byte) (pmeta.ProducerState, bool) {
+	element, ok := w.producers[id]
+	if !ok {
+		return pmeta.ProducerState{}, false
+	}
+	return element.Value.(pmeta.ProducerState), true
+}
+
+// trackProducerLocked records state as its producer's last append. Appends
+// arrive in LSN order, so state moves to the back of producerOrder. Past
+// Options.MaxProducers, the producer at the front, the one with the lowest
+// last LSN, is forgotten.
+func (w *Writer) trackProducerLocked(state pmeta.ProducerState) {
+	if element, ok := w.producers[state.ProducerID]; ok {
+		element.Value = state
+		w.producerOrder.MoveToBack(element)
+		return
+	}
+	w.producers[state.ProducerID] = w.producerOrder.PushBack(state)
+	if len(w.producers) <= w.opts.MaxProducers {
+		return
+	}
+	oldest := w.producerOrder.Front()
+	w.producerOrder.Remove(oldest)
+	delete(w.producers, oldest.Value.(pmeta.ProducerState).ProducerID)
+}
+
+// producerDeltaLocked returns the producers whose last append is at or after
+// baseLSN, the ones a segment starting there changes.
+func (w *Writer) producerDeltaLocked(baseLSN uint64) pmeta.Producers {
+	var states []pmeta.ProducerState
+	for element := w.producerOrder.Back(); element != nil; element = element.Prev() {
+		state := element.Value.(pmeta.ProducerState)
+		if state.LSN < baseLSN {
+			break
+		}
+		states = append(states, state)
+	}
+	// trackProducerLocked keeps IDs nonzero and distinct, and normalizeOptions
+	// bounds the count by pmeta.MaxProducers.
+	producers, _ := pmeta.NewProducers(states)
+	return producers
+}
+
 func (w *Writer) startSegmentLocked(ctx context.Context) error {
 	segmentUUID, err := w.opts.UUIDGen()
 	if err != nil {
@@ -751,11 +1474,14 @@ func (w *Writer) startSegmentLocked(ctx context.Context) error {
 	return nil
 }
 
-func (w *Writer) shouldCutBeforeLocked(nextRecordSize uint64) bool {
+// shouldCutBeforeLocked reports whether the active segment must be cut
+// before nextRecords records of nextRecordSize raw bytes in total are
+// appended to it.
+func (w *Writer) shouldCutBeforeLocked(nextRecords int, nextRecordSize uint64) bool {
 	if w.active == nil || w.active.records == 0 {
 		return false
 	}
-	if w.opts.Roll.MaxSegmentRecords > 0 && w.active.records >= w.opts.Roll.MaxSegmentRecords {
+	if w.opts.Roll.MaxSegmentRecords > 0 && uint64(w.active.records)+uint64(nextRecords) > uint64(w.opts.Roll.MaxSegmentRecords) {
 		return true
 	}
 	if w.opts.Roll.MaxSegmentAge > 0 && !w.active.firstRecordAt.IsZero() && w.opts.Clock.Now().Sub(w.active.firstRecordAt) >= w.opts.Roll.MaxSegmentAge {
@@ -958,6 +1684,7 @@ func (w *Writer) signalCommittedLocked() {
 	}
 	close(w.committedChanged)
 	w.committedChanged = make(chan struct{})
+	w.resolveFuturesLocked()
 }
 
 func (w *Writer) signalCommittedTerminalLocked() {
@@ -966,6 +1693,7 @@ func (w *Writer) signalCommittedTerminalLocked() {
 	}
 	close(w.committedChanged)
 	w.commitClosed = true
+	w.resolveFuturesLocked()
 }
 
 func (w *Writer) signalFinalizeLocked() {
@@ -1019,6 +1747,10 @@ func segmentRefFromResult(result segwriter.Result, streamID string, identity Wri
 		HashAlgo:         m.HashAlgo,
 		SegmentHash:      m.SegmentHash,
 		TrailerHash:      m.TrailerHash,
+		DictionaryID:     m.DictionaryID,
+		KeyID:            m.KeyID,
+		RecordsRoot:      m.RecordsRoot,
+		SegmentDigest:    m.SegmentDigest,
 	}
 }
 
@@ -1143,6 +1875,33 @@ func validateRetentionSnapshot(current Snapshot, result RetentionResult) error {
 	return nil
 }
 
+func validateRewriteSnapshot(current Snapshot, next Snapshot, segment, replacement pmeta.SegmentRef) error {
+	if err := validateHead(next.Head); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPublishResult, err)
+	}
+	wantLast := current.Head.LastSegment
+	if current.Head.HasLastSegment && wantLast == segment {
+		wantLast = replacement
+	}
+	switch {
+	case next.Identity != current.Identity:
+		return fmt.Errorf("%w: rewrite changed writer identity", ErrInvalidPublishResult)
+	case next.Head.StreamID != current.Head.StreamID:
+		return fmt.Errorf("%w: rewrite changed stream_id", ErrInvalidPublishResult)
+	case next.Head.Partition != current.Head.Partition:
+		return fmt.Errorf("%w: rewrite changed partition", ErrInvalidPublishResult)
+	case next.Head.NextLSN != current.Head.NextLSN:
+		return fmt.Errorf("%w: rewrite changed next_lsn from %d to %d", ErrInvalidPublishResult, current.Head.NextLSN, next.Head.NextLSN)
+	case next.Head.OldestLSN != current.Head.OldestLSN:
+		return fmt.Errorf("%w: rewrite changed oldest_lsn", ErrInvalidPublishResult)
+	case next.Head.SegmentCount != current.Head.SegmentCount:
+		return fmt.Errorf("%w: rewrite changed segment_count", ErrInvalidPublishResult)
+	case next.Head.HasLastSegment != current.Head.HasLastSegment || next.Head.LastSegment != wantLast:
+		return fmt.Errorf("%w: rewrite changed last segment", ErrInvalidPublishResult)
+	}
+	return nil
+}
+
 func wrapSegmentWrite(err error) error {
 	if err == nil {
 		return nil
@@ -1192,6 +1951,34 @@ func normalizeRetentionErr(err error) error {
 	}
 }
 
+func normalizeSealErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(err, ErrStaleWriter),
+		errors.Is(err, ErrSealUnsupported),
+		errors.Is(err, ErrSealFailed):
+		return err
+	default:
+		return fmt.Errorf("%w: %w", ErrSealFailed, err)
+	}
+}
+
+func normalizeRedactionErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(err, ErrStaleWriter),
+		errors.Is(err, ErrRedactionUnsupported),
+		errors.Is(err, ErrRedactionFailed):
+		return err
+	default:
+		return fmt.Errorf("%w: %w", ErrRedactionFailed, err)
+	}
+}
+
 func normalizeOptions(opts Options, snapshot Snapshot) (Options, error) {
 	if err := validateSnapshot(snapshot); err != nil {
 		return Options{}, err
@@ -1221,6 +2008,12 @@ func normalizeOptions(opts Options, snapshot Snapshot) (Options, error) {
 	if opts.Queue.MaxInflightSegments < 0 {
 		return Options{}, fmt.Errorf("%w: negative max inflight segments %d", ErrInvalidOptions, opts.Queue.MaxInflightSegments)
 	}
+	if opts.MaxProducers == 0 {
+		opts.MaxProducers = DefaultMaxProducers
+	}
+	if opts.MaxProducers < 0 || opts.MaxProducers > pmeta.MaxProducers {
+		return Options{}, fmt.Errorf("%w: max producers=%d outside 1..%d", ErrInvalidOptions, opts.MaxProducers, pmeta.MaxProducers)
+	}
 	if opts.Clock == nil {
 		opts.Clock = SystemClock{}
 	}
@@ -1230,9 +2023,16 @@ func normalizeOptions(opts Options, snapshot Snapshot) (Options, error) {
 	return opts, nil
 }
 
+func dictionaryID(d *segblock.Dictionary) uint32 {
+	if d == nil {
+		return 0
+	}
+	return d.ID()
+}
+
 func estimateInflightBytes(rawBytes uint64, records uint32, codec segformat.Codec) uint64 {
 	storedUpper := rawBytes
-	if codec == segformat.CodecZstd && rawBytes > 0 {
+	if codec != segformat.CodecNone && rawBytes > 0 {
 		storedUpper = satAdd(rawBytes, (rawBytes+3)/4)
 	}
 	blockCountUpper := uint64(records)
@@ -2,8 +2,11 @@ package writer
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -476,6 +479,593 @@ func TestWriterRejectsTimestampRegression(t *testing.T) {
 	}
 }
 
+func TestWriterRejectsReservedRedactionRecords(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w, err := New(testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for name, record := range map[string]Record{
+		"redaction header": {Headers: []segformat.Header{{Key: []byte(segformat.RedactionHeaderKey)}}, Value: []byte("a")},
+		"digest header":    {Headers: []segformat.Header{{Key: []byte("k")}, {Key: []byte(segformat.RedactionDigestHeaderKey)}}, Value: []byte("a")},
+		"redacted value":   {Value: []byte(segformat.RedactedValue)},
+	} {
+		if _, err := w.Append(ctx, record); !errors.Is(err, ErrReservedRecord) {
+			t.Fatalf("Append(%s) error = %v, want %v", name, err, ErrReservedRecord)
+		}
+		if _, err := w.AppendBatch(ctx, []Record{{Value: []byte("ok")}, record}); !errors.Is(err, ErrReservedRecord) {
+			t.Fatalf("AppendBatch(%s) error = %v, want %v", name, err, ErrReservedRecord)
+		}
+	}
+	// A rejected record leaves the writer usable and appends nothing.
+	result, err := w.Append(ctx, Record{Headers: []segformat.Header{{Key: []byte("partitionlog-redacted-by")}}, Value: []byte("a")})
+	if err != nil || result.LSN != 0 {
+		t.Fatalf("Append(after rejections) = %+v, %v; want lsn 0", result, err)
+	}
+}
+
+func TestWriterDeduplicatesProducerSequencesAcrossRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	factory := newMemorySegmentFactory()
+	producer := [16]byte{0xaa}
+	uuidGen := newSequenceUUIDGen()
+
+	opts := testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for seq := uint64(1); seq <= 2; seq++ {
+		result, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("v"), ProducerID: producer, Sequence: seq})
+		if err != nil || result.Duplicate {
+			t.Fatalf("Append(seq=%d) = %+v, %v; want fresh append", seq, result, err)
+		}
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("other")}); err != nil {
+		t.Fatalf("Append(no producer) error = %v", err)
+	}
+	retry, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("v"), ProducerID: producer, Sequence: 2})
+	if err != nil || !retry.Duplicate || retry.LSN != 1 {
+		t.Fatalf("Append(retry) = %+v, %v; want duplicate of lsn 1", retry, err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: producer, Sequence: 4}); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("Append(gap) error = %v, want %v", err, ErrSequenceGap)
+	}
+	if w.Err() != nil {
+		t.Fatalf("Err() = %v, want rejected sequences to keep the writer usable", w.Err())
+	}
+	snapshot, err := w.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 3 {
+		t.Fatalf("NextLSN = %d, want 3", snapshot.Head.NextLSN)
+	}
+
+	opts = testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	replacement, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(replacement) error = %v", err)
+	}
+	retry, err = replacement.Append(ctx, Record{TimestampMS: 2, Value: []byte("v"), ProducerID: producer, Sequence: 2})
+	if err != nil || !retry.Duplicate || retry.LSN != 1 {
+		t.Fatalf("Append(retry after restart) = %+v, %v; want duplicate of lsn 1", retry, err)
+	}
+	if _, err := replacement.Append(ctx, Record{TimestampMS: 2, ProducerID: producer, Sequence: 1}); !errors.Is(err, ErrDuplicateSequence) {
+		t.Fatalf("Append(old sequence) error = %v, want %v", err, ErrDuplicateSequence)
+	}
+	next, err := replacement.Append(ctx, Record{TimestampMS: 2, Value: []byte("v"), ProducerID: producer, Sequence: 3})
+	if err != nil || next.Duplicate || next.LSN != 3 {
+		t.Fatalf("Append(next sequence) = %+v, %v; want fresh lsn 3", next, err)
+	}
+	snapshot, err = replacement.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close(replacement) error = %v", err)
+	}
+	last, _ := snapshot.Head.Last()
+	if state, ok := last.Producers.Get(producer); !ok || state.Sequence != 3 || state.LSN != 3 {
+		t.Fatalf("last segment producer = %+v, %v; want sequence 3 at lsn 3", state, ok)
+	}
+}
+
+func TestWriterRejectsUnknownProducersAfterEviction(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	opts := testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory())
+	opts.MaxProducers = 2
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	first, second, third := [16]byte{1}, [16]byte{2}, [16]byte{3}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: first, Sequence: 5}); !errors.Is(err, ErrUnknownProducer) {
+		t.Fatalf("Append(new producer at 5) error = %v, want %v", err, ErrUnknownProducer)
+	}
+	for _, record := range []Record{
+		{TimestampMS: 1, ProducerID: first, Sequence: FirstSequence},
+		{TimestampMS: 1, ProducerID: second, Sequence: FirstSequence},
+		{TimestampMS: 1, ProducerID: first, Sequence: FirstSequence + 1},
+		{TimestampMS: 1, ProducerID: third, Sequence: FirstSequence},
+	} {
+		if _, err := w.Append(ctx, record); err != nil {
+			t.Fatalf("Append(%x seq=%d) error = %v", record.ProducerID, record.Sequence, err)
+		}
+	}
+
+	// second has the lowest last LSN, so third evicted it.
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: second, Sequence: FirstSequence + 1}); !errors.Is(err, ErrUnknownProducer) {
+		t.Fatalf("Append(evicted) error = %v, want %v", err, ErrUnknownProducer)
+	}
+	_, err = w.AppendBatch(ctx, []Record{{TimestampMS: 1, ProducerID: second, Sequence: FirstSequence + 1}})
+	if !errors.Is(err, ErrUnknownProducer) {
+		t.Fatalf("AppendBatch(evicted) error = %v, want %v", err, ErrUnknownProducer)
+	}
+	retry, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: first, Sequence: FirstSequence + 1})
+	if err != nil || !retry.Duplicate || retry.LSN != 2 {
+		t.Fatalf("Append(retry of tracked producer) = %+v, %v; want duplicate of lsn 2", retry, err)
+	}
+	if w.Err() != nil {
+		t.Fatalf("Err() = %v, want unknown producers to keep the writer usable", w.Err())
+	}
+	snapshot, err := w.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 4 {
+		t.Fatalf("NextLSN = %d, want 4", snapshot.Head.NextLSN)
+	}
+}
+
+func TestWriterStoresProducerDeltasOnSegmentsAndStateOnHead(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	factory := newMemorySegmentFactory()
+	opts := testOptions(t, cat, factory)
+	const producers = 512
+	opts.Roll.MaxSegmentRecords = 16
+	opts.MaxProducers = producers
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for i := 0; i < producers; i++ {
+		record := Record{TimestampMS: 1, ProducerID: [16]byte{1, byte(i >> 8), byte(i)}, Sequence: 1}
+		if _, err := w.Append(ctx, record); err != nil {
+			t.Fatalf("Append(%d) error = %v", i, err)
+		}
+	}
+	snapshot, err := w.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := snapshot.Head.Producers.Len(); got != producers {
+		t.Fatalf("head producers = %d, want %d", got, producers)
+	}
+
+	page, err := cat.ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 1, Limit: 100})
+	if err != nil {
+		t.Fatalf("ListSegments() error = %v", err)
+	}
+	if got, want := len(page.Segments), producers/16; got != want {
+		t.Fatalf("segments = %d, want %d", got, want)
+	}
+	for _, segment := range page.Segments {
+		if got := segment.Producers.Len(); got != 16 {
+			t.Fatalf("segment %d producers = %d, want only its own 16", segment.BaseLSN, got)
+		}
+		data, err := json.Marshal(segment)
+		if err != nil {
+			t.Fatalf("Marshal(%d) error = %v", segment.BaseLSN, err)
+		}
+		// The cumulative set of 512 producers alone would take about 30 KiB.
+		if len(data) > 4<<10 {
+			t.Fatalf("segment %d ref = %d bytes, want a bounded delta", segment.BaseLSN, len(data))
+		}
+	}
+
+	opts = testOptions(t, cat, factory)
+	opts.MaxProducers = producers
+	replacement, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(replacement) error = %v", err)
+	}
+	retry, err := replacement.Append(ctx, Record{TimestampMS: 2, ProducerID: [16]byte{1}, Sequence: 1})
+	if err != nil || !retry.Duplicate || retry.LSN != 0 {
+		t.Fatalf("Append(retry of first producer) = %+v, %v; want duplicate of lsn 0", retry, err)
+	}
+	if _, err := replacement.Close(ctx); err != nil {
+		t.Fatalf("Close(replacement) error = %v", err)
+	}
+}
+
+func TestWriterRefusesWALOfAnotherPartitionOrNewerWriter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	factory := newMemorySegmentFactory()
+	opts := testOptions(t, cat, factory)
+	opts.WAL = &memoryWAL{}
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	bound := opts.WAL.(*memoryWAL).headers[0]
+	if bound.Partition != 1 || bound.Epoch != w.identity.Epoch {
+		t.Fatalf("bound header = %+v, want partition 1 at epoch %d", bound, w.identity.Epoch)
+	}
+
+	otherPartition, newerWriter := bound, bound
+	otherPartition.Partition = 2
+	newerWriter.Epoch = math.MaxUint64
+	for name, header := range map[string]WALHeader{"other partition": otherPartition, "newer writer": newerWriter} {
+		wal := &memoryWAL{headers: []WALHeader{header}, records: []WALRecord{{LSN: 0, Record: Record{TimestampMS: 1}}}}
+		opts = testOptions(t, cat, factory)
+		opts.WAL = wal
+		if _, err := New(opts); !errors.Is(err, ErrWALMismatch) {
+			t.Fatalf("New(%s) error = %v, want %v", name, err, ErrWALMismatch)
+		}
+		if len(wal.records) != 1 {
+			t.Fatalf("WAL records after refused %s = %+v, want untouched", name, wal.records)
+		}
+	}
+}
+
+func TestWriterReplaysUncommittedWALRecordsOnRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	factory := newMemorySegmentFactory()
+	uuidGen := newSequenceUUIDGen()
+	wal := &memoryWAL{}
+	producer := [16]byte{0xbb}
+
+	opts := testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	opts.WAL = wal
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("committed")}); err != nil {
+		t.Fatalf("Append(committed) error = %v", err)
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 2, Value: []byte("a"), ProducerID: producer, Sequence: 1}); err != nil {
+		t.Fatalf("Append(a) error = %v", err)
+	}
+	if _, err := w.AppendBatch(ctx, []Record{{TimestampMS: 3, Value: []byte("b")}, {TimestampMS: 3, Value: []byte("c")}}); err != nil {
+		t.Fatalf("AppendBatch() error = %v", err)
+	}
+	// Abort stands in for a crash: the acknowledged records are never
+	// published.
+	if err := w.Abort(ctx); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+
+	opts = testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	opts.WAL = wal
+	replacement, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(replacement) error = %v", err)
+	}
+	retry, err := replacement.Append(ctx, Record{TimestampMS: 4, Value: []byte("a"), ProducerID: producer, Sequence: 1})
+	if err != nil || !retry.Duplicate || retry.LSN != 1 {
+		t.Fatalf("Append(retry) = %+v, %v; want duplicate of replayed lsn 1", retry, err)
+	}
+	next, err := replacement.Append(ctx, Record{TimestampMS: 4, Value: []byte("d")})
+	if err != nil || next.LSN != 4 {
+		t.Fatalf("Append(after replay) = %+v, %v; want lsn 4", next, err)
+	}
+	snapshot, err := replacement.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close(replacement) error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 5 {
+		t.Fatalf("NextLSN = %d, want 5", snapshot.Head.NextLSN)
+	}
+	if stored, _ := wal.Replay(ctx, 0); len(stored) != 0 {
+		t.Fatalf("WAL after commit = %+v, want trimmed", stored)
+	}
+}
+
+func TestWriterSkipsWALRecordsSupersededByNewerEpoch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	factory := newMemorySegmentFactory()
+	uuidGen := newSequenceUUIDGen()
+	wal := &memoryWAL{}
+
+	opts := testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	opts.WAL = wal
+	stale, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(stale) error = %v", err)
+	}
+	if _, err := stale.Append(ctx, Record{TimestampMS: 1, Value: []byte("lost")}); err != nil {
+		t.Fatalf("Append(stale) error = %v", err)
+	}
+	if err := stale.Abort(ctx); err != nil {
+		t.Fatalf("Abort(stale) error = %v", err)
+	}
+
+	// Another host takes over the partition without this WAL and commits
+	// LSN 0 first.
+	opts = testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	other, err := New(opts)
+	if err != nil {
+		t.Fatalf("New(other) error = %v", err)
+	}
+	if _, err := other.Append(ctx, Record{TimestampMS: 1, Value: []byte("won")}); err != nil {
+		t.Fatalf("Append(other) error = %v", err)
+	}
+	if _, err := other.Close(ctx); err != nil {
+		t.Fatalf("Close(other) error = %v", err)
+	}
+
+	opts = testOptions(t, cat, factory)
+	opts.UUIDGen = uuidGen
+	opts.WAL = wal
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	snapshot, err := w.Close(ctx)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if snapshot.Head.NextLSN != 1 || snapshot.Head.SegmentCount != 1 {
+		t.Fatalf("head = %+v, want only the newer epoch's segment", snapshot.Head)
+	}
+}
+
+func TestWriterGroupCommitsWALSyncsOutsideItsLock(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	wal := &memoryWAL{syncGate: make(chan struct{})}
+	opts := testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory())
+	opts.WAL = wal
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	const appends = 8
+	results := make(chan error, appends+1)
+	go func() {
+		_, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("first")})
+		results <- err
+	}()
+	wal.waitFor(t, "first sync", func(m *memoryWAL) bool { return m.syncs == 1 })
+	// The first Sync is blocked; the others still get LSNs and reach the WAL.
+	for i := 0; i < appends; i++ {
+		go func() {
+			_, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("next")})
+			results <- err
+		}()
+	}
+	wal.waitFor(t, "all appends written", func(m *memoryWAL) bool { return len(m.records) == appends+1 })
+	select {
+	case err := <-results:
+		t.Fatalf("Append returned %v before its Sync", err)
+	default:
+	}
+	close(wal.syncGate)
+	for i := 0; i < appends+1; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("Append error = %v", err)
+		}
+	}
+	wal.mu.Lock()
+	syncs := wal.syncs
+	wal.mu.Unlock()
+	if syncs != 2 {
+		t.Fatalf("WAL syncs = %d, want the waiting appends to share one", syncs)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestWriterFailsOnWALSyncError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	wal := &memoryWAL{syncErr: errors.New("disk gone")}
+	opts := testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory())
+	opts.WAL = wal
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, Value: []byte("v")}); !errors.Is(err, ErrWALFailed) {
+		t.Fatalf("Append() error = %v, want %v", err, ErrWALFailed)
+	}
+	if err := w.Err(); !errors.Is(err, ErrWALFailed) {
+		t.Fatalf("Err() = %v, want %v", err, ErrWALFailed)
+	}
+	if _, err := w.AppendBatch(ctx, []Record{{TimestampMS: 1}}); err == nil {
+		t.Fatal("AppendBatch(after failed sync) error = nil")
+	}
+}
+
+func TestWriterForgetsOldestProducerPastMaxProducers(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	opts := testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory())
+	opts.MaxProducers = 1
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	first, second := [16]byte{1}, [16]byte{2}
+	for _, record := range []Record{
+		{TimestampMS: 1, ProducerID: first, Sequence: 1},
+		{TimestampMS: 1, ProducerID: second, Sequence: 1},
+	} {
+		if _, err := w.Append(ctx, record); err != nil {
+			t.Fatalf("Append(%x) error = %v", record.ProducerID, err)
+		}
+	}
+	// The first producer was forgotten, so its retry is appended again.
+	result, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: first, Sequence: 1})
+	if err != nil || result.Duplicate || result.LSN != 2 {
+		t.Fatalf("Append(forgotten retry) = %+v, %v; want fresh lsn 2", result, err)
+	}
+
+	opts.MaxProducers = pmeta.MaxProducers + 1
+	if _, err := New(opts); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("New(max producers too large) error = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+func TestWriterAppendBatchAtIsConditionalAndCommitsWhole(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	opts := testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory())
+	opts.Roll.MaxSegmentRecords = 3
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := w.AppendIf(ctx, 0, Record{TimestampMS: 1, Value: []byte("a")}); err != nil {
+		t.Fatalf("AppendIf(0) error = %v", err)
+	}
+	var conflict LSNConflictError
+	if _, err := w.AppendIf(ctx, 0, Record{TimestampMS: 1, Value: []byte("b")}); !errors.As(err, &conflict) || conflict.Next != 1 {
+		t.Fatalf("AppendIf(stale) error = %v, want LSNConflictError next=1", err)
+	}
+	if w.Err() != nil {
+		t.Fatalf("Err() = %v, want a conflict to keep the writer usable", w.Err())
+	}
+
+	batch := []Record{
+		{TimestampMS: 2, Value: []byte("b")},
+		{TimestampMS: 2, Value: []byte("c")},
+		{TimestampMS: 3, Value: []byte("d")},
+	}
+	result, err := w.AppendBatchAt(ctx, 1, batch)
+	if err != nil || result.FirstLSN != 1 || result.LastLSN != 3 {
+		t.Fatalf("AppendBatchAt(1) = %+v, %v; want [1,3]", result, err)
+	}
+	// The batch does not fit beside LSN 0 and is cut into its own segment.
+	snapshot, err := w.WaitCommitted(ctx, result.LastLSN)
+	if err != nil {
+		t.Fatalf("WaitCommitted() error = %v", err)
+	}
+	last, _ := snapshot.Head.Last()
+	assertRange(t, last, 1, 3)
+
+	if _, err := w.WaitCommitted(ctx, 4); !errors.Is(err, ErrNotAppended) {
+		t.Fatalf("WaitCommitted(unassigned) error = %v, want %v", err, ErrNotAppended)
+	}
+	if _, err := w.AppendBatchAt(ctx, 4, nil); !errors.Is(err, ErrEmptyBatch) {
+		t.Fatalf("AppendBatchAt(empty) error = %v, want %v", err, ErrEmptyBatch)
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := w.WaitCommitted(ctx, 3); err != nil {
+		t.Fatalf("WaitCommitted(after close) error = %v", err)
+	}
+}
+
+func TestWriterAppendBatchIsNeverSplitAcrossSegments(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cat := catalog.NewMemoryCatalog()
+	opts := testOptions(t, cat, newMemorySegmentFactory())
+	opts.Roll.MaxSegmentRecords = 4
+	w, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	batch := func(n int) []Record {
+		records := make([]Record, n)
+		for i := range records {
+			records[i] = Record{TimestampMS: 1, Value: []byte{byte(i)}}
+		}
+		return records
+	}
+	for _, n := range []int{3, 3, 6, 1} {
+		if _, err := w.AppendBatch(ctx, batch(n)); err != nil {
+			t.Fatalf("AppendBatch(%d) error = %v", n, err)
+		}
+	}
+	if _, err := w.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	// Each batch that does not fit beside the previous one starts a new
+	// segment, and the oversized batch gets one of its own.
+	page, err := cat.ListSegments(ctx, catalog.ListSegmentsRequest{Partition: 1, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListSegments() error = %v", err)
+	}
+	want := [][2]uint64{{0, 2}, {3, 5}, {6, 11}, {12, 12}}
+	if len(page.Segments) != len(want) {
+		t.Fatalf("segments = %d, want %d", len(page.Segments), len(want))
+	}
+	for i, segment := range page.Segments {
+		assertRange(t, segment, want[i][0], want[i][1])
+	}
+}
+
+func TestWriterAppendBatchAtChecksProducerSequencesInOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w, err := New(testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	producer := [16]byte{7}
+	if _, err := w.AppendBatchAt(ctx, 0, []Record{
+		{TimestampMS: 1, ProducerID: producer, Sequence: 1},
+		{TimestampMS: 1, ProducerID: producer, Sequence: 3},
+	}); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("AppendBatchAt(gap) error = %v, want %v", err, ErrSequenceGap)
+	}
+	batch := []Record{
+		{TimestampMS: 1, ProducerID: producer, Sequence: 1},
+		{TimestampMS: 1, ProducerID: producer, Sequence: 2},
+	}
+	if _, err := w.AppendBatchAt(ctx, 0, batch); err != nil {
+		t.Fatalf("AppendBatchAt() error = %v", err)
+	}
+	if _, err := w.AppendBatchAt(ctx, 2, batch); !errors.Is(err, ErrDuplicateSequence) {
+		t.Fatalf("AppendBatchAt(retry) error = %v, want %v", err, ErrDuplicateSequence)
+	}
+	retry, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: producer, Sequence: 2})
+	if err != nil || !retry.Duplicate || retry.LSN != 1 {
+		t.Fatalf("Append(retry last) = %+v, %v; want duplicate of lsn 1", retry, err)
+	}
+}
+
 func TestWriterCutBackpressureOnInflight(t *testing.T) {
 	t.Parallel()
 
@@ -610,6 +1200,97 @@ func TestWriterAsyncPublishFailureSurfacesOnce(t *testing.T) {
 	}
 }
 
+func TestWriterAppendAsyncResolvesOnCommitAndFailsOnTerminalError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	w, err := New(testOptions(t, catalog.NewMemoryCatalog(), newMemorySegmentFactory()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	futures := make([]*AppendFuture, 3)
+	for i := range futures {
+		if futures[i], err = w.AppendAsync(ctx, Record{TimestampMS: 1, Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("AppendAsync(%d) error = %v", i, err)
+		}
+		if got := futures[i].Result().LSN; got != uint64(i) {
+			t.Fatalf("AppendAsync(%d) LSN = %d", i, got)
+		}
+	}
+	select {
+	case <-futures[0].Done():
+		t.Fatal("future resolved before its segment was published")
+	default:
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	for i, f := range futures {
+		snapshot, err := f.Wait(ctx)
+		if err != nil || snapshot.Head.NextLSN != 3 {
+			t.Fatalf("Wait(%d) = next_lsn %d, %v; want 3", i, snapshot.Head.NextLSN, err)
+		}
+	}
+	// A retry of a committed producer append resolves immediately.
+	producer := [16]byte{3}
+	if _, err := w.Append(ctx, Record{TimestampMS: 1, ProducerID: producer, Sequence: 1}); err != nil {
+		t.Fatalf("Append(producer) error = %v", err)
+	}
+	if _, err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush(producer) error = %v", err)
+	}
+	retry, err := w.AppendAsync(ctx, Record{TimestampMS: 1, ProducerID: producer, Sequence: 1})
+	if err != nil {
+		t.Fatalf("AppendAsync(retry) error = %v", err)
+	}
+	if !retry.Result().Duplicate {
+		t.Fatalf("AppendAsync(retry) = %+v, want duplicate", retry.Result())
+	}
+	select {
+	case <-retry.Done():
+	default:
+		t.Fatal("future of a committed duplicate is not resolved")
+	}
+
+	pending, err := w.AppendAsync(ctx, Record{TimestampMS: 1})
+	if err != nil {
+		t.Fatalf("AppendAsync(pending) error = %v", err)
+	}
+	if err := w.Abort(ctx); err != nil {
+		t.Fatalf("Abort() error = %v", err)
+	}
+	if _, err := pending.Wait(ctx); !errors.Is(err, ErrAborted) {
+		t.Fatalf("Wait(after abort) error = %v, want %v", err, ErrAborted)
+	}
+}
+
+func TestWriterAppendAsyncFailsOnAsyncPublishFailure(t *testing.T) {
+	t.Parallel()
+
+	session := &sessionStub{
+		snapshot: Snapshot{
+			Head:     pmeta.PartitionHead{Partition: 1, WriterEpoch: 1},
+			Identity: WriterIdentity{Epoch: 1, Tag: [16]byte{9, 8, 7}},
+		},
+		publish: func(context.Context, PublishRequest, Snapshot) (Snapshot, error) {
+			return Snapshot{}, fmt.Errorf("%w: boom", ErrPublishFailed)
+		},
+	}
+	w, err := New(testSessionOptions(session, newMemorySegmentFactory()))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	f, err := w.AppendAsync(context.Background(), Record{TimestampMS: 1, Value: []byte("a")})
+	if err != nil {
+		t.Fatalf("AppendAsync() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := f.Wait(ctx); !errors.Is(err, ErrPublishFailed) {
+		t.Fatalf("Wait() error = %v, want %v", err, ErrPublishFailed)
+	}
+}
+
 func TestWriterCutFailureBeforeSwapKeepsWriterUsable(t *testing.T) {
 	t.Parallel()
 
@@ -1284,6 +1965,92 @@ func (f *flakySegmentFactory) NewSegmentSink(ctx context.Context, info SegmentIn
 	return f.next.NewSegmentSink(ctx, info)
 }
 
+// memoryWAL is a WAL whose records survive the writers that share it. Sync
+// blocks until syncGate, when set, is closed and then returns syncErr.
+type memoryWAL struct {
+	mu       sync.Mutex
+	headers  []WALHeader
+	records  []WALRecord
+	syncs    int
+	syncGate chan struct{}
+	syncErr  error
+}
+
+func (m *memoryWAL) Bind(_ context.Context, header WALHeader) ([]WALHeader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := slices.Clone(m.headers)
+	m.headers = append(m.headers, header)
+	return stored, nil
+}
+
+func (m *memoryWAL) Append(_ context.Context, epoch, firstLSN uint64, records []Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keep := 0
+	for keep < len(m.records) && m.records[keep].LSN < firstLSN {
+		keep++
+	}
+	m.records = m.records[:keep]
+	for i, record := range records {
+		m.records = append(m.records, WALRecord{Epoch: epoch, LSN: firstLSN + uint64(i), Record: record})
+	}
+	return nil
+}
+
+func (m *memoryWAL) Sync(context.Context) error {
+	m.mu.Lock()
+	m.syncs++
+	gate, err := m.syncGate, m.syncErr
+	m.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	return err
+}
+
+// waitFor polls until cond holds for the WAL.
+func (m *memoryWAL) waitFor(t *testing.T, what string, cond func(*memoryWAL) bool) {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for {
+		m.mu.Lock()
+		ok := cond(m)
+		m.mu.Unlock()
+		if ok {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for the WAL: %s", what)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *memoryWAL) Replay(_ context.Context, fromLSN uint64) ([]WALRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var stored []WALRecord
+	for _, record := range m.records {
+		if record.LSN >= fromLSN {
+			stored = append(stored, record)
+		}
+	}
+	return stored, nil
+}
+
+func (m *memoryWAL) Trim(_ context.Context, lsn uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.records) > 0 && m.records[0].LSN < lsn {
+		m.records = m.records[1:]
+	}
+	return nil
+}
+
 func newSequenceUUIDGen() UUIDGen {
 	var n byte
 	return func() ([16]byte, error) {